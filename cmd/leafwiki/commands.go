@@ -0,0 +1,281 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/Gomez12/wiki/internal/core/auth"
+	"github.com/Gomez12/wiki/internal/core/backup"
+	"github.com/Gomez12/wiki/internal/core/importer"
+	"github.com/Gomez12/wiki/internal/core/seed"
+	verrors "github.com/Gomez12/wiki/internal/core/shared/errors"
+	"github.com/Gomez12/wiki/internal/core/staticsite"
+	"github.com/Gomez12/wiki/internal/core/tree"
+	"github.com/Gomez12/wiki/internal/wiki"
+)
+
+// errUsage is returned for invalid command arguments, the usage is printed instead of the error
+var errUsage = errors.New("invalid usage")
+
+// commandEnv is the configuration shared by all subcommands
+type commandEnv struct {
+	dataDir       string
+	adminPassword string
+}
+
+// runCommand runs a headless administration command on the data directory.
+// The commands must not run while a server uses the same data directory.
+func runCommand(env commandEnv, name string, args []string) error {
+	switch name {
+	case "reset-admin-password":
+		return resetAdminPassword(env)
+	case "user":
+		if len(args) == 0 || args[0] != "add" {
+			return errUsage
+		}
+		return addUser(env, args[1:])
+	case "reindex":
+		return reindex(env)
+	case "export":
+		return exportPages(env, args)
+	case "import":
+		return importPages(env, args)
+	case "backup":
+		if len(args) != 1 {
+			return errUsage
+		}
+		if err := backup.CreateFile(env.dataDir, args[0]); err != nil {
+			return err
+		}
+		fmt.Printf("Backup of %s written to %s\n", env.dataDir, args[0])
+		return nil
+	case "migrate-layout":
+		if len(args) != 1 {
+			return errUsage
+		}
+		return migrateLayout(env, args[0])
+	case "seed":
+		return seedPages(env, args)
+	default:
+		return fmt.Errorf("%w: unknown command %s", errUsage, name)
+	}
+}
+
+// openWiki opens the wiki without search indexing and file watcher.
+// No JWT secret is needed, the commands don't issue tokens.
+func openWiki(env commandEnv, opts ...wiki.Option) (*wiki.Wiki, error) {
+	return wiki.NewWiki(env.dataDir, env.adminPassword, "", false, opts...)
+}
+
+func resetAdminPassword(env commandEnv) error {
+	w, err := openWiki(env)
+	if err != nil {
+		return err
+	}
+	defer w.Close()
+
+	user, err := w.ResetAdminUserPassword()
+	if err != nil {
+		return err
+	}
+	fmt.Println("Admin password reset successfully.")
+	fmt.Printf("New password for user %s: %s\n", user.Username, user.Password)
+	return nil
+}
+
+func addUser(env commandEnv, args []string) error {
+	fs := flag.NewFlagSet("user add", flag.ContinueOnError)
+	username := fs.String("username", "", "username")
+	email := fs.String("email", "", "email address")
+	password := fs.String("password", "", "password")
+	role := fs.String("role", auth.RoleEditor, "role: admin or editor")
+	if err := fs.Parse(args); err != nil {
+		return errUsage
+	}
+
+	w, err := openWiki(env)
+	if err != nil {
+		return err
+	}
+	defer w.Close()
+
+	user, err := w.CreateUser(*username, *email, *password, *role)
+	if err != nil {
+		return err
+	}
+	fmt.Printf("User %s (%s) created with id %s.\n", user.Username, user.Role, user.ID)
+	return nil
+}
+
+func reindex(env commandEnv) error {
+	w, err := openWiki(env)
+	if err != nil {
+		return err
+	}
+	if err := w.ReindexAll(); err != nil {
+		_ = w.Close()
+		return err
+	}
+	// waits for the reindex job to finish
+	if err := w.Shutdown(context.Background()); err != nil {
+		return err
+	}
+
+	status := w.GetIndexingStatus()
+	fmt.Printf("Reindexed %d pages, %d failed.\n", status.Indexed, status.Failed)
+	for _, msg := range status.Errors {
+		fmt.Printf("  %s\n", msg)
+	}
+	return nil
+}
+
+func migrateLayout(env commandEnv, name string) error {
+	layout, err := tree.ParseLayout(name)
+	if err != nil {
+		return err
+	}
+
+	w, err := openWiki(env)
+	if err != nil {
+		return err
+	}
+	defer w.Close()
+
+	migration, err := w.MigrateLayout(layout)
+	if err != nil {
+		return err
+	}
+	fmt.Printf("Moved %d pages from the %s to the %s layout.\n", migration.Moved, migration.From, migration.To)
+	return nil
+}
+
+func seedPages(env commandEnv, args []string) error {
+	fs := flag.NewFlagSet("seed", flag.ContinueOnError)
+	pages := fs.Int("pages", 0, "number of generated pages besides the sample wiki")
+	randomSeed := fs.Uint64("seed", 1, "seed of the generated pages")
+	if err := fs.Parse(args); err != nil || fs.NArg() != 0 || *pages < 0 {
+		return errUsage
+	}
+
+	w, err := openWiki(env)
+	if err != nil {
+		return err
+	}
+	defer w.Close()
+
+	started := time.Now()
+	report, err := seed.Run(w, seed.Options{
+		Pages: *pages,
+		Seed:  *randomSeed,
+		Progress: func(done, total int) {
+			if done%1000 == 0 {
+				fmt.Printf("  %d/%d pages\n", done, total)
+			}
+		},
+	})
+	if err != nil {
+		return err
+	}
+	fmt.Printf("Created %d pages and %d assets in %s.\n", report.Pages, report.Assets, time.Since(started).Round(time.Millisecond))
+	fmt.Println("The server indexes them for the search when it starts.")
+	return nil
+}
+
+func exportPages(env commandEnv, args []string) error {
+	fs := flag.NewFlagSet("export", flag.ContinueOnError)
+	html := fs.Bool("html", false, "render a static HTML site instead of Markdown files")
+	if err := fs.Parse(args); err != nil || fs.NArg() != 1 {
+		return errUsage
+	}
+	destDir := fs.Arg(0)
+
+	w, err := openWiki(env)
+	if err != nil {
+		return err
+	}
+	defer w.Close()
+
+	var count int
+	if *html {
+		out, err := staticsite.NewDirWriter(destDir)
+		if err != nil {
+			return err
+		}
+		count, err = w.ExportHTML(context.Background(), out)
+		if err != nil {
+			return err
+		}
+	} else if count, err = w.Export(destDir); err != nil {
+		return err
+	}
+	fmt.Printf("Exported %d pages to %s\n", count, destDir)
+	return nil
+}
+
+func importPages(env commandEnv, args []string) error {
+	fs := flag.NewFlagSet("import", flag.ContinueOnError)
+	parentPath := fs.String("parent", "", "path of the page to import below (default: root)")
+	onCollision := fs.String("on-collision", string(importer.CollisionSuffix), "how to handle slugs which are already taken: suffix, reject or merge")
+	if err := fs.Parse(args); err != nil || fs.NArg() != 1 {
+		return errUsage
+	}
+	sourceDir, err := filepath.Abs(fs.Arg(0))
+	if err != nil {
+		return err
+	}
+
+	// the folder given on the command line is the import root
+	w, err := openWiki(env, wiki.WithImportRoot(sourceDir))
+	if err != nil {
+		return err
+	}
+	defer w.Close()
+
+	var parentID *string
+	if *parentPath != "" {
+		parent, err := w.FindByPath(*parentPath)
+		if err != nil {
+			return fmt.Errorf("parent page %s: %w", *parentPath, err)
+		}
+		parentID = &parent.ID
+	}
+
+	plan, err := w.AnalyzeImport(sourceDir)
+	if err != nil {
+		return err
+	}
+	plan.OnCollision = importer.CollisionStrategy(*onCollision)
+	report, err := w.ApplyImport(plan, parentID)
+	if err != nil {
+		return err
+	}
+	fmt.Printf("Imported %d pages from %s\n", report.Created, fs.Arg(0))
+	for _, collision := range report.Collisions {
+		fmt.Printf("  %s %s: %s -> %s\n", collision.Resolution, collision.SourcePath, collision.Slug, collision.ResolvedSlug)
+		if collision.Reason != "" {
+			fmt.Printf("    %s\n", collision.Reason)
+		}
+	}
+	for _, skipped := range plan.Skipped {
+		fmt.Printf("  skipped %s\n", skipped)
+	}
+	return nil
+}
+
+// describeError adds the field messages of validation errors
+func describeError(err error) error {
+	var vErr *verrors.ValidationErrors
+	if !errors.As(err, &vErr) || !vErr.HasErrors() {
+		return err
+	}
+	msgs := make([]string, 0, len(vErr.Errors))
+	for _, f := range vErr.Errors {
+		msgs = append(msgs, f.Field+": "+f.Message)
+	}
+	return errors.New(strings.Join(msgs, "; "))
+}
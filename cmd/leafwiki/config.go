@@ -0,0 +1,78 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"log/slog"
+	"os"
+	"slices"
+	"sync"
+
+	"github.com/Gomez12/wiki/internal/core/config"
+	"github.com/Gomez12/wiki/internal/core/logging"
+)
+
+// reloadableOptions take effect when the config file is reloaded, the other options need a restart
+var reloadableOptions = []string{"log-level", "log-format"}
+
+// configOptions returns the names of the flags a config file may set
+func configOptions() []string {
+	var names []string
+	flag.VisitAll(func(f *flag.Flag) {
+		if f.Name != "config" {
+			names = append(names, f.Name)
+		}
+	})
+	return names
+}
+
+// configReloader reads the config file again, on SIGHUP or POST /api/admin/config/reload.
+// It applies the log level and format and the settings section to the wikis; changes of other
+// options are logged, they need a restart.
+type configReloader struct {
+	mu   sync.Mutex
+	file *config.File
+	// logLevel and logFormat are set by flag or environment variable, empty if the file decides
+	logLevel  string
+	logFormat string
+	// apply updates the settings of all wikis, set once they are created
+	apply func(patch []byte) error
+}
+
+func (r *configReloader) reload() error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	file, err := config.Load(r.file.Path, configOptions())
+	if err != nil {
+		return err
+	}
+	level := r.logLevel
+	if level == "" {
+		level = file.Get("log-level", "info")
+	}
+	format := r.logFormat
+	if format == "" {
+		format = file.Get("log-format", logging.FormatText)
+	}
+	// validated before the settings are applied, so an invalid file changes nothing
+	logger, err := logging.New(os.Stderr, level, format)
+	if err != nil {
+		return fmt.Errorf("%s: %w", file.Path, err)
+	}
+	if file.Settings != nil && r.apply != nil {
+		if err := r.apply(file.Settings); err != nil {
+			return err
+		}
+	}
+	slog.SetDefault(logger)
+
+	for _, name := range configOptions() {
+		if !slices.Contains(reloadableOptions, name) && file.Get(name, "") != r.file.Get(name, "") {
+			slog.Warn("option of the config file changed, restart to apply it", "option", name)
+		}
+	}
+	r.file = file
+	slog.Info("reloaded config file", "file", file.Path)
+	return nil
+}
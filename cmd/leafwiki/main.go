@@ -1,30 +1,102 @@
 package main
 
 import (
+	"context"
+	"errors"
 	"flag"
 	"fmt"
-	"log"
+	"log/slog"
+	"net/http"
 	"os"
+	"os/signal"
+	"strconv"
+	"strings"
+	"syscall"
+	"time"
 
-	"github.com/Gomez12/wiki/internal/http"
-	"github.com/Gomez12/wiki/internal/wiki"
+	"github.com/Gomez12/wiki/internal/core/config"
+	"github.com/Gomez12/wiki/internal/core/gitsync"
+	"github.com/Gomez12/wiki/internal/core/logging"
+	"github.com/Gomez12/wiki/internal/core/proxy"
+	"github.com/Gomez12/wiki/internal/core/tree"
+	"github.com/Gomez12/wiki/pkg/leafwiki"
 )
 
+// shutdownTimeout is how long running requests and indexing jobs get to finish on shutdown
+const shutdownTimeout = 30 * time.Second
+
 func printUsage() {
 	fmt.Println(`LeafWiki – lightweight selfhosted wiki 🌿
 
 	Usage:
 	leafwiki [--host <HOST>] [--port <PORT>] [--data-dir <DIR>] [--admin-password <PASSWORD>]
-	leafwiki reset-admin-password
+	leafwiki [--data-dir <DIR>] --spaces <FILE>
+	leafwiki [--data-dir <DIR>] <command> [<args>]
 	leafwiki --help
 
+	Commands (run them only while the server is stopped):
+	reset-admin-password                  Generate a new password for the admin user
+	user add --username <NAME> --email <EMAIL> --password <PASSWORD> [--role admin|editor]
+	                                      Create a user (default role: editor)
+	reindex                               Rebuild the search index
+	export [--html] <DIR>                 Write all pages as Markdown files to an empty directory,
+	                                      or with --html as static HTML site
+	import [--parent <PATH>] <DIR>        Import a folder of Markdown files, e.g. an export
+	backup <FILE>                         Write the data directory to a tar.gz archive
+	migrate-layout <flat|folder>          Move the page files to another layout, keeping their history
+	seed [--pages <N>] [--seed <N>]       Add a sample wiki to a new data directory, with --pages also
+	                                      N generated pages for benchmarks
+
 	Options:
 	--host             Host/IP address to bind the server to (default: 0.0.0.0)
 	--port             Port to run the server on (default: 8080)
 	--data-dir         Path to data directory (default: ./data)
-	--admin-password   Initial admin password (used only if no admin exists)
+	--admin-password   Initial admin password (used only if no admin exists) (default: "", created with POST /api/setup)
 	--jwt-secret       Secret for signing auth tokens (JWT) (required)
 	--public-access    Allow public access to the wiki only with read access (default: false)
+	--search-partitions  Split the search index into one partition per top-level page (default: false)
+	--layout           Page files of a new data directory: flat (slug.md) or folder (slug/index.md) (default: flat)
+	--page-folder-assets  Store new assets in an assets folder next to their page (default: false)
+	--webdav           Serve the Markdown files at /webdav for mounting as network drive (default: false)
+	--case-insensitive-routes  Resolve routes which differ from a page slug only in case (default: false)
+	--follow-symlinks  Index and track the history of symlinked directories in the data directory (default: false)
+	--watch-poll-interval  Poll the data directory for changes instead of using file system events, e.g. 10s
+	                   (default: "", events; polling is used if events are not available)
+	--obsidian         Serve the pages as Obsidian vault with wikilinks, embeds and aliases (default: false)
+	--git-remote       Sync the content with this git remote (URL or path) (default: "", disabled)
+	--git-branch       Branch pulled from and pushed to (default: main)
+	--git-sync-interval  Time between two syncs, e.g. 5m or 1h (default: 5m)
+	--git-conflict-strategy  Conflict handling: theirs, ours or manual (default: manual)
+	--link-check-interval  Check external links periodically, e.g. 24h (default: "", only on demand)
+	--review-reminder-interval  Remind page owners of due reviews periodically, e.g. 24h (default: "", disabled)
+	--search-alert-interval  Check subscribed saved searches periodically, e.g. 1h (default: "", disabled)
+	--integrity-check-interval  Check the search database periodically besides at startup, e.g. 24h (default: "", only at startup)
+	--smtp-host        Mail server for review reminders (default: "", no emails)
+	--smtp-port        Port of the mail server (default: 587)
+	--smtp-username    Username of the mail server (default: "", no auth)
+	--smtp-password    Password of the mail server (default: "")
+	--smtp-from        Sender address of the emails (default: "")
+	--spellcheck-dir   Directory with hunspell dictionaries, e.g. en_US.aff and en_US.dic (default: "", disabled)
+	--import-dir       Directory whose folders admins can import (default: "", only uploaded archives)
+	--spaces           Host several wikis from a YAML file, each in <data-dir>/<name> (default: "", one wiki)
+	--log-level        Log level: debug, info, warn or error (default: info)
+	--log-format       Log format: text or json (default: text)
+	--content-security-policy  Content-Security-Policy header, {nonce} is replaced per request (default: built-in policy, "off" disables)
+	--hsts             Strict-Transport-Security header of HTTPS requests (default: max-age=31536000, "off" disables)
+	--referrer-policy  Referrer-Policy header (default: strict-origin-when-cross-origin, "off" disables)
+	--tls-domains      Serve HTTPS with certificates from Let's Encrypt for these comma separated domains (default: "", HTTP only)
+	--tls-email        Contact address for the ACME account (default: "")
+	--acme-directory   ACME directory URL, e.g. of the Let's Encrypt staging environment (default: Let's Encrypt)
+	--tls-cert         Certificate file to serve HTTPS with an own certificate (default: "", HTTP only)
+	--tls-key          Private key file of --tls-cert (default: "")
+	--https-port       Port of HTTPS; --port then redirects to it and answers ACME challenges (default: 443)
+	--socket           Listen on this Unix domain socket instead of host and port (default: "")
+	--base-path        Serve the wiki under this path behind a reverse proxy, e.g. /wiki (default: "", the root)
+	--trusted-proxies  Comma separated IPs and CIDR ranges of proxies whose X-Forwarded headers are trusted
+	                   (default: loopback and private networks)
+	--pprof            Serve the profiles of the runtime at /debug/pprof to admins (default: false)
+	--config           Read the options from a YAML or TOML file, flags and env variables take precedence
+	                   (default: ""); SIGHUP reloads its log level and settings
 	--inject-code-in-header  Raw HTML/JS code injected into <head> tag (e.g., analytics, custom CSS) (default: "")
 	                         WARNING: Use only with trusted code to avoid XSS vulnerabilities. No sanitization is performed.
 	                         
@@ -37,6 +109,46 @@ func printUsage() {
 	LEAFWIKI_ADMIN_PASSWORD
 	LEAFWIKI_PUBLIC_ACCESS
 	LEAFWIKI_INJECT_CODE_IN_HEADER
+	LEAFWIKI_SEARCH_PARTITIONS
+	LEAFWIKI_LAYOUT
+	LEAFWIKI_PAGE_FOLDER_ASSETS
+	LEAFWIKI_WEBDAV
+	LEAFWIKI_CASE_INSENSITIVE_ROUTES
+	LEAFWIKI_FOLLOW_SYMLINKS
+	LEAFWIKI_WATCH_POLL_INTERVAL
+	LEAFWIKI_OBSIDIAN
+	LEAFWIKI_GIT_REMOTE
+	LEAFWIKI_GIT_BRANCH
+	LEAFWIKI_GIT_SYNC_INTERVAL
+	LEAFWIKI_GIT_CONFLICT_STRATEGY
+	LEAFWIKI_LINK_CHECK_INTERVAL
+	LEAFWIKI_REVIEW_REMINDER_INTERVAL
+	LEAFWIKI_SEARCH_ALERT_INTERVAL
+	LEAFWIKI_INTEGRITY_CHECK_INTERVAL
+	LEAFWIKI_SMTP_HOST
+	LEAFWIKI_SMTP_PORT
+	LEAFWIKI_SMTP_USERNAME
+	LEAFWIKI_SMTP_PASSWORD
+	LEAFWIKI_SMTP_FROM
+	LEAFWIKI_SPELLCHECK_DIR
+	LEAFWIKI_IMPORT_DIR
+	LEAFWIKI_SPACES
+	LEAFWIKI_LOG_LEVEL
+	LEAFWIKI_LOG_FORMAT
+	LEAFWIKI_CONTENT_SECURITY_POLICY
+	LEAFWIKI_HSTS
+	LEAFWIKI_REFERRER_POLICY
+	LEAFWIKI_TLS_DOMAINS
+	LEAFWIKI_TLS_EMAIL
+	LEAFWIKI_ACME_DIRECTORY
+	LEAFWIKI_TLS_CERT
+	LEAFWIKI_TLS_KEY
+	LEAFWIKI_HTTPS_PORT
+	LEAFWIKI_SOCKET
+	LEAFWIKI_BASE_PATH
+	LEAFWIKI_TRUSTED_PROXIES
+	LEAFWIKI_PPROF
+	LEAFWIKI_CONFIG
 	`)
 }
 
@@ -46,75 +158,387 @@ func main() {
 	hostFlag := flag.String("host", "", "host/IP address to bind the server to (e.g. 127.0.0.1 or 0.0.0.0)")
 	portFlag := flag.String("port", "", "port to run the server on")
 	dataDirFlag := flag.String("data-dir", "", "path to data directory")
-	adminPasswordFlag := flag.String("admin-password", "", "initial admin password")
+	adminPasswordFlag := flag.String("admin-password", "", "initial admin password (default: created with POST /api/setup)")
 	jwtSecretFlag := flag.String("jwt-secret", "", "JWT secret for authentication")
-	publicAccessFlag := flag.String("public-access", "false", "allow public access to the wiki with read access (default: false)")
+	publicAccessFlag := flag.String("public-access", "", "allow public access to the wiki with read access (default: false)")
 	injectCodeInHeaderFlag := flag.String("inject-code-in-header", "", "raw string injected into <head> (default: \"\")")
+	searchPartitionsFlag := flag.String("search-partitions", "", "split the search index into one partition per top-level page (default: false)")
+	layoutFlag := flag.String("layout", "", "page files of a new data directory: flat or folder (default: flat)")
+	pageFolderAssetsFlag := flag.String("page-folder-assets", "", "store new assets next to their page (default: false)")
+	webdavFlag := flag.String("webdav", "", "serve the Markdown files at /webdav (default: false)")
+	followSymlinksFlag := flag.String("follow-symlinks", "", "index and track symlinked directories in the data directory (default: false)")
+	watchPollIntervalFlag := flag.String("watch-poll-interval", "", "poll the data directory for changes in this interval (default: file system events)")
+	obsidianFlag := flag.String("obsidian", "", "serve the pages as Obsidian vault (default: false)")
+	caseInsensitiveRoutesFlag := flag.String("case-insensitive-routes", "", "resolve routes which differ from a slug only in case (default: false)")
+	gitRemoteFlag := flag.String("git-remote", "", "sync the content with this git remote (default: disabled)")
+	gitBranchFlag := flag.String("git-branch", "", "git branch to sync (default: main)")
+	gitSyncIntervalFlag := flag.String("git-sync-interval", "", "time between two git syncs (default: 5m)")
+	gitConflictStrategyFlag := flag.String("git-conflict-strategy", "", "git conflict handling: theirs, ours or manual (default: manual)")
+	linkCheckIntervalFlag := flag.String("link-check-interval", "", "check external links periodically (default: only on demand)")
+	reviewReminderIntervalFlag := flag.String("review-reminder-interval", "", "remind page owners of due reviews periodically (default: disabled)")
+	searchAlertIntervalFlag := flag.String("search-alert-interval", "", "check subscribed saved searches periodically (default: disabled)")
+	integrityCheckIntervalFlag := flag.String("integrity-check-interval", "", "check the search database periodically (default: only at startup)")
+	smtpHostFlag := flag.String("smtp-host", "", "mail server for review reminders (default: no emails)")
+	smtpPortFlag := flag.String("smtp-port", "", "port of the mail server (default: 587)")
+	smtpUsernameFlag := flag.String("smtp-username", "", "username of the mail server (default: no auth)")
+	smtpPasswordFlag := flag.String("smtp-password", "", "password of the mail server")
+	smtpFromFlag := flag.String("smtp-from", "", "sender address of the emails")
+	spellcheckDirFlag := flag.String("spellcheck-dir", "", "directory with hunspell dictionaries (default: disabled)")
+	importDirFlag := flag.String("import-dir", "", "directory whose folders admins can import (default: only uploaded archives)")
+	spacesFlag := flag.String("spaces", "", "host several wikis configured in this YAML file (default: one wiki)")
+	logLevelFlag := flag.String("log-level", "", "log level: debug, info, warn or error (default: info)")
+	logFormatFlag := flag.String("log-format", "", "log format: text or json (default: text)")
+	cspFlag := flag.String("content-security-policy", "", "Content-Security-Policy header, \"off\" disables it (default: built-in policy)")
+	hstsFlag := flag.String("hsts", "", "Strict-Transport-Security header of HTTPS requests, \"off\" disables it (default: max-age=31536000)")
+	referrerPolicyFlag := flag.String("referrer-policy", "", "Referrer-Policy header, \"off\" disables it (default: strict-origin-when-cross-origin)")
+	tlsDomainsFlag := flag.String("tls-domains", "", "serve HTTPS with Let's Encrypt certificates for these comma separated domains (default: HTTP only)")
+	tlsEmailFlag := flag.String("tls-email", "", "contact address for the ACME account")
+	acmeDirectoryFlag := flag.String("acme-directory", "", "ACME directory URL (default: Let's Encrypt)")
+	tlsCertFlag := flag.String("tls-cert", "", "certificate file to serve HTTPS with (default: HTTP only)")
+	tlsKeyFlag := flag.String("tls-key", "", "private key file of --tls-cert")
+	httpsPortFlag := flag.String("https-port", "", "port of HTTPS (default: 443)")
+	socketFlag := flag.String("socket", "", "listen on this Unix domain socket instead of host and port")
+	basePathFlag := flag.String("base-path", "", "serve the wiki under this path, e.g. /wiki (default: the root)")
+	trustedProxiesFlag := flag.String("trusted-proxies", "", "IPs and CIDR ranges of trusted proxies (default: loopback and private networks)")
+	pprofFlag := flag.String("pprof", "", "serve the profiles of the runtime at /debug/pprof to admins (default: false)")
+	configFlag := flag.String("config", "", "read the options from this YAML or TOML file")
 	flag.Parse()
 
-	port := getOrFallback(*portFlag, "LEAFWIKI_PORT", "8080")
-	host := getOrFallback(*hostFlag, "LEAFWIKI_HOST", "0.0.0.0")
-	dataDir := getOrFallback(*dataDirFlag, "LEAFWIKI_DATA_DIR", "./data")
-	adminPassword := getOrFallback(*adminPasswordFlag, "LEAFWIKI_ADMIN_PASSWORD", "admin")
-	jwtSecret := getOrFallback(*jwtSecretFlag, "LEAFWIKI_JWT_SECRET", "")
-	publicAccess := getOrFallback(*publicAccessFlag, "LEAFWIKI_PUBLIC_ACCESS", "false")
-	injectCodeInHeader := getOrFallback(*injectCodeInHeaderFlag, "LEAFWIKI_INJECT_CODE_IN_HEADER", "")
+	// flags and environment variables take precedence over the config file
+	var cfg *config.File
+	if configFile := getOrFallback(*configFlag, "LEAFWIKI_CONFIG", ""); configFile != "" {
+		var err error
+		if cfg, err = config.Load(configFile, configOptions()); err != nil {
+			fmt.Fprintf(os.Stderr, "Invalid config file: %v\n", err)
+			os.Exit(1)
+		}
+	}
+
+	port := getOrFallback(*portFlag, "LEAFWIKI_PORT", cfg.Get("port", "8080"))
+	host := getOrFallback(*hostFlag, "LEAFWIKI_HOST", cfg.Get("host", "0.0.0.0"))
+	dataDir := getOrFallback(*dataDirFlag, "LEAFWIKI_DATA_DIR", cfg.Get("data-dir", "./data"))
+	adminPassword := getOrFallback(*adminPasswordFlag, "LEAFWIKI_ADMIN_PASSWORD", cfg.Get("admin-password", ""))
+	jwtSecret := getOrFallback(*jwtSecretFlag, "LEAFWIKI_JWT_SECRET", cfg.Get("jwt-secret", ""))
+	publicAccess := getOrFallback(*publicAccessFlag, "LEAFWIKI_PUBLIC_ACCESS", cfg.Get("public-access", "false"))
+	injectCodeInHeader := getOrFallback(*injectCodeInHeaderFlag, "LEAFWIKI_INJECT_CODE_IN_HEADER", cfg.Get("inject-code-in-header", ""))
+	searchPartitions := getOrFallback(*searchPartitionsFlag, "LEAFWIKI_SEARCH_PARTITIONS", cfg.Get("search-partitions", "false"))
+	layout := getOrFallback(*layoutFlag, "LEAFWIKI_LAYOUT", cfg.Get("layout", ""))
+	pageFolderAssets := getOrFallback(*pageFolderAssetsFlag, "LEAFWIKI_PAGE_FOLDER_ASSETS", cfg.Get("page-folder-assets", "false"))
+	webdav := getOrFallback(*webdavFlag, "LEAFWIKI_WEBDAV", cfg.Get("webdav", "false"))
+	followSymlinks := getOrFallback(*followSymlinksFlag, "LEAFWIKI_FOLLOW_SYMLINKS", cfg.Get("follow-symlinks", "false"))
+	watchPollInterval := getOrFallback(*watchPollIntervalFlag, "LEAFWIKI_WATCH_POLL_INTERVAL", cfg.Get("watch-poll-interval", ""))
+	obsidian := getOrFallback(*obsidianFlag, "LEAFWIKI_OBSIDIAN", cfg.Get("obsidian", "false"))
+	caseInsensitiveRoutes := getOrFallback(*caseInsensitiveRoutesFlag, "LEAFWIKI_CASE_INSENSITIVE_ROUTES", cfg.Get("case-insensitive-routes", "false"))
+	gitRemote := getOrFallback(*gitRemoteFlag, "LEAFWIKI_GIT_REMOTE", cfg.Get("git-remote", ""))
+	gitBranch := getOrFallback(*gitBranchFlag, "LEAFWIKI_GIT_BRANCH", cfg.Get("git-branch", "main"))
+	gitSyncInterval := getOrFallback(*gitSyncIntervalFlag, "LEAFWIKI_GIT_SYNC_INTERVAL", cfg.Get("git-sync-interval", "5m"))
+	gitConflictStrategy := getOrFallback(*gitConflictStrategyFlag, "LEAFWIKI_GIT_CONFLICT_STRATEGY", cfg.Get("git-conflict-strategy", "manual"))
+	linkCheckInterval := getOrFallback(*linkCheckIntervalFlag, "LEAFWIKI_LINK_CHECK_INTERVAL", cfg.Get("link-check-interval", ""))
+	reviewReminderInterval := getOrFallback(*reviewReminderIntervalFlag, "LEAFWIKI_REVIEW_REMINDER_INTERVAL", cfg.Get("review-reminder-interval", ""))
+	searchAlertInterval := getOrFallback(*searchAlertIntervalFlag, "LEAFWIKI_SEARCH_ALERT_INTERVAL", cfg.Get("search-alert-interval", ""))
+	integrityCheckInterval := getOrFallback(*integrityCheckIntervalFlag, "LEAFWIKI_INTEGRITY_CHECK_INTERVAL", cfg.Get("integrity-check-interval", ""))
+	smtpHost := getOrFallback(*smtpHostFlag, "LEAFWIKI_SMTP_HOST", cfg.Get("smtp-host", ""))
+	smtpPort := getOrFallback(*smtpPortFlag, "LEAFWIKI_SMTP_PORT", cfg.Get("smtp-port", "587"))
+	smtpUsername := getOrFallback(*smtpUsernameFlag, "LEAFWIKI_SMTP_USERNAME", cfg.Get("smtp-username", ""))
+	smtpPassword := getOrFallback(*smtpPasswordFlag, "LEAFWIKI_SMTP_PASSWORD", cfg.Get("smtp-password", ""))
+	smtpFrom := getOrFallback(*smtpFromFlag, "LEAFWIKI_SMTP_FROM", cfg.Get("smtp-from", ""))
+	spellcheckDir := getOrFallback(*spellcheckDirFlag, "LEAFWIKI_SPELLCHECK_DIR", cfg.Get("spellcheck-dir", ""))
+	importDir := getOrFallback(*importDirFlag, "LEAFWIKI_IMPORT_DIR", cfg.Get("import-dir", ""))
+	spacesFile := getOrFallback(*spacesFlag, "LEAFWIKI_SPACES", cfg.Get("spaces", ""))
+	logLevel := getOrFallback(*logLevelFlag, "LEAFWIKI_LOG_LEVEL", cfg.Get("log-level", "info"))
+	logFormat := getOrFallback(*logFormatFlag, "LEAFWIKI_LOG_FORMAT", cfg.Get("log-format", logging.FormatText))
+	csp := getOrFallback(*cspFlag, "LEAFWIKI_CONTENT_SECURITY_POLICY", cfg.Get("content-security-policy", ""))
+	hsts := getOrFallback(*hstsFlag, "LEAFWIKI_HSTS", cfg.Get("hsts", ""))
+	referrerPolicy := getOrFallback(*referrerPolicyFlag, "LEAFWIKI_REFERRER_POLICY", cfg.Get("referrer-policy", ""))
+	tlsConf := tlsConfig{
+		domains:      parseDomains(getOrFallback(*tlsDomainsFlag, "LEAFWIKI_TLS_DOMAINS", cfg.Get("tls-domains", ""))),
+		email:        getOrFallback(*tlsEmailFlag, "LEAFWIKI_TLS_EMAIL", cfg.Get("tls-email", "")),
+		directoryURL: getOrFallback(*acmeDirectoryFlag, "LEAFWIKI_ACME_DIRECTORY", cfg.Get("acme-directory", "")),
+		cacheDir:     certCacheDir(dataDir),
+		certFile:     getOrFallback(*tlsCertFlag, "LEAFWIKI_TLS_CERT", cfg.Get("tls-cert", "")),
+		keyFile:      getOrFallback(*tlsKeyFlag, "LEAFWIKI_TLS_KEY", cfg.Get("tls-key", "")),
+		httpsPort:    getOrFallback(*httpsPortFlag, "LEAFWIKI_HTTPS_PORT", cfg.Get("https-port", "443")),
+	}
+	socket := getOrFallback(*socketFlag, "LEAFWIKI_SOCKET", cfg.Get("socket", ""))
+	basePath := getOrFallback(*basePathFlag, "LEAFWIKI_BASE_PATH", cfg.Get("base-path", ""))
+	trustedProxies := getOrFallback(*trustedProxiesFlag, "LEAFWIKI_TRUSTED_PROXIES", cfg.Get("trusted-proxies", ""))
+	profiling := getOrFallback(*pprofFlag, "LEAFWIKI_PPROF", cfg.Get("pprof", "false"))
+
+	if err := logging.Setup(os.Stderr, logLevel, logFormat); err != nil {
+		fmt.Fprintf(os.Stderr, "Invalid logging configuration: %v\n", err)
+		os.Exit(1)
+	}
 
 	// Check if data directory exists
 	if _, err := os.Stat(dataDir); os.IsNotExist(err) {
 		if err := os.MkdirAll(dataDir, 0755); err != nil {
-			log.Fatalf("Failed to create data directory: %v", err)
+			fatal("Failed to create data directory", err)
 		}
 	}
 
 	args := flag.Args()
 	if len(args) > 0 {
 		switch args[0] {
-		case "reset-admin-password":
-			// Note: No JWT secret needed for this command
-			w, err := wiki.NewWiki(dataDir, adminPassword, "", false)
-			if err != nil {
-				log.Fatalf("Failed to initialize Wiki: %v", err)
-			}
-			defer w.Close()
-			user, err := w.ResetAdminUserPassword()
-			if err != nil {
-				log.Fatalf("Password reset failed: %v", err)
-			}
-
-			fmt.Println("Admin password reset successfully.")
-			fmt.Printf("New password for user %s: %s\n", user.Username, user.Password)
-			return
 		case "--help", "-h", "help":
 			printUsage()
 			return
-		default:
-			fmt.Printf("Unknown command: %s\n\n", args[0])
-			printUsage()
-			return
 		}
+		env := commandEnv{dataDir: dataDir, adminPassword: adminPassword}
+		if err := runCommand(env, args[0], args[1:]); err != nil {
+			if errors.Is(err, errUsage) {
+				fmt.Fprintf(os.Stderr, "%v\n\n", err)
+				printUsage()
+				os.Exit(2)
+			}
+			fmt.Fprintf(os.Stderr, "%s failed: %v\n", args[0], describeError(err))
+			os.Exit(1)
+		}
+		return
 	}
 
 	if jwtSecret == "" {
-		log.Fatal("JWT secret is required. Set it using --jwt-secret or LEAFWIKI_JWT_SECRET environment variable.")
+		slog.Error("JWT secret is required. Set it using --jwt-secret or LEAFWIKI_JWT_SECRET environment variable.")
+		os.Exit(1)
 	}
 
-	// needs to get injected by environment variable later
-	w, err := wiki.NewWiki(dataDir, adminPassword, jwtSecret, true)
+	opts := []leafwiki.Option{
+		leafwiki.WithAdminPassword(adminPassword),
+		leafwiki.WithPublicAccess(publicAccess == "true"),
+		leafwiki.WithInjectCodeInHeader(injectCodeInHeader),
+		leafwiki.WithSearchPartitions(searchPartitions == "true"),
+		leafwiki.WithPageFolderAssets(pageFolderAssets == "true"),
+		leafwiki.WithWebDAV(webdav == "true"),
+		leafwiki.WithProfiling(profiling == "true"),
+		leafwiki.WithCaseInsensitiveRoutes(caseInsensitiveRoutes == "true"),
+		leafwiki.WithFollowSymlinks(followSymlinks == "true"),
+		leafwiki.WithObsidian(obsidian == "true"),
+		leafwiki.WithSecurityHeaders(leafwiki.SecurityHeadersConfig{
+			ContentSecurityPolicy: csp,
+			HSTS:                  hsts,
+			ReferrerPolicy:        referrerPolicy,
+		}),
+	}
+	var reloader *configReloader
+	if cfg != nil {
+		reloader = &configReloader{
+			file:      cfg,
+			logLevel:  getOrFallback(*logLevelFlag, "LEAFWIKI_LOG_LEVEL", ""),
+			logFormat: getOrFallback(*logFormatFlag, "LEAFWIKI_LOG_FORMAT", ""),
+		}
+		opts = append(opts, leafwiki.WithConfigReload(reloader.reload))
+	}
+	if basePath != "" {
+		normalized, err := proxy.NormalizeBasePath(basePath)
+		if err != nil {
+			fatal("Invalid base path", err)
+		}
+		opts = append(opts, leafwiki.WithBasePath(normalized))
+	}
+	if trustedProxies != "" {
+		proxies := strings.Split(trustedProxies, ",")
+		if _, err := proxy.ParseTrusted(proxies); err != nil {
+			fatal("Invalid trusted proxies", err)
+		}
+		opts = append(opts, leafwiki.WithTrustedProxies(proxies))
+	}
+	if layout != "" {
+		pageLayout, err := tree.ParseLayout(layout)
+		if err != nil {
+			fatal("Invalid layout", fmt.Errorf("%q: %w", layout, err))
+		}
+		opts = append(opts, leafwiki.WithLayout(pageLayout))
+	}
+	if linkCheckInterval != "" {
+		interval, err := time.ParseDuration(linkCheckInterval)
+		if err != nil || interval <= 0 {
+			fatal("Invalid link check interval", fmt.Errorf("%q is not a positive duration", linkCheckInterval))
+		}
+		opts = append(opts, leafwiki.WithLinkCheck(leafwiki.LinkCheckConfig{Interval: interval}))
+	}
+	if reviewReminderInterval != "" {
+		interval, err := time.ParseDuration(reviewReminderInterval)
+		if err != nil || interval <= 0 {
+			fatal("Invalid review reminder interval", fmt.Errorf("%q is not a positive duration", reviewReminderInterval))
+		}
+		opts = append(opts, leafwiki.WithReviewReminders(interval))
+	}
+	if searchAlertInterval != "" {
+		interval, err := time.ParseDuration(searchAlertInterval)
+		if err != nil || interval <= 0 {
+			fatal("Invalid search alert interval", fmt.Errorf("%q is not a positive duration", searchAlertInterval))
+		}
+		opts = append(opts, leafwiki.WithSearchAlerts(interval))
+	}
+	if watchPollInterval != "" {
+		interval, err := time.ParseDuration(watchPollInterval)
+		if err != nil || interval <= 0 {
+			fatal("Invalid watch poll interval", fmt.Errorf("%q is not a positive duration", watchPollInterval))
+		}
+		opts = append(opts, leafwiki.WithWatchPolling(interval))
+	}
+	if integrityCheckInterval != "" {
+		interval, err := time.ParseDuration(integrityCheckInterval)
+		if err != nil || interval <= 0 {
+			fatal("Invalid integrity check interval", fmt.Errorf("%q is not a positive duration", integrityCheckInterval))
+		}
+		opts = append(opts, leafwiki.WithIntegrityCheck(interval))
+	}
+	if smtpHost != "" {
+		port, err := strconv.Atoi(smtpPort)
+		if err != nil || port <= 0 {
+			fatal("Invalid SMTP port", fmt.Errorf("%q is not a port", smtpPort))
+		}
+		if smtpFrom == "" {
+			fatal("Invalid SMTP configuration", errors.New("a sender address (--smtp-from) is required"))
+		}
+		opts = append(opts, leafwiki.WithSMTP(leafwiki.SMTPConfig{
+			Host:     smtpHost,
+			Port:     port,
+			Username: smtpUsername,
+			Password: smtpPassword,
+			From:     smtpFrom,
+		}))
+	}
+	if spellcheckDir != "" {
+		if info, err := os.Stat(spellcheckDir); err != nil || !info.IsDir() {
+			fatal("Invalid spellcheck directory", fmt.Errorf("%q is not a directory", spellcheckDir))
+		}
+		opts = append(opts, leafwiki.WithSpellcheck(spellcheckDir))
+	}
+	if importDir != "" {
+		if info, err := os.Stat(importDir); err != nil || !info.IsDir() {
+			fatal("Invalid import directory", fmt.Errorf("%q is not a directory", importDir))
+		}
+		opts = append(opts, leafwiki.WithImportRoot(importDir))
+	}
+	if err := tlsConf.validate(); err != nil {
+		fatal("Invalid TLS configuration", err)
+	}
+	// a socket passed by systemd takes precedence, then --socket
+	listener, err := systemdListener()
 	if err != nil {
-		log.Fatalf("Failed to initialize Wiki: %v", err)
+		fatal("Failed to use the systemd socket", err)
+	}
+	if listener == nil && socket != "" {
+		if listener, err = unixListener(socket); err != nil {
+			fatal("Failed to listen on socket", err)
+		}
+	}
+	if listener != nil && tlsConf.enabled() {
+		fatal("Invalid TLS configuration", errors.New("TLS is not supported with sockets, the proxy in front terminates it"))
+	}
+	if gitRemote != "" && spacesFile != "" {
+		fatal("Invalid configuration", errors.New("git sync is not supported with spaces"))
+	}
+	if gitRemote != "" {
+		interval, err := time.ParseDuration(gitSyncInterval)
+		if err != nil || interval <= 0 {
+			fatal("Invalid git sync interval", fmt.Errorf("%q is not a positive duration", gitSyncInterval))
+		}
+		opts = append(opts, leafwiki.WithGitSync(leafwiki.GitSyncConfig{
+			Remote:   gitRemote,
+			Branch:   gitBranch,
+			Interval: interval,
+			Strategy: gitsync.Strategy(gitConflictStrategy),
+		}))
+	}
+
+	var handler http.Handler
+	var shutdownWiki func(ctx context.Context) error
+	var applySettings func(patch []byte) error
+	if spacesFile != "" {
+		spaces, err := loadSpaces(spacesFile, dataDir, jwtSecret, opts)
+		if err != nil {
+			fatal("Failed to initialize spaces", err)
+		}
+		slog.Info("hosting spaces", "spaces", spaces.Names())
+		handler, shutdownWiki = spaces, spaces.Shutdown
+		for _, name := range spaces.Names() {
+			if srv, ok := spaces.Get(name); ok {
+				warnIfSetupRequired(srv, "space", name)
+			}
+		}
+		applySettings = func(patch []byte) error {
+			for _, name := range spaces.Names() {
+				if srv, ok := spaces.Get(name); ok {
+					if err := srv.ApplySettings(patch); err != nil {
+						return fmt.Errorf("space %s: %w", name, err)
+					}
+				}
+			}
+			return nil
+		}
+	} else {
+		srv, err := leafwiki.New(dataDir, jwtSecret, opts...)
+		if err != nil {
+			fatal("Failed to initialize Wiki", err)
+		}
+		handler, shutdownWiki, applySettings = srv.Handler(), srv.Shutdown, srv.ApplySettings
+		warnIfSetupRequired(srv)
 	}
-	defer w.Close()
+	if reloader != nil {
+		// the settings of the config file replace those changed in the admin UI
+		if cfg.Settings != nil {
+			if err := applySettings(cfg.Settings); err != nil {
+				_ = shutdownWiki(context.Background())
+				fatal("Invalid settings in config file", fmt.Errorf("%s: %w", cfg.Path, describeError(err)))
+			}
+		}
+		reloader.apply = applySettings
+		go reloadOnSIGHUP(reloader)
+	}
+
+	servers := newServers(host, port, listener, handler, tlsConf)
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
 
-	router := http.NewRouter(w, publicAccess == "true", injectCodeInHeader)
+	// Start servers
+	serveErr := make(chan error, len(servers))
+	for _, s := range servers {
+		go func() {
+			slog.Info("listening and serving "+s.name, "addr", s.Addr)
+			serveErr <- s.serve()
+		}()
+	}
+
+	select {
+	case err := <-serveErr:
+		_ = shutdownWiki(context.Background())
+		fatal("Failed to start server", err)
+	case <-ctx.Done():
+	}
+	// a second signal terminates immediately
+	stop()
 
-	// Start server - combine host and port
-	listenAddr := host + ":" + port
+	slog.Info("shutting down", "timeout", shutdownTimeout)
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), shutdownTimeout)
+	defer cancel()
 
-	// Start server
-	if err := router.Run(listenAddr); err != nil {
-		log.Fatalf("Failed to start server: %v", err)
+	// stop accepting requests first, so no request uses the closed databases
+	for _, s := range servers {
+		if err := s.Shutdown(shutdownCtx); err != nil {
+			slog.Error("could not shut down "+s.name+" server", "error", err)
+		}
+	}
+	if err := shutdownWiki(shutdownCtx); err != nil {
+		slog.Error("could not shut down wiki", "error", err)
+		os.Exit(1)
 	}
+	slog.Info("shutdown complete")
+}
+
+// warnIfSetupRequired points out that the wiki has no admin yet, anyone reaching it could claim it
+func warnIfSetupRequired(srv *leafwiki.Server, args ...any) {
+	if required, err := srv.SetupRequired(); err == nil && required {
+		slog.Warn("no users exist yet, create the first admin with POST /api/setup or set --admin-password", args...)
+	}
+}
+
+// fatal logs the error and exits
+func fatal(msg string, err error) {
+	slog.Error(msg, "error", err)
+	os.Exit(1)
 }
 
 func getOrFallback(flagVal, envVar, def string) string {
@@ -126,3 +550,14 @@ func getOrFallback(flagVal, envVar, def string) string {
 	}
 	return def
 }
+
+// reloadOnSIGHUP reloads the config file whenever the process receives SIGHUP
+func reloadOnSIGHUP(reloader *configReloader) {
+	hup := make(chan os.Signal, 1)
+	signal.Notify(hup, syscall.SIGHUP)
+	for range hup {
+		if err := reloader.reload(); err != nil {
+			slog.Error("could not reload config file, keeping the current configuration", "error", describeError(err))
+		}
+	}
+}
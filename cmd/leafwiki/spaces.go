@@ -0,0 +1,86 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/Gomez12/wiki/pkg/leafwiki"
+	"github.com/goccy/go-yaml"
+)
+
+// spacesFile is the configuration of --spaces, e.g.
+//
+//	default: docs
+//	spaces:
+//	  - name: docs
+//	    hosts: [docs.example.com]
+//	    publicAccess: true
+//	  - name: team
+//	    dataDir: /srv/team
+//	    hosts: [team.example.com]
+type spacesFile struct {
+	Default string        `yaml:"default"`
+	Spaces  []spaceConfig `yaml:"spaces"`
+}
+
+type spaceConfig struct {
+	Name string `yaml:"name"`
+	// DataDir defaults to <data-dir>/<name>
+	DataDir       string   `yaml:"dataDir"`
+	Hosts         []string `yaml:"hosts"`
+	PublicAccess  *bool    `yaml:"publicAccess"`
+	AdminPassword string   `yaml:"adminPassword"`
+}
+
+// loadSpaces opens every space of the file. The options apply to all spaces, the settings of
+// a space override them.
+func loadSpaces(file, dataDir, jwtSecret string, opts []leafwiki.Option) (*leafwiki.Spaces, error) {
+	content, err := os.ReadFile(file)
+	if err != nil {
+		return nil, err
+	}
+	var config spacesFile
+	if err := yaml.Unmarshal(content, &config); err != nil {
+		return nil, fmt.Errorf("invalid spaces file %s: %w", file, err)
+	}
+	if len(config.Spaces) == 0 {
+		return nil, fmt.Errorf("spaces file %s defines no spaces", file)
+	}
+
+	spaces, err := leafwiki.NewSpaces(jwtSecret)
+	if err != nil {
+		return nil, err
+	}
+	for _, sc := range config.Spaces {
+		spaceDataDir := sc.DataDir
+		if spaceDataDir == "" {
+			spaceDataDir = filepath.Join(dataDir, sc.Name)
+		}
+		if err := os.MkdirAll(spaceDataDir, 0755); err != nil {
+			return nil, err
+		}
+		spaceOpts := append([]leafwiki.Option{}, opts...)
+		if sc.PublicAccess != nil {
+			spaceOpts = append(spaceOpts, leafwiki.WithPublicAccess(*sc.PublicAccess))
+		}
+		if sc.AdminPassword != "" {
+			spaceOpts = append(spaceOpts, leafwiki.WithAdminPassword(sc.AdminPassword))
+		}
+		if _, err := spaces.Add(leafwiki.SpaceConfig{
+			Name:    sc.Name,
+			DataDir: spaceDataDir,
+			Hosts:   sc.Hosts,
+			Options: spaceOpts,
+		}); err != nil {
+			_ = spaces.Shutdown(context.Background())
+			return nil, err
+		}
+	}
+	if err := spaces.SetDefault(config.Default); err != nil {
+		_ = spaces.Shutdown(context.Background())
+		return nil, err
+	}
+	return spaces, nil
+}
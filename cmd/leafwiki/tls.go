@@ -0,0 +1,138 @@
+package main
+
+import (
+	"crypto/tls"
+	"errors"
+	"net"
+	"net/http"
+	"path/filepath"
+	"strings"
+
+	"golang.org/x/crypto/acme"
+	"golang.org/x/crypto/acme/autocert"
+)
+
+// tlsConfig configures HTTPS: with domains the certificates are obtained from an ACME CA
+// like Let's Encrypt, with certFile and keyFile an own certificate is used
+type tlsConfig struct {
+	domains      []string
+	email        string
+	directoryURL string
+	cacheDir     string
+	certFile     string
+	keyFile      string
+	httpsPort    string
+}
+
+func (c tlsConfig) enabled() bool {
+	return len(c.domains) > 0 || c.certFile != ""
+}
+
+func (c tlsConfig) validate() error {
+	if len(c.domains) > 0 && c.certFile != "" {
+		return errors.New("use either --tls-domains or --tls-cert, not both")
+	}
+	if (c.certFile == "") != (c.keyFile == "") {
+		return errors.New("--tls-cert and --tls-key are required together")
+	}
+	return nil
+}
+
+// server is a listener of the wiki with the function starting it
+type server struct {
+	*http.Server
+	name  string
+	serve func() error
+}
+
+// newServers returns the HTTP server of the handler, or with TLS the HTTPS server plus an
+// HTTP server redirecting to it, which also answers the ACME HTTP challenges. A listener,
+// e.g. a Unix socket, replaces listening on host and port.
+func newServers(host, port string, listener net.Listener, handler http.Handler, config tlsConfig) []server {
+	if listener != nil {
+		if listener.Addr().Network() == "unix" {
+			handler = localRemoteAddr(handler)
+		}
+		srv := &http.Server{Addr: listener.Addr().String(), Handler: handler}
+		return []server{{Server: srv, name: "HTTP", serve: func() error { return srv.Serve(listener) }}}
+	}
+
+	httpAddr := net.JoinHostPort(host, port)
+	if !config.enabled() {
+		srv := &http.Server{Addr: httpAddr, Handler: handler}
+		return []server{{Server: srv, name: "HTTP", serve: srv.ListenAndServe}}
+	}
+
+	httpsAddr := net.JoinHostPort(host, config.httpsPort)
+	httpsServer := &http.Server{Addr: httpsAddr, Handler: handler}
+	redirect := redirectToHTTPS(config.httpsPort)
+	serveHTTPS := func() error {
+		return httpsServer.ListenAndServeTLS(config.certFile, config.keyFile)
+	}
+
+	if len(config.domains) > 0 {
+		manager := &autocert.Manager{
+			Prompt:     autocert.AcceptTOS,
+			Cache:      autocert.DirCache(config.cacheDir),
+			HostPolicy: autocert.HostWhitelist(config.domains...),
+			Email:      config.email,
+		}
+		if config.directoryURL != "" {
+			manager.Client = &acme.Client{DirectoryURL: config.directoryURL}
+		}
+		httpsServer.TLSConfig = manager.TLSConfig()
+		redirect = manager.HTTPHandler(redirect)
+		// the certificates come from the manager
+		serveHTTPS = func() error {
+			return httpsServer.ListenAndServeTLS("", "")
+		}
+	} else {
+		httpsServer.TLSConfig = &tls.Config{MinVersion: tls.VersionTLS12}
+	}
+
+	httpServer := &http.Server{Addr: httpAddr, Handler: redirect}
+	return []server{
+		{Server: httpsServer, name: "HTTPS", serve: serveHTTPS},
+		{Server: httpServer, name: "HTTP", serve: httpServer.ListenAndServe},
+	}
+}
+
+// redirectToHTTPS redirects every request to the same URL with https, keeping the method of
+// requests other than GET and HEAD
+func redirectToHTTPS(httpsPort string) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		host := r.Host
+		if h, _, err := net.SplitHostPort(host); err == nil {
+			host = h
+		}
+		if host == "" {
+			http.Error(w, "missing host", http.StatusBadRequest)
+			return
+		}
+		if httpsPort != "443" {
+			host = net.JoinHostPort(host, httpsPort)
+		}
+
+		status := http.StatusMovedPermanently
+		if r.Method != http.MethodGet && r.Method != http.MethodHead {
+			status = http.StatusPermanentRedirect
+		}
+		http.Redirect(w, r, "https://"+host+r.URL.RequestURI(), status)
+	})
+}
+
+// parseDomains splits the comma separated list of --tls-domains
+func parseDomains(list string) []string {
+	domains := []string{}
+	for _, domain := range strings.Split(list, ",") {
+		if domain = strings.TrimSpace(domain); domain != "" {
+			domains = append(domains, domain)
+		}
+	}
+	return domains
+}
+
+// certCacheDir is where the ACME certificates are kept, so restarts don't request new ones
+func certCacheDir(dataDir string) string {
+	return filepath.Join(dataDir, "certs")
+}
@@ -19,7 +19,8 @@ start_docker() {
     --name wiki-e2e-tests \
     -v e2e-tests-data:/app/data \
     wiki-e2e-tests \
-    --jwt-secret=e2e-tests-secret
+    --jwt-secret=e2e-tests-secret \
+    --admin-password=admin
 
   echo "✅ Container started on http://localhost:8085"
 }
@@ -0,0 +1,102 @@
+package access
+
+import (
+	"strings"
+	"sync"
+
+	"github.com/Gomez12/wiki/internal/core/auth"
+)
+
+// maxCacheEntries limits the size of the access cache.
+// When the limit is reached the cache is dropped and filled again on demand.
+const maxCacheEntries = 10000
+
+// Checker decides whether a user may read the subtree rooted at a page path.
+// The user is nil for anonymous (public) access.
+type Checker interface {
+	CanRead(user *auth.User, subtree string) bool
+}
+
+// AllowAll is the default checker which grants read access to everyone
+type AllowAll struct{}
+
+func (AllowAll) CanRead(user *auth.User, subtree string) bool {
+	return true
+}
+
+type cacheKey struct {
+	userID  string
+	subtree string
+}
+
+// Cache remembers the decisions of a Checker per (user, subtree).
+// It must be invalidated whenever permissions change.
+type Cache struct {
+	mu      sync.RWMutex
+	checker Checker
+	entries map[cacheKey]bool
+}
+
+func NewCache(checker Checker) *Cache {
+	if checker == nil {
+		checker = AllowAll{}
+	}
+	return &Cache{
+		checker: checker,
+		entries: map[cacheKey]bool{},
+	}
+}
+
+// AllowsAll returns true if the underlying checker grants access to everyone,
+// so callers can skip filtering entirely.
+func (c *Cache) AllowsAll() bool {
+	_, ok := c.checker.(AllowAll)
+	return ok
+}
+
+// CanRead returns the cached decision for the user and subtree and asks the checker on a miss.
+func (c *Cache) CanRead(user *auth.User, subtree string) bool {
+	key := cacheKey{subtree: normalizeSubtree(subtree)}
+	if user != nil {
+		key.userID = user.ID
+	}
+
+	c.mu.RLock()
+	allowed, ok := c.entries[key]
+	c.mu.RUnlock()
+	if ok {
+		return allowed
+	}
+
+	allowed = c.checker.CanRead(user, key.subtree)
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if len(c.entries) >= maxCacheEntries {
+		c.entries = map[cacheKey]bool{}
+	}
+	c.entries[key] = allowed
+	return allowed
+}
+
+// Invalidate drops all cached decisions, e.g. after a permission change
+func (c *Cache) Invalidate() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.entries = map[cacheKey]bool{}
+}
+
+// InvalidateUser drops the cached decisions of a single user, e.g. after a role change
+func (c *Cache) InvalidateUser(userID string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for key := range c.entries {
+		if key.userID == userID {
+			delete(c.entries, key)
+		}
+	}
+}
+
+func normalizeSubtree(subtree string) string {
+	return strings.Trim(strings.TrimSpace(subtree), "/")
+}
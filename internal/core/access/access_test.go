@@ -0,0 +1,66 @@
+package access
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/Gomez12/wiki/internal/core/auth"
+)
+
+type countingChecker struct {
+	calls int
+	deny  string
+}
+
+func (c *countingChecker) CanRead(user *auth.User, subtree string) bool {
+	c.calls++
+	return user != nil && user.HasRole(auth.RoleAdmin) || !strings.HasPrefix(subtree, c.deny)
+}
+
+func TestCache_CachesPerUserAndSubtree(t *testing.T) {
+	checker := &countingChecker{deny: "private"}
+	cache := NewCache(checker)
+
+	editor := &auth.User{ID: "u1", Role: auth.RoleEditor}
+	admin := &auth.User{ID: "u2", Role: auth.RoleAdmin}
+
+	if cache.CanRead(editor, "/private/notes") {
+		t.Fatalf("expected editor to be denied")
+	}
+	if !cache.CanRead(admin, "private/notes") {
+		t.Fatalf("expected admin to be allowed")
+	}
+	if !cache.CanRead(nil, "docs") {
+		t.Fatalf("expected anonymous user to read docs")
+	}
+
+	// Same keys again: answered from the cache
+	cache.CanRead(editor, "private/notes/")
+	cache.CanRead(admin, "private/notes")
+	if checker.calls != 3 {
+		t.Fatalf("expected 3 checker calls, got %d", checker.calls)
+	}
+
+	cache.InvalidateUser("u1")
+	cache.CanRead(editor, "private/notes")
+	cache.CanRead(admin, "private/notes")
+	if checker.calls != 4 {
+		t.Fatalf("expected only the invalidated user to be checked again, got %d calls", checker.calls)
+	}
+
+	cache.Invalidate()
+	cache.CanRead(admin, "private/notes")
+	if checker.calls != 5 {
+		t.Fatalf("expected checker to be called after invalidation, got %d calls", checker.calls)
+	}
+}
+
+func TestCache_DefaultsToAllowAll(t *testing.T) {
+	cache := NewCache(nil)
+	if !cache.AllowsAll() {
+		t.Fatalf("expected default cache to allow all")
+	}
+	if !cache.CanRead(nil, "anything") {
+		t.Fatalf("expected anonymous access to be allowed")
+	}
+}
@@ -1,19 +1,40 @@
 package assets
 
 import (
+	"errors"
 	"fmt"
 	"io"
-	"mime/multipart"
 	"os"
 	"path"
+	"regexp"
 	"sync"
 
 	"github.com/Gomez12/wiki/internal/core/tree"
 )
 
+// PageFolderLinkPrefix is the prefix of links to the assets stored next to their page.
+// The links are relative to the page folder, so a page folder is portable as plain files.
+const PageFolderLinkPrefix = "./assets/"
+
+// pageFolderName is the folder of the assets next to the index.md of a page
+const pageFolderName = "assets"
+
+// pageFolderLinkRegex matches relative links to the assets of a page in Markdown and HTML,
+// e.g. ](./assets/image.png) or src="assets/image.png"
+var pageFolderLinkRegex = regexp.MustCompile(`(\]\(\s*<?|(?:src|href)=["'])(?:\./)?assets/`)
+
+var (
+	ErrAssetNotFound    = errors.New("asset not found")
+	ErrAssetExists      = errors.New("new asset already exists")
+	ErrInvalidAssetName = errors.New("invalid asset name")
+)
+
 type AssetService struct {
-	assetsDir string
-	slugger   *tree.SlugService
+	storageDir string
+	assetsDir  string
+	slugger    *tree.SlugService
+	// pageFolders stores new assets next to their page, see SetPageFolders
+	pageFolders bool
 
 	mu sync.RWMutex
 }
@@ -30,8 +51,9 @@ func NewAssetService(storageDir string, slugger *tree.SlugService) *AssetService
 	}
 
 	return &AssetService{
-		assetsDir: assetsDir,
-		slugger:   slugger,
+		storageDir: storageDir,
+		assetsDir:  assetsDir,
+		slugger:    slugger,
 	}
 }
 
@@ -39,6 +61,90 @@ func (s *AssetService) GetAssetsDir() string {
 	return s.assetsDir
 }
 
+// SetPageFolders stores new assets in an assets folder next to the index.md of their page
+// instead of assets/<page id>. The page must be a folder, see TreeService.EnsurePageFolder.
+func (s *AssetService) SetPageFolders(enabled bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.pageFolders = enabled
+}
+
+// PageFolders reports whether new assets are stored next to their page
+func (s *AssetService) PageFolders() bool {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.pageFolders
+}
+
+// PageFolderPath returns the folder of the assets stored next to the page
+func (s *AssetService) PageFolderPath(page *tree.PageNode) string {
+	return path.Join(s.storageDir, tree.GeneratePathFromPageNode(page), pageFolderName)
+}
+
+// RewriteAssetLinks replaces the links to an asset of a page in Markdown and HTML, as
+// /assets/<page id>/<name> or relative to the page as ./assets/<name>, with newURL
+func RewriteAssetLinks(content, pageID, filename, newURL string) string {
+	re := regexp.MustCompile(`(\]\(\s*<?|(?:src|href)=["'])(?:/assets/` + regexp.QuoteMeta(pageID) + `/|(?:\./)?assets/)` +
+		regexp.QuoteMeta(filename) + `([)>"'\s#?]|$)`)
+	return re.ReplaceAllStringFunc(content, func(match string) string {
+		parts := re.FindStringSubmatch(match)
+		return parts[1] + newURL + parts[2]
+	})
+}
+
+// ResolvePageFolderLinks turns the relative links to the assets next to a page into links
+// to /assets/<page id>/, which serves them like the other assets of the page
+func ResolvePageFolderLinks(content, pageID string) string {
+	return pageFolderLinkRegex.ReplaceAllString(content, "${1}/assets/"+pageID+"/")
+}
+
+// AssetFilePath returns the file of an asset of the page, stored next to the page or in
+// assets/<page id>
+func (s *AssetService) AssetFilePath(page *tree.PageNode, filename string) (string, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	dir, err := s.assetDirLocked(page, filename)
+	if err != nil {
+		return "", err
+	}
+	return path.Join(dir, filename), nil
+}
+
+// assetDirLocked returns the folder which holds the asset of the page.
+// Lock must be held by the caller
+func (s *AssetService) assetDirLocked(page *tree.PageNode, filename string) (string, error) {
+	if filename == "" || filename != path.Base(filename) {
+		return "", fmt.Errorf("%w: %s", ErrInvalidAssetName, filename)
+	}
+	for _, dir := range []string{s.PageFolderPath(page), path.Join(s.assetsDir, page.ID)} {
+		if info, err := os.Stat(path.Join(dir, filename)); err == nil && !info.IsDir() {
+			return dir, nil
+		}
+	}
+	return "", fmt.Errorf("%w: %s", ErrAssetNotFound, filename)
+}
+
+// publicPathIn returns the public path of an asset in dir, relative for the assets next to the page
+func (s *AssetService) publicPathIn(page *tree.PageNode, dir, filename string) string {
+	if dir == s.PageFolderPath(page) {
+		return PageFolderLinkPrefix + filename
+	}
+	return s.buildPublicPath(page, filename)
+}
+
+// listFiles returns the names of the files in dir
+func listFiles(dir string) []string {
+	entries, _ := os.ReadDir(dir)
+	names := make([]string, 0, len(entries))
+	for _, e := range entries {
+		if !e.IsDir() {
+			names = append(names, e.Name())
+		}
+	}
+	return names
+}
+
 func (s *AssetService) ensureAssetPagePathExists(page *tree.PageNode) (string, error) {
 	pagePath := path.Join(s.assetsDir, page.ID)
 	// check if the page path exists
@@ -68,38 +174,40 @@ func (s *AssetService) buildPublicPath(page *tree.PageNode, filename string) str
 }
 
 // SaveAssetForPage saves a file under a page's slug-based path and returns its public URL.
-func (s *AssetService) SaveAssetForPage(page *tree.PageNode, file multipart.File, originalFilename string) (string, error) {
+func (s *AssetService) SaveAssetForPage(page *tree.PageNode, file io.Reader, originalFilename string) (string, error) {
 	s.mu.Lock()
 	defer s.mu.Unlock()
+	return s.saveAssetLocked(page, file, originalFilename)
+}
 
-	uploadPath, err := s.ensureAssetPagePathExists(page)
+// saveAssetLocked saves a file under a unique name in the folder of new assets of the page.
+// Lock must be held by the caller
+func (s *AssetService) saveAssetLocked(page *tree.PageNode, file io.Reader, originalFilename string) (string, error) {
+	uploadPath, err := s.uploadDirLocked(page)
 	if err != nil {
 		return "", fmt.Errorf("could not upload file: %w", err)
 	}
 
-	// Read existing filenames
-	entries, _ := os.ReadDir(uploadPath)
-	existing := make([]string, 0, len(entries))
-	for _, e := range entries {
-		existing = append(existing, e.Name())
-	}
+	// Read existing filenames of both folders, so the names stay unique per page
+	existing := append(listFiles(path.Join(s.assetsDir, page.ID)), listFiles(s.PageFolderPath(page))...)
 
 	finalFilename := s.slugger.GenerateUniqueFilename(existing, originalFilename)
-	fullPath := path.Join(uploadPath, finalFilename)
-
-	// Create and write the file
-	out, err := os.Create(fullPath)
-	if err != nil {
-		return "", fmt.Errorf("could not create file: %w", err)
+	if err := writeFile(path.Join(uploadPath, finalFilename), file); err != nil {
+		return "", err
 	}
-	defer out.Close()
 
-	if _, err := io.Copy(out, file); err != nil {
-		return "", fmt.Errorf("could not write file: %w", err)
-	}
+	// Return public path (served from /assets or relative to the page)
+	return s.publicPathIn(page, uploadPath, finalFilename), nil
+}
 
-	// Return public path (served from /assets)
-	return s.buildPublicPath(page, finalFilename), nil
+// uploadDirLocked returns the folder of new assets of the page and creates it.
+// Lock must be held by the caller
+func (s *AssetService) uploadDirLocked(page *tree.PageNode) (string, error) {
+	if !s.pageFolders {
+		return s.ensureAssetPagePathExists(page)
+	}
+	uploadPath := s.PageFolderPath(page)
+	return uploadPath, os.MkdirAll(uploadPath, 0755)
 }
 
 // ListAssetsForPage returns the full paths of all assets for a given page
@@ -107,21 +215,12 @@ func (s *AssetService) ListAssetsForPage(page *tree.PageNode) ([]string, error)
 	s.mu.RLock()
 	defer s.mu.RUnlock()
 
-	pagePath, err := s.getAssetPagePath(page)
-	if err != nil {
-		return []string{}, nil
-	}
-
-	files, err := os.ReadDir(pagePath)
-	if err != nil && !os.IsNotExist(err) {
-		return []string{}, nil
-	}
-
 	result := []string{}
-	for _, f := range files {
-		if !f.IsDir() {
-			result = append(result, s.buildPublicPath(page, f.Name()))
-		}
+	for _, name := range listFiles(path.Join(s.assetsDir, page.ID)) {
+		result = append(result, s.buildPublicPath(page, name))
+	}
+	for _, name := range listFiles(s.PageFolderPath(page)) {
+		result = append(result, PageFolderLinkPrefix+name)
 	}
 
 	return result, nil
@@ -132,9 +231,9 @@ func (s *AssetService) DeleteAsset(page *tree.PageNode, filename string) error {
 	s.mu.Lock()
 	defer s.mu.Unlock()
 
-	assetPath, err := s.getAssetPagePath(page)
+	assetPath, err := s.assetDirLocked(page, filename)
 	if err != nil {
-		return fmt.Errorf("asset not found: %s", filename)
+		return err
 	}
 
 	fullPath := path.Join(assetPath, filename)
@@ -171,49 +270,100 @@ func (s *AssetService) RenameAsset(page *tree.PageNode, oldFilename, newFilename
 	s.mu.Lock()
 	defer s.mu.Unlock()
 
-	assetPath, err := s.getAssetPagePath(page)
+	assetPath, err := s.assetDirLocked(page, oldFilename)
 	if err != nil {
-		return "", fmt.Errorf("could not rename asset: %w", err)
+		return "", err
+	}
+	if err := s.validateNewFilename(oldFilename, newFilename); err != nil {
+		return "", err
 	}
 
 	oldFullPath := path.Join(assetPath, oldFilename)
 	newFullPath := path.Join(assetPath, newFilename)
 
-	// Ensure that the new filename has the same extension as the old one
+	// Ensure that no file with the new name already exists
+	if _, err := os.Stat(newFullPath); !os.IsNotExist(err) {
+		return "", fmt.Errorf("%w: %s", ErrAssetExists, newFilename)
+	}
+
+	if err := os.Rename(oldFullPath, newFullPath); err != nil {
+		return "", fmt.Errorf("could not rename asset: %w", err)
+	}
+	if err := s.moveVersionsLocked(page.ID, oldFilename, page.ID, newFilename); err != nil {
+		return "", err
+	}
+
+	return s.publicPathIn(page, assetPath, newFilename), nil
+}
+
+// validateNewFilename ensures that a renamed asset keeps its extension and that the rest of
+// its name is a valid slug
+func (s *AssetService) validateNewFilename(oldFilename, newFilename string) error {
 	oldExt := path.Ext(oldFilename)
 	newExt := path.Ext(newFilename)
 	if oldExt != newExt {
-		return "", fmt.Errorf("new asset must have the same extension as the old one: %s", oldExt)
+		return fmt.Errorf("%w: the new asset must have the same extension as the old one: %s", ErrInvalidAssetName, oldExt)
 	}
 
-	// Used for slug validation
 	// The extension is not part of the slug, so we remove it
-	newFilenameWithoutExt := newFilename[:len(newFilename)-len(newExt)]
-	// Ensure that the new asset is a valid filename (slug)
-	if err := s.slugger.IsValidSlug(newFilenameWithoutExt); err != nil {
-		return "", fmt.Errorf("invalid asset name: %s", newFilename)
+	if newFilename != path.Base(newFilename) || s.slugger.IsValidSlug(newFilename[:len(newFilename)-len(newExt)]) != nil {
+		return fmt.Errorf("%w: %s", ErrInvalidAssetName, newFilename)
 	}
+	return nil
+}
 
-	// Ensure that no file with the new name already exists
-	if _, err := os.Stat(newFullPath); !os.IsNotExist(err) {
-		return "", fmt.Errorf("new asset already exists: %s", newFilename)
-	}
+// MoveAsset moves an asset of a page to another page, optionally under a new filename with the
+// same extension. It is stored like new assets of the target page, next to the page if
+// SetPageFolders is enabled. Returns the public path of the moved asset.
+func (s *AssetService) MoveAsset(page *tree.PageNode, target *tree.PageNode, oldFilename, newFilename string) (string, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
 
-	if _, err := os.Stat(oldFullPath); os.IsNotExist(err) {
-		return "", fmt.Errorf("old asset does not exist: %s", oldFilename)
+	assetPath, err := s.assetDirLocked(page, oldFilename)
+	if err != nil {
+		return "", err
+	}
+	if err := s.validateNewFilename(oldFilename, newFilename); err != nil {
+		return "", err
+	}
+	for _, dir := range []string{s.PageFolderPath(target), path.Join(s.assetsDir, target.ID)} {
+		if _, err := os.Stat(path.Join(dir, newFilename)); !os.IsNotExist(err) {
+			return "", fmt.Errorf("%w: %s", ErrAssetExists, newFilename)
+		}
 	}
 
-	if err := os.Rename(oldFullPath, newFullPath); err != nil {
-		return "", fmt.Errorf("could not rename asset: %w", err)
+	targetPath, err := s.uploadDirLocked(target)
+	if err != nil {
+		return "", fmt.Errorf("could not move asset: %w", err)
+	}
+	if err := os.Rename(path.Join(assetPath, oldFilename), path.Join(targetPath, newFilename)); err != nil {
+		return "", fmt.Errorf("could not move asset: %w", err)
+	}
+	if err := s.moveVersionsLocked(page.ID, oldFilename, target.ID, newFilename); err != nil {
+		return "", err
 	}
 
-	return s.buildPublicPath(page, newFilename), nil
+	if files, err := os.ReadDir(assetPath); err == nil && len(files) == 0 {
+		_ = os.Remove(assetPath)
+	}
+	return s.publicPathIn(target, targetPath, newFilename), nil
 }
 
 func (s *AssetService) CopyAllAssets(sourcePage *tree.PageNode, targetPage *tree.PageNode) error {
 	s.mu.Lock()
 	defer s.mu.Unlock()
 
+	// The assets next to the page are copied next to the target page, which must be a folder
+	if names := listFiles(s.PageFolderPath(sourcePage)); len(names) > 0 {
+		targetFolder := s.PageFolderPath(targetPage)
+		if err := os.MkdirAll(targetFolder, 0755); err != nil {
+			return fmt.Errorf("could not create target asset path: %w", err)
+		}
+		if err := s.copyAssetDir(s.PageFolderPath(sourcePage), targetFolder); err != nil {
+			return err
+		}
+	}
+
 	sourceAssetPath, err := s.getAssetPagePath(sourcePage)
 	if err != nil {
 		// No assets to copy
@@ -224,7 +374,10 @@ func (s *AssetService) CopyAllAssets(sourcePage *tree.PageNode, targetPage *tree
 	if err != nil {
 		return fmt.Errorf("could not create target asset path: %w", err)
 	}
+	return s.copyAssetDir(sourceAssetPath, targetAssetPath)
+}
 
+func (s *AssetService) copyAssetDir(sourceAssetPath string, targetAssetPath string) error {
 	entries, err := os.ReadDir(sourceAssetPath)
 	if err != nil {
 		return fmt.Errorf("could not read source asset directory: %w", err)
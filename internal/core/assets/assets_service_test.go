@@ -1,6 +1,7 @@
 package assets
 
 import (
+	"errors"
 	"os"
 	"path/filepath"
 	"strings"
@@ -158,3 +159,180 @@ func TestAssetRename(t *testing.T) {
 		t.Errorf("unexpected asset list after rename: %v", files)
 	}
 }
+
+func TestSaveAssetInPageFolder(t *testing.T) {
+	tmp := t.TempDir()
+	page := &tree.PageNode{Slug: "lonely-page", ID: "a7b3"}
+	pagePath := filepath.Join(tmp, "lonely-page")
+	if err := os.MkdirAll(pagePath, 0755); err != nil {
+		t.Fatalf("failed to create test directory: %v", err)
+	}
+	service := NewAssetService(tmp, tree.NewSlugService())
+	service.SetPageFolders(true)
+
+	file, name, err := test_utils.CreateMultipartFile("my-image.png", []byte("hello image"))
+	if err != nil {
+		t.Fatalf("failed to create test file: %v", err)
+	}
+	defer file.Close()
+
+	url, err := service.SaveAssetForPage(page, file, name)
+	if err != nil {
+		t.Fatalf("SaveAsset failed: %v", err)
+	}
+	if url != "./assets/my-image.png" {
+		t.Errorf("expected a link relative to the page, got %q", url)
+	}
+	if _, err := os.Stat(filepath.Join(pagePath, "assets", "my-image.png")); err != nil {
+		t.Errorf("expected the asset next to the page: %v", err)
+	}
+
+	renamed, err := service.RenameAsset(page, "my-image.png", "renamed.png")
+	if err != nil || renamed != "./assets/renamed.png" {
+		t.Fatalf("RenameAsset failed: %q %v", renamed, err)
+	}
+	files, _ := service.ListAssetsForPage(page)
+	if len(files) != 1 || files[0] != "./assets/renamed.png" {
+		t.Errorf("unexpected asset list: %v", files)
+	}
+
+	if err := service.DeleteAsset(page, "renamed.png"); err != nil {
+		t.Fatalf("DeleteAsset failed: %v", err)
+	}
+	if _, err := os.Stat(filepath.Join(pagePath, "assets")); !os.IsNotExist(err) {
+		t.Errorf("expected the empty assets folder to be removed")
+	}
+}
+
+func TestMoveAsset(t *testing.T) {
+	tmp := t.TempDir()
+	from := &tree.PageNode{Slug: "from", ID: "f1"}
+	to := &tree.PageNode{Slug: "to", ID: "t1"}
+	service := NewAssetService(tmp, tree.NewSlugService())
+
+	for _, name := range []string{"a.png", "b.png"} {
+		file, _, err := test_utils.CreateMultipartFile(name, []byte(name))
+		if err != nil {
+			t.Fatalf("failed to create test file: %v", err)
+		}
+		if _, err := service.SaveAssetForPage(from, file, name); err != nil {
+			t.Fatalf("SaveAsset failed: %v", err)
+		}
+		file.Close()
+	}
+	if _, err := service.MoveAsset(from, to, "a.png", "b.png"); err != nil {
+		t.Fatalf("MoveAsset failed: %v", err)
+	}
+	if _, err := service.MoveAsset(from, to, "b.png", "b.png"); !errors.Is(err, ErrAssetExists) {
+		t.Errorf("expected ErrAssetExists, got %v", err)
+	}
+	if _, err := service.MoveAsset(from, to, "missing.png", "c.png"); !errors.Is(err, ErrAssetNotFound) {
+		t.Errorf("expected ErrAssetNotFound, got %v", err)
+	}
+	if _, err := service.MoveAsset(from, to, "b.png", "../c.png"); !errors.Is(err, ErrInvalidAssetName) {
+		t.Errorf("expected ErrInvalidAssetName, got %v", err)
+	}
+
+	url, err := service.MoveAsset(from, to, "b.png", "c.png")
+	if err != nil || url != "/assets/t1/c.png" {
+		t.Fatalf("MoveAsset failed: %q %v", url, err)
+	}
+	files, _ := service.ListAssetsForPage(to)
+	if len(files) != 2 {
+		t.Errorf("expected both assets at the target page, got %v", files)
+	}
+	if _, err := os.Stat(filepath.Join(tmp, "assets", "f1")); !os.IsNotExist(err) {
+		t.Errorf("expected the empty assets dir of the source page to be removed")
+	}
+}
+
+func TestAssetVersions(t *testing.T) {
+	tmp := t.TempDir()
+	page := &tree.PageNode{Slug: "versioned", ID: "v1"}
+	service := NewAssetService(tmp, tree.NewSlugService())
+
+	upload := func(content string) string {
+		file, name, err := test_utils.CreateMultipartFile("logo.png", []byte(content))
+		if err != nil {
+			t.Fatalf("failed to create test file: %v", err)
+		}
+		defer file.Close()
+		url, err := service.ReplaceAssetForPage(page, file, name)
+		if err != nil {
+			t.Fatalf("ReplaceAsset failed: %v", err)
+		}
+		return url
+	}
+	readAsset := func() string {
+		data, _ := os.ReadFile(filepath.Join(tmp, "assets", "v1", "logo.png"))
+		return string(data)
+	}
+
+	for _, content := range []string{"first", "second", "third"} {
+		if url := upload(content); url != "/assets/v1/logo.png" {
+			t.Fatalf("expected the asset to be replaced, got %s", url)
+		}
+	}
+	if readAsset() != "third" {
+		t.Errorf("expected the latest content, got %q", readAsset())
+	}
+
+	versions, err := service.ListAssetVersions(page, "logo.png")
+	if err != nil {
+		t.Fatalf("ListAssetVersions failed: %v", err)
+	}
+	if versions.Current == nil || versions.Current.Size != int64(len("third")) || len(versions.Versions) != 2 {
+		t.Fatalf("unexpected versions: %+v", versions)
+	}
+	first := versions.Versions[1]
+	if data, _ := os.ReadFile(filepath.Join(tmp, "asset-versions", "objects", first.Hash[:2], first.Hash)); string(data) != "first" {
+		t.Errorf("expected the content of the first version, got %q", data)
+	}
+
+	if _, err := service.RestoreAssetVersion(page, "logo.png", first.Hash); err != nil {
+		t.Fatalf("RestoreAssetVersion failed: %v", err)
+	}
+	if readAsset() != "first" {
+		t.Errorf("expected the restored content, got %q", readAsset())
+	}
+	// the replaced content is kept, a content which is kept already isn't stored twice
+	if versions, _ = service.ListAssetVersions(page, "logo.png"); len(versions.Versions) != 3 {
+		t.Errorf("expected 3 previous versions, got %+v", versions.Versions)
+	}
+	if _, err := service.RestoreAssetVersion(page, "logo.png", strings.Repeat("0", 64)); !errors.Is(err, ErrAssetVersionNotFound) {
+		t.Errorf("expected ErrAssetVersionNotFound, got %v", err)
+	}
+
+	// the versions move along with a renamed asset and outlive its deletion
+	if _, err := service.RenameAsset(page, "logo.png", "brand.png"); err != nil {
+		t.Fatalf("RenameAsset failed: %v", err)
+	}
+	if err := service.DeleteAsset(page, "brand.png"); err != nil {
+		t.Fatalf("DeleteAsset failed: %v", err)
+	}
+	versions, err = service.ListAssetVersions(page, "brand.png")
+	if err != nil || versions.Current != nil || len(versions.Versions) != 3 {
+		t.Fatalf("expected the versions of the deleted asset, got %+v %v", versions, err)
+	}
+	if url, err := service.RestoreAssetVersion(page, "brand.png", versions.Versions[0].Hash); err != nil || url != "/assets/v1/brand.png" {
+		t.Errorf("expected the deleted asset to be restored, got %q %v", url, err)
+	}
+}
+
+func TestRewriteAssetLinks(t *testing.T) {
+	content := "![a](/assets/p1/a.png) [a](./assets/a.png) <img src=\"assets/a.png\"> [b](/assets/p1/a.png.bak) [c](/assets/p2/a.png)"
+	got := RewriteAssetLinks(content, "p1", "a.png", "/assets/p2/b.png")
+	want := "![a](/assets/p2/b.png) [a](/assets/p2/b.png) <img src=\"/assets/p2/b.png\"> [b](/assets/p1/a.png.bak) [c](/assets/p2/a.png)"
+	if got != want {
+		t.Errorf("unexpected links:\n got %s\nwant %s", got, want)
+	}
+}
+
+func TestResolvePageFolderLinks(t *testing.T) {
+	content := "![a](./assets/a.png) [b](assets/b.pdf) <img src=\"./assets/c.png\"> [d](/assets/x/d.png) [e](docs/assets/e.png)"
+	got := ResolvePageFolderLinks(content, "p1")
+	want := "![a](/assets/p1/a.png) [b](/assets/p1/b.pdf) <img src=\"/assets/p1/c.png\"> [d](/assets/x/d.png) [e](docs/assets/e.png)"
+	if got != want {
+		t.Errorf("unexpected links:\n got %s\nwant %s", got, want)
+	}
+}
@@ -0,0 +1,36 @@
+package assets
+
+import (
+	"mime"
+	"path"
+	"strings"
+)
+
+// mediaTypes are the content types of audio and video files, which the mime package only
+// knows if the system has a mime.types file
+var mediaTypes = map[string]string{
+	".aac":  "audio/aac",
+	".flac": "audio/flac",
+	".m4a":  "audio/mp4",
+	".mp3":  "audio/mpeg",
+	".oga":  "audio/ogg",
+	".ogg":  "audio/ogg",
+	".opus": "audio/ogg",
+	".wav":  "audio/wav",
+	".weba": "audio/webm",
+	".m4v":  "video/mp4",
+	".mov":  "video/quicktime",
+	".mp4":  "video/mp4",
+	".ogv":  "video/ogg",
+	".webm": "video/webm",
+	".vtt":  "text/vtt; charset=utf-8",
+}
+
+// ContentType returns the content type of an asset by its extension, or "" if it is unknown
+func ContentType(filename string) string {
+	ext := strings.ToLower(path.Ext(filename))
+	if contentType, ok := mediaTypes[ext]; ok {
+		return contentType
+	}
+	return mime.TypeByExtension(ext)
+}
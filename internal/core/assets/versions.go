@@ -0,0 +1,288 @@
+package assets
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"regexp"
+	"sort"
+	"time"
+
+	"github.com/Gomez12/wiki/internal/core/tree"
+)
+
+// versionsDirName is the folder in the storage dir which keeps the previous versions of the
+// assets: the contents once per hash in objects/, and per page a list of the versions of
+// each asset in <page id>/<name>.json
+const versionsDirName = "asset-versions"
+
+var hashRegex = regexp.MustCompile(`^[0-9a-f]{64}$`)
+
+var ErrAssetVersionNotFound = errors.New("asset version not found")
+
+// AssetVersion is a content of an asset, identified by its SHA-256 hash
+type AssetVersion struct {
+	Hash string `json:"hash"`
+	Size int64  `json:"size"`
+	// SavedAt is when this content was uploaded, the modification time of the file
+	SavedAt time.Time `json:"savedAt"`
+}
+
+// AssetVersions are the current and the previous versions of an asset
+type AssetVersions struct {
+	// Current is nil if the asset has been deleted since
+	Current *AssetVersion `json:"current"`
+	// Versions are the previous versions, the newest first
+	Versions []AssetVersion `json:"versions"`
+}
+
+// ReplaceAssetForPage saves a file like SaveAssetForPage, but replaces an asset with the same
+// name instead of choosing a unique one. The replaced content is kept as previous version.
+func (s *AssetService) ReplaceAssetForPage(page *tree.PageNode, file io.Reader, originalFilename string) (string, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	filename := s.slugger.GenerateUniqueFilename(nil, originalFilename)
+	dir, err := s.assetDirLocked(page, filename)
+	if err != nil {
+		return s.saveAssetLocked(page, file, originalFilename)
+	}
+	if err := s.archiveLocked(page.ID, dir, filename); err != nil {
+		return "", err
+	}
+	if err := writeFile(path.Join(dir, filename), file); err != nil {
+		return "", err
+	}
+	return s.publicPathIn(page, dir, filename), nil
+}
+
+// ListAssetVersions returns the current and previous versions of an asset. The versions of
+// a deleted asset are kept, so it can be restored.
+func (s *AssetService) ListAssetVersions(page *tree.PageNode, filename string) (*AssetVersions, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	if filename == "" || filename != path.Base(filename) {
+		return nil, fmt.Errorf("%w: %s", ErrInvalidAssetName, filename)
+	}
+	versions, err := s.readVersionsLocked(page.ID, filename)
+	if err != nil {
+		return nil, err
+	}
+
+	result := &AssetVersions{Versions: make([]AssetVersion, 0, len(versions))}
+	if dir, err := s.assetDirLocked(page, filename); err == nil {
+		current, err := fileVersion(path.Join(dir, filename))
+		if err != nil {
+			return nil, err
+		}
+		result.Current = current
+	}
+	for i := len(versions) - 1; i >= 0; i-- {
+		result.Versions = append(result.Versions, versions[i])
+	}
+	if result.Current == nil && len(result.Versions) == 0 {
+		return nil, fmt.Errorf("%w: %s", ErrAssetNotFound, filename)
+	}
+	return result, nil
+}
+
+// AssetVersionPath returns the file with the content of a previous version of an asset
+func (s *AssetService) AssetVersionPath(page *tree.PageNode, filename, hash string) (string, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	if err := s.findVersionLocked(page.ID, filename, hash); err != nil {
+		return "", err
+	}
+	return s.objectPath(hash), nil
+}
+
+// RestoreAssetVersion replaces the content of an asset with a previous version, the replaced
+// content becomes a previous version itself. A deleted asset is restored like a new upload.
+// Returns the public path of the asset.
+func (s *AssetService) RestoreAssetVersion(page *tree.PageNode, filename, hash string) (string, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if err := s.findVersionLocked(page.ID, filename, hash); err != nil {
+		return "", err
+	}
+
+	dir, err := s.assetDirLocked(page, filename)
+	if err == nil {
+		if err := s.archiveLocked(page.ID, dir, filename); err != nil {
+			return "", err
+		}
+	} else if dir, err = s.uploadDirLocked(page); err != nil {
+		return "", fmt.Errorf("could not restore asset: %w", err)
+	}
+
+	object, err := os.Open(s.objectPath(hash))
+	if err != nil {
+		return "", fmt.Errorf("could not restore asset: %w", err)
+	}
+	defer object.Close()
+	if err := writeFile(path.Join(dir, filename), object); err != nil {
+		return "", err
+	}
+	return s.publicPathIn(page, dir, filename), nil
+}
+
+// archiveLocked keeps the current content of an asset as previous version.
+// Lock must be held by the caller
+func (s *AssetService) archiveLocked(pageID, dir, filename string) error {
+	fullPath := path.Join(dir, filename)
+	version, err := fileVersion(fullPath)
+	if err != nil {
+		return fmt.Errorf("could not keep the previous version: %w", err)
+	}
+	versions, err := s.readVersionsLocked(pageID, filename)
+	if err != nil {
+		return err
+	}
+	if len(versions) > 0 && versions[len(versions)-1].Hash == version.Hash {
+		return nil
+	}
+
+	objectPath := s.objectPath(version.Hash)
+	if _, err := os.Stat(objectPath); os.IsNotExist(err) {
+		if err := os.MkdirAll(path.Dir(objectPath), 0755); err != nil {
+			return fmt.Errorf("could not keep the previous version: %w", err)
+		}
+		in, err := os.Open(fullPath)
+		if err != nil {
+			return fmt.Errorf("could not keep the previous version: %w", err)
+		}
+		defer in.Close()
+		// written under a temporary name, so an interrupted copy never becomes an object
+		if err := writeFile(objectPath+".tmp", in); err != nil {
+			return err
+		}
+		if err := os.Rename(objectPath+".tmp", objectPath); err != nil {
+			return fmt.Errorf("could not keep the previous version: %w", err)
+		}
+	}
+	return s.writeVersionsLocked(pageID, filename, append(versions, *version))
+}
+
+// moveVersionsLocked moves the previous versions along with a renamed or moved asset.
+// Lock must be held by the caller
+func (s *AssetService) moveVersionsLocked(fromPageID, fromName, toPageID, toName string) error {
+	versions, err := s.readVersionsLocked(fromPageID, fromName)
+	if err != nil || len(versions) == 0 {
+		return err
+	}
+	// a deleted asset of the same name may have left versions at the target
+	existing, err := s.readVersionsLocked(toPageID, toName)
+	if err != nil {
+		return err
+	}
+	versions = append(existing, versions...)
+	sort.SliceStable(versions, func(i, j int) bool {
+		return versions[i].SavedAt.Before(versions[j].SavedAt)
+	})
+	if err := s.writeVersionsLocked(toPageID, toName, versions); err != nil {
+		return err
+	}
+	return os.Remove(s.versionsPath(fromPageID, fromName))
+}
+
+// findVersionLocked checks that the hash is a previous version of the asset.
+// Lock must be held by the caller
+func (s *AssetService) findVersionLocked(pageID, filename, hash string) error {
+	if filename == "" || filename != path.Base(filename) {
+		return fmt.Errorf("%w: %s", ErrInvalidAssetName, filename)
+	}
+	if !hashRegex.MatchString(hash) {
+		return fmt.Errorf("%w: %s", ErrAssetVersionNotFound, hash)
+	}
+	versions, err := s.readVersionsLocked(pageID, filename)
+	if err != nil {
+		return err
+	}
+	for _, version := range versions {
+		if version.Hash == hash {
+			return nil
+		}
+	}
+	return fmt.Errorf("%w: %s", ErrAssetVersionNotFound, hash)
+}
+
+// readVersionsLocked returns the previous versions of an asset, the oldest first.
+// Lock must be held by the caller
+func (s *AssetService) readVersionsLocked(pageID, filename string) ([]AssetVersion, error) {
+	data, err := os.ReadFile(s.versionsPath(pageID, filename))
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("could not read the versions of %s: %w", filename, err)
+	}
+	var versions []AssetVersion
+	if err := json.Unmarshal(data, &versions); err != nil {
+		return nil, fmt.Errorf("could not read the versions of %s: %w", filename, err)
+	}
+	return versions, nil
+}
+
+// writeVersionsLocked stores the previous versions of an asset.
+// Lock must be held by the caller
+func (s *AssetService) writeVersionsLocked(pageID, filename string, versions []AssetVersion) error {
+	versionsPath := s.versionsPath(pageID, filename)
+	if err := os.MkdirAll(path.Dir(versionsPath), 0755); err != nil {
+		return fmt.Errorf("could not write the versions of %s: %w", filename, err)
+	}
+	data, err := json.MarshalIndent(versions, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := os.WriteFile(versionsPath, data, 0644); err != nil {
+		return fmt.Errorf("could not write the versions of %s: %w", filename, err)
+	}
+	return nil
+}
+
+func (s *AssetService) versionsPath(pageID, filename string) string {
+	return path.Join(s.storageDir, versionsDirName, pageID, filename+".json")
+}
+
+func (s *AssetService) objectPath(hash string) string {
+	return path.Join(s.storageDir, versionsDirName, "objects", hash[:2], hash)
+}
+
+// fileVersion hashes the content of a file
+func fileVersion(fullPath string) (*AssetVersion, error) {
+	file, err := os.Open(fullPath)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+	info, err := file.Stat()
+	if err != nil {
+		return nil, err
+	}
+	hash := sha256.New()
+	if _, err := io.Copy(hash, file); err != nil {
+		return nil, err
+	}
+	return &AssetVersion{Hash: hex.EncodeToString(hash.Sum(nil)), Size: info.Size(), SavedAt: info.ModTime().UTC()}, nil
+}
+
+// writeFile creates or truncates the file and copies the content into it
+func writeFile(fullPath string, content io.Reader) error {
+	out, err := os.Create(fullPath)
+	if err != nil {
+		return fmt.Errorf("could not create file: %w", err)
+	}
+	defer out.Close()
+	if _, err := io.Copy(out, content); err != nil {
+		return fmt.Errorf("could not write file: %w", err)
+	}
+	return out.Close()
+}
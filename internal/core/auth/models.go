@@ -30,6 +30,39 @@ func (u *User) ToPublicUser() *PublicUser {
 	}
 }
 
+// UserListOptions controls which users are returned by a user listing
+type UserListOptions struct {
+	// Query matches the username or email, case-insensitive
+	Query string
+	// Sort is one of the UserSort constants
+	Sort string
+	// Descending reverses the sort order
+	Descending bool
+	// Limit is the maximum number of users; 0 returns all users
+	Limit  int
+	Offset int
+}
+
+// Sort fields of a user listing
+const (
+	UserSortUsername = "username"
+	UserSortEmail    = "email"
+	UserSortRole     = "role"
+	UserSortCreated  = "created"
+)
+
+var userSortColumns = map[string]string{
+	UserSortUsername: "username",
+	UserSortEmail:    "email",
+	UserSortRole:     "role",
+	UserSortCreated:  "created_at",
+}
+
+func IsValidUserSort(sort string) bool {
+	_, ok := userSortColumns[sort]
+	return ok
+}
+
 // Roles
 const (
 	RoleAdmin  = "admin"
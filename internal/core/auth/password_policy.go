@@ -0,0 +1,94 @@
+package auth
+
+import (
+	_ "embed"
+	"fmt"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// maxPasswordMinLength is the largest minimum length an admin can configure
+const maxPasswordMinLength = 128
+
+//go:embed breached_passwords.txt
+var breachedPasswordList string
+
+// breachedPasswords are well-known passwords from public breach lists, lowercased
+var breachedPasswords = func() map[string]struct{} {
+	set := make(map[string]struct{})
+	for _, line := range strings.Split(breachedPasswordList, "\n") {
+		if line = strings.TrimSpace(line); line != "" && !strings.HasPrefix(line, "#") {
+			set[strings.ToLower(line)] = struct{}{}
+		}
+	}
+	return set
+}()
+
+// PasswordPolicy describes the requirements for new passwords
+type PasswordPolicy struct {
+	MinLength        int  `json:"minLength"`
+	RequireUppercase bool `json:"requireUppercase"`
+	RequireLowercase bool `json:"requireLowercase"`
+	RequireDigit     bool `json:"requireDigit"`
+	RequireSymbol    bool `json:"requireSymbol"`
+	// RejectBreached rejects passwords from the bundled list of breached passwords
+	RejectBreached bool `json:"rejectBreached"`
+}
+
+// DefaultPasswordPolicy is used as long as no policy has been configured
+func DefaultPasswordPolicy() PasswordPolicy {
+	return PasswordPolicy{MinLength: 8}
+}
+
+// Check validates the policy itself
+func (p PasswordPolicy) Check() error {
+	if p.MinLength < 1 || p.MinLength > maxPasswordMinLength {
+		return fmt.Errorf("minimum length must be between 1 and %d", maxPasswordMinLength)
+	}
+	return nil
+}
+
+// Violations returns a message for every requirement the password doesn't meet
+func (p PasswordPolicy) Violations(password string) []string {
+	var violations []string
+	if utf8.RuneCountInString(password) < p.MinLength {
+		violations = append(violations, fmt.Sprintf("Password must be at least %d characters long", p.MinLength))
+	}
+
+	var upper, lower, digit, symbol bool
+	for _, r := range password {
+		switch {
+		case unicode.IsUpper(r):
+			upper = true
+		case unicode.IsLower(r):
+			lower = true
+		case unicode.IsDigit(r):
+			digit = true
+		case unicode.IsPunct(r) || unicode.IsSymbol(r) || unicode.IsSpace(r):
+			symbol = true
+		}
+	}
+	if p.RequireUppercase && !upper {
+		violations = append(violations, "Password must contain an uppercase letter")
+	}
+	if p.RequireLowercase && !lower {
+		violations = append(violations, "Password must contain a lowercase letter")
+	}
+	if p.RequireDigit && !digit {
+		violations = append(violations, "Password must contain a digit")
+	}
+	if p.RequireSymbol && !symbol {
+		violations = append(violations, "Password must contain a symbol")
+	}
+	if p.RejectBreached && IsBreachedPassword(password) {
+		violations = append(violations, "Password is too common and has appeared in data breaches")
+	}
+	return violations
+}
+
+// IsBreachedPassword reports whether the password is on the bundled breach list
+func IsBreachedPassword(password string) bool {
+	_, ok := breachedPasswords[strings.ToLower(password)]
+	return ok
+}
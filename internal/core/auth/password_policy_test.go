@@ -0,0 +1,50 @@
+package auth
+
+import "testing"
+
+func TestPasswordPolicy_Violations(t *testing.T) {
+	policy := PasswordPolicy{
+		MinLength:        10,
+		RequireUppercase: true,
+		RequireLowercase: true,
+		RequireDigit:     true,
+		RequireSymbol:    true,
+		RejectBreached:   true,
+	}
+
+	cases := map[string]int{
+		"Sh0rt!":            1,
+		"alllowercase":      3,
+		"ALLUPPERCASE1":     2,
+		"Corr3ct-Horse!":    0,
+		"Password1234":      2,
+		"PASSWORD1234!aa":   0,
+		"äöüÄÖÜ12345 space": 0,
+	}
+	for password, want := range cases {
+		if got := policy.Violations(password); len(got) != want {
+			t.Errorf("Violations(%q) = %v, want %d violations", password, got, want)
+		}
+	}
+}
+
+func TestPasswordPolicy_RejectBreached(t *testing.T) {
+	policy := PasswordPolicy{MinLength: 1, RejectBreached: true}
+	if len(policy.Violations("QWERTY123")) != 1 {
+		t.Errorf("expected breached password to be rejected regardless of case")
+	}
+	if len(policy.Violations("a-rather-unusual-passphrase")) != 0 {
+		t.Errorf("expected unusual password to be accepted")
+	}
+}
+
+func TestPasswordPolicy_Check(t *testing.T) {
+	if err := DefaultPasswordPolicy().Check(); err != nil {
+		t.Errorf("default policy should be valid: %v", err)
+	}
+	for _, n := range []int{0, -1, maxPasswordMinLength + 1} {
+		if err := (PasswordPolicy{MinLength: n}).Check(); err == nil {
+			t.Errorf("expected error for minimum length %d", n)
+		}
+	}
+}
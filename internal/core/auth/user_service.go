@@ -1,12 +1,16 @@
 package auth
 
 import (
+	"encoding/json"
 	"fmt"
 
 	"github.com/Gomez12/wiki/internal/core/shared"
 	"golang.org/x/crypto/bcrypt"
 )
 
+// passwordPolicySetting is the settings key of the password policy
+const passwordPolicySetting = "password_policy"
+
 type UserService struct {
 	store *UserStore
 }
@@ -199,6 +203,16 @@ func (s *UserService) GetUsers() ([]*User, error) {
 	return users, nil
 }
 
+// HasUsers reports whether any user exists
+func (s *UserService) HasUsers() (bool, error) {
+	count, err := s.store.GetUserCount()
+	return count > 0, err
+}
+
+func (s *UserService) ListUsers(opts UserListOptions) ([]*User, int, error) {
+	return s.store.ListUsers(opts)
+}
+
 func (s *UserService) GetUserByEmailOrUsernameAndPassword(identifier, password string) (*User, error) {
 	user, err := s.store.GetUserByUsername(identifier)
 	if err != nil {
@@ -246,7 +260,7 @@ func (s *UserService) ChangeOwnPassword(id, oldPassword, newPassword string) err
 
 func (s *UserService) ResetAdminUserPassword() (*User, error) {
 	// Generate a new password for the admin user
-	password, err := shared.GenerateRandomPassword(16)
+	password, err := s.generatePassword()
 	if err != nil {
 		return nil, fmt.Errorf("failed to generate password: %w", err)
 	}
@@ -278,6 +292,53 @@ func (s *UserService) ResetAdminUserPassword() (*User, error) {
 	return adminUser, nil
 }
 
+// GetPasswordPolicy returns the configured password policy, or the default policy
+func (s *UserService) GetPasswordPolicy() (PasswordPolicy, error) {
+	value, ok, err := s.store.GetSetting(passwordPolicySetting)
+	if err != nil {
+		return PasswordPolicy{}, err
+	}
+	policy := DefaultPasswordPolicy()
+	if !ok {
+		return policy, nil
+	}
+	if err := json.Unmarshal([]byte(value), &policy); err != nil {
+		return PasswordPolicy{}, fmt.Errorf("failed to decode password policy: %w", err)
+	}
+	return policy, nil
+}
+
+// SetPasswordPolicy stores the password policy. It only applies to passwords set afterwards.
+func (s *UserService) SetPasswordPolicy(policy PasswordPolicy) error {
+	if err := policy.Check(); err != nil {
+		return err
+	}
+	data, err := json.Marshal(policy)
+	if err != nil {
+		return err
+	}
+	return s.store.SetSetting(passwordPolicySetting, string(data))
+}
+
+// generatePassword returns a random password which satisfies the password policy
+func (s *UserService) generatePassword() (string, error) {
+	policy, err := s.GetPasswordPolicy()
+	if err != nil {
+		return "", err
+	}
+	length := max(16, policy.MinLength)
+	for range 20 {
+		password, err := shared.GenerateRandomPassword(length)
+		if err != nil {
+			return "", err
+		}
+		if len(policy.Violations(password)) == 0 {
+			return password, nil
+		}
+	}
+	return "", fmt.Errorf("no password satisfying the password policy could be generated")
+}
+
 func (s *UserService) Close() error {
 	return s.store.Close()
 }
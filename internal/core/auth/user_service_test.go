@@ -117,3 +117,43 @@ func TestUserService_InitDefaultAdmin(t *testing.T) {
 		t.Errorf("Expected default admin user, got: %+v", users)
 	}
 }
+
+func TestUserService_PasswordPolicy(t *testing.T) {
+	service := setupTestUserService(t)
+
+	policy, err := service.GetPasswordPolicy()
+	if err != nil {
+		t.Fatalf("GetPasswordPolicy failed: %v", err)
+	}
+	if policy != DefaultPasswordPolicy() {
+		t.Errorf("expected default policy, got %+v", policy)
+	}
+
+	strict := PasswordPolicy{MinLength: 20, RequireDigit: true, RequireSymbol: true, RejectBreached: true}
+	if err := service.SetPasswordPolicy(strict); err != nil {
+		t.Fatalf("SetPasswordPolicy failed: %v", err)
+	}
+	if err := service.SetPasswordPolicy(PasswordPolicy{}); err == nil {
+		t.Errorf("expected invalid policy to be rejected")
+	}
+
+	policy, err = service.GetPasswordPolicy()
+	if err != nil {
+		t.Fatalf("GetPasswordPolicy failed: %v", err)
+	}
+	if policy != strict {
+		t.Errorf("expected stored policy %+v, got %+v", strict, policy)
+	}
+
+	// the reset flow generates a password which satisfies the policy
+	if err := service.InitDefaultAdmin("admin"); err != nil {
+		t.Fatalf("InitDefaultAdmin failed: %v", err)
+	}
+	admin, err := service.ResetAdminUserPassword()
+	if err != nil {
+		t.Fatalf("ResetAdminUserPassword failed: %v", err)
+	}
+	if v := strict.Violations(admin.Password); len(v) != 0 {
+		t.Errorf("generated password violates policy: %v", v)
+	}
+}
@@ -58,6 +58,10 @@ func (f *UserStore) ensureSchema() error {
 			role TEXT NOT NULL,
 			created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
 		);
+		CREATE TABLE IF NOT EXISTS settings (
+			key TEXT PRIMARY KEY,
+			value TEXT NOT NULL
+		);
 	`)
 	if err != nil {
 		return err
@@ -273,6 +277,64 @@ func (f *UserStore) GetAllUsers() ([]*User, error) {
 	return users, nil
 }
 
+// ListUsers returns a window of the users matching the options and the total number of matches
+func (f *UserStore) ListUsers(opts UserListOptions) ([]*User, int, error) {
+	// Ensure the database is connected
+	err := f.Connect()
+	if err != nil {
+		return nil, 0, err
+	}
+
+	where := ""
+	var args []interface{}
+	if q := strings.TrimSpace(opts.Query); q != "" {
+		like := "%" + strings.NewReplacer(`\`, `\\`, `%`, `\%`, `_`, `\_`).Replace(strings.ToLower(q)) + "%"
+		where = ` WHERE LOWER(username) LIKE ? ESCAPE '\' OR LOWER(email) LIKE ? ESCAPE '\'`
+		args = append(args, like, like)
+	}
+
+	var total int
+	if err := f.db.QueryRow(`SELECT COUNT(*) FROM users`+where+`;`, args...).Scan(&total); err != nil {
+		return nil, 0, err
+	}
+
+	column, ok := userSortColumns[opts.Sort]
+	if !ok {
+		column = userSortColumns[UserSortUsername]
+	}
+	order := "ASC"
+	if opts.Descending {
+		order = "DESC"
+	}
+	limit := opts.Limit
+	if limit <= 0 {
+		limit = -1
+	}
+	args = append(args, limit, opts.Offset)
+
+	// the column is taken from a fixed list, the username keeps the order stable
+	rows, err := f.db.Query(`
+		SELECT id, username, password, email, role
+		FROM users`+where+`
+		ORDER BY `+column+` COLLATE NOCASE `+order+`, username COLLATE NOCASE ASC
+		LIMIT ? OFFSET ?;
+	`, args...)
+	if err != nil {
+		return nil, 0, err
+	}
+	defer rows.Close()
+
+	users := []*User{}
+	for rows.Next() {
+		user := &User{}
+		if err := rows.Scan(&user.ID, &user.Username, &user.Password, &user.Email, &user.Role); err != nil {
+			return nil, 0, err
+		}
+		users = append(users, user)
+	}
+	return users, total, rows.Err()
+}
+
 func (f *UserStore) GetUserCount() (int, error) {
 	// Ensure the database is connected
 	err := f.Connect()
@@ -408,3 +470,35 @@ func (f *UserStore) UpdatePassword(userID string, newPassword string) error {
 	}
 	return nil
 }
+
+// GetSetting returns the value of a setting and whether it has been set
+func (f *UserStore) GetSetting(key string) (string, bool, error) {
+	err := f.Connect()
+	if err != nil {
+		return "", false, err
+	}
+
+	var value string
+	err = f.db.QueryRow(`SELECT value FROM settings WHERE key = ?;`, key).Scan(&value)
+	if err == sql.ErrNoRows {
+		return "", false, nil
+	}
+	if err != nil {
+		return "", false, err
+	}
+	return value, true, nil
+}
+
+// SetSetting stores the value of a setting, replacing the previous one
+func (f *UserStore) SetSetting(key, value string) error {
+	err := f.Connect()
+	if err != nil {
+		return err
+	}
+
+	_, err = f.db.Exec(`
+		INSERT INTO settings (key, value) VALUES (?, ?)
+		ON CONFLICT(key) DO UPDATE SET value = excluded.value;
+	`, key, value)
+	return err
+}
@@ -0,0 +1,109 @@
+package backup
+
+import (
+	"archive/tar"
+	"compress/gzip"
+	"errors"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+var ErrTargetInsideDataDir = errors.New("backup file must not be inside the data directory")
+
+// CreateFile writes a backup of dataDir to the tar.gz archive target.
+// The wiki must not be running, so the databases are not written during the backup.
+func CreateFile(dataDir, target string) error {
+	inside, err := isInside(dataDir, target)
+	if err != nil {
+		return err
+	}
+	if inside {
+		return ErrTargetInsideDataDir
+	}
+
+	f, err := os.Create(target)
+	if err != nil {
+		return err
+	}
+	if err := Create(dataDir, f); err != nil {
+		f.Close()
+		_ = os.Remove(target)
+		return err
+	}
+	return f.Close()
+}
+
+// Create writes all files of dataDir as gzip compressed tar archive to out.
+// Paths in the archive are relative to dataDir, so extracting it restores the data directory.
+func Create(dataDir string, out io.Writer) error {
+	gz := gzip.NewWriter(out)
+	tw := tar.NewWriter(gz)
+
+	err := filepath.WalkDir(dataDir, func(p string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		rel, err := filepath.Rel(dataDir, p)
+		if err != nil || rel == "." {
+			return err
+		}
+		// symlinks and other special files are not part of a wiki
+		if !d.IsDir() && !d.Type().IsRegular() {
+			return nil
+		}
+
+		info, err := d.Info()
+		if err != nil {
+			return err
+		}
+		header, err := tar.FileInfoHeader(info, "")
+		if err != nil {
+			return err
+		}
+		header.Name = filepath.ToSlash(rel)
+		if d.IsDir() {
+			header.Name += "/"
+		}
+		if err := tw.WriteHeader(header); err != nil {
+			return err
+		}
+		if d.IsDir() {
+			return nil
+		}
+
+		f, err := os.Open(p)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+		_, err = io.Copy(tw, f)
+		return err
+	})
+	if err != nil {
+		return err
+	}
+
+	if err := tw.Close(); err != nil {
+		return err
+	}
+	return gz.Close()
+}
+
+func isInside(dir, target string) (bool, error) {
+	absDir, err := filepath.Abs(dir)
+	if err != nil {
+		return false, err
+	}
+	absTarget, err := filepath.Abs(target)
+	if err != nil {
+		return false, err
+	}
+	rel, err := filepath.Rel(absDir, absTarget)
+	if err != nil {
+		return false, nil
+	}
+	return rel != ".." && !strings.HasPrefix(rel, ".."+string(filepath.Separator)), nil
+}
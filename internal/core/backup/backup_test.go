@@ -0,0 +1,71 @@
+package backup
+
+import (
+	"archive/tar"
+	"compress/gzip"
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestCreateFile_ArchivesDataDir(t *testing.T) {
+	dataDir := t.TempDir()
+	if err := os.MkdirAll(filepath.Join(dataDir, "root", "docs"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(dataDir, "root", "docs", "index.md"), []byte("# Docs"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(dataDir, "users.db"), []byte("db"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	target := filepath.Join(t.TempDir(), "backup.tar.gz")
+	if err := CreateFile(dataDir, target); err != nil {
+		t.Fatalf("CreateFile failed: %v", err)
+	}
+
+	f, err := os.Open(target)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	gz, err := gzip.NewReader(f)
+	if err != nil {
+		t.Fatalf("invalid gzip: %v", err)
+	}
+	files := map[string]string{}
+	tr := tar.NewReader(gz)
+	for {
+		header, err := tr.Next()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			t.Fatalf("invalid tar: %v", err)
+		}
+		data, _ := io.ReadAll(tr)
+		files[header.Name] = string(data)
+	}
+
+	if files["root/docs/index.md"] != "# Docs" || files["users.db"] != "db" {
+		t.Errorf("unexpected archive content: %v", files)
+	}
+	if _, ok := files["root/docs/"]; !ok {
+		t.Errorf("expected directory entry in archive: %v", files)
+	}
+}
+
+func TestCreateFile_RejectsTargetInsideDataDir(t *testing.T) {
+	dataDir := t.TempDir()
+
+	err := CreateFile(dataDir, filepath.Join(dataDir, "backup.tar.gz"))
+	if !errors.Is(err, ErrTargetInsideDataDir) {
+		t.Fatalf("expected ErrTargetInsideDataDir, got %v", err)
+	}
+	if _, err := os.Stat(filepath.Join(dataDir, "backup.tar.gz")); !os.IsNotExist(err) {
+		t.Errorf("expected no backup file to be created")
+	}
+}
@@ -0,0 +1,72 @@
+package badge
+
+import (
+	"fmt"
+	"html"
+	"unicode/utf8"
+)
+
+// Colors used for badges
+const (
+	ColorGreen  = "#4c1"
+	ColorBlue   = "#007ec6"
+	ColorYellow = "#dfb317"
+	ColorRed    = "#e05d44"
+	ColorGrey   = "#555"
+)
+
+// Badge is a small label/message pair which can be embedded in READMEs and dashboards
+type Badge struct {
+	Label   string
+	Message string
+	Color   string
+}
+
+// Endpoint is the JSON format of shields.io endpoint badges
+type Endpoint struct {
+	SchemaVersion int    `json:"schemaVersion"`
+	Label         string `json:"label"`
+	Message       string `json:"message"`
+	Color         string `json:"color"`
+}
+
+// ToEndpoint returns the badge in the shields.io endpoint format
+func (b Badge) ToEndpoint() Endpoint {
+	return Endpoint{
+		SchemaVersion: 1,
+		Label:         b.Label,
+		Message:       b.Message,
+		Color:         b.Color,
+	}
+}
+
+// charWidth is an approximation of the glyph width of Verdana 11px
+const charWidth = 7
+
+// SVG renders the badge as a flat SVG image
+func (b Badge) SVG() string {
+	labelWidth := textWidth(b.Label)
+	messageWidth := textWidth(b.Message)
+	width := labelWidth + messageWidth
+	label := html.EscapeString(b.Label)
+	message := html.EscapeString(b.Message)
+	color := b.Color
+	if color == "" {
+		color = ColorBlue
+	}
+
+	return fmt.Sprintf(`<svg xmlns="http://www.w3.org/2000/svg" width="%[1]d" height="20" role="img" aria-label="%[4]s: %[5]s">`+
+		`<title>%[4]s: %[5]s</title>`+
+		`<rect width="%[2]d" height="20" fill="%[7]s"/>`+
+		`<rect x="%[2]d" width="%[3]d" height="20" fill="%[6]s"/>`+
+		`<g fill="#fff" text-anchor="middle" font-family="Verdana,Geneva,DejaVu Sans,sans-serif" font-size="11">`+
+		`<text x="%[8]d" y="14">%[4]s</text>`+
+		`<text x="%[9]d" y="14">%[5]s</text>`+
+		`</g></svg>`,
+		width, labelWidth, messageWidth, label, message, html.EscapeString(color), ColorGrey,
+		labelWidth/2, labelWidth+messageWidth/2)
+}
+
+func textWidth(s string) int {
+	return utf8.RuneCountInString(s)*charWidth + 10
+}
@@ -0,0 +1,27 @@
+package badge
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestBadge_SVG(t *testing.T) {
+	svg := Badge{Label: "pages", Message: "<42>", Color: ColorGreen}.SVG()
+
+	if !strings.HasPrefix(svg, "<svg") || !strings.HasSuffix(svg, "</svg>") {
+		t.Fatalf("expected svg document, got %s", svg)
+	}
+	if !strings.Contains(svg, "&lt;42&gt;") {
+		t.Errorf("expected escaped message, got %s", svg)
+	}
+	if !strings.Contains(svg, ColorGreen) {
+		t.Errorf("expected color in svg, got %s", svg)
+	}
+}
+
+func TestBadge_ToEndpoint(t *testing.T) {
+	endpoint := Badge{Label: "search", Message: "ready", Color: ColorGreen}.ToEndpoint()
+	if endpoint.SchemaVersion != 1 || endpoint.Label != "search" || endpoint.Message != "ready" {
+		t.Errorf("unexpected endpoint: %+v", endpoint)
+	}
+}
@@ -0,0 +1,196 @@
+// Package config reads the configuration file of the server, an alternative to the flags and
+// environment variables. The file is YAML or TOML and uses the names of the flags as keys;
+// the settings section holds runtime settings like those of PUT /api/admin/settings:
+//
+//	port: 8080
+//	data-dir: /var/lib/leafwiki
+//	log-level: info
+//	trusted-proxies: [10.0.0.5]
+//	settings:
+//	  rateLimit:
+//	    perIP: {requestsPerMinute: 30, burst: 10}
+//	  webhook:
+//	    url: https://example.com/hook
+package config
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/Gomez12/wiki/internal/core/settings"
+	"github.com/goccy/go-yaml"
+	"github.com/pelletier/go-toml/v2"
+)
+
+// SettingsKey is the section of the runtime settings
+const SettingsKey = "settings"
+
+// File is a parsed configuration file
+type File struct {
+	Path string
+	// Options are the values of the flags by name, lists are joined with commas
+	Options map[string]string
+	// Settings is the settings section as JSON object, nil without one
+	Settings []byte
+}
+
+// Get returns the value of the option, def if the file doesn't set it
+func (f *File) Get(name, def string) string {
+	if f == nil {
+		return def
+	}
+	if value, ok := f.Options[name]; ok {
+		return value
+	}
+	return def
+}
+
+// Load reads and validates the file. Its options have to be in known, the settings section
+// has to contain known settings of the right type. The errors name the file and the option.
+func Load(path string, known []string) (*File, error) {
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	raw := map[string]any{}
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".yaml", ".yml":
+		err = yaml.Unmarshal(content, &raw)
+	case ".toml":
+		err = toml.Unmarshal(content, &raw)
+	default:
+		return nil, fmt.Errorf("%s: unsupported format, use .yaml, .yml or .toml", path)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+
+	file := &File{Path: path, Options: map[string]string{}}
+	for _, key := range sortedKeys(raw) {
+		value := raw[key]
+		if key == SettingsKey {
+			if file.Settings, err = parseSettings(value); err != nil {
+				return nil, fmt.Errorf("%s: %s: %w", path, SettingsKey, err)
+			}
+			continue
+		}
+		if !slices.Contains(known, key) {
+			msg := fmt.Sprintf("%s: unknown option %q", path, key)
+			if suggestion := closest(key, append(slices.Clone(known), SettingsKey)); suggestion != "" {
+				msg += fmt.Sprintf(", did you mean %q?", suggestion)
+			}
+			return nil, errors.New(msg)
+		}
+		if file.Options[key], err = optionValue(value); err != nil {
+			return nil, fmt.Errorf("%s: option %q %w", path, key, err)
+		}
+	}
+	return file, nil
+}
+
+// optionValue turns the value of an option into the string a flag would have
+func optionValue(value any) (string, error) {
+	switch v := value.(type) {
+	case nil:
+		return "", nil
+	case string:
+		return v, nil
+	case bool:
+		return strconv.FormatBool(v), nil
+	case int, int8, int16, int32, int64, uint, uint8, uint16, uint32, uint64, float32, float64:
+		return fmt.Sprint(v), nil
+	case []any:
+		items := make([]string, 0, len(v))
+		for _, item := range v {
+			s, err := optionValue(item)
+			if err != nil || strings.Contains(s, ",") {
+				return "", errors.New("must be a list of values")
+			}
+			items = append(items, s)
+		}
+		return strings.Join(items, ","), nil
+	default:
+		return "", fmt.Errorf("must be a value or a list of values, not %T", value)
+	}
+}
+
+// parseSettings converts the settings section to JSON and checks it against the settings
+func parseSettings(value any) ([]byte, error) {
+	if _, ok := value.(map[string]any); !ok {
+		return nil, errors.New("must be a section of settings")
+	}
+	data, err := json.Marshal(value)
+	if err != nil {
+		return nil, err
+	}
+
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.DisallowUnknownFields()
+	var s settings.Settings
+	if err := decoder.Decode(&s); err != nil {
+		return nil, describeJSONError(err)
+	}
+	return data, nil
+}
+
+// describeJSONError turns the errors of the decoder into messages about the settings
+func describeJSONError(err error) error {
+	var typeErr *json.UnmarshalTypeError
+	if errors.As(err, &typeErr) {
+		return fmt.Errorf("%q must be of type %s", typeErr.Field, typeErr.Type)
+	}
+	if name, ok := strings.CutPrefix(err.Error(), "json: unknown field "); ok {
+		return fmt.Errorf("unknown setting %s", name)
+	}
+	return err
+}
+
+// closest returns the candidate most similar to name, empty if none is similar
+func closest(name string, candidates []string) string {
+	best, bestDistance := "", len(name)/2+1
+	for _, candidate := range candidates {
+		if d := distance(name, candidate); d < bestDistance {
+			best, bestDistance = candidate, d
+		}
+	}
+	return best
+}
+
+// distance is the Levenshtein distance of a and b
+func distance(a, b string) int {
+	prev := make([]int, len(b)+1)
+	for j := range prev {
+		prev[j] = j
+	}
+	for i := 1; i <= len(a); i++ {
+		cur := make([]int, len(b)+1)
+		cur[0] = i
+		for j := 1; j <= len(b); j++ {
+			cost := 1
+			if a[i-1] == b[j-1] {
+				cost = 0
+			}
+			cur[j] = min(prev[j]+1, cur[j-1]+1, prev[j-1]+cost)
+		}
+		prev = cur
+	}
+	return prev[len(b)]
+}
+
+func sortedKeys(m map[string]any) []string {
+	keys := make([]string, 0, len(m))
+	for key := range m {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
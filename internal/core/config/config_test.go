@@ -0,0 +1,100 @@
+package config
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+var knownOptions = []string{"port", "data-dir", "log-level", "public-access", "trusted-proxies"}
+
+func writeFile(t *testing.T, name, content string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), name)
+	if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestLoad_YAML(t *testing.T) {
+	path := writeFile(t, "leafwiki.yaml", `
+port: 9090
+data-dir: /srv/wiki
+public-access: true
+trusted-proxies: [10.0.0.5, 192.168.0.0/16]
+settings:
+  rateLimit:
+    perIP: {requestsPerMinute: 60, burst: 20}
+`)
+	file, err := Load(path, knownOptions)
+	if err != nil {
+		t.Fatalf("Load failed: %v", err)
+	}
+
+	want := map[string]string{"port": "9090", "data-dir": "/srv/wiki", "public-access": "true", "trusted-proxies": "10.0.0.5,192.168.0.0/16"}
+	for name, value := range want {
+		if got := file.Get(name, ""); got != value {
+			t.Errorf("%s = %q, want %q", name, got, value)
+		}
+	}
+	if got := file.Get("log-level", "info"); got != "info" {
+		t.Errorf("Expected the default for a missing option, got %q", got)
+	}
+
+	var s map[string]map[string]map[string]int
+	if err := json.Unmarshal(file.Settings, &s); err != nil || s["rateLimit"]["perIP"]["burst"] != 20 {
+		t.Errorf("Unexpected settings %s: %v", file.Settings, err)
+	}
+}
+
+func TestLoad_TOML(t *testing.T) {
+	path := writeFile(t, "leafwiki.toml", `
+port = 9090
+log-level = "debug"
+
+[settings.webhook]
+url = "https://example.com/hook"
+events = ["page.created"]
+`)
+	file, err := Load(path, knownOptions)
+	if err != nil {
+		t.Fatalf("Load failed: %v", err)
+	}
+	if file.Get("port", "") != "9090" || file.Get("log-level", "") != "debug" {
+		t.Errorf("Unexpected options %v", file.Options)
+	}
+	if !strings.Contains(string(file.Settings), `"url":"https://example.com/hook"`) {
+		t.Errorf("Unexpected settings %s", file.Settings)
+	}
+}
+
+func TestLoad_Errors(t *testing.T) {
+	tests := []struct {
+		name, file, content, want string
+	}{
+		{"unknown option", "a.yaml", "prot: 8080\n", `unknown option "prot", did you mean "port"?`},
+		{"nested option", "b.yaml", "port:\n  number: 8080\n", `option "port" must be a value`},
+		{"unknown setting", "c.yaml", "settings:\n  rateLimits: {}\n", `settings: unknown setting "rateLimits"`},
+		{"setting type", "d.yaml", "settings:\n  maxUploadSize: big\n", `"maxUploadSize" must be of type int64`},
+		{"syntax", "e.toml", "port = \n", "e.toml"},
+		{"format", "f.json", "{}", "unsupported format"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := Load(writeFile(t, tt.file, tt.content), knownOptions)
+			if err == nil || !strings.Contains(err.Error(), tt.want) {
+				t.Errorf("Expected an error containing %q, got %v", tt.want, err)
+			}
+		})
+	}
+}
+
+func TestFile_GetWithoutFile(t *testing.T) {
+	var file *File
+	if got := file.Get("port", "8080"); got != "8080" {
+		t.Errorf("Expected the default without a file, got %q", got)
+	}
+}
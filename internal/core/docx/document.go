@@ -0,0 +1,374 @@
+package docx
+
+import (
+	"archive/zip"
+	"bytes"
+	"encoding/xml"
+	"fmt"
+	"image"
+	_ "image/gif"
+	_ "image/jpeg"
+	_ "image/png"
+	"io"
+	"strconv"
+	"strings"
+
+	"github.com/Gomez12/wiki/internal/core/frontmatter"
+	"github.com/russross/blackfriday/v2"
+)
+
+const (
+	// emuPerPixel converts pixels at 96 dpi to English Metric Units
+	emuPerPixel = 9525
+	// maxImageWidth is the width between the page margins in EMU
+	maxImageWidth = 6120000
+	listIndent    = 360
+)
+
+// ImageLoader returns the content of the image a Markdown link points to
+type ImageLoader func(src string) ([]byte, bool)
+
+// Render writes the Markdown as DOCX document. Images are embedded when images can load them,
+// otherwise their alt text is shown.
+func Render(out io.Writer, title, markdown string, images ImageLoader) error {
+	_, body, _ := frontmatter.Split(markdown)
+	root := blackfriday.New(blackfriday.WithExtensions(blackfriday.CommonExtensions)).Parse([]byte(body))
+
+	d := &document{images: images}
+	// pages usually start with their title as heading, so it is not repeated
+	if first := root.FirstChild; first == nil || first.Type != blackfriday.Heading || first.Level != 1 {
+		d.paragraph("Title", 0, "", []run{{text: title}})
+	}
+	d.blocks(root, 0)
+
+	zw := zip.NewWriter(out)
+	files := []struct{ name, content string }{
+		{"[Content_Types].xml", contentTypesXML},
+		{"_rels/.rels", packageRelsXML},
+		{"word/styles.xml", stylesXML},
+		{"word/_rels/document.xml.rels", d.relationships()},
+		{"word/document.xml", documentHeader + d.body.String() + documentFooter},
+	}
+	for _, f := range files {
+		if err := writeZipFile(zw, f.name, []byte(f.content)); err != nil {
+			return err
+		}
+	}
+	for _, m := range d.media {
+		if err := writeZipFile(zw, "word/media/"+m.name, m.data); err != nil {
+			return err
+		}
+	}
+	return zw.Close()
+}
+
+func writeZipFile(zw *zip.Writer, name string, data []byte) error {
+	f, err := zw.Create(name)
+	if err != nil {
+		return err
+	}
+	_, err = f.Write(data)
+	return err
+}
+
+type relationship struct {
+	id, kind, target string
+	external         bool
+}
+
+type media struct {
+	name string
+	data []byte
+}
+
+type run struct {
+	text   string
+	bold   bool
+	italic bool
+	strike bool
+	code   bool
+	// link is the relationship of an external hyperlink
+	link  string
+	image string
+	br    bool
+}
+
+type document struct {
+	images ImageLoader
+	body   bytes.Buffer
+	rels   []relationship
+	media  []media
+	// drawings counts the embedded images, their ids must be unique
+	drawings int
+}
+
+func (d *document) addRelationship(kind, target string, external bool) string {
+	id := "rId" + strconv.Itoa(len(d.rels)+2) // rId1 is the style sheet
+	d.rels = append(d.rels, relationship{id: id, kind: kind, target: target, external: external})
+	return id
+}
+
+func (d *document) relationships() string {
+	var b strings.Builder
+	b.WriteString(`<?xml version="1.0" encoding="UTF-8" standalone="yes"?>` + "\n")
+	b.WriteString(`<Relationships xmlns="http://schemas.openxmlformats.org/package/2006/relationships">` + "\n")
+	fmt.Fprintf(&b, `<Relationship Id="rId1" Type="%s" Target="styles.xml"/>`+"\n", relStyles)
+	for _, r := range d.rels {
+		mode := ""
+		if r.external {
+			mode = ` TargetMode="External"`
+		}
+		fmt.Fprintf(&b, `<Relationship Id="%s" Type="%s" Target="%s"%s/>`+"\n", r.id, r.kind, escape(r.target), mode)
+	}
+	b.WriteString(`</Relationships>`)
+	return b.String()
+}
+
+func (d *document) blocks(parent *blackfriday.Node, indent int) {
+	for n := parent.FirstChild; n != nil; n = n.Next {
+		d.block(n, indent)
+	}
+}
+
+func (d *document) block(n *blackfriday.Node, indent int) {
+	switch n.Type {
+	case blackfriday.Heading:
+		level := min(n.Level, 4)
+		d.paragraph("Heading"+strconv.Itoa(level), indent, "", d.inlineRuns(n, run{}))
+	case blackfriday.Paragraph:
+		d.paragraph("", indent, "", d.inlineRuns(n, run{}))
+	case blackfriday.List:
+		number := 1
+		for item := n.FirstChild; item != nil; item = item.Next {
+			marker := "•"
+			if n.ListFlags&blackfriday.ListTypeOrdered != 0 {
+				marker = strconv.Itoa(number) + "."
+				number++
+			}
+			d.listItem(item, indent+listIndent, marker)
+		}
+	case blackfriday.CodeBlock:
+		var runs []run
+		for i, line := range strings.Split(strings.TrimSuffix(string(n.Literal), "\n"), "\n") {
+			if i > 0 {
+				runs = append(runs, run{br: true})
+			}
+			runs = append(runs, run{text: line})
+		}
+		d.paragraph("Code", indent, "", runs)
+	case blackfriday.BlockQuote:
+		for c := n.FirstChild; c != nil; c = c.Next {
+			if c.Type == blackfriday.Paragraph {
+				d.paragraph("Quote", indent, "", d.inlineRuns(c, run{}))
+			} else {
+				d.block(c, indent)
+			}
+		}
+	case blackfriday.HorizontalRule:
+		d.body.WriteString(`<w:p><w:pPr><w:pBdr><w:bottom w:val="single" w:sz="6" w:space="1" w:color="9CA3AF"/></w:pBdr></w:pPr></w:p>` + "\n")
+	case blackfriday.Table:
+		d.table(n)
+	case blackfriday.HTMLBlock:
+		// raw HTML can't be rendered
+	default:
+		d.blocks(n, indent)
+	}
+}
+
+// listItem renders the first paragraph of the item with its marker and the other blocks indented below
+func (d *document) listItem(item *blackfriday.Node, indent int, marker string) {
+	for c := item.FirstChild; c != nil; c = c.Next {
+		if marker != "" && c.Type == blackfriday.Paragraph {
+			d.paragraph("", indent, marker, d.inlineRuns(c, run{}))
+			marker = ""
+			continue
+		}
+		d.block(c, indent)
+	}
+	if marker != "" {
+		d.paragraph("", indent, marker, nil)
+	}
+}
+
+func (d *document) table(n *blackfriday.Node) {
+	d.body.WriteString(`<w:tbl><w:tblPr><w:tblW w:w="5000" w:type="pct"/><w:tblBorders>`)
+	for _, side := range []string{"top", "left", "bottom", "right", "insideH", "insideV"} {
+		fmt.Fprintf(&d.body, `<w:%s w:val="single" w:sz="4" w:space="0" w:color="D1D5DB"/>`, side)
+	}
+	d.body.WriteString(`</w:tblBorders></w:tblPr>` + "\n")
+	n.Walk(func(node *blackfriday.Node, entering bool) blackfriday.WalkStatus {
+		if !entering || node.Type != blackfriday.TableRow {
+			return blackfriday.GoToNext
+		}
+		d.body.WriteString("<w:tr>")
+		for cell := node.FirstChild; cell != nil; cell = cell.Next {
+			d.body.WriteString("<w:tc>")
+			d.paragraph("", 0, "", d.inlineRuns(cell, run{bold: cell.IsHeader}))
+			d.body.WriteString("</w:tc>")
+		}
+		d.body.WriteString("</w:tr>\n")
+		return blackfriday.SkipChildren
+	})
+	d.body.WriteString("</w:tbl>\n")
+	// Word merges a table with a directly following one
+	d.body.WriteString("<w:p/>\n")
+}
+
+// paragraph writes a paragraph, a marker is placed in the hanging indent
+func (d *document) paragraph(style string, indent int, marker string, runs []run) {
+	d.body.WriteString("<w:p>")
+	if style != "" || indent > 0 {
+		d.body.WriteString("<w:pPr>")
+		if style != "" {
+			fmt.Fprintf(&d.body, `<w:pStyle w:val="%s"/>`, style)
+		}
+		if indent > 0 {
+			hanging := ""
+			if marker != "" {
+				hanging = fmt.Sprintf(` w:hanging="%d"`, listIndent)
+			}
+			fmt.Fprintf(&d.body, `<w:ind w:left="%d"%s/>`, indent, hanging)
+		}
+		d.body.WriteString("</w:pPr>")
+	}
+	if marker != "" {
+		d.writeRun(run{text: marker})
+		d.body.WriteString("<w:r><w:tab/></w:r>")
+	}
+	for _, r := range runs {
+		if r.link != "" {
+			fmt.Fprintf(&d.body, `<w:hyperlink r:id="%s">`, r.link)
+			d.writeRun(r)
+			d.body.WriteString("</w:hyperlink>")
+			continue
+		}
+		d.writeRun(r)
+	}
+	d.body.WriteString("</w:p>\n")
+}
+
+func (d *document) writeRun(r run) {
+	if r.image != "" {
+		d.body.WriteString(r.image)
+		return
+	}
+	d.body.WriteString("<w:r>")
+	var props strings.Builder
+	if r.link != "" {
+		props.WriteString(`<w:rStyle w:val="Hyperlink"/>`)
+	}
+	if r.code {
+		props.WriteString(`<w:rFonts w:ascii="Courier New" w:hAnsi="Courier New" w:cs="Courier New"/>`)
+	}
+	if r.bold {
+		props.WriteString("<w:b/>")
+	}
+	if r.italic {
+		props.WriteString("<w:i/>")
+	}
+	if r.strike {
+		props.WriteString("<w:strike/>")
+	}
+	if props.Len() > 0 {
+		d.body.WriteString("<w:rPr>" + props.String() + "</w:rPr>")
+	}
+	if r.br {
+		d.body.WriteString("<w:br/>")
+	} else {
+		d.body.WriteString(`<w:t xml:space="preserve">` + escape(strings.ReplaceAll(r.text, "\t", "    ")) + "</w:t>")
+	}
+	d.body.WriteString("</w:r>")
+}
+
+// inlineRuns flattens the inline children of n, format carries the inherited formatting
+func (d *document) inlineRuns(n *blackfriday.Node, format run) []run {
+	var runs []run
+	for c := n.FirstChild; c != nil; c = c.Next {
+		r := format
+		switch c.Type {
+		case blackfriday.Text:
+			r.text = string(c.Literal)
+			runs = append(runs, r)
+		case blackfriday.Code:
+			r.text, r.code = string(c.Literal), true
+			runs = append(runs, r)
+		case blackfriday.Softbreak:
+			r.text = " "
+			runs = append(runs, r)
+		case blackfriday.Hardbreak:
+			r.br = true
+			runs = append(runs, r)
+		case blackfriday.Emph:
+			r.italic = true
+			runs = append(runs, d.inlineRuns(c, r)...)
+		case blackfriday.Strong:
+			r.bold = true
+			runs = append(runs, d.inlineRuns(c, r)...)
+		case blackfriday.Del:
+			r.strike = true
+			runs = append(runs, d.inlineRuns(c, r)...)
+		case blackfriday.Link:
+			dest := string(c.LinkData.Destination)
+			if strings.HasPrefix(dest, "http://") || strings.HasPrefix(dest, "https://") || strings.HasPrefix(dest, "mailto:") {
+				r.link = d.addRelationship(relHyperlink, dest, true)
+			}
+			runs = append(runs, d.inlineRuns(c, r)...)
+		case blackfriday.Image:
+			alt := ""
+			for _, a := range d.inlineRuns(c, run{}) {
+				alt += a.text
+			}
+			if drawing, ok := d.embedImage(string(c.LinkData.Destination), alt); ok {
+				runs = append(runs, run{image: drawing})
+			} else {
+				r.text, r.italic = "["+alt+"]", true
+				runs = append(runs, r)
+			}
+		case blackfriday.HTMLSpan:
+			// raw HTML can't be rendered
+		default:
+			runs = append(runs, d.inlineRuns(c, r)...)
+		}
+	}
+	return runs
+}
+
+// embedImage adds the image to the package and returns the run showing it
+func (d *document) embedImage(src, alt string) (string, bool) {
+	if d.images == nil {
+		return "", false
+	}
+	data, ok := d.images(src)
+	if !ok {
+		return "", false
+	}
+	cfg, format, err := image.DecodeConfig(bytes.NewReader(data))
+	if err != nil || cfg.Width == 0 || cfg.Height == 0 {
+		return "", false
+	}
+
+	d.drawings++
+	name := fmt.Sprintf("image%d.%s", d.drawings, format)
+	d.media = append(d.media, media{name: name, data: data})
+	id := d.addRelationship(relImage, "media/"+name, false)
+
+	cx, cy := cfg.Width*emuPerPixel, cfg.Height*emuPerPixel
+	if cx > maxImageWidth {
+		cy = int(int64(cy) * maxImageWidth / int64(cx))
+		cx = maxImageWidth
+	}
+	return fmt.Sprintf(`<w:r><w:drawing><wp:inline distT="0" distB="0" distL="0" distR="0">`+
+		`<wp:extent cx="%[1]d" cy="%[2]d"/><wp:docPr id="%[3]d" name="Picture %[3]d" descr="%[4]s"/>`+
+		`<a:graphic><a:graphicData uri="http://schemas.openxmlformats.org/drawingml/2006/picture">`+
+		`<pic:pic><pic:nvPicPr><pic:cNvPr id="%[3]d" name="%[5]s"/><pic:cNvPicPr/></pic:nvPicPr>`+
+		`<pic:blipFill><a:blip r:embed="%[6]s"/><a:stretch><a:fillRect/></a:stretch></pic:blipFill>`+
+		`<pic:spPr><a:xfrm><a:off x="0" y="0"/><a:ext cx="%[1]d" cy="%[2]d"/></a:xfrm><a:prstGeom prst="rect"><a:avLst/></a:prstGeom></pic:spPr>`+
+		`</pic:pic></a:graphicData></a:graphic></wp:inline></w:drawing></w:r>`,
+		cx, cy, d.drawings, escape(alt), name, id), true
+}
+
+func escape(s string) string {
+	var b strings.Builder
+	_ = xml.EscapeText(&b, []byte(s))
+	return b.String()
+}
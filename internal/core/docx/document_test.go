@@ -0,0 +1,90 @@
+package docx
+
+import (
+	"archive/zip"
+	"bytes"
+	"encoding/xml"
+	"image"
+	"image/png"
+	"io"
+	"strings"
+	"testing"
+)
+
+func readZip(t *testing.T, data []byte) map[string]string {
+	t.Helper()
+	zr, err := zip.NewReader(bytes.NewReader(data), int64(len(data)))
+	if err != nil {
+		t.Fatalf("invalid zip: %v", err)
+	}
+	files := map[string]string{}
+	for _, f := range zr.File {
+		rc, err := f.Open()
+		if err != nil {
+			t.Fatalf("open %s: %v", f.Name, err)
+		}
+		content, _ := io.ReadAll(rc)
+		rc.Close()
+		files[f.Name] = string(content)
+	}
+	return files
+}
+
+func checkWellFormed(t *testing.T, name, content string) {
+	t.Helper()
+	dec := xml.NewDecoder(strings.NewReader(content))
+	for {
+		if _, err := dec.Token(); err == io.EOF {
+			return
+		} else if err != nil {
+			t.Fatalf("%s is not well-formed: %v", name, err)
+		}
+	}
+}
+
+func TestRender(t *testing.T) {
+	var img bytes.Buffer
+	_ = png.Encode(&img, image.NewRGBA(image.Rect(0, 0, 2000, 1000)))
+
+	markdown := "---\nstatus: draft\n---\nIntro with **bold** & `code` and a [link](https://example.com).\n\n" +
+		"![Diagram](assets/diagram.png) ![Missing](assets/missing.png)\n\n" +
+		"| Name | Value |\n|------|-------|\n| a    | 1 < 2 |\n\n" +
+		"```\nline one\nline two\n```\n\n" +
+		"1. first\n2. second\n"
+
+	var buf bytes.Buffer
+	err := Render(&buf, "Guide", markdown, func(src string) ([]byte, bool) {
+		if src == "assets/diagram.png" {
+			return img.Bytes(), true
+		}
+		return nil, false
+	})
+	if err != nil {
+		t.Fatalf("Render failed: %v", err)
+	}
+
+	files := readZip(t, buf.Bytes())
+	for _, name := range []string{"[Content_Types].xml", "_rels/.rels", "word/styles.xml", "word/_rels/document.xml.rels", "word/document.xml"} {
+		content, ok := files[name]
+		if !ok {
+			t.Fatalf("missing part %s", name)
+		}
+		checkWellFormed(t, name, content)
+	}
+	if _, ok := files["word/media/image1.png"]; !ok {
+		t.Errorf("expected the image to be embedded")
+	}
+
+	doc := files["word/document.xml"]
+	for _, want := range []string{`<w:pStyle w:val="Title"/>`, ">Guide<", "&amp;", "1 &lt; 2", "<w:tbl>", "line one", "<w:br/>", "[Missing]", "2.", `cx="6120000" cy="3060000"`} {
+		if !strings.Contains(doc, want) {
+			t.Errorf("expected %q in document", want)
+		}
+	}
+	if strings.Contains(doc, "status: draft") {
+		t.Errorf("expected frontmatter to be skipped")
+	}
+	if rels := files["word/_rels/document.xml.rels"]; !strings.Contains(rels, `Target="https://example.com" TargetMode="External"`) {
+		t.Errorf("expected an external hyperlink relationship, got %s", rels)
+	}
+}
@@ -0,0 +1,146 @@
+package drafts
+
+import (
+	"database/sql"
+	"errors"
+	"path"
+	"time"
+
+	_ "modernc.org/sqlite"
+)
+
+type DraftStore struct {
+	storageDir string
+	filename   string
+	db         *sql.DB
+}
+
+func NewDraftStore(storageDir string) (*DraftStore, error) {
+	d := &DraftStore{
+		storageDir: storageDir,
+		filename:   "drafts.db",
+	}
+
+	err := d.Connect()
+	if err != nil {
+		return nil, err
+	}
+
+	return d, d.ensureSchema()
+}
+
+func (d *DraftStore) Connect() error {
+	// Database is already open and connected
+	if d.db != nil {
+		return nil
+	}
+	db, err := sql.Open("sqlite", path.Join(d.storageDir, d.filename))
+	if err != nil {
+		return err
+	}
+	d.db = db
+	return nil
+}
+
+func (d *DraftStore) ensureSchema() error {
+	err := d.Connect()
+	if err != nil {
+		return err
+	}
+	_, err = d.db.Exec(`
+		CREATE TABLE IF NOT EXISTS drafts (
+			user_id TEXT NOT NULL,
+			page_id TEXT NOT NULL,
+			title TEXT NOT NULL,
+			content TEXT NOT NULL,
+			saved_at INTEGER NOT NULL,
+			PRIMARY KEY (user_id, page_id)
+		);
+		CREATE INDEX IF NOT EXISTS idx_drafts_page ON drafts(page_id);
+	`)
+	return err
+}
+
+func (d *DraftStore) Close() error {
+	if d.db != nil {
+		err := d.db.Close()
+		if err != nil {
+			return err
+		}
+		d.db = nil
+	}
+	return nil
+}
+
+// Save replaces the draft of a user for a page
+func (d *DraftStore) Save(userID, pageID, title, content string) (*Draft, error) {
+	err := d.Connect()
+	if err != nil {
+		return nil, err
+	}
+
+	draft := &Draft{PageID: pageID, Title: title, Content: content, SavedAt: time.Now().UTC()}
+	_, err = d.db.Exec(`
+		INSERT INTO drafts (user_id, page_id, title, content, saved_at)
+		VALUES (?, ?, ?, ?, ?)
+		ON CONFLICT(user_id, page_id) DO UPDATE SET title = excluded.title, content = excluded.content, saved_at = excluded.saved_at;
+	`, userID, pageID, title, content, draft.SavedAt.UnixNano())
+	if err != nil {
+		return nil, err
+	}
+	return draft, nil
+}
+
+// Get returns the draft of a user for a page
+func (d *DraftStore) Get(userID, pageID string) (*Draft, error) {
+	err := d.Connect()
+	if err != nil {
+		return nil, err
+	}
+
+	draft := &Draft{PageID: pageID}
+	var savedAt int64
+	err = d.db.QueryRow(`
+		SELECT title, content, saved_at
+		FROM drafts
+		WHERE user_id = ? AND page_id = ?;
+	`, userID, pageID).Scan(&draft.Title, &draft.Content, &savedAt)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrDraftNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	draft.SavedAt = time.Unix(0, savedAt).UTC()
+	return draft, nil
+}
+
+// Delete removes the draft of a user for a page. A missing draft is no error.
+func (d *DraftStore) Delete(userID, pageID string) error {
+	err := d.Connect()
+	if err != nil {
+		return err
+	}
+	_, err = d.db.Exec(`DELETE FROM drafts WHERE user_id = ? AND page_id = ?;`, userID, pageID)
+	return err
+}
+
+// DeleteForPage removes the drafts of all users for a page
+func (d *DraftStore) DeleteForPage(pageID string) error {
+	err := d.Connect()
+	if err != nil {
+		return err
+	}
+	_, err = d.db.Exec(`DELETE FROM drafts WHERE page_id = ?;`, pageID)
+	return err
+}
+
+// DeleteForUser removes all drafts of a user
+func (d *DraftStore) DeleteForUser(userID string) error {
+	err := d.Connect()
+	if err != nil {
+		return err
+	}
+	_, err = d.db.Exec(`DELETE FROM drafts WHERE user_id = ?;`, userID)
+	return err
+}
@@ -0,0 +1,82 @@
+package drafts
+
+import (
+	"errors"
+	"testing"
+)
+
+func setupTestDraftStore(t *testing.T) *DraftStore {
+	t.Helper()
+	store, err := NewDraftStore(t.TempDir())
+	if err != nil {
+		t.Fatalf("Failed to create draft store: %v", err)
+	}
+	return store
+}
+
+func TestDraftStore_SaveGetDelete(t *testing.T) {
+	store := setupTestDraftStore(t)
+	defer store.Close()
+
+	if _, err := store.Get("u1", "a"); !errors.Is(err, ErrDraftNotFound) {
+		t.Fatalf("Expected ErrDraftNotFound, got %v", err)
+	}
+
+	if _, err := store.Save("u1", "a", "Title", "first"); err != nil {
+		t.Fatalf("Failed to save draft: %v", err)
+	}
+	saved, err := store.Save("u1", "a", "New Title", "second")
+	if err != nil {
+		t.Fatalf("Failed to save draft: %v", err)
+	}
+
+	draft, err := store.Get("u1", "a")
+	if err != nil {
+		t.Fatalf("Failed to get draft: %v", err)
+	}
+	if draft.Title != "New Title" || draft.Content != "second" || !draft.SavedAt.Equal(saved.SavedAt) {
+		t.Errorf("Expected the last draft, got %+v", draft)
+	}
+
+	// Drafts are per user
+	if _, err := store.Get("u2", "a"); !errors.Is(err, ErrDraftNotFound) {
+		t.Errorf("Expected no draft for other user, got %v", err)
+	}
+
+	if err := store.Delete("u1", "a"); err != nil {
+		t.Fatalf("Failed to delete draft: %v", err)
+	}
+	if _, err := store.Get("u1", "a"); !errors.Is(err, ErrDraftNotFound) {
+		t.Errorf("Expected the draft to be deleted, got %v", err)
+	}
+	if err := store.Delete("u1", "a"); err != nil {
+		t.Errorf("Expected no error for a missing draft, got %v", err)
+	}
+}
+
+func TestDraftStore_DeleteForPageAndUser(t *testing.T) {
+	store := setupTestDraftStore(t)
+	defer store.Close()
+
+	for _, d := range []struct{ user, page string }{{"u1", "a"}, {"u2", "a"}, {"u1", "b"}, {"u2", "b"}} {
+		if _, err := store.Save(d.user, d.page, "Title", "content"); err != nil {
+			t.Fatalf("Failed to save draft: %v", err)
+		}
+	}
+
+	if err := store.DeleteForPage("a"); err != nil {
+		t.Fatalf("Failed to delete drafts of page: %v", err)
+	}
+	if err := store.DeleteForUser("u1"); err != nil {
+		t.Fatalf("Failed to delete drafts of user: %v", err)
+	}
+
+	for _, d := range []struct{ user, page string }{{"u1", "a"}, {"u2", "a"}, {"u1", "b"}} {
+		if _, err := store.Get(d.user, d.page); !errors.Is(err, ErrDraftNotFound) {
+			t.Errorf("Expected the draft of %s for %s to be deleted, got %v", d.user, d.page, err)
+		}
+	}
+	if _, err := store.Get("u2", "b"); err != nil {
+		t.Errorf("Expected the draft of u2 for b to be kept, got %v", err)
+	}
+}
@@ -0,0 +1,5 @@
+package drafts
+
+import "errors"
+
+var ErrDraftNotFound = errors.New("draft not found")
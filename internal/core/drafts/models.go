@@ -0,0 +1,12 @@
+package drafts
+
+import "time"
+
+// Draft is the unsaved state of the editor of a user, autosaved while a page is edited.
+// It is kept apart from the Markdown file until the page is saved.
+type Draft struct {
+	PageID  string    `json:"pageId"`
+	Title   string    `json:"title"`
+	Content string    `json:"content"`
+	SavedAt time.Time `json:"savedAt"`
+}
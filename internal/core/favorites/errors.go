@@ -0,0 +1,5 @@
+package favorites
+
+import "errors"
+
+var ErrFavoriteNotFound = errors.New("favorite not found")
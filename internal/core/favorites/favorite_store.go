@@ -0,0 +1,173 @@
+package favorites
+
+import (
+	"database/sql"
+	"path"
+	"time"
+
+	_ "modernc.org/sqlite"
+)
+
+type FavoriteStore struct {
+	storageDir string
+	filename   string
+	db         *sql.DB
+}
+
+func NewFavoriteStore(storageDir string) (*FavoriteStore, error) {
+	f := &FavoriteStore{
+		storageDir: storageDir,
+		filename:   "favorites.db",
+	}
+
+	err := f.Connect()
+	if err != nil {
+		return nil, err
+	}
+
+	return f, f.ensureSchema()
+}
+
+func (f *FavoriteStore) Connect() error {
+	// Database is already open and connected
+	if f.db != nil {
+		return nil
+	}
+	db, err := sql.Open("sqlite", path.Join(f.storageDir, f.filename))
+	if err != nil {
+		return err
+	}
+	f.db = db
+	return nil
+}
+
+func (f *FavoriteStore) ensureSchema() error {
+	err := f.Connect()
+	if err != nil {
+		return err
+	}
+	_, err = f.db.Exec(`
+		CREATE TABLE IF NOT EXISTS favorites (
+			user_id TEXT NOT NULL,
+			page_id TEXT NOT NULL,
+			created_at INTEGER NOT NULL,
+			PRIMARY KEY (user_id, page_id)
+		);
+		CREATE INDEX IF NOT EXISTS idx_favorites_page ON favorites(page_id);
+	`)
+	return err
+}
+
+func (f *FavoriteStore) Close() error {
+	if f.db != nil {
+		err := f.db.Close()
+		if err != nil {
+			return err
+		}
+		f.db = nil
+	}
+	return nil
+}
+
+// Add stars a page for a user. Starring a page twice keeps the first date.
+func (f *FavoriteStore) Add(userID, pageID string) (*Favorite, error) {
+	err := f.Connect()
+	if err != nil {
+		return nil, err
+	}
+
+	_, err = f.db.Exec(`
+		INSERT INTO favorites (user_id, page_id, created_at)
+		VALUES (?, ?, ?)
+		ON CONFLICT(user_id, page_id) DO NOTHING;
+	`, userID, pageID, time.Now().UTC().UnixNano())
+	if err != nil {
+		return nil, err
+	}
+
+	row := f.db.QueryRow(`
+		SELECT page_id, created_at
+		FROM favorites
+		WHERE user_id = ? AND page_id = ?;
+	`, userID, pageID)
+	return scanFavorite(row)
+}
+
+// Remove unstars a page for a user
+func (f *FavoriteStore) Remove(userID, pageID string) error {
+	err := f.Connect()
+	if err != nil {
+		return err
+	}
+	res, err := f.db.Exec(`DELETE FROM favorites WHERE user_id = ? AND page_id = ?;`, userID, pageID)
+	if err != nil {
+		return err
+	}
+	if n, err := res.RowsAffected(); err == nil && n == 0 {
+		return ErrFavoriteNotFound
+	}
+	return err
+}
+
+// List returns the favorites of a user, most recently starred first.
+func (f *FavoriteStore) List(userID string) ([]*Favorite, error) {
+	err := f.Connect()
+	if err != nil {
+		return nil, err
+	}
+
+	rows, err := f.db.Query(`
+		SELECT page_id, created_at
+		FROM favorites
+		WHERE user_id = ?
+		ORDER BY created_at DESC;
+	`, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	list := []*Favorite{}
+	for rows.Next() {
+		fav, err := scanFavorite(rows)
+		if err != nil {
+			return nil, err
+		}
+		list = append(list, fav)
+	}
+	return list, rows.Err()
+}
+
+// DeleteForPage removes a page from the favorites of all users
+func (f *FavoriteStore) DeleteForPage(pageID string) error {
+	err := f.Connect()
+	if err != nil {
+		return err
+	}
+	_, err = f.db.Exec(`DELETE FROM favorites WHERE page_id = ?;`, pageID)
+	return err
+}
+
+// DeleteForUser removes all favorites of a user
+func (f *FavoriteStore) DeleteForUser(userID string) error {
+	err := f.Connect()
+	if err != nil {
+		return err
+	}
+	_, err = f.db.Exec(`DELETE FROM favorites WHERE user_id = ?;`, userID)
+	return err
+}
+
+type rowScanner interface {
+	Scan(dest ...any) error
+}
+
+func scanFavorite(row rowScanner) (*Favorite, error) {
+	fav := &Favorite{}
+	var createdAt int64
+	if err := row.Scan(&fav.PageID, &createdAt); err != nil {
+		return nil, err
+	}
+	fav.CreatedAt = time.Unix(0, createdAt).UTC()
+	return fav, nil
+}
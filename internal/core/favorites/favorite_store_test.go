@@ -0,0 +1,82 @@
+package favorites
+
+import (
+	"testing"
+	"time"
+)
+
+func setupTestFavoriteStore(t *testing.T) *FavoriteStore {
+	t.Helper()
+	store, err := NewFavoriteStore(t.TempDir())
+	if err != nil {
+		t.Fatalf("Failed to create favorite store: %v", err)
+	}
+	return store
+}
+
+func TestFavoriteStore_AddListRemove(t *testing.T) {
+	store := setupTestFavoriteStore(t)
+	defer store.Close()
+
+	first, err := store.Add("u1", "a")
+	if err != nil {
+		t.Fatalf("Failed to add favorite: %v", err)
+	}
+	time.Sleep(time.Millisecond)
+	if _, err := store.Add("u1", "b"); err != nil {
+		t.Fatalf("Failed to add favorite: %v", err)
+	}
+	// Starring again keeps the first date
+	again, err := store.Add("u1", "a")
+	if err != nil {
+		t.Fatalf("Failed to add favorite: %v", err)
+	}
+	if !again.CreatedAt.Equal(first.CreatedAt) {
+		t.Errorf("Expected the first date to be kept, got %v and %v", first.CreatedAt, again.CreatedAt)
+	}
+
+	list, err := store.List("u1")
+	if err != nil {
+		t.Fatalf("Failed to list favorites: %v", err)
+	}
+	if len(list) != 2 || list[0].PageID != "b" || list[1].PageID != "a" {
+		t.Fatalf("Expected b, a, got %v", list)
+	}
+
+	// Favorites are per user
+	if list, _ := store.List("u2"); len(list) != 0 {
+		t.Errorf("Expected no favorites for other user, got %v", list)
+	}
+
+	if err := store.Remove("u1", "a"); err != nil {
+		t.Fatalf("Failed to remove favorite: %v", err)
+	}
+	if err := store.Remove("u1", "a"); err != ErrFavoriteNotFound {
+		t.Errorf("Expected ErrFavoriteNotFound, got %v", err)
+	}
+}
+
+func TestFavoriteStore_DeleteForPageAndUser(t *testing.T) {
+	store := setupTestFavoriteStore(t)
+	defer store.Close()
+
+	for _, fav := range [][2]string{{"u1", "a"}, {"u1", "b"}, {"u2", "a"}} {
+		if _, err := store.Add(fav[0], fav[1]); err != nil {
+			t.Fatalf("Failed to add favorite: %v", err)
+		}
+	}
+
+	if err := store.DeleteForPage("a"); err != nil {
+		t.Fatalf("Failed to delete favorites of page: %v", err)
+	}
+	if list, _ := store.List("u2"); len(list) != 0 {
+		t.Errorf("Expected the page to be removed for all users, got %v", list)
+	}
+
+	if err := store.DeleteForUser("u1"); err != nil {
+		t.Fatalf("Failed to delete favorites of user: %v", err)
+	}
+	if list, _ := store.List("u1"); len(list) != 0 {
+		t.Errorf("Expected no favorites after deleting the user, got %v", list)
+	}
+}
@@ -0,0 +1,17 @@
+package favorites
+
+import "time"
+
+// Favorite is a page starred by a user
+type Favorite struct {
+	PageID    string    `json:"pageId"`
+	CreatedAt time.Time `json:"createdAt"`
+}
+
+// FavoritePage is an entry of the favorites list of a user
+type FavoritePage struct {
+	PageID    string    `json:"pageId"`
+	Title     string    `json:"title"`
+	Path      string    `json:"path"`
+	CreatedAt time.Time `json:"createdAt"`
+}
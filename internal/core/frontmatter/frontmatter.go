@@ -0,0 +1,122 @@
+package frontmatter
+
+import (
+	"fmt"
+	"math"
+	"strings"
+
+	"github.com/goccy/go-yaml"
+)
+
+const delimiter = "---"
+
+// Split separates a leading YAML frontmatter block from the Markdown body.
+// The block must start on the first line with "---" and end with a line containing only "---".
+// ok is false if the content has no frontmatter, in which case body is the full content.
+func Split(content string) (front string, body string, ok bool) {
+	normalized := strings.TrimPrefix(content, "\ufeff")
+	firstLineEnd := strings.IndexByte(normalized, '\n')
+	if firstLineEnd < 0 || strings.TrimRight(normalized[:firstLineEnd], "\r ") != delimiter {
+		return "", content, false
+	}
+
+	rest := normalized[firstLineEnd+1:]
+	offset := 0
+	for offset <= len(rest) {
+		lineEnd := strings.IndexByte(rest[offset:], '\n')
+		var line string
+		if lineEnd < 0 {
+			line = rest[offset:]
+		} else {
+			line = rest[offset : offset+lineEnd]
+		}
+
+		if strings.TrimRight(line, "\r ") == delimiter {
+			front = rest[:offset]
+			if lineEnd < 0 {
+				return front, "", true
+			}
+			return front, rest[offset+lineEnd+1:], true
+		}
+
+		if lineEnd < 0 {
+			break
+		}
+		offset += lineEnd + 1
+	}
+
+	return "", content, false
+}
+
+// Parse returns the frontmatter fields and the Markdown body of a page.
+// Content without frontmatter returns an empty map.
+func Parse(content string) (map[string]any, string, error) {
+	front, body, ok := Split(content)
+	fields := map[string]any{}
+	if !ok || strings.TrimSpace(front) == "" {
+		return fields, body, nil
+	}
+
+	if err := yaml.Unmarshal([]byte(front), &fields); err != nil {
+		return map[string]any{}, body, fmt.Errorf("invalid frontmatter: %w", err)
+	}
+	if fields == nil {
+		fields = map[string]any{}
+	}
+	return fields, body, nil
+}
+
+// String returns a frontmatter field as string.
+// Scalars are formatted, missing fields and lists or maps return ok=false.
+func String(fields map[string]any, key string) (string, bool) {
+	value, exists := fields[key]
+	if !exists || value == nil {
+		return "", false
+	}
+	switch v := value.(type) {
+	case string:
+		return v, true
+	case []any, map[string]any:
+		return "", false
+	default:
+		return fmt.Sprint(v), true
+	}
+}
+
+// Replace sets the frontmatter of the content to the fields, keeping the Markdown body.
+// Empty fields remove the frontmatter.
+func Replace(content string, fields map[string]any) (string, error) {
+	_, body, _ := Split(content)
+	if len(fields) == 0 {
+		return body, nil
+	}
+	front, err := yaml.Marshal(normalize(fields))
+	if err != nil {
+		return "", fmt.Errorf("invalid frontmatter: %w", err)
+	}
+	return delimiter + "\n" + string(front) + delimiter + "\n" + body, nil
+}
+
+// normalize turns whole numbers decoded from JSON as float64 into integers,
+// so 2 is written as 2 and not as 2.0
+func normalize(value any) any {
+	switch v := value.(type) {
+	case float64:
+		if v == math.Trunc(v) && math.Abs(v) < 1<<53 {
+			return int64(v)
+		}
+	case map[string]any:
+		normalized := make(map[string]any, len(v))
+		for key, item := range v {
+			normalized[key] = normalize(item)
+		}
+		return normalized
+	case []any:
+		normalized := make([]any, len(v))
+		for i, item := range v {
+			normalized[i] = normalize(item)
+		}
+		return normalized
+	}
+	return value
+}
@@ -0,0 +1,83 @@
+package frontmatter
+
+import "testing"
+
+func TestSplit(t *testing.T) {
+	tests := []struct {
+		name    string
+		content string
+		front   string
+		body    string
+		ok      bool
+	}{
+		{"no frontmatter", "# Title\n", "", "# Title\n", false},
+		{"frontmatter", "---\nstatus: done\n---\n# Title\n", "status: done\n", "# Title\n", true},
+		{"windows line endings", "---\r\nstatus: done\r\n---\r\nbody", "status: done\r\n", "body", true},
+		{"empty frontmatter", "---\n---\nbody", "", "body", true},
+		{"frontmatter only", "---\nstatus: done\n---", "status: done\n", "", true},
+		{"unterminated", "---\nstatus: done\n# Title", "", "---\nstatus: done\n# Title", false},
+		{"rule later in file", "# Title\n---\nfoo\n---\n", "", "# Title\n---\nfoo\n---\n", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			front, body, ok := Split(tt.content)
+			if front != tt.front || body != tt.body || ok != tt.ok {
+				t.Errorf("Split() = (%q, %q, %v), want (%q, %q, %v)", front, body, ok, tt.front, tt.body, tt.ok)
+			}
+		})
+	}
+}
+
+func TestParse(t *testing.T) {
+	fields, body, err := Parse("---\nstatus: In Progress\npriority: 2\ntags: [a, b]\n---\nbody")
+	if err != nil {
+		t.Fatalf("Parse failed: %v", err)
+	}
+	if body != "body" {
+		t.Errorf("unexpected body %q", body)
+	}
+	if v, ok := String(fields, "status"); !ok || v != "In Progress" {
+		t.Errorf("unexpected status %q", v)
+	}
+	if v, ok := String(fields, "priority"); !ok || v != "2" {
+		t.Errorf("unexpected priority %q", v)
+	}
+	if _, ok := String(fields, "tags"); ok {
+		t.Errorf("expected list field not to be a string")
+	}
+	if _, ok := String(fields, "missing"); ok {
+		t.Errorf("expected missing field to be reported")
+	}
+
+	if _, _, err := Parse("---\nstatus: [\n---\nbody"); err == nil {
+		t.Errorf("expected error for invalid YAML")
+	}
+}
+
+func TestReplace(t *testing.T) {
+	content, err := Replace("---\nstatus: draft\n---\n# Title\n", map[string]any{"status": "done", "tags": []any{"a", "b"}})
+	if err != nil {
+		t.Fatalf("Replace failed: %v", err)
+	}
+	fields, body, err := Parse(content)
+	if err != nil {
+		t.Fatalf("Parse failed: %v", err)
+	}
+	if body != "# Title\n" {
+		t.Errorf("expected the body to be kept, got %q", body)
+	}
+	if v, _ := String(fields, "status"); v != "done" {
+		t.Errorf("unexpected status %q in %q", v, content)
+	}
+	if tags, ok := fields["tags"].([]any); !ok || len(tags) != 2 {
+		t.Errorf("unexpected tags %v", fields["tags"])
+	}
+
+	if content, _ := Replace("# Title", map[string]any{"owner": "ops", "priority": float64(2)}); content != "---\nowner: ops\npriority: 2\n---\n# Title" {
+		t.Errorf("unexpected content %q", content)
+	}
+	if content, _ := Replace("---\nstatus: draft\n---\n# Title", nil); content != "# Title" {
+		t.Errorf("expected frontmatter to be removed, got %q", content)
+	}
+}
@@ -0,0 +1,252 @@
+// Package gitsync keeps the content of the data directory in sync with a git remote,
+// so several wikis or contributors working with git can share one content repository.
+package gitsync
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Strategy decides how conflicting changes are resolved
+type Strategy string
+
+const (
+	// StrategyTheirs prefers the changes of the remote
+	StrategyTheirs Strategy = "theirs"
+	// StrategyOurs prefers the local changes
+	StrategyOurs Strategy = "ours"
+	// StrategyManual aborts the merge and lists the conflicting files, nothing is pushed
+	// until the conflicts are resolved in the repository
+	StrategyManual Strategy = "manual"
+)
+
+var ErrInvalidStrategy = errors.New("invalid conflict strategy")
+var ErrRemoteRequired = errors.New("git remote is required")
+
+// ErrConflict is returned when the remote changes could not be merged
+var ErrConflict = errors.New("merge conflict")
+
+// gitignore limits the repository to the content, the databases are local to every wiki
+const gitignore = `/*
+!/.gitignore
+!/root/
+!/assets/
+!/tree.json
+`
+
+// Config of the sync
+type Config struct {
+	// Remote is the URL or path of the remote repository
+	Remote string
+	// Branch is the branch pulled and pushed, main by default
+	Branch string
+	// Interval between two syncs, 5 minutes by default
+	Interval time.Duration
+	// Strategy for conflicts, manual by default
+	Strategy Strategy
+	// AuthorName and AuthorEmail are used for the commits of local changes
+	AuthorName  string
+	AuthorEmail string
+}
+
+// Status is the outcome of the last sync
+type Status struct {
+	LastSync time.Time `json:"lastSync"`
+	// Pulled is true if remote changes were merged
+	Pulled bool `json:"pulled"`
+	// Conflicts lists the files which couldn't be merged
+	Conflicts []string `json:"conflicts"`
+	Error     string   `json:"error,omitempty"`
+}
+
+// Syncer syncs a directory with the remote, one sync at a time
+type Syncer struct {
+	dir string
+	cfg Config
+
+	mu sync.Mutex
+
+	statusMu sync.RWMutex
+	status   Status
+}
+
+// New validates the config and fills in the defaults
+func New(dir string, cfg Config) (*Syncer, error) {
+	if strings.TrimSpace(cfg.Remote) == "" {
+		return nil, ErrRemoteRequired
+	}
+	if cfg.Branch == "" {
+		cfg.Branch = "main"
+	}
+	if cfg.Interval <= 0 {
+		cfg.Interval = 5 * time.Minute
+	}
+	switch cfg.Strategy {
+	case "":
+		cfg.Strategy = StrategyManual
+	case StrategyTheirs, StrategyOurs, StrategyManual:
+	default:
+		return nil, fmt.Errorf("%w: %s", ErrInvalidStrategy, cfg.Strategy)
+	}
+	if cfg.AuthorName == "" {
+		cfg.AuthorName = "LeafWiki"
+	}
+	if cfg.AuthorEmail == "" {
+		cfg.AuthorEmail = "leafwiki@localhost"
+	}
+	return &Syncer{dir: dir, cfg: cfg, status: Status{Conflicts: []string{}}}, nil
+}
+
+// Interval returns the time between two syncs
+func (s *Syncer) Interval() time.Duration {
+	return s.cfg.Interval
+}
+
+// Status returns the outcome of the last sync
+func (s *Syncer) Status() Status {
+	s.statusMu.RLock()
+	defer s.statusMu.RUnlock()
+	return s.status
+}
+
+// Sync commits the local changes, merges the changes of the remote and pushes the result.
+// It returns true if remote changes were merged, so the caller can reload the content.
+// When the merge fails, it is aborted and ErrConflict is returned, the conflicting files are
+// listed in the status.
+func (s *Syncer) Sync(ctx context.Context) (bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	status := Status{LastSync: time.Now().UTC(), Conflicts: []string{}}
+	pulled, conflicts, err := s.sync(ctx)
+	status.Pulled = pulled
+	if conflicts != nil {
+		status.Conflicts = conflicts
+	}
+	if err != nil {
+		status.Error = err.Error()
+	}
+
+	s.statusMu.Lock()
+	s.status = status
+	s.statusMu.Unlock()
+	return pulled, err
+}
+
+func (s *Syncer) sync(ctx context.Context) (bool, []string, error) {
+	created, err := s.ensureRepository(ctx)
+	if err != nil {
+		return false, nil, err
+	}
+	if err := s.commitLocalChanges(ctx); err != nil {
+		return false, nil, err
+	}
+
+	heads, err := s.git(ctx, "ls-remote", "--heads", s.cfg.Remote, s.cfg.Branch)
+	if err != nil {
+		return false, nil, err
+	}
+	pulled := false
+	// an empty remote gets the local content
+	if strings.TrimSpace(heads) != "" {
+		// a new repository adopts the content of the remote, e.g. the tree and welcome page
+		// of a fresh wiki would always conflict
+		strategy := s.cfg.Strategy
+		if created {
+			strategy = StrategyTheirs
+		}
+		var conflicts []string
+		pulled, conflicts, err = s.merge(ctx, strategy)
+		if err != nil {
+			return false, conflicts, err
+		}
+	}
+
+	if _, err := s.git(ctx, "push", "--quiet", s.cfg.Remote, "HEAD:refs/heads/"+s.cfg.Branch); err != nil {
+		return pulled, nil, err
+	}
+	return pulled, nil, nil
+}
+
+// ensureRepository initializes the repository on the first sync and returns true if it did
+func (s *Syncer) ensureRepository(ctx context.Context) (bool, error) {
+	if _, err := os.Stat(filepath.Join(s.dir, ".git")); err == nil {
+		return false, nil
+	}
+	if _, err := s.git(ctx, "init", "--quiet", "--initial-branch="+s.cfg.Branch); err != nil {
+		return false, err
+	}
+	return true, os.WriteFile(filepath.Join(s.dir, ".gitignore"), []byte(gitignore), 0644)
+}
+
+func (s *Syncer) commitLocalChanges(ctx context.Context) error {
+	if _, err := s.git(ctx, "add", "--all"); err != nil {
+		return err
+	}
+	changes, err := s.git(ctx, "status", "--porcelain")
+	if err != nil || strings.TrimSpace(changes) == "" {
+		return err
+	}
+	_, err = s.git(ctx, "commit", "--quiet", "--message", "Update wiki content")
+	return err
+}
+
+// merge fetches the remote branch and merges it with the strategy
+func (s *Syncer) merge(ctx context.Context, strategy Strategy) (bool, []string, error) {
+	if _, err := s.git(ctx, "fetch", "--quiet", s.cfg.Remote, s.cfg.Branch); err != nil {
+		return false, nil, err
+	}
+	// nothing new on the remote
+	if _, err := s.git(ctx, "merge-base", "--is-ancestor", "FETCH_HEAD", "HEAD"); err == nil {
+		return false, nil, nil
+	}
+
+	args := []string{"merge", "--quiet", "--no-edit", "--allow-unrelated-histories", "--message", "Merge remote wiki content"}
+	if strategy != StrategyManual {
+		args = append(args, "--strategy-option", string(strategy))
+	}
+	if _, mergeErr := s.git(ctx, append(args, "FETCH_HEAD")...); mergeErr != nil {
+		unmerged, err := s.git(ctx, "diff", "--name-only", "--diff-filter=U")
+		if err != nil {
+			return false, nil, err
+		}
+		conflicts := []string{}
+		for _, line := range strings.Split(unmerged, "\n") {
+			if line = strings.TrimSpace(line); line != "" {
+				conflicts = append(conflicts, line)
+			}
+		}
+		if len(conflicts) == 0 {
+			return false, nil, mergeErr
+		}
+		if _, err := s.git(ctx, "merge", "--abort"); err != nil {
+			return false, conflicts, err
+		}
+		return false, conflicts, fmt.Errorf("%w in %d files", ErrConflict, len(conflicts))
+	}
+	return true, nil, nil
+}
+
+// git runs a git command in the directory and returns its output
+func (s *Syncer) git(ctx context.Context, args ...string) (string, error) {
+	// paths with non-ASCII characters are listed unquoted
+	args = append([]string{"-c", "user.name=" + s.cfg.AuthorName, "-c", "user.email=" + s.cfg.AuthorEmail, "-c", "core.quotePath=false"}, args...)
+	cmd := exec.CommandContext(ctx, "git", args...)
+	cmd.Dir = s.dir
+	// never wait for credentials on the terminal
+	cmd.Env = append(os.Environ(), "GIT_TERMINAL_PROMPT=0")
+	var stdout, stderr bytes.Buffer
+	cmd.Stdout, cmd.Stderr = &stdout, &stderr
+	if err := cmd.Run(); err != nil {
+		return stdout.String(), fmt.Errorf("git %s: %w: %s", args[6], err, strings.TrimSpace(stderr.String()))
+	}
+	return stdout.String(), nil
+}
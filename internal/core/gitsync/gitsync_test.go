@@ -0,0 +1,171 @@
+package gitsync
+
+import (
+	"context"
+	"errors"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"testing"
+)
+
+// setupRemote creates an empty bare repository
+func setupRemote(t *testing.T) string {
+	t.Helper()
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git is not installed")
+	}
+	remote := filepath.Join(t.TempDir(), "remote.git")
+	if out, err := exec.Command("git", "init", "--quiet", "--bare", remote).CombinedOutput(); err != nil {
+		t.Fatalf("git init failed: %v %s", err, out)
+	}
+	return remote
+}
+
+func writeFile(t *testing.T, dir, name, content string) {
+	t.Helper()
+	p := filepath.Join(dir, name)
+	if err := os.MkdirAll(filepath.Dir(p), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(p, []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func readFile(t *testing.T, dir, name string) string {
+	t.Helper()
+	data, err := os.ReadFile(filepath.Join(dir, name))
+	if err != nil {
+		t.Fatalf("read %s: %v", name, err)
+	}
+	return string(data)
+}
+
+func newSyncer(t *testing.T, dir, remote string, strategy Strategy) *Syncer {
+	t.Helper()
+	s, err := New(dir, Config{Remote: remote, Strategy: strategy})
+	if err != nil {
+		t.Fatalf("New failed: %v", err)
+	}
+	return s
+}
+
+func TestSync_SharesContentBetweenWikis(t *testing.T) {
+	remote := setupRemote(t)
+	ctx := context.Background()
+
+	a, b := t.TempDir(), t.TempDir()
+	writeFile(t, a, "root/docs.md", "# Docs")
+	writeFile(t, a, "users.db", "local only")
+	syncA := newSyncer(t, a, remote, StrategyManual)
+	if pulled, err := syncA.Sync(ctx); err != nil || pulled {
+		t.Fatalf("first sync: pulled=%v err=%v", pulled, err)
+	}
+
+	// the first sync of a new repository adopts the remote content despite the manual strategy
+	writeFile(t, b, "root/docs.md", "# Default docs")
+	syncB := newSyncer(t, b, remote, StrategyManual)
+	pulled, err := syncB.Sync(ctx)
+	if err != nil || !pulled {
+		t.Fatalf("expected to pull the content, pulled=%v err=%v", pulled, err)
+	}
+	if got := readFile(t, b, "root/docs.md"); got != "# Docs" {
+		t.Errorf("unexpected content %q", got)
+	}
+	if _, err := os.Stat(filepath.Join(b, "users.db")); !os.IsNotExist(err) {
+		t.Errorf("expected databases to stay local")
+	}
+
+	// changes of both sides in different files are merged
+	writeFile(t, b, "root/install.md", "# Install")
+	if _, err := syncB.Sync(ctx); err != nil {
+		t.Fatalf("sync B failed: %v", err)
+	}
+	writeFile(t, a, "root/docs.md", "# Docs\nupdated")
+	if pulled, err := syncA.Sync(ctx); err != nil || !pulled {
+		t.Fatalf("sync A: pulled=%v err=%v", pulled, err)
+	}
+	if got := readFile(t, a, "root/install.md"); got != "# Install" {
+		t.Errorf("expected the page of B, got %q", got)
+	}
+	if pulled, err := syncA.Sync(ctx); err != nil || pulled {
+		t.Errorf("expected nothing new, pulled=%v err=%v", pulled, err)
+	}
+}
+
+func TestSync_ConflictStrategies(t *testing.T) {
+	remote := setupRemote(t)
+	ctx := context.Background()
+
+	a, b := t.TempDir(), t.TempDir()
+	writeFile(t, a, "root/page.md", "original")
+	syncA := newSyncer(t, a, remote, StrategyManual)
+	if _, err := syncA.Sync(ctx); err != nil {
+		t.Fatal(err)
+	}
+	syncB := newSyncer(t, b, remote, StrategyManual)
+	if _, err := syncB.Sync(ctx); err != nil {
+		t.Fatal(err)
+	}
+
+	writeFile(t, a, "root/page.md", "from A")
+	if _, err := syncA.Sync(ctx); err != nil {
+		t.Fatal(err)
+	}
+	writeFile(t, b, "root/page.md", "from B")
+
+	// manual: the merge is aborted and the conflict listed
+	if _, err := syncB.Sync(ctx); !errors.Is(err, ErrConflict) {
+		t.Fatalf("expected ErrConflict, got %v", err)
+	}
+	status := syncB.Status()
+	if len(status.Conflicts) != 1 || status.Conflicts[0] != "root/page.md" || status.Error == "" {
+		t.Errorf("unexpected status %+v", status)
+	}
+	if got := readFile(t, b, "root/page.md"); got != "from B" {
+		t.Errorf("expected local content to be kept, got %q", got)
+	}
+
+	// theirs: the remote wins
+	syncB.cfg.Strategy = StrategyTheirs
+	if pulled, err := syncB.Sync(ctx); err != nil || !pulled {
+		t.Fatalf("sync with theirs: pulled=%v err=%v", pulled, err)
+	}
+	if got := readFile(t, b, "root/page.md"); got != "from A" {
+		t.Errorf("expected remote content, got %q", got)
+	}
+	if status := syncB.Status(); len(status.Conflicts) != 0 || status.Error != "" {
+		t.Errorf("expected a clean status, got %+v", status)
+	}
+
+	// ours: the local change wins and is pushed
+	writeFile(t, a, "root/page.md", "A again")
+	if _, err := syncA.Sync(ctx); err != nil {
+		t.Fatal(err)
+	}
+	writeFile(t, b, "root/page.md", "B wins")
+	syncB.cfg.Strategy = StrategyOurs
+	if _, err := syncB.Sync(ctx); err != nil {
+		t.Fatalf("sync with ours: %v", err)
+	}
+	if _, err := syncA.Sync(ctx); err != nil {
+		t.Fatal(err)
+	}
+	if got := readFile(t, a, "root/page.md"); got != "B wins" {
+		t.Errorf("expected the change of B to reach A, got %q", got)
+	}
+}
+
+func TestNew_ValidatesConfig(t *testing.T) {
+	if _, err := New(t.TempDir(), Config{}); !errors.Is(err, ErrRemoteRequired) {
+		t.Errorf("expected ErrRemoteRequired, got %v", err)
+	}
+	if _, err := New(t.TempDir(), Config{Remote: "x", Strategy: "mine"}); !errors.Is(err, ErrInvalidStrategy) {
+		t.Errorf("expected ErrInvalidStrategy, got %v", err)
+	}
+	s, err := New(t.TempDir(), Config{Remote: "x"})
+	if err != nil || s.cfg.Branch != "main" || s.cfg.Strategy != StrategyManual || s.Interval() <= 0 {
+		t.Errorf("expected defaults, got %+v (%v)", s, err)
+	}
+}
@@ -0,0 +1,54 @@
+// Package highlight adds syntax highlighting to the code blocks of rendered pages with the
+// lexers of Chroma. Tokens are wrapped in spans with the short class names of Pygments and
+// Chroma, the colors come from the stylesheet of the theme.
+package highlight
+
+import (
+	"html"
+	"regexp"
+	"strings"
+
+	"github.com/alecthomas/chroma/v2"
+	chromahtml "github.com/alecthomas/chroma/v2/formatters/html"
+	"github.com/alecthomas/chroma/v2/lexers"
+	"github.com/alecthomas/chroma/v2/styles"
+)
+
+// codeBlockRegex matches the fenced code blocks rendered by blackfriday
+var codeBlockRegex = regexp.MustCompile(`(?s)<pre><code class="language-([a-zA-Z0-9+#_-]+)">(.*?)</code></pre>`)
+
+// formatter writes the tokens as spans with classes, without a surrounding pre element
+var formatter = chromahtml.New(chromahtml.WithClasses(true), chromahtml.PreventSurroundingPre(true))
+
+// Blocks highlights the code blocks with a known language in the rendered HTML. The code is
+// unescaped and escaped again token by token, so it must be sanitized HTML.
+func Blocks(rendered string) string {
+	return codeBlockRegex.ReplaceAllStringFunc(rendered, func(block string) string {
+		groups := codeBlockRegex.FindStringSubmatch(block)
+		highlighted, ok := Code(html.UnescapeString(groups[2]), groups[1])
+		if !ok {
+			return block
+		}
+		return `<pre class="chroma"><code class="language-` + groups[1] + `">` + highlighted + `</code></pre>`
+	})
+}
+
+// Code returns the escaped code with the tokens wrapped in spans. ok is false when Chroma has
+// no lexer for the language.
+func Code(code, lang string) (string, bool) {
+	lexer := lexers.Get(strings.ToLower(lang))
+	if lexer == nil {
+		return "", false
+	}
+	iterator, err := chroma.Coalesce(lexer).Tokenise(nil, code)
+	if err != nil {
+		return "", false
+	}
+
+	var b strings.Builder
+	// with classes the style only matters for the stylesheet
+	if err := formatter.Format(&b, styles.Fallback, iterator); err != nil {
+		return "", false
+	}
+	return b.String(), true
+}
@@ -0,0 +1,88 @@
+package highlight
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestCode(t *testing.T) {
+	tests := []struct {
+		lang string
+		code string
+		want []string
+	}{
+		{"go", "// add\nfunc add(a int) bool { return true }", []string{
+			`<span class="c1">// add</span>`, `<span class="kd">func</span>`, `<span class="nf">add</span>`,
+			`<span class="k">return</span>`, `<span class="kc">true</span>`,
+		}},
+		{"python", "def f():\n    \"\"\"doc\n    string\"\"\"\n    return None  # done", []string{
+			`<span class="k">def</span>`, `<span class="s2">    string&#34;&#34;&#34;</span>`,
+			`<span class="kc">None</span>`, `<span class="c1"># done</span>`,
+		}},
+		{"json", `{"name": "leaf", "count": 12, "ok": false}`, []string{
+			`<span class="nt">&#34;name&#34;</span>`, `<span class="s2">&#34;leaf&#34;</span>`,
+			`<span class="mi">12</span>`, `<span class="kc">false</span>`,
+		}},
+		{"yaml", "title: Guide # comment\ndraft: yes", []string{
+			`<span class="nt">title</span>`, `<span class="c"># comment</span>`, `<span class="kc">yes</span>`,
+		}},
+		{"SQL", "SELECT id FROM pages -- all", []string{
+			`<span class="k">SELECT</span>`, `<span class="k">FROM</span>`, `<span class="c1">-- all</span>`,
+		}},
+		{"js", `const s = "<script>" /* x */`, []string{
+			`<span class="s2">&#34;&lt;script&gt;&#34;</span>`, `<span class="cm">/* x */</span>`,
+		}},
+	}
+	for _, tt := range tests {
+		got, ok := Code(tt.code, tt.lang)
+		if !ok {
+			t.Fatalf("Expected %s to be supported", tt.lang)
+		}
+		for _, want := range tt.want {
+			if !strings.Contains(got, want) {
+				t.Errorf("%s: expected %q in %q", tt.lang, want, got)
+			}
+		}
+		if strings.Contains(got, "<pre") {
+			t.Errorf("%s: expected no surrounding pre in %q", tt.lang, got)
+		}
+	}
+
+	if _, ok := Code("x", "no-such-language"); ok {
+		t.Errorf("Expected unknown languages to be unsupported")
+	}
+}
+
+func TestBlocks(t *testing.T) {
+	rendered := "<p>x</p>\n<pre><code class=\"language-go\">var s = &#34;a&lt;b&#34;\n</code></pre>\n" +
+		"<pre><code class=\"language-unknown\">var x</code></pre>\n<pre><code>var y</code></pre>"
+	got := Blocks(rendered)
+
+	for _, want := range []string{`<pre class="chroma"><code class="language-go"><span class="kd">var</span>`, `<span class="s">&#34;a&lt;b&#34;</span>`} {
+		if !strings.Contains(got, want) {
+			t.Errorf("Expected %q in %q", want, got)
+		}
+	}
+	for _, kept := range []string{`<pre><code class="language-unknown">var x</code></pre>`, `<pre><code>var y</code></pre>`} {
+		if !strings.Contains(got, kept) {
+			t.Errorf("Expected %q to be kept in %q", kept, got)
+		}
+	}
+}
+
+func TestThemes(t *testing.T) {
+	for _, name := range []string{DefaultTheme, "monokai", "dracula", "nord", Off} {
+		if !ValidTheme(name) {
+			t.Errorf("Expected %q to be a valid theme", name)
+		}
+	}
+	if ValidTheme("neon") {
+		t.Errorf("Expected an unknown theme to be invalid")
+	}
+	if css := CSS("monokai"); !strings.Contains(css, ".chroma { color: #f8f8f2; background-color: #272822") || !strings.Contains(css, ".chroma .k { color: #66d9ef }") {
+		t.Errorf("Unexpected CSS %q", css)
+	}
+	if CSS(Off) != "" || CSS("neon") != "" {
+		t.Errorf("Expected no CSS when highlighting is off")
+	}
+}
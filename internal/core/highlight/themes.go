@@ -0,0 +1,38 @@
+package highlight
+
+import (
+	"strings"
+
+	"github.com/alecthomas/chroma/v2/styles"
+)
+
+// Off disables highlighting
+const Off = "off"
+
+// DefaultTheme is used as long as no theme has been configured
+const DefaultTheme = "github"
+
+// Themes returns the names of the available themes, which are the styles of Chroma
+func Themes() []string {
+	return styles.Names()
+}
+
+// ValidTheme reports whether the theme exists or is Off
+func ValidTheme(name string) bool {
+	_, ok := styles.Registry[name]
+	return ok || name == Off
+}
+
+// CSS returns the stylesheet of a theme, it is empty for Off and unknown themes. Apart from the
+// bg class of the background, the rules are scoped to the chroma class of the code blocks.
+func CSS(name string) string {
+	style, ok := styles.Registry[name]
+	if !ok {
+		return ""
+	}
+	var b strings.Builder
+	if err := formatter.WriteCSS(&b, style); err != nil {
+		return ""
+	}
+	return b.String()
+}
@@ -0,0 +1,203 @@
+// Package ignore implements gitignore-style rules, which exclude files in the data dir from
+// indexing, the history and the tree.
+package ignore
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"sync"
+)
+
+// Filename is the file with the ignore rules in the root of the pages
+const Filename = ".leafwikiignore"
+
+// rule is a single pattern of an ignore file
+type rule struct {
+	re *regexp.Regexp
+	// negate re-includes paths excluded by a previous rule
+	negate bool
+	// dirOnly matches only directories, the pattern ended with a slash
+	dirOnly bool
+}
+
+// Matcher decides which paths are ignored. The zero value ignores nothing.
+type Matcher struct {
+	rules []rule
+}
+
+// Parse reads the rules of an ignore file. Blank lines and lines starting with # are
+// skipped, and so are invalid patterns.
+func Parse(text string) *Matcher {
+	m := &Matcher{}
+	for _, line := range strings.Split(text, "\n") {
+		if r, ok, err := parseRule(line); ok && err == nil {
+			m.rules = append(m.rules, r)
+		}
+	}
+	return m
+}
+
+// ValidatePattern returns an error if the pattern can't be parsed
+func ValidatePattern(pattern string) error {
+	_, _, err := parseRule(pattern)
+	return err
+}
+
+// parseRule converts a pattern to a rule, ok is false for blank lines and comments
+func parseRule(line string) (rule, bool, error) {
+	pattern := strings.TrimRight(strings.TrimSuffix(line, "\r"), " ")
+	if pattern == "" || strings.HasPrefix(pattern, "#") {
+		return rule{}, false, nil
+	}
+
+	var r rule
+	if strings.HasPrefix(pattern, "!") {
+		r.negate = true
+		pattern = pattern[1:]
+	} else if strings.HasPrefix(pattern, `\!`) || strings.HasPrefix(pattern, `\#`) {
+		pattern = pattern[1:]
+	}
+	if strings.HasSuffix(pattern, "/") {
+		r.dirOnly = true
+		pattern = strings.TrimRight(pattern, "/")
+	}
+	// patterns with a slash are relative to the root, others match at any level
+	anchored := strings.Contains(pattern, "/")
+	pattern = strings.TrimPrefix(pattern, "/")
+	if pattern == "" {
+		return rule{}, false, fmt.Errorf("empty pattern %q", line)
+	}
+
+	prefix := "^(?:.*/)?"
+	if anchored {
+		prefix = "^"
+	}
+	expr, err := translate(pattern)
+	if err != nil {
+		return rule{}, false, fmt.Errorf("invalid pattern %q: %w", line, err)
+	}
+	if r.re, err = regexp.Compile(prefix + expr + "$"); err != nil {
+		return rule{}, false, fmt.Errorf("invalid pattern %q: %w", line, err)
+	}
+	return r, true, nil
+}
+
+// translate converts a glob to a regular expression: * and ? don't match slashes,
+// ** matches across directories and [...] is a character class
+func translate(pattern string) (string, error) {
+	var b strings.Builder
+	for i := 0; i < len(pattern); i++ {
+		c := pattern[i]
+		switch {
+		case strings.HasPrefix(pattern[i:], "**/"):
+			b.WriteString("(?:.*/)?")
+			i += 2
+		case strings.HasPrefix(pattern[i:], "**"):
+			b.WriteString(".*")
+			i++
+		case c == '*':
+			b.WriteString("[^/]*")
+		case c == '?':
+			b.WriteString("[^/]")
+		case c == '[':
+			end := strings.IndexByte(pattern[i+1:], ']')
+			if end < 0 {
+				return "", fmt.Errorf("unclosed character class")
+			}
+			class := pattern[i+1 : i+1+end]
+			if strings.HasPrefix(class, "!") {
+				class = "^" + class[1:]
+			}
+			b.WriteString("[" + strings.ReplaceAll(class, `\`, `\\`) + "]")
+			i += end + 1
+		case c == '\\' && i+1 < len(pattern):
+			i++
+			b.WriteString(regexp.QuoteMeta(pattern[i : i+1]))
+		default:
+			b.WriteString(regexp.QuoteMeta(string(c)))
+		}
+	}
+	return b.String(), nil
+}
+
+// Match returns true if the slash-separated path relative to the root is ignored. Like with
+// gitignore, everything below an ignored directory is ignored, the last matching rule wins.
+func (m *Matcher) Match(rel string, isDir bool) bool {
+	if m == nil || len(m.rules) == 0 {
+		return false
+	}
+	rel = strings.Trim(rel, "/")
+	if rel == "" || rel == "." {
+		return false
+	}
+	parts := strings.Split(rel, "/")
+	for i := 1; i < len(parts); i++ {
+		if m.matchPath(strings.Join(parts[:i], "/"), true) {
+			return true
+		}
+	}
+	return m.matchPath(rel, isDir)
+}
+
+func (m *Matcher) matchPath(p string, isDir bool) bool {
+	ignored := false
+	for _, r := range m.rules {
+		if (r.dirOnly && !isDir) || ignored == !r.negate {
+			continue
+		}
+		if r.re.MatchString(p) {
+			ignored = !r.negate
+		}
+	}
+	return ignored
+}
+
+// Rules are the ignore rules of a directory: the rules of its ignore file, followed by
+// additional patterns, e.g. from the settings. They are safe for concurrent use.
+type Rules struct {
+	dir string
+
+	mu       sync.RWMutex
+	patterns []string
+	matcher  *Matcher
+}
+
+// NewRules loads the ignore file of dir, a missing file ignores nothing
+func NewRules(dir string) *Rules {
+	r := &Rules{dir: dir}
+	r.Reload()
+	return r
+}
+
+// SetPatterns replaces the additional patterns, which come after the rules of the file
+func (r *Rules) SetPatterns(patterns []string) {
+	r.mu.Lock()
+	r.patterns = append([]string{}, patterns...)
+	r.mu.Unlock()
+	r.Reload()
+}
+
+// Reload reads the ignore file again, e.g. after it changed
+func (r *Rules) Reload() {
+	// a missing file ignores nothing
+	data, _ := os.ReadFile(filepath.Join(r.dir, Filename))
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	text := string(data) + "\n" + strings.Join(r.patterns, "\n")
+	r.matcher = Parse(text)
+}
+
+// Match returns true if the slash-separated path relative to the directory is ignored.
+// Nil rules ignore nothing.
+func (r *Rules) Match(rel string, isDir bool) bool {
+	if r == nil {
+		return false
+	}
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	return r.matcher.Match(rel, isDir)
+}
@@ -0,0 +1,95 @@
+package ignore
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestMatcher_Match(t *testing.T) {
+	m := Parse(`# dependencies and editor state
+node_modules
+.obsidian/
+/drafts-private/
+*.tmp.md
+docs/**/internal
+!keep.tmp.md
+\#literal.md
+`)
+
+	tests := []struct {
+		path  string
+		isDir bool
+		want  bool
+	}{
+		{"node_modules", true, true},
+		{"node_modules/pkg/readme.md", false, true},
+		{"docs/node_modules/readme.md", false, true},
+		{".obsidian", true, true},
+		{".obsidian/workspace.md", false, true},
+		{"notes/.obsidian", false, false},
+		{"drafts-private/idea.md", false, true},
+		{"docs/drafts-private/idea.md", false, false},
+		{"scratch.tmp.md", false, true},
+		{"docs/scratch.tmp.md", false, true},
+		{"keep.tmp.md", false, false},
+		{"docs/internal/secret.md", false, true},
+		{"docs/a/b/internal/secret.md", false, true},
+		{"internal/secret.md", false, false},
+		{"#literal.md", false, true},
+		{"docs/guide.md", false, false},
+		{"", true, false},
+	}
+	for _, tt := range tests {
+		if got := m.Match(tt.path, tt.isDir); got != tt.want {
+			t.Errorf("Match(%q, %v) = %v, want %v", tt.path, tt.isDir, got, tt.want)
+		}
+	}
+}
+
+func TestMatcher_ParentStaysIgnored(t *testing.T) {
+	// like gitignore, files can't be re-included below an ignored directory
+	m := Parse("private/\n!private/public.md\n")
+	if !m.Match("private/public.md", false) {
+		t.Error("expected the file below the ignored directory to stay ignored")
+	}
+}
+
+func TestValidatePattern(t *testing.T) {
+	if err := ValidatePattern("docs/[abc"); err == nil {
+		t.Error("expected an error for an unclosed character class")
+	}
+	if err := ValidatePattern("docs/[a-c]*.md"); err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+}
+
+func TestRules(t *testing.T) {
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, Filename), []byte("drafts/\n"), 0o644); err != nil {
+		t.Fatalf("failed to write ignore file: %v", err)
+	}
+
+	rules := NewRules(dir)
+	if !rules.Match("drafts/idea.md", false) || rules.Match("archive/old.md", false) {
+		t.Error("expected the rules of the file")
+	}
+
+	rules.SetPatterns([]string{"archive/"})
+	if !rules.Match("drafts/idea.md", false) || !rules.Match("archive/old.md", false) {
+		t.Error("expected the rules of the file and the patterns")
+	}
+
+	if err := os.Remove(filepath.Join(dir, Filename)); err != nil {
+		t.Fatalf("failed to remove ignore file: %v", err)
+	}
+	rules.Reload()
+	if rules.Match("drafts/idea.md", false) || !rules.Match("archive/old.md", false) {
+		t.Error("expected only the patterns after the file was removed")
+	}
+
+	var none *Rules
+	if none.Match("drafts/idea.md", false) {
+		t.Error("expected nil rules to ignore nothing")
+	}
+}
@@ -0,0 +1,299 @@
+// Package images removes the metadata of uploaded JPEG and PNG images and scales down large ones
+package images
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"image"
+	"image/draw"
+	"image/jpeg"
+	"image/png"
+	"path"
+	"strings"
+)
+
+// DefaultQuality is the JPEG quality of re-encoded images
+const DefaultQuality = 85
+
+var ErrInvalidImage = errors.New("invalid image")
+
+// Options select how an image is processed
+type Options struct {
+	// StripMetadata removes EXIF (including GPS positions), XMP, IPTC and comments. JPEG files
+	// rotated by their EXIF orientation are rotated for real, so they still display upright.
+	StripMetadata bool
+	// MaxDimension scales down images whose width or height is larger, 0 keeps the size
+	MaxDimension int
+	// Quality is the JPEG quality of re-encoded images, from 1 to 100
+	Quality int
+}
+
+// Supported reports whether images with the extension of the filename are processed
+func Supported(filename string) bool {
+	switch strings.ToLower(path.Ext(filename)) {
+	case ".jpg", ".jpeg", ".png":
+		return true
+	}
+	return false
+}
+
+// Process applies the options to a JPEG or PNG image, other files are returned unchanged.
+// Metadata is removed without re-encoding the image, unless the image is scaled or rotated.
+func Process(data []byte, filename string, opts Options) ([]byte, error) {
+	if opts.Quality <= 0 || opts.Quality > 100 {
+		opts.Quality = DefaultQuality
+	}
+	switch strings.ToLower(path.Ext(filename)) {
+	case ".jpg", ".jpeg":
+		return processJPEG(data, opts)
+	case ".png":
+		return processPNG(data, opts)
+	}
+	return data, nil
+}
+
+func processJPEG(data []byte, opts Options) ([]byte, error) {
+	stripped, orientation, err := stripJPEG(data)
+	if err != nil {
+		return nil, err
+	}
+	config, err := jpeg.DecodeConfig(bytes.NewReader(data))
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidImage, err)
+	}
+
+	resize := tooLarge(config.Width, config.Height, opts.MaxDimension)
+	rotate := opts.StripMetadata && orientation > 1 && orientation <= 8
+	if !resize && !rotate {
+		if opts.StripMetadata {
+			return stripped, nil
+		}
+		return data, nil
+	}
+
+	img, err := jpeg.Decode(bytes.NewReader(data))
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidImage, err)
+	}
+	// the encoder writes no EXIF, so the orientation is applied to the pixels
+	img = orient(img, orientation)
+	if resize {
+		img = scaleDown(img, opts.MaxDimension)
+	}
+	var buf bytes.Buffer
+	if err := jpeg.Encode(&buf, img, &jpeg.Options{Quality: opts.Quality}); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// stripJPEG removes the APP1 (EXIF, XMP), APP13 (IPTC) and comment segments and returns
+// the EXIF orientation, 0 if there is none
+func stripJPEG(data []byte) ([]byte, int, error) {
+	if len(data) < 4 || data[0] != 0xFF || data[1] != 0xD8 {
+		return nil, 0, fmt.Errorf("%w: not a JPEG file", ErrInvalidImage)
+	}
+	out := make([]byte, 0, len(data))
+	out = append(out, 0xFF, 0xD8)
+	orientation := 0
+	pos := 2
+	for pos < len(data) {
+		if data[pos] != 0xFF || pos+1 >= len(data) {
+			return nil, 0, fmt.Errorf("%w: broken JPEG segment", ErrInvalidImage)
+		}
+		marker := data[pos+1]
+		switch {
+		case marker == 0xFF:
+			// fill byte
+			pos++
+			continue
+		case marker == 0x01 || (marker >= 0xD0 && marker <= 0xD7):
+			out = append(out, data[pos:pos+2]...)
+			pos += 2
+			continue
+		case marker == 0xD9:
+			return append(out, data[pos:]...), orientation, nil
+		}
+		if pos+4 > len(data) {
+			return nil, 0, fmt.Errorf("%w: broken JPEG segment", ErrInvalidImage)
+		}
+		end := pos + 2 + int(binary.BigEndian.Uint16(data[pos+2:]))
+		if end > len(data) {
+			return nil, 0, fmt.Errorf("%w: broken JPEG segment", ErrInvalidImage)
+		}
+		if marker == 0xDA {
+			// start of scan, the compressed image data follows up to the end
+			return append(out, data[pos:]...), orientation, nil
+		}
+		switch marker {
+		case 0xE1:
+			if o := exifOrientation(data[pos+4 : end]); o > 0 {
+				orientation = o
+			}
+		case 0xED, 0xFE:
+		default:
+			out = append(out, data[pos:end]...)
+		}
+		pos = end
+	}
+	return out, orientation, nil
+}
+
+// exifOrientation reads the orientation tag of IFD0 from an APP1 segment, 0 if it has none
+func exifOrientation(segment []byte) int {
+	tiff, ok := bytes.CutPrefix(segment, []byte("Exif\x00\x00"))
+	if !ok || len(tiff) < 8 {
+		return 0
+	}
+	var order binary.ByteOrder
+	switch string(tiff[:2]) {
+	case "II":
+		order = binary.LittleEndian
+	case "MM":
+		order = binary.BigEndian
+	default:
+		return 0
+	}
+	ifd := int(order.Uint32(tiff[4:]))
+	if ifd < 8 || ifd+2 > len(tiff) {
+		return 0
+	}
+	count := int(order.Uint16(tiff[ifd:]))
+	for i := 0; i < count; i++ {
+		entry := ifd + 2 + i*12
+		if entry+12 > len(tiff) {
+			return 0
+		}
+		if order.Uint16(tiff[entry:]) == 0x0112 {
+			return int(order.Uint16(tiff[entry+8:]))
+		}
+	}
+	return 0
+}
+
+// pngMetadataChunks are the ancillary PNG chunks with text, EXIF and time stamps
+var pngMetadataChunks = map[string]bool{"tEXt": true, "zTXt": true, "iTXt": true, "eXIf": true, "tIME": true}
+
+var pngSignature = []byte("\x89PNG\r\n\x1a\n")
+
+func processPNG(data []byte, opts Options) ([]byte, error) {
+	config, err := png.DecodeConfig(bytes.NewReader(data))
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidImage, err)
+	}
+	if tooLarge(config.Width, config.Height, opts.MaxDimension) {
+		img, err := png.Decode(bytes.NewReader(data))
+		if err != nil {
+			return nil, fmt.Errorf("%w: %v", ErrInvalidImage, err)
+		}
+		var buf bytes.Buffer
+		encoder := png.Encoder{CompressionLevel: png.BestCompression}
+		if err := encoder.Encode(&buf, scaleDown(img, opts.MaxDimension)); err != nil {
+			return nil, err
+		}
+		return buf.Bytes(), nil
+	}
+	if !opts.StripMetadata {
+		return data, nil
+	}
+
+	out := make([]byte, 0, len(data))
+	out = append(out, pngSignature...)
+	pos := len(pngSignature)
+	for pos < len(data) {
+		if pos+12 > len(data) {
+			return nil, fmt.Errorf("%w: broken PNG chunk", ErrInvalidImage)
+		}
+		end := pos + 12 + int(binary.BigEndian.Uint32(data[pos:]))
+		if end > len(data) || end < pos {
+			return nil, fmt.Errorf("%w: broken PNG chunk", ErrInvalidImage)
+		}
+		if !pngMetadataChunks[string(data[pos+4:pos+8])] {
+			out = append(out, data[pos:end]...)
+		}
+		pos = end
+	}
+	return out, nil
+}
+
+func tooLarge(width, height, maxDimension int) bool {
+	return maxDimension > 0 && (width > maxDimension || height > maxDimension)
+}
+
+// orient rotates and flips the image as described by an EXIF orientation from 2 to 8
+func orient(img image.Image, orientation int) image.Image {
+	if orientation < 2 || orientation > 8 {
+		return img
+	}
+	b := img.Bounds()
+	w, h := b.Dx(), b.Dy()
+	var dst *image.RGBA
+	if orientation >= 5 {
+		dst = image.NewRGBA(image.Rect(0, 0, h, w))
+	} else {
+		dst = image.NewRGBA(image.Rect(0, 0, w, h))
+	}
+	for y := 0; y < h; y++ {
+		for x := 0; x < w; x++ {
+			var dx, dy int
+			switch orientation {
+			case 2:
+				dx, dy = w-1-x, y
+			case 3:
+				dx, dy = w-1-x, h-1-y
+			case 4:
+				dx, dy = x, h-1-y
+			case 5:
+				dx, dy = y, x
+			case 6:
+				dx, dy = h-1-y, x
+			case 7:
+				dx, dy = h-1-y, w-1-x
+			case 8:
+				dx, dy = y, w-1-x
+			}
+			dst.Set(dx, dy, img.At(b.Min.X+x, b.Min.Y+y))
+		}
+	}
+	return dst
+}
+
+// scaleDown fits the image into maxDimension, keeping its aspect ratio. Every pixel is the
+// average of the source pixels it covers, which keeps text in screenshots legible.
+func scaleDown(img image.Image, maxDimension int) image.Image {
+	b := img.Bounds()
+	w, h := b.Dx(), b.Dy()
+	dw, dh := maxDimension, maxDimension
+	if w >= h {
+		dh = max(1, h*maxDimension/w)
+	} else {
+		dw = max(1, w*maxDimension/h)
+	}
+
+	src := image.NewRGBA(image.Rect(0, 0, w, h))
+	draw.Draw(src, src.Bounds(), img, b.Min, draw.Src)
+	dst := image.NewRGBA(image.Rect(0, 0, dw, dh))
+	for y := 0; y < dh; y++ {
+		y0, y1 := y*h/dh, max((y+1)*h/dh, y*h/dh+1)
+		for x := 0; x < dw; x++ {
+			x0, x1 := x*w/dw, max((x+1)*w/dw, x*w/dw+1)
+			var sum [4]int
+			for sy := y0; sy < y1; sy++ {
+				row := src.Pix[sy*src.Stride:]
+				for sx := x0; sx < x1; sx++ {
+					for c := 0; c < 4; c++ {
+						sum[c] += int(row[sx*4+c])
+					}
+				}
+			}
+			n := (y1 - y0) * (x1 - x0)
+			i := y*dst.Stride + x*4
+			for c := 0; c < 4; c++ {
+				dst.Pix[i+c] = uint8(sum[c] / n)
+			}
+		}
+	}
+	return dst
+}
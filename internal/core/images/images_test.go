@@ -0,0 +1,141 @@
+package images
+
+import (
+	"bytes"
+	"encoding/binary"
+	"hash/crc32"
+	"image"
+	"image/color"
+	"image/jpeg"
+	"image/png"
+	"testing"
+)
+
+func testImage(width, height int) image.Image {
+	img := image.NewRGBA(image.Rect(0, 0, width, height))
+	for y := 0; y < height; y++ {
+		for x := 0; x < width; x++ {
+			img.Set(x, y, color.RGBA{R: uint8(x * 10), G: uint8(y * 10), B: 100, A: 255})
+		}
+	}
+	return img
+}
+
+// withExif inserts an APP1 segment with the orientation and a GPS marker after the SOI
+func withExif(t *testing.T, jpg []byte, orientation uint16) []byte {
+	t.Helper()
+	tiff := []byte("MM\x00\x2a\x00\x00\x00\x08")
+	tiff = binary.BigEndian.AppendUint16(tiff, 1)
+	tiff = binary.BigEndian.AppendUint16(tiff, 0x0112)
+	tiff = binary.BigEndian.AppendUint16(tiff, 3)
+	tiff = binary.BigEndian.AppendUint32(tiff, 1)
+	tiff = binary.BigEndian.AppendUint16(tiff, orientation)
+	tiff = append(tiff, 0, 0, 0, 0, 0, 0)
+	tiff = append(tiff, []byte("GPS 52.52N 13.40E")...)
+	payload := append([]byte("Exif\x00\x00"), tiff...)
+
+	segment := []byte{0xFF, 0xE1}
+	segment = binary.BigEndian.AppendUint16(segment, uint16(len(payload)+2))
+	segment = append(segment, payload...)
+	out := append([]byte{}, jpg[:2]...)
+	out = append(out, segment...)
+	return append(out, jpg[2:]...)
+}
+
+func encodeJPEG(t *testing.T, img image.Image) []byte {
+	t.Helper()
+	var buf bytes.Buffer
+	if err := jpeg.Encode(&buf, img, nil); err != nil {
+		t.Fatalf("jpeg.Encode failed: %v", err)
+	}
+	return buf.Bytes()
+}
+
+func TestProcess_StripsJPEGMetadata(t *testing.T) {
+	data := withExif(t, encodeJPEG(t, testImage(8, 4)), 1)
+
+	out, err := Process(data, "photo.JPG", Options{StripMetadata: true})
+	if err != nil {
+		t.Fatalf("Process failed: %v", err)
+	}
+	if bytes.Contains(out, []byte("Exif")) || bytes.Contains(out, []byte("GPS")) {
+		t.Error("expected the EXIF segment to be removed")
+	}
+	if _, err := jpeg.Decode(bytes.NewReader(out)); err != nil {
+		t.Errorf("expected a valid JPEG: %v", err)
+	}
+
+	kept, err := Process(data, "photo.jpg", Options{})
+	if err != nil || !bytes.Equal(kept, data) {
+		t.Errorf("expected the image to be unchanged without options, got %v", err)
+	}
+}
+
+func TestProcess_RotatesByOrientation(t *testing.T) {
+	data := withExif(t, encodeJPEG(t, testImage(8, 4)), 6)
+
+	out, err := Process(data, "photo.jpeg", Options{StripMetadata: true})
+	if err != nil {
+		t.Fatalf("Process failed: %v", err)
+	}
+	config, err := jpeg.DecodeConfig(bytes.NewReader(out))
+	if err != nil {
+		t.Fatalf("expected a valid JPEG: %v", err)
+	}
+	if config.Width != 4 || config.Height != 8 {
+		t.Errorf("expected the image to be rotated to 4x8, got %dx%d", config.Width, config.Height)
+	}
+	if bytes.Contains(out, []byte("GPS")) {
+		t.Error("expected the EXIF segment to be removed")
+	}
+}
+
+func TestProcess_ScalesDown(t *testing.T) {
+	out, err := Process(encodeJPEG(t, testImage(400, 100)), "wide.jpg", Options{MaxDimension: 100, Quality: 70})
+	if err != nil {
+		t.Fatalf("Process failed: %v", err)
+	}
+	config, _ := jpeg.DecodeConfig(bytes.NewReader(out))
+	if config.Width != 100 || config.Height != 25 {
+		t.Errorf("expected 100x25, got %dx%d", config.Width, config.Height)
+	}
+
+	var buf bytes.Buffer
+	_ = png.Encode(&buf, testImage(50, 200))
+	out, err = Process(buf.Bytes(), "tall.png", Options{MaxDimension: 100})
+	if err != nil {
+		t.Fatalf("Process failed: %v", err)
+	}
+	pngConfig, _ := png.DecodeConfig(bytes.NewReader(out))
+	if pngConfig.Width != 25 || pngConfig.Height != 100 {
+		t.Errorf("expected 25x100, got %dx%d", pngConfig.Width, pngConfig.Height)
+	}
+}
+
+func TestProcess_StripsPNGTextChunks(t *testing.T) {
+	var buf bytes.Buffer
+	_ = png.Encode(&buf, testImage(4, 4))
+	data := buf.Bytes()
+
+	// insert a tEXt chunk after the IHDR chunk (8 bytes signature, 25 bytes IHDR)
+	text := []byte("tEXtAuthor\x00Jane Doe")
+	chunk := binary.BigEndian.AppendUint32(nil, uint32(len(text)-4))
+	chunk = append(chunk, text...)
+	chunk = binary.BigEndian.AppendUint32(chunk, crc32.ChecksumIEEE(text))
+	withText := append(append(append([]byte{}, data[:33]...), chunk...), data[33:]...)
+
+	out, err := Process(withText, "screen.png", Options{StripMetadata: true})
+	if err != nil {
+		t.Fatalf("Process failed: %v", err)
+	}
+	if !bytes.Equal(out, data) {
+		t.Error("expected the text chunk to be removed and the rest to be kept")
+	}
+
+	if _, err := Process([]byte("not an image"), "broken.png", Options{StripMetadata: true}); err == nil {
+		t.Error("expected an error for a broken image")
+	}
+	if out, _ := Process([]byte("GIF89a"), "anim.gif", Options{StripMetadata: true}); string(out) != "GIF89a" {
+		t.Error("expected unsupported images to be unchanged")
+	}
+}
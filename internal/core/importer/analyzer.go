@@ -0,0 +1,291 @@
+package importer
+
+import (
+	"fmt"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/Gomez12/wiki/internal/core/frontmatter"
+	"github.com/Gomez12/wiki/internal/core/tree"
+	"github.com/gosimple/slug"
+)
+
+// indexFiles are used as content of the page of their folder
+var indexFiles = map[string]bool{
+	"index.md":  true,
+	"readme.md": true,
+}
+
+var (
+	headingRegex = regexp.MustCompile(`(?m)^#\s+(.+?)\s*#*\s*$`)
+	linkRegex    = regexp.MustCompile(`\]\(([^)\s]+\.md)(?:#[^)]*)?\)`)
+)
+
+type sourceFile struct {
+	relPath string
+	title   string
+	links   []string
+}
+
+// Analyze walks a folder of Markdown files without tree metadata and proposes a hierarchy.
+// Folders become pages (using their index.md or README.md as content), titles are taken
+// from the frontmatter or the first heading, and files in the same folder which are only
+// linked from a single sibling are nested below that sibling.
+func Analyze(sourceDir string) (*Plan, error) {
+	info, err := os.Stat(sourceDir)
+	if err != nil || !info.IsDir() {
+		return nil, ErrSourceNotFound
+	}
+
+	plan := &Plan{SourceDir: sourceDir, Skipped: []string{}, OnCollision: CollisionSuffix}
+	pages, err := analyzeDir(sourceDir, "", plan)
+	if err != nil {
+		return nil, err
+	}
+	plan.Pages = pages
+	return plan, nil
+}
+
+func analyzeDir(sourceDir, relDir string, plan *Plan) ([]*ProposedPage, error) {
+	entries, err := os.ReadDir(filepath.Join(sourceDir, filepath.FromSlash(relDir)))
+	if err != nil {
+		return nil, err
+	}
+
+	siblings := &tree.PageNode{}
+	slugs := tree.NewSlugService()
+	var files []*sourceFile
+	var pages []*ProposedPage
+
+	for _, entry := range entries {
+		name := entry.Name()
+		if strings.HasPrefix(name, ".") || entry.Type()&fs.ModeSymlink != 0 {
+			// links could point outside the source directory
+			continue
+		}
+		relPath := path.Join(relDir, name)
+
+		if entry.IsDir() {
+			children, err := analyzeDir(sourceDir, relPath, plan)
+			if err != nil {
+				return nil, err
+			}
+			folder := &ProposedPage{Title: humanize(name), Reason: ReasonFolder, Children: children}
+			if index := findIndexFile(sourceDir, relPath); index != "" {
+				if file, err := readSourceFile(sourceDir, index); err == nil {
+					folder.SourcePath = index
+					if file.title != "" {
+						folder.Title = file.title
+					}
+				} else {
+					plan.Skipped = append(plan.Skipped, index)
+				}
+			}
+			if folder.SourcePath == "" && len(children) == 0 {
+				continue
+			}
+			folder.Slug = uniqueSlug(slugs, siblings, name)
+			pages = append(pages, folder)
+			continue
+		}
+
+		if filepath.Ext(name) != ".md" || (relDir != "" && indexFiles[strings.ToLower(name)]) {
+			continue
+		}
+
+		file, err := readSourceFile(sourceDir, relPath)
+		if err != nil {
+			plan.Skipped = append(plan.Skipped, relPath)
+			continue
+		}
+		files = append(files, file)
+	}
+
+	byPath := map[string]*ProposedPage{}
+	for _, file := range files {
+		base := strings.TrimSuffix(path.Base(file.relPath), ".md")
+		title := file.title
+		if title == "" {
+			title = humanize(base)
+		}
+		byPath[file.relPath] = &ProposedPage{
+			SourcePath: file.relPath,
+			Title:      title,
+			Slug:       uniqueSlug(slugs, siblings, base),
+			Reason:     ReasonRoot,
+			Children:   []*ProposedPage{},
+		}
+	}
+
+	parents := linkParents(files)
+	for _, file := range files {
+		page := byPath[file.relPath]
+		if parent, ok := parents[file.relPath]; ok {
+			page.Reason = ReasonLink
+			byPath[parent].Children = append(byPath[parent].Children, page)
+			continue
+		}
+		if relDir != "" {
+			page.Reason = ReasonFolder
+		}
+		pages = append(pages, page)
+	}
+
+	sortPages(pages)
+	return pages, nil
+}
+
+// linkParents nests files which are linked from exactly one sibling below that sibling.
+// Assignments which would create a cycle are skipped.
+func linkParents(files []*sourceFile) map[string]string {
+	known := map[string]bool{}
+	for _, f := range files {
+		known[f.relPath] = true
+	}
+
+	inbound := map[string][]string{}
+	for _, f := range files {
+		seen := map[string]bool{}
+		for _, link := range f.links {
+			target := path.Join(path.Dir(f.relPath), link)
+			if target == f.relPath || !known[target] || seen[target] {
+				continue
+			}
+			seen[target] = true
+			inbound[target] = append(inbound[target], f.relPath)
+		}
+	}
+
+	parents := map[string]string{}
+	for _, f := range files {
+		sources := inbound[f.relPath]
+		if len(sources) != 1 {
+			continue
+		}
+		parent := sources[0]
+		cycle := false
+		for p, ok := parent, true; ok; p, ok = parents[p] {
+			if p == f.relPath {
+				cycle = true
+				break
+			}
+		}
+		if !cycle {
+			parents[f.relPath] = parent
+		}
+	}
+	return parents
+}
+
+func readSourceFile(sourceDir, relPath string) (*sourceFile, error) {
+	content, err := ReadSource(sourceDir, relPath)
+	if err != nil {
+		return nil, err
+	}
+	return &sourceFile{
+		relPath: relPath,
+		title:   ExtractTitle(content),
+		links:   extractLinks(content),
+	}, nil
+}
+
+// ExtractTitle returns the frontmatter title or the first level one heading of a Markdown file
+func ExtractTitle(content string) string {
+	fields, body, err := frontmatter.Parse(content)
+	if err == nil {
+		if title, ok := frontmatter.String(fields, "title"); ok && strings.TrimSpace(title) != "" {
+			return strings.TrimSpace(title)
+		}
+	}
+	if match := headingRegex.FindStringSubmatch(body); match != nil {
+		return strings.TrimSpace(match[1])
+	}
+	return ""
+}
+
+func extractLinks(content string) []string {
+	var links []string
+	for _, match := range linkRegex.FindAllStringSubmatch(content, -1) {
+		link := match[1]
+		if strings.Contains(link, "://") || strings.HasPrefix(link, "/") {
+			continue
+		}
+		links = append(links, link)
+	}
+	return links
+}
+
+func findIndexFile(sourceDir, relDir string) string {
+	entries, err := os.ReadDir(filepath.Join(sourceDir, filepath.FromSlash(relDir)))
+	if err != nil {
+		return ""
+	}
+	// index.md wins over README.md
+	found := ""
+	for _, entry := range entries {
+		name := strings.ToLower(entry.Name())
+		if entry.IsDir() || !indexFiles[name] {
+			continue
+		}
+		if found == "" || name == "index.md" {
+			found = path.Join(relDir, entry.Name())
+		}
+	}
+	return found
+}
+
+// uniqueSlug proposes a slug which is unique among the already proposed siblings.
+// File names often contain underscores, which are kept by the slug normalization
+// but rejected by the slug validation, so they are replaced first.
+func uniqueSlug(slugs *tree.SlugService, siblings *tree.PageNode, name string) string {
+	name = strings.ReplaceAll(name, "_", "-")
+	// reserved slugs are resolved by a numeric suffix, anything else would never become valid
+	if slugs.IsValidSlug(slug.Make(name)+"-1") != nil {
+		name = "page"
+	}
+	proposed := slugs.GenerateUniqueSlug(siblings, "", name)
+	siblings.Children = append(siblings.Children, &tree.PageNode{ID: fmt.Sprintf("import-%d", len(siblings.Children)), Slug: proposed})
+	return proposed
+}
+
+// humanize turns a file or folder name into a title
+func humanize(name string) string {
+	name = strings.NewReplacer("-", " ", "_", " ").Replace(name)
+	name = strings.TrimSpace(name)
+	if name == "" {
+		return "Untitled"
+	}
+	r := []rune(name)
+	return strings.ToUpper(string(r[0])) + string(r[1:])
+}
+
+func sortPages(pages []*ProposedPage) {
+	sort.SliceStable(pages, func(i, j int) bool {
+		return strings.ToLower(pages[i].Title) < strings.ToLower(pages[j].Title)
+	})
+	for _, p := range pages {
+		sortPages(p.Children)
+	}
+}
+
+// ReadSource reads a file of the plan. The path must stay inside the source directory, so it
+// must not be a link either.
+func ReadSource(sourceDir, relPath string) (string, error) {
+	rel := filepath.Clean(filepath.FromSlash(relPath))
+	if rel == "." || filepath.IsAbs(rel) || rel == ".." || strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+		return "", ErrInvalidSourcePath
+	}
+	if info, err := os.Lstat(filepath.Join(sourceDir, rel)); err == nil && info.Mode()&fs.ModeSymlink != 0 {
+		return "", ErrInvalidSourcePath
+	}
+	data, err := os.ReadFile(filepath.Join(sourceDir, rel))
+	if err != nil {
+		return "", err
+	}
+	return string(data), nil
+}
@@ -0,0 +1,98 @@
+package importer
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func writeSource(t *testing.T, dir, rel, content string) {
+	t.Helper()
+	full := filepath.Join(dir, filepath.FromSlash(rel))
+	if err := os.MkdirAll(filepath.Dir(full), 0o755); err != nil {
+		t.Fatalf("failed to create dir: %v", err)
+	}
+	if err := os.WriteFile(full, []byte(content), 0o644); err != nil {
+		t.Fatalf("failed to write file: %v", err)
+	}
+}
+
+func TestAnalyze(t *testing.T) {
+	dir := t.TempDir()
+	writeSource(t, dir, "overview.md", "# Project Overview\nSee [details](details.md).")
+	writeSource(t, dir, "details.md", "Some details without heading")
+	writeSource(t, dir, "standalone.md", "---\ntitle: From Frontmatter\n---\n# Ignored")
+	writeSource(t, dir, "guides/README.md", "# All Guides")
+	writeSource(t, dir, "guides/install_steps.md", "# Installing")
+	writeSource(t, dir, "empty/.keep", "")
+
+	plan, err := Analyze(dir)
+	if err != nil {
+		t.Fatalf("Analyze failed: %v", err)
+	}
+
+	if plan.Count() != 5 {
+		t.Fatalf("expected 5 pages, got %d: %+v", plan.Count(), plan.Pages)
+	}
+
+	byTitle := map[string]*ProposedPage{}
+	var collect func(pages []*ProposedPage)
+	collect = func(pages []*ProposedPage) {
+		for _, p := range pages {
+			byTitle[p.Title] = p
+			collect(p.Children)
+		}
+	}
+	collect(plan.Pages)
+
+	guides := byTitle["All Guides"]
+	if guides == nil || guides.SourcePath != "guides/README.md" || guides.Slug != "guides" || guides.Reason != ReasonFolder {
+		t.Fatalf("unexpected folder page: %+v", guides)
+	}
+	if len(guides.Children) != 1 || guides.Children[0].Title != "Installing" || guides.Children[0].Slug != "install-steps" {
+		t.Errorf("unexpected folder children: %+v", guides.Children)
+	}
+
+	overview := byTitle["Project Overview"]
+	if overview == nil || len(overview.Children) != 1 || overview.Children[0].Title != "Details" || overview.Children[0].Reason != ReasonLink {
+		t.Errorf("expected details to be nested below overview by link, got %+v", overview)
+	}
+
+	if byTitle["From Frontmatter"] == nil {
+		t.Errorf("expected frontmatter title to be used")
+	}
+}
+
+func TestAnalyze_LinkCycles(t *testing.T) {
+	dir := t.TempDir()
+	writeSource(t, dir, "a.md", "[b](b.md)")
+	writeSource(t, dir, "b.md", "[a](a.md)")
+
+	plan, err := Analyze(dir)
+	if err != nil {
+		t.Fatalf("Analyze failed: %v", err)
+	}
+	if plan.Count() != 2 || len(plan.Pages) != 1 {
+		t.Fatalf("expected one page nested below the other, got %+v", plan.Pages)
+	}
+}
+
+func TestReadSource_RejectsTraversal(t *testing.T) {
+	dir := t.TempDir()
+	writeSource(t, dir, "a.md", "content")
+
+	if content, err := ReadSource(dir, "a.md"); err != nil || content != "content" {
+		t.Fatalf("expected content, got %q, %v", content, err)
+	}
+	for _, p := range []string{"../a.md", "", "/etc/passwd", "sub/../../a.md"} {
+		if _, err := ReadSource(dir, p); err != ErrInvalidSourcePath {
+			t.Errorf("expected ErrInvalidSourcePath for %q, got %v", p, err)
+		}
+	}
+}
+
+func TestAnalyze_MissingSource(t *testing.T) {
+	if _, err := Analyze(filepath.Join(t.TempDir(), "missing")); err != ErrSourceNotFound {
+		t.Errorf("expected ErrSourceNotFound, got %v", err)
+	}
+}
@@ -0,0 +1,80 @@
+package importer
+
+import (
+	"archive/zip"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// ExtractArchive writes the files of a zip archive to destDir, which can then be analyzed
+// like a folder. Entries which would end up outside destDir, links and other special files
+// are rejected. maxSize limits the extracted size of all files together.
+func ExtractArchive(r io.ReaderAt, size int64, destDir string, maxSize int64) error {
+	zr, err := zip.NewReader(r, size)
+	if err != nil {
+		return ErrInvalidArchive
+	}
+
+	var total int64
+	for _, f := range zr.File {
+		rel := filepath.Clean(filepath.FromSlash(f.Name))
+		if filepath.IsAbs(rel) || rel == ".." || strings.HasPrefix(rel, ".."+string(filepath.Separator)) ||
+			strings.Contains(f.Name, `\`) {
+			return ErrInvalidSourcePath
+		}
+		target := filepath.Join(destDir, rel)
+
+		mode := f.Mode()
+		switch {
+		case mode.IsDir():
+			if err := os.MkdirAll(target, 0o755); err != nil {
+				return err
+			}
+			continue
+		case !mode.IsRegular():
+			return ErrInvalidArchive
+		}
+
+		if err := os.MkdirAll(filepath.Dir(target), 0o755); err != nil {
+			return err
+		}
+		written, err := extractFile(f, target, maxSize-total)
+		if err != nil {
+			return err
+		}
+		total += written
+	}
+	return nil
+}
+
+// extractFile writes a file of the archive, at most limit bytes. The size in the header is not
+// trusted, the content is counted while it is written.
+func extractFile(f *zip.File, target string, limit int64) (int64, error) {
+	src, err := f.Open()
+	if err != nil {
+		return 0, ErrInvalidArchive
+	}
+	defer src.Close()
+
+	dst, err := os.OpenFile(target, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0o644)
+	if os.IsExist(err) {
+		// the archive has the same file twice
+		return 0, ErrInvalidArchive
+	}
+	if err != nil {
+		return 0, err
+	}
+	written, err := io.Copy(dst, io.LimitReader(src, limit+1))
+	if closeErr := dst.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		return written, ErrInvalidArchive
+	}
+	if written > limit {
+		return written, ErrArchiveTooLarge
+	}
+	return written, nil
+}
@@ -0,0 +1,81 @@
+package importer
+
+import (
+	"archive/zip"
+	"bytes"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func zipArchive(t *testing.T, files map[string]string) *bytes.Reader {
+	t.Helper()
+	var buf bytes.Buffer
+	zw := zip.NewWriter(&buf)
+	for name, content := range files {
+		f, err := zw.Create(name)
+		if err != nil {
+			t.Fatalf("failed to add %s: %v", name, err)
+		}
+		if _, err := f.Write([]byte(content)); err != nil {
+			t.Fatalf("failed to write %s: %v", name, err)
+		}
+	}
+	if err := zw.Close(); err != nil {
+		t.Fatalf("failed to close archive: %v", err)
+	}
+	return bytes.NewReader(buf.Bytes())
+}
+
+func TestExtractArchive(t *testing.T) {
+	dir := t.TempDir()
+	archive := zipArchive(t, map[string]string{"docs/": "", "docs/index.md": "# Docs", "home.md": "# Home"})
+	if err := ExtractArchive(archive, archive.Size(), dir, 1<<20); err != nil {
+		t.Fatalf("ExtractArchive failed: %v", err)
+	}
+	if data, err := os.ReadFile(filepath.Join(dir, "docs", "index.md")); err != nil || string(data) != "# Docs" {
+		t.Errorf("expected the extracted file, got %q, %v", data, err)
+	}
+
+	for name, files := range map[string]map[string]string{
+		"traversal": {"../evil.md": "x"},
+		"absolute":  {"/etc/evil.md": "x"},
+		"backslash": {`..\evil.md`: "x"},
+	} {
+		archive := zipArchive(t, files)
+		if err := ExtractArchive(archive, archive.Size(), t.TempDir(), 1<<20); err != ErrInvalidSourcePath {
+			t.Errorf("%s: expected ErrInvalidSourcePath, got %v", name, err)
+		}
+	}
+
+	archive = zipArchive(t, map[string]string{"a.md": "12345", "b.md": "67890"})
+	if err := ExtractArchive(archive, archive.Size(), t.TempDir(), 8); err != ErrArchiveTooLarge {
+		t.Errorf("expected ErrArchiveTooLarge, got %v", err)
+	}
+
+	notZip := bytes.NewReader([]byte("# not a zip"))
+	if err := ExtractArchive(notZip, notZip.Size(), t.TempDir(), 1<<20); err != ErrInvalidArchive {
+		t.Errorf("expected ErrInvalidArchive, got %v", err)
+	}
+}
+
+func TestAnalyze_SkipsLinks(t *testing.T) {
+	outside := t.TempDir()
+	writeSource(t, outside, "secret.md", "# Secret")
+	dir := t.TempDir()
+	writeSource(t, dir, "home.md", "# Home")
+	if err := os.Symlink(filepath.Join(outside, "secret.md"), filepath.Join(dir, "secret.md")); err != nil {
+		t.Skipf("symlinks not supported: %v", err)
+	}
+
+	plan, err := Analyze(dir)
+	if err != nil {
+		t.Fatalf("Analyze failed: %v", err)
+	}
+	if len(plan.Pages) != 1 || plan.Pages[0].SourcePath != "home.md" {
+		t.Errorf("expected only home.md, got %+v", plan.Pages)
+	}
+	if _, err := ReadSource(dir, "secret.md"); err != ErrInvalidSourcePath {
+		t.Errorf("expected ErrInvalidSourcePath for a link, got %v", err)
+	}
+}
@@ -0,0 +1,8 @@
+package importer
+
+import "errors"
+
+var ErrSourceNotFound = errors.New("import source directory not found")
+var ErrInvalidSourcePath = errors.New("import source path is outside the source directory")
+var ErrInvalidArchive = errors.New("import archive is not a valid zip file")
+var ErrArchiveTooLarge = errors.New("import archive is too large")
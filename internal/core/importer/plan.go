@@ -0,0 +1,94 @@
+package importer
+
+// Reasons why a page was placed at its position in the proposed hierarchy
+const (
+	ReasonRoot   = "root"
+	ReasonFolder = "folder"
+	ReasonLink   = "link"
+)
+
+// ProposedPage is a page of the proposed hierarchy.
+// SourcePath is relative to the source directory and empty for folders without an index file.
+type ProposedPage struct {
+	SourcePath string          `json:"sourcePath"`
+	Title      string          `json:"title"`
+	Slug       string          `json:"slug"`
+	Reason     string          `json:"reason"`
+	Children   []*ProposedPage `json:"children"`
+}
+
+// CollisionStrategy decides what happens when an imported page has the slug of a page which
+// already exists below the same parent
+type CollisionStrategy string
+
+const (
+	// CollisionSuffix imports the page with a numeric suffix, e.g. guide-1
+	CollisionSuffix CollisionStrategy = "suffix"
+	// CollisionReject aborts the import before any page is created and lists all collisions
+	CollisionReject CollisionStrategy = "reject"
+	// CollisionMerge imports the children into the existing page. The existing page keeps
+	// its content, unless it is empty.
+	CollisionMerge CollisionStrategy = "merge"
+)
+
+// Valid reports whether the strategy is known, empty means CollisionSuffix
+func (s CollisionStrategy) Valid() bool {
+	return s == "" || s == CollisionSuffix || s == CollisionReject || s == CollisionMerge
+}
+
+// Plan is the proposed hierarchy for a folder of Markdown files.
+// It can be edited by the user before it is applied.
+type Plan struct {
+	SourceDir string          `json:"sourceDir"`
+	Pages     []*ProposedPage `json:"pages"`
+	// Skipped lists Markdown files which could not be read
+	Skipped []string `json:"skipped"`
+	// OnCollision resolves slugs which are already taken, CollisionSuffix by default
+	OnCollision CollisionStrategy `json:"onCollision"`
+}
+
+// Resolutions of slug collisions in the import report
+const (
+	ResolutionRenamed = "renamed"
+	ResolutionMerged  = "merged"
+	// ResolutionSkipped is an existing page which kept its own content, the children of the
+	// imported page are still merged into it
+	ResolutionSkipped = "skipped"
+)
+
+// Collision is an imported page whose slug was already taken
+type Collision struct {
+	SourcePath string `json:"sourcePath"`
+	Slug       string `json:"slug"`
+	// Resolution is renamed, merged or skipped
+	Resolution string `json:"resolution"`
+	// Reason explains why a page was skipped
+	Reason string `json:"reason,omitempty"`
+	// PageID is the imported page, or the existing page it was merged into
+	PageID string `json:"pageId"`
+	// ResolvedSlug is the slug the page was imported with
+	ResolvedSlug string `json:"resolvedSlug"`
+}
+
+// Report describes the result of applying a plan
+type Report struct {
+	Created int `json:"created"`
+	// Merged counts the pages merged into existing pages
+	Merged int `json:"merged"`
+	// Skipped counts the existing pages which kept their content
+	Skipped    int         `json:"skipped"`
+	Collisions []Collision `json:"collisions"`
+}
+
+// Count returns the number of pages in the plan
+func (p *Plan) Count() int {
+	var count func(pages []*ProposedPage) int
+	count = func(pages []*ProposedPage) int {
+		n := 0
+		for _, page := range pages {
+			n += 1 + count(page.Children)
+		}
+		return n
+	}
+	return count(p.Pages)
+}
@@ -0,0 +1,54 @@
+// Package include expands {{include: path/to/page}} directives, which embed the content of one
+// page in another when it is rendered
+package include
+
+import (
+	"regexp"
+	"strings"
+
+	"github.com/Gomez12/wiki/internal/core/frontmatter"
+	"github.com/Gomez12/wiki/internal/core/macro"
+)
+
+// MaxDepth limits how deep includes may be nested
+const MaxDepth = 5
+
+// directiveRegex matches {{include: path/to/page}}, group 1 is the route of the page
+var directiveRegex = regexp.MustCompile(`\{\{\s*include:\s*/?([^}\s]+?)/?\s*\}\}`)
+
+// Resolver returns the Markdown of the page with the route, e.g. docs/warning
+type Resolver func(route string) (string, bool)
+
+// Expand replaces the include directives of the Markdown with the content of the included
+// pages, without their frontmatter. route is the page being rendered, it is used to detect
+// cycles. Directives in code blocks and code spans are kept, so the syntax can be documented.
+// Missing pages, cycles and too deep nesting are shown as note instead of the content.
+func Expand(markdown, route string, resolve Resolver) string {
+	return expand(markdown, []string{strings.Trim(route, "/")}, resolve)
+}
+
+func expand(markdown string, stack []string, resolve Resolver) string {
+	return forEachDirective(markdown, func(route string) string {
+		for _, parent := range stack {
+			if parent == route {
+				return macro.Note("include of %s skipped: it includes itself", route)
+			}
+		}
+		if len(stack) > MaxDepth {
+			return macro.Note("include of %s skipped: includes are nested deeper than %d levels", route, MaxDepth)
+		}
+		content, ok := resolve(route)
+		if !ok {
+			return macro.Note("include of %s failed: page not found", route)
+		}
+		_, body, _ := frontmatter.Split(content)
+		return strings.TrimRight(expand(body, append(stack[:len(stack):len(stack)], route), resolve), "\n")
+	})
+}
+
+// forEachDirective replaces the directives outside of code with the result of replace
+func forEachDirective(markdown string, replace func(route string) string) string {
+	return macro.ReplaceOutsideCode(markdown, directiveRegex, func(match []string) string {
+		return replace(match[1])
+	})
+}
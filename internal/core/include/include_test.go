@@ -0,0 +1,50 @@
+package include
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestExpand(t *testing.T) {
+	pages := map[string]string{
+		"shared/warning": "---\nauthor: ops\n---\n> **Warning:** {{include: shared/detail}}\n",
+		"shared/detail":  "Back up first.",
+		"loop/a":         "A {{include: loop/b}}",
+		"loop/b":         "B {{include: loop/a}}",
+	}
+	resolve := func(route string) (string, bool) {
+		content, ok := pages[route]
+		return content, ok
+	}
+
+	got := Expand("# Guide\n{{include: /shared/warning/}}\nText", "guide", resolve)
+	if got != "# Guide\n> **Warning:** Back up first.\nText" {
+		t.Errorf("Unexpected expansion %q", got)
+	}
+
+	got = Expand("{{ include: missing }}", "guide", resolve)
+	if !strings.Contains(got, "include of missing failed: page not found") {
+		t.Errorf("Expected a note for missing pages, got %q", got)
+	}
+
+	got = Expand("{{include: loop/b}}", "loop/a", resolve)
+	if got != "B *⚠ include of loop/a skipped: it includes itself*" {
+		t.Errorf("Expected the cycle to be detected, got %q", got)
+	}
+
+	code := "`{{include: shared/detail}}`\n```\n{{include: shared/detail}}\n```\n"
+	if got := Expand(code, "guide", resolve); got != code {
+		t.Errorf("Expected directives in code to be kept, got %q", got)
+	}
+}
+
+func TestExpand_MaxDepth(t *testing.T) {
+	resolve := func(route string) (string, bool) {
+		// every level includes the next one
+		return "x{{include: " + route + "x}}", true
+	}
+	got := Expand("{{include: p}}", "root", resolve)
+	if strings.Count(got, "x") < MaxDepth || !strings.Contains(got, "nested deeper than 5 levels") {
+		t.Errorf("Expected nesting to stop at %d levels, got %q", MaxDepth, got)
+	}
+}
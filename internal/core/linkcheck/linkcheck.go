@@ -0,0 +1,199 @@
+// Package linkcheck finds dead external links in the pages of the wiki
+package linkcheck
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/Gomez12/wiki/internal/core/frontmatter"
+	"github.com/russross/blackfriday/v2"
+)
+
+// ErrRunning is returned when a check is started while another one is running
+var ErrRunning = errors.New("link check is already running")
+
+// Config of the checker
+type Config struct {
+	// Interval between two scheduled checks, 0 checks only on demand
+	Interval time.Duration
+	// Delay between two requests, 500ms by default, so remote servers are not flooded
+	Delay time.Duration
+	// Timeout of a single request, 10 seconds by default
+	Timeout time.Duration
+}
+
+// Page is a page whose links are checked
+type Page struct {
+	ID      string
+	Content string
+}
+
+// Result is the outcome of checking one link of a page
+type Result struct {
+	PageID     string    `json:"pageId"`
+	URL        string    `json:"url"`
+	StatusCode int       `json:"statusCode"`
+	Error      string    `json:"error,omitempty"`
+	CheckedAt  time.Time `json:"checkedAt"`
+}
+
+// Dead returns true if the link could not be reached or answered with an error.
+// 429 Too Many Requests says nothing about the link, so it is not dead.
+func (r Result) Dead() bool {
+	return r.Error != "" || (r.StatusCode >= 400 && r.StatusCode != http.StatusTooManyRequests)
+}
+
+// Checker checks the external links of pages, one check at a time
+type Checker struct {
+	cfg    Config
+	client *http.Client
+	store  *Store
+
+	mu      sync.Mutex
+	running bool
+}
+
+// New opens the result store in storageDir and fills in the defaults of the config
+func New(storageDir string, cfg Config) (*Checker, error) {
+	if cfg.Delay <= 0 {
+		cfg.Delay = 500 * time.Millisecond
+	}
+	if cfg.Timeout <= 0 {
+		cfg.Timeout = 10 * time.Second
+	}
+	store, err := NewStore(storageDir)
+	if err != nil {
+		return nil, err
+	}
+	return &Checker{cfg: cfg, client: &http.Client{Timeout: cfg.Timeout}, store: store}, nil
+}
+
+// Interval returns the time between two scheduled checks, 0 if checks only run on demand
+func (c *Checker) Interval() time.Duration {
+	return c.cfg.Interval
+}
+
+// Running returns true while a check is running
+func (c *Checker) Running() bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.running
+}
+
+// Begin marks a check as running, so the caller can start it in the background
+// and report ErrRunning right away. End must be called when the check is done.
+func (c *Checker) Begin() error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.running {
+		return ErrRunning
+	}
+	c.running = true
+	return nil
+}
+
+// End marks the running check as done
+func (c *Checker) End() {
+	c.mu.Lock()
+	c.running = false
+	c.mu.Unlock()
+}
+
+// Check requests every external link of the pages once, waiting Delay between two requests,
+// and replaces the stored results. A cancelled check keeps the previous results.
+// The check must have been started with Begin.
+func (c *Checker) Check(ctx context.Context, pages []Page) error {
+	checked := map[string]Result{}
+	results := []Result{}
+	for _, page := range pages {
+		for _, url := range ExtractURLs(page.Content) {
+			result, ok := checked[url]
+			if !ok {
+				if len(checked) > 0 {
+					select {
+					case <-ctx.Done():
+						return ctx.Err()
+					case <-time.After(c.cfg.Delay):
+					}
+				}
+				result = c.checkURL(ctx, url)
+				if ctx.Err() != nil {
+					return ctx.Err()
+				}
+				checked[url] = result
+			}
+			result.PageID = page.ID
+			results = append(results, result)
+		}
+	}
+	return c.store.ReplaceResults(results)
+}
+
+// Results returns the results of the last check
+func (c *Checker) Results() ([]Result, error) {
+	return c.store.Results()
+}
+
+func (c *Checker) Close() error {
+	return c.store.Close()
+}
+
+// checkURL sends a HEAD request and falls back to GET for servers which don't support HEAD
+func (c *Checker) checkURL(ctx context.Context, url string) Result {
+	result := Result{URL: url, CheckedAt: time.Now().UTC()}
+	status, err := c.request(ctx, http.MethodHead, url)
+	if err == nil && (status == http.StatusMethodNotAllowed || status == http.StatusNotImplemented) {
+		status, err = c.request(ctx, http.MethodGet, url)
+	}
+	if err != nil {
+		result.Error = err.Error()
+		return result
+	}
+	result.StatusCode = status
+	return result
+}
+
+func (c *Checker) request(ctx context.Context, method, url string) (int, error) {
+	req, err := http.NewRequestWithContext(ctx, method, url, nil)
+	if err != nil {
+		return 0, err
+	}
+	req.Header.Set("User-Agent", "LeafWiki link checker")
+	resp, err := c.client.Do(req)
+	if err != nil {
+		return 0, fmt.Errorf("request failed: %w", errors.Unwrap(err))
+	}
+	// the body is not needed, but a little of it is read so the connection can be reused
+	_, _ = io.CopyN(io.Discard, resp.Body, 4096)
+	_ = resp.Body.Close()
+	return resp.StatusCode, nil
+}
+
+// ExtractURLs returns the http and https links and images of the Markdown body without
+// duplicates. Links in code are skipped.
+func ExtractURLs(markdown string) []string {
+	_, body, _ := frontmatter.Split(markdown)
+	root := blackfriday.New(blackfriday.WithExtensions(blackfriday.CommonExtensions)).Parse([]byte(body))
+
+	urls := []string{}
+	seen := map[string]bool{}
+	root.Walk(func(node *blackfriday.Node, entering bool) blackfriday.WalkStatus {
+		if !entering || (node.Type != blackfriday.Link && node.Type != blackfriday.Image) {
+			return blackfriday.GoToNext
+		}
+		url := string(node.LinkData.Destination)
+		lower := strings.ToLower(url)
+		if (strings.HasPrefix(lower, "http://") || strings.HasPrefix(lower, "https://")) && !seen[url] {
+			seen[url] = true
+			urls = append(urls, url)
+		}
+		return blackfriday.GoToNext
+	})
+	return urls
+}
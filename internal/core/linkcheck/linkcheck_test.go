@@ -0,0 +1,97 @@
+package linkcheck
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestExtractURLs(t *testing.T) {
+	markdown := "---\nsource: https://front.example.com\n---\n" +
+		"[Docs](https://example.com/docs) and ![Logo](http://example.com/logo.png) and [internal](/docs/guide)\n\n" +
+		"Again [docs](https://example.com/docs), mail [me](mailto:me@example.com) and `https://code.example.com`\n\n" +
+		"```\n[skipped](https://block.example.com)\n```\n"
+
+	urls := ExtractURLs(markdown)
+	if strings.Join(urls, " ") != "https://example.com/docs http://example.com/logo.png" {
+		t.Errorf("unexpected URLs %v", urls)
+	}
+}
+
+func TestChecker_Check(t *testing.T) {
+	var requests atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests.Add(1)
+		switch r.URL.Path {
+		case "/ok":
+			w.WriteHeader(http.StatusOK)
+		case "/no-head":
+			if r.Method == http.MethodHead {
+				w.WriteHeader(http.StatusMethodNotAllowed)
+				return
+			}
+			w.WriteHeader(http.StatusOK)
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer server.Close()
+
+	checker, err := New(t.TempDir(), Config{Delay: time.Millisecond})
+	if err != nil {
+		t.Fatalf("New failed: %v", err)
+	}
+	defer checker.Close()
+
+	pages := []Page{
+		{ID: "a", Content: "[ok](" + server.URL + "/ok) [gone](" + server.URL + "/gone)"},
+		{ID: "b", Content: "[ok](" + server.URL + "/ok) [head](" + server.URL + "/no-head) [down](http://127.0.0.1:1/)"},
+	}
+	if err := checker.Begin(); err != nil {
+		t.Fatalf("Begin failed: %v", err)
+	}
+	if err := checker.Begin(); !errors.Is(err, ErrRunning) {
+		t.Errorf("expected ErrRunning, got %v", err)
+	}
+	err = checker.Check(context.Background(), pages)
+	checker.End()
+	if err != nil {
+		t.Fatalf("Check failed: %v", err)
+	}
+	// ok once, gone once, no-head with HEAD and GET
+	if got := requests.Load(); got != 4 {
+		t.Errorf("expected each URL to be requested once, got %d requests", got)
+	}
+
+	results, err := checker.Results()
+	if err != nil {
+		t.Fatalf("Results failed: %v", err)
+	}
+	if len(results) != 5 {
+		t.Fatalf("expected 5 results, got %+v", results)
+	}
+	dead := []string{}
+	for _, r := range results {
+		if r.Dead() {
+			dead = append(dead, r.PageID+" "+strings.TrimPrefix(r.URL, server.URL))
+		}
+	}
+	if strings.Join(dead, ",") != "a /gone,b http://127.0.0.1:1/" {
+		t.Errorf("unexpected dead links %v", dead)
+	}
+
+	// a cancelled check keeps the previous results
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if err := checker.Check(ctx, pages); !errors.Is(err, context.Canceled) {
+		t.Errorf("expected context.Canceled, got %v", err)
+	}
+	if results, _ := checker.Results(); len(results) != 5 {
+		t.Errorf("expected previous results to be kept, got %d", len(results))
+	}
+}
@@ -0,0 +1,115 @@
+package linkcheck
+
+import (
+	"database/sql"
+	"path"
+	"time"
+
+	_ "modernc.org/sqlite"
+)
+
+// Store keeps the results of the last check in links.db
+type Store struct {
+	storageDir string
+	filename   string
+	db         *sql.DB
+}
+
+func NewStore(storageDir string) (*Store, error) {
+	s := &Store{
+		storageDir: storageDir,
+		filename:   "links.db",
+	}
+
+	if err := s.Connect(); err != nil {
+		return nil, err
+	}
+	return s, s.ensureSchema()
+}
+
+func (s *Store) Connect() error {
+	// Database is already open and connected
+	if s.db != nil {
+		return nil
+	}
+	db, err := sql.Open("sqlite", path.Join(s.storageDir, s.filename))
+	if err != nil {
+		return err
+	}
+	s.db = db
+	return nil
+}
+
+func (s *Store) ensureSchema() error {
+	_, err := s.db.Exec(`
+		CREATE TABLE IF NOT EXISTS link_results (
+			page_id TEXT NOT NULL,
+			url TEXT NOT NULL,
+			status_code INTEGER NOT NULL DEFAULT 0,
+			error TEXT NOT NULL DEFAULT '',
+			checked_at INTEGER NOT NULL,
+			PRIMARY KEY (page_id, url)
+		);
+	`)
+	return err
+}
+
+func (s *Store) Close() error {
+	if s.db != nil {
+		err := s.db.Close()
+		if err != nil {
+			return err
+		}
+		s.db = nil
+	}
+	return nil
+}
+
+// ReplaceResults stores the results of a check in place of the previous ones
+func (s *Store) ReplaceResults(results []Result) error {
+	if err := s.Connect(); err != nil {
+		return err
+	}
+	tx, err := s.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer func() { _ = tx.Rollback() }()
+
+	if _, err := tx.Exec(`DELETE FROM link_results`); err != nil {
+		return err
+	}
+	for _, r := range results {
+		if _, err := tx.Exec(`
+			INSERT INTO link_results (page_id, url, status_code, error, checked_at) VALUES (?, ?, ?, ?, ?)
+			ON CONFLICT(page_id, url) DO NOTHING
+		`, r.PageID, r.URL, r.StatusCode, r.Error, r.CheckedAt.UnixNano()); err != nil {
+			return err
+		}
+	}
+	return tx.Commit()
+}
+
+// Results returns the stored results ordered by page and URL
+func (s *Store) Results() ([]Result, error) {
+	if err := s.Connect(); err != nil {
+		return nil, err
+	}
+	rows, err := s.db.Query(`SELECT page_id, url, status_code, error, checked_at FROM link_results ORDER BY page_id, url`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	results := []Result{}
+	for rows.Next() {
+		var r Result
+		var checkedAt int64
+		if err := rows.Scan(&r.PageID, &r.URL, &r.StatusCode, &r.Error, &checkedAt); err != nil {
+			return nil, err
+		}
+		r.CheckedAt = time.Unix(0, checkedAt).UTC()
+		results = append(results, r)
+	}
+	return results, rows.Err()
+}
@@ -0,0 +1,200 @@
+// Package lint checks a page against content rules, like a missing title heading or images
+// without alt text. Unlike the problems found by validate, the findings don't change how the
+// page is rendered, every rule can be switched off in the settings.
+package lint
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/Gomez12/wiki/internal/core/frontmatter"
+)
+
+// Rules of the warnings
+const (
+	RuleMissingH1        = "missing-h1"
+	RuleDuplicateHeading = "duplicate-heading"
+	RuleBrokenLink       = "broken-link"
+	RuleImageAlt         = "image-alt"
+	RuleLongLine         = "long-line"
+)
+
+// DefaultMaxLineLength is the number of characters a line may have by default
+const DefaultMaxLineLength = 120
+
+// Config enables the rules
+type Config struct {
+	MissingH1         bool `json:"missingH1"`
+	DuplicateHeadings bool `json:"duplicateHeadings"`
+	// BrokenLinks checks the links to other pages and assets, external links are checked by
+	// the link check
+	BrokenLinks bool `json:"brokenLinks"`
+	ImageAlt    bool `json:"imageAlt"`
+	LongLines   bool `json:"longLines"`
+	// MaxLineLength is the number of characters a line may have with LongLines
+	MaxLineLength int `json:"maxLineLength"`
+}
+
+// DefaultConfig enables every rule
+func DefaultConfig() Config {
+	return Config{
+		MissingH1:         true,
+		DuplicateHeadings: true,
+		BrokenLinks:       true,
+		ImageAlt:          true,
+		LongLines:         true,
+		MaxLineLength:     DefaultMaxLineLength,
+	}
+}
+
+// Warning is a finding of a rule. Line is the 1-based line of the content it refers to.
+type Warning struct {
+	Rule    string `json:"rule"`
+	Line    int    `json:"line"`
+	Message string `json:"message"`
+}
+
+// Result lists the warnings of a page, empty if none were found
+type Result struct {
+	Warnings []Warning `json:"warnings"`
+}
+
+// LinkExists reports whether the target of a link without scheme exists, e.g. /docs/guide,
+// ../setup or /assets/<page id>/diagram.png. Query and fragment are removed.
+type LinkExists func(target string) bool
+
+var (
+	fenceRegex = regexp.MustCompile("^ {0,3}(`{3,}|~{3,})")
+	// headingRegex matches ATX headings, the second group is the text without closing hashes
+	headingRegex = regexp.MustCompile(`^ {0,3}(#{1,6})(?:[ \t]+(.*?))?(?:[ \t]+#+)?[ \t]*$`)
+	// setextH1Regex matches the underline of a level 1 setext heading
+	setextH1Regex = regexp.MustCompile(`^ {0,3}=+[ \t]*$`)
+	codeSpanRegex = regexp.MustCompile("`+[^`]*`+")
+	// linkRegex matches inline links and images, the groups are the !, the text and the target
+	linkRegex   = regexp.MustCompile(`(!?)\[([^\]]*)\]\(\s*<?([^)\s>]*)>?(?:\s+[^)]*)?\)`)
+	schemeRegex = regexp.MustCompile(`^[a-zA-Z][a-zA-Z0-9+.-]*:`)
+)
+
+// Page checks the content with the enabled rules. exists is only called for BrokenLinks.
+func Page(content string, cfg Config, exists LinkExists) Result {
+	lines := strings.Split(strings.ReplaceAll(content, "\r\n", "\n"), "\n")
+	start := bodyStart(content, lines)
+
+	var warnings []Warning
+	headings := map[string]int{}
+	hasH1 := false
+	var fence string
+	for i := start; i < len(lines); i++ {
+		line, number := lines[i], i+1
+		if fence != "" {
+			// the closing fence uses the same character, at least as often, and nothing else
+			if match := fenceRegex.FindStringSubmatch(line); match != nil && match[1][0] == fence[0] &&
+				len(match[1]) >= len(fence) && strings.TrimSpace(line[len(match[0]):]) == "" {
+				fence = ""
+			}
+			continue
+		}
+		if match := fenceRegex.FindStringSubmatch(line); match != nil {
+			fence = match[1]
+			continue
+		}
+
+		level, text := heading(lines, i, start)
+		if level == 1 {
+			hasH1 = true
+		}
+		if key := strings.ToLower(text); key != "" && cfg.DuplicateHeadings {
+			if first, ok := headings[key]; ok {
+				warnings = append(warnings, Warning{Rule: RuleDuplicateHeading, Line: number,
+					Message: fmt.Sprintf("the heading %q is already used on line %d", text, first)})
+			} else {
+				headings[key] = number
+			}
+		}
+
+		warnings = append(warnings, checkLinks(line, number, cfg, exists)...)
+		if cfg.LongLines && cfg.MaxLineLength > 0 && longLine(line, cfg.MaxLineLength) {
+			warnings = append(warnings, Warning{Rule: RuleLongLine, Line: number,
+				Message: fmt.Sprintf("the line has %d characters, more than %d", utf8.RuneCountInString(line), cfg.MaxLineLength)})
+		}
+	}
+
+	if cfg.MissingH1 && !hasH1 {
+		warnings = append(warnings, Warning{Rule: RuleMissingH1, Line: start + 1, Message: "the page has no level 1 heading"})
+	}
+	sort.SliceStable(warnings, func(i, j int) bool { return warnings[i].Line < warnings[j].Line })
+	if warnings == nil {
+		warnings = []Warning{}
+	}
+	return Result{Warnings: warnings}
+}
+
+// bodyStart returns the index of the first line after the frontmatter
+func bodyStart(content string, lines []string) int {
+	if strings.TrimRight(strings.TrimPrefix(lines[0], "\ufeff"), "\r ") != "---" {
+		return 0
+	}
+	front, _, ok := frontmatter.Split(content)
+	if !ok {
+		return 0
+	}
+	return strings.Count(front, "\n") + 2
+}
+
+// heading returns the level and the text of a heading at lines[i], a setext heading is
+// returned at its underline. The level is 0 for other lines.
+func heading(lines []string, i, start int) (int, string) {
+	if match := headingRegex.FindStringSubmatch(lines[i]); match != nil {
+		return len(match[1]), strings.TrimSpace(match[2])
+	}
+	if i > start && setextH1Regex.MatchString(lines[i]) {
+		if text := strings.TrimSpace(lines[i-1]); text != "" && !headingRegex.MatchString(lines[i-1]) {
+			return 1, text
+		}
+	}
+	return 0, ""
+}
+
+// checkLinks reports images without alt text and links to pages or assets which don't exist
+func checkLinks(line string, number int, cfg Config, exists LinkExists) []Warning {
+	if !cfg.ImageAlt && !cfg.BrokenLinks {
+		return nil
+	}
+	// links in code spans are shown as written
+	line = codeSpanRegex.ReplaceAllStringFunc(line, func(span string) string { return strings.Repeat(" ", len(span)) })
+
+	var warnings []Warning
+	for _, match := range linkRegex.FindAllStringSubmatch(line, -1) {
+		image, text, target := match[1] == "!", match[2], match[3]
+		if image {
+			if cfg.ImageAlt && strings.TrimSpace(text) == "" {
+				warnings = append(warnings, Warning{Rule: RuleImageAlt, Line: number,
+					Message: fmt.Sprintf("the image %s has no alt text", target)})
+			}
+			continue
+		}
+		if !cfg.BrokenLinks || exists == nil || schemeRegex.MatchString(target) || strings.HasPrefix(target, "//") {
+			continue
+		}
+		if cut, _, _ := strings.Cut(target, "#"); cut != "" {
+			if cut, _, _ = strings.Cut(cut, "?"); cut != "" && !exists(cut) {
+				warnings = append(warnings, Warning{Rule: RuleBrokenLink, Line: number,
+					Message: fmt.Sprintf("the link target %s does not exist", target)})
+			}
+		}
+	}
+	return warnings
+}
+
+// longLine reports whether the line is longer than limit characters. Table rows and lines
+// without spaces, like long URLs, can't be wrapped and are left out.
+func longLine(line string, limit int) bool {
+	if utf8.RuneCountInString(line) <= limit {
+		return false
+	}
+	trimmed := strings.TrimSpace(line)
+	return !strings.HasPrefix(trimmed, "|") && strings.ContainsAny(trimmed, " \t")
+}
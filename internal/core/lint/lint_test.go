@@ -0,0 +1,71 @@
+package lint
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+)
+
+func TestPage(t *testing.T) {
+	exists := func(target string) bool { return target == "/docs/guide" || target == "setup" }
+	long := "# Title\n\n" + strings.Repeat("word ", 30) + "\n"
+
+	tests := []struct {
+		name    string
+		content string
+		// want lists the warnings as rule:line
+		want []string
+	}{
+		{"valid page", "---\nstatus: done\n---\n# Title\n\nSee [guide](/docs/guide#setup) and [setup](setup).\n\n![Diagram](/assets/1/d.png)\n", nil},
+		{"missing h1", "## Intro\ntext\n", []string{"missing-h1:1"}},
+		{"missing h1 after frontmatter", "---\ntitle: x\n---\ntext\n", []string{"missing-h1:4"}},
+		{"setext h1", "Title\n=====\n", nil},
+		{"h1 in code", "```\n# not a heading\n```\n", []string{"missing-h1:1"}},
+		{"duplicate headings", "# Title\n## Setup\ntext\n### setup ###\n## Other\n", []string{"duplicate-heading:4"}},
+		{"broken links", "# Title\n[a](/docs/missing) [b](../gone?x=1#top) [c](#top) [d](https://example.com) [e](mailto:a@b.c)\n", []string{"broken-link:2", "broken-link:2"}},
+		{"link in code span", "# Title\n`[a](/docs/missing)`\n", nil},
+		{"image alt", "# Title\n![](a.png) ![ ](b.png) ![Logo](c.png)\n", []string{"image-alt:2", "image-alt:2"}},
+		{"long line", long, []string{"long-line:3"}},
+		{"long url and table", "# Title\n" + strings.Repeat("x", 200) + "\n| " + strings.Repeat("cell ", 40) + "|\n", nil},
+		{"long line in code", "# Title\n```\n" + strings.Repeat("code ", 40) + "\n```\n", nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result := Page(tt.content, DefaultConfig(), exists)
+			if result.Warnings == nil {
+				t.Fatal("expected an empty list instead of nil")
+			}
+			var got []string
+			for _, w := range result.Warnings {
+				if w.Message == "" {
+					t.Errorf("expected a message for %+v", w)
+				}
+				got = append(got, fmt.Sprintf("%s:%d", w.Rule, w.Line))
+			}
+			if fmt.Sprint(got) != fmt.Sprint(tt.want) {
+				t.Errorf("Page() = %v (%+v), want %v", got, result.Warnings, tt.want)
+			}
+		})
+	}
+}
+
+func TestPage_DisabledRules(t *testing.T) {
+	content := "## Intro\n## Intro\n[a](/missing) ![](a.png)\n" + strings.Repeat("word ", 30) + "\n"
+	called := false
+	result := Page(content, Config{MaxLineLength: DefaultMaxLineLength}, func(string) bool {
+		called = true
+		return false
+	})
+	if len(result.Warnings) != 0 || called {
+		t.Errorf("expected no warnings with every rule off, got %+v", result.Warnings)
+	}
+
+	cfg := DefaultConfig()
+	cfg.MaxLineLength = 200
+	for _, w := range Page(content, cfg, nil).Warnings {
+		if w.Rule == RuleLongLine || w.Rule == RuleBrokenLink {
+			t.Errorf("unexpected warning %+v", w)
+		}
+	}
+}
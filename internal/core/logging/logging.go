@@ -0,0 +1,111 @@
+package logging
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"log/slog"
+	"strings"
+)
+
+const (
+	FormatText = "text"
+	FormatJSON = "json"
+)
+
+// ParseLevel parses a log level: debug, info, warn or error
+func ParseLevel(level string) (slog.Level, error) {
+	var l slog.Level
+	if err := l.UnmarshalText([]byte(strings.TrimSpace(level))); err != nil {
+		return 0, fmt.Errorf("invalid log level %q: use debug, info, warn or error", level)
+	}
+	return l, nil
+}
+
+// New creates a logger writing to out with the given level and format (text or json)
+func New(out io.Writer, level, format string) (*slog.Logger, error) {
+	l, err := ParseLevel(level)
+	if err != nil {
+		return nil, err
+	}
+
+	opts := &slog.HandlerOptions{Level: l}
+	switch strings.ToLower(strings.TrimSpace(format)) {
+	case FormatText, "":
+		return slog.New(slog.NewTextHandler(out, opts)), nil
+	case FormatJSON:
+		return slog.New(slog.NewJSONHandler(out, opts)), nil
+	default:
+		return nil, fmt.Errorf("invalid log format %q: use text or json", format)
+	}
+}
+
+// Setup replaces the default logger, which is used by all packages
+func Setup(out io.Writer, level, format string) error {
+	logger, err := New(out, level, format)
+	if err != nil {
+		return err
+	}
+	slog.SetDefault(logger)
+	return nil
+}
+
+// Component returns a logger tagged with a component, e.g. watcher or history.
+// It writes to the default logger at the time of the call, so it can be stored in
+// package variables before Setup runs.
+func Component(name string) *slog.Logger {
+	return slog.New(&defaultHandler{}).With("component", name)
+}
+
+// defaultHandler forwards records to the handler of the current default logger
+type defaultHandler struct {
+	// ops are the WithAttrs and WithGroup calls, applied in order when a record is handled
+	ops []func(slog.Handler) slog.Handler
+}
+
+func (h *defaultHandler) Enabled(ctx context.Context, level slog.Level) bool {
+	return slog.Default().Handler().Enabled(ctx, level)
+}
+
+func (h *defaultHandler) Handle(ctx context.Context, r slog.Record) error {
+	target := slog.Default().Handler()
+	for _, op := range h.ops {
+		target = op(target)
+	}
+	return target.Handle(ctx, r)
+}
+
+func (h *defaultHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	return h.with(func(next slog.Handler) slog.Handler { return next.WithAttrs(attrs) })
+}
+
+func (h *defaultHandler) WithGroup(name string) slog.Handler {
+	return h.with(func(next slog.Handler) slog.Handler { return next.WithGroup(name) })
+}
+
+func (h *defaultHandler) with(op func(slog.Handler) slog.Handler) *defaultHandler {
+	ops := make([]func(slog.Handler) slog.Handler, len(h.ops), len(h.ops)+1)
+	copy(ops, h.ops)
+	return &defaultHandler{ops: append(ops, op)}
+}
+
+type requestIDKey struct{}
+
+// WithRequestID returns a context carrying the request id
+func WithRequestID(ctx context.Context, id string) context.Context {
+	return context.WithValue(ctx, requestIDKey{}, id)
+}
+
+// RequestID returns the request id of the context, or "" if there is none
+func RequestID(ctx context.Context) string {
+	id, _ := ctx.Value(requestIDKey{}).(string)
+	return id
+}
+
+// FromContext returns the default logger tagged with the request id of the context
+func FromContext(ctx context.Context) *slog.Logger {
+	if id := RequestID(ctx); id != "" {
+		return slog.Default().With("request_id", id)
+	}
+	return slog.Default()
+}
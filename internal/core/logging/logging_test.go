@@ -0,0 +1,64 @@
+package logging
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"log/slog"
+	"strings"
+	"testing"
+)
+
+func TestNew(t *testing.T) {
+	var buf bytes.Buffer
+	logger, err := New(&buf, "warn", "json")
+	if err != nil {
+		t.Fatalf("New failed: %v", err)
+	}
+
+	logger.Info("hidden")
+	logger.Warn("shown", "path", "docs/a.md")
+
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	if len(lines) != 1 {
+		t.Fatalf("expected 1 log line, got %d: %q", len(lines), buf.String())
+	}
+	var entry map[string]interface{}
+	if err := json.Unmarshal([]byte(lines[0]), &entry); err != nil {
+		t.Fatalf("expected JSON output: %v", err)
+	}
+	if entry["msg"] != "shown" || entry["path"] != "docs/a.md" || entry["level"] != "WARN" {
+		t.Errorf("unexpected entry: %v", entry)
+	}
+
+	if _, err := New(&buf, "verbose", "text"); err == nil {
+		t.Errorf("expected error for invalid level")
+	}
+	if _, err := New(&buf, "info", "xml"); err == nil {
+		t.Errorf("expected error for invalid format")
+	}
+}
+
+func TestFromContext(t *testing.T) {
+	// component loggers created before Setup write to the configured logger
+	watcherLog := Component("watcher")
+
+	var buf bytes.Buffer
+	prev := slog.Default()
+	defer slog.SetDefault(prev)
+	if err := Setup(&buf, "debug", "text"); err != nil {
+		t.Fatalf("Setup failed: %v", err)
+	}
+
+	ctx := WithRequestID(context.Background(), "abc123")
+	if RequestID(ctx) != "abc123" {
+		t.Errorf("expected request id to be stored in the context")
+	}
+	FromContext(ctx).Info("handled")
+	watcherLog.Debug("indexed")
+
+	out := buf.String()
+	if !strings.Contains(out, "request_id=abc123") || !strings.Contains(out, "component=watcher") {
+		t.Errorf("unexpected output: %q", out)
+	}
+}
@@ -0,0 +1,99 @@
+// Package macro expands {{name arg=value}} macros in Markdown, e.g. {{date}} or {{childlist depth=2}}
+package macro
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// macroRegex matches {{name}} and {{name key=value key="quoted value"}}
+var macroRegex = regexp.MustCompile(`\{\{\s*([a-zA-Z]+)((?:\s+[a-zA-Z]+=(?:"[^"]*"|[^\s"}]+))*)\s*\}\}`)
+
+var argRegex = regexp.MustCompile(`([a-zA-Z]+)=(?:"([^"]*)"|([^\s"}]+))`)
+
+// Func returns the Markdown a macro expands to
+type Func func(args map[string]string) (string, error)
+
+// Macros are the macros known to Expand by name
+type Macros map[string]Func
+
+// Expand replaces the known macros outside of code with their Markdown. Unknown macros are
+// kept as they are, failing ones are replaced by a note.
+func Expand(markdown string, macros Macros) string {
+	return ReplaceOutsideCode(markdown, macroRegex, func(match []string) string {
+		fn, ok := macros[strings.ToLower(match[1])]
+		if !ok {
+			return match[0]
+		}
+		args := map[string]string{}
+		for _, arg := range argRegex.FindAllStringSubmatch(match[2], -1) {
+			args[strings.ToLower(arg[1])] = arg[2] + arg[3]
+		}
+		expanded, err := fn(args)
+		if err != nil {
+			return Note("%s: %v", match[1], err)
+		}
+		return expanded
+	})
+}
+
+// Note formats a message shown in place of a directive which couldn't be expanded
+func Note(format string, args ...any) string {
+	return "*⚠ " + fmt.Sprintf(format, args...) + "*"
+}
+
+// ReplaceOutsideCode replaces the matches of re with the result of replace, leaving code
+// blocks and code spans alone so the syntax can be documented. replace gets the submatches.
+func ReplaceOutsideCode(markdown string, re *regexp.Regexp, replace func(match []string) string) string {
+	if !re.MatchString(markdown) {
+		return markdown
+	}
+
+	lines := strings.SplitAfter(markdown, "\n")
+	fence := ""
+	for i, line := range lines {
+		trimmed := strings.TrimLeft(line, " ")
+		if fence != "" {
+			if strings.HasPrefix(trimmed, fence) {
+				fence = ""
+			}
+			continue
+		}
+		if strings.HasPrefix(trimmed, "```") || strings.HasPrefix(trimmed, "~~~") {
+			fence = trimmed[:3]
+			continue
+		}
+		if strings.HasPrefix(line, "    ") || strings.HasPrefix(line, "\t") {
+			continue
+		}
+		lines[i] = replaceOutsideCodeSpans(line, re, replace)
+	}
+	return strings.Join(lines, "")
+}
+
+// replaceOutsideCodeSpans replaces the matches of a line which aren't inside backticks
+func replaceOutsideCodeSpans(line string, re *regexp.Regexp, replace func(match []string) string) string {
+	matches := re.FindAllStringSubmatchIndex(line, -1)
+	if matches == nil {
+		return line
+	}
+	var b strings.Builder
+	last := 0
+	for _, m := range matches {
+		if strings.Count(line[:m[0]], "`")%2 == 1 {
+			continue
+		}
+		groups := make([]string, len(m)/2)
+		for g := range groups {
+			if m[2*g] >= 0 {
+				groups[g] = line[m[2*g]:m[2*g+1]]
+			}
+		}
+		b.WriteString(line[last:m[0]])
+		b.WriteString(replace(groups))
+		last = m[1]
+	}
+	b.WriteString(line[last:])
+	return b.String()
+}
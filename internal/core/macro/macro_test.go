@@ -0,0 +1,32 @@
+package macro
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestExpand(t *testing.T) {
+	var gotArgs map[string]string
+	macros := Macros{
+		"pagetitle": func(map[string]string) (string, error) { return "Guide", nil },
+		"recentchanges": func(args map[string]string) (string, error) {
+			gotArgs = args
+			return "- changes", nil
+		},
+		"broken": func(map[string]string) (string, error) { return "", errors.New("no access") },
+	}
+
+	got := Expand("# {{pagetitle}}\n{{ recentchanges path=docs title=\"Last edits\" }}\n{{unknown}} {{broken}}", macros)
+	want := "# Guide\n- changes\n{{unknown}} *⚠ broken: no access*"
+	if got != want {
+		t.Errorf("Expected %q, got %q", want, got)
+	}
+	if gotArgs["path"] != "docs" || gotArgs["title"] != "Last edits" {
+		t.Errorf("Unexpected arguments %v", gotArgs)
+	}
+
+	code := "`{{pagetitle}}`\n```\n{{pagetitle}}\n```\n    {{pagetitle}}\n"
+	if got := Expand(code, macros); got != code {
+		t.Errorf("Expected macros in code to be kept, got %q", got)
+	}
+}
@@ -0,0 +1,81 @@
+package notify
+
+import (
+	"errors"
+	"fmt"
+	"mime"
+	"net"
+	"net/smtp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// SMTPConfig configures the mail server used to send emails
+type SMTPConfig struct {
+	Host string
+	// Port of the server, 587 by default
+	Port int
+	// Username and Password authenticate with PLAIN auth, no auth if empty
+	Username string
+	Password string
+	// From is the sender address
+	From string
+}
+
+// Enabled reports whether a mail server is configured
+func (c SMTPConfig) Enabled() bool {
+	return c.Host != ""
+}
+
+// Mailer sends plain text emails
+type Mailer struct {
+	config SMTPConfig
+	// send is smtp.SendMail, replaced in tests
+	send func(addr string, a smtp.Auth, from string, to []string, msg []byte) error
+}
+
+func NewMailer(config SMTPConfig) *Mailer {
+	if config.Port == 0 {
+		config.Port = 587
+	}
+	return &Mailer{config: config, send: smtp.SendMail}
+}
+
+// Enabled reports whether the mailer has a mail server
+func (m *Mailer) Enabled() bool {
+	return m != nil && m.config.Enabled()
+}
+
+// Send sends a plain text email to the recipients
+func (m *Mailer) Send(to []string, subject, body string) error {
+	if !m.Enabled() {
+		return errors.New("no mail server configured")
+	}
+	if len(to) == 0 {
+		return errors.New("no recipients")
+	}
+	for _, addr := range append([]string{m.config.From}, to...) {
+		// header injection
+		if strings.ContainsAny(addr, "\r\n") {
+			return fmt.Errorf("invalid address %q", addr)
+		}
+	}
+
+	var msg strings.Builder
+	msg.WriteString("From: " + m.config.From + "\r\n")
+	msg.WriteString("To: " + strings.Join(to, ", ") + "\r\n")
+	msg.WriteString("Subject: " + mime.QEncoding.Encode("utf-8", subject) + "\r\n")
+	msg.WriteString("Date: " + time.Now().Format(time.RFC1123Z) + "\r\n")
+	msg.WriteString("MIME-Version: 1.0\r\n")
+	msg.WriteString("Content-Type: text/plain; charset=utf-8\r\n")
+	msg.WriteString("\r\n")
+	msg.WriteString(strings.ReplaceAll(strings.ReplaceAll(body, "\r\n", "\n"), "\n", "\r\n"))
+
+	var auth smtp.Auth
+	if m.config.Username != "" {
+		auth = smtp.PlainAuth("", m.config.Username, m.config.Password, m.config.Host)
+	}
+	addr := net.JoinHostPort(m.config.Host, strconv.Itoa(m.config.Port))
+	return m.send(addr, auth, m.config.From, to, []byte(msg.String()))
+}
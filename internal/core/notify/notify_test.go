@@ -0,0 +1,83 @@
+package notify
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/smtp"
+	"strings"
+	"testing"
+)
+
+func TestWebhook_Send(t *testing.T) {
+	var gotEvent, gotSignature, gotBody string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		gotEvent, gotSignature, gotBody = r.Header.Get(EventHeader), r.Header.Get(SignatureHeader), string(body)
+	}))
+	defer server.Close()
+
+	hook := Webhook{URL: server.URL, Secret: "s3cret"}
+	if err := hook.Send(context.Background(), server.Client(), "page.test", map[string]string{"id": "p1"}); err != nil {
+		t.Fatalf("Send failed: %v", err)
+	}
+	if gotEvent != "page.test" || gotBody != `{"id":"p1"}` {
+		t.Errorf("Unexpected request: event %q, body %q", gotEvent, gotBody)
+	}
+	if gotSignature != Sign("s3cret", []byte(gotBody)) || !strings.HasPrefix(gotSignature, "sha256=") {
+		t.Errorf("Unexpected signature %q", gotSignature)
+	}
+}
+
+func TestWebhook_SendFailsOnErrorStatus(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusInternalServerError)
+	}))
+	defer server.Close()
+
+	if err := (Webhook{URL: server.URL}).Send(context.Background(), server.Client(), "page.test", nil); err == nil {
+		t.Error("Expected an error for status 500")
+	}
+}
+
+func TestWebhook_Subscribed(t *testing.T) {
+	if (Webhook{}).Subscribed("page.test") {
+		t.Error("Expected a webhook without URL to want nothing")
+	}
+	if !(Webhook{URL: "http://x"}).Subscribed("page.test") {
+		t.Error("Expected a webhook without events to want all events")
+	}
+	hook := Webhook{URL: "http://x", Events: []string{"page.other"}}
+	if hook.Subscribed("page.test") || !hook.Subscribed("page.other") {
+		t.Error("Expected only the listed events")
+	}
+}
+
+func TestMailer_Send(t *testing.T) {
+	mailer := NewMailer(SMTPConfig{Host: "mail.example.com", Username: "wiki", Password: "pw", From: "wiki@example.com"})
+	var gotAddr string
+	var gotTo []string
+	var gotMsg string
+	mailer.send = func(addr string, a smtp.Auth, from string, to []string, msg []byte) error {
+		gotAddr, gotTo, gotMsg = addr, to, string(msg)
+		return nil
+	}
+
+	if err := mailer.Send([]string{"ada@example.com"}, "Review due: Setup", "Line 1\nLine 2"); err != nil {
+		t.Fatalf("Send failed: %v", err)
+	}
+	if gotAddr != "mail.example.com:587" || len(gotTo) != 1 || gotTo[0] != "ada@example.com" {
+		t.Errorf("Unexpected delivery to %s %v", gotAddr, gotTo)
+	}
+	if !strings.Contains(gotMsg, "Subject: Review due: Setup\r\n") || !strings.HasSuffix(gotMsg, "\r\n\r\nLine 1\r\nLine 2") {
+		t.Errorf("Unexpected message %q", gotMsg)
+	}
+
+	if err := mailer.Send([]string{"x@example.com\r\nBcc: y@example.com"}, "s", "b"); err == nil {
+		t.Error("Expected addresses with line breaks to be rejected")
+	}
+	if err := NewMailer(SMTPConfig{}).Send([]string{"ada@example.com"}, "s", "b"); err == nil {
+		t.Error("Expected an error without mail server")
+	}
+}
@@ -0,0 +1,79 @@
+// Package notify delivers notifications by webhook and by email
+package notify
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net/http"
+)
+
+const (
+	// EventHeader names the event of a webhook request
+	EventHeader = "X-LeafWiki-Event"
+	// SignatureHeader carries "sha256=" and the hex HMAC-SHA256 of the body, keyed with the
+	// secret of the webhook, so receivers can verify the sender
+	SignatureHeader = "X-LeafWiki-Signature"
+)
+
+// Webhook is the receiver of the notifications
+type Webhook struct {
+	URL    string
+	Secret string
+	// Events the receiver subscribed to, all events if empty
+	Events []string
+}
+
+// Subscribed reports whether the webhook is configured and wants the event
+func (h Webhook) Subscribed(event string) bool {
+	if h.URL == "" {
+		return false
+	}
+	if len(h.Events) == 0 {
+		return true
+	}
+	for _, e := range h.Events {
+		if e == event {
+			return true
+		}
+	}
+	return false
+}
+
+// Send posts the payload as JSON to the webhook. Responses other than 2xx are errors.
+func (h Webhook) Send(ctx context.Context, client *http.Client, event string, payload any) error {
+	body, err := json.Marshal(payload)
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, h.URL, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set(EventHeader, event)
+	if h.Secret != "" {
+		req.Header.Set(SignatureHeader, Sign(h.Secret, body))
+	}
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return fmt.Errorf("webhook answered %s", resp.Status)
+	}
+	return nil
+}
+
+// Sign returns the value of the signature header of a body
+func Sign(secret string, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write(body)
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
@@ -0,0 +1,179 @@
+// Package obsidian converts the Markdown flavour of Obsidian, [[wikilinks]] and ![[embeds]],
+// to standard Markdown when pages are rendered, so a vault can be served without rewriting it
+package obsidian
+
+import (
+	"net/url"
+	"path"
+	"regexp"
+	"strings"
+
+	"github.com/Gomez12/wiki/internal/core/frontmatter"
+	"github.com/Gomez12/wiki/internal/core/include"
+	"github.com/Gomez12/wiki/internal/core/macro"
+	"github.com/Gomez12/wiki/internal/core/toc"
+)
+
+// ConfigDir is the folder with the settings of a vault
+const ConfigDir = ".obsidian"
+
+// TrashDir is the folder of the notes deleted in Obsidian
+const TrashDir = ".trash"
+
+// linkRegex matches [[note]], [[note#heading|text]] and ![[file]]. The groups are the
+// embed marker, the target, the heading and the text.
+var linkRegex = regexp.MustCompile(`(!?)\[\[([^\[\]|#]*)(?:#([^\[\]|]*))?(?:\|([^\[\]]*))?\]\]`)
+
+// imageExtensions are embedded as images, other attachments as links
+var imageExtensions = map[string]bool{
+	".png": true, ".jpg": true, ".jpeg": true, ".gif": true, ".svg": true, ".webp": true, ".bmp": true, ".avif": true,
+}
+
+// Resolver looks up the targets of links and embeds
+type Resolver interface {
+	// Note returns the route and the content of a note by its name, path or alias
+	Note(name string) (route string, content string, ok bool)
+	// Attachment returns the URL of a file embedded in the note with the route
+	Attachment(name, route string) (url string, ok bool)
+}
+
+// Expand converts the wikilinks and embeds of the Markdown of the note with the route.
+// Links become links to the route of the note, embedded images become images and embedded
+// notes are replaced by their content, or the section below the heading. Links in code are
+// kept. Links to missing notes point to the route the note would have, so it can be created.
+func Expand(markdown, route string, r Resolver) string {
+	return expand(markdown, []string{strings.Trim(route, "/")}, r)
+}
+
+func expand(markdown string, stack []string, r Resolver) string {
+	return macro.ReplaceOutsideCode(markdown, linkRegex, func(match []string) string {
+		embed, target, heading, text := match[1] == "!", strings.TrimSpace(match[2]), strings.TrimSpace(match[3]), strings.TrimSpace(match[4])
+		if embed {
+			return embedTarget(target, heading, text, stack, r)
+		}
+		return link(target, heading, text, r)
+	})
+}
+
+// link converts [[target#heading|text]] to a Markdown link
+func link(target, heading, text string, r Resolver) string {
+	// block references (#^id) have no anchor in the rendered page
+	if strings.HasPrefix(heading, "^") {
+		heading = ""
+	}
+	if text == "" {
+		text = target
+		switch {
+		case target == "":
+			text = heading
+		case heading != "":
+			text = target + " > " + heading
+		}
+	}
+
+	href := ""
+	if target != "" {
+		noteRoute, _, ok := r.Note(target)
+		if !ok {
+			noteRoute = strings.TrimSuffix(target, ".md")
+		}
+		href = escapeRoute(noteRoute)
+	}
+	if heading != "" {
+		href += "#" + toc.Anchor(heading)
+	}
+	return "[" + escapeText(text) + "](" + href + ")"
+}
+
+// embedTarget converts ![[target]] to an image, a link or the content of the note
+func embedTarget(target, heading, text string, stack []string, r Resolver) string {
+	route := stack[len(stack)-1]
+	ext := strings.ToLower(path.Ext(target))
+	if ext != "" && ext != ".md" {
+		url, ok := r.Attachment(target, route)
+		if !ok {
+			return macro.Note("embed of %s failed: file not found", target)
+		}
+		if imageExtensions[ext] {
+			// the text of an embedded image is its size, e.g. ![[diagram.png|300]]
+			return "![" + escapeText(path.Base(target)) + "](" + url + ")"
+		}
+		if text == "" {
+			text = path.Base(target)
+		}
+		return "[" + escapeText(text) + "](" + url + ")"
+	}
+
+	noteRoute, content, ok := r.Note(target)
+	if !ok {
+		return macro.Note("embed of %s failed: note not found", target)
+	}
+	for _, parent := range stack {
+		if parent == noteRoute {
+			return macro.Note("embed of %s skipped: it embeds itself", target)
+		}
+	}
+	if len(stack) > include.MaxDepth {
+		return macro.Note("embed of %s skipped: embeds are nested deeper than %d levels", target, include.MaxDepth)
+	}
+	_, body, _ := frontmatter.Split(content)
+	if heading != "" && !strings.HasPrefix(heading, "^") {
+		body = section(body, heading)
+	}
+	return strings.TrimRight(expand(body, append(stack[:len(stack):len(stack)], noteRoute), r), "\n")
+}
+
+// section returns the part of the Markdown from the heading up to the next heading of the
+// same or a higher level, the whole Markdown if the heading is missing
+func section(markdown, heading string) string {
+	lines := strings.Split(markdown, "\n")
+	start, level := -1, 0
+	for i, line := range lines {
+		l, title := atxHeading(line)
+		if l == 0 {
+			continue
+		}
+		if start < 0 {
+			if strings.EqualFold(title, heading) {
+				start, level = i, l
+			}
+			continue
+		}
+		if l <= level {
+			return strings.Join(lines[start:i], "\n")
+		}
+	}
+	if start < 0 {
+		return markdown
+	}
+	return strings.Join(lines[start:], "\n")
+}
+
+// atxHeading returns the level and the text of a # heading, level 0 for other lines
+func atxHeading(line string) (int, string) {
+	trimmed := strings.TrimLeft(line, "#")
+	level := len(line) - len(trimmed)
+	if level == 0 || level > 6 || (trimmed != "" && trimmed[0] != ' ' && trimmed[0] != '\t') {
+		return 0, ""
+	}
+	return level, strings.TrimSpace(strings.TrimRight(strings.TrimSpace(trimmed), "#"))
+}
+
+// escapeRoute returns the root-relative URL of a route with escaped segments
+func escapeRoute(route string) string {
+	segments := strings.Split(strings.Trim(route, "/"), "/")
+	for i, segment := range segments {
+		segments[i] = url.PathEscape(segment)
+	}
+	return "/" + strings.Join(segments, "/")
+}
+
+// escapeText escapes the brackets of link text
+func escapeText(text string) string {
+	return strings.NewReplacer(`\`, `\\`, "[", `\[`, "]", `\]`).Replace(text)
+}
+
+// EscapePath returns the URL path of a slash-separated file path with escaped segments
+func EscapePath(p string) string {
+	return strings.TrimPrefix(escapeRoute(p), "/")
+}
@@ -0,0 +1,84 @@
+package obsidian
+
+import (
+	"strings"
+	"testing"
+)
+
+type fakeResolver struct {
+	notes map[string]string
+	files map[string]bool
+}
+
+func (f fakeResolver) Note(name string) (string, string, bool) {
+	for route, content := range f.notes {
+		if strings.EqualFold(route, name) || strings.HasSuffix(strings.ToLower(route), "/"+strings.ToLower(name)) {
+			return route, content, true
+		}
+	}
+	return "", "", false
+}
+
+func (f fakeResolver) Attachment(name, route string) (string, bool) {
+	if !f.files[name] {
+		return "", false
+	}
+	return "/vault/attachments/" + EscapePath(name), true
+}
+
+func newFakeResolver() fakeResolver {
+	return fakeResolver{
+		notes: map[string]string{
+			"projects/Project Plan": "---\naliases: [Plan]\n---\n# Project Plan\n\n## Goals\nShip it.\n\n## Risks\nNone.\n",
+			"loop/a":                "A ![[b]]",
+			"loop/b":                "B ![[a]]",
+		},
+		files: map[string]bool{"diagram 1.png": true, "spec.pdf": true},
+	}
+}
+
+func TestExpand_Links(t *testing.T) {
+	r := newFakeResolver()
+
+	got := Expand("See [[Project Plan]], [[Project Plan#Risks|the risks]] and [[Missing Note]].", "home", r)
+	want := "See [Project Plan](/projects/Project%20Plan), [the risks](/projects/Project%20Plan#risks) and [Missing Note](/Missing%20Note)."
+	if got != want {
+		t.Errorf("Unexpected links\n got %q\nwant %q", got, want)
+	}
+
+	if got := Expand("[[#Goals]]", "home", r); got != "[Goals](#goals)" {
+		t.Errorf("Expected a link to the heading of the page, got %q", got)
+	}
+
+	code := "`[[Project Plan]]`\n```\n![[diagram 1.png]]\n```\n"
+	if got := Expand(code, "home", r); got != code {
+		t.Errorf("Expected links in code to be kept, got %q", got)
+	}
+}
+
+func TestExpand_Embeds(t *testing.T) {
+	r := newFakeResolver()
+
+	got := Expand("![[diagram 1.png|300]] ![[spec.pdf]]", "home", r)
+	want := "![diagram 1.png](/vault/attachments/diagram%201.png) [spec.pdf](/vault/attachments/spec.pdf)"
+	if got != want {
+		t.Errorf("Unexpected attachments\n got %q\nwant %q", got, want)
+	}
+
+	got = Expand("![[Project Plan#Goals]]", "home", r)
+	if got != "## Goals\nShip it." {
+		t.Errorf("Expected the section to be embedded, got %q", got)
+	}
+
+	got = Expand("![[Project Plan]]", "home", r)
+	if strings.Contains(got, "aliases") || !strings.Contains(got, "## Risks") {
+		t.Errorf("Expected the note without frontmatter, got %q", got)
+	}
+
+	if got := Expand("![[b]]", "loop/a", r); !strings.Contains(got, "embed of a skipped: it embeds itself") {
+		t.Errorf("Expected the cycle to be detected, got %q", got)
+	}
+	if got := Expand("![[missing.png]]", "home", r); !strings.Contains(got, "embed of missing.png failed") {
+		t.Errorf("Expected a note for missing files, got %q", got)
+	}
+}
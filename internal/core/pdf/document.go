@@ -0,0 +1,175 @@
+// Package pdf writes simple PDF documents with the standard Type1 fonts and renders
+// Markdown pages into them. The standard fonts need no embedding, so only characters of
+// the WinAnsi (Latin-1) encoding can be shown.
+package pdf
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"io"
+	"strings"
+)
+
+// A4 page size in points
+const (
+	PageWidth  = 595.28
+	PageHeight = 841.89
+)
+
+// Color is an RGB color with components between 0 and 1
+type Color struct{ R, G, B float64 }
+
+var (
+	Black     = Color{}
+	Gray      = Color{0.45, 0.45, 0.45}
+	LightGray = Color{0.94, 0.94, 0.94}
+	LinkBlue  = Color{0.1, 0.3, 0.7}
+)
+
+// Document is a PDF document built page by page.
+// Coordinates are in points with the origin at the bottom left of a page.
+type Document struct {
+	pages []*page
+}
+
+type page struct {
+	content bytes.Buffer
+	links   []link
+}
+
+// link is a clickable area jumping to a position on another page
+type link struct {
+	x1, y1, x2, y2 float64
+	destPage       int
+	destY          float64
+}
+
+// AddPage appends an empty page and returns its index
+func (d *Document) AddPage() int {
+	d.pages = append(d.pages, &page{})
+	return len(d.pages) - 1
+}
+
+// PageCount returns the number of pages
+func (d *Document) PageCount() int {
+	return len(d.pages)
+}
+
+// Text draws s with its baseline starting at x, y
+func (d *Document) Text(p int, x, y float64, font Font, size float64, color Color, s string) {
+	fmt.Fprintf(&d.pages[p].content, "BT %s rg /F%d %s Tf %s %s Td (%s) Tj ET\n",
+		rgb(color), font, num(size), num(x), num(y), escape(encodeWinAnsi(s)))
+}
+
+// Line draws a line with the given width
+func (d *Document) Line(p int, x1, y1, x2, y2, width float64, color Color) {
+	fmt.Fprintf(&d.pages[p].content, "%s RG %s w %s %s m %s %s l S\n",
+		rgb(color), num(width), num(x1), num(y1), num(x2), num(y2))
+}
+
+// FillRect fills the rectangle with its bottom left corner at x, y
+func (d *Document) FillRect(p int, x, y, w, h float64, color Color) {
+	fmt.Fprintf(&d.pages[p].content, "%s rg %s %s %s %s re f\n", rgb(color), num(x), num(y), num(w), num(h))
+}
+
+// Link makes the rectangle x1, y1, x2, y2 a link to the position destY on page destPage
+func (d *Document) Link(p int, x1, y1, x2, y2 float64, destPage int, destY float64) {
+	d.pages[p].links = append(d.pages[p].links, link{x1, y1, x2, y2, destPage, destY})
+}
+
+// WriteTo writes the document in PDF format
+func (d *Document) WriteTo(w io.Writer) (int64, error) {
+	out := &countingWriter{w: bufio.NewWriter(w)}
+	var offsets []int64
+
+	// object numbers: 1 catalog, 2 page tree, 3.. fonts, then page and content objects
+	fontObj := 3
+	firstPageObj := fontObj + len(fontNames)
+	pageObj := func(i int) int { return firstPageObj + 2*i }
+
+	obj := func(body string) {
+		offsets = append(offsets, out.n)
+		fmt.Fprintf(out, "%d 0 obj\n%s\nendobj\n", len(offsets), body)
+	}
+
+	out.WriteString("%PDF-1.4\n%\xe2\xe3\xcf\xd3\n")
+	obj("<< /Type /Catalog /Pages 2 0 R >>")
+
+	kids := make([]string, len(d.pages))
+	for i := range d.pages {
+		kids[i] = fmt.Sprintf("%d 0 R", pageObj(i))
+	}
+	obj(fmt.Sprintf("<< /Type /Pages /Kids [%s] /Count %d >>", strings.Join(kids, " "), len(d.pages)))
+
+	fonts := make([]string, len(fontNames))
+	for i, name := range fontNames {
+		obj(fmt.Sprintf("<< /Type /Font /Subtype /Type1 /BaseFont /%s /Encoding /WinAnsiEncoding >>", name))
+		fonts[i] = fmt.Sprintf("/F%d %d 0 R", i, fontObj+i)
+	}
+
+	for i, p := range d.pages {
+		var annots []string
+		for _, l := range p.links {
+			annots = append(annots, fmt.Sprintf("<< /Type /Annot /Subtype /Link /Border [0 0 0] /Rect [%s %s %s %s] /Dest [%d 0 R /XYZ 0 %s 0] >>",
+				num(l.x1), num(l.y1), num(l.x2), num(l.y2), pageObj(l.destPage), num(l.destY)))
+		}
+		annotEntry := ""
+		if len(annots) > 0 {
+			annotEntry = " /Annots [" + strings.Join(annots, " ") + "]"
+		}
+		obj(fmt.Sprintf("<< /Type /Page /Parent 2 0 R /MediaBox [0 0 %s %s] /Resources << /Font << %s >> >> /Contents %d 0 R%s >>",
+			num(PageWidth), num(PageHeight), strings.Join(fonts, " "), pageObj(i)+1, annotEntry))
+		obj(fmt.Sprintf("<< /Length %d >>\nstream\n%sendstream", p.content.Len(), p.content.String()))
+	}
+
+	xref := out.n
+	fmt.Fprintf(out, "xref\n0 %d\n0000000000 65535 f \n", len(offsets)+1)
+	for _, offset := range offsets {
+		fmt.Fprintf(out, "%010d 00000 n \n", offset)
+	}
+	fmt.Fprintf(out, "trailer\n<< /Size %d /Root 1 0 R >>\nstartxref\n%d\n%%%%EOF\n", len(offsets)+1, xref)
+
+	if out.err != nil {
+		return out.n, out.err
+	}
+	return out.n, out.w.Flush()
+}
+
+type countingWriter struct {
+	w   *bufio.Writer
+	n   int64
+	err error
+}
+
+func (c *countingWriter) Write(p []byte) (int, error) {
+	if c.err != nil {
+		return 0, c.err
+	}
+	n, err := c.w.Write(p)
+	c.n += int64(n)
+	c.err = err
+	return n, err
+}
+
+func (c *countingWriter) WriteString(s string) {
+	_, _ = c.Write([]byte(s))
+}
+
+func num(f float64) string {
+	s := fmt.Sprintf("%.2f", f)
+	s = strings.TrimRight(strings.TrimRight(s, "0"), ".")
+	if s == "-0" || s == "" {
+		return "0"
+	}
+	return s
+}
+
+func rgb(c Color) string {
+	return num(c.R) + " " + num(c.G) + " " + num(c.B)
+}
+
+func escape(s string) string {
+	r := strings.NewReplacer(`\`, `\\`, `(`, `\(`, `)`, `\)`, "\r", `\r`, "\n", `\n`)
+	return r.Replace(s)
+}
@@ -0,0 +1,420 @@
+package pdf
+
+import (
+	"io"
+	"strconv"
+	"strings"
+	"unicode"
+
+	"github.com/Gomez12/wiki/internal/core/frontmatter"
+	"github.com/russross/blackfriday/v2"
+)
+
+const (
+	margin       = 56.0
+	footerHeight = 24.0
+	contentWidth = PageWidth - 2*margin
+
+	bodySize   = 10.5
+	bodyLine   = 15.0
+	codeSize   = 9.0
+	codeLine   = 12.0
+	listIndent = 16.0
+
+	tocEntryLine  = 16.0
+	tocHeaderSize = 50.0
+)
+
+var headingSizes = map[int]float64{1: 18, 2: 15, 3: 13}
+
+// Section is a wiki page rendered into the document
+type Section struct {
+	Title    string
+	Markdown string
+	// Depth indents the section in the table of contents
+	Depth int
+}
+
+// Render writes the sections as PDF document. Every section starts on a new page.
+// With more than one section, a table of contents linking to the sections comes first.
+func Render(out io.Writer, title string, sections []Section) error {
+	l := &layout{doc: &Document{}}
+
+	tocPages := 0
+	if len(sections) > 1 {
+		tocPages = tocPageCount(len(sections))
+		for i := 0; i < tocPages; i++ {
+			l.doc.AddPage()
+		}
+	}
+
+	targets := make([]position, len(sections))
+	for i, s := range sections {
+		l.newPage()
+		targets[i] = position{l.page, l.y}
+
+		_, body, _ := frontmatter.Split(s.Markdown)
+		// a parser can't be reused for several documents
+		root := blackfriday.New(blackfriday.WithExtensions(blackfriday.CommonExtensions)).Parse([]byte(body))
+		// pages usually start with their title as heading, so it is not repeated
+		if first := root.FirstChild; first == nil || first.Type != blackfriday.Heading || first.Level != 1 {
+			l.paragraph([]run{{text: s.Title, font: FontBold}}, margin, contentWidth, headingSizes[1]+2, 26)
+			l.space(8)
+		}
+		l.blocks(root, margin)
+	}
+
+	if tocPages > 0 {
+		l.toc(title, sections, targets)
+	}
+	l.footers()
+
+	_, err := l.doc.WriteTo(out)
+	return err
+}
+
+type position struct {
+	page int
+	y    float64
+}
+
+type run struct {
+	text  string
+	font  Font
+	color Color
+}
+
+type token struct {
+	text    string
+	font    Font
+	color   Color
+	space   bool
+	newline bool
+}
+
+type layout struct {
+	doc  *Document
+	page int
+	// y is the top of the free space on the current page
+	y float64
+	// marker is the list bullet drawn in front of the next line
+	marker  string
+	markerX float64
+	tight   bool
+}
+
+func (l *layout) newPage() {
+	l.page = l.doc.AddPage()
+	l.y = PageHeight - margin
+}
+
+func (l *layout) space(h float64) {
+	l.y -= h
+}
+
+// line reserves a line of height h, starting a new page if needed, and returns its baseline
+func (l *layout) line(h, size float64) float64 {
+	if l.y-h < margin+footerHeight {
+		l.newPage()
+	}
+	l.y -= h
+	baseline := l.y + (h-size)/2 + 0.22*size
+	if l.marker != "" {
+		l.doc.Text(l.page, l.markerX, baseline, FontRegular, bodySize, Black, l.marker)
+		l.marker = ""
+	}
+	return baseline
+}
+
+func (l *layout) blocks(parent *blackfriday.Node, indent float64) {
+	for n := parent.FirstChild; n != nil; n = n.Next {
+		l.block(n, indent)
+	}
+}
+
+func (l *layout) block(n *blackfriday.Node, indent float64) {
+	width := PageWidth - margin - indent
+	switch n.Type {
+	case blackfriday.Heading:
+		size, ok := headingSizes[n.Level]
+		if !ok {
+			size = 11.5
+		}
+		l.space(size * 0.6)
+		l.paragraph(inlineRuns(n, FontBold, Black), indent, width, size, size*1.35)
+		l.space(4)
+	case blackfriday.Paragraph:
+		l.paragraph(inlineRuns(n, FontRegular, Black), indent, width, bodySize, bodyLine)
+		if l.tight {
+			l.space(2)
+		} else {
+			l.space(6)
+		}
+	case blackfriday.List:
+		tight := l.tight
+		l.tight = n.Tight
+		number := 1
+		for item := n.FirstChild; item != nil; item = item.Next {
+			l.marker = "•"
+			if n.ListFlags&blackfriday.ListTypeOrdered != 0 {
+				l.marker = strconv.Itoa(number) + "."
+				number++
+			}
+			l.markerX = indent
+			l.blocks(item, indent+listIndent)
+		}
+		l.marker = ""
+		l.tight = tight
+		if !tight {
+			l.space(4)
+		}
+	case blackfriday.CodeBlock:
+		l.code(string(n.Literal), indent, width)
+		l.space(6)
+	case blackfriday.BlockQuote:
+		start := position{l.page, l.y}
+		l.blocks(n, indent+14)
+		if start.page == l.page {
+			l.doc.Line(l.page, indent+4, start.y, indent+4, l.y+4, 2, LightGray)
+		}
+	case blackfriday.HorizontalRule:
+		l.line(12, bodySize)
+		l.doc.Line(l.page, indent, l.y+6, PageWidth-margin, l.y+6, 0.5, Gray)
+	case blackfriday.Table:
+		l.table(n, indent, width)
+		l.space(6)
+	case blackfriday.HTMLBlock:
+		// raw HTML can't be rendered
+	default:
+		l.blocks(n, indent)
+	}
+}
+
+// paragraph wraps the runs into lines of the given width
+func (l *layout) paragraph(runs []run, x, width, size, lineHeight float64) {
+	type placed struct {
+		token
+		x float64
+	}
+	var line []placed
+	lineWidth := 0.0
+	emit := func() {
+		baseline := l.line(lineHeight, size)
+		for _, p := range line {
+			l.doc.Text(l.page, x+p.x, baseline, p.font, size, p.color, p.text)
+		}
+		line = line[:0]
+		lineWidth = 0
+	}
+
+	for _, t := range tokenize(runs) {
+		if t.newline {
+			emit()
+			continue
+		}
+		for _, part := range breakWord(t.text, t.font, size, width) {
+			w := TextWidth(t.font, size, part)
+			gap := 0.0
+			if t.space && len(line) > 0 {
+				gap = TextWidth(t.font, size, " ")
+			}
+			if len(line) > 0 && lineWidth+gap+w > width {
+				emit()
+				gap = 0
+			}
+			line = append(line, placed{token{text: part, font: t.font, color: t.color}, lineWidth + gap})
+			lineWidth += gap + w
+			t.space = false
+		}
+	}
+	if len(line) > 0 {
+		emit()
+	}
+}
+
+func (l *layout) code(literal string, x, width float64) {
+	maxChars := int((width - 8) / (codeSize * 0.6))
+	for _, text := range strings.Split(strings.TrimRight(literal, "\n"), "\n") {
+		text = strings.TrimRight(encodeWinAnsi(text), " ")
+		for {
+			chunk := text
+			if len(chunk) > maxChars {
+				chunk, text = text[:maxChars], text[maxChars:]
+			} else {
+				text = ""
+			}
+			baseline := l.line(codeLine, codeSize)
+			l.doc.FillRect(l.page, x, l.y, width, codeLine, LightGray)
+			l.doc.Text(l.page, x+4, baseline, FontMono, codeSize, Black, chunk)
+			if text == "" {
+				break
+			}
+		}
+	}
+}
+
+// table renders each row as a line with the cells separated by a bar
+func (l *layout) table(n *blackfriday.Node, x, width float64) {
+	n.Walk(func(node *blackfriday.Node, entering bool) blackfriday.WalkStatus {
+		if !entering || node.Type != blackfriday.TableRow {
+			return blackfriday.GoToNext
+		}
+		var runs []run
+		for cell := node.FirstChild; cell != nil; cell = cell.Next {
+			font := FontRegular
+			if cell.IsHeader {
+				font = FontBold
+			}
+			if len(runs) > 0 {
+				runs = append(runs, run{text: " | ", font: FontRegular, color: Gray})
+			}
+			runs = append(runs, inlineRuns(cell, font, Black)...)
+		}
+		l.paragraph(runs, x, width, bodySize, bodyLine)
+		if node.Parent != nil && node.Parent.Type == blackfriday.TableHead {
+			l.doc.Line(l.page, x, l.y+1, x+width, l.y+1, 0.5, Gray)
+		}
+		return blackfriday.SkipChildren
+	})
+}
+
+func (l *layout) toc(title string, sections []Section, targets []position) {
+	page := 0
+	y := PageHeight - margin - 26
+	l.doc.Text(page, margin, y, FontBold, 20, Black, title)
+	l.doc.Text(page, margin, y-20, FontRegular, 12, Gray, "Contents")
+	y = PageHeight - margin - tocHeaderSize
+
+	for i, s := range sections {
+		if y-tocEntryLine < margin+footerHeight {
+			page++
+			y = PageHeight - margin
+		}
+		y -= tocEntryLine
+		baseline := y + 4
+		indent := margin + float64(s.Depth)*14
+		number := strconv.Itoa(targets[i].page + 1)
+		numberX := PageWidth - margin - TextWidth(FontRegular, bodySize, number)
+
+		entry := truncate(s.Title, FontRegular, bodySize, numberX-indent-12)
+		l.doc.Text(page, indent, baseline, FontRegular, bodySize, Black, entry)
+		l.doc.Text(page, numberX, baseline, FontRegular, bodySize, Black, number)
+		l.doc.Link(page, indent, y, PageWidth-margin, y+tocEntryLine, targets[i].page, targets[i].y)
+	}
+}
+
+func (l *layout) footers() {
+	total := strconv.Itoa(l.doc.PageCount())
+	for i := 0; i < l.doc.PageCount(); i++ {
+		text := strconv.Itoa(i+1) + " / " + total
+		x := (PageWidth - TextWidth(FontRegular, 8, text)) / 2
+		l.doc.Text(i, x, margin/2, FontRegular, 8, Gray, text)
+	}
+}
+
+// tocPageCount returns the number of pages needed for a table of contents with n entries
+func tocPageCount(n int) int {
+	available := PageHeight - 2*margin - footerHeight
+	first := int((available - tocHeaderSize) / tocEntryLine)
+	if n <= first {
+		return 1
+	}
+	rest := int(available / tocEntryLine)
+	return 1 + (n-first+rest-1)/rest
+}
+
+func inlineRuns(n *blackfriday.Node, font Font, color Color) []run {
+	var runs []run
+	for c := n.FirstChild; c != nil; c = c.Next {
+		switch c.Type {
+		case blackfriday.Text:
+			runs = append(runs, run{text: string(c.Literal), font: font, color: color})
+		case blackfriday.Code:
+			runs = append(runs, run{text: string(c.Literal), font: FontMono, color: color})
+		case blackfriday.Softbreak:
+			runs = append(runs, run{text: " ", font: font, color: color})
+		case blackfriday.Hardbreak:
+			runs = append(runs, run{text: "\n", font: font, color: color})
+		case blackfriday.Emph:
+			inner := FontItalic
+			if font == FontBold {
+				inner = FontBold
+			}
+			runs = append(runs, inlineRuns(c, inner, color)...)
+		case blackfriday.Strong:
+			runs = append(runs, inlineRuns(c, FontBold, color)...)
+		case blackfriday.Link:
+			runs = append(runs, inlineRuns(c, font, LinkBlue)...)
+		case blackfriday.Image:
+			alt := ""
+			for _, r := range inlineRuns(c, font, color) {
+				alt += r.text
+			}
+			runs = append(runs, run{text: "[" + alt + "]", font: FontItalic, color: Gray})
+		case blackfriday.HTMLSpan:
+			// raw HTML can't be rendered
+		default:
+			runs = append(runs, inlineRuns(c, font, color)...)
+		}
+	}
+	return runs
+}
+
+// tokenize splits the runs into words, remembering whether whitespace preceded them
+func tokenize(runs []run) []token {
+	var tokens []token
+	space := false
+	for _, r := range runs {
+		if r.text == "\n" {
+			tokens = append(tokens, token{newline: true})
+			space = false
+			continue
+		}
+		var word strings.Builder
+		flush := func() {
+			if word.Len() > 0 {
+				tokens = append(tokens, token{text: word.String(), font: r.font, color: r.color, space: space})
+				space = false
+				word.Reset()
+			}
+		}
+		for _, ch := range r.text {
+			if unicode.IsSpace(ch) {
+				flush()
+				space = true
+			} else {
+				word.WriteRune(ch)
+			}
+		}
+		flush()
+	}
+	return tokens
+}
+
+// breakWord splits words which are wider than a line, e.g. long URLs
+func breakWord(word string, font Font, size, width float64) []string {
+	if TextWidth(font, size, word) <= width {
+		return []string{word}
+	}
+	var parts []string
+	var current []rune
+	for _, r := range word {
+		if len(current) > 0 && TextWidth(font, size, string(append(current, r))) > width {
+			parts = append(parts, string(current))
+			current = current[:0]
+		}
+		current = append(current, r)
+	}
+	return append(parts, string(current))
+}
+
+func truncate(s string, font Font, size, width float64) string {
+	if TextWidth(font, size, s) <= width {
+		return s
+	}
+	runes := []rune(s)
+	for len(runes) > 0 && TextWidth(font, size, string(runes)+"…") > width {
+		runes = runes[:len(runes)-1]
+	}
+	return string(runes) + "…"
+}
@@ -0,0 +1,96 @@
+package pdf
+
+import (
+	"bytes"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+// checkXref verifies that every xref entry points to the start of its object
+func checkXref(t *testing.T, data []byte) {
+	t.Helper()
+	start := regexp.MustCompile(`startxref\n(\d+)\n%%EOF\n$`).FindSubmatch(data)
+	if start == nil {
+		t.Fatalf("missing startxref")
+	}
+	xref, _ := strconv.Atoi(string(start[1]))
+	if !bytes.HasPrefix(data[xref:], []byte("xref\n")) {
+		t.Fatalf("startxref does not point to the xref table")
+	}
+	lines := strings.Split(string(data[xref:]), "\n")
+	count, _ := strconv.Atoi(strings.Fields(lines[1])[1])
+	for obj := 1; obj < count; obj++ {
+		offset, _ := strconv.Atoi(lines[2+obj][:10])
+		if want := fmt.Sprintf("%d 0 obj\n", obj); !bytes.HasPrefix(data[offset:], []byte(want)) {
+			t.Errorf("xref entry of object %d points to %q", obj, data[offset:offset+10])
+		}
+	}
+}
+
+func TestRender_SinglePage(t *testing.T) {
+	var buf bytes.Buffer
+	err := Render(&buf, "Guide", []Section{{
+		Title:    "Guide",
+		Markdown: "---\nstatus: draft\n---\n# Guide\n\nSome **bold** text (with parens).\n\n```\ncode line\n```\n\n- first\n- second\n",
+	}})
+	if err != nil {
+		t.Fatalf("Render failed: %v", err)
+	}
+	data := buf.Bytes()
+	if !bytes.HasPrefix(data, []byte("%PDF-1.4")) {
+		t.Fatalf("missing PDF header")
+	}
+	checkXref(t, data)
+
+	for _, want := range []string{"(Guide)", "(bold)", `(\(with)`, "(parens\\).)", "(\x95)", "(code line)", "(1 / 1)"} {
+		if !bytes.Contains(data, []byte(want)) {
+			t.Errorf("expected %q in document", want)
+		}
+	}
+	if bytes.Contains(data, []byte("status: draft")) {
+		t.Errorf("expected frontmatter to be skipped")
+	}
+	if bytes.Contains(data, []byte("/Annots")) {
+		t.Errorf("expected no table of contents for a single page")
+	}
+}
+
+func TestRender_SubtreeWithTableOfContents(t *testing.T) {
+	long := strings.Repeat("A long paragraph which needs more than one page. ", 400)
+	var buf bytes.Buffer
+	err := Render(&buf, "Docs", []Section{
+		{Title: "Docs", Markdown: long},
+		{Title: "Install", Markdown: "Run the binary.", Depth: 1},
+	})
+	if err != nil {
+		t.Fatalf("Render failed: %v", err)
+	}
+	data := buf.Bytes()
+	checkXref(t, data)
+
+	match := regexp.MustCompile(`/Count (\d+)`).FindSubmatch(data)
+	if match == nil {
+		t.Fatalf("missing page count")
+	}
+	if pages, _ := strconv.Atoi(string(match[1])); pages < 4 {
+		t.Errorf("expected a table of contents and several content pages, got %d pages", pages)
+	}
+	if !bytes.Contains(data, []byte("(Contents)")) || !bytes.Contains(data, []byte("/Subtype /Link")) {
+		t.Errorf("expected a linked table of contents")
+	}
+	if !bytes.Contains(data, []byte("(Install)")) {
+		t.Errorf("expected the second section")
+	}
+}
+
+func TestEncodeWinAnsi(t *testing.T) {
+	if got := encodeWinAnsi("Grüße – 你"); got != "Gr\xfc\xdfe \x96 ?" {
+		t.Errorf("unexpected encoding %q", got)
+	}
+	if w := TextWidth(FontMono, 10, "abc"); w != 18 {
+		t.Errorf("expected width 18, got %v", w)
+	}
+}
@@ -0,0 +1,101 @@
+// Package proxy holds the configuration for running behind a reverse proxy: the proxies whose
+// X-Forwarded headers are trusted and the base path the wiki is served under.
+package proxy
+
+import (
+	"fmt"
+	"net"
+	"path"
+	"regexp"
+	"strings"
+)
+
+// basePathRegex limits base paths to characters which need no escaping in URLs and HTML
+var basePathRegex = regexp.MustCompile(`^[A-Za-z0-9._~/-]+$`)
+
+// DefaultTrustedProxies are the loopback and private networks, where a reverse proxy on the
+// same host or in the same container network runs. Clients connecting directly from the
+// internet can't spoof their address then.
+var DefaultTrustedProxies = []string{
+	"127.0.0.0/8",
+	"::1/128",
+	"10.0.0.0/8",
+	"172.16.0.0/12",
+	"192.168.0.0/16",
+	"fc00::/7",
+}
+
+// Trusted are the networks of the trusted proxies
+type Trusted []*net.IPNet
+
+// ParseTrusted parses IP addresses and CIDR ranges, e.g. 10.0.0.1 or 10.0.0.0/8
+func ParseTrusted(entries []string) (Trusted, error) {
+	trusted := Trusted{}
+	for _, entry := range entries {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+		if !strings.Contains(entry, "/") {
+			ip := net.ParseIP(entry)
+			if ip == nil {
+				return nil, fmt.Errorf("invalid proxy address %q", entry)
+			}
+			bits := 8 * net.IPv6len
+			if ip.To4() != nil {
+				ip, bits = ip.To4(), 8*net.IPv4len
+			}
+			trusted = append(trusted, &net.IPNet{IP: ip, Mask: net.CIDRMask(bits, bits)})
+			continue
+		}
+		_, network, err := net.ParseCIDR(entry)
+		if err != nil {
+			return nil, fmt.Errorf("invalid proxy range %q", entry)
+		}
+		trusted = append(trusted, network)
+	}
+	return trusted, nil
+}
+
+// Contains reports whether the address, with or without port, belongs to a trusted proxy
+func (t Trusted) Contains(addr string) bool {
+	if host, _, err := net.SplitHostPort(addr); err == nil {
+		addr = host
+	}
+	ip := net.ParseIP(addr)
+	if ip == nil {
+		return false
+	}
+	for _, network := range t {
+		if network.Contains(ip) {
+			return true
+		}
+	}
+	return false
+}
+
+// Strings returns the networks in CIDR notation
+func (t Trusted) Strings() []string {
+	list := make([]string, len(t))
+	for i, network := range t {
+		list[i] = network.String()
+	}
+	return list
+}
+
+// NormalizeBasePath cleans the path the wiki is served under, e.g. wiki/ becomes /wiki.
+// The root, "" or "/", becomes "".
+func NormalizeBasePath(basePath string) (string, error) {
+	basePath = strings.TrimSpace(basePath)
+	if basePath == "" {
+		return "", nil
+	}
+	if !basePathRegex.MatchString(basePath) {
+		return "", fmt.Errorf("invalid base path %q", basePath)
+	}
+	basePath = path.Clean("/" + basePath)
+	if basePath == "/" {
+		return "", nil
+	}
+	return basePath, nil
+}
@@ -0,0 +1,67 @@
+package proxy
+
+import "testing"
+
+func TestParseTrusted(t *testing.T) {
+	trusted, err := ParseTrusted([]string{"10.0.0.0/8", " 192.168.1.5 ", "::1", ""})
+	if err != nil {
+		t.Fatalf("ParseTrusted failed: %v", err)
+	}
+
+	tests := map[string]bool{
+		"10.1.2.3":         true,
+		"10.1.2.3:4567":    true,
+		"192.168.1.5":      true,
+		"192.168.1.6":      false,
+		"[::1]:8080":       true,
+		"203.0.113.7:1234": false,
+		"not an address":   false,
+	}
+	for addr, want := range tests {
+		if got := trusted.Contains(addr); got != want {
+			t.Errorf("Contains(%q) = %v, want %v", addr, got, want)
+		}
+	}
+
+	for _, invalid := range []string{"10.0.0.0/33", "proxy.local"} {
+		if _, err := ParseTrusted([]string{invalid}); err == nil {
+			t.Errorf("Expected an error for %q", invalid)
+		}
+	}
+}
+
+func TestDefaultTrustedProxies(t *testing.T) {
+	trusted, err := ParseTrusted(DefaultTrustedProxies)
+	if err != nil {
+		t.Fatalf("ParseTrusted failed: %v", err)
+	}
+	if !trusted.Contains("172.17.0.1") || !trusted.Contains("127.0.0.1") {
+		t.Error("Expected loopback and container networks to be trusted")
+	}
+	if trusted.Contains("8.8.8.8") {
+		t.Error("Expected public addresses not to be trusted")
+	}
+}
+
+func TestNormalizeBasePath(t *testing.T) {
+	tests := map[string]string{
+		"":           "",
+		"/":          "",
+		"/wiki":      "/wiki",
+		"wiki/":      "/wiki",
+		"/docs/wiki": "/docs/wiki",
+		"//wiki//":   "/wiki",
+	}
+	for in, want := range tests {
+		got, err := NormalizeBasePath(in)
+		if err != nil || got != want {
+			t.Errorf("NormalizeBasePath(%q) = %q, %v, want %q", in, got, err, want)
+		}
+	}
+
+	for _, invalid := range []string{"/wiki?x=1", `/wiki"`, "/wi ki", "/wiki%2F"} {
+		if _, err := NormalizeBasePath(invalid); err == nil {
+			t.Errorf("Expected an error for %q", invalid)
+		}
+	}
+}
@@ -0,0 +1,103 @@
+// Package ratelimit limits how often clients may call expensive operations, with a token
+// bucket per client
+package ratelimit
+
+import (
+	"math"
+	"sync"
+	"time"
+)
+
+// sweepInterval is how often the buckets of idle clients are removed
+const sweepInterval = time.Minute
+
+// Rate refills the bucket of a client with PerMinute tokens a minute, up to Burst tokens
+type Rate struct {
+	PerMinute int
+	Burst     int
+}
+
+// Enabled reports whether the rate limits anything
+func (r Rate) Enabled() bool {
+	return r.PerMinute > 0 && r.Burst > 0
+}
+
+// Result is the state of the bucket of a client after a request
+type Result struct {
+	Allowed bool
+	// Limit is the size of the bucket
+	Limit int
+	// Remaining are the requests left right now
+	Remaining int
+	// Reset is when the bucket is full again
+	Reset time.Duration
+	// RetryAfter is when the next request is allowed, 0 if it is allowed right away
+	RetryAfter time.Duration
+}
+
+type bucket struct {
+	tokens  float64
+	updated time.Time
+}
+
+// Limiter holds the buckets of the clients. The rate is passed per request, so it can be
+// changed while the limiter is running.
+type Limiter struct {
+	mu        sync.Mutex
+	buckets   map[string]*bucket
+	lastSweep time.Time
+	// now is time.Now, replaced in tests
+	now func() time.Time
+}
+
+func NewLimiter() *Limiter {
+	return &Limiter{buckets: map[string]*bucket{}, now: time.Now}
+}
+
+// Allow takes a token from the bucket of the client identified by key
+func (l *Limiter) Allow(key string, rate Rate) Result {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	now := l.now()
+	perSecond := float64(rate.PerMinute) / 60
+	burst := float64(rate.Burst)
+	l.sweep(now, perSecond, burst)
+
+	b, ok := l.buckets[key]
+	if !ok {
+		b = &bucket{tokens: burst, updated: now}
+		l.buckets[key] = b
+	}
+	b.tokens = math.Min(burst, b.tokens+now.Sub(b.updated).Seconds()*perSecond)
+	b.updated = now
+
+	result := Result{Limit: rate.Burst}
+	if b.tokens >= 1 {
+		b.tokens--
+		result.Allowed = true
+	} else {
+		result.RetryAfter = seconds((1 - b.tokens) / perSecond)
+	}
+	result.Remaining = int(b.tokens)
+	result.Reset = seconds((burst - b.tokens) / perSecond)
+	return result
+}
+
+// sweep removes the buckets which are full again, they are the same as new ones.
+// Lock must be held by the caller
+func (l *Limiter) sweep(now time.Time, perSecond, burst float64) {
+	if now.Sub(l.lastSweep) < sweepInterval {
+		return
+	}
+	l.lastSweep = now
+	for key, b := range l.buckets {
+		if b.tokens+now.Sub(b.updated).Seconds()*perSecond >= burst {
+			delete(l.buckets, key)
+		}
+	}
+}
+
+func seconds(s float64) time.Duration {
+	return time.Duration(s * float64(time.Second))
+}
@@ -0,0 +1,39 @@
+package ratelimit
+
+import (
+	"testing"
+	"time"
+)
+
+func TestLimiter_Allow(t *testing.T) {
+	now := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+	l := NewLimiter()
+	l.now = func() time.Time { return now }
+	rate := Rate{PerMinute: 60, Burst: 2}
+
+	for i := 0; i < 2; i++ {
+		if r := l.Allow("a", rate); !r.Allowed || r.Remaining != 1-i {
+			t.Fatalf("request %d: expected to be allowed, got %+v", i, r)
+		}
+	}
+	r := l.Allow("a", rate)
+	if r.Allowed || r.RetryAfter != time.Second || r.Reset != 2*time.Second || r.Limit != 2 {
+		t.Fatalf("expected the third request to be rejected, got %+v", r)
+	}
+	if r := l.Allow("b", rate); !r.Allowed {
+		t.Errorf("expected another client to have its own bucket, got %+v", r)
+	}
+
+	// one token a second refills the bucket
+	now = now.Add(1500 * time.Millisecond)
+	if r := l.Allow("a", rate); !r.Allowed || r.Remaining != 0 {
+		t.Errorf("expected a refilled token, got %+v", r)
+	}
+
+	// full buckets are removed
+	now = now.Add(time.Hour)
+	l.Allow("c", rate)
+	if _, ok := l.buckets["a"]; ok || len(l.buckets) != 1 {
+		t.Errorf("expected idle buckets to be removed, got %d buckets", len(l.buckets))
+	}
+}
@@ -0,0 +1,5 @@
+package reading
+
+import "errors"
+
+var ErrPositionNotFound = errors.New("reading position not found")
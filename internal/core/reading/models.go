@@ -0,0 +1,20 @@
+package reading
+
+import "time"
+
+// Position is the last place a user read on a page.
+// Anchor identifies the position inside the page, e.g. a heading id.
+type Position struct {
+	PageID    string    `json:"pageId"`
+	Anchor    string    `json:"anchor"`
+	UpdatedAt time.Time `json:"updatedAt"`
+}
+
+// RecentPage is an entry of the "recently read" list of a user
+type RecentPage struct {
+	PageID    string    `json:"pageId"`
+	Title     string    `json:"title"`
+	Path      string    `json:"path"`
+	Anchor    string    `json:"anchor"`
+	UpdatedAt time.Time `json:"updatedAt"`
+}
@@ -0,0 +1,355 @@
+package reading
+
+import (
+	"database/sql"
+	"path"
+	"time"
+
+	_ "modernc.org/sqlite"
+)
+
+type ReadingStore struct {
+	storageDir string
+	filename   string
+	db         *sql.DB
+}
+
+func NewReadingStore(storageDir string) (*ReadingStore, error) {
+	r := &ReadingStore{
+		storageDir: storageDir,
+		filename:   "reading.db",
+	}
+
+	err := r.Connect()
+	if err != nil {
+		return nil, err
+	}
+
+	return r, r.ensureSchema()
+}
+
+func (r *ReadingStore) Connect() error {
+	// Database is already open and connected
+	if r.db != nil {
+		return nil
+	}
+	db, err := sql.Open("sqlite", path.Join(r.storageDir, r.filename))
+	if err != nil {
+		return err
+	}
+	r.db = db
+	return nil
+}
+
+func (r *ReadingStore) ensureSchema() error {
+	err := r.Connect()
+	if err != nil {
+		return err
+	}
+	// visited_at is the last view of the page, updated_at the last saved position, a row of a
+	// page which was only viewed has no position (updated_at 0)
+	_, err = r.db.Exec(`
+		CREATE TABLE IF NOT EXISTS reading_positions (
+			user_id TEXT NOT NULL,
+			page_id TEXT NOT NULL,
+			anchor TEXT NOT NULL DEFAULT '',
+			updated_at INTEGER NOT NULL,
+			visited_at INTEGER NOT NULL DEFAULT 0,
+			PRIMARY KEY (user_id, page_id)
+		);
+		CREATE INDEX IF NOT EXISTS idx_reading_positions_recent ON reading_positions(user_id, updated_at DESC);
+		CREATE TABLE IF NOT EXISTS visit_tracking (
+			user_id TEXT PRIMARY KEY,
+			enabled_at INTEGER NOT NULL
+		);
+	`)
+	if err != nil {
+		return err
+	}
+	return r.migrateVisits()
+}
+
+// migrateVisits moves the visits of the page_visits table, which kept them apart from the
+// positions, into reading_positions
+func (r *ReadingStore) migrateVisits() error {
+	var hasColumn bool
+	if err := r.db.QueryRow(`SELECT EXISTS (SELECT 1 FROM pragma_table_info('reading_positions') WHERE name = 'visited_at');`).Scan(&hasColumn); err != nil {
+		return err
+	}
+	if !hasColumn {
+		if _, err := r.db.Exec(`ALTER TABLE reading_positions ADD COLUMN visited_at INTEGER NOT NULL DEFAULT 0;`); err != nil {
+			return err
+		}
+	}
+
+	var hasVisits bool
+	if err := r.db.QueryRow(`SELECT EXISTS (SELECT 1 FROM sqlite_master WHERE type = 'table' AND name = 'page_visits');`).Scan(&hasVisits); err != nil {
+		return err
+	}
+	if !hasVisits {
+		return nil
+	}
+	tx, err := r.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer func() { _ = tx.Rollback() }()
+	// the users who were tracked so far keep being tracked
+	if _, err := tx.Exec(`
+		INSERT INTO reading_positions (user_id, page_id, anchor, updated_at, visited_at)
+		SELECT user_id, page_id, '', 0, visited_at FROM page_visits WHERE true
+		ON CONFLICT(user_id, page_id) DO UPDATE SET visited_at = excluded.visited_at;
+		INSERT OR IGNORE INTO visit_tracking (user_id, enabled_at)
+		SELECT user_id, MIN(visited_at) FROM page_visits GROUP BY user_id;
+		DROP TABLE page_visits;
+	`); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+func (r *ReadingStore) Close() error {
+	if r.db != nil {
+		err := r.db.Close()
+		if err != nil {
+			return err
+		}
+		r.db = nil
+	}
+	return nil
+}
+
+// SavePosition stores the position of a user on a page, replacing the previous one.
+func (r *ReadingStore) SavePosition(userID, pageID, anchor string) (*Position, error) {
+	err := r.Connect()
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now().UTC()
+	_, err = r.db.Exec(`
+		INSERT INTO reading_positions (user_id, page_id, anchor, updated_at)
+		VALUES (?, ?, ?, ?)
+		ON CONFLICT(user_id, page_id) DO UPDATE SET anchor = excluded.anchor, updated_at = excluded.updated_at;
+	`, userID, pageID, anchor, now.UnixNano())
+	if err != nil {
+		return nil, err
+	}
+
+	return &Position{PageID: pageID, Anchor: anchor, UpdatedAt: now}, nil
+}
+
+func (r *ReadingStore) GetPosition(userID, pageID string) (*Position, error) {
+	err := r.Connect()
+	if err != nil {
+		return nil, err
+	}
+
+	row := r.db.QueryRow(`
+		SELECT page_id, anchor, updated_at
+		FROM reading_positions
+		WHERE user_id = ? AND page_id = ? AND updated_at > 0;
+	`, userID, pageID)
+
+	pos, err := scanPosition(row)
+	if err == sql.ErrNoRows {
+		return nil, ErrPositionNotFound
+	}
+	return pos, err
+}
+
+// ListRecent returns the positions of a user, most recently read first.
+func (r *ReadingStore) ListRecent(userID string, limit int) ([]*Position, error) {
+	err := r.Connect()
+	if err != nil {
+		return nil, err
+	}
+
+	rows, err := r.db.Query(`
+		SELECT page_id, anchor, updated_at
+		FROM reading_positions
+		WHERE user_id = ? AND updated_at > 0
+		ORDER BY updated_at DESC
+		LIMIT ?;
+	`, userID, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	positions := []*Position{}
+	for rows.Next() {
+		pos, err := scanPosition(rows)
+		if err != nil {
+			return nil, err
+		}
+		positions = append(positions, pos)
+	}
+	return positions, rows.Err()
+}
+
+// RecordVisit stores that a user viewed a page now, keeping the position on the page.
+func (r *ReadingStore) RecordVisit(userID, pageID string) error {
+	err := r.Connect()
+	if err != nil {
+		return err
+	}
+	_, err = r.db.Exec(`
+		INSERT INTO reading_positions (user_id, page_id, anchor, updated_at, visited_at)
+		VALUES (?, ?, '', 0, ?)
+		ON CONFLICT(user_id, page_id) DO UPDATE SET visited_at = excluded.visited_at;
+	`, userID, pageID, time.Now().UTC().UnixNano())
+	return err
+}
+
+// ListActivity returns the pages a user read or viewed, most recent first. UpdatedAt of the
+// positions is the latest of both, the anchor is empty for pages which were only viewed.
+func (r *ReadingStore) ListActivity(userID string, limit int) ([]*Position, error) {
+	err := r.Connect()
+	if err != nil {
+		return nil, err
+	}
+
+	rows, err := r.db.Query(`
+		SELECT page_id, anchor, MAX(updated_at, visited_at) AS active_at
+		FROM reading_positions
+		WHERE user_id = ?
+		ORDER BY active_at DESC
+		LIMIT ?;
+	`, userID, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	positions := []*Position{}
+	for rows.Next() {
+		pos, err := scanPosition(rows)
+		if err != nil {
+			return nil, err
+		}
+		positions = append(positions, pos)
+	}
+	return positions, rows.Err()
+}
+
+// SetTracking opts a user in or out of recording the page views. Opting out removes the
+// views recorded so far.
+func (r *ReadingStore) SetTracking(userID string, enabled bool) error {
+	err := r.Connect()
+	if err != nil {
+		return err
+	}
+	if enabled {
+		_, err = r.db.Exec(`INSERT OR IGNORE INTO visit_tracking (user_id, enabled_at) VALUES (?, ?);`,
+			userID, time.Now().UTC().UnixNano())
+		return err
+	}
+	if _, err := r.db.Exec(`DELETE FROM visit_tracking WHERE user_id = ?;`, userID); err != nil {
+		return err
+	}
+	return r.deleteVisits(`WHERE user_id = ?`, userID)
+}
+
+// Tracking reports whether a user opted in to recording the page views
+func (r *ReadingStore) Tracking(userID string) (bool, error) {
+	err := r.Connect()
+	if err != nil {
+		return false, err
+	}
+	var enabled bool
+	err = r.db.QueryRow(`SELECT EXISTS (SELECT 1 FROM visit_tracking WHERE user_id = ?);`, userID).Scan(&enabled)
+	return enabled, err
+}
+
+// LastViewed returns the latest time any user read or visited each page
+func (r *ReadingStore) LastViewed() (map[string]time.Time, error) {
+	err := r.Connect()
+	if err != nil {
+		return nil, err
+	}
+
+	rows, err := r.db.Query(`
+		SELECT page_id, MAX(MAX(updated_at, visited_at))
+		FROM reading_positions
+		GROUP BY page_id;
+	`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	viewed := map[string]time.Time{}
+	for rows.Next() {
+		var pageID string
+		var viewedAt int64
+		if err := rows.Scan(&pageID, &viewedAt); err != nil {
+			return nil, err
+		}
+		viewed[pageID] = time.Unix(0, viewedAt).UTC()
+	}
+	return viewed, rows.Err()
+}
+
+// DeleteAllVisits removes the visits of all users, the positions are kept
+func (r *ReadingStore) DeleteAllVisits() error {
+	err := r.Connect()
+	if err != nil {
+		return err
+	}
+	return r.deleteVisits(``)
+}
+
+// deleteVisits removes the visits of the rows matched by the where clause
+func (r *ReadingStore) deleteVisits(where string, args ...any) error {
+	tx, err := r.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer func() { _ = tx.Rollback() }()
+	if _, err := tx.Exec(`UPDATE reading_positions SET visited_at = 0 `+where+`;`, args...); err != nil {
+		return err
+	}
+	// pages which were only viewed
+	if _, err := tx.Exec(`DELETE FROM reading_positions WHERE updated_at = 0 AND visited_at = 0;`); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// DeleteForPage removes the positions and visits of all users on a page
+func (r *ReadingStore) DeleteForPage(pageID string) error {
+	err := r.Connect()
+	if err != nil {
+		return err
+	}
+	_, err = r.db.Exec(`DELETE FROM reading_positions WHERE page_id = ?;`, pageID)
+	return err
+}
+
+// DeleteForUser removes all positions and visits of a user and the opt-in to tracking
+func (r *ReadingStore) DeleteForUser(userID string) error {
+	err := r.Connect()
+	if err != nil {
+		return err
+	}
+	if _, err := r.db.Exec(`DELETE FROM reading_positions WHERE user_id = ?;`, userID); err != nil {
+		return err
+	}
+	_, err = r.db.Exec(`DELETE FROM visit_tracking WHERE user_id = ?;`, userID)
+	return err
+}
+
+type rowScanner interface {
+	Scan(dest ...any) error
+}
+
+func scanPosition(row rowScanner) (*Position, error) {
+	pos := &Position{}
+	var updatedAt int64
+	if err := row.Scan(&pos.PageID, &pos.Anchor, &updatedAt); err != nil {
+		return nil, err
+	}
+	pos.UpdatedAt = time.Unix(0, updatedAt).UTC()
+	return pos, nil
+}
@@ -0,0 +1,244 @@
+package reading
+
+import (
+	"database/sql"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func setupTestReadingStore(t *testing.T) *ReadingStore {
+	t.Helper()
+	store, err := NewReadingStore(t.TempDir())
+	if err != nil {
+		t.Fatalf("Failed to create reading store: %v", err)
+	}
+	return store
+}
+
+func TestReadingStore_SaveAndGetPosition(t *testing.T) {
+	store := setupTestReadingStore(t)
+	defer store.Close()
+
+	if _, err := store.GetPosition("u1", "page1"); err != ErrPositionNotFound {
+		t.Fatalf("Expected ErrPositionNotFound, got %v", err)
+	}
+
+	if _, err := store.SavePosition("u1", "page1", "intro"); err != nil {
+		t.Fatalf("Failed to save position: %v", err)
+	}
+	if _, err := store.SavePosition("u1", "page1", "setup"); err != nil {
+		t.Fatalf("Failed to update position: %v", err)
+	}
+
+	pos, err := store.GetPosition("u1", "page1")
+	if err != nil {
+		t.Fatalf("Failed to get position: %v", err)
+	}
+	if pos.Anchor != "setup" {
+		t.Errorf("Expected anchor 'setup', got %q", pos.Anchor)
+	}
+
+	// Positions are per user
+	if _, err := store.GetPosition("u2", "page1"); err != ErrPositionNotFound {
+		t.Errorf("Expected no position for other user, got %v", err)
+	}
+}
+
+func TestReadingStore_ListRecent(t *testing.T) {
+	store := setupTestReadingStore(t)
+	defer store.Close()
+
+	for _, id := range []string{"a", "b", "c"} {
+		if _, err := store.SavePosition("u1", id, ""); err != nil {
+			t.Fatalf("Failed to save position: %v", err)
+		}
+		time.Sleep(time.Millisecond)
+	}
+	// Reading "a" again moves it to the front
+	if _, err := store.SavePosition("u1", "a", "end"); err != nil {
+		t.Fatalf("Failed to save position: %v", err)
+	}
+
+	recent, err := store.ListRecent("u1", 2)
+	if err != nil {
+		t.Fatalf("Failed to list recent: %v", err)
+	}
+	if len(recent) != 2 || recent[0].PageID != "a" || recent[1].PageID != "c" {
+		t.Fatalf("Unexpected recent order: %+v", recent)
+	}
+
+	if err := store.DeleteForPage("a"); err != nil {
+		t.Fatalf("Failed to delete page positions: %v", err)
+	}
+	if err := store.DeleteForUser("u1"); err != nil {
+		t.Fatalf("Failed to delete user positions: %v", err)
+	}
+	recent, err = store.ListRecent("u1", 10)
+	if err != nil {
+		t.Fatalf("Failed to list recent: %v", err)
+	}
+	if len(recent) != 0 {
+		t.Fatalf("Expected no positions, got %d", len(recent))
+	}
+}
+
+func TestReadingStore_Visits(t *testing.T) {
+	store := setupTestReadingStore(t)
+	defer store.Close()
+
+	if _, err := store.SavePosition("u1", "b", "setup"); err != nil {
+		t.Fatalf("Failed to save position: %v", err)
+	}
+	time.Sleep(time.Millisecond)
+	for _, id := range []string{"a", "c", "a"} {
+		if err := store.RecordVisit("u1", id); err != nil {
+			t.Fatalf("Failed to record visit: %v", err)
+		}
+		time.Sleep(time.Millisecond)
+	}
+	if err := store.RecordVisit("u2", "c"); err != nil {
+		t.Fatalf("Failed to record visit: %v", err)
+	}
+
+	// visits and positions are one list, viewing a page keeps its position
+	activity, err := store.ListActivity("u1", 10)
+	if err != nil {
+		t.Fatalf("Failed to list activity: %v", err)
+	}
+	if len(activity) != 3 || activity[0].PageID != "a" || activity[1].PageID != "c" || activity[2].PageID != "b" || activity[2].Anchor != "setup" {
+		t.Fatalf("Expected a, c, b, got %+v", activity)
+	}
+	if err := store.RecordVisit("u1", "b"); err != nil {
+		t.Fatalf("Failed to record visit: %v", err)
+	}
+	if pos, err := store.GetPosition("u1", "b"); err != nil || pos.Anchor != "setup" {
+		t.Errorf("Expected the position to be kept, got %+v, %v", pos, err)
+	}
+	// pages which were only viewed have no position
+	if _, err := store.GetPosition("u1", "a"); err != ErrPositionNotFound {
+		t.Errorf("Expected ErrPositionNotFound for a viewed page, got %v", err)
+	}
+	if recent, _ := store.ListRecent("u1", 10); len(recent) != 1 || recent[0].PageID != "b" {
+		t.Errorf("Expected only the read page, got %+v", recent)
+	}
+
+	if err := store.DeleteAllVisits(); err != nil {
+		t.Fatalf("Failed to delete all visits: %v", err)
+	}
+	if activity, _ := store.ListActivity("u1", 10); len(activity) != 1 || activity[0].PageID != "b" {
+		t.Errorf("Expected only the position after deleting all visits, got %+v", activity)
+	}
+	if activity, _ := store.ListActivity("u2", 10); len(activity) != 0 {
+		t.Errorf("Expected no visits after deleting all, got %+v", activity)
+	}
+}
+
+func TestReadingStore_Tracking(t *testing.T) {
+	store := setupTestReadingStore(t)
+	defer store.Close()
+
+	if tracking, err := store.Tracking("u1"); err != nil || tracking {
+		t.Fatalf("Expected tracking to be opt-in, got %v, %v", tracking, err)
+	}
+	for _, enabled := range []bool{true, true} {
+		if err := store.SetTracking("u1", enabled); err != nil {
+			t.Fatalf("Failed to set tracking: %v", err)
+		}
+	}
+	if tracking, _ := store.Tracking("u1"); !tracking {
+		t.Fatal("Expected the user to be tracked")
+	}
+
+	for _, user := range []string{"u1", "u2"} {
+		if err := store.RecordVisit(user, "a"); err != nil {
+			t.Fatalf("Failed to record visit: %v", err)
+		}
+	}
+	// opting out forgets the visits of the user only
+	if err := store.SetTracking("u1", false); err != nil {
+		t.Fatalf("Failed to set tracking: %v", err)
+	}
+	if tracking, _ := store.Tracking("u1"); tracking {
+		t.Error("Expected the user to be no longer tracked")
+	}
+	if activity, _ := store.ListActivity("u1", 10); len(activity) != 0 {
+		t.Errorf("Expected no visits after opting out, got %+v", activity)
+	}
+	if activity, _ := store.ListActivity("u2", 10); len(activity) != 1 {
+		t.Errorf("Expected the visits of other users to be kept, got %+v", activity)
+	}
+
+	if err := store.SetTracking("u2", true); err != nil {
+		t.Fatalf("Failed to set tracking: %v", err)
+	}
+	if err := store.DeleteForUser("u2"); err != nil {
+		t.Fatalf("Failed to delete user: %v", err)
+	}
+	if tracking, _ := store.Tracking("u2"); tracking {
+		t.Error("Expected the opt-in to be removed with the user")
+	}
+}
+
+func TestReadingStore_MigratesVisits(t *testing.T) {
+	dir := t.TempDir()
+	db, err := sql.Open("sqlite", filepath.Join(dir, "reading.db"))
+	if err != nil {
+		t.Fatalf("Failed to open database: %v", err)
+	}
+	// the schema before the visits were kept with the positions
+	_, err = db.Exec(`
+		CREATE TABLE reading_positions (user_id TEXT NOT NULL, page_id TEXT NOT NULL, anchor TEXT NOT NULL DEFAULT '',
+			updated_at INTEGER NOT NULL, PRIMARY KEY (user_id, page_id));
+		CREATE TABLE page_visits (user_id TEXT NOT NULL, page_id TEXT NOT NULL, visited_at INTEGER NOT NULL,
+			PRIMARY KEY (user_id, page_id));
+		INSERT INTO reading_positions VALUES ('u1', 'a', 'intro', 1);
+		INSERT INTO page_visits VALUES ('u1', 'a', 3), ('u1', 'b', 2);
+	`)
+	_ = db.Close()
+	if err != nil {
+		t.Fatalf("Failed to create old schema: %v", err)
+	}
+
+	store, err := NewReadingStore(dir)
+	if err != nil {
+		t.Fatalf("Failed to create reading store: %v", err)
+	}
+	defer store.Close()
+
+	activity, err := store.ListActivity("u1", 10)
+	if err != nil {
+		t.Fatalf("Failed to list activity: %v", err)
+	}
+	if len(activity) != 2 || activity[0].PageID != "a" || activity[0].Anchor != "intro" || activity[1].PageID != "b" {
+		t.Fatalf("Expected the visits to be migrated, got %+v", activity)
+	}
+	if tracking, _ := store.Tracking("u1"); !tracking {
+		t.Error("Expected the tracked user to stay opted in")
+	}
+}
+
+func TestReadingStore_LastViewed(t *testing.T) {
+	store := setupTestReadingStore(t)
+	defer store.Close()
+
+	if _, err := store.SavePosition("u1", "a", ""); err != nil {
+		t.Fatalf("Failed to save position: %v", err)
+	}
+	time.Sleep(time.Millisecond)
+	if err := store.RecordVisit("u2", "a"); err != nil {
+		t.Fatalf("Failed to record visit: %v", err)
+	}
+	if err := store.RecordVisit("u2", "b"); err != nil {
+		t.Fatalf("Failed to record visit: %v", err)
+	}
+
+	viewed, err := store.LastViewed()
+	if err != nil {
+		t.Fatalf("Failed to get last views: %v", err)
+	}
+	activity, _ := store.ListActivity("u2", 10)
+	if len(viewed) != 2 || !viewed["a"].Equal(activity[1].UpdatedAt) {
+		t.Errorf("Expected the latest view of each page, got %v", viewed)
+	}
+}
@@ -0,0 +1,160 @@
+// Package redirects remembers the old routes of moved and renamed pages
+package redirects
+
+import (
+	"database/sql"
+	"errors"
+	"path"
+	"time"
+
+	_ "modernc.org/sqlite"
+)
+
+var ErrRedirectNotFound = errors.New("redirect not found")
+
+// Redirect points an old route to the page now found elsewhere.
+// The page is stored by ID, so the redirect follows later moves of the page.
+type Redirect struct {
+	From      string    `json:"from"`
+	PageID    string    `json:"pageId"`
+	CreatedAt time.Time `json:"createdAt"`
+}
+
+type RedirectStore struct {
+	storageDir string
+	filename   string
+	db         *sql.DB
+}
+
+func NewRedirectStore(storageDir string) (*RedirectStore, error) {
+	r := &RedirectStore{
+		storageDir: storageDir,
+		filename:   "redirects.db",
+	}
+
+	if err := r.Connect(); err != nil {
+		return nil, err
+	}
+	return r, r.ensureSchema()
+}
+
+func (r *RedirectStore) Connect() error {
+	// Database is already open and connected
+	if r.db != nil {
+		return nil
+	}
+	db, err := sql.Open("sqlite", path.Join(r.storageDir, r.filename))
+	if err != nil {
+		return err
+	}
+	r.db = db
+	return nil
+}
+
+func (r *RedirectStore) ensureSchema() error {
+	_, err := r.db.Exec(`
+		CREATE TABLE IF NOT EXISTS redirects (
+			from_route TEXT PRIMARY KEY,
+			page_id TEXT NOT NULL,
+			created_at INTEGER NOT NULL
+		);
+		CREATE INDEX IF NOT EXISTS idx_redirects_page ON redirects(page_id);
+	`)
+	return err
+}
+
+func (r *RedirectStore) Close() error {
+	if r.db != nil {
+		err := r.db.Close()
+		if err != nil {
+			return err
+		}
+		r.db = nil
+	}
+	return nil
+}
+
+// Add points the route to the page, replacing an existing redirect of the route
+func (r *RedirectStore) Add(from, pageID string) error {
+	if err := r.Connect(); err != nil {
+		return err
+	}
+	_, err := r.db.Exec(`
+		INSERT INTO redirects (from_route, page_id, created_at) VALUES (?, ?, ?)
+		ON CONFLICT(from_route) DO UPDATE SET page_id = excluded.page_id, created_at = excluded.created_at;
+	`, from, pageID, time.Now().UnixNano())
+	return err
+}
+
+// Get returns the redirect of a route
+func (r *RedirectStore) Get(from string) (*Redirect, error) {
+	if err := r.Connect(); err != nil {
+		return nil, err
+	}
+	row := r.db.QueryRow(`SELECT from_route, page_id, created_at FROM redirects WHERE from_route = ?`, from)
+	redirect, err := scanRedirect(row)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrRedirectNotFound
+	}
+	return redirect, err
+}
+
+// List returns all redirects ordered by route
+func (r *RedirectStore) List() ([]*Redirect, error) {
+	if err := r.Connect(); err != nil {
+		return nil, err
+	}
+	rows, err := r.db.Query(`SELECT from_route, page_id, created_at FROM redirects ORDER BY from_route`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	redirects := []*Redirect{}
+	for rows.Next() {
+		redirect, err := scanRedirect(rows)
+		if err != nil {
+			return nil, err
+		}
+		redirects = append(redirects, redirect)
+	}
+	return redirects, rows.Err()
+}
+
+// Delete removes the redirect of a route
+func (r *RedirectStore) Delete(from string) error {
+	if err := r.Connect(); err != nil {
+		return err
+	}
+	res, err := r.db.Exec(`DELETE FROM redirects WHERE from_route = ?`, from)
+	if err != nil {
+		return err
+	}
+	if n, err := res.RowsAffected(); err == nil && n == 0 {
+		return ErrRedirectNotFound
+	}
+	return nil
+}
+
+// DeleteForPage removes all redirects to a page, e.g. when it was deleted
+func (r *RedirectStore) DeleteForPage(pageID string) error {
+	if err := r.Connect(); err != nil {
+		return err
+	}
+	_, err := r.db.Exec(`DELETE FROM redirects WHERE page_id = ?`, pageID)
+	return err
+}
+
+type scanner interface {
+	Scan(dest ...any) error
+}
+
+func scanRedirect(row scanner) (*Redirect, error) {
+	var redirect Redirect
+	var createdAt int64
+	if err := row.Scan(&redirect.From, &redirect.PageID, &createdAt); err != nil {
+		return nil, err
+	}
+	redirect.CreatedAt = time.Unix(0, createdAt).UTC()
+	return &redirect, nil
+}
@@ -0,0 +1,44 @@
+package redirects
+
+import "testing"
+
+func TestRedirectStore(t *testing.T) {
+	store, err := NewRedirectStore(t.TempDir())
+	if err != nil {
+		t.Fatalf("Failed to create redirect store: %v", err)
+	}
+	defer store.Close()
+
+	if _, err := store.Get("docs"); err != ErrRedirectNotFound {
+		t.Fatalf("Expected ErrRedirectNotFound, got %v", err)
+	}
+
+	for _, r := range []struct{ from, pageID string }{{"docs", "p1"}, {"docs/guide", "p2"}, {"docs", "p3"}} {
+		if err := store.Add(r.from, r.pageID); err != nil {
+			t.Fatalf("Failed to add redirect: %v", err)
+		}
+	}
+
+	redirect, err := store.Get("docs")
+	if err != nil || redirect.PageID != "p3" {
+		t.Fatalf("Expected the redirect to be replaced, got %+v (%v)", redirect, err)
+	}
+
+	list, err := store.List()
+	if err != nil || len(list) != 2 || list[0].From != "docs" || list[1].From != "docs/guide" {
+		t.Fatalf("Unexpected redirects %+v (%v)", list, err)
+	}
+
+	if err := store.Delete("docs"); err != nil {
+		t.Fatalf("Failed to delete redirect: %v", err)
+	}
+	if err := store.Delete("docs"); err != ErrRedirectNotFound {
+		t.Errorf("Expected ErrRedirectNotFound, got %v", err)
+	}
+	if err := store.DeleteForPage("p2"); err != nil {
+		t.Fatalf("Failed to delete redirects of page: %v", err)
+	}
+	if list, _ := store.List(); len(list) != 0 {
+		t.Errorf("Expected no redirects, got %+v", list)
+	}
+}
@@ -0,0 +1,152 @@
+// Package review remembers the review reminders which were sent, so every review date of
+// a page is notified once on every channel
+package review
+
+import (
+	"database/sql"
+	"path"
+	"time"
+
+	_ "modernc.org/sqlite"
+)
+
+type ReminderStore struct {
+	storageDir string
+	filename   string
+	db         *sql.DB
+}
+
+func NewReminderStore(storageDir string) (*ReminderStore, error) {
+	r := &ReminderStore{
+		storageDir: storageDir,
+		filename:   "reviews.db",
+	}
+
+	err := r.Connect()
+	if err != nil {
+		return nil, err
+	}
+
+	return r, r.ensureSchema()
+}
+
+func (r *ReminderStore) Connect() error {
+	// Database is already open and connected
+	if r.db != nil {
+		return nil
+	}
+	db, err := sql.Open("sqlite", path.Join(r.storageDir, r.filename))
+	if err != nil {
+		return err
+	}
+	r.db = db
+	return nil
+}
+
+func (r *ReminderStore) ensureSchema() error {
+	err := r.Connect()
+	if err != nil {
+		return err
+	}
+	_, err = r.db.Exec(`
+		CREATE TABLE IF NOT EXISTS review_reminders (
+			page_id TEXT NOT NULL,
+			review_by TEXT NOT NULL,
+			notified_at INTEGER NOT NULL,
+			PRIMARY KEY (page_id, review_by)
+		);
+		CREATE TABLE IF NOT EXISTS review_reminder_channels (
+			page_id TEXT NOT NULL,
+			review_by TEXT NOT NULL,
+			channel TEXT NOT NULL,
+			delivered_at INTEGER NOT NULL,
+			PRIMARY KEY (page_id, review_by, channel)
+		);
+	`)
+	return err
+}
+
+func (r *ReminderStore) Close() error {
+	if r.db != nil {
+		err := r.db.Close()
+		if err != nil {
+			return err
+		}
+		r.db = nil
+	}
+	return nil
+}
+
+// MarkNotified records that the owners of a page were reminded of the review date
+func (r *ReminderStore) MarkNotified(pageID, reviewBy string) error {
+	err := r.Connect()
+	if err != nil {
+		return err
+	}
+	_, err = r.db.Exec(`
+		INSERT INTO review_reminders (page_id, review_by, notified_at)
+		VALUES (?, ?, ?)
+		ON CONFLICT(page_id, review_by) DO NOTHING;
+	`, pageID, reviewBy, time.Now().UTC().UnixNano())
+	return err
+}
+
+// NotifiedAt returns when the owners of a page were reminded of the review date, nil if not yet
+func (r *ReminderStore) NotifiedAt(pageID, reviewBy string) (*time.Time, error) {
+	err := r.Connect()
+	if err != nil {
+		return nil, err
+	}
+	var notifiedAt int64
+	err = r.db.QueryRow(`
+		SELECT notified_at FROM review_reminders WHERE page_id = ? AND review_by = ?;
+	`, pageID, reviewBy).Scan(&notifiedAt)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	t := time.Unix(0, notifiedAt).UTC()
+	return &t, nil
+}
+
+// MarkDelivered records that the reminder of the review date was delivered on a channel, like
+// the webhook or email
+func (r *ReminderStore) MarkDelivered(pageID, reviewBy, channel string) error {
+	err := r.Connect()
+	if err != nil {
+		return err
+	}
+	_, err = r.db.Exec(`
+		INSERT INTO review_reminder_channels (page_id, review_by, channel, delivered_at)
+		VALUES (?, ?, ?, ?)
+		ON CONFLICT(page_id, review_by, channel) DO NOTHING;
+	`, pageID, reviewBy, channel, time.Now().UTC().UnixNano())
+	return err
+}
+
+// Delivered returns the channels the reminder of the review date was delivered on
+func (r *ReminderStore) Delivered(pageID, reviewBy string) (map[string]bool, error) {
+	err := r.Connect()
+	if err != nil {
+		return nil, err
+	}
+	rows, err := r.db.Query(`
+		SELECT channel FROM review_reminder_channels WHERE page_id = ? AND review_by = ?;
+	`, pageID, reviewBy)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	channels := map[string]bool{}
+	for rows.Next() {
+		var channel string
+		if err := rows.Scan(&channel); err != nil {
+			return nil, err
+		}
+		channels[channel] = true
+	}
+	return channels, rows.Err()
+}
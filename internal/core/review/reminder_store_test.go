@@ -0,0 +1,58 @@
+package review
+
+import "testing"
+
+func TestReminderStore_MarkNotified(t *testing.T) {
+	store, err := NewReminderStore(t.TempDir())
+	if err != nil {
+		t.Fatalf("Failed to create reminder store: %v", err)
+	}
+	defer store.Close()
+
+	if at, err := store.NotifiedAt("p1", "2024-06-30"); err != nil || at != nil {
+		t.Fatalf("Expected no reminder yet, got %v, %v", at, err)
+	}
+	if err := store.MarkNotified("p1", "2024-06-30"); err != nil {
+		t.Fatalf("MarkNotified failed: %v", err)
+	}
+	first, err := store.NotifiedAt("p1", "2024-06-30")
+	if err != nil || first == nil {
+		t.Fatalf("Expected the reminder, got %v, %v", first, err)
+	}
+
+	// marking again keeps the first time
+	if err := store.MarkNotified("p1", "2024-06-30"); err != nil {
+		t.Fatalf("MarkNotified failed: %v", err)
+	}
+	if again, _ := store.NotifiedAt("p1", "2024-06-30"); !again.Equal(*first) {
+		t.Errorf("Expected the first time to be kept, got %v and %v", first, again)
+	}
+
+	// a new review date is reminded again
+	if at, _ := store.NotifiedAt("p1", "2024-12-31"); at != nil {
+		t.Errorf("Expected no reminder for the new date, got %v", at)
+	}
+}
+
+func TestReminderStore_MarkDelivered(t *testing.T) {
+	store, err := NewReminderStore(t.TempDir())
+	if err != nil {
+		t.Fatalf("Failed to create reminder store: %v", err)
+	}
+	defer store.Close()
+
+	if channels, err := store.Delivered("p1", "2024-06-30"); err != nil || len(channels) != 0 {
+		t.Fatalf("Expected no channels yet, got %v, %v", channels, err)
+	}
+	for _, channel := range []string{"webhook", "webhook", "email"} {
+		if err := store.MarkDelivered("p1", "2024-06-30", channel); err != nil {
+			t.Fatalf("MarkDelivered failed: %v", err)
+		}
+	}
+	if channels, _ := store.Delivered("p1", "2024-06-30"); len(channels) != 2 || !channels["webhook"] || !channels["email"] {
+		t.Errorf("Expected webhook and email, got %v", channels)
+	}
+	if channels, _ := store.Delivered("p1", "2024-12-31"); len(channels) != 0 {
+		t.Errorf("Expected no channels for the new date, got %v", channels)
+	}
+}
@@ -0,0 +1,394 @@
+// Package savedsearch stores the named search queries of the users and the alerts of the
+// subscribed ones
+package savedsearch
+
+import (
+	"database/sql"
+	"errors"
+	"path"
+	"time"
+
+	"github.com/Gomez12/wiki/internal/core/shared"
+	_ "modernc.org/sqlite"
+)
+
+// ErrSavedSearchNotFound is returned when a user has no saved search with an ID
+var ErrSavedSearchNotFound = errors.New("saved search not found")
+
+// SavedSearch is a named search query of a user
+type SavedSearch struct {
+	ID     string `json:"id"`
+	UserID string `json:"-"`
+	Name   string `json:"name"`
+	Query  string `json:"query"`
+	// Subscribed searches create an alert when a new or changed page starts matching
+	Subscribed bool      `json:"subscribed"`
+	CreatedAt  time.Time `json:"createdAt"`
+	// CheckedAt is when the matches were last compared, nil if not yet
+	CheckedAt *time.Time `json:"checkedAt"`
+}
+
+// Change tells why a page is in an alert
+type Change string
+
+const (
+	// ChangeNew is a page which didn't match before
+	ChangeNew Change = "new"
+	// ChangeUpdated is a matching page whose content changed
+	ChangeUpdated Change = "updated"
+)
+
+// Alert is a page which started matching a subscribed search
+type Alert struct {
+	ID         int64     `json:"id"`
+	SearchID   string    `json:"searchId"`
+	SearchName string    `json:"searchName"`
+	PageID     string    `json:"pageId"`
+	Title      string    `json:"title"`
+	Path       string    `json:"path"`
+	Change     Change    `json:"change"`
+	CreatedAt  time.Time `json:"createdAt"`
+	Read       bool      `json:"read"`
+}
+
+type Store struct {
+	storageDir string
+	filename   string
+	db         *sql.DB
+}
+
+func NewStore(storageDir string) (*Store, error) {
+	s := &Store{
+		storageDir: storageDir,
+		filename:   "savedsearches.db",
+	}
+
+	err := s.Connect()
+	if err != nil {
+		return nil, err
+	}
+
+	return s, s.ensureSchema()
+}
+
+func (s *Store) Connect() error {
+	// Database is already open and connected
+	if s.db != nil {
+		return nil
+	}
+	db, err := sql.Open("sqlite", path.Join(s.storageDir, s.filename))
+	if err != nil {
+		return err
+	}
+	s.db = db
+	return nil
+}
+
+func (s *Store) ensureSchema() error {
+	err := s.Connect()
+	if err != nil {
+		return err
+	}
+	_, err = s.db.Exec(`
+		CREATE TABLE IF NOT EXISTS saved_searches (
+			id TEXT PRIMARY KEY,
+			user_id TEXT NOT NULL,
+			name TEXT NOT NULL,
+			query TEXT NOT NULL,
+			subscribed INTEGER NOT NULL,
+			created_at INTEGER NOT NULL,
+			checked_at INTEGER
+		);
+		CREATE INDEX IF NOT EXISTS idx_saved_searches_user ON saved_searches(user_id);
+		CREATE TABLE IF NOT EXISTS saved_search_matches (
+			search_id TEXT NOT NULL,
+			page_id TEXT NOT NULL,
+			hash TEXT NOT NULL,
+			PRIMARY KEY (search_id, page_id)
+		);
+		CREATE TABLE IF NOT EXISTS search_alerts (
+			id INTEGER PRIMARY KEY AUTOINCREMENT,
+			search_id TEXT NOT NULL,
+			user_id TEXT NOT NULL,
+			page_id TEXT NOT NULL,
+			title TEXT NOT NULL,
+			path TEXT NOT NULL,
+			change TEXT NOT NULL,
+			created_at INTEGER NOT NULL,
+			read INTEGER NOT NULL DEFAULT 0
+		);
+		CREATE INDEX IF NOT EXISTS idx_search_alerts_user ON search_alerts(user_id);
+	`)
+	return err
+}
+
+func (s *Store) Close() error {
+	if s.db != nil {
+		err := s.db.Close()
+		if err != nil {
+			return err
+		}
+		s.db = nil
+	}
+	return nil
+}
+
+// Create saves a search query for a user
+func (s *Store) Create(userID, name, query string, subscribed bool) (*SavedSearch, error) {
+	err := s.Connect()
+	if err != nil {
+		return nil, err
+	}
+	id, err := shared.GenerateUniqueID()
+	if err != nil {
+		return nil, err
+	}
+	_, err = s.db.Exec(`
+		INSERT INTO saved_searches (id, user_id, name, query, subscribed, created_at)
+		VALUES (?, ?, ?, ?, ?, ?);
+	`, id, userID, name, query, subscribed, time.Now().UTC().UnixNano())
+	if err != nil {
+		return nil, err
+	}
+	return s.Get(userID, id)
+}
+
+// Get returns a saved search of a user
+func (s *Store) Get(userID, id string) (*SavedSearch, error) {
+	err := s.Connect()
+	if err != nil {
+		return nil, err
+	}
+	row := s.db.QueryRow(`
+		SELECT id, user_id, name, query, subscribed, created_at, checked_at
+		FROM saved_searches
+		WHERE id = ? AND user_id = ?;
+	`, id, userID)
+	search, err := scanSearch(row)
+	if err == sql.ErrNoRows {
+		return nil, ErrSavedSearchNotFound
+	}
+	return search, err
+}
+
+// Update changes a saved search of a user. A changed query starts over with its matches,
+// so the pages which already match it don't raise alerts.
+func (s *Store) Update(userID, id, name, query string, subscribed bool) (*SavedSearch, error) {
+	current, err := s.Get(userID, id)
+	if err != nil {
+		return nil, err
+	}
+	tx, err := s.db.Begin()
+	if err != nil {
+		return nil, err
+	}
+	defer func() { _ = tx.Rollback() }()
+
+	if current.Query != query || (subscribed && !current.Subscribed) {
+		if _, err := tx.Exec(`DELETE FROM saved_search_matches WHERE search_id = ?;`, id); err != nil {
+			return nil, err
+		}
+		if _, err := tx.Exec(`UPDATE saved_searches SET checked_at = NULL WHERE id = ?;`, id); err != nil {
+			return nil, err
+		}
+	}
+	_, err = tx.Exec(`
+		UPDATE saved_searches SET name = ?, query = ?, subscribed = ? WHERE id = ?;
+	`, name, query, subscribed, id)
+	if err != nil {
+		return nil, err
+	}
+	if err := tx.Commit(); err != nil {
+		return nil, err
+	}
+	return s.Get(userID, id)
+}
+
+// Delete removes a saved search of a user with its matches and alerts
+func (s *Store) Delete(userID, id string) error {
+	if _, err := s.Get(userID, id); err != nil {
+		return err
+	}
+	tx, err := s.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer func() { _ = tx.Rollback() }()
+
+	for _, stmt := range []string{
+		`DELETE FROM saved_search_matches WHERE search_id = ?;`,
+		`DELETE FROM search_alerts WHERE search_id = ?;`,
+		`DELETE FROM saved_searches WHERE id = ?;`,
+	} {
+		if _, err := tx.Exec(stmt, id); err != nil {
+			return err
+		}
+	}
+	return tx.Commit()
+}
+
+// List returns the saved searches of a user by name
+func (s *Store) List(userID string) ([]*SavedSearch, error) {
+	return s.query(`
+		SELECT id, user_id, name, query, subscribed, created_at, checked_at
+		FROM saved_searches
+		WHERE user_id = ?
+		ORDER BY name COLLATE NOCASE, created_at;
+	`, userID)
+}
+
+// Subscribed returns the subscribed searches of all users
+func (s *Store) Subscribed() ([]*SavedSearch, error) {
+	return s.query(`
+		SELECT id, user_id, name, query, subscribed, created_at, checked_at
+		FROM saved_searches
+		WHERE subscribed = 1
+		ORDER BY created_at;
+	`)
+}
+
+func (s *Store) query(query string, args ...any) ([]*SavedSearch, error) {
+	err := s.Connect()
+	if err != nil {
+		return nil, err
+	}
+	rows, err := s.db.Query(query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	list := []*SavedSearch{}
+	for rows.Next() {
+		search, err := scanSearch(rows)
+		if err != nil {
+			return nil, err
+		}
+		list = append(list, search)
+	}
+	return list, rows.Err()
+}
+
+// Matches returns the content hash of every page which matched a search at the last check
+func (s *Store) Matches(searchID string) (map[string]string, error) {
+	err := s.Connect()
+	if err != nil {
+		return nil, err
+	}
+	rows, err := s.db.Query(`SELECT page_id, hash FROM saved_search_matches WHERE search_id = ?;`, searchID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	matches := map[string]string{}
+	for rows.Next() {
+		var pageID, hash string
+		if err := rows.Scan(&pageID, &hash); err != nil {
+			return nil, err
+		}
+		matches[pageID] = hash
+	}
+	return matches, rows.Err()
+}
+
+// RecordCheck replaces the matches of a search and adds the alerts of the check
+func (s *Store) RecordCheck(search *SavedSearch, matches map[string]string, alerts []Alert) error {
+	err := s.Connect()
+	if err != nil {
+		return err
+	}
+	tx, err := s.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer func() { _ = tx.Rollback() }()
+
+	if _, err := tx.Exec(`DELETE FROM saved_search_matches WHERE search_id = ?;`, search.ID); err != nil {
+		return err
+	}
+	for pageID, hash := range matches {
+		if _, err := tx.Exec(`
+			INSERT INTO saved_search_matches (search_id, page_id, hash) VALUES (?, ?, ?);
+		`, search.ID, pageID, hash); err != nil {
+			return err
+		}
+	}
+	now := time.Now().UTC().UnixNano()
+	for _, alert := range alerts {
+		if _, err := tx.Exec(`
+			INSERT INTO search_alerts (search_id, user_id, page_id, title, path, change, created_at)
+			VALUES (?, ?, ?, ?, ?, ?, ?);
+		`, search.ID, search.UserID, alert.PageID, alert.Title, alert.Path, string(alert.Change), now); err != nil {
+			return err
+		}
+	}
+	if _, err := tx.Exec(`UPDATE saved_searches SET checked_at = ? WHERE id = ?;`, now, search.ID); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// Alerts returns the alerts of a user, the newest first
+func (s *Store) Alerts(userID string, unreadOnly bool) ([]*Alert, error) {
+	err := s.Connect()
+	if err != nil {
+		return nil, err
+	}
+	rows, err := s.db.Query(`
+		SELECT a.id, a.search_id, s.name, a.page_id, a.title, a.path, a.change, a.created_at, a.read
+		FROM search_alerts a
+		JOIN saved_searches s ON s.id = a.search_id
+		WHERE a.user_id = ? AND (? = 0 OR a.read = 0)
+		ORDER BY a.created_at DESC, a.id DESC;
+	`, userID, unreadOnly)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	list := []*Alert{}
+	for rows.Next() {
+		alert := &Alert{}
+		var change string
+		var createdAt int64
+		if err := rows.Scan(&alert.ID, &alert.SearchID, &alert.SearchName, &alert.PageID, &alert.Title,
+			&alert.Path, &change, &createdAt, &alert.Read); err != nil {
+			return nil, err
+		}
+		alert.Change = Change(change)
+		alert.CreatedAt = time.Unix(0, createdAt).UTC()
+		list = append(list, alert)
+	}
+	return list, rows.Err()
+}
+
+// MarkAlertsRead marks all alerts of a user as read
+func (s *Store) MarkAlertsRead(userID string) error {
+	err := s.Connect()
+	if err != nil {
+		return err
+	}
+	_, err = s.db.Exec(`UPDATE search_alerts SET read = 1 WHERE user_id = ?;`, userID)
+	return err
+}
+
+type rowScanner interface {
+	Scan(dest ...any) error
+}
+
+func scanSearch(row rowScanner) (*SavedSearch, error) {
+	search := &SavedSearch{}
+	var createdAt int64
+	var checkedAt sql.NullInt64
+	if err := row.Scan(&search.ID, &search.UserID, &search.Name, &search.Query, &search.Subscribed,
+		&createdAt, &checkedAt); err != nil {
+		return nil, err
+	}
+	search.CreatedAt = time.Unix(0, createdAt).UTC()
+	if checkedAt.Valid {
+		t := time.Unix(0, checkedAt.Int64).UTC()
+		search.CheckedAt = &t
+	}
+	return search, nil
+}
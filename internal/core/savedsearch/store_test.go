@@ -0,0 +1,50 @@
+package savedsearch
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestStore_UpdateStartsOver(t *testing.T) {
+	store, err := NewStore(t.TempDir())
+	if err != nil {
+		t.Fatalf("NewStore failed: %v", err)
+	}
+	defer store.Close()
+
+	saved, err := store.Create("u1", "Postgres", "postgres", true)
+	if err != nil {
+		t.Fatalf("Create failed: %v", err)
+	}
+	if err := store.RecordCheck(saved, map[string]string{"p1": "h1"}, []Alert{{PageID: "p1", Title: "Backup", Path: "backup", Change: ChangeNew}}); err != nil {
+		t.Fatalf("RecordCheck failed: %v", err)
+	}
+	if got, _ := store.Get("u1", saved.ID); got.CheckedAt == nil {
+		t.Fatalf("expected the check to be recorded")
+	}
+
+	// a renamed search keeps its matches
+	renamed, err := store.Update("u1", saved.ID, "Databases", "postgres", true)
+	if err != nil {
+		t.Fatalf("Update failed: %v", err)
+	}
+	if matches, _ := store.Matches(saved.ID); len(matches) != 1 || renamed.CheckedAt == nil {
+		t.Fatalf("expected the matches to be kept, got %v", matches)
+	}
+
+	// a new query starts over
+	changed, err := store.Update("u1", saved.ID, "Databases", "mysql", true)
+	if err != nil {
+		t.Fatalf("Update failed: %v", err)
+	}
+	if matches, _ := store.Matches(saved.ID); len(matches) != 0 || changed.CheckedAt != nil {
+		t.Fatalf("expected the matches to be reset, got %v", matches)
+	}
+
+	if alerts, err := store.Alerts("u1", true); err != nil || len(alerts) != 1 || alerts[0].SearchName != "Databases" {
+		t.Fatalf("unexpected alerts %+v: %v", alerts, err)
+	}
+	if _, err := store.Update("u2", saved.ID, "Mine", "mysql", false); !errors.Is(err, ErrSavedSearchNotFound) {
+		t.Errorf("expected ErrSavedSearchNotFound for another user, got %v", err)
+	}
+}
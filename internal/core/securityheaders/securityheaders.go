@@ -0,0 +1,105 @@
+// Package securityheaders holds the configuration of the security headers sent with every
+// response: Content-Security-Policy, Strict-Transport-Security, Referrer-Policy and
+// X-Content-Type-Options.
+package securityheaders
+
+import (
+	"regexp"
+	"strings"
+)
+
+// Off disables a header
+const Off = "off"
+
+// NoncePlaceholder is replaced by a random nonce per request in the Content-Security-Policy.
+// The nonce is added to the script tags of the frontend.
+const NoncePlaceholder = "{nonce}"
+
+// DefaultContentSecurityPolicy allows scripts only from the wiki itself, the frontend is
+// bundled. Images and media may be embedded from other HTTPS sites, inline styles are
+// needed by the editor and diagrams.
+const DefaultContentSecurityPolicy = "default-src 'self'; " +
+	"script-src 'self' 'nonce-" + NoncePlaceholder + "'; " +
+	"style-src 'self' 'unsafe-inline'; " +
+	"img-src 'self' data: blob: https:; " +
+	"media-src 'self' blob: https:; " +
+	"font-src 'self' data:; " +
+	"connect-src 'self'; " +
+	"object-src 'none'; " +
+	"base-uri 'self'; " +
+	"form-action 'self'; " +
+	"frame-ancestors 'self'"
+
+// DefaultHSTS is sent with HTTPS requests only, so a wiki served over plain HTTP stays reachable
+const DefaultHSTS = "max-age=31536000"
+
+const DefaultReferrerPolicy = "strict-origin-when-cross-origin"
+
+// Config overrides the header values of a deployment. Empty fields use the defaults,
+// Off disables a header.
+type Config struct {
+	// ContentSecurityPolicy may contain NoncePlaceholder, e.g. 'nonce-{nonce}' in script-src
+	ContentSecurityPolicy string
+	// HSTS is the value of Strict-Transport-Security, e.g. max-age=31536000; includeSubDomains
+	HSTS           string
+	ReferrerPolicy string
+}
+
+// Resolve applies the defaults; disabled headers are empty in the result
+func (c Config) Resolve() Config {
+	return Config{
+		ContentSecurityPolicy: resolve(c.ContentSecurityPolicy, DefaultContentSecurityPolicy),
+		HSTS:                  resolve(c.HSTS, DefaultHSTS),
+		ReferrerPolicy:        resolve(c.ReferrerPolicy, DefaultReferrerPolicy),
+	}
+}
+
+func resolve(value, def string) string {
+	value = strings.TrimSpace(value)
+	switch {
+	case value == "":
+		return def
+	case strings.EqualFold(value, Off):
+		return ""
+	}
+	return value
+}
+
+var scriptTagRegex = regexp.MustCompile(`(?i)(<script)(\s|>)`)
+
+// AddNonce adds the nonce attribute to the script tags of an HTML document, so they are
+// allowed by a policy with 'nonce-<nonce>'
+func AddNonce(html []byte, nonce string) []byte {
+	return scriptTagRegex.ReplaceAll(html, []byte(`$1 nonce="`+nonce+`"$2`))
+}
+
+// AddSource allows one more source for a directive of a policy, e.g. a CDN for script-src.
+// A missing directive starts from the sources of default-src; without either the policy
+// doesn't restrict the directive and is returned unchanged.
+func AddSource(policy, directive, source string) string {
+	parts := strings.Split(policy, ";")
+	defaultSources := ""
+	hasDefault := false
+	for i, part := range parts {
+		name, sources, _ := strings.Cut(strings.TrimSpace(part), " ")
+		switch {
+		case strings.EqualFold(name, directive):
+			parts[i] = " " + name + " " + addSource(sources, source)
+			return strings.TrimSpace(strings.Join(parts, ";"))
+		case strings.EqualFold(name, "default-src"):
+			defaultSources, hasDefault = sources, true
+		}
+	}
+	if !hasDefault {
+		return policy
+	}
+	return strings.TrimSpace(policy) + "; " + directive + " " + addSource(defaultSources, source)
+}
+
+func addSource(sources, source string) string {
+	sources = strings.TrimSpace(sources)
+	if sources == "" || sources == "'none'" {
+		return source
+	}
+	return sources + " " + source
+}
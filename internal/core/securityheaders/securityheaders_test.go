@@ -0,0 +1,38 @@
+package securityheaders
+
+import "testing"
+
+func TestConfig_Resolve(t *testing.T) {
+	resolved := Config{HSTS: "off", ReferrerPolicy: "no-referrer"}.Resolve()
+	if resolved.ContentSecurityPolicy != DefaultContentSecurityPolicy {
+		t.Errorf("Expected the default policy, got %q", resolved.ContentSecurityPolicy)
+	}
+	if resolved.HSTS != "" || resolved.ReferrerPolicy != "no-referrer" {
+		t.Errorf("Unexpected headers: %+v", resolved)
+	}
+}
+
+func TestAddNonce(t *testing.T) {
+	html := `<head><script type="module" src="/static/app.js"></script><SCRIPT>track()</SCRIPT><noscript></noscript></head>`
+	got := string(AddNonce([]byte(html), "abc"))
+	want := `<head><script nonce="abc" type="module" src="/static/app.js"></script><SCRIPT nonce="abc">track()</SCRIPT><noscript></noscript></head>`
+	if got != want {
+		t.Errorf("Unexpected HTML:\n%s", got)
+	}
+}
+
+func TestAddSource(t *testing.T) {
+	tests := []struct {
+		policy, directive, want string
+	}{
+		{"default-src 'self'; script-src 'self'", "script-src", "default-src 'self'; script-src 'self' https://cdn.test"},
+		{"default-src 'self'; object-src 'none'", "style-src", "default-src 'self'; object-src 'none'; style-src 'self' https://cdn.test"},
+		{"object-src 'none'", "object-src", "object-src https://cdn.test"},
+		{"frame-ancestors 'self'", "script-src", "frame-ancestors 'self'"},
+	}
+	for _, tt := range tests {
+		if got := AddSource(tt.policy, tt.directive, "https://cdn.test"); got != tt.want {
+			t.Errorf("AddSource(%q, %q) = %q, want %q", tt.policy, tt.directive, got, tt.want)
+		}
+	}
+}
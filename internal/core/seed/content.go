@@ -0,0 +1,295 @@
+package seed
+
+import (
+	"image/color"
+	"strings"
+)
+
+// samplePages is the sample wiki, parents come before their children
+var samplePages = []samplePage{
+	{
+		path:  "sample",
+		title: "Sample Wiki",
+		content: `---
+childIndex: true
+tags: [sample]
+---
+# Sample Wiki
+
+This wiki was created by ` + "`leafwiki seed`" + ` to try out LeafWiki. It shows nested pages,
+assets, frontmatter fields, links between pages and macros. Delete it once you are done.
+
+Start with the [Team Handbook](/sample/handbook) or the [Architecture](/sample/engineering/architecture).
+
+## Recently changed
+
+{{recentchanges path=sample limit=5}}
+`,
+	},
+	{
+		path:  "sample/handbook",
+		title: "Team Handbook",
+		content: `---
+tags: [handbook]
+summary: How the team works together.
+---
+# Team Handbook
+
+Everything a new team member needs to know, from the first day to the first release.
+
+{{childlist depth=2}}
+`,
+	},
+	{
+		path:  "sample/handbook/onboarding",
+		title: "Onboarding",
+		content: `---
+tags: [handbook, onboarding]
+status: done
+owner: [admin]
+---
+# Onboarding
+
+Status: **{{field name=status}}**
+
+## First day
+
+1. Get your accounts for the chat, the mail and the wiki.
+2. Read the [code review guidelines](/sample/handbook/processes/code-review).
+3. Set up your machine with the checklist below.
+
+## Checklist
+
+- [x] Laptop
+- [x] Accounts
+- [ ] First pull request
+
+Download the [checklist as text file]({{asset:checklist.txt}}).
+`,
+		assets: []sampleAsset{{name: "checklist.txt", data: func() []byte {
+			return []byte("Onboarding checklist\n\n[ ] Laptop\n[ ] Accounts\n[ ] First pull request\n")
+		}}},
+	},
+	{
+		path:  "sample/handbook/processes",
+		title: "Processes",
+		content: `---
+tags: [handbook, process]
+---
+# Processes
+
+{{childlist}}
+`,
+	},
+	{
+		path:  "sample/handbook/processes/code-review",
+		title: "Code Review",
+		content: `---
+tags: [process, engineering]
+status: in progress
+---
+# Code Review
+
+Every change is reviewed by one other person before it is merged.
+
+| Size of the change | Reviewers | Expected response |
+|--------------------|-----------|-------------------|
+| Small (< 50 lines) | 1         | same day          |
+| Medium             | 1         | next day          |
+| Large (> 500 lines)| 2         | split it up       |
+
+A good commit message:
+
+` + "```" + `text
+Fix race condition in file watcher initialization
+
+The watcher started before the tree was loaded, so the first
+events referred to pages which didn't exist yet.
+` + "```" + `
+
+Releases follow the [release process](/sample/handbook/processes/releases).
+`,
+	},
+	{
+		path:  "sample/handbook/processes/releases",
+		title: "Releases",
+		content: `---
+tags: [process, release]
+status: draft
+review-by: 2030-01-31
+owner: [admin]
+---
+# Releases
+
+` + "```" + `mermaid
+flowchart LR
+  A[Merge] --> B[Tag] --> C[Build] --> D[Publish]
+` + "```" + `
+
+1. All changes are [reviewed](/sample/handbook/processes/code-review).
+2. The changelog is updated.
+3. The tag triggers the build of the binaries.
+`,
+	},
+	{
+		path:  "sample/engineering",
+		title: "Engineering",
+		content: `---
+tags: [engineering]
+summary: Architecture, API and runbooks.
+---
+# Engineering
+
+{{childlist depth=2}}
+`,
+	},
+	{
+		path:  "sample/engineering/architecture",
+		title: "Architecture",
+		content: `---
+tags: [engineering, architecture]
+status: done
+---
+# Architecture
+
+![Overview]({{asset:overview.png}})
+
+## Components
+
+- **Frontend**: a single page application served by the backend.
+- **Backend**: a single Go binary serving the API and the files.
+- **Storage**: Markdown files, one per page, and SQLite databases for search and users.
+
+## Capacity
+
+The search index grows roughly linearly with the content: $size \approx 1.5 \cdot n_{bytes}$.
+
+The endpoints are described in the [API Reference](/sample/engineering/api-reference#endpoints).
+`,
+		assets: []sampleAsset{{name: "overview.png", data: func() []byte {
+			return pngImage(320, 180, color.RGBA{R: 60, G: 160, B: 90, A: 255})
+		}}},
+	},
+	{
+		path:  "sample/engineering/api-reference",
+		title: "API Reference",
+		content: `---
+tags: [engineering, api]
+---
+# API Reference
+
+## Endpoints
+
+| Method | Path             | Description        |
+|--------|------------------|--------------------|
+| GET    | /api/tree        | The page tree      |
+| GET    | /api/pages/{id}  | A page             |
+| GET    | /api/search?q=   | Full text search   |
+
+## Example
+
+` + "```" + `bash
+curl -H "Authorization: Bearer $TOKEN" http://localhost:8080/api/tree
+` + "```" + `
+
+See the [Architecture](/sample/engineering/architecture) for the components behind it.
+`,
+	},
+	{
+		path:  "sample/engineering/runbooks",
+		title: "Runbooks",
+		content: `---
+tags: [engineering, operations]
+---
+# Runbooks
+
+What to do when things go wrong.
+
+{{childlist}}
+`,
+	},
+	{
+		path:  "sample/engineering/runbooks/database-failover",
+		title: "Database Failover",
+		content: `---
+tags: [operations, database]
+status: draft
+review-by: 2025-01-31
+owner: [admin]
+---
+# Database Failover
+
+> **Warning:** Only follow these steps if the primary database is unreachable for more than five minutes.
+
+1. Check the [architecture](/sample/engineering/architecture) for the affected components.
+2. Promote the replica.
+3. Update the connection settings and restart the services.
+`,
+	},
+	{
+		path:  "sample/meetings",
+		title: "Meeting Notes",
+		content: `---
+tags: [meetings]
+---
+# Meeting Notes
+
+{{recentchanges path=sample/meetings limit=10}}
+`,
+	},
+	{
+		path:  "sample/meetings/2024-05-06-planning",
+		title: "Planning 2024-05-06",
+		content: `---
+tags: [meetings, planning]
+---
+# Planning 2024-05-06
+
+## Decisions
+
+- The next release ships the [database failover](/sample/engineering/runbooks/database-failover) runbook.
+- Code reviews stay at [one reviewer](/sample/handbook/processes/code-review).
+
+## Action items
+
+- [ ] Update the onboarding checklist
+- [x] Draft the release notes
+`,
+	},
+	{
+		path:  "sample/glossary",
+		title: "Glossary",
+		content: `---
+tags: [sample, reference]
+---
+# Glossary
+
+- **Asset**: a file attached to a page, like the image on the [Architecture](/sample/engineering/architecture) page.
+- **Slug**: the part of the path of a page, e.g. ` + "`onboarding`" + `.
+- **Frontmatter**: the fields at the top of a page between ` + "`---`" + `, like ` + "`tags`" + ` and ` + "`status`" + `.
+`,
+	},
+}
+
+// tags are used by the generated pages
+var tags = []string{"engineering", "operations", "planning", "process", "reference", "design", "security", "support"}
+
+// statuses are used by the generated pages
+var statuses = []string{"draft", "in progress", "done"}
+
+// vocabulary is the words of the generated pages, a mix of common and technical words so the
+// search index gets frequent and rare terms
+var vocabulary = strings.Fields(`
+the a of and to in is for on with as by at from that this it be are was or an not
+system service request response page index search query token user team release review
+deploy build test cache database server client network storage backup restore migrate
+config setting option feature document section table figure image asset link reference
+performance latency throughput memory process thread worker queue schedule event message
+error warning failure recovery incident runbook alert monitor metric dashboard report
+design architecture component module package interface contract version change history
+security access permission role account password secret certificate encryption audit
+quickly carefully usually always never often rarely later before after during between
+small large simple complex stable critical optional required default custom internal
+update create delete rename move copy merge split check verify validate measure compare
+customer support ticket priority estimate milestone roadmap sprint planning retrospective
+`)
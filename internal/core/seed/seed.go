@@ -0,0 +1,328 @@
+// Package seed fills a wiki with sample content: a small, hand-written wiki showing the
+// features (nested pages, assets, frontmatter tags, links, macros) and optionally a number of
+// generated pages to exercise the search index at a realistic scale.
+//
+// The content is deterministic for the same options, so benchmarks can be repeated.
+package seed
+
+import (
+	"bytes"
+	"fmt"
+	"image"
+	"image/color"
+	"image/png"
+	"io"
+	"math/rand/v2"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/Gomez12/wiki/internal/core/tree"
+)
+
+// Target is the wiki the content is written to, implemented by *wiki.Wiki
+type Target interface {
+	CreatePage(parentID *string, title string, slug string) (*tree.Page, error)
+	UpdatePage(id, title, slug, content string) (*tree.Page, error)
+	UploadAsset(pageID string, file io.Reader, filename string) (string, error)
+}
+
+// Options select the content to create
+type Options struct {
+	// Pages is the number of generated pages besides the sample wiki
+	Pages int
+	// Seed makes the generated pages reproducible
+	Seed uint64
+	// Progress is called after every created page, may be nil
+	Progress func(done, total int)
+}
+
+// Report counts what was created
+type Report struct {
+	Pages  int `json:"pages"`
+	Assets int `json:"assets"`
+}
+
+const (
+	// SampleSlug is the top-level page of the sample wiki
+	SampleSlug = "sample"
+	// GeneratedSlug is the top-level page of the generated pages
+	GeneratedSlug = "generated"
+	// generatedFanOut is the number of children of each generated page, the generated pages
+	// form a tree of this fan-out
+	generatedFanOut = 10
+	// generatedAssetEvery is how often a generated page gets an image
+	generatedAssetEvery = 25
+)
+
+// Run creates the sample wiki and the generated pages. It fails if the top-level pages
+// already exist, so it is meant for new data directories.
+func Run(target Target, opts Options) (Report, error) {
+	if opts.Pages < 0 {
+		return Report{}, fmt.Errorf("number of pages must not be negative")
+	}
+	s := &seeder{
+		target: target,
+		opts:   opts,
+		total:  len(samplePages),
+		rand:   rand.New(rand.NewPCG(opts.Seed, opts.Seed^0x9e3779b97f4a7c15)),
+	}
+	if opts.Pages > 0 {
+		// the generated pages and their top-level page
+		s.total += opts.Pages + 1
+	}
+	if err := s.sample(); err != nil {
+		return s.report, err
+	}
+	if opts.Pages > 0 {
+		if err := s.generate(); err != nil {
+			return s.report, err
+		}
+	}
+	return s.report, nil
+}
+
+type seeder struct {
+	target Target
+	opts   Options
+	total  int
+	rand   *rand.Rand
+	report Report
+}
+
+// samplePage is a page of the sample wiki. The content may contain {{asset:name}}, which is
+// replaced by the URL of the uploaded asset.
+type samplePage struct {
+	path    string
+	title   string
+	content string
+	assets  []sampleAsset
+}
+
+type sampleAsset struct {
+	name string
+	data func() []byte
+}
+
+func (s *seeder) sample() error {
+	ids := map[string]string{}
+	for _, page := range samplePages {
+		parentPath, slug := splitPath(page.path)
+		var parentID *string
+		if parentPath != "" {
+			id := ids[parentPath]
+			parentID = &id
+		}
+		created, err := s.createPage(parentID, page.title, slug)
+		if err != nil {
+			return fmt.Errorf("create %s: %w", page.path, err)
+		}
+		ids[page.path] = created.ID
+
+		content := page.content
+		for _, asset := range page.assets {
+			url, err := s.uploadAsset(created.ID, asset.name, asset.data())
+			if err != nil {
+				return fmt.Errorf("upload %s to %s: %w", asset.name, page.path, err)
+			}
+			content = strings.ReplaceAll(content, "{{asset:"+asset.name+"}}", url)
+		}
+		if err := s.updatePage(created, content); err != nil {
+			return fmt.Errorf("write %s: %w", page.path, err)
+		}
+	}
+	return nil
+}
+
+// generate creates opts.Pages pages below GeneratedSlug, each with a few paragraphs of text,
+// tags and links to earlier pages
+func (s *seeder) generate() error {
+	root, err := s.createPage(nil, "Generated Pages", GeneratedSlug)
+	if err != nil {
+		return fmt.Errorf("create %s: %w", GeneratedSlug, err)
+	}
+	rootContent := fmt.Sprintf("# Generated Pages\n\n%d generated pages for benchmarks.\n\n{{childlist depth=1}}\n", s.opts.Pages)
+	if err := s.updatePage(root, rootContent); err != nil {
+		return err
+	}
+
+	pages := make([]*tree.Page, 0, s.opts.Pages)
+	paths := make([]string, 0, s.opts.Pages)
+	for i := range s.opts.Pages {
+		// a tree of generatedFanOut children per page
+		parent, parentPath := root, GeneratedSlug
+		if i >= generatedFanOut {
+			parent, parentPath = pages[i/generatedFanOut-1], paths[i/generatedFanOut-1]
+		}
+		title := s.title()
+		slug := fmt.Sprintf("page-%d", i+1)
+		page, err := s.createPage(&parent.ID, title, slug)
+		if err != nil {
+			return fmt.Errorf("create generated page %d: %w", i+1, err)
+		}
+		pages = append(pages, page)
+		paths = append(paths, parentPath+"/"+slug)
+
+		var image string
+		if (i+1)%generatedAssetEvery == 0 {
+			url, err := s.uploadAsset(page.ID, "figure.png", pngImage(160, 90, s.color()))
+			if err != nil {
+				return fmt.Errorf("upload asset of generated page %d: %w", i+1, err)
+			}
+			image = url
+		}
+		if err := s.updatePage(page, s.content(title, paths[:i], image)); err != nil {
+			return fmt.Errorf("write generated page %d: %w", i+1, err)
+		}
+	}
+	return nil
+}
+
+// Corpus writes the generated pages as Markdown files below dir/GeneratedSlug, without a
+// wiki. It is meant for benchmarks of the search index, which attaches the files to the tree
+// while indexing. Pages with children are written as index.md of their folder.
+func Corpus(dir string, pages int, seed uint64) error {
+	if pages < 0 {
+		return fmt.Errorf("number of pages must not be negative")
+	}
+	s := &seeder{rand: rand.New(rand.NewPCG(seed, seed^0x9e3779b97f4a7c15))}
+	paths := make([]string, 0, pages)
+	for i := range pages {
+		parentPath := GeneratedSlug
+		if i >= generatedFanOut {
+			parentPath = paths[i/generatedFanOut-1]
+		}
+		paths = append(paths, fmt.Sprintf("%s/page-%d", parentPath, i+1))
+	}
+
+	files := map[string]string{GeneratedSlug + "/index.md": fmt.Sprintf("# Generated Pages\n\n%d generated pages for benchmarks.\n", pages)}
+	for i, p := range paths {
+		file := p + ".md"
+		if (i+1)*generatedFanOut < pages {
+			// the page has children
+			file = p + "/index.md"
+		}
+		files[file] = s.content(s.title(), paths[:i], "")
+	}
+	for file, content := range files {
+		full := filepath.Join(dir, filepath.FromSlash(file))
+		if err := os.MkdirAll(filepath.Dir(full), 0o755); err != nil {
+			return err
+		}
+		if err := os.WriteFile(full, []byte(content), 0o644); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (s *seeder) createPage(parentID *string, title, slug string) (*tree.Page, error) {
+	page, err := s.target.CreatePage(parentID, title, slug)
+	if err != nil {
+		return nil, err
+	}
+	s.report.Pages++
+	if s.opts.Progress != nil {
+		s.opts.Progress(s.report.Pages, s.total)
+	}
+	return page, nil
+}
+
+func (s *seeder) updatePage(page *tree.Page, content string) error {
+	_, err := s.target.UpdatePage(page.ID, page.Title, page.Slug, content)
+	return err
+}
+
+func (s *seeder) uploadAsset(pageID, name string, data []byte) (string, error) {
+	url, err := s.target.UploadAsset(pageID, bytes.NewReader(data), name)
+	if err != nil {
+		return "", err
+	}
+	s.report.Assets++
+	return url, nil
+}
+
+// title returns a title of two or three words
+func (s *seeder) title() string {
+	words := make([]string, 2+s.rand.IntN(2))
+	for i := range words {
+		word := vocabulary[s.rand.IntN(len(vocabulary))]
+		words[i] = strings.ToUpper(word[:1]) + word[1:]
+	}
+	return strings.Join(words, " ")
+}
+
+// content returns the Markdown of a generated page: frontmatter with tags, paragraphs,
+// a list, links to earlier pages and the image, if any
+func (s *seeder) content(title string, earlier []string, image string) string {
+	var b strings.Builder
+	pageTags := make([]string, 1+s.rand.IntN(3))
+	for i, j := range s.rand.Perm(len(tags))[:len(pageTags)] {
+		pageTags[i] = tags[j]
+	}
+	fmt.Fprintf(&b, "---\ntags: [%s]\nstatus: %s\n---\n# %s\n\n", strings.Join(pageTags, ", "), statuses[s.rand.IntN(len(statuses))], title)
+
+	for section := range 2 + s.rand.IntN(3) {
+		if section > 0 {
+			fmt.Fprintf(&b, "## %s\n\n", s.title())
+		}
+		for range 1 + s.rand.IntN(3) {
+			b.WriteString(s.sentences(2+s.rand.IntN(5)) + "\n\n")
+		}
+	}
+	for range 2 + s.rand.IntN(4) {
+		b.WriteString("- " + s.sentences(1) + "\n")
+	}
+	b.WriteString("\n")
+
+	if image != "" {
+		fmt.Fprintf(&b, "![Figure](%s)\n\n", image)
+	}
+	if len(earlier) > 0 {
+		b.WriteString("See also:\n\n")
+		for range min(len(earlier), 1+s.rand.IntN(3)) {
+			target := earlier[s.rand.IntN(len(earlier))]
+			fmt.Fprintf(&b, "- [%s](/%s)\n", target[strings.LastIndex(target, "/")+1:], target)
+		}
+	}
+	return b.String()
+}
+
+// sentences returns n sentences of 6 to 17 words
+func (s *seeder) sentences(n int) string {
+	list := make([]string, n)
+	for i := range list {
+		words := make([]string, 6+s.rand.IntN(12))
+		for j := range words {
+			words[j] = vocabulary[s.rand.IntN(len(vocabulary))]
+		}
+		words[0] = strings.ToUpper(words[0][:1]) + words[0][1:]
+		list[i] = strings.Join(words, " ") + "."
+	}
+	return strings.Join(list, " ")
+}
+
+func (s *seeder) color() color.RGBA {
+	return color.RGBA{R: uint8(s.rand.IntN(256)), G: uint8(s.rand.IntN(256)), B: uint8(s.rand.IntN(256)), A: 255}
+}
+
+// pngImage returns a PNG with a diagonal gradient of the color
+func pngImage(width, height int, c color.RGBA) []byte {
+	img := image.NewRGBA(image.Rect(0, 0, width, height))
+	for y := range height {
+		for x := range width {
+			shade := uint8((x + y) * 255 / (width + height))
+			img.Set(x, y, color.RGBA{R: c.R / 2, G: c.G/2 + shade/4, B: c.B/2 + shade/2, A: 255})
+		}
+	}
+	var buf bytes.Buffer
+	_ = png.Encode(&buf, img)
+	return buf.Bytes()
+}
+
+func splitPath(path string) (parent, slug string) {
+	if i := strings.LastIndex(path, "/"); i >= 0 {
+		return path[:i], path[i+1:]
+	}
+	return "", path
+}
@@ -0,0 +1,157 @@
+package seed
+
+import (
+	"fmt"
+	"io"
+	"io/fs"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/Gomez12/wiki/internal/core/tree"
+)
+
+// fakeTarget records the pages and assets in memory, pages by path
+type fakeTarget struct {
+	paths    map[string]string
+	contents map[string]string
+	assets   []string
+}
+
+func newFakeTarget() *fakeTarget {
+	return &fakeTarget{paths: map[string]string{}, contents: map[string]string{}}
+}
+
+func (f *fakeTarget) CreatePage(parentID *string, title string, slug string) (*tree.Page, error) {
+	if err := tree.NewSlugService().IsValidSlug(slug); err != nil {
+		return nil, err
+	}
+	path := slug
+	if parentID != nil {
+		path = f.paths[*parentID] + "/" + slug
+	}
+	for _, existing := range f.paths {
+		if existing == path {
+			return nil, fmt.Errorf("page %s already exists", path)
+		}
+	}
+	id := fmt.Sprintf("id-%d", len(f.paths)+1)
+	f.paths[id] = path
+	return &tree.Page{PageNode: &tree.PageNode{ID: id, Title: title, Slug: slug}}, nil
+}
+
+func (f *fakeTarget) UpdatePage(id, title, slug, content string) (*tree.Page, error) {
+	f.contents[f.paths[id]] = content
+	return &tree.Page{PageNode: &tree.PageNode{ID: id, Title: title, Slug: slug}, Content: content}, nil
+}
+
+func (f *fakeTarget) UploadAsset(pageID string, file io.Reader, filename string) (string, error) {
+	if _, err := io.ReadAll(file); err != nil {
+		return "", err
+	}
+	url := "/assets/" + pageID + "/" + filename
+	f.assets = append(f.assets, url)
+	return url, nil
+}
+
+var pageLinkRegex = regexp.MustCompile(`\]\(/([a-z0-9/-]+)(?:#[a-z-]+)?\)`)
+
+func TestRun(t *testing.T) {
+	target := newFakeTarget()
+	var lastDone, lastTotal int
+	report, err := Run(target, Options{Pages: 60, Seed: 1, Progress: func(done, total int) {
+		lastDone, lastTotal = done, total
+	}})
+	if err != nil {
+		t.Fatalf("Run failed: %v", err)
+	}
+
+	wantPages := len(samplePages) + 1 + 60
+	if report.Pages != wantPages || len(target.paths) != wantPages {
+		t.Errorf("Expected %d pages, got %d (%d created)", wantPages, report.Pages, len(target.paths))
+	}
+	if wantAssets := 2 + 60/generatedAssetEvery; report.Assets != wantAssets {
+		t.Errorf("Expected %d assets, got %d", wantAssets, report.Assets)
+	}
+	if lastDone != wantPages || lastTotal != wantPages {
+		t.Errorf("Expected the progress to end at %d/%d, got %d/%d", wantPages, wantPages, lastDone, lastTotal)
+	}
+	if _, ok := target.contents["generated/page-1/page-1"]; ok {
+		t.Errorf("Expected unique paths of the generated pages")
+	}
+	if _, ok := target.contents["generated/page-2/page-21"]; !ok {
+		t.Errorf("Expected page 21 below page 2 with a fan-out of %d", generatedFanOut)
+	}
+
+	for path, content := range target.contents {
+		if strings.Contains(content, "{{asset:") {
+			t.Errorf("Unreplaced asset placeholder in %s", path)
+		}
+		if !strings.HasPrefix(content, "---\n") && path != GeneratedSlug {
+			t.Errorf("Expected frontmatter in %s", path)
+		}
+		// links point to pages which exist
+		for _, match := range pageLinkRegex.FindAllStringSubmatch(content, -1) {
+			if _, ok := target.contents[match[1]]; !ok {
+				t.Errorf("Broken link to %s in %s", match[1], path)
+			}
+		}
+	}
+}
+
+func TestRun_Deterministic(t *testing.T) {
+	first, second, other := newFakeTarget(), newFakeTarget(), newFakeTarget()
+	for target, seed := range map[*fakeTarget]uint64{first: 7, second: 7, other: 8} {
+		if _, err := Run(target, Options{Pages: 20, Seed: seed}); err != nil {
+			t.Fatalf("Run failed: %v", err)
+		}
+	}
+	if first.contents["generated/page-5"] != second.contents["generated/page-5"] {
+		t.Errorf("Expected the same content for the same seed")
+	}
+	if first.contents["generated/page-5"] == other.contents["generated/page-5"] {
+		t.Errorf("Expected other content for another seed")
+	}
+}
+
+func TestRun_ExistingSample(t *testing.T) {
+	target := newFakeTarget()
+	if _, err := target.CreatePage(nil, "Sample", SampleSlug); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := Run(target, Options{}); err == nil {
+		t.Errorf("Expected an error if the sample wiki exists")
+	}
+	if _, err := Run(newFakeTarget(), Options{Pages: -1}); err == nil {
+		t.Errorf("Expected an error for a negative number of pages")
+	}
+}
+
+func TestCorpus(t *testing.T) {
+	dir := t.TempDir()
+	if err := Corpus(dir, 25, 1); err != nil {
+		t.Fatalf("Corpus failed: %v", err)
+	}
+
+	var files []string
+	err := filepath.WalkDir(dir, func(p string, d fs.DirEntry, err error) error {
+		if err == nil && !d.IsDir() {
+			rel, _ := filepath.Rel(dir, p)
+			files = append(files, filepath.ToSlash(rel))
+		}
+		return err
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(files) != 26 {
+		t.Errorf("Expected 26 files, got %d: %v", len(files), files)
+	}
+	for _, want := range []string{"generated/index.md", "generated/page-1/index.md", "generated/page-2/page-21.md", "generated/page-3.md"} {
+		if !slices.Contains(files, want) {
+			t.Errorf("Expected %s in %v", want, files)
+		}
+	}
+}
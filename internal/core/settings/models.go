@@ -0,0 +1,139 @@
+package settings
+
+import (
+	"github.com/Gomez12/wiki/internal/core/auth"
+	"github.com/Gomez12/wiki/internal/core/highlight"
+	"github.com/Gomez12/wiki/internal/core/images"
+	"github.com/Gomez12/wiki/internal/core/lint"
+	"github.com/Gomez12/wiki/internal/core/ratelimit"
+	"github.com/Gomez12/wiki/internal/core/staticsite"
+	"github.com/Gomez12/wiki/internal/core/texmath"
+)
+
+// DefaultSiteTitle is shown as long as no site title has been configured
+const DefaultSiteTitle = "LeafWiki"
+
+// DefaultMaxUploadSize is the default upload limit for assets in bytes
+const DefaultMaxUploadSize int64 = 500 << 20
+
+// DefaultMaxRequestSize is the default size limit in bytes of other requests, e.g. page saves
+const DefaultMaxRequestSize int64 = 10 << 20
+
+// DefaultStreamThreshold is the default size in bytes from which assets are streamed from disk
+const DefaultStreamThreshold int64 = 1 << 20
+
+// Settings are the runtime-tunable options of a wiki. They are changed by
+// administrators and take effect without a restart.
+type Settings struct {
+	SiteTitle string `json:"siteTitle"`
+	// PublicAccess overrides the public access flag given at startup; nil keeps the flag
+	PublicAccess *bool `json:"publicAccess"`
+	// MaxUploadSize is the maximum size of an uploaded asset in bytes
+	MaxUploadSize int64 `json:"maxUploadSize"`
+	// MaxRequestSize is the maximum size in bytes of the body of other requests, e.g. page saves
+	MaxRequestSize int64 `json:"maxRequestSize"`
+	// StreamThreshold is the size in bytes from which assets are streamed from disk, smaller
+	// assets are read at once so their file isn't kept open while slow clients download them
+	StreamThreshold int64 `json:"streamThreshold"`
+	// StripImageMetadata removes EXIF (including GPS positions) and other metadata from
+	// uploaded JPEG and PNG images
+	StripImageMetadata bool `json:"stripImageMetadata"`
+	// ImageMaxDimension scales down uploaded JPEG and PNG images which are wider or higher; 0 keeps the size
+	ImageMaxDimension int `json:"imageMaxDimension"`
+	// ImageQuality is the JPEG quality of scaled down images, from 1 to 100
+	ImageQuality int `json:"imageQuality"`
+	// HistoryRetentionDays prunes page history older than this many days; 0 keeps everything
+	HistoryRetentionDays int           `json:"historyRetentionDays"`
+	Webhook              WebhookConfig `json:"webhook"`
+	// Math controls how $...$ and $$...$$ math is rendered in exported pages
+	Math texmath.Mode `json:"math"`
+	// CodeTheme is the highlighting theme of code blocks in rendered pages, "off" disables it
+	CodeTheme string `json:"codeTheme"`
+	// RawHTML controls how HTML in the Markdown is rendered: stripped, sanitized or kept as
+	// written for pages edited by trusted roles
+	RawHTML staticsite.RawHTML `json:"rawHTML"`
+	// RawHTMLTrustedRoles may add any raw HTML while RawHTML is trusted
+	RawHTMLTrustedRoles []string `json:"rawHTMLTrustedRoles"`
+	// IgnorePatterns are gitignore-style patterns applied after the rules of the
+	// .leafwikiignore file, matching files are not indexed, tracked or attached to the tree
+	IgnorePatterns []string `json:"ignorePatterns"`
+	// TrackRecentPages records the pages viewed by the users who opted in for their "recent"
+	// list, it is the privacy switch to disable the tracking for everyone
+	TrackRecentPages bool `json:"trackRecentPages"`
+	// Lint enables the content rules checked when a page is saved and with the lint endpoint
+	Lint lint.Config `json:"lint"`
+	// RateLimit limits the requests to expensive endpoints like search, exports and uploads
+	RateLimit RateLimitConfig `json:"rateLimit"`
+	// RequestTimeout limits how long searches, rendering and exports may take
+	RequestTimeout RequestTimeoutConfig `json:"requestTimeout"`
+}
+
+// WebhookConfig describes where change notifications are delivered
+type WebhookConfig struct {
+	URL    string   `json:"url"`
+	Secret string   `json:"secret"`
+	Events []string `json:"events"`
+}
+
+// RateLimitConfig limits the requests of each client to search, rendering, exports and uploads
+type RateLimitConfig struct {
+	// PerIP limits the requests without a token per client IP
+	PerIP RateLimit `json:"perIP"`
+	// PerToken limits the requests with a token per user
+	PerToken RateLimit `json:"perToken"`
+}
+
+// RateLimit is a token bucket refilled with RequestsPerMinute tokens a minute, up to Burst tokens
+type RateLimit struct {
+	// RequestsPerMinute is the sustained rate, 0 disables the limit
+	RequestsPerMinute int `json:"requestsPerMinute"`
+	// Burst is the number of requests allowed at once
+	Burst int `json:"burst"`
+}
+
+// RequestTimeoutConfig is the time in seconds after which the work of a request is cancelled,
+// 0 disables the timeout
+type RequestTimeoutConfig struct {
+	SearchSeconds int `json:"searchSeconds"`
+	RenderSeconds int `json:"renderSeconds"`
+	// ExportSeconds applies to the exports of single pages and of the whole wiki
+	ExportSeconds int `json:"exportSeconds"`
+}
+
+// Rate returns the limit as rate of the limiter
+func (r RateLimit) Rate() ratelimit.Rate {
+	return ratelimit.Rate{PerMinute: r.RequestsPerMinute, Burst: r.Burst}
+}
+
+// Defaults returns the settings used for every option which hasn't been configured
+func Defaults() Settings {
+	return Settings{
+		SiteTitle:           DefaultSiteTitle,
+		MaxUploadSize:       DefaultMaxUploadSize,
+		MaxRequestSize:      DefaultMaxRequestSize,
+		StreamThreshold:     DefaultStreamThreshold,
+		StripImageMetadata:  true,
+		ImageQuality:        images.DefaultQuality,
+		Webhook:             WebhookConfig{Events: []string{}},
+		Math:                texmath.ModeOff,
+		CodeTheme:           highlight.DefaultTheme,
+		RawHTML:             staticsite.RawHTMLSanitize,
+		RawHTMLTrustedRoles: []string{auth.RoleAdmin},
+		IgnorePatterns:      []string{},
+		TrackRecentPages:    true,
+		RateLimit: RateLimitConfig{
+			PerIP:    RateLimit{RequestsPerMinute: 30, Burst: 10},
+			PerToken: RateLimit{RequestsPerMinute: 120, Burst: 30},
+		},
+		RequestTimeout: RequestTimeoutConfig{SearchSeconds: 10, RenderSeconds: 10, ExportSeconds: 120},
+		Lint:           lint.DefaultConfig(),
+	}
+}
+
+// PublicAccessOr returns the configured public access mode, or fallback if it isn't configured
+func (s Settings) PublicAccessOr(fallback bool) bool {
+	if s.PublicAccess == nil {
+		return fallback
+	}
+	return *s.PublicAccess
+}
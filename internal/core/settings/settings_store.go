@@ -0,0 +1,157 @@
+package settings
+
+import (
+	"database/sql"
+	"encoding/json"
+	"path"
+	"sync"
+
+	_ "modernc.org/sqlite"
+)
+
+// settingsKey is the key the settings are stored under
+const settingsKey = "settings"
+
+type SettingsStore struct {
+	storageDir string
+	filename   string
+	db         *sql.DB
+
+	// mu guards cached, which is read on every request
+	mu     sync.RWMutex
+	cached *Settings
+}
+
+func NewSettingsStore(storageDir string) (*SettingsStore, error) {
+	s := &SettingsStore{
+		storageDir: storageDir,
+		filename:   "settings.db",
+	}
+
+	err := s.Connect()
+	if err != nil {
+		return nil, err
+	}
+
+	return s, s.ensureSchema()
+}
+
+func (s *SettingsStore) Connect() error {
+	// Database is already open and connected
+	if s.db != nil {
+		return nil
+	}
+	db, err := sql.Open("sqlite", path.Join(s.storageDir, s.filename))
+	if err != nil {
+		return err
+	}
+	s.db = db
+	return nil
+}
+
+func (s *SettingsStore) ensureSchema() error {
+	err := s.Connect()
+	if err != nil {
+		return err
+	}
+	_, err = s.db.Exec(`
+		CREATE TABLE IF NOT EXISTS settings (
+			key TEXT PRIMARY KEY,
+			value TEXT NOT NULL,
+			updated_at DATETIME DEFAULT CURRENT_TIMESTAMP
+		);
+	`)
+	return err
+}
+
+func (s *SettingsStore) Close() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.db != nil {
+		err := s.db.Close()
+		if err != nil {
+			return err
+		}
+		s.db = nil
+	}
+	return nil
+}
+
+// Get returns the stored settings. Options which haven't been stored keep their default.
+func (s *SettingsStore) Get() (Settings, error) {
+	s.mu.RLock()
+	if s.cached != nil {
+		defer s.mu.RUnlock()
+		return s.cached.clone(), nil
+	}
+	s.mu.RUnlock()
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.cached != nil {
+		return s.cached.clone(), nil
+	}
+
+	err := s.Connect()
+	if err != nil {
+		return Settings{}, err
+	}
+
+	settings := Defaults()
+	var value string
+	err = s.db.QueryRow(`SELECT value FROM settings WHERE key = ?;`, settingsKey).Scan(&value)
+	switch {
+	case err == sql.ErrNoRows:
+	case err != nil:
+		return Settings{}, err
+	default:
+		if err := json.Unmarshal([]byte(value), &settings); err != nil {
+			return Settings{}, err
+		}
+	}
+
+	s.cached = &settings
+	return settings.clone(), nil
+}
+
+// Save replaces the stored settings
+func (s *SettingsStore) Save(settings Settings) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	err := s.Connect()
+	if err != nil {
+		return err
+	}
+
+	data, err := json.Marshal(settings)
+	if err != nil {
+		return err
+	}
+
+	_, err = s.db.Exec(`
+		INSERT INTO settings (key, value, updated_at) VALUES (?, ?, CURRENT_TIMESTAMP)
+		ON CONFLICT(key) DO UPDATE SET value = excluded.value, updated_at = excluded.updated_at;
+	`, settingsKey, string(data))
+	if err != nil {
+		return err
+	}
+
+	saved := settings.clone()
+	s.cached = &saved
+	return nil
+}
+
+// clone copies the settings, so callers can't modify the cached settings
+func (s Settings) clone() Settings {
+	c := s
+	if s.PublicAccess != nil {
+		v := *s.PublicAccess
+		c.PublicAccess = &v
+	}
+	c.Webhook.Events = append([]string{}, s.Webhook.Events...)
+	c.IgnorePatterns = append([]string{}, s.IgnorePatterns...)
+	c.RawHTMLTrustedRoles = append([]string{}, s.RawHTMLTrustedRoles...)
+	return c
+}
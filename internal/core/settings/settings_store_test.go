@@ -0,0 +1,61 @@
+package settings
+
+import "testing"
+
+func TestSettingsStore_DefaultsAndSave(t *testing.T) {
+	dir := t.TempDir()
+	store, err := NewSettingsStore(dir)
+	if err != nil {
+		t.Fatalf("NewSettingsStore failed: %v", err)
+	}
+
+	got, err := store.Get()
+	if err != nil {
+		t.Fatalf("Get failed: %v", err)
+	}
+	if got.SiteTitle != DefaultSiteTitle || got.MaxUploadSize != DefaultMaxUploadSize || got.PublicAccess != nil {
+		t.Errorf("expected defaults, got %+v", got)
+	}
+	if !got.PublicAccessOr(true) || got.PublicAccessOr(false) {
+		t.Errorf("expected unset public access to follow the fallback")
+	}
+
+	public := false
+	got.SiteTitle = "Team Wiki"
+	got.PublicAccess = &public
+	got.HistoryRetentionDays = 30
+	got.Webhook = WebhookConfig{URL: "https://example.com/hook", Events: []string{"page.updated"}}
+	if err := store.Save(got); err != nil {
+		t.Fatalf("Save failed: %v", err)
+	}
+
+	// modifying the returned settings must not change the cached ones
+	got.Webhook.Events[0] = "changed"
+	*got.PublicAccess = true
+	if cached, _ := store.Get(); cached.Webhook.Events[0] != "page.updated" || cached.PublicAccessOr(true) {
+		t.Errorf("cached settings were modified: %+v", cached)
+	}
+
+	if err := store.Close(); err != nil {
+		t.Fatalf("Close failed: %v", err)
+	}
+	reopened, err := NewSettingsStore(dir)
+	if err != nil {
+		t.Fatalf("NewSettingsStore failed: %v", err)
+	}
+	defer reopened.Close()
+
+	loaded, err := reopened.Get()
+	if err != nil {
+		t.Fatalf("Get failed: %v", err)
+	}
+	if loaded.SiteTitle != "Team Wiki" || loaded.HistoryRetentionDays != 30 || loaded.PublicAccessOr(true) {
+		t.Errorf("unexpected settings after reopen: %+v", loaded)
+	}
+	if loaded.MaxUploadSize != DefaultMaxUploadSize {
+		t.Errorf("expected default upload size to be kept, got %d", loaded.MaxUploadSize)
+	}
+	if len(loaded.Webhook.Events) != 1 || loaded.Webhook.Events[0] != "page.updated" {
+		t.Errorf("unexpected webhook events: %v", loaded.Webhook.Events)
+	}
+}
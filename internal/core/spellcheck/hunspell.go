@@ -0,0 +1,494 @@
+package spellcheck
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"regexp"
+	"strconv"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"golang.org/x/text/encoding/htmlindex"
+	"golang.org/x/text/transform"
+)
+
+// maxSuggestions is the number of suggestions returned for a misspelled word
+const maxSuggestions = 5
+
+// Dictionary is a hunspell dictionary, read from an .aff and a .dic file. It supports
+// prefixes and suffixes with cross products, NEEDAFFIX, FORBIDDENWORD, TRY and REP;
+// compounding is not supported, so compounds must be listed in the .dic file.
+type Dictionary struct {
+	// words maps the stems to their flags, a stem can be listed more than once
+	words     map[string][]flagSet
+	prefixes  []affix
+	suffixes  []affix
+	try       string
+	rep       [][2]string
+	needAffix string
+	forbidden string
+}
+
+type flagSet map[string]bool
+
+// affix is one rule of a PFX or SFX class
+type affix struct {
+	flag  string
+	cross bool
+	strip string
+	add   string
+	cond  *regexp.Regexp
+}
+
+// flagParser splits the flags of a word or rule as set by the FLAG option
+type flagParser func(string) []string
+
+// ReadDictionary reads a hunspell dictionary. The encoding is taken from the SET option of
+// the affix file, UTF-8 if it is missing.
+func ReadDictionary(aff, dic io.Reader) (*Dictionary, error) {
+	affData, err := io.ReadAll(aff)
+	if err != nil {
+		return nil, err
+	}
+	encoding := ""
+	for _, line := range strings.Split(string(affData), "\n") {
+		if fields := strings.Fields(line); len(fields) >= 2 && fields[0] == "SET" {
+			encoding = fields[1]
+			break
+		}
+	}
+	decode := func(r io.Reader) io.Reader { return r }
+	if encoding != "" && !strings.EqualFold(encoding, "UTF-8") {
+		enc, err := htmlindex.Get(encoding)
+		if err != nil {
+			return nil, fmt.Errorf("unsupported dictionary encoding %q", encoding)
+		}
+		decode = func(r io.Reader) io.Reader { return transform.NewReader(r, enc.NewDecoder()) }
+	}
+
+	d := &Dictionary{words: map[string][]flagSet{}}
+	flags, err := d.readAffixes(decode(strings.NewReader(string(affData))))
+	if err != nil {
+		return nil, err
+	}
+	if err := d.readWords(decode(dic), flags); err != nil {
+		return nil, err
+	}
+	return d, nil
+}
+
+// readAffixes reads the options and affix rules and returns how flags are written
+func (d *Dictionary) readAffixes(r io.Reader) (flagParser, error) {
+	flags := flagParser(func(s string) []string {
+		list := []string{}
+		for _, r := range s {
+			list = append(list, string(r))
+		}
+		return list
+	})
+	one := func(s string) string {
+		if list := flags(s); len(list) > 0 {
+			return list[0]
+		}
+		return ""
+	}
+
+	// cross tells which affix classes may combine with the other kind, e.g. "SFXA"
+	cross := map[string]bool{}
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 0, 64*1024), 1024*1024)
+	lineNo := 0
+	for scanner.Scan() {
+		lineNo++
+		fields := strings.Fields(scanner.Text())
+		if len(fields) < 2 || strings.HasPrefix(fields[0], "#") {
+			continue
+		}
+		switch fields[0] {
+		case "FLAG":
+			switch fields[1] {
+			case "long":
+				flags = func(s string) []string {
+					runes := []rune(s)
+					list := []string{}
+					for i := 0; i+1 < len(runes); i += 2 {
+						list = append(list, string(runes[i:i+2]))
+					}
+					return list
+				}
+			case "num":
+				flags = func(s string) []string {
+					list := []string{}
+					for _, f := range strings.Split(s, ",") {
+						if f = strings.TrimSpace(f); f != "" {
+							list = append(list, f)
+						}
+					}
+					return list
+				}
+			}
+		case "TRY":
+			d.try = fields[1]
+		case "NEEDAFFIX":
+			d.needAffix = one(fields[1])
+		case "FORBIDDENWORD":
+			d.forbidden = one(fields[1])
+		case "REP":
+			// the first REP line holds the number of rules
+			if len(fields) >= 3 {
+				d.rep = append(d.rep, [2]string{repText(fields[1]), repText(fields[2])})
+			}
+		case "PFX", "SFX":
+			// the header of a class is "SFX flag cross count", its rules "SFX flag strip add condition"
+			if len(fields) == 4 && (fields[2] == "Y" || fields[2] == "N") {
+				if _, err := strconv.Atoi(fields[3]); err == nil {
+					cross[fields[0]+one(fields[1])] = fields[2] == "Y"
+					continue
+				}
+			}
+			if len(fields) < 4 {
+				return nil, fmt.Errorf("invalid affix rule in line %d", lineNo)
+			}
+			a, err := d.parseAffix(fields, one(fields[1]))
+			if err != nil {
+				return nil, fmt.Errorf("invalid affix rule in line %d: %w", lineNo, err)
+			}
+			a.cross = cross[fields[0]+a.flag]
+			if fields[0] == "PFX" {
+				d.prefixes = append(d.prefixes, a)
+			} else {
+				d.suffixes = append(d.suffixes, a)
+			}
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	return flags, nil
+}
+
+// parseAffix parses a rule, the condition is matched at the start of the stem for prefixes
+// and at the end for suffixes
+func (d *Dictionary) parseAffix(fields []string, flag string) (affix, error) {
+	a := affix{flag: flag, strip: fields[2], add: fields[3]}
+	if a.strip == "0" {
+		a.strip = ""
+	}
+	// continuation flags of twofold affixes are not supported
+	if i := strings.Index(a.add, "/"); i >= 0 {
+		a.add = a.add[:i]
+	}
+	if a.add == "0" {
+		a.add = ""
+	}
+	cond := "."
+	if len(fields) >= 5 {
+		cond = fields[4]
+	}
+	pattern := conditionPattern(cond)
+	if fields[0] == "PFX" {
+		pattern = "^" + pattern
+	} else {
+		pattern += "$"
+	}
+	re, err := regexp.Compile(pattern)
+	if err != nil {
+		return affix{}, err
+	}
+	a.cond = re
+	return a, nil
+}
+
+// conditionPattern turns an affix condition into a regular expression. Conditions only
+// know characters, classes in brackets and the dot.
+func conditionPattern(cond string) string {
+	var b strings.Builder
+	inClass := false
+	for i, r := range cond {
+		switch {
+		case r == '[' && !inClass:
+			inClass = true
+			b.WriteRune(r)
+		case r == ']' && inClass:
+			inClass = false
+			b.WriteRune(r)
+		case r == '^' && inClass && i > 0 && cond[i-1] == '[':
+			b.WriteRune(r)
+		case r == '.' && !inClass:
+			b.WriteRune(r)
+		default:
+			b.WriteString(regexp.QuoteMeta(string(r)))
+		}
+	}
+	return b.String()
+}
+
+// repText decodes the underscores REP rules use for spaces
+func repText(s string) string {
+	return strings.ReplaceAll(s, "_", " ")
+}
+
+// readWords reads the stems of the .dic file, the first line holds their approximate count
+func (d *Dictionary) readWords(r io.Reader, flags flagParser) error {
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 0, 64*1024), 1024*1024)
+	first := true
+	for scanner.Scan() {
+		line := strings.TrimRight(scanner.Text(), "\r")
+		if first {
+			first = false
+			if _, err := strconv.Atoi(strings.TrimSpace(line)); err == nil {
+				continue
+			}
+		}
+		// morphological fields follow after whitespace, lines starting with it are comments
+		if line == "" || line[0] == ' ' || line[0] == '\t' {
+			continue
+		}
+		if i := strings.IndexAny(line, " \t"); i >= 0 {
+			line = line[:i]
+		}
+		word, flagText := splitWordFlags(line)
+		if word == "" {
+			continue
+		}
+		set := flagSet{}
+		for _, f := range flags(flagText) {
+			set[f] = true
+		}
+		d.words[word] = append(d.words[word], set)
+	}
+	return scanner.Err()
+}
+
+// splitWordFlags splits "word/flags", a slash in the word is escaped as \/
+func splitWordFlags(line string) (string, string) {
+	for i := 0; i < len(line); i++ {
+		if line[i] == '\\' && i+1 < len(line) && line[i+1] == '/' {
+			i++
+			continue
+		}
+		if line[i] == '/' {
+			return strings.ReplaceAll(line[:i], `\/`, "/"), line[i+1:]
+		}
+	}
+	return strings.ReplaceAll(line, `\/`, "/"), ""
+}
+
+// Correct tells if the word is spelled correctly. Capitalized and upper case words are
+// also accepted in lower case, so words at the start of a sentence pass.
+func (d *Dictionary) Correct(word string) bool {
+	if word == "" {
+		return true
+	}
+	for _, variant := range caseVariants(word) {
+		if d.correct(variant) {
+			return true
+		}
+	}
+	return false
+}
+
+// caseVariants returns the spellings a word is looked up in
+func caseVariants(word string) []string {
+	variants := []string{word}
+	first, size := utf8.DecodeRuneInString(word)
+	rest := word[size:]
+	switch {
+	case !unicode.IsUpper(first):
+	case rest == strings.ToLower(rest):
+		variants = append(variants, strings.ToLower(word))
+	case word == strings.ToUpper(word):
+		variants = append(variants, strings.ToLower(word), string(first)+strings.ToLower(rest))
+	}
+	return variants
+}
+
+// correct looks up a word as stem, with a suffix, a prefix or both
+func (d *Dictionary) correct(word string) bool {
+	for _, set := range d.words[word] {
+		if !set[d.needAffix] && !set[d.forbidden] {
+			return true
+		}
+	}
+	if d.forbiddenWord(word) {
+		return false
+	}
+	for _, sfx := range d.suffixes {
+		stem, ok := sfx.stripSuffix(word)
+		if !ok {
+			continue
+		}
+		if d.hasStem(stem, sfx.flag) {
+			return true
+		}
+		if !sfx.cross {
+			continue
+		}
+		for _, pfx := range d.prefixes {
+			if root, ok := pfx.stripPrefix(stem); ok && pfx.cross && d.hasStem(root, sfx.flag, pfx.flag) {
+				return true
+			}
+		}
+	}
+	for _, pfx := range d.prefixes {
+		if stem, ok := pfx.stripPrefix(word); ok && d.hasStem(stem, pfx.flag) {
+			return true
+		}
+	}
+	return false
+}
+
+// forbiddenWord tells if the word is listed with FORBIDDENWORD
+func (d *Dictionary) forbiddenWord(word string) bool {
+	if d.forbidden == "" {
+		return false
+	}
+	for _, set := range d.words[word] {
+		if set[d.forbidden] {
+			return true
+		}
+	}
+	return false
+}
+
+// hasStem tells if the stem is listed with all the flags
+func (d *Dictionary) hasStem(stem string, flags ...string) bool {
+	for _, set := range d.words[stem] {
+		if set[d.forbidden] && d.forbidden != "" {
+			continue
+		}
+		all := true
+		for _, f := range flags {
+			all = all && set[f]
+		}
+		if all {
+			return true
+		}
+	}
+	return false
+}
+
+// stripSuffix returns the stem a word with the suffix was built from
+func (a affix) stripSuffix(word string) (string, bool) {
+	if !strings.HasSuffix(word, a.add) || len(word) == len(a.add) && a.strip == "" {
+		return "", false
+	}
+	stem := word[:len(word)-len(a.add)] + a.strip
+	return stem, a.cond.MatchString(stem)
+}
+
+// stripPrefix returns the stem a word with the prefix was built from
+func (a affix) stripPrefix(word string) (string, bool) {
+	if !strings.HasPrefix(word, a.add) || len(word) == len(a.add) && a.strip == "" {
+		return "", false
+	}
+	stem := a.strip + word[len(a.add):]
+	return stem, a.cond.MatchString(stem)
+}
+
+// Suggest returns up to five corrections of a misspelled word: replacements of the REP
+// table first, then words one edit away using the characters of TRY, then splits in two
+// words. The case of the word is kept.
+func (d *Dictionary) Suggest(word string) []string {
+	suggestions := []string{}
+	seen := map[string]bool{word: true}
+	add := func(candidate string) bool {
+		if seen[candidate] {
+			return false
+		}
+		seen[candidate] = true
+		if !d.suggestable(candidate) {
+			return false
+		}
+		suggestions = append(suggestions, matchCase(word, candidate))
+		return len(suggestions) >= maxSuggestions
+	}
+
+	lower := word
+	if len(caseVariants(word)) > 1 {
+		lower = strings.ToLower(word)
+	}
+	for _, rep := range d.rep {
+		for i := strings.Index(lower, rep[0]); i >= 0; {
+			if add(lower[:i] + rep[1] + lower[i+len(rep[0]):]) {
+				return suggestions
+			}
+			next := strings.Index(lower[i+1:], rep[0])
+			if next < 0 {
+				break
+			}
+			i += next + 1
+		}
+	}
+	for _, candidate := range edits(lower, d.try) {
+		if add(candidate) {
+			return suggestions
+		}
+	}
+	runes := []rune(lower)
+	for i := 1; i < len(runes); i++ {
+		if d.Correct(string(runes[:i])) && d.Correct(string(runes[i:])) && add(string(runes[:i])+" "+string(runes[i:])) {
+			return suggestions
+		}
+	}
+	return suggestions
+}
+
+// suggestable tells if a candidate is correct, split candidates are checked by Suggest
+func (d *Dictionary) suggestable(candidate string) bool {
+	for _, part := range strings.Fields(candidate) {
+		if !d.Correct(part) {
+			return false
+		}
+	}
+	return candidate != ""
+}
+
+// edits returns the words one edit away, swapped neighbours first as they are the most
+// common typo
+func edits(word, try string) []string {
+	runes := []rune(word)
+	chars := []rune(try)
+	if len(chars) == 0 {
+		chars = []rune("esianrtolcdugmphbyfvkwzESIANRTOLCDUGMPHBYFVKWZ'")
+	}
+	list := []string{}
+	for i := 0; i+1 < len(runes); i++ {
+		swapped := append([]rune{}, runes...)
+		swapped[i], swapped[i+1] = swapped[i+1], swapped[i]
+		list = append(list, string(swapped))
+	}
+	for i := range runes {
+		for _, c := range chars {
+			if c != runes[i] {
+				list = append(list, string(runes[:i])+string(c)+string(runes[i+1:]))
+			}
+		}
+	}
+	for i := range runes {
+		list = append(list, string(runes[:i])+string(runes[i+1:]))
+	}
+	for i := 0; i <= len(runes); i++ {
+		for _, c := range chars {
+			list = append(list, string(runes[:i])+string(c)+string(runes[i:]))
+		}
+	}
+	return list
+}
+
+// matchCase spells a suggestion like the misspelled word: capitalized or upper case
+func matchCase(word, suggestion string) string {
+	first, _ := utf8.DecodeRuneInString(word)
+	switch {
+	case !unicode.IsUpper(first):
+		return suggestion
+	case utf8.RuneCountInString(word) > 1 && word == strings.ToUpper(word):
+		return strings.ToUpper(suggestion)
+	default:
+		s, n := utf8.DecodeRuneInString(suggestion)
+		return string(unicode.ToUpper(s)) + suggestion[n:]
+	}
+}
@@ -0,0 +1,257 @@
+// Package spellcheck finds misspelled words in Markdown with hunspell dictionaries
+package spellcheck
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+	"unicode"
+	"unicode/utf16"
+	"unicode/utf8"
+)
+
+// ErrUnknownLanguage is returned when there is no dictionary for a language
+var ErrUnknownLanguage = errors.New("no dictionary for language")
+
+// Misspelling is a word which is neither in the dictionary nor in the custom dictionary.
+// Offset and Length count UTF-16 code units, like the positions of the editor.
+type Misspelling struct {
+	Word        string   `json:"word"`
+	Offset      int      `json:"offset"`
+	Length      int      `json:"length"`
+	Suggestions []string `json:"suggestions"`
+}
+
+// Checker checks texts with the dictionaries of a directory, a language is the name of an
+// .aff and .dic pair like en_US. Dictionaries are loaded on first use.
+type Checker struct {
+	dir   string
+	store *Store
+
+	mu    sync.Mutex
+	dicts map[string]*Dictionary
+}
+
+// New opens the custom dictionary in storageDir, the hunspell dictionaries are read from dir
+func New(storageDir, dir string) (*Checker, error) {
+	store, err := NewStore(storageDir)
+	if err != nil {
+		return nil, err
+	}
+	return &Checker{dir: dir, store: store, dicts: map[string]*Dictionary{}}, nil
+}
+
+// Store returns the custom dictionary
+func (c *Checker) Store() *Store {
+	return c.store
+}
+
+// Close closes the custom dictionary
+func (c *Checker) Close() error {
+	return c.store.Close()
+}
+
+// Languages returns the languages with both an .aff and a .dic file, sorted
+func (c *Checker) Languages() ([]string, error) {
+	entries, err := os.ReadDir(c.dir)
+	if err != nil {
+		return nil, err
+	}
+	languages := []string{}
+	for _, entry := range entries {
+		name := entry.Name()
+		if entry.IsDir() || filepath.Ext(name) != ".aff" {
+			continue
+		}
+		language := strings.TrimSuffix(name, ".aff")
+		if _, err := os.Stat(filepath.Join(c.dir, language+".dic")); err == nil {
+			languages = append(languages, language)
+		}
+	}
+	sort.Strings(languages)
+	return languages, nil
+}
+
+// dictionary returns the dictionary of a language, reading it on first use
+func (c *Checker) dictionary(language string) (*Dictionary, error) {
+	languages, err := c.Languages()
+	if err != nil {
+		return nil, err
+	}
+	// only listed languages are opened, so the name can't leave the directory
+	if i := sort.SearchStrings(languages, language); i == len(languages) || languages[i] != language {
+		return nil, ErrUnknownLanguage
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if d, ok := c.dicts[language]; ok {
+		return d, nil
+	}
+	aff, err := os.Open(filepath.Join(c.dir, language+".aff"))
+	if err != nil {
+		return nil, err
+	}
+	defer aff.Close()
+	dic, err := os.Open(filepath.Join(c.dir, language+".dic"))
+	if err != nil {
+		return nil, err
+	}
+	defer dic.Close()
+	d, err := ReadDictionary(aff, dic)
+	if err != nil {
+		return nil, fmt.Errorf("could not read dictionary %s: %w", language, err)
+	}
+	c.dicts[language] = d
+	return d, nil
+}
+
+// Check returns the misspelled words of a Markdown text in order. Code, links, HTML tags,
+// macros and the frontmatter are skipped, as are the words of the custom dictionary.
+func (c *Checker) Check(language, markdown string) ([]Misspelling, error) {
+	d, err := c.dictionary(language)
+	if err != nil {
+		return nil, err
+	}
+	custom, err := c.store.Set()
+	if err != nil {
+		return nil, err
+	}
+
+	misspellings := []Misspelling{}
+	suggestions := map[string][]string{}
+	for _, w := range Words(markdown) {
+		if custom[strings.ToLower(w.Text)] || d.Correct(w.Text) {
+			continue
+		}
+		// possessives aren't always in the dictionaries
+		if stem, ok := strings.CutSuffix(w.Text, "'s"); ok && (custom[strings.ToLower(stem)] || d.Correct(stem)) {
+			continue
+		}
+		if _, ok := suggestions[w.Text]; !ok {
+			suggestions[w.Text] = d.Suggest(w.Text)
+		}
+		misspellings = append(misspellings, Misspelling{
+			Word:        w.Text,
+			Offset:      w.Offset,
+			Length:      w.Length,
+			Suggestions: suggestions[w.Text],
+		})
+	}
+	return misspellings, nil
+}
+
+// Word is a word of a text, Offset and Length count UTF-16 code units
+type Word struct {
+	Text   string
+	Offset int
+	Length int
+}
+
+var (
+	frontmatterBlock = regexp.MustCompile(`\A---\r?\n(?s:.*?)\r?\n---[ \t]*(?:\r?\n|\z)`)
+	skippedSpans     = regexp.MustCompile(strings.Join([]string{
+		"`+[^`]*`+",                     // code spans
+		`\{\{[^}]*\}\}`,                 // macros
+		`!?\[\[[^\]]*\]\]`,              // wikilinks
+		`\]\([^)]*\)`,                   // link destinations
+		`(?m)^\s*\[[^\]]+\]:\s*\S+.*$`,  // link definitions
+		`<[^>\s][^>]*>`,                 // HTML tags and autolinks
+		`[a-zA-Z][a-zA-Z0-9+.-]*://\S+`, // URLs
+		`[\w.+-]+@[\w-]+\.[\w.-]+`,      // email addresses
+	}, "|"))
+)
+
+// Words returns the words of a Markdown text which are checked. Words with digits or
+// underscores are left out as they are mostly identifiers, so are single letters.
+func Words(markdown string) []Word {
+	// skipped bytes are blanked in text, so the spans are found outside code only
+	text := []byte(markdown)
+	skip := make([]bool, len(text))
+	blank := func(start, end int) {
+		for i := start; i < end; i++ {
+			skip[i] = true
+			if text[i] != '\n' {
+				text[i] = ' '
+			}
+		}
+	}
+
+	if loc := frontmatterBlock.FindStringIndex(markdown); loc != nil {
+		blank(loc[0], loc[1])
+	}
+	// code blocks, fenced or indented
+	offset := 0
+	fence := ""
+	for _, line := range strings.SplitAfter(markdown, "\n") {
+		trimmed := strings.TrimLeft(line, " ")
+		switch {
+		case fence != "":
+			if strings.HasPrefix(trimmed, fence) {
+				fence = ""
+			}
+			blank(offset, offset+len(line))
+		case strings.HasPrefix(trimmed, "```") || strings.HasPrefix(trimmed, "~~~"):
+			fence = trimmed[:3]
+			blank(offset, offset+len(line))
+		case strings.HasPrefix(line, "    ") || strings.HasPrefix(line, "\t"):
+			blank(offset, offset+len(line))
+		}
+		offset += len(line)
+	}
+	for _, loc := range skippedSpans.FindAllIndex(text, -1) {
+		blank(loc[0], loc[1])
+	}
+
+	words := []Word{}
+	var word strings.Builder
+	units, wordStart := 0, 0
+	flush := func() {
+		// apostrophes around a word are quotes
+		text := strings.TrimLeft(word.String(), "'")
+		offset := wordStart + word.Len() - len(text)
+		text = strings.TrimRight(text, "'")
+		word.Reset()
+		if utf8.RuneCountInString(text) < 2 || strings.ContainsFunc(text, func(r rune) bool {
+			return unicode.IsDigit(r) || r == '_'
+		}) {
+			return
+		}
+		words = append(words, Word{Text: text, Offset: offset, Length: utf16Len(text)})
+	}
+	for i, original := range markdown {
+		r := original
+		// the typographic apostrophe is looked up as '
+		if r == '’' {
+			r = '\''
+		}
+		if skip[i] {
+			r = ' '
+		}
+		if unicode.IsLetter(r) || unicode.IsMark(r) || unicode.IsDigit(r) || r == '_' || r == '\'' {
+			if word.Len() == 0 {
+				wordStart = units
+			}
+			word.WriteRune(r)
+		} else if word.Len() > 0 {
+			flush()
+		}
+		units += utf16.RuneLen(original)
+	}
+	flush()
+	return words
+}
+
+// utf16Len returns the length of s in UTF-16 code units
+func utf16Len(s string) int {
+	n := 0
+	for _, r := range s {
+		n += utf16.RuneLen(r)
+	}
+	return n
+}
@@ -0,0 +1,174 @@
+package spellcheck
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+const testAff = `SET UTF-8
+TRY esianrtolcdugmphbyfvkwz
+FORBIDDENWORD !
+
+REP 1
+REP f ph
+
+PFX U Y 1
+PFX U 0 un .
+
+SFX S Y 2
+SFX S y ies [^aeiou]y
+SFX S 0 s [^y]
+
+SFX D N 1
+SFX D 0 d e
+`
+
+const testDic = `8
+page/SUD
+story/S
+wiki/S
+write
+phone/S
+the
+is
+wikis/!
+`
+
+func readTestDictionary(t *testing.T) *Dictionary {
+	t.Helper()
+	d, err := ReadDictionary(strings.NewReader(testAff), strings.NewReader(testDic))
+	if err != nil {
+		t.Fatalf("ReadDictionary failed: %v", err)
+	}
+	return d
+}
+
+func TestDictionary_Correct(t *testing.T) {
+	d := readTestDictionary(t)
+
+	for _, word := range []string{"page", "pages", "unpages", "paged", "stories", "Page", "PAGES", "write"} {
+		if !d.Correct(word) {
+			t.Errorf("expected %q to be correct", word)
+		}
+	}
+	// unpaged needs a cross product, but D doesn't allow one; wikis is forbidden
+	for _, word := range []string{"storys", "unpaged", "wikis", "wrte", "pAGE"} {
+		if d.Correct(word) {
+			t.Errorf("expected %q to be misspelled", word)
+		}
+	}
+}
+
+func TestDictionary_Suggest(t *testing.T) {
+	d := readTestDictionary(t)
+
+	if got := d.Suggest("fone"); len(got) == 0 || got[0] != "phone" {
+		t.Errorf("expected the REP replacement first, got %v", got)
+	}
+	if got := d.Suggest("Pgae"); len(got) == 0 || got[0] != "Page" {
+		t.Errorf("expected the swapped letters in the case of the word, got %v", got)
+	}
+	if got := d.Suggest("thewiki"); !reflect.DeepEqual(got, []string{"the wiki"}) {
+		t.Errorf("expected the split words, got %v", got)
+	}
+}
+
+func TestWords(t *testing.T) {
+	markdown := "---\ntitle: Tpyo\n---\n# Héllo 👋 wrld\n\n`cde` [lnk](http://exmple.com) {{macro foo}}\n\n```\ncodee\n```\n\n'Quoted' don’t x v2 snake_case\n"
+
+	got := []string{}
+	for _, w := range Words(markdown) {
+		got = append(got, w.Text)
+	}
+	want := []string{"Héllo", "wrld", "lnk", "Quoted", "don't"}
+	if !reflect.DeepEqual(got, want) {
+		t.Fatalf("expected %v, got %v", want, got)
+	}
+
+	// offsets count UTF-16 code units, the emoji counts twice
+	words := Words("👋 wrld")
+	if len(words) != 1 || words[0].Offset != 3 || words[0].Length != 4 {
+		t.Errorf("unexpected position: %+v", words)
+	}
+}
+
+func TestChecker_Check(t *testing.T) {
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, "en_US.aff"), []byte(testAff), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, "en_US.dic"), []byte(testDic), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	// a dictionary without .dic is no language
+	if err := os.WriteFile(filepath.Join(dir, "de_DE.aff"), []byte(testAff), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	c, err := New(t.TempDir(), dir)
+	if err != nil {
+		t.Fatalf("New failed: %v", err)
+	}
+	defer c.Close()
+
+	if languages, err := c.Languages(); err != nil || !reflect.DeepEqual(languages, []string{"en_US"}) {
+		t.Fatalf("unexpected languages %v: %v", languages, err)
+	}
+	if _, err := c.Check("../en_US", "page"); !errors.Is(err, ErrUnknownLanguage) {
+		t.Errorf("expected ErrUnknownLanguage, got %v", err)
+	}
+
+	got, err := c.Check("en_US", "The wiki is pgae Leafwiki")
+	if err != nil {
+		t.Fatalf("Check failed: %v", err)
+	}
+	if len(got) != 2 || got[0].Word != "pgae" || got[0].Offset != 12 || got[0].Length != 4 || got[0].Suggestions[0] != "page" {
+		t.Fatalf("unexpected misspellings: %+v", got)
+	}
+
+	if _, err := c.Store().Add("LeafWiki", "u1"); err != nil {
+		t.Fatalf("Add failed: %v", err)
+	}
+	got, err = c.Check("en_US", "The wiki is pgae Leafwiki")
+	if err != nil {
+		t.Fatalf("Check failed: %v", err)
+	}
+	if len(got) != 1 || got[0].Word != "pgae" {
+		t.Errorf("expected the custom word to be accepted, got %+v", got)
+	}
+}
+
+func TestStore_AddListRemove(t *testing.T) {
+	store, err := NewStore(t.TempDir())
+	if err != nil {
+		t.Fatalf("NewStore failed: %v", err)
+	}
+	defer store.Close()
+
+	if _, err := store.Add("Kubernetes", "u1"); err != nil {
+		t.Fatalf("Add failed: %v", err)
+	}
+	// the first entry is kept
+	if w, err := store.Add("kubernetes", "u2"); err != nil || w.AddedBy != "u1" || w.Word != "kubernetes" {
+		t.Fatalf("unexpected word %+v: %v", w, err)
+	}
+	if _, err := store.Add("gin", "u2"); err != nil {
+		t.Fatalf("Add failed: %v", err)
+	}
+
+	list, err := store.List()
+	if err != nil || len(list) != 2 || list[0].Word != "gin" {
+		t.Fatalf("unexpected list %+v: %v", list, err)
+	}
+
+	if err := store.Remove("KUBERNETES"); err != nil {
+		t.Fatalf("Remove failed: %v", err)
+	}
+	if err := store.Remove("kubernetes"); !errors.Is(err, ErrWordNotFound) {
+		t.Errorf("expected ErrWordNotFound, got %v", err)
+	}
+}
@@ -0,0 +1,181 @@
+package spellcheck
+
+import (
+	"database/sql"
+	"errors"
+	"path"
+	"strings"
+	"time"
+
+	_ "modernc.org/sqlite"
+)
+
+// ErrWordNotFound is returned when a word is not in the custom dictionary
+var ErrWordNotFound = errors.New("word not found")
+
+// CustomWord is a word added to the custom dictionary of the wiki
+type CustomWord struct {
+	Word      string    `json:"word"`
+	AddedBy   string    `json:"addedBy"`
+	CreatedAt time.Time `json:"createdAt"`
+}
+
+// Store holds the custom dictionary, its words are accepted in every language
+type Store struct {
+	storageDir string
+	filename   string
+	db         *sql.DB
+}
+
+func NewStore(storageDir string) (*Store, error) {
+	s := &Store{
+		storageDir: storageDir,
+		filename:   "spellcheck.db",
+	}
+
+	err := s.Connect()
+	if err != nil {
+		return nil, err
+	}
+
+	return s, s.ensureSchema()
+}
+
+func (s *Store) Connect() error {
+	// Database is already open and connected
+	if s.db != nil {
+		return nil
+	}
+	db, err := sql.Open("sqlite", path.Join(s.storageDir, s.filename))
+	if err != nil {
+		return err
+	}
+	s.db = db
+	return nil
+}
+
+func (s *Store) ensureSchema() error {
+	err := s.Connect()
+	if err != nil {
+		return err
+	}
+	_, err = s.db.Exec(`
+		CREATE TABLE IF NOT EXISTS custom_words (
+			word TEXT PRIMARY KEY,
+			added_by TEXT NOT NULL,
+			created_at INTEGER NOT NULL
+		);
+	`)
+	return err
+}
+
+func (s *Store) Close() error {
+	if s.db != nil {
+		err := s.db.Close()
+		if err != nil {
+			return err
+		}
+		s.db = nil
+	}
+	return nil
+}
+
+// wordKey is how a word is stored, custom words are not case-sensitive
+func wordKey(word string) string {
+	return strings.ToLower(strings.TrimSpace(word))
+}
+
+// Add adds a word to the custom dictionary. Adding a word twice keeps the first entry.
+func (s *Store) Add(word, addedBy string) (*CustomWord, error) {
+	err := s.Connect()
+	if err != nil {
+		return nil, err
+	}
+
+	_, err = s.db.Exec(`
+		INSERT INTO custom_words (word, added_by, created_at)
+		VALUES (?, ?, ?)
+		ON CONFLICT(word) DO NOTHING;
+	`, wordKey(word), addedBy, time.Now().UTC().UnixNano())
+	if err != nil {
+		return nil, err
+	}
+
+	row := s.db.QueryRow(`
+		SELECT word, added_by, created_at
+		FROM custom_words
+		WHERE word = ?;
+	`, wordKey(word))
+	return scanWord(row)
+}
+
+// Remove removes a word from the custom dictionary
+func (s *Store) Remove(word string) error {
+	err := s.Connect()
+	if err != nil {
+		return err
+	}
+	res, err := s.db.Exec(`DELETE FROM custom_words WHERE word = ?;`, wordKey(word))
+	if err != nil {
+		return err
+	}
+	if n, err := res.RowsAffected(); err == nil && n == 0 {
+		return ErrWordNotFound
+	}
+	return err
+}
+
+// List returns the words of the custom dictionary in alphabetical order
+func (s *Store) List() ([]*CustomWord, error) {
+	err := s.Connect()
+	if err != nil {
+		return nil, err
+	}
+
+	rows, err := s.db.Query(`
+		SELECT word, added_by, created_at
+		FROM custom_words
+		ORDER BY word;
+	`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	list := []*CustomWord{}
+	for rows.Next() {
+		w, err := scanWord(rows)
+		if err != nil {
+			return nil, err
+		}
+		list = append(list, w)
+	}
+	return list, rows.Err()
+}
+
+// Set returns the words of the custom dictionary in lower case for the lookup
+func (s *Store) Set() (map[string]bool, error) {
+	list, err := s.List()
+	if err != nil {
+		return nil, err
+	}
+	set := make(map[string]bool, len(list))
+	for _, w := range list {
+		set[w.Word] = true
+	}
+	return set, nil
+}
+
+type rowScanner interface {
+	Scan(dest ...any) error
+}
+
+func scanWord(row rowScanner) (*CustomWord, error) {
+	w := &CustomWord{}
+	var createdAt int64
+	if err := row.Scan(&w.Word, &w.AddedBy, &createdAt); err != nil {
+		return nil, err
+	}
+	w.CreatedAt = time.Unix(0, createdAt).UTC()
+	return w, nil
+}
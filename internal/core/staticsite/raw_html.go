@@ -0,0 +1,160 @@
+package staticsite
+
+import (
+	"bytes"
+	"crypto/rand"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/microcosm-cc/bluemonday"
+	"github.com/russross/blackfriday/v2"
+	"golang.org/x/net/html"
+)
+
+// RawHTML controls how HTML written in the Markdown of the pages is rendered
+type RawHTML string
+
+const (
+	// RawHTMLStrip removes raw HTML, only the Markdown is rendered
+	RawHTMLStrip RawHTML = "strip"
+	// RawHTMLSanitize keeps the tags and attributes of an allowlist, e.g. <details> or <sup>,
+	// but no scripts, styles or event handlers
+	RawHTMLSanitize RawHTML = "sanitize"
+	// RawHTMLTrusted keeps raw HTML as written. The Markdown around it is still sanitized.
+	// Only trusted users may add raw HTML which the allowlist would change, see DisallowedHTML.
+	RawHTMLTrusted RawHTML = "trusted"
+)
+
+// Valid reports whether the mode is known
+func (m RawHTML) Valid() bool {
+	return m == RawHTMLStrip || m == RawHTMLSanitize || m == RawHTMLTrusted
+}
+
+// renderHTML converts the Markdown to sanitized HTML, raw HTML is handled as configured
+func renderHTML(markdown string, mode RawHTML, policy *bluemonday.Policy) (string, *rawFragments) {
+	switch mode {
+	case RawHTMLStrip:
+		renderer := blackfriday.NewHTMLRenderer(blackfriday.HTMLRendererParameters{Flags: blackfriday.CommonHTMLFlags | blackfriday.SkipHTML})
+		return string(policy.SanitizeBytes(blackfriday.Run([]byte(markdown), blackfriday.WithRenderer(renderer)))), &rawFragments{}
+	case RawHTMLTrusted:
+		renderer := &protectingRenderer{
+			HTMLRenderer: blackfriday.NewHTMLRenderer(blackfriday.HTMLRendererParameters{Flags: blackfriday.CommonHTMLFlags}),
+			raw:          newRawFragments(),
+		}
+		return string(policy.SanitizeBytes(blackfriday.Run([]byte(markdown), blackfriday.WithRenderer(renderer)))), renderer.raw
+	}
+	return string(policy.SanitizeBytes(blackfriday.Run([]byte(markdown)))), &rawFragments{}
+}
+
+// rawFragments holds the raw HTML replaced by placeholders until the HTML has been sanitized.
+// The placeholders contain a random nonce of the render, so the Markdown can't contain them.
+type rawFragments struct {
+	nonce     string
+	fragments [][]byte
+}
+
+func newRawFragments() *rawFragments {
+	return &rawFragments{nonce: rand.Text()}
+}
+
+// Restore replaces the placeholders with the raw HTML. They are looked up in the order they
+// were emitted, and each one only once.
+func (r *rawFragments) Restore(rendered string) string {
+	if len(r.fragments) == 0 {
+		return rendered
+	}
+	var b strings.Builder
+	pos := 0
+	for i, fragment := range r.fragments {
+		placeholder := r.placeholder(i)
+		idx := strings.Index(rendered[pos:], placeholder)
+		if idx < 0 {
+			continue
+		}
+		b.WriteString(rendered[pos : pos+idx])
+		b.Write(fragment)
+		pos += idx + len(placeholder)
+	}
+	b.WriteString(rendered[pos:])
+	return b.String()
+}
+
+func (r *rawFragments) placeholder(i int) string {
+	return fmt.Sprintf("LEAFWIKIRAWHTML%s%dEND", r.nonce, i)
+}
+
+// protectingRenderer renders raw HTML as placeholders, so the sanitizer leaves it alone
+type protectingRenderer struct {
+	*blackfriday.HTMLRenderer
+	raw *rawFragments
+}
+
+func (r *protectingRenderer) RenderNode(w io.Writer, node *blackfriday.Node, entering bool) blackfriday.WalkStatus {
+	switch node.Type {
+	case blackfriday.HTMLBlock:
+		fmt.Fprintf(w, "\n%s\n", r.raw.placeholder(len(r.raw.fragments)))
+	case blackfriday.HTMLSpan:
+		io.WriteString(w, r.raw.placeholder(len(r.raw.fragments)))
+	default:
+		return r.HTMLRenderer.RenderNode(w, node, entering)
+	}
+	r.raw.fragments = append(r.raw.fragments, node.Literal)
+	return blackfriday.GoToNext
+}
+
+// DisallowedHTML returns the tags of the raw HTML in the Markdown which the sanitizer would
+// remove or change, e.g. <script> or a tag with an onclick attribute. Each tag is returned once.
+func DisallowedHTML(markdown string) []string {
+	policy := newPolicy()
+	seen := map[string]bool{}
+	disallowed := []string{}
+
+	root := blackfriday.New(blackfriday.WithExtensions(blackfriday.CommonExtensions)).Parse([]byte(markdown))
+	root.Walk(func(node *blackfriday.Node, entering bool) blackfriday.WalkStatus {
+		if !entering || (node.Type != blackfriday.HTMLBlock && node.Type != blackfriday.HTMLSpan) {
+			return blackfriday.GoToNext
+		}
+		tokenizer := html.NewTokenizer(bytes.NewReader(node.Literal))
+		for {
+			tokenType := tokenizer.Next()
+			if tokenType == html.ErrorToken {
+				break
+			}
+			if tokenType != html.StartTagToken && tokenType != html.SelfClosingTagToken {
+				continue
+			}
+			tag := tokenizer.Token()
+			if source := tag.String(); !seen[source] && !keepsTag(policy, tag) {
+				seen[source] = true
+				disallowed = append(disallowed, source)
+			}
+		}
+		return blackfriday.GoToNext
+	})
+	return disallowed
+}
+
+// keepsTag reports whether the policy keeps the tag with all its attributes. Attributes added
+// by the policy, like rel="nofollow", don't matter.
+func keepsTag(policy *bluemonday.Policy, tag html.Token) bool {
+	tokenizer := html.NewTokenizer(strings.NewReader(policy.Sanitize(tag.String())))
+	tokenType := tokenizer.Next()
+	if tokenType != html.StartTagToken && tokenType != html.SelfClosingTagToken {
+		return false
+	}
+	sanitized := tokenizer.Token()
+	if sanitized.Data != tag.Data {
+		return false
+	}
+	kept := map[string]string{}
+	for _, attr := range sanitized.Attr {
+		kept[attr.Key] = attr.Val
+	}
+	for _, attr := range tag.Attr {
+		if val, ok := kept[attr.Key]; !ok || val != attr.Val {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,292 @@
+package staticsite
+
+import (
+	"bytes"
+	"embed"
+	"encoding/json"
+	"fmt"
+	"html/template"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"regexp"
+	"strings"
+
+	"github.com/Gomez12/wiki/internal/core/frontmatter"
+	"github.com/Gomez12/wiki/internal/core/highlight"
+	"github.com/Gomez12/wiki/internal/core/texmath"
+	"github.com/Gomez12/wiki/internal/core/tree"
+	"github.com/microcosm-cc/bluemonday"
+)
+
+//go:embed templates
+var templatesFS embed.FS
+
+var pageTemplate = template.Must(template.ParseFS(templatesFS, "templates/page.html"))
+
+// absoluteLinkRegex matches root-relative links of the wiki, e.g. /docs/guide or /assets/<id>/image.png
+var absoluteLinkRegex = regexp.MustCompile(`(href|src)="/([^/"][^"]*)?"`)
+
+var whitespaceRegex = regexp.MustCompile(`\s+`)
+
+// Source is the wiki rendered by Build
+type Source struct {
+	SiteTitle string
+	Tree      *tree.PageNode
+	// Content returns the Markdown of a page
+	Content func(node *tree.PageNode) (string, error)
+	// AssetsDir contains the assets of the pages, one folder per page id
+	AssetsDir string
+	// PageAssetsDir returns the folder of the assets stored next to a page, optional.
+	// They are written to assets/<page id> like the others.
+	PageAssetsDir func(node *tree.PageNode) string
+	RenderOptions
+}
+
+// RenderOptions control how the Markdown of the pages is rendered
+type RenderOptions struct {
+	// Math controls how $...$ and $$...$$ math is rendered
+	Math texmath.Mode
+	// CodeTheme is the highlighting theme of code blocks; "" and highlight.Off disable highlighting
+	CodeTheme string
+	// RawHTML controls how HTML in the Markdown is rendered; "" sanitizes it
+	RawHTML RawHTML
+}
+
+// SearchEntry is an entry of search-index.json
+type SearchEntry struct {
+	Title string `json:"title"`
+	Path  string `json:"path"`
+	Text  string `json:"text"`
+}
+
+type navItem struct {
+	Title    string
+	Href     string
+	Active   bool
+	Children []*navItem
+}
+
+type pageData struct {
+	SiteTitle string
+	Title     string
+	Root      string
+	Nav       []*navItem
+	Content   template.HTML
+}
+
+// Build renders every page to <path>/index.html and adds an index.html listing the top-level
+// pages, the assets and a search-index.json used by the client-side search.
+// All links are relative, so the site can be hosted below any path. Returns the number of pages.
+func Build(src Source, out Writer) (int, error) {
+	b := &builder{
+		src:    src,
+		out:    out,
+		policy: newPolicy(),
+		strict: bluemonday.StrictPolicy(),
+		search: []SearchEntry{},
+	}
+
+	count, err := b.renderPages(src.Tree.Children, "")
+	if err != nil {
+		return count, err
+	}
+
+	var list strings.Builder
+	list.WriteString("<h1>" + template.HTMLEscapeString(src.SiteTitle) + "</h1>\n<ul>\n")
+	for _, node := range src.Tree.Children {
+		fmt.Fprintf(&list, "<li><a href=\"%s\">%s</a></li>\n", pageHref("", node.Slug), template.HTMLEscapeString(node.Title))
+	}
+	list.WriteString("</ul>")
+	if err := b.writePage("index.html", "", "", "", template.HTML(list.String())); err != nil {
+		return count, err
+	}
+
+	index, err := json.Marshal(b.search)
+	if err != nil {
+		return count, err
+	}
+	if err := out.WriteFile("search-index.json", bytes.NewReader(index)); err != nil {
+		return count, err
+	}
+	for _, name := range []string{"style.css", "search.js"} {
+		data, err := templatesFS.ReadFile("templates/" + name)
+		if err != nil {
+			return count, err
+		}
+		if name == "style.css" {
+			data = append(data, highlight.CSS(src.CodeTheme)...)
+		}
+		if err := out.WriteFile(name, bytes.NewReader(data)); err != nil {
+			return count, err
+		}
+	}
+	return count, b.copyAssets()
+}
+
+// languageClassRegex matches the language of fenced code blocks, e.g. language-go
+var languageClassRegex = regexp.MustCompile(`^language-[a-zA-Z0-9]+$`)
+
+// newPolicy returns the sanitizer policy for user content which keeps the language of code blocks
+func newPolicy() *bluemonday.Policy {
+	policy := bluemonday.UGCPolicy()
+	policy.AllowAttrs("class").Matching(languageClassRegex).OnElements("code")
+	return policy
+}
+
+// renderMarkdown converts the Markdown body to sanitized HTML. Math and code highlighting are
+// added after sanitizing, both escape their source. Trusted raw HTML is restored after sanitizing.
+func renderMarkdown(body string, opts RenderOptions, policy *bluemonday.Policy) string {
+	protected, m := texmath.Protect(body, opts.Math)
+	rendered, raw := renderHTML(protected, opts.RawHTML, policy)
+	if opts.CodeTheme != "" && opts.CodeTheme != highlight.Off {
+		rendered = highlight.Blocks(rendered)
+	}
+	return m.Restore(raw.Restore(rendered))
+}
+
+type builder struct {
+	src    Source
+	out    Writer
+	policy *bluemonday.Policy
+	// strict strips all tags for the search index
+	strict *bluemonday.Policy
+	search []SearchEntry
+}
+
+func (b *builder) renderPages(nodes []*tree.PageNode, parentPath string) (int, error) {
+	count := 0
+	for _, node := range nodes {
+		pagePath := path.Join(parentPath, node.Slug)
+		content, err := b.src.Content(node)
+		if err != nil {
+			return count, fmt.Errorf("could not read page %s: %w", node.ID, err)
+		}
+
+		_, body, _ := frontmatter.Split(content)
+		rendered := renderMarkdown(body, b.src.RenderOptions, b.policy)
+		root := strings.Repeat("../", strings.Count(pagePath, "/")+1)
+		html := rewriteLinks(rendered, root)
+
+		if err := b.writePage(pagePath+"/index.html", root, node.ID, node.Title, template.HTML(html)); err != nil {
+			return count, err
+		}
+		if b.src.PageAssetsDir != nil {
+			if err := b.copyDir(b.src.PageAssetsDir(node), "assets/"+node.ID); err != nil {
+				return count, err
+			}
+		}
+		b.search = append(b.search, SearchEntry{
+			Title: node.Title,
+			Path:  pagePath,
+			Text:  strings.TrimSpace(whitespaceRegex.ReplaceAllString(b.strict.Sanitize(rendered), " ")),
+		})
+		count++
+
+		n, err := b.renderPages(node.Children, pagePath)
+		count += n
+		if err != nil {
+			return count, err
+		}
+	}
+	return count, nil
+}
+
+func (b *builder) writePage(name, root, activeID, title string, content template.HTML) error {
+	data := pageData{
+		SiteTitle: b.src.SiteTitle,
+		Title:     title,
+		Root:      root,
+		Nav:       buildNav(b.src.Tree.Children, root, "", activeID),
+		Content:   content,
+	}
+	var buf bytes.Buffer
+	if err := pageTemplate.Execute(&buf, data); err != nil {
+		return err
+	}
+	return b.out.WriteFile(name, &buf)
+}
+
+func (b *builder) copyAssets() error {
+	if b.src.AssetsDir == "" {
+		return nil
+	}
+	return b.copyDir(b.src.AssetsDir, "assets")
+}
+
+// copyDir writes the files below dir to the folder target of the site, a missing dir is skipped
+func (b *builder) copyDir(dir, target string) error {
+	return filepath.WalkDir(dir, func(p string, d fs.DirEntry, err error) error {
+		if err != nil {
+			if os.IsNotExist(err) && p == dir {
+				return nil
+			}
+			return err
+		}
+		if !d.Type().IsRegular() {
+			return nil
+		}
+		rel, err := filepath.Rel(dir, p)
+		if err != nil {
+			return err
+		}
+		f, err := os.Open(p)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+		return b.out.WriteFile(target+"/"+filepath.ToSlash(rel), f)
+	})
+}
+
+func buildNav(nodes []*tree.PageNode, root, parentPath, activeID string) []*navItem {
+	items := make([]*navItem, 0, len(nodes))
+	for _, node := range nodes {
+		pagePath := path.Join(parentPath, node.Slug)
+		items = append(items, &navItem{
+			Title:    node.Title,
+			Href:     pageHref(root, pagePath),
+			Active:   node.ID == activeID,
+			Children: buildNav(node.Children, root, pagePath, activeID),
+		})
+	}
+	return items
+}
+
+func pageHref(root, pagePath string) string {
+	return root + pagePath + "/index.html"
+}
+
+// PrefixLinks prefixes the root-relative wiki links with the base path the wiki is served under
+func PrefixLinks(html, basePath string) string {
+	if basePath == "" {
+		return html
+	}
+	return absoluteLinkRegex.ReplaceAllString(html, `$1="`+basePath+`/$2"`)
+}
+
+// rewriteLinks turns root-relative wiki links into links relative to the page.
+// Asset links keep their file, page links point to the index.html of the page.
+func rewriteLinks(html, root string) string {
+	return absoluteLinkRegex.ReplaceAllStringFunc(html, func(match string) string {
+		parts := absoluteLinkRegex.FindStringSubmatch(match)
+		attr, target := parts[1], parts[2]
+		if strings.HasPrefix(target, "assets/") {
+			return attr + `="` + root + target + `"`
+		}
+
+		fragment := ""
+		if i := strings.IndexByte(target, '#'); i >= 0 {
+			target, fragment = target[:i], target[i:]
+		}
+		if i := strings.IndexByte(target, '?'); i >= 0 {
+			target = target[:i]
+		}
+		target = strings.TrimSuffix(target, "/")
+		if target == "" {
+			return attr + `="` + root + "index.html" + fragment + `"`
+		}
+		return attr + `="` + pageHref(root, target) + fragment + `"`
+	})
+}
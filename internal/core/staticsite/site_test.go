@@ -0,0 +1,220 @@
+package staticsite
+
+import (
+	"bytes"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/Gomez12/wiki/internal/core/texmath"
+	"github.com/Gomez12/wiki/internal/core/tree"
+)
+
+func TestBuild_RendersPagesWithRelativeLinks(t *testing.T) {
+	guide := &tree.PageNode{ID: "guide", Title: "Guide", Slug: "guide"}
+	docs := &tree.PageNode{ID: "docs", Title: "Docs", Slug: "docs", Children: []*tree.PageNode{guide}}
+	root := &tree.PageNode{ID: "root", Children: []*tree.PageNode{docs}}
+	contents := map[string]string{
+		"docs":  "---\nstatus: done\n---\n# Docs\nSee [the guide](/docs/guide#setup).",
+		"guide": "# Guide\n![logo](/assets/guide/logo.png)\n<script>alert(1)</script>",
+	}
+
+	assetsDir := t.TempDir()
+	if err := os.MkdirAll(filepath.Join(assetsDir, "guide"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(assetsDir, "guide", "logo.png"), []byte("png"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	outDir := filepath.Join(t.TempDir(), "site")
+	out, err := NewDirWriter(outDir)
+	if err != nil {
+		t.Fatalf("NewDirWriter failed: %v", err)
+	}
+	count, err := Build(Source{
+		SiteTitle: "Team Wiki",
+		Tree:      root,
+		Content:   func(node *tree.PageNode) (string, error) { return contents[node.ID], nil },
+		AssetsDir: assetsDir,
+	}, out)
+	if err != nil {
+		t.Fatalf("Build failed: %v", err)
+	}
+	if count != 2 {
+		t.Errorf("expected 2 pages, got %d", count)
+	}
+
+	read := func(name string) string {
+		data, err := os.ReadFile(filepath.Join(outDir, filepath.FromSlash(name)))
+		if err != nil {
+			t.Fatalf("expected %s: %v", name, err)
+		}
+		return string(data)
+	}
+
+	docsHTML := read("docs/index.html")
+	if !strings.Contains(docsHTML, `href="../docs/guide/index.html#setup"`) {
+		t.Errorf("expected relative page link, got:\n%s", docsHTML)
+	}
+	if strings.Contains(docsHTML, "status: done") {
+		t.Errorf("expected frontmatter to be stripped")
+	}
+
+	guideHTML := read("docs/guide/index.html")
+	if !strings.Contains(guideHTML, `src="../../assets/guide/logo.png"`) {
+		t.Errorf("expected relative asset link, got:\n%s", guideHTML)
+	}
+	if strings.Contains(guideHTML, "<script>alert") {
+		t.Errorf("expected content to be sanitized")
+	}
+	if !strings.Contains(guideHTML, `class="active">Guide</a>`) {
+		t.Errorf("expected active page in navigation")
+	}
+
+	if !strings.Contains(read("index.html"), `href="docs/index.html"`) {
+		t.Errorf("expected top-level page in index.html")
+	}
+	if read("assets/guide/logo.png") != "png" {
+		t.Errorf("expected asset to be copied")
+	}
+
+	var entries []SearchEntry
+	if err := json.Unmarshal([]byte(read("search-index.json")), &entries); err != nil {
+		t.Fatalf("invalid search index: %v", err)
+	}
+	if len(entries) != 2 || entries[1].Path != "docs/guide" || entries[1].Text != "Guide" {
+		t.Errorf("unexpected search index: %+v", entries)
+	}
+	read("style.css")
+	read("search.js")
+
+	if _, err := NewDirWriter(outDir); err != ErrDirNotEmpty {
+		t.Errorf("expected ErrDirNotEmpty, got %v", err)
+	}
+}
+
+func TestRenderPage_Standalone(t *testing.T) {
+	var buf bytes.Buffer
+	if err := RenderPage(&buf, "Guide", "---\nstatus: done\n---\n# Guide\n![logo](assets/logo.png)\n<script>alert(1)</script>", RenderOptions{}); err != nil {
+		t.Fatalf("RenderPage failed: %v", err)
+	}
+	html := buf.String()
+	for _, want := range []string{"<title>Guide</title>", "<style>", "font-family", `src="assets/logo.png"`} {
+		if !strings.Contains(html, want) {
+			t.Errorf("expected %q in page", want)
+		}
+	}
+	if strings.Contains(html, "alert(1)") || strings.Contains(html, "status: done") {
+		t.Errorf("expected scripts and frontmatter to be removed")
+	}
+}
+
+func TestRenderPage_Math(t *testing.T) {
+	markdown := "Area: $\\pi r^2$ and `$code$`\n\n$$\n\\frac{a}{b}\n$$\n"
+	tests := []struct {
+		mode    texmath.Mode
+		want    []string
+		notWant []string
+	}{
+		{texmath.ModeOff, []string{"$\\pi r^2$"}, []string{"<math", "math-inline"}},
+		{texmath.ModeMathML, []string{"<msup><mi>r</mi><mn>2</mn></msup>", `<math xmlns="http://www.w3.org/1998/Math/MathML" display="block">`, "<code>$code$</code>"}, []string{"LEAFWIKIMATH", "<p><math"}},
+		{texmath.ModeMarkup, []string{`<span class="math math-inline">\(\pi r^2\)</span>`, `<div class="math math-display">\[\frac{a}{b}\]</div>`}, []string{"LEAFWIKIMATH"}},
+	}
+	for _, tt := range tests {
+		var buf bytes.Buffer
+		if err := RenderPage(&buf, "Math", markdown, RenderOptions{Math: tt.mode}); err != nil {
+			t.Fatalf("RenderPage failed: %v", err)
+		}
+		html := buf.String()
+		for _, want := range tt.want {
+			if !strings.Contains(html, want) {
+				t.Errorf("%s: expected %q in %s", tt.mode, want, html)
+			}
+		}
+		for _, notWant := range tt.notWant {
+			if strings.Contains(html, notWant) {
+				t.Errorf("%s: unexpected %q in %s", tt.mode, notWant, html)
+			}
+		}
+	}
+}
+
+func TestRenderPage_HighlightsCode(t *testing.T) {
+	markdown := "```go\nfunc main() {}\n```\n"
+
+	var buf bytes.Buffer
+	if err := RenderPage(&buf, "Code", markdown, RenderOptions{CodeTheme: "monokai"}); err != nil {
+		t.Fatalf("RenderPage failed: %v", err)
+	}
+	html := buf.String()
+	for _, want := range []string{`<pre class="chroma"><code class="language-go"><span class="kd">func</span>`, ".chroma { color: #f8f8f2; background-color: #272822"} {
+		if !strings.Contains(html, want) {
+			t.Errorf("expected %q in %s", want, html)
+		}
+	}
+
+	if got := RenderHTML(markdown, RenderOptions{CodeTheme: "off"}); strings.Contains(got, "chroma") {
+		t.Errorf("expected no highlighting, got %s", got)
+	}
+}
+
+func TestRenderHTML_RawHTML(t *testing.T) {
+	markdown := "<div title=\"Note\">Boxed</div>\n\nText<sup style=\"color: red\">2</sup> [link](javascript:alert)\n\n<script>track()</script>\n"
+	tests := []struct {
+		mode    RawHTML
+		want    []string
+		notWant []string
+	}{
+		{RawHTMLStrip, []string{"Text2"}, []string{"<div", "<sup", "track()"}},
+		{RawHTMLSanitize, []string{`<div title="Note">Boxed</div>`, "<sup>2</sup>"}, []string{"style=", "track()", "javascript:"}},
+		{RawHTMLTrusted, []string{`<div title="Note">Boxed</div>`, `<sup style="color: red">2</sup>`, "<script>track()</script>"}, []string{"javascript:", "LEAFWIKIRAWHTML"}},
+	}
+	for _, tt := range tests {
+		html := RenderHTML(markdown, RenderOptions{RawHTML: tt.mode})
+		for _, want := range tt.want {
+			if !strings.Contains(html, want) {
+				t.Errorf("%s: expected %q in %s", tt.mode, want, html)
+			}
+		}
+		for _, notWant := range tt.notWant {
+			if strings.Contains(html, notWant) {
+				t.Errorf("%s: unexpected %q in %s", tt.mode, notWant, html)
+			}
+		}
+	}
+}
+
+func TestRenderHTML_RawHTMLPlaceholders(t *testing.T) {
+	// text which looks like a placeholder is no placeholder
+	markdown := "`LEAFWIKIRAWHTML0END` LEAFWIKIRAWHTML1END\n\n<b>bold</b> <i>italic</i>\n"
+	html := RenderHTML(markdown, RenderOptions{RawHTML: RawHTMLTrusted})
+	if !strings.Contains(html, "<code>LEAFWIKIRAWHTML0END</code> LEAFWIKIRAWHTML1END") {
+		t.Errorf("expected the text to be kept, got %s", html)
+	}
+	if !strings.Contains(html, "<b>bold</b> <i>italic</i>") || strings.Count(html, "<b>") != 1 {
+		t.Errorf("expected each raw fragment once, got %s", html)
+	}
+
+	raw := newRawFragments()
+	raw.fragments = [][]byte{[]byte("<b>"), []byte("</b>")}
+	// a placeholder which reappears after its position isn't replaced again
+	rendered := raw.placeholder(0) + "x" + raw.placeholder(1) + raw.placeholder(0)
+	if got := raw.Restore(rendered); got != "<b>x</b>"+raw.placeholder(0) {
+		t.Errorf("Restore() = %q", got)
+	}
+	if other := newRawFragments(); other.placeholder(0) == raw.placeholder(0) {
+		t.Error("expected a new nonce for every render")
+	}
+}
+
+func TestDisallowedHTML(t *testing.T) {
+	markdown := "<details><summary>More</summary></details>\n\n<a href=\"https://example.com\">ok</a> <span onclick=\"x()\">no</span>\n\n```html\n<script>code()</script>\n```\n\n<script>a()</script>\n\n<script>b()</script>\n"
+	got := DisallowedHTML(markdown)
+	want := []string{`<span onclick="x()">`, "<script>"}
+	if strings.Join(got, " ") != strings.Join(want, " ") {
+		t.Errorf("DisallowedHTML() = %q, want %q", got, want)
+	}
+}
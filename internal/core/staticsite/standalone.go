@@ -0,0 +1,34 @@
+package staticsite
+
+import (
+	"html/template"
+	"io"
+
+	"github.com/Gomez12/wiki/internal/core/frontmatter"
+	"github.com/Gomez12/wiki/internal/core/highlight"
+)
+
+var standaloneTemplate = template.Must(template.ParseFS(templatesFS, "templates/standalone.html"))
+
+// RenderPage writes a single page as self-contained HTML document with the styles inlined.
+// Links are kept as they are, the caller rewrites them before when needed.
+func RenderPage(out io.Writer, title, markdown string, opts RenderOptions) error {
+	style, err := templatesFS.ReadFile("templates/style.css")
+	if err != nil {
+		return err
+	}
+	style = append(style, highlight.CSS(opts.CodeTheme)...)
+
+	return standaloneTemplate.Execute(out, struct {
+		Title   string
+		Style   template.CSS
+		Content template.HTML
+	}{title, template.CSS(style), template.HTML(RenderHTML(markdown, opts))})
+}
+
+// RenderHTML returns the sanitized HTML of the Markdown without frontmatter. Code blocks
+// refer to the stylesheet returned by highlight.CSS.
+func RenderHTML(markdown string, opts RenderOptions) string {
+	_, body, _ := frontmatter.Split(markdown)
+	return renderMarkdown(body, opts, newPolicy())
+}
@@ -0,0 +1,43 @@
+(function () {
+  var root = document.body.dataset.root || ''
+  var input = document.getElementById('search')
+  var results = document.getElementById('search-results')
+  var index = null
+
+  function load() {
+    if (index) {
+      return Promise.resolve(index)
+    }
+    return fetch(root + 'search-index.json')
+      .then(function (res) {
+        return res.json()
+      })
+      .then(function (data) {
+        index = data
+        return index
+      })
+  }
+
+  input.addEventListener('input', function () {
+    var query = input.value.trim().toLowerCase()
+    results.innerHTML = ''
+    if (query.length < 2) {
+      return
+    }
+    load().then(function (pages) {
+      pages
+        .filter(function (page) {
+          return (page.title + ' ' + page.text).toLowerCase().indexOf(query) !== -1
+        })
+        .slice(0, 20)
+        .forEach(function (page) {
+          var link = document.createElement('a')
+          link.href = root + page.path + '/index.html'
+          link.textContent = page.title
+          var item = document.createElement('li')
+          item.appendChild(link)
+          results.appendChild(item)
+        })
+    })
+  })
+})()
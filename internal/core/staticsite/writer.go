@@ -0,0 +1,66 @@
+package staticsite
+
+import (
+	"archive/zip"
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+)
+
+var ErrDirNotEmpty = errors.New("destination directory must be empty")
+
+// Writer stores the files of a generated site. Names are slash separated and relative to the site root.
+type Writer interface {
+	WriteFile(name string, r io.Reader) error
+}
+
+// DirWriter writes the site to a directory
+type DirWriter struct {
+	dir string
+}
+
+// NewDirWriter creates dir if needed. It fails if dir exists and is not empty.
+func NewDirWriter(dir string) (*DirWriter, error) {
+	if entries, err := os.ReadDir(dir); err == nil && len(entries) > 0 {
+		return nil, ErrDirNotEmpty
+	}
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return nil, err
+	}
+	return &DirWriter{dir: dir}, nil
+}
+
+func (d *DirWriter) WriteFile(name string, r io.Reader) error {
+	target := filepath.Join(d.dir, filepath.FromSlash(name))
+	if err := os.MkdirAll(filepath.Dir(target), 0755); err != nil {
+		return err
+	}
+	f, err := os.Create(target)
+	if err != nil {
+		return err
+	}
+	if _, err := io.Copy(f, r); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
+
+// ZipWriter adds the site to a zip archive. The caller closes the archive.
+type ZipWriter struct {
+	zw *zip.Writer
+}
+
+func NewZipWriter(zw *zip.Writer) *ZipWriter {
+	return &ZipWriter{zw: zw}
+}
+
+func (z *ZipWriter) WriteFile(name string, r io.Reader) error {
+	w, err := z.zw.Create(name)
+	if err != nil {
+		return err
+	}
+	_, err = io.Copy(w, r)
+	return err
+}
@@ -0,0 +1,365 @@
+package texmath
+
+import (
+	"html"
+	"strings"
+	"unicode"
+)
+
+// maxDepth limits the nesting of groups, deeper expressions are shown as error
+const maxDepth = 50
+
+var identifiers = map[string]string{
+	"alpha": "α", "beta": "β", "gamma": "γ", "delta": "δ", "epsilon": "ϵ", "varepsilon": "ε",
+	"zeta": "ζ", "eta": "η", "theta": "θ", "vartheta": "ϑ", "iota": "ι", "kappa": "κ",
+	"lambda": "λ", "mu": "μ", "nu": "ν", "xi": "ξ", "pi": "π", "varpi": "ϖ", "rho": "ρ",
+	"varrho": "ϱ", "sigma": "σ", "varsigma": "ς", "tau": "τ", "upsilon": "υ", "phi": "ϕ",
+	"varphi": "φ", "chi": "χ", "psi": "ψ", "omega": "ω",
+	"Gamma": "Γ", "Delta": "Δ", "Theta": "Θ", "Lambda": "Λ", "Xi": "Ξ", "Pi": "Π",
+	"Sigma": "Σ", "Upsilon": "Υ", "Phi": "Φ", "Psi": "Ψ", "Omega": "Ω",
+	"infty": "∞", "partial": "∂", "nabla": "∇", "emptyset": "∅", "ell": "ℓ", "hbar": "ℏ",
+	"Re": "ℜ", "Im": "ℑ", "aleph": "ℵ",
+}
+
+var operators = map[string]string{
+	"times": "×", "cdot": "⋅", "pm": "±", "mp": "∓", "div": "÷", "ast": "∗", "star": "⋆",
+	"circ": "∘", "bullet": "∙", "leq": "≤", "le": "≤", "geq": "≥", "ge": "≥", "neq": "≠",
+	"ne": "≠", "approx": "≈", "equiv": "≡", "sim": "∼", "simeq": "≃", "cong": "≅",
+	"propto": "∝", "ll": "≪", "gg": "≫", "sum": "∑", "prod": "∏", "coprod": "∐", "int": "∫",
+	"iint": "∬", "oint": "∮", "to": "→", "rightarrow": "→", "leftarrow": "←", "gets": "←",
+	"Rightarrow": "⇒", "Leftarrow": "⇐", "leftrightarrow": "↔", "Leftrightarrow": "⇔",
+	"implies": "⟹", "iff": "⟺", "mapsto": "↦", "in": "∈", "notin": "∉", "ni": "∋",
+	"subset": "⊂", "subseteq": "⊆", "supset": "⊃", "supseteq": "⊇", "cup": "∪", "cap": "∩",
+	"bigcup": "⋃", "bigcap": "⋂", "setminus": "∖", "forall": "∀", "exists": "∃", "neg": "¬",
+	"lnot": "¬", "land": "∧", "wedge": "∧", "lor": "∨", "vee": "∨", "oplus": "⊕",
+	"otimes": "⊗", "perp": "⊥", "parallel": "∥", "mid": "∣", "cdots": "⋯", "ldots": "…",
+	"dots": "…", "vdots": "⋮", "ddots": "⋱", "langle": "⟨", "rangle": "⟩", "lfloor": "⌊",
+	"rfloor": "⌋", "lceil": "⌈", "rceil": "⌉", "{": "{", "}": "}", "lbrace": "{",
+	"rbrace": "}", "|": "‖", "prime": "′", "angle": "∠", "triangle": "△", "$": "$", "%": "%", "&": "&", "#": "#", "_": "_",
+}
+
+// functions are written upright, like \sin
+var functions = map[string]bool{
+	"sin": true, "cos": true, "tan": true, "cot": true, "sec": true, "csc": true,
+	"arcsin": true, "arccos": true, "arctan": true, "sinh": true, "cosh": true, "tanh": true,
+	"log": true, "ln": true, "lg": true, "exp": true, "lim": true, "max": true, "min": true,
+	"sup": true, "inf": true, "det": true, "gcd": true, "dim": true, "ker": true, "arg": true,
+	"Pr": true, "mod": true,
+}
+
+var spaces = map[string]string{
+	",": "0.167em", ":": "0.222em", ";": "0.278em", " ": "0.25em", "quad": "1em",
+	"qquad": "2em", "!": "-0.167em",
+}
+
+var accents = map[string]string{
+	"hat": "^", "widehat": "^", "bar": "¯", "overline": "¯", "vec": "→", "tilde": "~",
+	"widetilde": "~", "dot": "˙", "ddot": "¨",
+}
+
+var variants = map[string]string{
+	"mathbf": "bold", "boldsymbol": "bold", "mathit": "italic", "mathbb": "double-struck",
+	"mathcal": "script", "mathfrak": "fraktur", "mathsf": "sans-serif", "mathtt": "monospace",
+	"mathrm": "normal",
+}
+
+// ToMathML converts a TeX expression to MathML. The common subset of TeX used in notes is
+// supported: scripts, fractions, roots, Greek letters, operators, accents and font styles.
+// Unknown commands are shown as error, the TeX source is kept as annotation.
+func ToMathML(tex string, display bool) string {
+	p := &parser{src: []rune(tex)}
+	row := p.row(0, 0)
+
+	var b strings.Builder
+	b.WriteString(`<math xmlns="http://www.w3.org/1998/Math/MathML"`)
+	if display {
+		b.WriteString(` display="block"`)
+	}
+	b.WriteString(`><semantics><mrow>`)
+	b.WriteString(strings.Join(row, ""))
+	b.WriteString(`</mrow><annotation encoding="application/x-tex">`)
+	b.WriteString(html.EscapeString(tex))
+	b.WriteString(`</annotation></semantics></math>`)
+	return b.String()
+}
+
+type parser struct {
+	src []rune
+	pos int
+	// lefts counts the open \left delimiters, \right only ends a row inside of them
+	lefts int
+}
+
+// row parses nodes until the closing rune (0 for the end) or \right
+func (p *parser) row(closing rune, depth int) []string {
+	nodes := []string{}
+	for {
+		p.skipSpace()
+		if p.pos >= len(p.src) {
+			return nodes
+		}
+		c := p.src[p.pos]
+		if closing != 0 && c == closing {
+			p.pos++
+			return nodes
+		}
+		if closing == 0 && c == '}' {
+			// unbalanced brace
+			p.pos++
+			nodes = append(nodes, mo("}"))
+			continue
+		}
+		if p.lefts > 0 && p.peekCommand() == "right" {
+			return nodes
+		}
+
+		switch c {
+		case '^', '_':
+			p.pos++
+			base := `<mrow></mrow>`
+			if len(nodes) > 0 {
+				base = nodes[len(nodes)-1]
+				nodes = nodes[:len(nodes)-1]
+			}
+			nodes = append(nodes, p.scripts(base, c, depth))
+		default:
+			nodes = append(nodes, p.atom(depth))
+		}
+	}
+}
+
+// scripts parses the sub- and superscript of base, the first one being marked by first
+func (p *parser) scripts(base string, first rune, depth int) string {
+	script := p.argument(depth)
+	p.skipSpace()
+	if p.pos < len(p.src) && (p.src[p.pos] == '^' || p.src[p.pos] == '_') && p.src[p.pos] != first {
+		p.pos++
+		other := p.argument(depth)
+		if first == '_' {
+			return `<msubsup>` + base + script + other + `</msubsup>`
+		}
+		return `<msubsup>` + base + other + script + `</msubsup>`
+	}
+	if first == '_' {
+		return `<msub>` + base + script + `</msub>`
+	}
+	return `<msup>` + base + script + `</msup>`
+}
+
+// argument parses a group or a single atom
+func (p *parser) argument(depth int) string {
+	p.skipSpace()
+	if p.pos >= len(p.src) {
+		return `<mrow></mrow>`
+	}
+	return p.atom(depth)
+}
+
+// atom parses a single token or group
+func (p *parser) atom(depth int) string {
+	if depth > maxDepth {
+		p.pos = len(p.src)
+		return merror("expression too deeply nested")
+	}
+	c := p.src[p.pos]
+	switch {
+	case c == '{':
+		p.pos++
+		return `<mrow>` + strings.Join(p.row('}', depth+1), "") + `</mrow>`
+	case c == '\\':
+		return p.command(depth)
+	case unicode.IsDigit(c) || (c == '.' && p.pos+1 < len(p.src) && unicode.IsDigit(p.src[p.pos+1])):
+		start := p.pos
+		for p.pos < len(p.src) && (unicode.IsDigit(p.src[p.pos]) || p.src[p.pos] == '.') {
+			p.pos++
+		}
+		return `<mn>` + string(p.src[start:p.pos]) + `</mn>`
+	case unicode.IsLetter(c):
+		p.pos++
+		return mi(string(c))
+	case c == '\'':
+		p.pos++
+		return mo("′")
+	case c == '&':
+		p.pos++
+		return `<mspace width="1em"></mspace>`
+	default:
+		p.pos++
+		return mo(string(c))
+	}
+}
+
+// command parses a command starting with a backslash
+func (p *parser) command(depth int) string {
+	name := p.readCommand()
+	switch {
+	case name == "":
+		return mo("\\")
+	case name == "\\":
+		return `<mspace linebreak="newline"></mspace>`
+	case identifiers[name] != "":
+		return mi(identifiers[name])
+	case operators[name] != "":
+		return mo(operators[name])
+	case functions[name]:
+		return `<mi mathvariant="normal">` + name + `</mi>`
+	case spaces[name] != "":
+		return `<mspace width="` + spaces[name] + `"></mspace>`
+	case accents[name] != "":
+		return `<mover accent="true">` + p.argument(depth+1) + mo(accents[name]) + `</mover>`
+	case variants[name] != "":
+		return p.variant(variants[name])
+	}
+
+	switch name {
+	case "frac", "dfrac", "tfrac":
+		num := p.argument(depth + 1)
+		den := p.argument(depth + 1)
+		return `<mfrac>` + num + den + `</mfrac>`
+	case "binom":
+		top := p.argument(depth + 1)
+		bottom := p.argument(depth + 1)
+		return `<mrow>` + mo("(") + `<mfrac linethickness="0">` + top + bottom + `</mfrac>` + mo(")") + `</mrow>`
+	case "sqrt":
+		p.skipSpace()
+		if p.pos < len(p.src) && p.src[p.pos] == '[' {
+			p.pos++
+			index := `<mrow>` + strings.Join(p.row(']', depth+1), "") + `</mrow>`
+			return `<mroot>` + p.argument(depth+1) + index + `</mroot>`
+		}
+		return `<msqrt>` + p.argument(depth+1) + `</msqrt>`
+	case "text", "textrm", "textit", "textbf", "mbox", "operatorname":
+		text := html.EscapeString(p.rawGroup())
+		if name == "operatorname" {
+			return `<mi mathvariant="normal">` + text + `</mi>`
+		}
+		return `<mtext>` + text + `</mtext>`
+	case "left":
+		delim := p.delimiter()
+		p.lefts++
+		inner := strings.Join(p.row(0, depth+1), "")
+		p.lefts--
+		closing := ""
+		if p.peekCommand() == "right" {
+			p.readCommand()
+			closing = p.delimiter()
+		}
+		return `<mrow>` + delim + inner + closing + `</mrow>`
+	case "right":
+		return p.delimiter()
+	case "displaystyle", "textstyle", "limits", "nolimits":
+		return ""
+	}
+	return merror("\\" + name)
+}
+
+// variant parses the argument of a font command like \mathbf{x}
+func (p *parser) variant(variant string) string {
+	var b strings.Builder
+	b.WriteString(`<mrow>`)
+	for _, r := range p.rawGroup() {
+		switch {
+		case unicode.IsSpace(r):
+		case unicode.IsDigit(r):
+			b.WriteString(`<mn mathvariant="` + variant + `">` + string(r) + `</mn>`)
+		default:
+			b.WriteString(`<mi mathvariant="` + variant + `">` + html.EscapeString(string(r)) + `</mi>`)
+		}
+	}
+	b.WriteString(`</mrow>`)
+	return b.String()
+}
+
+// delimiter parses the delimiter after \left or \right, "." is the invisible delimiter
+func (p *parser) delimiter() string {
+	p.skipSpace()
+	if p.pos >= len(p.src) {
+		return ""
+	}
+	if p.src[p.pos] == '\\' {
+		name := p.readCommand()
+		if op, ok := operators[name]; ok {
+			return `<mo stretchy="true">` + html.EscapeString(op) + `</mo>`
+		}
+		return merror("\\" + name)
+	}
+	c := p.src[p.pos]
+	p.pos++
+	if c == '.' {
+		return ""
+	}
+	return `<mo stretchy="true">` + html.EscapeString(string(c)) + `</mo>`
+}
+
+// rawGroup returns the text of a braced group, or a single character
+func (p *parser) rawGroup() string {
+	p.skipSpace()
+	if p.pos >= len(p.src) {
+		return ""
+	}
+	if p.src[p.pos] != '{' {
+		p.pos++
+		return string(p.src[p.pos-1])
+	}
+	level := 0
+	start := p.pos + 1
+	for ; p.pos < len(p.src); p.pos++ {
+		switch p.src[p.pos] {
+		case '{':
+			level++
+		case '}':
+			level--
+			if level == 0 {
+				p.pos++
+				return string(p.src[start : p.pos-1])
+			}
+		}
+	}
+	return string(p.src[start:])
+}
+
+// readCommand reads the name of the command at the current backslash
+func (p *parser) readCommand() string {
+	p.pos++
+	if p.pos >= len(p.src) {
+		return ""
+	}
+	start := p.pos
+	for p.pos < len(p.src) && unicode.IsLetter(p.src[p.pos]) && p.src[p.pos] < unicode.MaxASCII {
+		p.pos++
+	}
+	if p.pos == start {
+		// control symbols like \, or \{
+		p.pos++
+	}
+	return string(p.src[start:p.pos])
+}
+
+// peekCommand returns the name of the command at the current position without consuming it
+func (p *parser) peekCommand() string {
+	if p.pos >= len(p.src) || p.src[p.pos] != '\\' {
+		return ""
+	}
+	pos := p.pos
+	name := p.readCommand()
+	p.pos = pos
+	return name
+}
+
+func (p *parser) skipSpace() {
+	for p.pos < len(p.src) && unicode.IsSpace(p.src[p.pos]) {
+		p.pos++
+	}
+}
+
+func mi(s string) string {
+	return `<mi>` + html.EscapeString(s) + `</mi>`
+}
+
+func mo(s string) string {
+	return `<mo>` + html.EscapeString(s) + `</mo>`
+}
+
+func merror(s string) string {
+	return `<merror><mtext>` + html.EscapeString(s) + `</mtext></merror>`
+}
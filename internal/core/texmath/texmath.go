@@ -0,0 +1,254 @@
+// Package texmath recognizes $...$ and $$...$$ math in Markdown and renders it after the
+// Markdown has been converted to HTML
+package texmath
+
+import (
+	"fmt"
+	"html"
+	"strings"
+)
+
+// Mode controls how math in pages is rendered
+type Mode string
+
+const (
+	// ModeOff leaves dollar signs as they are
+	ModeOff Mode = "off"
+	// ModeMathML renders math to MathML on the server
+	ModeMathML Mode = "mathml"
+	// ModeMarkup emits the TeX source in \(...\) and \[...\] delimiters for KaTeX or MathJax
+	ModeMarkup Mode = "markup"
+)
+
+// Valid reports whether the mode is known
+func (m Mode) Valid() bool {
+	return m == ModeOff || m == ModeMathML || m == ModeMarkup
+}
+
+// Expression is a math expression found in the Markdown
+type Expression struct {
+	TeX     string
+	Display bool
+}
+
+// Math holds the expressions replaced by placeholders until the HTML has been rendered
+type Math struct {
+	mode        Mode
+	expressions []Expression
+}
+
+// Protect replaces the math in the Markdown with placeholders, so the Markdown renderer and
+// the sanitizer leave it alone. Math in code spans and code blocks is skipped. Call Restore
+// on the rendered HTML afterwards.
+func Protect(markdown string, mode Mode) (string, *Math) {
+	m := &Math{mode: mode}
+	if mode != ModeMathML && mode != ModeMarkup {
+		return markdown, m
+	}
+
+	code := codeRanges(markdown)
+	var out strings.Builder
+	last := 0
+	for i := 0; i < len(markdown); i++ {
+		if end, ok := code[i]; ok {
+			i = end - 1
+			continue
+		}
+		switch markdown[i] {
+		case '\\':
+			i++
+		case '$':
+			expr, end, ok := scanMath(markdown, i, code)
+			if !ok {
+				continue
+			}
+			out.WriteString(markdown[last:i])
+			out.WriteString(placeholder(len(m.expressions)))
+			m.expressions = append(m.expressions, expr)
+			last = end
+			i = end - 1
+		}
+	}
+	if len(m.expressions) == 0 {
+		return markdown, m
+	}
+	out.WriteString(markdown[last:])
+	return out.String(), m
+}
+
+// Expressions returns the math found by Protect
+func (m *Math) Expressions() []Expression {
+	return m.expressions
+}
+
+// Restore replaces the placeholders in the rendered HTML with the rendered math.
+// Display math standing alone in a paragraph replaces the paragraph.
+func (m *Math) Restore(rendered string) string {
+	if len(m.expressions) == 0 {
+		return rendered
+	}
+	// replace the last expressions first, so LEAFWIKIMATH1END doesn't match in LEAFWIKIMATH11END
+	for i := len(m.expressions) - 1; i >= 0; i-- {
+		expr := m.expressions[i]
+		token := placeholder(i)
+		if expr.Display {
+			rendered = strings.ReplaceAll(rendered, "<p>"+token+"</p>", m.render(expr, true))
+		}
+		rendered = strings.ReplaceAll(rendered, token, m.render(expr, false))
+	}
+	return rendered
+}
+
+// render returns the HTML of an expression, block is set when it replaces a whole paragraph
+func (m *Math) render(expr Expression, block bool) string {
+	if m.mode == ModeMathML {
+		return ToMathML(expr.TeX, expr.Display)
+	}
+	tex := html.EscapeString(expr.TeX)
+	switch {
+	case block:
+		return `<div class="math math-display">\[` + tex + `\]</div>`
+	case expr.Display:
+		return `<span class="math math-display">\[` + tex + `\]</span>`
+	default:
+		return `<span class="math math-inline">\(` + tex + `\)</span>`
+	}
+}
+
+func placeholder(i int) string {
+	return fmt.Sprintf("LEAFWIKIMATH%dEND", i)
+}
+
+// scanMath reads the expression starting with the dollar sign at start. Display math is
+// delimited by $$ and may span lines. Inline math follows Pandoc: the opening $ must be
+// followed by a non-space, the closing $ preceded by a non-space and not followed by a
+// digit, so prices like $5 and $10 stay text.
+func scanMath(s string, start int, code map[int]int) (Expression, int, bool) {
+	if strings.HasPrefix(s[start:], "$$") {
+		for i := start + 2; i < len(s)-1; i++ {
+			if _, ok := code[i]; ok {
+				return Expression{}, 0, false
+			}
+			if s[i] == '\\' {
+				i++
+				continue
+			}
+			if s[i] == '$' && s[i+1] == '$' {
+				tex := strings.TrimSpace(s[start+2 : i])
+				if tex == "" {
+					return Expression{}, 0, false
+				}
+				return Expression{TeX: tex, Display: true}, i + 2, true
+			}
+		}
+		return Expression{}, 0, false
+	}
+
+	if start+1 >= len(s) || isSpace(s[start+1]) {
+		return Expression{}, 0, false
+	}
+	for i := start + 1; i < len(s); i++ {
+		if _, ok := code[i]; ok {
+			return Expression{}, 0, false
+		}
+		switch s[i] {
+		case '\\':
+			i++
+		case '\n':
+			// inline math doesn't span paragraphs
+			if strings.HasPrefix(strings.TrimLeft(s[i+1:], " \t"), "\n") {
+				return Expression{}, 0, false
+			}
+		case '$':
+			if isSpace(s[i-1]) || (i+1 < len(s) && s[i+1] >= '0' && s[i+1] <= '9') {
+				continue
+			}
+			return Expression{TeX: s[start+1 : i], Display: false}, i + 1, true
+		}
+	}
+	return Expression{}, 0, false
+}
+
+func isSpace(c byte) bool {
+	return c == ' ' || c == '\t' || c == '\n' || c == '\r'
+}
+
+// codeRanges returns the code blocks and code spans of the Markdown, mapping the start
+// offset of each to its end offset
+func codeRanges(s string) map[int]int {
+	ranges := map[int]int{}
+
+	offset := 0
+	fence := ""
+	fenceStart := 0
+	prevBlank := true
+	for offset < len(s) {
+		end := strings.IndexByte(s[offset:], '\n')
+		if end < 0 {
+			end = len(s)
+		} else {
+			end += offset + 1
+		}
+		line := strings.TrimRight(s[offset:end], "\r\n")
+		trimmed := strings.TrimLeft(line, " ")
+
+		switch {
+		case fence != "":
+			if strings.HasPrefix(trimmed, fence) && strings.Trim(trimmed, fence[:1]) == "" {
+				ranges[fenceStart] = end
+				fence = ""
+			}
+		case len(line)-len(trimmed) <= 3 && (strings.HasPrefix(trimmed, "```") || strings.HasPrefix(trimmed, "~~~")):
+			fence = trimmed[:len(trimmed)-len(strings.TrimLeft(trimmed, trimmed[:1]))]
+			fenceStart = offset
+		case prevBlank && (strings.HasPrefix(line, "    ") || strings.HasPrefix(line, "\t")):
+			// indented code block, the following indented lines are part of it as well
+			ranges[offset] = end
+			offset = end
+			continue
+		default:
+			codeSpans(s, offset, end, ranges)
+		}
+		prevBlank = strings.TrimSpace(line) == ""
+		offset = end
+	}
+	if fence != "" {
+		// an unclosed fence runs to the end of the page
+		ranges[fenceStart] = len(s)
+	}
+	return ranges
+}
+
+// codeSpans adds the `code spans` between start and end
+func codeSpans(s string, start, end int, ranges map[int]int) {
+	for i := start; i < end; i++ {
+		if s[i] == '\\' {
+			i++
+			continue
+		}
+		if s[i] != '`' {
+			continue
+		}
+		n := 1
+		for i+n < end && s[i+n] == '`' {
+			n++
+		}
+		delim := s[i : i+n]
+		closing := -1
+		for j := i + n; j < end; j++ {
+			if strings.HasPrefix(s[j:], delim) && (j+n >= len(s) || s[j+n] != '`') {
+				closing = j
+				break
+			}
+			for j+1 < end && s[j] == '`' && s[j+1] == '`' {
+				j++
+			}
+		}
+		if closing < 0 {
+			i += n - 1
+			continue
+		}
+		ranges[i] = closing + n
+		i = closing + n - 1
+	}
+}
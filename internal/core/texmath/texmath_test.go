@@ -0,0 +1,89 @@
+package texmath
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestProtect_FindsMath(t *testing.T) {
+	markdown := "Euler: $e^{i\\pi} + 1 = 0$ costs $5 and $10.\n\n$$\n\\sum_{n=1}^\\infty n\n$$\n\n" +
+		"`$not math$` and\n\n```\n$$ code $$\n```\n\n    $indented$\n\nEscaped \\$a$ and $ spaced $."
+	protected, m := Protect(markdown, ModeMarkup)
+
+	expressions := m.Expressions()
+	if len(expressions) != 2 {
+		t.Fatalf("Expected 2 expressions, got %+v", expressions)
+	}
+	if expressions[0].TeX != "e^{i\\pi} + 1 = 0" || expressions[0].Display {
+		t.Errorf("Unexpected inline expression %+v", expressions[0])
+	}
+	if expressions[1].TeX != "\\sum_{n=1}^\\infty n" || !expressions[1].Display {
+		t.Errorf("Unexpected display expression %+v", expressions[1])
+	}
+	for _, kept := range []string{"$5 and $10", "`$not math$`", "$$ code $$", "$indented$", "\\$a$", "$ spaced $"} {
+		if !strings.Contains(protected, kept) {
+			t.Errorf("Expected %q to be kept, got %q", kept, protected)
+		}
+	}
+}
+
+func TestProtect_Off(t *testing.T) {
+	markdown := "Inline $x$"
+	protected, m := Protect(markdown, ModeOff)
+	if protected != markdown || len(m.Expressions()) != 0 {
+		t.Errorf("Expected the markdown to be unchanged, got %q", protected)
+	}
+}
+
+func TestRestore_Markup(t *testing.T) {
+	_, m := Protect("$a<b$\n\n$$x$$", ModeMarkup)
+	rendered := m.Restore("<p>LEAFWIKIMATH0END</p>\n<p>LEAFWIKIMATH1END</p>")
+
+	want := `<p><span class="math math-inline">\(a&lt;b\)</span></p>` + "\n" + `<div class="math math-display">\[x\]</div>`
+	if rendered != want {
+		t.Errorf("Expected %q, got %q", want, rendered)
+	}
+}
+
+func TestRestore_ManyExpressions(t *testing.T) {
+	markdown := strings.Repeat("$x$ ", 12)
+	protected, m := Protect(markdown, ModeMarkup)
+	rendered := m.Restore(protected)
+	if strings.Contains(rendered, "LEAFWIKIMATH") || strings.Count(rendered, `\(x\)`) != 12 {
+		t.Errorf("Unexpected rendering %q", rendered)
+	}
+}
+
+func TestToMathML(t *testing.T) {
+	tests := []struct {
+		tex  string
+		want string
+	}{
+		{"x^2", `<msup><mi>x</mi><mn>2</mn></msup>`},
+		{"a_{i}^{n}", `<msubsup><mi>a</mi><mrow><mi>i</mi></mrow><mrow><mi>n</mi></mrow></msubsup>`},
+		{"\\frac{1}{2}", `<mfrac><mrow><mn>1</mn></mrow><mrow><mn>2</mn></mrow></mfrac>`},
+		{"\\sqrt[3]{x}", `<mroot><mrow><mi>x</mi></mrow><mrow><mn>3</mn></mrow></mroot>`},
+		{"\\alpha \\leq \\beta", `<mi>α</mi><mo>≤</mo><mi>β</mi>`},
+		{"\\sin x", `<mi mathvariant="normal">sin</mi><mi>x</mi>`},
+		{"\\text{if } x<0", `<mtext>if </mtext><mi>x</mi><mo>&lt;</mo><mn>0</mn>`},
+		{"\\mathbf{v}", `<mrow><mi mathvariant="bold">v</mi></mrow>`},
+		{"\\left( x \\right)", `<mrow><mo stretchy="true">(</mo><mi>x</mi><mo stretchy="true">)</mo></mrow>`},
+		{"\\unknown", `<merror><mtext>\unknown</mtext></merror>`},
+	}
+	for _, tt := range tests {
+		got := ToMathML(tt.tex, false)
+		if !strings.Contains(got, "<semantics><mrow>"+tt.want+"</mrow>") {
+			t.Errorf("ToMathML(%q) = %q, want %q", tt.tex, got, tt.want)
+		}
+	}
+
+	if got := ToMathML("x", true); !strings.Contains(got, `display="block"`) {
+		t.Errorf("Expected display math, got %q", got)
+	}
+	if got := ToMathML("<script>", false); strings.Contains(got, "<script>") {
+		t.Errorf("Expected the source to be escaped, got %q", got)
+	}
+	if got := ToMathML(strings.Repeat("{", 1000), false); !strings.Contains(got, "<merror>") {
+		t.Errorf("Expected deeply nested groups to fail, got %q", got)
+	}
+}
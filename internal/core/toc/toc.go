@@ -0,0 +1,77 @@
+// Package toc extracts the table of contents of a Markdown page
+package toc
+
+import (
+	"regexp"
+	"strings"
+
+	"github.com/Gomez12/wiki/internal/core/frontmatter"
+	"github.com/russross/blackfriday/v2"
+)
+
+// Heading is a heading of the page with the headings nested below it
+type Heading struct {
+	Level int    `json:"level"`
+	Text  string `json:"text"`
+	// Anchor is the id of the heading in the rendered page, e.g. getting-started
+	Anchor   string     `json:"anchor"`
+	Children []*Heading `json:"children"`
+}
+
+// Extract returns the top-level headings of the Markdown body, frontmatter and headings in
+// code blocks are skipped. A heading is nested below the closest preceding heading with a
+// lower level, so skipped levels (h1 followed by h3) still nest.
+func Extract(markdown string) []*Heading {
+	_, body, _ := frontmatter.Split(markdown)
+	root := blackfriday.New(blackfriday.WithExtensions(blackfriday.CommonExtensions)).Parse([]byte(body))
+
+	headings := []*Heading{}
+	var stack []*Heading
+	for node := root.FirstChild; node != nil; node = node.Next {
+		if node.Type != blackfriday.Heading {
+			continue
+		}
+		text := strings.TrimSpace(plainText(node))
+		heading := &Heading{Level: node.Level, Text: text, Anchor: Anchor(text), Children: []*Heading{}}
+
+		for len(stack) > 0 && stack[len(stack)-1].Level >= heading.Level {
+			stack = stack[:len(stack)-1]
+		}
+		if len(stack) == 0 {
+			headings = append(headings, heading)
+		} else {
+			parent := stack[len(stack)-1]
+			parent.Children = append(parent.Children, heading)
+		}
+		stack = append(stack, heading)
+	}
+	return headings
+}
+
+// plainText concatenates the text of a node without formatting
+func plainText(node *blackfriday.Node) string {
+	var b strings.Builder
+	node.Walk(func(n *blackfriday.Node, entering bool) blackfriday.WalkStatus {
+		if entering && (n.Type == blackfriday.Text || n.Type == blackfriday.Code) {
+			b.Write(n.Literal)
+		}
+		return blackfriday.GoToNext
+	})
+	return b.String()
+}
+
+var (
+	umlautReplacer    = strings.NewReplacer("ö", "o", "ü", "u", "ß", "s", "ä", "a")
+	anchorInvalid     = regexp.MustCompile(`[^A-Za-z0-9_\s-]`)
+	anchorSeparators  = regexp.MustCompile(`[\s_-]+`)
+	anchorOuterDashes = regexp.MustCompile(`^-+|-+$`)
+)
+
+// Anchor returns the id the web interface gives a heading with the text.
+// Keep it in sync with slugify in the Headline component of the UI.
+func Anchor(text string) string {
+	anchor := umlautReplacer.Replace(strings.ToLower(text))
+	anchor = anchorInvalid.ReplaceAllString(strings.TrimSpace(anchor), "")
+	anchor = anchorSeparators.ReplaceAllString(anchor, "-")
+	return anchorOuterDashes.ReplaceAllString(anchor, "")
+}
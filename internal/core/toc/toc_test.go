@@ -0,0 +1,47 @@
+package toc
+
+import "testing"
+
+func TestAnchor(t *testing.T) {
+	tests := map[string]string{
+		"Getting Started":         "getting-started",
+		"  Größe & Übersicht  ":   "grose-ubersicht",
+		"API_v2 -- Overview!":     "api-v2-overview",
+		"---":                     "",
+		"Setup (Linux/macOS)":     "setup-linuxmacos",
+		"Straße für Änderungen ✓": "strase-fur-anderungen",
+	}
+	for text, want := range tests {
+		if got := Anchor(text); got != want {
+			t.Errorf("Anchor(%q) = %q, want %q", text, got, want)
+		}
+	}
+}
+
+func TestExtract(t *testing.T) {
+	markdown := "---\ntitle: Guide\n---\n# Guide\n\nIntro\n\n## Install **now**\n\n```\n# not a heading\n```\n\n### `go` build\n\n## Usage\n\n#### Deep\n\n# Appendix\n"
+
+	headings := Extract(markdown)
+	if len(headings) != 2 || headings[0].Text != "Guide" || headings[1].Anchor != "appendix" {
+		t.Fatalf("unexpected top-level headings: %+v", headings)
+	}
+
+	guide := headings[0]
+	if len(guide.Children) != 2 {
+		t.Fatalf("expected 2 sections below the title, got %+v", guide.Children)
+	}
+	install, usage := guide.Children[0], guide.Children[1]
+	if install.Text != "Install now" || install.Anchor != "install-now" || install.Level != 2 {
+		t.Errorf("unexpected heading %+v", install)
+	}
+	if len(install.Children) != 1 || install.Children[0].Text != "go build" {
+		t.Errorf("expected the code heading below install, got %+v", install.Children)
+	}
+	if len(usage.Children) != 1 || usage.Children[0].Level != 4 {
+		t.Errorf("expected skipped levels to nest, got %+v", usage.Children)
+	}
+
+	if headings := Extract("no headings"); len(headings) != 0 {
+		t.Errorf("expected no headings, got %+v", headings)
+	}
+}
@@ -60,10 +60,23 @@ func FoldPageFolderIfEmpty(storageDir string, pagePath string) error {
 		return fmt.Errorf("could not read folder: %w", err)
 	}
 
-	// Only fold if exactly 1 file: index.md
+	// Only fold if exactly 1 file: index.md. The page order of the folder is obsolete then.
+	hasOrder := false
+	for i, entry := range entries {
+		if entry.Name() == orderFilename {
+			hasOrder = true
+			entries = append(entries[:i], entries[i+1:]...)
+			break
+		}
+	}
 	if len(entries) != 1 || entries[0].Name() != "index.md" {
 		return nil
 	}
+	if hasOrder {
+		if err := os.Remove(path.Join(dirPath, orderFilename)); err != nil {
+			return fmt.Errorf("could not remove page order: %w", err)
+		}
+	}
 
 	// Move index.md → page.md
 	if err := os.Rename(indexPath, mdPath); err != nil {
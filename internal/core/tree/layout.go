@@ -0,0 +1,175 @@
+package tree
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path"
+	"strings"
+)
+
+// Layout is how the pages are stored as files in the data dir
+type Layout string
+
+const (
+	// LayoutFlat stores pages without children as slug.md and pages with children as
+	// slug/index.md. It is the layout of data dirs which have none recorded.
+	LayoutFlat Layout = "flat"
+	// LayoutFolder stores every page as slug/index.md, so files can live next to a page
+	LayoutFolder Layout = "folder"
+)
+
+// layoutFilename records the layout of the data dir next to the tree
+const layoutFilename = "layout.json"
+
+var ErrInvalidLayout = errors.New("invalid layout, expected flat or folder")
+
+// ParseLayout returns the layout with the name, flat or folder
+func ParseLayout(name string) (Layout, error) {
+	switch Layout(strings.ToLower(strings.TrimSpace(name))) {
+	case LayoutFlat:
+		return LayoutFlat, nil
+	case LayoutFolder:
+		return LayoutFolder, nil
+	}
+	return "", ErrInvalidLayout
+}
+
+type layoutFile struct {
+	Layout Layout `json:"layout"`
+}
+
+// LoadLayout returns the layout recorded in the storage dir, false if none was recorded yet
+func LoadLayout(storageDir string) (Layout, bool, error) {
+	data, err := os.ReadFile(path.Join(storageDir, layoutFilename))
+	if os.IsNotExist(err) {
+		return LayoutFlat, false, nil
+	}
+	if err != nil {
+		return "", false, fmt.Errorf("could not read layout: %w", err)
+	}
+	var file layoutFile
+	if err := json.Unmarshal(data, &file); err != nil {
+		return "", false, fmt.Errorf("could not parse layout: %w", err)
+	}
+	layout, err := ParseLayout(string(file.Layout))
+	if err != nil {
+		return "", false, err
+	}
+	return layout, true, nil
+}
+
+// SaveLayout records the layout in the storage dir
+func SaveLayout(storageDir string, layout Layout) error {
+	data, err := json.Marshal(layoutFile{Layout: layout})
+	if err != nil {
+		return err
+	}
+	return writeFileAtomic(path.Join(storageDir, layoutFilename), data, 0o644)
+}
+
+// SetLayout sets how new pages are stored. It doesn't touch existing pages, see MigrateLayout.
+func (t *TreeService) SetLayout(layout Layout) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.store.layout = layout
+}
+
+// Layout returns how the pages are stored
+func (t *TreeService) Layout() Layout {
+	t.mu.RLock()
+	defer t.mu.RUnlock()
+	return t.store.layout
+}
+
+// MigrateLayout converts the files of all pages to the layout and records it. Pages whose
+// folder holds other files than index.md stay folders in the flat layout, like pages with
+// children. It returns the moved files as old to new path, relative to the pages folder.
+func (t *TreeService) MigrateLayout(layout Layout) (map[string]string, error) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	if _, err := ParseLayout(string(layout)); err != nil {
+		return nil, err
+	}
+	if t.tree == nil {
+		return nil, ErrTreeNotLoaded
+	}
+
+	moves := map[string]string{}
+	var migrate func(node *PageNode) error
+	migrate = func(node *PageNode) error {
+		// children first, so folders are folded bottom up
+		for _, child := range node.Children {
+			if err := migrate(child); err != nil {
+				return err
+			}
+		}
+		if node.Parent == nil {
+			return nil
+		}
+
+		pagePath := GeneratePathFromPageNode(node)
+		relPath := strings.TrimPrefix(pagePath, GeneratePathFromPageNode(t.tree)+"/")
+		flatFile := path.Join(t.storageDir, pagePath+".md")
+		indexFile := path.Join(t.storageDir, pagePath, "index.md")
+
+		switch layout {
+		case LayoutFolder:
+			if _, err := os.Stat(flatFile); err != nil {
+				return nil
+			}
+			if err := EnsurePageIsFolder(t.storageDir, pagePath); err != nil {
+				return fmt.Errorf("could not convert %s: %w", relPath, err)
+			}
+			moves[relPath+".md"] = relPath + "/index.md"
+		case LayoutFlat:
+			if len(node.Children) > 0 {
+				return nil
+			}
+			if _, err := os.Stat(indexFile); err != nil {
+				return nil
+			}
+			if err := FoldPageFolderIfEmpty(t.storageDir, pagePath); err != nil {
+				return fmt.Errorf("could not convert %s: %w", relPath, err)
+			}
+			if _, err := os.Stat(flatFile); err == nil {
+				moves[relPath+"/index.md"] = relPath + ".md"
+			}
+		}
+		return nil
+	}
+	if err := migrate(t.tree); err != nil {
+		return moves, err
+	}
+
+	t.store.layout = layout
+	return moves, SaveLayout(t.storageDir, layout)
+}
+
+// EnsurePageFolder turns a page stored as slug.md into slug/index.md, so files can be stored
+// next to it. It returns the moved file as old and new path relative to the pages folder,
+// empty paths if the page was a folder already.
+func (t *TreeService) EnsurePageFolder(id string) (string, string, error) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	if t.tree == nil {
+		return "", "", ErrTreeNotLoaded
+	}
+	node, err := t.findPageByIDLocked(t.tree.Children, id)
+	if err != nil {
+		return "", "", err
+	}
+
+	pagePath := GeneratePathFromPageNode(node)
+	if _, err := os.Stat(path.Join(t.storageDir, pagePath+".md")); err != nil {
+		return "", "", nil
+	}
+	if err := EnsurePageIsFolder(t.storageDir, pagePath); err != nil {
+		return "", "", err
+	}
+	relPath := strings.TrimPrefix(pagePath, GeneratePathFromPageNode(t.tree)+"/")
+	return relPath + ".md", relPath + "/index.md", nil
+}
@@ -0,0 +1,82 @@
+package tree
+
+import (
+	"os"
+	"path"
+	"sort"
+	"strings"
+)
+
+// orderFilename stores the custom order of the pages in a folder, one slug per line.
+// It lives next to the pages, so the order survives rebuilding the tree from the
+// filesystem and travels with the folder when it is moved or synced.
+const orderFilename = ".order"
+
+func (t *TreeService) orderPath(parent *PageNode) string {
+	return path.Join(t.storageDir, GeneratePathFromPageNode(parent), orderFilename)
+}
+
+// saveOrderLocked persists the current order of the children of parent
+func (t *TreeService) saveOrderLocked(parent *PageNode) error {
+	if len(parent.Children) == 0 {
+		if err := os.Remove(t.orderPath(parent)); err != nil && !os.IsNotExist(err) {
+			return err
+		}
+		return nil
+	}
+	slugs := make([]string, 0, len(parent.Children))
+	for _, child := range parent.Children {
+		slugs = append(slugs, child.Slug)
+	}
+	return os.WriteFile(t.orderPath(parent), []byte(strings.Join(slugs, "\n")+"\n"), 0644)
+}
+
+// refreshOrderLocked updates the persisted order of parent after its children changed.
+// Folders without custom order are left alone. This is best effort: a stale order only
+// affects where pages end up when the tree is rebuilt.
+func (t *TreeService) refreshOrderLocked(parent *PageNode) {
+	if parent == nil {
+		return
+	}
+	if _, err := os.Stat(t.orderPath(parent)); err != nil {
+		return
+	}
+	_ = t.saveOrderLocked(parent)
+}
+
+// loadOrder returns the rank of each slug in the persisted order of parent, nil without one
+func (t *TreeService) loadOrder(parent *PageNode) map[string]int {
+	data, err := os.ReadFile(t.orderPath(parent))
+	if err != nil {
+		return nil
+	}
+	ranks := map[string]int{}
+	for _, slug := range strings.Split(string(data), "\n") {
+		slug = strings.TrimSpace(slug)
+		if _, seen := ranks[slug]; slug != "" && !seen {
+			ranks[slug] = len(ranks)
+		}
+	}
+	return ranks
+}
+
+// applyOrderLocked sorts the children of parent by the persisted order. Children missing in
+// the order keep their relative order after the listed ones.
+func (t *TreeService) applyOrderLocked(parent *PageNode) {
+	ranks := t.loadOrder(parent)
+	if ranks == nil {
+		return
+	}
+	rank := func(child *PageNode) int {
+		if r, ok := ranks[child.Slug]; ok {
+			return r
+		}
+		return len(ranks)
+	}
+	sort.SliceStable(parent.Children, func(i, j int) bool {
+		return rank(parent.Children[i]) < rank(parent.Children[j])
+	})
+	for i, child := range parent.Children {
+		child.Position = i
+	}
+}
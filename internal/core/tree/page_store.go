@@ -56,11 +56,14 @@ func writeFileAtomic(filename string, data []byte, perm os.FileMode) error {
 
 type PageStore struct {
 	storageDir string
+	// layout is how new pages are stored, see Layout
+	layout Layout
 }
 
 func NewPageStore(storageDir string) *PageStore {
 	return &PageStore{
 		storageDir: storageDir,
+		layout:     LayoutFlat,
 	}
 }
 
@@ -162,6 +165,18 @@ func (f *PageStore) CreatePage(parentEntry *PageNode, newEntry *PageNode) error
 		return fmt.Errorf("file already exists: %v", err)
 	}
 
+	// In the folder layout every page gets a folder of its own
+	if f.layout == LayoutFolder {
+		folder := path.Join(parentPath, newEntry.Slug)
+		if _, err := os.Stat(folder); err == nil {
+			return fmt.Errorf("folder already exists: %v", folder)
+		}
+		if err := os.MkdirAll(folder, 0755); err != nil {
+			return fmt.Errorf("could not create folder: %v", err)
+		}
+		newFilename = path.Join(folder, "index.md")
+	}
+
 	// Create the file
 	content := []byte("# " + newEntry.Title + "\n")
 	if err := writeFileAtomic(newFilename, content, 0o644); err != nil {
@@ -195,9 +210,7 @@ func (f *PageStore) DeletePage(entry *PageNode) error {
 		}
 	}
 
-	if entry.Parent != nil {
-		_ = FoldPageFolderIfEmpty(f.storageDir, GeneratePathFromPageNode(entry.Parent))
-	}
+	f.foldParent(entry)
 
 	return nil
 }
@@ -288,13 +301,20 @@ func (f *PageStore) MovePage(entry *PageNode, parentEntry *PageNode) error {
 		return fmt.Errorf("could not move file: %v", err)
 	}
 
-	if entry.Parent != nil {
-		_ = FoldPageFolderIfEmpty(f.storageDir, GeneratePathFromPageNode(entry.Parent))
-	}
+	f.foldParent(entry)
 
 	return nil
 }
 
+// foldParent turns the folder of the parent of entry back into a flat file if entry was
+// its last child. Pages keep their folder in the folder layout.
+func (f *PageStore) foldParent(entry *PageNode) {
+	if entry.Parent == nil || f.layout == LayoutFolder {
+		return
+	}
+	_ = FoldPageFolderIfEmpty(f.storageDir, GeneratePathFromPageNode(entry.Parent))
+}
+
 // ReadPageContent returns the content of a page
 func (f *PageStore) ReadPageContent(entry *PageNode) (string, error) {
 	if entry == nil {
@@ -0,0 +1,40 @@
+package tree
+
+import (
+	"strings"
+
+	"golang.org/x/text/unicode/norm"
+)
+
+// NormalizeRoute returns the route in Unicode normalization form C. Filenames created on
+// macOS are usually decomposed (NFD), while browsers and most editors send composed routes.
+func NormalizeRoute(route string) string {
+	return norm.NFC.String(route)
+}
+
+// SetCaseInsensitiveRoutes lets route lookups ignore the case of slugs which have no exact match
+func (t *TreeService) SetCaseInsensitiveRoutes(enabled bool) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.caseInsensitiveRoutes = enabled
+}
+
+// matchChildLocked returns the child matching a route segment, nil if there is none.
+// An exact match wins; otherwise slugs are compared in normalization form C and, with
+// case-insensitive routes, ignoring case.
+// Lock must be held by the caller
+func (t *TreeService) matchChildLocked(children []*PageNode, segment string) *PageNode {
+	for _, child := range children {
+		if child.Slug == segment {
+			return child
+		}
+	}
+	segment = NormalizeRoute(segment)
+	for _, child := range children {
+		slug := NormalizeRoute(child.Slug)
+		if slug == segment || (t.caseInsensitiveRoutes && strings.EqualFold(slug, segment)) {
+			return child
+		}
+	}
+	return nil
+}
@@ -2,6 +2,8 @@ package tree
 
 import (
 	"fmt"
+	"os"
+	"path"
 	"sort"
 	"strings"
 	"sync"
@@ -16,6 +18,8 @@ type TreeService struct {
 	treeFilename string
 	tree         *PageNode
 	store        *PageStore
+	// caseInsensitiveRoutes lets route lookups ignore the case of slugs
+	caseInsensitiveRoutes bool
 
 	mu sync.RWMutex
 }
@@ -218,6 +222,7 @@ func (t *TreeService) DeletePage(id string, recursive bool) error {
 	}
 
 	t.reindexPositions(parent)
+	t.refreshOrderLocked(parent)
 
 	return t.saveTreeLocked()
 }
@@ -249,7 +254,11 @@ func (t *TreeService) UpdatePage(id string, title string, slug string, content s
 
 	// Update the page
 	page.Title = title
+	oldSlug := page.Slug
 	page.Slug = slug
+	if oldSlug != slug {
+		t.refreshOrderLocked(page.Parent)
+	}
 
 	// Save the tree
 	return t.saveTreeLocked()
@@ -303,27 +312,25 @@ func (t *TreeService) FindPageByRoutePath(entry []*PageNode, routePath string) (
 	// recursive function to find the entry
 	var findEntry func(entry []*PageNode, routePart []string) (*Page, error)
 	findEntry = func(entry []*PageNode, routePart []string) (*Page, error) {
-		for _, e := range entry {
-			if e.Slug == routePart[0] {
-				if len(routePart) == 1 {
-					// Get the content of the entry
-					content, err := t.store.ReadPageContent(e)
-					if err != nil {
-						return nil, fmt.Errorf("could not get page content: %v", err)
-					}
-
-					return &Page{
-						PageNode: e,
-						Content:  content,
-					}, nil
-				}
-
-				// Find the entry in the children
-				return findEntry(e.Children, routePart[1:])
+		e := t.matchChildLocked(entry, routePart[0])
+		if e == nil {
+			return nil, ErrPageNotFound
+		}
+		if len(routePart) == 1 {
+			// Get the content of the entry
+			content, err := t.store.ReadPageContent(e)
+			if err != nil {
+				return nil, fmt.Errorf("could not get page content: %v", err)
 			}
+
+			return &Page{
+				PageNode: e,
+				Content:  content,
+			}, nil
 		}
 
-		return nil, ErrPageNotFound
+		// Find the entry in the children
+		return findEntry(e.Children, routePart[1:])
 	}
 
 	return findEntry(t.tree.Children, routePart)
@@ -387,16 +394,13 @@ func (t *TreeService) LookupPagePathLocked(entry []*PageNode, p string) (*PathLo
 		lookup.Segments[i] = segment
 
 		// Check if the segment exists in the current entry
-		for _, e := range entry {
-			if e.Slug == part {
-				// Segment exists
-				lookup.Segments[i].Exists = true
-				lookup.Segments[i].ID = &e.ID
-
-				// Move to the next entry
-				entry = e.Children
-				break
-			}
+		if e := t.matchChildLocked(entry, part); e != nil {
+			// Segment exists
+			lookup.Segments[i].Exists = true
+			lookup.Segments[i].ID = &e.ID
+
+			// Move to the next entry
+			entry = e.Children
 		}
 
 		// If the segment does not exist, set the pathExists flag to false
@@ -539,6 +543,8 @@ func (t *TreeService) AttachExistingPath(routePath string, title string) (*PageN
 				Children: []*PageNode{},
 			}
 			current.Children = append(current.Children, child)
+			// place the discovered page where a custom order of the folder puts it
+			t.applyOrderLocked(current)
 		}
 
 		// Set title for the last segment when provided
@@ -556,6 +562,62 @@ func (t *TreeService) AttachExistingPath(routePath string, title string) (*PageN
 	return current, nil
 }
 
+// DetachMissingPath removes the node of the given route path from the in-memory tree
+// when neither its file nor its folder exists on disk anymore (without touching the filesystem).
+// It is the counterpart of AttachExistingPath for pages removed outside of the API.
+// Returns true if a node was removed.
+func (t *TreeService) DetachMissingPath(routePath string) (bool, error) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	if t.tree == nil {
+		return false, ErrTreeNotLoaded
+	}
+
+	cleanRoute := strings.Trim(strings.TrimSpace(routePath), "/")
+	cleanRoute = strings.TrimSuffix(cleanRoute, "/index")
+	if cleanRoute == "" {
+		return false, fmt.Errorf("route path must not be empty")
+	}
+
+	current := t.tree
+	for _, slug := range strings.Split(cleanRoute, "/") {
+		var child *PageNode
+		for _, c := range current.Children {
+			if c.Slug == slug {
+				child = c
+				break
+			}
+		}
+		if child == nil {
+			return false, nil
+		}
+		current = child
+	}
+
+	entryPath := path.Join(t.storageDir, GeneratePathFromPageNode(current))
+	if _, err := os.Stat(entryPath + ".md"); err == nil {
+		return false, nil
+	}
+	if _, err := os.Stat(entryPath); err == nil {
+		return false, nil
+	}
+
+	parent := current.Parent
+	for i, c := range parent.Children {
+		if c == current {
+			parent.Children = append(parent.Children[:i], parent.Children[i+1:]...)
+			break
+		}
+	}
+	t.reindexPositions(parent)
+
+	if err := t.saveTreeLocked(); err != nil {
+		return false, fmt.Errorf("could not save tree: %w", err)
+	}
+	return true, nil
+}
+
 // MovePage moves a page to another parent
 func (t *TreeService) MovePage(id string, parentID string) error {
 	t.mu.Lock()
@@ -625,6 +687,8 @@ func (t *TreeService) MovePage(id string, parentID string) error {
 	// Reindex the positions of the old parent
 	t.reindexPositions(newParent)
 	t.reindexPositions(oldParent)
+	t.refreshOrderLocked(newParent)
+	t.refreshOrderLocked(oldParent)
 
 	// Save the tree
 	return t.saveTreeLocked()
@@ -692,6 +756,11 @@ func (t *TreeService) SortPages(parentID string, orderedIDs []string) error {
 	// Reindex the positions
 	t.reindexPositions(parent)
 
+	// Persist the order next to the pages, so it survives rebuilding the tree
+	if err := t.saveOrderLocked(parent); err != nil {
+		return fmt.Errorf("could not save page order: %w", err)
+	}
+
 	// Save the tree
 	return t.saveTreeLocked()
 }
@@ -482,6 +482,37 @@ func TestTreeService_FindPageByRoutePath_PartialMatch(t *testing.T) {
 	}
 }
 
+func TestTreeService_FindPageByRoutePath_Normalized(t *testing.T) {
+	service := NewTreeService(t.TempDir())
+	_ = service.LoadTree()
+
+	// decomposed "café", as in filenames created on macOS
+	decomposed := "cafe\u0301"
+	if _, err := service.CreatePage(nil, "Café", decomposed); err != nil {
+		t.Fatalf("CreatePage failed: %v", err)
+	}
+	if _, err := service.CreatePage(nil, "Docs", "docs"); err != nil {
+		t.Fatalf("CreatePage failed: %v", err)
+	}
+
+	page, err := service.FindPageByRoutePath(service.GetTree().Children, "caf\u00e9")
+	if err != nil || page.Slug != decomposed {
+		t.Fatalf("expected the composed route to find the page, got %v", err)
+	}
+	if _, err := service.FindPageByRoutePath(service.GetTree().Children, "Docs"); err == nil {
+		t.Error("expected case-sensitive routes by default")
+	}
+
+	service.SetCaseInsensitiveRoutes(true)
+	if page, err := service.FindPageByRoutePath(service.GetTree().Children, "Docs"); err != nil || page.Slug != "docs" {
+		t.Errorf("expected the route to match ignoring case, got %v", err)
+	}
+	lookup, err := service.LookupPagePath(service.GetTree().Children, "CAF\u00c9")
+	if err != nil || !lookup.Exists {
+		t.Errorf("expected the lookup to match, got %+v, %v", lookup, err)
+	}
+}
+
 func setupTestTree() *TreeService {
 	ts := NewTreeService(os.TempDir())
 	ts.tree = &PageNode{
@@ -803,3 +834,177 @@ func TestTreeService_EnsurePagePath_PathStartingWithSlash(t *testing.T) {
 		t.Errorf("expected nil result for invalid path")
 	}
 }
+
+func TestTreeService_DetachMissingPath(t *testing.T) {
+	tmpDir := t.TempDir()
+	service := NewTreeService(tmpDir)
+	_ = service.LoadTree()
+
+	if _, err := service.CreatePage(nil, "Keep", "keep"); err != nil {
+		t.Fatalf("CreatePage failed: %v", err)
+	}
+	parentID, err := service.CreatePage(nil, "Docs", "docs")
+	if err != nil {
+		t.Fatalf("CreatePage failed: %v", err)
+	}
+	if _, err := service.CreatePage(parentID, "Guide", "guide"); err != nil {
+		t.Fatalf("CreatePage failed: %v", err)
+	}
+
+	// Existing files are kept
+	if removed, err := service.DetachMissingPath("keep"); err != nil || removed {
+		t.Fatalf("expected existing page to be kept, got %v, %v", removed, err)
+	}
+
+	// Removed outside of the API
+	if err := os.RemoveAll(filepath.Join(tmpDir, "root", "docs")); err != nil {
+		t.Fatalf("failed to remove folder: %v", err)
+	}
+	removed, err := service.DetachMissingPath("docs")
+	if err != nil || !removed {
+		t.Fatalf("expected removed folder to be detached, got %v, %v", removed, err)
+	}
+
+	children := service.GetTree().Children
+	if len(children) != 1 || children[0].Slug != "keep" || children[0].Position != 0 {
+		t.Errorf("unexpected tree after detach: %+v", children)
+	}
+
+	if removed, err := service.DetachMissingPath("unknown/path"); err != nil || removed {
+		t.Errorf("expected unknown path to be ignored, got %v, %v", removed, err)
+	}
+}
+
+func TestTreeService_SortPages_SurvivesRebuild(t *testing.T) {
+	tmpDir := t.TempDir()
+	ts := NewTreeService(tmpDir)
+	if err := ts.LoadTree(); err != nil {
+		t.Fatalf("LoadTree failed: %v", err)
+	}
+	docsID, _ := ts.CreatePage(nil, "Docs", "docs")
+	ids := map[string]string{}
+	for _, slug := range []string{"alpha", "beta", "gamma"} {
+		id, err := ts.CreatePage(docsID, strings.ToUpper(slug), slug)
+		if err != nil {
+			t.Fatalf("CreatePage failed: %v", err)
+		}
+		ids[slug] = *id
+	}
+	if err := ts.SortPages(*docsID, []string{ids["gamma"], ids["alpha"], ids["beta"]}); err != nil {
+		t.Fatalf("SortPages failed: %v", err)
+	}
+	// renaming keeps the position
+	if err := ts.UpdatePage(ids["alpha"], "Alpha", "first", ""); err != nil {
+		t.Fatalf("UpdatePage failed: %v", err)
+	}
+
+	// rebuild the tree from the filesystem, the indexer discovers the pages alphabetically
+	if err := os.Remove(filepath.Join(tmpDir, "tree.json")); err != nil {
+		t.Fatalf("could not remove tree.json: %v", err)
+	}
+	rebuilt := NewTreeService(tmpDir)
+	if err := rebuilt.LoadTree(); err != nil {
+		t.Fatalf("LoadTree failed: %v", err)
+	}
+	for _, route := range []string{"docs/beta", "docs/first", "docs/gamma", "docs/new"} {
+		if _, err := rebuilt.AttachExistingPath(route, ""); err != nil {
+			t.Fatalf("AttachExistingPath failed: %v", err)
+		}
+	}
+
+	var slugs []string
+	for _, child := range rebuilt.GetTree().Children[0].Children {
+		slugs = append(slugs, child.Slug)
+	}
+	if strings.Join(slugs, ",") != "gamma,first,beta,new" {
+		t.Errorf("expected the custom order after the rebuild, got %v", slugs)
+	}
+}
+
+func TestFoldPageFolderIfEmpty_RemovesOrder(t *testing.T) {
+	tmpDir := t.TempDir()
+	ts := NewTreeService(tmpDir)
+	if err := ts.LoadTree(); err != nil {
+		t.Fatalf("LoadTree failed: %v", err)
+	}
+	docsID, _ := ts.CreatePage(nil, "Docs", "docs")
+	a, _ := ts.CreatePage(docsID, "A", "a")
+	b, _ := ts.CreatePage(docsID, "B", "b")
+	if err := ts.SortPages(*docsID, []string{*b, *a}); err != nil {
+		t.Fatalf("SortPages failed: %v", err)
+	}
+
+	for _, id := range []string{*a, *b} {
+		if err := ts.DeletePage(id, false); err != nil {
+			t.Fatalf("DeletePage failed: %v", err)
+		}
+	}
+	if _, err := os.Stat(filepath.Join(tmpDir, "root", "docs.md")); err != nil {
+		t.Errorf("expected the folder to be folded into docs.md: %v", err)
+	}
+}
+
+func TestTreeService_FolderLayout(t *testing.T) {
+	tmpDir := t.TempDir()
+	ts := NewTreeService(tmpDir)
+	if err := ts.LoadTree(); err != nil {
+		t.Fatalf("LoadTree failed: %v", err)
+	}
+	ts.SetLayout(LayoutFolder)
+
+	docsID, _ := ts.CreatePage(nil, "Docs", "docs")
+	childID, err := ts.CreatePage(docsID, "Intro", "intro")
+	if err != nil {
+		t.Fatalf("CreatePage failed: %v", err)
+	}
+	if _, err := os.Stat(filepath.Join(tmpDir, "root", "docs", "intro", "index.md")); err != nil {
+		t.Errorf("expected intro/index.md in the folder layout: %v", err)
+	}
+
+	// the parent keeps its folder when its last child is deleted
+	if err := ts.DeletePage(*childID, false); err != nil {
+		t.Fatalf("DeletePage failed: %v", err)
+	}
+	if _, err := os.Stat(filepath.Join(tmpDir, "root", "docs", "index.md")); err != nil {
+		t.Errorf("expected docs/index.md to stay: %v", err)
+	}
+}
+
+func TestTreeService_MigrateLayout(t *testing.T) {
+	tmpDir := t.TempDir()
+	ts := NewTreeService(tmpDir)
+	if err := ts.LoadTree(); err != nil {
+		t.Fatalf("LoadTree failed: %v", err)
+	}
+	docsID, _ := ts.CreatePage(nil, "Docs", "docs")
+	_, _ = ts.CreatePage(docsID, "Intro", "intro")
+	_, _ = ts.CreatePage(nil, "About", "about")
+
+	moves, err := ts.MigrateLayout(LayoutFolder)
+	if err != nil {
+		t.Fatalf("MigrateLayout failed: %v", err)
+	}
+	if len(moves) != 2 || moves["docs/intro.md"] != "docs/intro/index.md" || moves["about.md"] != "about/index.md" {
+		t.Errorf("unexpected moves: %v", moves)
+	}
+	if layout, ok, _ := LoadLayout(tmpDir); !ok || layout != LayoutFolder {
+		t.Errorf("expected the folder layout to be recorded, got %q", layout)
+	}
+	if content, err := ts.GetPage(*docsID); err != nil || content.Content == "" {
+		t.Errorf("expected the page to be readable after the migration: %v", err)
+	}
+
+	moves, err = ts.MigrateLayout(LayoutFlat)
+	if err != nil {
+		t.Fatalf("MigrateLayout failed: %v", err)
+	}
+	if len(moves) != 2 || moves["docs/intro/index.md"] != "docs/intro.md" {
+		t.Errorf("unexpected moves: %v", moves)
+	}
+	if _, err := os.Stat(filepath.Join(tmpDir, "root", "docs", "index.md")); err != nil {
+		t.Errorf("expected docs to stay a folder with children: %v", err)
+	}
+	if _, err := os.Stat(filepath.Join(tmpDir, "root", "about.md")); err != nil {
+		t.Errorf("expected about.md after migrating back: %v", err)
+	}
+}
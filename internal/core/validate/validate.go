@@ -0,0 +1,151 @@
+// Package validate finds problems of a Markdown page which the editor can point out before
+// the page is saved, because they change or break how the page is rendered
+package validate
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/Gomez12/wiki/internal/core/frontmatter"
+	"github.com/goccy/go-yaml"
+)
+
+// Rules of the warnings
+const (
+	RuleFrontmatter = "frontmatter"
+	RuleCodeFence   = "code-fence"
+	RuleTable       = "table"
+)
+
+// Warning is a problem of the page. Line is the 1-based line of the content it refers to.
+type Warning struct {
+	Rule    string `json:"rule"`
+	Line    int    `json:"line"`
+	Message string `json:"message"`
+}
+
+// Result lists the warnings of a page, empty if none were found
+type Result struct {
+	Warnings []Warning `json:"warnings"`
+}
+
+// Markdown checks the frontmatter, the code fences and the tables of the content
+func Markdown(content string) Result {
+	lines := strings.Split(strings.ReplaceAll(content, "\r\n", "\n"), "\n")
+	frontWarnings, bodyStart := checkFrontmatter(content, lines)
+	warnings := append([]Warning{}, frontWarnings...)
+	return Result{Warnings: append(warnings, checkBody(lines, bodyStart)...)}
+}
+
+// checkFrontmatter reports frontmatter which is not closed or not valid YAML and returns the
+// index of the first line of the body
+func checkFrontmatter(content string, lines []string) ([]Warning, int) {
+	if strings.TrimRight(strings.TrimPrefix(lines[0], "\ufeff"), "\r ") != "---" {
+		return nil, 0
+	}
+	front, _, ok := frontmatter.Split(content)
+	if !ok {
+		// without the closing line the block is rendered as part of the page
+		return []Warning{{Rule: RuleFrontmatter, Line: 1, Message: "frontmatter is not closed with a --- line"}}, 0
+	}
+	bodyStart := strings.Count(front, "\n") + 2
+
+	if _, _, err := frontmatter.Parse(content); err != nil {
+		warning := Warning{Rule: RuleFrontmatter, Line: 1, Message: err.Error()}
+		var yamlErr yaml.Error
+		if errors.As(err, &yamlErr) {
+			warning.Message = "invalid frontmatter: " + yamlErr.GetMessage()
+			if token := yamlErr.GetToken(); token != nil && token.Position != nil {
+				// the YAML starts on the second line
+				warning.Line = token.Position.Line + 1
+			}
+		}
+		return []Warning{warning}, bodyStart
+	}
+	return nil, bodyStart
+}
+
+var (
+	fenceRegex          = regexp.MustCompile("^ {0,3}(`{3,}|~{3,})")
+	tableDelimiterRegex = regexp.MustCompile(`^\s*\|?\s*:?-+:?\s*(\|\s*:?-+:?\s*)*\|?\s*$`)
+)
+
+// checkBody reports code fences which are not closed and tables whose rows don't have the
+// columns of the header
+func checkBody(lines []string, start int) []Warning {
+	var warnings []Warning
+	var fence string
+	fenceLine := 0
+	for i := start; i < len(lines); i++ {
+		line := lines[i]
+		if fence != "" {
+			// the closing fence uses the same character, at least as often, and nothing else
+			if match := fenceRegex.FindStringSubmatch(line); match != nil && match[1][0] == fence[0] &&
+				len(match[1]) >= len(fence) && strings.TrimSpace(line[len(match[0]):]) == "" {
+				fence = ""
+			}
+			continue
+		}
+		if match := fenceRegex.FindStringSubmatch(line); match != nil {
+			fence, fenceLine = match[1], i+1
+			continue
+		}
+
+		if i+1 < len(lines) && strings.Contains(line, "|") && strings.Contains(lines[i+1], "|") &&
+			tableDelimiterRegex.MatchString(lines[i+1]) {
+			var tableWarnings []Warning
+			tableWarnings, i = checkTable(lines, i)
+			warnings = append(warnings, tableWarnings...)
+		}
+	}
+	if fence != "" {
+		warnings = append(warnings, Warning{Rule: RuleCodeFence, Line: fenceLine,
+			Message: fmt.Sprintf("code fence %s is not closed, the rest of the page is shown as code", fence)})
+	}
+	return warnings
+}
+
+// checkTable checks the table whose header is at lines[header] and returns the index of its
+// last line
+func checkTable(lines []string, header int) ([]Warning, int) {
+	columns := tableColumns(lines[header])
+	if delimiter := tableColumns(lines[header+1]); delimiter != columns {
+		// the lines are not rendered as a table at all
+		return []Warning{{Rule: RuleTable, Line: header + 2,
+			Message: fmt.Sprintf("the header has %d columns but the delimiter row %d", columns, delimiter)}}, header + 1
+	}
+
+	var warnings []Warning
+	last := header + 1
+	for i := header + 2; i < len(lines) && strings.TrimSpace(lines[i]) != "" && strings.Contains(lines[i], "|"); i++ {
+		if cells := tableColumns(lines[i]); cells != columns {
+			warnings = append(warnings, Warning{Rule: RuleTable, Line: i + 1,
+				Message: fmt.Sprintf("the row has %d columns but the header %d", cells, columns)})
+		}
+		last = i
+	}
+	return warnings, last
+}
+
+// tableColumns counts the cells of a table row, which are separated by the pipes which are
+// not escaped; leading and trailing pipes are optional
+func tableColumns(row string) int {
+	row = strings.TrimSpace(row)
+	row = strings.TrimPrefix(row, "|")
+	if strings.HasSuffix(row, "|") && !strings.HasSuffix(row, `\|`) {
+		row = strings.TrimSuffix(row, "|")
+	}
+
+	columns := 1
+	for i := 0; i < len(row); i++ {
+		switch row[i] {
+		case '\\':
+			i++
+		case '|':
+			columns++
+		}
+	}
+	return columns
+}
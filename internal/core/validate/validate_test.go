@@ -0,0 +1,46 @@
+package validate
+
+import (
+	"fmt"
+	"testing"
+)
+
+func TestMarkdown(t *testing.T) {
+	tests := []struct {
+		name    string
+		content string
+		// want lists the warnings as rule:line
+		want []string
+	}{
+		{"valid page", "---\nstatus: done\n---\n# Title\n\n| a | b |\n|---|:-:|\n| 1 | 2 |\n\n```go\nfunc main() {}\n```\n", nil},
+		{"invalid yaml", "---\nstatus: done\ntags: [a, b\n---\n# Title\n", []string{"frontmatter:3"}},
+		{"frontmatter not a map", "---\n- a\n- b\n---\nbody", []string{"frontmatter:2"}},
+		{"unclosed frontmatter", "---\nstatus: done\n# Title\n", []string{"frontmatter:1"}},
+		{"unclosed fence", "# Title\n\n~~~\ncode\n```\n", []string{"code-fence:3"}},
+		{"longer closing fence", "````\n```\n`````\n", nil},
+		{"fence after frontmatter", "---\nstatus: done\n---\n```\ncode\n", []string{"code-fence:4"}},
+		{"table in code", "```\n| a | b |\n|---|\n```\n", nil},
+		{"delimiter columns", "| a | b |\n|---|\n| 1 | 2 |\n", []string{"table:2"}},
+		{"row columns", "a | b\n--- | ---\n1 | 2 | 3\n1\\|2 | 3\n4 | 5 |\n\nafter | text\n", []string{"table:3"}},
+		{"setext heading", "a | b\n---\n", nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result := Markdown(tt.content)
+			if result.Warnings == nil {
+				t.Fatal("expected an empty list instead of nil")
+			}
+			var got []string
+			for _, w := range result.Warnings {
+				if w.Message == "" {
+					t.Errorf("expected a message for %+v", w)
+				}
+				got = append(got, fmt.Sprintf("%s:%d", w.Rule, w.Line))
+			}
+			if fmt.Sprint(got) != fmt.Sprint(tt.want) {
+				t.Errorf("Markdown() = %v (%+v), want %v", got, result.Warnings, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,62 @@
+package api
+
+import (
+	"bytes"
+	"net/http"
+	"os"
+	"strings"
+
+	"github.com/Gomez12/wiki/internal/core/assets"
+	"github.com/Gomez12/wiki/internal/wiki"
+	"github.com/gin-gonic/gin"
+)
+
+// AssetFileHandler serves the assets at /assets/<page id>/<name>, from the assets folder
+// next to the page or from the assets dir. Like before, the assets are public so they can
+// be used in images. Range requests are supported, so embedded video and audio can seek;
+// assets from the stream threshold on are streamed from disk, smaller ones are read at once.
+func AssetFileHandler(w *wiki.Wiki) gin.HandlerFunc {
+	fileServer := http.StripPrefix("/assets", http.FileServer(gin.Dir(w.GetAssetService().GetAssetsDir(), true)))
+	return func(c *gin.Context) {
+		pageID, name, ok := strings.Cut(strings.TrimPrefix(c.Param("filepath"), "/"), "/")
+		if ok {
+			if fullPath, err := w.AssetFile(pageID, name); err == nil {
+				serveAsset(c, fullPath, name, w.StreamThreshold())
+				return
+			}
+		}
+		fileServer.ServeHTTP(c.Writer, c.Request)
+	}
+}
+
+// serveAsset serves the file with the content type of the asset name, http.ServeContent
+// handles the Range, If-Range and conditional headers
+func serveAsset(c *gin.Context, fullPath, name string, streamThreshold int64) {
+	file, err := os.Open(fullPath)
+	if err != nil {
+		c.Status(http.StatusNotFound)
+		return
+	}
+	defer file.Close()
+	info, err := file.Stat()
+	if err != nil {
+		c.Status(http.StatusInternalServerError)
+		return
+	}
+
+	if contentType := assets.ContentType(name); contentType != "" {
+		c.Header("Content-Type", contentType)
+	}
+	if info.Size() >= streamThreshold {
+		http.ServeContent(c.Writer, c.Request, name, info.ModTime(), file)
+		return
+	}
+
+	data := make([]byte, info.Size())
+	if _, err := file.ReadAt(data, 0); err != nil {
+		c.Status(http.StatusInternalServerError)
+		return
+	}
+	file.Close()
+	http.ServeContent(c.Writer, c.Request, name, info.ModTime(), bytes.NewReader(data))
+}
@@ -0,0 +1,45 @@
+package api
+
+import (
+	"net/http"
+
+	"github.com/Gomez12/wiki/internal/wiki"
+	"github.com/gin-gonic/gin"
+)
+
+// GetAssetVersionsHandler lists the current and previous versions of an asset
+func GetAssetVersionsHandler(w *wiki.Wiki) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		versions, err := w.AssetVersions(c.Param("id"), c.Param("name"))
+		if err != nil {
+			respondWithError(c, err)
+			return
+		}
+		c.JSON(http.StatusOK, versions)
+	}
+}
+
+// GetAssetVersionFileHandler serves the content of a previous version of an asset
+func GetAssetVersionFileHandler(w *wiki.Wiki) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		name := c.Param("name")
+		fullPath, err := w.AssetVersionFile(c.Param("id"), name, c.Param("hash"))
+		if err != nil {
+			respondWithError(c, err)
+			return
+		}
+		serveAsset(c, fullPath, name, w.StreamThreshold())
+	}
+}
+
+// RestoreAssetVersionHandler makes a previous version the current content of an asset
+func RestoreAssetVersionHandler(w *wiki.Wiki) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		url, err := w.RestoreAssetVersion(c.Param("id"), c.Param("name"), c.Param("hash"))
+		if err != nil {
+			respondWithError(c, err)
+			return
+		}
+		c.JSON(http.StatusOK, gin.H{"url": url})
+	}
+}
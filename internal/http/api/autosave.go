@@ -0,0 +1,70 @@
+package api
+
+import (
+	"net/http"
+
+	"github.com/Gomez12/wiki/internal/wiki"
+	"github.com/gin-gonic/gin"
+)
+
+// SaveDraftHandler stores the periodic autosave of the editor apart from the page
+func SaveDraftHandler(w *wiki.Wiki) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		user, ok := currentUser(c)
+		if !ok {
+			return
+		}
+
+		var req struct {
+			Title   string `json:"title"`
+			Content string `json:"content"`
+		}
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid payload"})
+			return
+		}
+
+		draft, err := w.SaveDraft(user.ID, c.Param("id"), req.Title, req.Content)
+		if err != nil {
+			respondWithError(c, err)
+			return
+		}
+
+		c.JSON(http.StatusOK, draft)
+	}
+}
+
+// GetDraftHandler returns the autosaved draft of the user, to recover unsaved changes
+func GetDraftHandler(w *wiki.Wiki) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		user, ok := currentUser(c)
+		if !ok {
+			return
+		}
+
+		draft, err := w.GetDraft(user.ID, c.Param("id"))
+		if err != nil {
+			respondWithError(c, err)
+			return
+		}
+
+		c.JSON(http.StatusOK, draft)
+	}
+}
+
+// DiscardDraftHandler drops the autosaved draft of the user
+func DiscardDraftHandler(w *wiki.Wiki) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		user, ok := currentUser(c)
+		if !ok {
+			return
+		}
+
+		if err := w.DiscardDraft(user.ID, c.Param("id")); err != nil {
+			respondWithError(c, err)
+			return
+		}
+
+		c.Status(http.StatusNoContent)
+	}
+}
@@ -0,0 +1,53 @@
+package api
+
+import (
+	"net/http"
+	"strconv"
+
+	"github.com/Gomez12/wiki/internal/core/badge"
+	"github.com/Gomez12/wiki/internal/wiki"
+	"github.com/gin-gonic/gin"
+)
+
+// BadgeHandler renders a wiki statistic as badge.
+// Supported badges are "pages", "updated" and "search".
+// The badge is returned as SVG, or in the shields.io endpoint format with ?format=json.
+func BadgeHandler(w *wiki.Wiki) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		stats := w.GetStats()
+
+		var b badge.Badge
+		switch c.Param("name") {
+		case "pages":
+			b = badge.Badge{Label: "pages", Message: strconv.Itoa(stats.PageCount), Color: badge.ColorBlue}
+		case "updated":
+			b = badge.Badge{Label: "last updated", Message: "never", Color: badge.ColorGrey}
+			if !stats.LastUpdated.IsZero() {
+				b.Message = stats.LastUpdated.UTC().Format("2006-01-02")
+				b.Color = badge.ColorGreen
+			}
+		case "search":
+			b = badge.Badge{Label: "search", Message: "ready", Color: badge.ColorGreen}
+			switch {
+			case stats.Indexing.Active:
+				b.Message = "indexing"
+				b.Color = badge.ColorYellow
+			case stats.Indexing.Failed > 0:
+				b.Message = strconv.Itoa(stats.Indexing.Failed) + " failed"
+				b.Color = badge.ColorRed
+			}
+		default:
+			c.JSON(http.StatusNotFound, gin.H{"error": "Badge not found"})
+			return
+		}
+
+		// Badges are embedded in other pages, keep them fresh but cacheable
+		c.Header("Cache-Control", "public, max-age=300")
+
+		if c.Query("format") == "json" {
+			c.JSON(http.StatusOK, b.ToEndpoint())
+			return
+		}
+		c.Data(http.StatusOK, "image/svg+xml; charset=utf-8", []byte(b.SVG()))
+	}
+}
@@ -0,0 +1,31 @@
+package api
+
+import (
+	"net/http"
+	"strconv"
+
+	"github.com/Gomez12/wiki/internal/wiki"
+	"github.com/gin-gonic/gin"
+)
+
+// GetDuplicatePagesHandler reports the pages with identical or near-identical content
+func GetDuplicatePagesHandler(w *wiki.Wiki) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		threshold := wiki.DefaultDuplicateThreshold
+		if value := c.Query("threshold"); value != "" {
+			parsed, err := strconv.ParseFloat(value, 64)
+			if err != nil {
+				c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid threshold value"})
+				return
+			}
+			threshold = parsed
+		}
+
+		report, err := w.DuplicatePages(threshold)
+		if err != nil {
+			respondWithError(c, err)
+			return
+		}
+		c.JSON(http.StatusOK, report)
+	}
+}
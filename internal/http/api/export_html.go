@@ -0,0 +1,29 @@
+package api
+
+import (
+	"archive/zip"
+	"net/http"
+
+	"github.com/Gomez12/wiki/internal/core/staticsite"
+	"github.com/Gomez12/wiki/internal/wiki"
+	"github.com/gin-gonic/gin"
+)
+
+// ExportHTMLHandler streams the wiki as static HTML site in a zip archive.
+// An error while streaming leaves a truncated archive, so clients notice it.
+func ExportHTMLHandler(w *wiki.Wiki) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		c.Header("Content-Type", "application/zip")
+		c.Header("Content-Disposition", `attachment; filename="leafwiki-site.zip"`)
+		c.Status(http.StatusOK)
+
+		zw := zip.NewWriter(c.Writer)
+		if _, err := w.ExportHTML(c.Request.Context(), staticsite.NewZipWriter(zw)); err != nil {
+			_ = c.Error(err)
+			return
+		}
+		if err := zw.Close(); err != nil {
+			_ = c.Error(err)
+		}
+	}
+}
@@ -0,0 +1,26 @@
+package api
+
+import (
+	"net/http"
+
+	"github.com/Gomez12/wiki/internal/wiki"
+	"github.com/gin-gonic/gin"
+)
+
+// ExportPageHandler renders a page as downloadable file.
+// Query parameters:
+//   - format: pdf (default), md, html or docx. Markdown and HTML exports of pages with
+//     assets are zipped together with the assets
+//   - recursive=true: include the pages below, with a table of contents (pdf only)
+func ExportPageHandler(w *wiki.Wiki) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		file, err := w.ExportPage(c.Request.Context(), c.Param("id"), c.DefaultQuery("format", "pdf"), c.Query("recursive") == "true")
+		if err != nil {
+			respondWithError(c, err)
+			return
+		}
+
+		c.Header("Content-Disposition", `attachment; filename="`+file.Filename+`"`)
+		c.Data(http.StatusOK, file.ContentType, file.Data)
+	}
+}
@@ -0,0 +1,66 @@
+package api
+
+import (
+	"net/http"
+
+	"github.com/Gomez12/wiki/internal/wiki"
+	"github.com/gin-gonic/gin"
+)
+
+func GetFavoritesHandler(w *wiki.Wiki) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		user, ok := currentUser(c)
+		if !ok {
+			return
+		}
+
+		list, err := w.GetFavorites(user.ID)
+		if err != nil {
+			respondWithError(c, err)
+			return
+		}
+
+		c.JSON(http.StatusOK, list)
+	}
+}
+
+func AddFavoriteHandler(w *wiki.Wiki) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		user, ok := currentUser(c)
+		if !ok {
+			return
+		}
+
+		var req struct {
+			PageID string `json:"pageId" binding:"required"`
+		}
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid payload"})
+			return
+		}
+
+		fav, err := w.AddFavorite(user.ID, req.PageID)
+		if err != nil {
+			respondWithError(c, err)
+			return
+		}
+
+		c.JSON(http.StatusCreated, fav)
+	}
+}
+
+func RemoveFavoriteHandler(w *wiki.Wiki) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		user, ok := currentUser(c)
+		if !ok {
+			return
+		}
+
+		if err := w.RemoveFavorite(user.ID, c.Param("pageId")); err != nil {
+			respondWithError(c, err)
+			return
+		}
+
+		c.Status(http.StatusNoContent)
+	}
+}
@@ -0,0 +1,29 @@
+package api
+
+import (
+	"net/http"
+
+	"github.com/Gomez12/wiki/internal/wiki"
+	"github.com/gin-gonic/gin"
+)
+
+// GetHistoryHandler returns the history of all files below the directory given by path, e.g. docs/
+func GetHistoryHandler(w *wiki.Wiki) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		opts, ok := parseHistoryOptions(c)
+		if !ok {
+			return
+		}
+
+		history, total, err := w.GetSubtreeHistory(c.Query("path"), opts)
+		if err != nil {
+			respondWithError(c, err)
+			return
+		}
+
+		c.JSON(http.StatusOK, gin.H{
+			"history": history,
+			"total":   total,
+		})
+	}
+}
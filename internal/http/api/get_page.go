@@ -16,12 +16,27 @@ func GetPageHandler(w *wiki.Wiki) gin.HandlerFunc {
 			return
 		}
 
+		asOf, ok := parseAsOf(c)
+		if !ok {
+			return
+		}
+		if asOf != nil {
+			page, err := w.PageAsOf(id, *asOf)
+			if err != nil {
+				respondWithError(c, err)
+				return
+			}
+			respondWithETag(c, ToAPIPage(page))
+			return
+		}
+
 		page, err := w.GetPage(id)
 		if err != nil {
 			c.JSON(http.StatusNotFound, gin.H{"error": "page not found"})
 			return
 		}
 
-		c.JSON(http.StatusOK, ToAPIPage(page))
+		recordVisit(c, w, page.ID)
+		respondWithETag(c, ToAPIPage(page))
 	}
 }
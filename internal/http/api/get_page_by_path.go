@@ -1,8 +1,11 @@
 package api
 
 import (
+	"errors"
 	"net/http"
 
+	"github.com/Gomez12/wiki/internal/core/auth"
+	"github.com/Gomez12/wiki/internal/core/tree"
 	"github.com/Gomez12/wiki/internal/wiki"
 	"github.com/gin-gonic/gin"
 )
@@ -15,12 +18,44 @@ func GetPageByPathHandler(w *wiki.Wiki) gin.HandlerFunc {
 			return
 		}
 
+		asOf, ok := parseAsOf(c)
+		if !ok {
+			return
+		}
+		if asOf != nil {
+			page, err := w.FindByPathAsOf(path, *asOf)
+			if err != nil {
+				respondWithError(c, err)
+				return
+			}
+			respondWithETag(c, ToAPIPage(page))
+			return
+		}
+
 		page, err := w.FindByPath(path)
+		if errors.Is(err, tree.ErrPageNotFound) {
+			// The user is only set on authenticated routes, their favorites are ranked higher
+			var user *auth.User
+			if userValue, exists := c.Get("user"); exists {
+				user, _ = userValue.(*auth.User)
+			}
+			body := gin.H{
+				"error":       "Page not found",
+				"suggestions": w.SuggestPagesForUser(user, path),
+			}
+			// the page was moved or renamed, clients can follow the redirect
+			if redirect, err := w.ResolveRedirect(path); err == nil {
+				body["redirect"] = redirect
+			}
+			c.JSON(http.StatusNotFound, body)
+			return
+		}
 		if err != nil {
 			respondWithError(c, err)
 			return
 		}
 
-		c.JSON(http.StatusOK, ToAPIPage(page))
+		recordVisit(c, w, page.ID)
+		respondWithETag(c, ToAPIPage(page))
 	}
 }
@@ -2,7 +2,9 @@ package api
 
 import (
 	"net/http"
+	"strconv"
 
+	"github.com/Gomez12/wiki/internal/search"
 	"github.com/Gomez12/wiki/internal/wiki"
 	"github.com/gin-gonic/gin"
 )
@@ -15,7 +17,12 @@ func GetPageHistoryHandler(w *wiki.Wiki) gin.HandlerFunc {
 			return
 		}
 
-		history, currentHash, err := w.GetPageHistory(path)
+		opts, ok := parseHistoryOptions(c)
+		if !ok {
+			return
+		}
+
+		history, total, currentHash, err := w.GetPageHistory(path, opts)
 		if err != nil {
 			respondWithError(c, err)
 			return
@@ -23,7 +30,54 @@ func GetPageHistoryHandler(w *wiki.Wiki) gin.HandlerFunc {
 
 		c.JSON(http.StatusOK, gin.H{
 			"history":     history,
+			"total":       total,
 			"currentHash": currentHash,
 		})
 	}
 }
+
+func GetPageHistoryEntryHandler(w *wiki.Wiki) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		id, err := strconv.ParseInt(c.Param("entryId"), 10, 64)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid history entry id"})
+			return
+		}
+
+		entry, err := w.GetPageHistoryEntry(id)
+		if err != nil {
+			respondWithError(c, err)
+			return
+		}
+
+		c.JSON(http.StatusOK, entry)
+	}
+}
+
+// parseHistoryOptions reads the paging and filter query parameters and responds with 400 if they are invalid
+func parseHistoryOptions(c *gin.Context) (search.HistoryOptions, bool) {
+	opts := search.HistoryOptions{
+		IncludeContent: c.DefaultQuery("content", "true") != "false",
+		Status:         search.FileHistoryStatus(c.Query("status")),
+	}
+
+	if limitStr := c.Query("limit"); limitStr != "" {
+		limit, err := strconv.Atoi(limitStr)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid limit value"})
+			return opts, false
+		}
+		opts.Limit = limit
+	}
+
+	if offsetStr := c.Query("offset"); offsetStr != "" {
+		offset, err := strconv.Atoi(offsetStr)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid offset value"})
+			return opts, false
+		}
+		opts.Offset = offset
+	}
+
+	return opts, true
+}
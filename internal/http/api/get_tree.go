@@ -2,14 +2,74 @@ package api
 
 import (
 	"net/http"
+	"strconv"
 
+	"github.com/Gomez12/wiki/internal/core/tree"
 	"github.com/Gomez12/wiki/internal/wiki"
 	"github.com/gin-gonic/gin"
 )
 
+// GetTreeHandler returns the page tree.
+// Query parameters:
+//   - path: return the subtree of the page with this route path instead of the whole tree
+//   - depth: limit the number of descendant levels, e.g. depth=1 returns the node with its direct children
+//   - children=true: return only the direct children of the node as a list
+//   - asOf: reconstruct the tree as it was at this time from the page history, see wiki.TreeAsOf
 func GetTreeHandler(w *wiki.Wiki) gin.HandlerFunc {
 	return func(c *gin.Context) {
-		tree := w.GetTree()
-		c.JSON(http.StatusOK, ToAPINode(tree, ""))
+		depth := -1
+		if depthStr := c.Query("depth"); depthStr != "" {
+			d, err := strconv.Atoi(depthStr)
+			if err != nil || d < 0 {
+				c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid depth value"})
+				return
+			}
+			depth = d
+		}
+
+		asOf, ok := parseAsOf(c)
+		if !ok {
+			return
+		}
+
+		node := w.GetTree()
+		if asOf != nil {
+			historic, err := w.TreeAsOf(*asOf)
+			if err != nil {
+				respondWithError(c, err)
+				return
+			}
+			node = historic
+		}
+		if path := c.Query("path"); path != "" {
+			findByPath := w.FindByPath
+			if asOf != nil {
+				findByPath = func(route string) (*tree.Page, error) {
+					return w.FindByPathAsOf(route, *asOf)
+				}
+			}
+			page, err := findByPath(path)
+			if err != nil {
+				respondWithError(c, err)
+				return
+			}
+			node = page.PageNode
+		}
+
+		parentPath := ""
+		if node.Parent != nil {
+			parentPath = buildPathFromNode(node.Parent)
+		}
+
+		if c.Query("children") == "true" {
+			children := make([]*Node, 0, len(node.Children))
+			for _, child := range node.Children {
+				children = append(children, ToAPINodeWithDepth(child, buildPathFromNode(node), 0))
+			}
+			respondWithETag(c, children)
+			return
+		}
+
+		respondWithETag(c, ToAPINodeWithDepth(node, parentPath, depth))
 	}
 }
@@ -2,19 +2,62 @@ package api
 
 import (
 	"net/http"
+	"strconv"
 
+	"github.com/Gomez12/wiki/internal/core/auth"
 	"github.com/Gomez12/wiki/internal/wiki"
 	"github.com/gin-gonic/gin"
 )
 
+// defaultUserListLimit is the page size if no limit is given
+const defaultUserListLimit = 50
+
+// GetUsersHandler returns a page of users.
+// Query parameters: q (username or email), sort (username, email, role, created),
+// order (asc, desc), limit and offset.
 func GetUsersHandler(wikiInstance *wiki.Wiki) gin.HandlerFunc {
 	return func(c *gin.Context) {
-		users, err := wikiInstance.GetUsers()
+		opts := auth.UserListOptions{
+			Query:      c.Query("q"),
+			Sort:       c.Query("sort"),
+			Descending: c.Query("order") == "desc",
+			Limit:      defaultUserListLimit,
+		}
+
+		if order := c.Query("order"); order != "" && order != "asc" && order != "desc" {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid order value"})
+			return
+		}
+
+		if limitStr := c.Query("limit"); limitStr != "" {
+			limit, err := strconv.Atoi(limitStr)
+			if err != nil {
+				c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid limit value"})
+				return
+			}
+			opts.Limit = limit
+		}
+
+		if offsetStr := c.Query("offset"); offsetStr != "" {
+			offset, err := strconv.Atoi(offsetStr)
+			if err != nil {
+				c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid offset value"})
+				return
+			}
+			opts.Offset = offset
+		}
+
+		users, total, err := wikiInstance.ListUsers(opts)
 		if err != nil {
-			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to load users"})
+			respondWithError(c, err)
 			return
 		}
 
-		c.JSON(http.StatusOK, users)
+		c.JSON(http.StatusOK, gin.H{
+			"users":  users,
+			"total":  total,
+			"limit":  opts.Limit,
+			"offset": opts.Offset,
+		})
 	}
 }
@@ -0,0 +1,41 @@
+package api
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/Gomez12/wiki/internal/core/gitsync"
+	"github.com/Gomez12/wiki/internal/wiki"
+	"github.com/gin-gonic/gin"
+)
+
+// GetGitSyncStatusHandler returns the outcome of the last sync with the git remote
+func GetGitSyncStatusHandler(wikiInstance *wiki.Wiki) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		status, err := wikiInstance.GitSyncStatus()
+		if err != nil {
+			respondWithError(c, err)
+			return
+		}
+		c.JSON(http.StatusOK, status)
+	}
+}
+
+// SyncGitHandler syncs with the git remote right away and returns the outcome.
+// Conflicts which stopped the sync are listed with 409.
+func SyncGitHandler(wikiInstance *wiki.Wiki) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		status, err := wikiInstance.SyncGit(c.Request.Context())
+		switch {
+		case errors.Is(err, gitsync.ErrConflict):
+			c.JSON(http.StatusConflict, status)
+		case errors.Is(err, wiki.ErrGitSyncDisabled):
+			respondWithError(c, err)
+		case err != nil:
+			_ = c.Error(err)
+			c.JSON(http.StatusBadGateway, status)
+		default:
+			c.JSON(http.StatusOK, status)
+		}
+	}
+}
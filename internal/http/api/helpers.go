@@ -1,16 +1,35 @@
 package api
 
 import (
+	"context"
 	"errors"
 	"net/http"
 	"strings"
+	"time"
 
+	"github.com/Gomez12/wiki/internal/core/assets"
+	"github.com/Gomez12/wiki/internal/core/auth"
+	"github.com/Gomez12/wiki/internal/core/drafts"
+	"github.com/Gomez12/wiki/internal/core/favorites"
+	"github.com/Gomez12/wiki/internal/core/frontmatter"
+	"github.com/Gomez12/wiki/internal/core/importer"
+	"github.com/Gomez12/wiki/internal/core/linkcheck"
+	"github.com/Gomez12/wiki/internal/core/reading"
+	"github.com/Gomez12/wiki/internal/core/redirects"
+	"github.com/Gomez12/wiki/internal/core/savedsearch"
 	verrors "github.com/Gomez12/wiki/internal/core/shared/errors"
+	"github.com/Gomez12/wiki/internal/core/spellcheck"
 	"github.com/Gomez12/wiki/internal/core/tree"
+	"github.com/Gomez12/wiki/internal/search"
+	"github.com/Gomez12/wiki/internal/wiki"
 	"github.com/gin-gonic/gin"
 )
 
 func respondWithError(c *gin.Context, err error) {
+	if respondIfTimedOut(c) {
+		return
+	}
+
 	var vErr *verrors.ValidationErrors
 	if errors.As(err, &vErr) {
 		c.JSON(http.StatusBadRequest, gin.H{
@@ -35,16 +54,118 @@ func respondWithError(c *gin.Context, err error) {
 		c.JSON(http.StatusBadRequest, gin.H{"error": "Move would create a circular reference"})
 	case errors.Is(err, tree.ErrPageCannotBeMovedToItself):
 		c.JSON(http.StatusBadRequest, gin.H{"error": "Page cannot be moved to itself"})
+	case errors.Is(err, assets.ErrAssetNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": "Asset not found"})
+	case errors.Is(err, assets.ErrAssetExists):
+		c.JSON(http.StatusConflict, gin.H{"error": "Asset already exists"})
+	case errors.Is(err, assets.ErrAssetVersionNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": "Asset version not found"})
+	case errors.Is(err, assets.ErrInvalidAssetName):
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid asset name"})
+	case errors.Is(err, reading.ErrPositionNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": "Reading position not found"})
+	case errors.Is(err, favorites.ErrFavoriteNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": "Favorite not found"})
+	case errors.Is(err, drafts.ErrDraftNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": "Draft not found"})
+	case errors.Is(err, wiki.ErrDraftTooLarge):
+		c.JSON(http.StatusRequestEntityTooLarge, gin.H{"error": "Draft too large"})
+	case errors.Is(err, savedsearch.ErrSavedSearchNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": "Saved search not found"})
+	case errors.Is(err, search.ErrHistoryEntryNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": "History entry not found"})
+	case errors.Is(err, importer.ErrSourceNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": "Import source directory not found"})
+	case errors.Is(err, importer.ErrInvalidSourcePath):
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Import source path is outside the source directory"})
+	case errors.Is(err, importer.ErrInvalidArchive):
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Import archive is not a valid zip file"})
+	case errors.Is(err, importer.ErrArchiveTooLarge):
+		c.JSON(http.StatusRequestEntityTooLarge, gin.H{"error": "Import archive too large"})
+	case errors.Is(err, wiki.ErrNothingToUndo):
+		c.JSON(http.StatusConflict, gin.H{"error": "No previous version to restore"})
+	case errors.Is(err, search.ErrHistoryLabelNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": "History label not found"})
+	case errors.Is(err, search.ErrHistoryLabelExists):
+		c.JSON(http.StatusConflict, gin.H{"error": "History label already exists"})
+	case errors.Is(err, search.ErrIndexingInProgress):
+		c.JSON(http.StatusConflict, gin.H{"error": "Indexing is already in progress"})
+	case errors.Is(err, redirects.ErrRedirectNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": "Redirect not found"})
+	case errors.Is(err, linkcheck.ErrRunning):
+		c.JSON(http.StatusConflict, gin.H{"error": "Link check is already running"})
+	case errors.Is(err, wiki.ErrVaultFileNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": "File not found"})
+	case errors.Is(err, wiki.ErrSpellcheckDisabled):
+		c.JSON(http.StatusNotFound, gin.H{"error": "Spellcheck is not configured"})
+	case errors.Is(err, spellcheck.ErrWordNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": "Word not found"})
+	case errors.Is(err, wiki.ErrSetupCompleted):
+		c.JSON(http.StatusConflict, gin.H{"error": "Setup is already completed"})
+	case errors.Is(err, wiki.ErrConfigReloadDisabled):
+		c.JSON(http.StatusNotFound, gin.H{"error": "No config file is configured"})
+	case errors.Is(err, wiki.ErrGitSyncDisabled):
+		c.JSON(http.StatusNotFound, gin.H{"error": "Git sync is not configured"})
+	case errors.Is(err, wiki.ErrShuttingDown):
+		c.JSON(http.StatusServiceUnavailable, gin.H{"error": "Server is shutting down"})
 	default:
+		// attached to the context, so the request log contains the error
+		_ = c.Error(err)
 		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 	}
 }
 
+// respondIfTimedOut responds with 504 if the timeout of the route cancelled the request. The
+// cancelled work ends with all kinds of errors, so the context is checked instead of the error.
+func respondIfTimedOut(c *gin.Context) bool {
+	if !errors.Is(c.Request.Context().Err(), context.DeadlineExceeded) {
+		return false
+	}
+	c.JSON(http.StatusGatewayTimeout, gin.H{"error": "Request timed out"})
+	return true
+}
+
+// parseAsOf reads the asOf query parameter, an RFC 3339 timestamp or a date meaning the start
+// of that day in UTC. It returns nil if the parameter is not set and responds with 400 if it is invalid.
+func parseAsOf(c *gin.Context) (*time.Time, bool) {
+	value := c.Query("asOf")
+	if value == "" {
+		return nil, true
+	}
+	asOf, err := time.Parse(time.RFC3339, value)
+	if err != nil {
+		asOf, err = time.Parse(time.DateOnly, value)
+	}
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid asOf value, expected an RFC 3339 timestamp or a date"})
+		return nil, false
+	}
+	return &asOf, true
+}
+
+// currentUser returns the authenticated user and responds with 401 if there is none
+func currentUser(c *gin.Context) (*auth.User, bool) {
+	userValue, exists := c.Get("user")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not authenticated"})
+		return nil, false
+	}
+	user, ok := userValue.(*auth.User)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid user"})
+		return nil, false
+	}
+	return user, true
+}
+
 func ToAPIPage(p *tree.Page) *Page {
+	// invalid frontmatter is returned as empty map, the content still shows it
+	fields, _, _ := frontmatter.Parse(p.Content)
 	return &Page{
-		PageNode: p.PageNode,
-		Content:  p.Content,
-		Path:     buildPathFromNode(p.PageNode),
+		PageNode:    p.PageNode,
+		Content:     p.Content,
+		Path:        buildPathFromNode(p.PageNode),
+		Frontmatter: fields,
 	}
 }
 
@@ -59,6 +180,12 @@ func buildPathFromNode(node *tree.PageNode) string {
 }
 
 func ToAPINode(node *tree.PageNode, parentPath string) *Node {
+	return ToAPINodeWithDepth(node, parentPath, -1)
+}
+
+// ToAPINodeWithDepth converts a node and its descendants up to the given depth.
+// A negative depth includes all descendants, a depth of 0 only the node itself.
+func ToAPINodeWithDepth(node *tree.PageNode, parentPath string, depth int) *Node {
 	path := node.Slug
 
 	if node.Slug == "root" {
@@ -70,15 +197,20 @@ func ToAPINode(node *tree.PageNode, parentPath string) *Node {
 	}
 
 	apiNode := &Node{
-		ID:       node.ID,
-		Title:    node.Title,
-		Slug:     node.Slug,
-		Path:     path,
-		Position: node.Position,
+		ID:          node.ID,
+		Title:       node.Title,
+		Slug:        node.Slug,
+		Path:        path,
+		Position:    node.Position,
+		HasChildren: len(node.Children) > 0,
+	}
+
+	if depth == 0 {
+		return apiNode
 	}
 
 	for _, child := range node.Children {
-		apiNode.Children = append(apiNode.Children, ToAPINode(child, path))
+		apiNode.Children = append(apiNode.Children, ToAPINodeWithDepth(child, path, depth-1))
 	}
 
 	return apiNode
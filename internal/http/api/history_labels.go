@@ -0,0 +1,74 @@
+package api
+
+import (
+	"net/http"
+	"strconv"
+
+	"github.com/Gomez12/wiki/internal/wiki"
+	"github.com/gin-gonic/gin"
+)
+
+type labelHistoryEntryRequest struct {
+	Label string `json:"label" binding:"required"`
+}
+
+func LabelHistoryEntryHandler(w *wiki.Wiki) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid history entry id"})
+			return
+		}
+
+		var req labelHistoryEntryRequest
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid request"})
+			return
+		}
+
+		label, err := w.LabelHistoryEntry(id, req.Label)
+		if err != nil {
+			respondWithError(c, err)
+			return
+		}
+
+		c.JSON(http.StatusCreated, label)
+	}
+}
+
+func GetPageLabelsHandler(w *wiki.Wiki) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		path := c.Query("path")
+		if path == "" {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "missing path"})
+			return
+		}
+
+		labels, err := w.GetPageLabels(path)
+		if err != nil {
+			respondWithError(c, err)
+			return
+		}
+
+		c.JSON(http.StatusOK, gin.H{"labels": labels})
+	}
+}
+
+// GetPageAtLabelHandler returns the snapshot of a page as of a label
+func GetPageAtLabelHandler(w *wiki.Wiki) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		path := c.Query("path")
+		if path == "" {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "missing path"})
+			return
+		}
+
+		entry, err := w.GetPageAtLabel(path, c.Param("label"))
+		if err != nil {
+			respondWithError(c, err)
+			return
+		}
+
+		c.JSON(http.StatusOK, entry)
+	}
+}
@@ -0,0 +1,98 @@
+package api
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/Gomez12/wiki/internal/core/importer"
+	"github.com/Gomez12/wiki/internal/wiki"
+	"github.com/gin-gonic/gin"
+)
+
+type analyzeImportRequest struct {
+	SourceDir string `json:"sourceDir" binding:"required"`
+}
+
+type applyImportRequest struct {
+	ParentID *string        `json:"parentId"` // optional
+	Plan     *importer.Plan `json:"plan" binding:"required"`
+}
+
+func AnalyzeImportHandler(w *wiki.Wiki) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var req analyzeImportRequest
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid request"})
+			return
+		}
+
+		plan, err := w.AnalyzeImport(req.SourceDir)
+		if err != nil {
+			respondWithError(c, err)
+			return
+		}
+
+		c.JSON(http.StatusOK, plan)
+	}
+}
+
+// UploadImportHandler extracts an uploaded zip archive and returns the plan to import it. Like
+// the plan of a folder, it is imported with ApplyImportHandler.
+func UploadImportHandler(w *wiki.Wiki) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		maxUploadSize := w.MaxUploadSize()
+		if c.Request.ContentLength > maxUploadSize+uploadOverhead {
+			c.JSON(http.StatusRequestEntityTooLarge, gin.H{"error": "file too large"})
+			return
+		}
+		c.Request.Body = http.MaxBytesReader(c.Writer, c.Request.Body, maxUploadSize+uploadOverhead)
+
+		if err := c.Request.ParseMultipartForm(uploadMemory); err != nil {
+			var tooLarge *http.MaxBytesError
+			if errors.As(err, &tooLarge) {
+				c.JSON(http.StatusRequestEntityTooLarge, gin.H{"error": "file too large"})
+				return
+			}
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid multipart form"})
+			return
+		}
+		defer c.Request.MultipartForm.RemoveAll()
+
+		file, header, err := c.Request.FormFile("file")
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "missing file"})
+			return
+		}
+		defer file.Close()
+		if header.Size > maxUploadSize {
+			c.JSON(http.StatusRequestEntityTooLarge, gin.H{"error": "file too large"})
+			return
+		}
+
+		plan, err := w.ImportArchive(file, header.Size)
+		if err != nil {
+			respondWithError(c, err)
+			return
+		}
+
+		c.JSON(http.StatusOK, plan)
+	}
+}
+
+func ApplyImportHandler(w *wiki.Wiki) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var req applyImportRequest
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid request"})
+			return
+		}
+
+		report, err := w.ApplyImport(req.Plan, req.ParentID)
+		if err != nil {
+			respondWithError(c, err)
+			return
+		}
+
+		c.JSON(http.StatusCreated, report)
+	}
+}
@@ -0,0 +1,22 @@
+package api
+
+import (
+	"net/http"
+
+	"github.com/Gomez12/wiki/internal/wiki"
+	"github.com/gin-gonic/gin"
+)
+
+// GetIntegrityReportHandler returns the result of the last integrity check of the search database
+func GetIntegrityReportHandler(wikiInstance *wiki.Wiki) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		c.JSON(http.StatusOK, wikiInstance.LastIntegrityCheck())
+	}
+}
+
+// CheckIntegrityHandler checks the search database now and repairs it if it is damaged
+func CheckIntegrityHandler(wikiInstance *wiki.Wiki) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		c.JSON(http.StatusOK, wikiInstance.CheckIntegrity())
+	}
+}
@@ -0,0 +1,32 @@
+package api
+
+import (
+	"net/http"
+
+	"github.com/Gomez12/wiki/internal/wiki"
+	"github.com/gin-gonic/gin"
+)
+
+// GetLinkReportHandler returns the dead external links found by the last check
+func GetLinkReportHandler(wikiInstance *wiki.Wiki) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		report, err := wikiInstance.GetLinkReport()
+		if err != nil {
+			respondWithError(c, err)
+			return
+		}
+		c.JSON(http.StatusOK, report)
+	}
+}
+
+// CheckLinksHandler starts checking the external links in the background
+func CheckLinksHandler(wikiInstance *wiki.Wiki) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		report, err := wikiInstance.CheckLinks()
+		if err != nil {
+			respondWithError(c, err)
+			return
+		}
+		c.JSON(http.StatusAccepted, report)
+	}
+}
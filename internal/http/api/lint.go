@@ -0,0 +1,22 @@
+package api
+
+import (
+	"net/http"
+
+	"github.com/Gomez12/wiki/internal/wiki"
+	"github.com/gin-gonic/gin"
+)
+
+// GetPageLintHandler checks the saved content of a page with the lint rules enabled in the
+// settings, e.g. for a missing level 1 heading or broken links to other pages
+func GetPageLintHandler(w *wiki.Wiki) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		result, err := w.LintPage(c.Param("id"))
+		if err != nil {
+			respondWithError(c, err)
+			return
+		}
+
+		c.JSON(http.StatusOK, result)
+	}
+}
@@ -0,0 +1,30 @@
+package api
+
+import (
+	"net/http"
+
+	"github.com/Gomez12/wiki/internal/wiki"
+	"github.com/gin-gonic/gin"
+)
+
+// MoveAssetHandler renames an asset or moves it to another page and rewrites the links to it
+func MoveAssetHandler(w *wiki.Wiki) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var req struct {
+			Name   string `json:"name"`
+			PageID string `json:"pageId"`
+		}
+		if err := c.ShouldBindJSON(&req); err != nil || (req.Name == "" && req.PageID == "") {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid payload"})
+			return
+		}
+
+		url, err := w.MoveAsset(c.Param("id"), c.Param("name"), req.PageID, req.Name)
+		if err != nil {
+			respondWithError(c, err)
+			return
+		}
+
+		c.JSON(http.StatusOK, gin.H{"url": url})
+	}
+}
@@ -0,0 +1,20 @@
+package api
+
+import (
+	"net/http"
+
+	"github.com/Gomez12/wiki/internal/wiki"
+	"github.com/gin-gonic/gin"
+)
+
+func CheckMovePageHandler(w *wiki.Wiki) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		check, err := w.CheckMove(c.Param("id"), c.Query("parentId"))
+		if err != nil {
+			respondWithError(c, err)
+			return
+		}
+
+		c.JSON(http.StatusOK, check)
+	}
+}
@@ -1,10 +1,12 @@
 package api
 
 type Node struct {
-	ID       string  `json:"id"`
-	Title    string  `json:"title"`
-	Slug     string  `json:"slug"`
-	Path     string  `json:"path"`
-	Position int     `json:"position"`
-	Children []*Node `json:"children"`
+	ID       string `json:"id"`
+	Title    string `json:"title"`
+	Slug     string `json:"slug"`
+	Path     string `json:"path"`
+	Position int    `json:"position"`
+	// HasChildren is set even if the children are not included because of a depth limit
+	HasChildren bool    `json:"hasChildren"`
+	Children    []*Node `json:"children"`
 }
@@ -6,4 +6,6 @@ type Page struct {
 	*tree.PageNode
 	Content string `json:"content"`
 	Path    string `json:"path"`
+	// Frontmatter holds the fields of the YAML frontmatter of the content, empty if it has none
+	Frontmatter map[string]any `json:"frontmatter"`
 }
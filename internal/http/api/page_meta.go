@@ -0,0 +1,20 @@
+package api
+
+import (
+	"net/http"
+
+	"github.com/Gomez12/wiki/internal/wiki"
+	"github.com/gin-gonic/gin"
+)
+
+func GetPageMetaHandler(w *wiki.Wiki) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		meta, err := w.GetPageMeta(c.Param("id"))
+		if err != nil {
+			respondWithError(c, err)
+			return
+		}
+
+		c.JSON(http.StatusOK, meta)
+	}
+}
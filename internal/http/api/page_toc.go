@@ -0,0 +1,20 @@
+package api
+
+import (
+	"net/http"
+
+	"github.com/Gomez12/wiki/internal/wiki"
+	"github.com/gin-gonic/gin"
+)
+
+func GetPageTOCHandler(w *wiki.Wiki) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		contents, err := w.GetPageTOC(c.Param("id"))
+		if err != nil {
+			respondWithError(c, err)
+			return
+		}
+
+		c.JSON(http.StatusOK, contents)
+	}
+}
@@ -0,0 +1,39 @@
+package api
+
+import (
+	"net/http"
+
+	"github.com/Gomez12/wiki/internal/core/auth"
+	"github.com/Gomez12/wiki/internal/wiki"
+	"github.com/gin-gonic/gin"
+)
+
+func GetPasswordPolicyHandler(w *wiki.Wiki) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		policy, err := w.GetPasswordPolicy()
+		if err != nil {
+			respondWithError(c, err)
+			return
+		}
+
+		c.JSON(http.StatusOK, policy)
+	}
+}
+
+func UpdatePasswordPolicyHandler(w *wiki.Wiki) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var req auth.PasswordPolicy
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid request"})
+			return
+		}
+
+		policy, err := w.UpdatePasswordPolicy(req)
+		if err != nil {
+			respondWithError(c, err)
+			return
+		}
+
+		c.JSON(http.StatusOK, policy)
+	}
+}
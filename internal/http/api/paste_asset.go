@@ -0,0 +1,35 @@
+package api
+
+import (
+	"io"
+	"net/http"
+
+	"github.com/Gomez12/wiki/internal/wiki"
+	"github.com/gin-gonic/gin"
+)
+
+// PasteAssetHandler stores an image pasted into the editor, sent as raw bytes or data URL,
+// and returns the Markdown which embeds it
+func PasteAssetHandler(w *wiki.Wiki) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		// a data URL is a third larger than the image it encodes
+		maxPasteSize := w.MaxPasteSize()
+		body := http.MaxBytesReader(c.Writer, c.Request.Body, maxPasteSize+maxPasteSize/3+1024)
+		data, err := io.ReadAll(body)
+		if err != nil {
+			c.JSON(http.StatusRequestEntityTooLarge, gin.H{"error": "file too large"})
+			return
+		}
+		if len(data) == 0 {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "missing image"})
+			return
+		}
+
+		pasted, err := w.PasteImage(c.Param("id"), data, c.Query("name"))
+		if err != nil {
+			respondWithError(c, err)
+			return
+		}
+		c.JSON(http.StatusCreated, pasted)
+	}
+}
@@ -0,0 +1,50 @@
+package api
+
+import (
+	"net/http"
+
+	"github.com/Gomez12/wiki/internal/wiki"
+	"github.com/gin-gonic/gin"
+)
+
+func SaveReadingPositionHandler(w *wiki.Wiki) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		user, ok := currentUser(c)
+		if !ok {
+			return
+		}
+
+		var req struct {
+			Anchor string `json:"anchor"`
+		}
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid payload"})
+			return
+		}
+
+		pos, err := w.SaveReadingPosition(user.ID, c.Param("id"), req.Anchor)
+		if err != nil {
+			respondWithError(c, err)
+			return
+		}
+
+		c.JSON(http.StatusOK, pos)
+	}
+}
+
+func GetReadingPositionHandler(w *wiki.Wiki) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		user, ok := currentUser(c)
+		if !ok {
+			return
+		}
+
+		pos, err := w.GetReadingPosition(user.ID, c.Param("id"))
+		if err != nil {
+			respondWithError(c, err)
+			return
+		}
+
+		c.JSON(http.StatusOK, pos)
+	}
+}
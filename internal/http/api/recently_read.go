@@ -0,0 +1,97 @@
+package api
+
+import (
+	"net/http"
+	"strconv"
+
+	"github.com/Gomez12/wiki/internal/core/auth"
+	"github.com/Gomez12/wiki/internal/wiki"
+	"github.com/gin-gonic/gin"
+)
+
+// maxRecentlyRead caps the number of entries of the "recently read" list
+const maxRecentlyRead = 50
+
+// GetRecentlyReadHandler lists the pages the user read last, and the pages they viewed if they
+// opted in to tracking
+func GetRecentlyReadHandler(w *wiki.Wiki) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		user, ok := currentUser(c)
+		if !ok {
+			return
+		}
+
+		limit := 10
+		if limitStr := c.Query("limit"); limitStr != "" {
+			var err error
+			limit, err = strconv.Atoi(limitStr)
+			if err != nil || limit <= 0 {
+				c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid limit value"})
+				return
+			}
+		}
+		if limit > maxRecentlyRead {
+			limit = maxRecentlyRead
+		}
+
+		recent, err := w.GetRecentlyRead(user.ID, limit)
+		if err != nil {
+			respondWithError(c, err)
+			return
+		}
+
+		c.JSON(http.StatusOK, recent)
+	}
+}
+
+// GetRecentPagesTrackingHandler reports whether the pages the user views are recorded
+func GetRecentPagesTrackingHandler(w *wiki.Wiki) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		user, ok := currentUser(c)
+		if !ok {
+			return
+		}
+
+		enabled, err := w.RecentPagesTracking(user.ID)
+		if err != nil {
+			respondWithError(c, err)
+			return
+		}
+
+		c.JSON(http.StatusOK, gin.H{"enabled": enabled, "available": w.TracksRecentPages()})
+	}
+}
+
+// SetRecentPagesTrackingHandler opts the user in or out of recording the pages they view
+func SetRecentPagesTrackingHandler(w *wiki.Wiki) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		user, ok := currentUser(c)
+		if !ok {
+			return
+		}
+
+		var req struct {
+			Enabled *bool `json:"enabled" binding:"required"`
+		}
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid payload"})
+			return
+		}
+
+		if err := w.SetRecentPagesTracking(user.ID, *req.Enabled); err != nil {
+			respondWithError(c, err)
+			return
+		}
+
+		c.JSON(http.StatusOK, gin.H{"enabled": *req.Enabled, "available": w.TracksRecentPages()})
+	}
+}
+
+// recordVisit tracks the page view of an authenticated user, anonymous views are not tracked
+func recordVisit(c *gin.Context, w *wiki.Wiki, pageID string) {
+	if userValue, exists := c.Get("user"); exists {
+		if user, ok := userValue.(*auth.User); ok && user != nil {
+			w.RecordPageVisit(user.ID, pageID)
+		}
+	}
+}
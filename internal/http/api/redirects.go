@@ -0,0 +1,36 @@
+package api
+
+import (
+	"net/http"
+
+	"github.com/Gomez12/wiki/internal/wiki"
+	"github.com/gin-gonic/gin"
+)
+
+// GetRedirectsHandler lists the redirects of moved and renamed pages
+func GetRedirectsHandler(w *wiki.Wiki) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		list, err := w.ListRedirects()
+		if err != nil {
+			respondWithError(c, err)
+			return
+		}
+		c.JSON(http.StatusOK, list)
+	}
+}
+
+// DeleteRedirectHandler removes the redirect of the route given by the from query parameter
+func DeleteRedirectHandler(w *wiki.Wiki) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		from := c.Query("from")
+		if from == "" {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "missing from"})
+			return
+		}
+		if err := w.DeleteRedirect(from); err != nil {
+			respondWithError(c, err)
+			return
+		}
+		c.Status(http.StatusNoContent)
+	}
+}
@@ -0,0 +1,37 @@
+package api
+
+import (
+	"net/http"
+
+	"github.com/Gomez12/wiki/internal/wiki"
+	"github.com/gin-gonic/gin"
+)
+
+// GetIndexErrorsHandler lists the files which could not be indexed and why
+func GetIndexErrorsHandler(wikiInstance *wiki.Wiki) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		results, err := wikiInstance.IndexErrors()
+		if err != nil {
+			respondWithError(c, err)
+			return
+		}
+		c.JSON(http.StatusOK, results)
+	}
+}
+
+func ReindexHandler(wikiInstance *wiki.Wiki) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var err error
+		if partition := c.Query("partition"); partition != "" {
+			err = wikiInstance.ReindexPartition(partition)
+		} else {
+			err = wikiInstance.ReindexAll()
+		}
+		if err != nil {
+			respondWithError(c, err)
+			return
+		}
+
+		c.JSON(http.StatusAccepted, wikiInstance.GetIndexingStatus())
+	}
+}
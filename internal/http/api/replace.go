@@ -0,0 +1,27 @@
+package api
+
+import (
+	"net/http"
+
+	"github.com/Gomez12/wiki/internal/wiki"
+	"github.com/gin-gonic/gin"
+)
+
+// ReplaceHandler finds and replaces text across pages, or previews it with dryRun
+func ReplaceHandler(w *wiki.Wiki) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var req wiki.ReplaceOptions
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid request"})
+			return
+		}
+
+		result, err := w.ReplaceInPages(req)
+		if err != nil {
+			respondWithError(c, err)
+			return
+		}
+
+		c.JSON(http.StatusOK, result)
+	}
+}
@@ -0,0 +1,27 @@
+package api
+
+import (
+	"net/http"
+
+	"github.com/Gomez12/wiki/internal/wiki"
+	"github.com/gin-gonic/gin"
+)
+
+// RestructureTreeHandler moves and sorts the pages of a subtree into the structure of a drag
+// and drop in the tree, all at once or not at all
+func RestructureTreeHandler(w *wiki.Wiki) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var req wiki.Restructure
+		if err := c.ShouldBindJSON(&req); err != nil || req.Children == nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid payload"})
+			return
+		}
+
+		result, err := w.RestructureTree(req)
+		if err != nil {
+			respondWithError(c, err)
+			return
+		}
+		c.JSON(http.StatusOK, result)
+	}
+}
@@ -0,0 +1,32 @@
+package api
+
+import (
+	"net/http"
+
+	"github.com/Gomez12/wiki/internal/wiki"
+	"github.com/gin-gonic/gin"
+)
+
+// GetDueReviewsHandler lists the pages whose review-by date has been reached
+func GetDueReviewsHandler(wikiInstance *wiki.Wiki) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		due, err := wikiInstance.DueReviews()
+		if err != nil {
+			respondWithError(c, err)
+			return
+		}
+		c.JSON(http.StatusOK, due)
+	}
+}
+
+// SendReviewRemindersHandler reminds the owners of the due pages right away
+func SendReviewRemindersHandler(wikiInstance *wiki.Wiki) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		result, err := wikiInstance.SendReviewReminders(c.Request.Context())
+		if err != nil {
+			respondWithError(c, err)
+			return
+		}
+		c.JSON(http.StatusOK, result)
+	}
+}
@@ -0,0 +1,138 @@
+package api
+
+import (
+	"net/http"
+
+	"github.com/Gomez12/wiki/internal/wiki"
+	"github.com/gin-gonic/gin"
+)
+
+type savedSearchRequest struct {
+	Name       string `json:"name" binding:"required"`
+	Query      string `json:"query" binding:"required"`
+	Subscribed bool   `json:"subscribed"`
+}
+
+func GetSavedSearchesHandler(w *wiki.Wiki) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		user, ok := currentUser(c)
+		if !ok {
+			return
+		}
+
+		list, err := w.GetSavedSearches(user.ID)
+		if err != nil {
+			respondWithError(c, err)
+			return
+		}
+
+		c.JSON(http.StatusOK, list)
+	}
+}
+
+func CreateSavedSearchHandler(w *wiki.Wiki) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		user, ok := currentUser(c)
+		if !ok {
+			return
+		}
+
+		var req savedSearchRequest
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid payload"})
+			return
+		}
+
+		saved, err := w.CreateSavedSearch(user.ID, req.Name, req.Query, req.Subscribed)
+		if err != nil {
+			respondWithError(c, err)
+			return
+		}
+
+		c.JSON(http.StatusCreated, saved)
+	}
+}
+
+func UpdateSavedSearchHandler(w *wiki.Wiki) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		user, ok := currentUser(c)
+		if !ok {
+			return
+		}
+
+		var req savedSearchRequest
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid payload"})
+			return
+		}
+
+		saved, err := w.UpdateSavedSearch(user.ID, c.Param("id"), req.Name, req.Query, req.Subscribed)
+		if err != nil {
+			respondWithError(c, err)
+			return
+		}
+
+		c.JSON(http.StatusOK, saved)
+	}
+}
+
+func DeleteSavedSearchHandler(w *wiki.Wiki) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		user, ok := currentUser(c)
+		if !ok {
+			return
+		}
+
+		if err := w.DeleteSavedSearch(user.ID, c.Param("id")); err != nil {
+			respondWithError(c, err)
+			return
+		}
+
+		c.Status(http.StatusNoContent)
+	}
+}
+
+func GetSearchAlertsHandler(w *wiki.Wiki) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		user, ok := currentUser(c)
+		if !ok {
+			return
+		}
+
+		alerts, err := w.GetSearchAlerts(user.ID, c.Query("unread") == "true")
+		if err != nil {
+			respondWithError(c, err)
+			return
+		}
+
+		c.JSON(http.StatusOK, alerts)
+	}
+}
+
+func MarkSearchAlertsReadHandler(w *wiki.Wiki) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		user, ok := currentUser(c)
+		if !ok {
+			return
+		}
+
+		if err := w.MarkSearchAlertsRead(user.ID); err != nil {
+			respondWithError(c, err)
+			return
+		}
+
+		c.Status(http.StatusNoContent)
+	}
+}
+
+// CheckSavedSearchesHandler runs the subscribed searches right away
+func CheckSavedSearchesHandler(w *wiki.Wiki) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		result, err := w.CheckSavedSearches(c.Request.Context())
+		if err != nil {
+			respondWithError(c, err)
+			return
+		}
+		c.JSON(http.StatusOK, result)
+	}
+}
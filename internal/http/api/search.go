@@ -4,6 +4,7 @@ import (
 	"net/http"
 	"strconv"
 
+	"github.com/Gomez12/wiki/internal/core/auth"
 	"github.com/Gomez12/wiki/internal/wiki"
 	"github.com/gin-gonic/gin"
 )
@@ -39,8 +40,21 @@ func SearchHandler(wikiInstance *wiki.Wiki) gin.HandlerFunc {
 			return
 		}
 
-		results, err := wikiInstance.Search(query, offset, limit)
+		// The user is only set on authenticated routes, public access searches anonymously
+		var user *auth.User
+		if userValue, exists := c.Get("user"); exists {
+			user, _ = userValue.(*auth.User)
+		}
+
+		search := wikiInstance.SearchForUser
+		if c.Query("includeDeleted") == "true" {
+			search = wikiInstance.SearchWithDeletedForUser
+		}
+		results, err := search(c.Request.Context(), user, query, offset, limit)
 		if err != nil {
+			if respondIfTimedOut(c) {
+				return
+			}
 			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to perform search"})
 			return
 		}
@@ -0,0 +1,85 @@
+package api
+
+import (
+	"net/http"
+
+	"github.com/Gomez12/wiki/internal/wiki"
+	"github.com/gin-gonic/gin"
+)
+
+// GetConfigHandler returns the public configuration the frontend needs before login.
+// publicAccess is used as long as the public access mode hasn't been configured in the settings.
+func GetConfigHandler(w *wiki.Wiki, publicAccess bool) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		s, err := w.GetSettings()
+		if err != nil {
+			respondWithError(c, err)
+			return
+		}
+		setupRequired, err := w.SetupRequired()
+		if err != nil {
+			respondWithError(c, err)
+			return
+		}
+
+		c.JSON(http.StatusOK, gin.H{
+			// the frontend shows the setup instead of the login until the first admin exists
+			"setupRequired": setupRequired,
+			"publicAccess":  s.PublicAccessOr(publicAccess),
+			"siteTitle":     s.SiteTitle,
+			"math":          s.Math,
+			// the frontend shows the "recently visited" list only while pages are tracked
+			"trackRecentPages": s.TrackRecentPages,
+			// the editor only checks the spelling if dictionaries are configured
+			"spellcheck": w.SpellcheckEnabled(),
+		})
+	}
+}
+
+func GetSettingsHandler(w *wiki.Wiki) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		s, err := w.GetSettings()
+		if err != nil {
+			respondWithError(c, err)
+			return
+		}
+
+		c.JSON(http.StatusOK, s)
+	}
+}
+
+// UpdateSettingsHandler updates the settings. Options missing in the request keep their current value.
+func UpdateSettingsHandler(w *wiki.Wiki) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		s, err := w.GetSettings()
+		if err != nil {
+			respondWithError(c, err)
+			return
+		}
+		if err := c.ShouldBindJSON(&s); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid request"})
+			return
+		}
+
+		updated, err := w.UpdateSettings(s)
+		if err != nil {
+			respondWithError(c, err)
+			return
+		}
+
+		c.JSON(http.StatusOK, updated)
+	}
+}
+
+// ReloadConfigHandler reads the config file again, like SIGHUP, and returns the settings afterwards
+func ReloadConfigHandler(w *wiki.Wiki) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		s, err := w.ReloadConfig()
+		if err != nil {
+			respondWithError(c, err)
+			return
+		}
+
+		c.JSON(http.StatusOK, s)
+	}
+}
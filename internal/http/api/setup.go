@@ -0,0 +1,28 @@
+package api
+
+import (
+	"net/http"
+
+	"github.com/Gomez12/wiki/internal/wiki"
+	"github.com/gin-gonic/gin"
+)
+
+// SetupHandler creates the first admin of a new wiki and logs it in. Once a user exists the
+// endpoint is locked and answers 409 Conflict.
+func SetupHandler(wikiInstance *wiki.Wiki) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var req wiki.SetupRequest
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid setup payload"})
+			return
+		}
+
+		token, err := wikiInstance.Setup(req)
+		if err != nil {
+			respondWithError(c, err)
+			return
+		}
+
+		c.JSON(http.StatusCreated, token)
+	}
+}
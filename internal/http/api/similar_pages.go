@@ -0,0 +1,69 @@
+package api
+
+import (
+	"net/http"
+	"strconv"
+
+	"github.com/Gomez12/wiki/internal/core/auth"
+	"github.com/Gomez12/wiki/internal/wiki"
+	"github.com/gin-gonic/gin"
+)
+
+// GetSimilarPagesHandler returns the pages related to a page, for "See also" suggestions
+func GetSimilarPagesHandler(w *wiki.Wiki) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		limit, ok := similarLimit(c)
+		if !ok {
+			return
+		}
+
+		// The user is only set on authenticated routes, public access reads anonymously
+		var user *auth.User
+		if userValue, exists := c.Get("user"); exists {
+			user, _ = userValue.(*auth.User)
+		}
+
+		similar, err := w.SimilarPagesForUser(user, c.Param("id"), limit)
+		if err != nil {
+			respondWithError(c, err)
+			return
+		}
+		c.JSON(http.StatusOK, similar)
+	}
+}
+
+// FindSimilarPagesHandler returns the pages similar to a page about to be created, so likely
+// duplicates can be pointed out
+func FindSimilarPagesHandler(w *wiki.Wiki) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		user, ok := currentUser(c)
+		if !ok {
+			return
+		}
+		limit, ok := similarLimit(c)
+		if !ok {
+			return
+		}
+
+		similar, err := w.SimilarToForUser(user, c.Query("title"), c.Query("content"), limit)
+		if err != nil {
+			respondWithError(c, err)
+			return
+		}
+		c.JSON(http.StatusOK, similar)
+	}
+}
+
+// similarLimit reads the limit query parameter and responds with 400 if it is invalid
+func similarLimit(c *gin.Context) (int, bool) {
+	limitStr := c.Query("limit")
+	if limitStr == "" {
+		return wiki.DefaultSimilarLimit, true
+	}
+	limit, err := strconv.Atoi(limitStr)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid limit value"})
+		return 0, false
+	}
+	return limit, true
+}
@@ -0,0 +1,93 @@
+package api
+
+import (
+	"errors"
+	"io"
+	"net/http"
+
+	"github.com/Gomez12/wiki/internal/wiki"
+	"github.com/gin-gonic/gin"
+)
+
+// SpellcheckPageHandler checks the spelling of a page. The body is optional: content checks
+// unsaved changes of the editor, language overrides the language of the page.
+func SpellcheckPageHandler(w *wiki.Wiki) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var req struct {
+			Language string  `json:"language"`
+			Content  *string `json:"content"`
+		}
+		if err := c.ShouldBindJSON(&req); err != nil && !errors.Is(err, io.EOF) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid payload"})
+			return
+		}
+
+		result, err := w.SpellcheckPage(c.Param("id"), req.Language, req.Content)
+		if err != nil {
+			respondWithError(c, err)
+			return
+		}
+
+		c.JSON(http.StatusOK, result)
+	}
+}
+
+func GetSpellcheckLanguagesHandler(w *wiki.Wiki) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		languages, err := w.SpellcheckLanguages()
+		if err != nil {
+			respondWithError(c, err)
+			return
+		}
+
+		c.JSON(http.StatusOK, languages)
+	}
+}
+
+func GetCustomWordsHandler(w *wiki.Wiki) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		words, err := w.GetCustomWords()
+		if err != nil {
+			respondWithError(c, err)
+			return
+		}
+
+		c.JSON(http.StatusOK, words)
+	}
+}
+
+func AddCustomWordHandler(w *wiki.Wiki) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		user, ok := currentUser(c)
+		if !ok {
+			return
+		}
+
+		var req struct {
+			Word string `json:"word" binding:"required"`
+		}
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid payload"})
+			return
+		}
+
+		word, err := w.AddCustomWord(req.Word, user.ID)
+		if err != nil {
+			respondWithError(c, err)
+			return
+		}
+
+		c.JSON(http.StatusCreated, word)
+	}
+}
+
+func RemoveCustomWordHandler(w *wiki.Wiki) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if err := w.RemoveCustomWord(c.Param("word")); err != nil {
+			respondWithError(c, err)
+			return
+		}
+
+		c.Status(http.StatusNoContent)
+	}
+}
@@ -0,0 +1,39 @@
+package api
+
+import (
+	"net/http"
+	"strconv"
+
+	"github.com/Gomez12/wiki/internal/wiki"
+	"github.com/gin-gonic/gin"
+)
+
+// GetStalePagesHandler reports the pages which are due for a review, see wiki.StalePages
+func GetStalePagesHandler(w *wiki.Wiki) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		opts := wiki.StaleOptions{Months: wiki.DefaultStaleMonths, ViewedDays: wiki.DefaultStaleViewedDays}
+		if months := c.Query("months"); months != "" {
+			value, err := strconv.Atoi(months)
+			if err != nil {
+				c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid months value"})
+				return
+			}
+			opts.Months = value
+		}
+		if viewedDays := c.Query("viewedDays"); viewedDays != "" {
+			value, err := strconv.Atoi(viewedDays)
+			if err != nil {
+				c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid viewedDays value"})
+				return
+			}
+			opts.ViewedDays = value
+		}
+
+		report, err := w.StalePages(opts)
+		if err != nil {
+			respondWithError(c, err)
+			return
+		}
+		c.JSON(http.StatusOK, report)
+	}
+}
@@ -0,0 +1,20 @@
+package api
+
+import (
+	"net/http"
+
+	"github.com/Gomez12/wiki/internal/wiki"
+	"github.com/gin-gonic/gin"
+)
+
+func GetStatusRollupHandler(w *wiki.Wiki) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		rollup, err := w.GetStatusRollup(c.Param("id"), c.Query("field"))
+		if err != nil {
+			respondWithError(c, err)
+			return
+		}
+
+		c.JSON(http.StatusOK, rollup)
+	}
+}
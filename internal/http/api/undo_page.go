@@ -0,0 +1,20 @@
+package api
+
+import (
+	"net/http"
+
+	"github.com/Gomez12/wiki/internal/wiki"
+	"github.com/gin-gonic/gin"
+)
+
+func UndoPageHandler(w *wiki.Wiki) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		page, err := w.UndoPage(c.Param("id"))
+		if err != nil {
+			respondWithError(c, err)
+			return
+		}
+
+		c.JSON(http.StatusOK, ToAPIPage(page))
+	}
+}
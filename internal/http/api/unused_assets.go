@@ -0,0 +1,41 @@
+package api
+
+import (
+	"net/http"
+
+	"github.com/Gomez12/wiki/internal/wiki"
+	"github.com/gin-gonic/gin"
+)
+
+// GetUnusedAssetsHandler reports the assets which no page links to
+func GetUnusedAssetsHandler(w *wiki.Wiki) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		report, err := w.UnusedAssets()
+		if err != nil {
+			respondWithError(c, err)
+			return
+		}
+		c.JSON(http.StatusOK, report)
+	}
+}
+
+// DeleteUnusedAssetsHandler deletes the selected or all unused assets, see wiki.DeleteUnusedAssets
+func DeleteUnusedAssetsHandler(w *wiki.Wiki) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var req struct {
+			URLs   []string `json:"urls"`
+			DryRun bool     `json:"dryRun"`
+		}
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid payload"})
+			return
+		}
+
+		result, err := w.DeleteUnusedAssets(req.URLs, req.DryRun)
+		if err != nil {
+			respondWithError(c, err)
+			return
+		}
+		c.JSON(http.StatusOK, result)
+	}
+}
@@ -3,10 +3,18 @@ package api
 import (
 	"net/http"
 
+	"github.com/Gomez12/wiki/internal/core/frontmatter"
+	"github.com/Gomez12/wiki/internal/core/lint"
 	"github.com/Gomez12/wiki/internal/wiki"
 	"github.com/gin-gonic/gin"
 )
 
+// SavedPage is an updated page with the findings of the lint rules, which don't prevent saving
+type SavedPage struct {
+	*Page
+	Lint lint.Result `json:"lint"`
+}
+
 func UpdatePageHandler(w *wiki.Wiki) gin.HandlerFunc {
 	return func(c *gin.Context) {
 		id := c.Param("id")
@@ -15,18 +23,44 @@ func UpdatePageHandler(w *wiki.Wiki) gin.HandlerFunc {
 			Title   string `json:"title" binding:"required"`
 			Slug    string `json:"slug" binding:"required"`
 			Content string `json:"content" binding:"required"`
+			// Frontmatter replaces the frontmatter of the content if set, {} removes it
+			Frontmatter *map[string]any `json:"frontmatter"`
 		}
 		if err := c.ShouldBindJSON(&req); err != nil {
 			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid payload"})
 			return
 		}
 
-		page, err := w.UpdatePage(id, req.Title, req.Slug, req.Content)
+		content := req.Content
+		if req.Frontmatter != nil {
+			var err error
+			if content, err = frontmatter.Replace(content, *req.Frontmatter); err != nil {
+				c.JSON(http.StatusBadRequest, gin.H{"error": "invalid frontmatter"})
+				return
+			}
+		}
+
+		user, ok := currentUser(c)
+		if !ok {
+			return
+		}
+
+		page, err := w.UpdatePageForUser(user, id, req.Title, req.Slug, content)
 		if err != nil {
 			respondWithError(c, err)
 			return
 		}
+		// the saved page replaces the autosaved draft, one left behind is only offered again
+		if err := w.DiscardDraft(user.ID, id); err != nil {
+			_ = c.Error(err)
+		}
 
-		c.JSON(http.StatusOK, ToAPIPage(page))
+		saved := SavedPage{Page: ToAPIPage(page), Lint: lint.Result{Warnings: []lint.Warning{}}}
+		if result, err := w.LintPage(page.ID); err == nil {
+			saved.Lint = *result
+		} else {
+			_ = c.Error(err)
+		}
+		c.JSON(http.StatusOK, saved)
 	}
 }
@@ -1,23 +1,42 @@
 package api
 
 import (
+	"errors"
 	"net/http"
 
 	"github.com/Gomez12/wiki/internal/wiki"
 	"github.com/gin-gonic/gin"
 )
 
+// uploadMemory is the part of an upload kept in memory, the rest is written to a temporary
+// file, so large uploads don't fill the memory
+const uploadMemory = 1 << 20
+
+// uploadOverhead allows for the multipart headers and form fields besides the file
+const uploadOverhead = 64 << 10
+
 func UploadAssetHandler(w *wiki.Wiki) gin.HandlerFunc {
 	return func(c *gin.Context) {
 
-		const maxUploadSize = 500 << 20 // 50 MB
-		c.Request.Body = http.MaxBytesReader(c.Writer, c.Request.Body, maxUploadSize)
+		maxUploadSize := w.MaxUploadSize()
+		if c.Request.ContentLength > maxUploadSize+uploadOverhead {
+			c.JSON(http.StatusRequestEntityTooLarge, gin.H{"error": "file too large"})
+			return
+		}
+		c.Request.Body = http.MaxBytesReader(c.Writer, c.Request.Body, maxUploadSize+uploadOverhead)
 
 		// Parse form
-		if err := c.Request.ParseMultipartForm(maxUploadSize); err != nil {
-			c.JSON(http.StatusRequestEntityTooLarge, gin.H{"error": "file too large"})
+		if err := c.Request.ParseMultipartForm(uploadMemory); err != nil {
+			var tooLarge *http.MaxBytesError
+			if errors.As(err, &tooLarge) {
+				c.JSON(http.StatusRequestEntityTooLarge, gin.H{"error": "file too large"})
+				return
+			}
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid multipart form"})
 			return
 		}
+		// removes the temporary files of the upload
+		defer c.Request.MultipartForm.RemoveAll()
 
 		pageID := c.Param("id")
 		file, header, err := c.Request.FormFile("file")
@@ -26,8 +45,17 @@ func UploadAssetHandler(w *wiki.Wiki) gin.HandlerFunc {
 			return
 		}
 		defer file.Close()
+		if header.Size > maxUploadSize {
+			c.JSON(http.StatusRequestEntityTooLarge, gin.H{"error": "file too large"})
+			return
+		}
 
-		url, err := w.UploadAsset(pageID, file, header.Filename)
+		upload := w.UploadAsset
+		if c.Request.FormValue("replace") == "true" {
+			// replaces the asset of the same name and keeps its content as previous version
+			upload = w.ReplaceAsset
+		}
+		url, err := upload(pageID, file, header.Filename)
 		if err != nil {
 			respondWithError(c, err)
 			return
@@ -0,0 +1,24 @@
+package api
+
+import (
+	"net/http"
+
+	"github.com/Gomez12/wiki/internal/core/validate"
+	"github.com/gin-gonic/gin"
+)
+
+// ValidateMarkdownHandler checks the Markdown of the editor before it is saved and returns
+// the warnings: invalid frontmatter, code fences which are not closed and malformed tables
+func ValidateMarkdownHandler() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var req struct {
+			Content string `json:"content"`
+		}
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid payload"})
+			return
+		}
+
+		c.JSON(http.StatusOK, validate.Markdown(req.Content))
+	}
+}
@@ -0,0 +1,19 @@
+package api
+
+import (
+	"github.com/Gomez12/wiki/internal/wiki"
+	"github.com/gin-gonic/gin"
+)
+
+// VaultFileHandler serves an attachment of an Obsidian vault, like the assets it is public
+// so it can be used in images
+func VaultFileHandler(w *wiki.Wiki) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		fullPath, err := w.VaultFile(c.Param("filepath"))
+		if err != nil {
+			respondWithError(c, err)
+			return
+		}
+		c.File(fullPath)
+	}
+}
@@ -0,0 +1,463 @@
+package http
+
+import (
+	"net/http"
+
+	"github.com/Gomez12/wiki/internal/core/assets"
+	"github.com/Gomez12/wiki/internal/core/auth"
+	"github.com/Gomez12/wiki/internal/core/drafts"
+	"github.com/Gomez12/wiki/internal/core/favorites"
+	"github.com/Gomez12/wiki/internal/core/gitsync"
+	"github.com/Gomez12/wiki/internal/core/importer"
+	"github.com/Gomez12/wiki/internal/core/lint"
+	"github.com/Gomez12/wiki/internal/core/reading"
+	"github.com/Gomez12/wiki/internal/core/savedsearch"
+	"github.com/Gomez12/wiki/internal/core/settings"
+	"github.com/Gomez12/wiki/internal/core/spellcheck"
+	"github.com/Gomez12/wiki/internal/core/texmath"
+	"github.com/Gomez12/wiki/internal/core/tree"
+	"github.com/Gomez12/wiki/internal/core/validate"
+	"github.com/Gomez12/wiki/internal/http/api"
+	"github.com/Gomez12/wiki/internal/search"
+	"github.com/Gomez12/wiki/internal/wiki"
+)
+
+// routeAccess describes who may call a route
+type routeAccess int
+
+const (
+	// accessPublic routes need no authentication
+	accessPublic routeAccess = iota
+	// accessRead routes are public while public access is enabled
+	accessRead
+	accessAuth
+	accessAdmin
+)
+
+type queryParam struct {
+	Name        string
+	Description string
+	// Type is the JSON schema type, string if empty
+	Type     string
+	Required bool
+}
+
+// apiRoute documents a route of the API. The OpenAPI document is generated from apiRoutes,
+// and a test makes sure every route registered in NewRouter is listed here.
+type apiRoute struct {
+	Method  string
+	Path    string
+	Tag     string
+	Summary string
+	Access  routeAccess
+	Query   []queryParam
+	// Body is a value of the JSON request body type
+	Body interface{}
+	// Multipart is the name of the file field of a multipart upload
+	Multipart string
+	// Status is the success status, 200 if zero
+	Status int
+	// Response is a value of the JSON response type, nil for responses without body
+	Response interface{}
+	// ContentType is set for responses which are not JSON
+	ContentType string
+}
+
+var historyQuery = []queryParam{
+	{Name: "limit", Type: "integer", Description: "Maximum number of entries"},
+	{Name: "offset", Type: "integer", Description: "Number of entries to skip"},
+	{Name: "status", Description: "Only entries with this status (created, modified, moved, deleted)"},
+	{Name: "content", Type: "boolean", Description: "Include the content of the entries (default true)"},
+}
+
+var asOfQuery = queryParam{Name: "asOf", Description: "Return the state at this RFC 3339 timestamp or date from the page history"}
+
+type messageResponse struct {
+	Message string `json:"message"`
+}
+
+type savedSearchBody struct {
+	Name       string `json:"name" binding:"required"`
+	Query      string `json:"query" binding:"required"`
+	Subscribed bool   `json:"subscribed"`
+}
+
+type recentPagesTracking struct {
+	Enabled   bool `json:"enabled"`
+	Available bool `json:"available"`
+}
+
+var apiRoutes = []apiRoute{
+	// Auth & config
+	{Method: http.MethodPost, Path: "/auth/login", Tag: "Auth", Summary: "Log in with username or email", Access: accessPublic,
+		Body: struct {
+			Identifier string `json:"identifier" binding:"required"`
+			Password   string `json:"password" binding:"required"`
+		}{}, Response: auth.AuthToken{}},
+	{Method: http.MethodPost, Path: "/auth/refresh-token", Tag: "Auth", Summary: "Exchange a refresh token for new tokens", Access: accessPublic,
+		Body: struct {
+			Token string `json:"token" binding:"required"`
+		}{}, Response: auth.AuthToken{}},
+	{Method: http.MethodGet, Path: "/config", Tag: "Config", Summary: "Get the public configuration", Access: accessPublic,
+		Response: struct {
+			PublicAccess     bool         `json:"publicAccess"`
+			SiteTitle        string       `json:"siteTitle"`
+			Math             texmath.Mode `json:"math"`
+			TrackRecentPages bool         `json:"trackRecentPages"`
+			Spellcheck       bool         `json:"spellcheck"`
+			SetupRequired    bool         `json:"setupRequired"`
+		}{}},
+	{Method: http.MethodPost, Path: "/setup", Tag: "Config", Summary: "Create the first admin and set the site title; only allowed while no user exists",
+		Access: accessPublic, Body: wiki.SetupRequest{}, Status: http.StatusCreated, Response: auth.AuthToken{}},
+	{Method: http.MethodGet, Path: "/openapi.json", Tag: "Config", Summary: "Get this OpenAPI document", Access: accessPublic,
+		ContentType: "application/json"},
+	{Method: http.MethodGet, Path: "/docs", Tag: "Config", Summary: "Explore the API with Swagger UI", Access: accessPublic,
+		ContentType: "text/html"},
+
+	// Tree
+	{Method: http.MethodGet, Path: "/tree", Tag: "Tree", Summary: "Get the page tree", Access: accessRead,
+		Query: []queryParam{
+			{Name: "path", Description: "Return the subtree below this page path"},
+			{Name: "depth", Type: "integer", Description: "Maximum depth of the returned tree"},
+			{Name: "children", Type: "boolean", Description: "Return only the direct children"},
+			asOfQuery,
+		}, Response: api.Node{}},
+
+	// Pages
+	{Method: http.MethodGet, Path: "/pages/by-path", Tag: "Pages", Summary: "Get a page by its path", Access: accessRead,
+		Query: []queryParam{{Name: "path", Required: true}, asOfQuery}, Response: api.Page{}},
+	{Method: http.MethodGet, Path: "/pages/lookup", Tag: "Pages", Summary: "Look up which segments of a path exist", Access: accessRead,
+		Query: []queryParam{{Name: "path", Required: true}}, Response: tree.PathLookup{}},
+	{Method: http.MethodGet, Path: "/pages/:id", Tag: "Pages", Summary: "Get a page", Access: accessRead,
+		Query: []queryParam{asOfQuery}, Response: api.Page{}},
+	{Method: http.MethodGet, Path: "/pages/:id/status-rollup", Tag: "Pages", Summary: "Summarize a frontmatter field over the subtree", Access: accessRead,
+		Query: []queryParam{{Name: "field", Description: "Frontmatter field, status by default"}}, Response: wiki.StatusRollup{}},
+	{Method: http.MethodGet, Path: "/pages/:id/meta", Tag: "Pages", Summary: "Get dates, word count, contributors and backlinks of a page", Access: accessRead,
+		Response: wiki.PageMeta{}},
+	{Method: http.MethodGet, Path: "/pages/:id/toc", Tag: "Pages", Summary: "Get the heading hierarchy of a page with anchors", Access: accessRead,
+		Response: wiki.PageTOC{}},
+	{Method: http.MethodGet, Path: "/pages/:id/lint", Tag: "Pages", Summary: "Check a page with the lint rules enabled in the settings", Access: accessRead,
+		Response: lint.Result{}},
+	{Method: http.MethodGet, Path: "/pages/:id/similar", Tag: "Pages", Summary: "List related pages by the key terms they share", Access: accessRead,
+		Query:    []queryParam{{Name: "limit", Type: "integer", Description: "Maximum number of pages, default 5, at most 50"}},
+		Response: []search.SimilarPage{}},
+	{Method: http.MethodGet, Path: "/pages/:id/html", Tag: "Pages", Summary: "Get a page rendered to sanitized HTML with highlighted code", Access: accessRead,
+		Response: wiki.RenderedPage{}},
+	{Method: http.MethodGet, Path: "/pages/:id/export", Tag: "Pages", Summary: "Download a page as PDF, Markdown, HTML or DOCX", Access: accessRead,
+		Query: []queryParam{
+			{Name: "format", Description: "pdf (default), md, html or docx; md and html of pages with assets are zipped"},
+			{Name: "recursive", Type: "boolean", Description: "Include the pages below with a table of contents, pdf only"},
+		}, ContentType: "application/octet-stream"},
+	{Method: http.MethodPost, Path: "/pages", Tag: "Pages", Summary: "Create a page", Access: accessAuth,
+		Body: struct {
+			ParentID *string `json:"parentId"`
+			Title    string  `json:"title" binding:"required"`
+			Slug     string  `json:"slug" binding:"required"`
+		}{}, Status: http.StatusCreated, Response: api.Page{}},
+	{Method: http.MethodPost, Path: "/pages/ensure", Tag: "Pages", Summary: "Create a page and its missing parents", Access: accessAuth,
+		Body: struct {
+			Path        string `json:"path" binding:"required"`
+			TargetTitle string `json:"targetTitle" binding:"required"`
+		}{}, Response: api.Page{}},
+	{Method: http.MethodPost, Path: "/pages/copy/:id", Tag: "Pages", Summary: "Copy a page", Access: accessAuth,
+		Body: struct {
+			TargetParentID *string `json:"targetParentId"`
+			Title          string  `json:"title" binding:"required"`
+			Slug           string  `json:"slug" binding:"required"`
+		}{}, Status: http.StatusCreated, Response: api.Page{}},
+	{Method: http.MethodPut, Path: "/pages/:id", Tag: "Pages", Summary: "Update a page; frontmatter replaces the frontmatter of the content, the response lists the findings of the lint rules", Access: accessAuth,
+		Body: struct {
+			Title       string          `json:"title" binding:"required"`
+			Slug        string          `json:"slug" binding:"required"`
+			Content     string          `json:"content" binding:"required"`
+			Frontmatter *map[string]any `json:"frontmatter"`
+		}{}, Response: api.SavedPage{}},
+	{Method: http.MethodGet, Path: "/pages/:id/autosave", Tag: "Pages", Summary: "Get the autosaved draft of the user to recover unsaved changes", Access: accessAuth,
+		Response: drafts.Draft{}},
+	{Method: http.MethodPut, Path: "/pages/:id/autosave", Tag: "Pages", Summary: "Autosave a draft of the editor without saving the page", Access: accessAuth,
+		Body: struct {
+			Title   string `json:"title"`
+			Content string `json:"content"`
+		}{}, Response: drafts.Draft{}},
+	{Method: http.MethodDelete, Path: "/pages/:id/autosave", Tag: "Pages", Summary: "Discard the autosaved draft of the user", Access: accessAuth,
+		Status: http.StatusNoContent},
+	{Method: http.MethodPost, Path: "/pages/:id/undo", Tag: "Pages", Summary: "Restore the previous version of a page", Access: accessAuth,
+		Response: api.Page{}},
+	{Method: http.MethodDelete, Path: "/pages/:id", Tag: "Pages", Summary: "Delete a page", Access: accessAuth,
+		Query: []queryParam{{Name: "recursive", Type: "boolean", Description: "Delete the page with its children"}}, Response: messageResponse{}},
+	{Method: http.MethodPut, Path: "/pages/:id/move", Tag: "Pages", Summary: "Move a page to another parent", Access: accessAuth,
+		Body: struct {
+			ParentID string `json:"parentId"`
+		}{}, Response: messageResponse{}},
+	{Method: http.MethodGet, Path: "/pages/:id/move-check", Tag: "Pages", Summary: "Check for slug conflicts, redirects and link updates before moving a page", Access: accessAuth,
+		Query: []queryParam{{Name: "parentId", Description: "New parent, the root if empty"}}, Response: wiki.MoveCheck{}},
+	{Method: http.MethodPut, Path: "/pages/:id/sort", Tag: "Pages", Summary: "Sort the children of a page", Access: accessAuth,
+		Body: struct {
+			OrderedIDs []string `json:"orderedIds"`
+		}{}, Response: messageResponse{}},
+	{Method: http.MethodPost, Path: "/tree/restructure", Tag: "Tree", Summary: "Move and sort the pages of a subtree into the desired parents and order at once", Access: accessAuth,
+		Body: wiki.Restructure{}, Response: wiki.RestructureResult{}},
+	{Method: http.MethodGet, Path: "/pages/similar", Tag: "Pages", Summary: "Find likely duplicates of a page about to be created", Access: accessAuth,
+		Query: []queryParam{
+			{Name: "title", Description: "Title of the new page"},
+			{Name: "content", Description: "Markdown content of the new page"},
+			{Name: "limit", Type: "integer", Description: "Maximum number of pages, default 5, at most 50"},
+		}, Response: []search.SimilarPage{}},
+	{Method: http.MethodGet, Path: "/pages/slug-suggestion", Tag: "Pages", Summary: "Suggest a unique slug for a title", Access: accessAuth,
+		Query: []queryParam{{Name: "parentID"}, {Name: "currentID"}, {Name: "title", Required: true}},
+		Response: struct {
+			Slug string `json:"slug"`
+		}{}},
+
+	// History
+	{Method: http.MethodGet, Path: "/pages/history", Tag: "History", Summary: "Get the history of a page", Access: accessRead,
+		Query: append([]queryParam{{Name: "path", Required: true}}, historyQuery...),
+		Response: struct {
+			History     []search.FileHistoryEntry `json:"history"`
+			Total       int                       `json:"total"`
+			CurrentHash string                    `json:"currentHash"`
+		}{}},
+	{Method: http.MethodGet, Path: "/pages/history/:entryId", Tag: "History", Summary: "Get a history entry with its content", Access: accessRead,
+		Response: search.FileHistoryEntry{}},
+	{Method: http.MethodGet, Path: "/history", Tag: "History", Summary: "Get the history of a subtree", Access: accessRead,
+		Query: append([]queryParam{{Name: "path", Description: "Page path of the subtree, the whole wiki if empty"}}, historyQuery...),
+		Response: struct {
+			History []search.FileHistoryEntry `json:"history"`
+			Total   int                       `json:"total"`
+		}{}},
+	{Method: http.MethodGet, Path: "/pages/labels", Tag: "History", Summary: "List the labels of a page", Access: accessRead,
+		Query: []queryParam{{Name: "path", Required: true}},
+		Response: struct {
+			Labels []search.HistoryLabel `json:"labels"`
+		}{}},
+	{Method: http.MethodGet, Path: "/pages/labels/:label", Tag: "History", Summary: "Get the version of a page with a label", Access: accessRead,
+		Query: []queryParam{{Name: "path", Required: true}}, Response: search.FileHistoryEntry{}},
+	{Method: http.MethodPost, Path: "/history/:id/label", Tag: "History", Summary: "Label a history entry", Access: accessAuth,
+		Body: struct {
+			Label string `json:"label" binding:"required"`
+		}{}, Status: http.StatusCreated, Response: search.HistoryLabel{}},
+
+	// Search
+	{Method: http.MethodGet, Path: "/search", Tag: "Search", Summary: "Search pages", Access: accessRead,
+		Query: []queryParam{
+			{Name: "q", Required: true, Description: "Search query"},
+			{Name: "offset", Type: "integer"},
+			{Name: "limit", Type: "integer"},
+			{Name: "includeDeleted", Type: "boolean", Description: "Also search the last snapshots of deleted pages"},
+		}, Response: search.SearchResult{}},
+	{Method: http.MethodGet, Path: "/search/status", Tag: "Search", Summary: "Get the indexing status", Access: accessRead,
+		Response: search.IndexingStatus{}},
+
+	// Stats & badges
+	{Method: http.MethodGet, Path: "/stats", Tag: "Stats", Summary: "Get wiki statistics", Access: accessRead, Response: wiki.Stats{}},
+	{Method: http.MethodGet, Path: "/badges/:name", Tag: "Stats", Summary: "Get a badge as SVG, or as shields.io endpoint with format=json", Access: accessRead,
+		Query: []queryParam{{Name: "format", Description: "json for a shields.io endpoint"}}, ContentType: "image/svg+xml"},
+
+	// Users
+	{Method: http.MethodPost, Path: "/users", Tag: "Users", Summary: "Create a user", Access: accessAdmin,
+		Body: struct {
+			Username string `json:"username" binding:"required"`
+			Email    string `json:"email" binding:"required"`
+			Password string `json:"password" binding:"required"`
+			Role     string `json:"role" binding:"required"`
+		}{}, Status: http.StatusCreated, Response: auth.PublicUser{}},
+	{Method: http.MethodGet, Path: "/users", Tag: "Users", Summary: "List users", Access: accessAdmin,
+		Query: []queryParam{
+			{Name: "q", Description: "Filter by username or email"},
+			{Name: "sort", Description: "username, email, role or created"},
+			{Name: "order", Description: "asc or desc"},
+			{Name: "limit", Type: "integer", Description: "Page size from 1 to 500, default 50"},
+			{Name: "offset", Type: "integer"},
+		},
+		Response: struct {
+			Users  []auth.PublicUser `json:"users"`
+			Total  int               `json:"total"`
+			Limit  int               `json:"limit"`
+			Offset int               `json:"offset"`
+		}{}},
+	{Method: http.MethodPut, Path: "/users/:id", Tag: "Users", Summary: "Update a user (admins or the user itself)", Access: accessAuth,
+		Body: struct {
+			Username string `json:"username" binding:"required"`
+			Email    string `json:"email" binding:"required"`
+			Password string `json:"password"`
+			Role     string `json:"role" binding:"required"`
+		}{}, Response: auth.PublicUser{}},
+	{Method: http.MethodDelete, Path: "/users/:id", Tag: "Users", Summary: "Delete a user", Access: accessAdmin, Status: http.StatusNoContent},
+	{Method: http.MethodPut, Path: "/users/me/password", Tag: "Users", Summary: "Change the own password", Access: accessAuth,
+		Body: struct {
+			OldPassword string `json:"old_password" binding:"required"`
+			NewPassword string `json:"new_password" binding:"required"`
+		}{}, Status: http.StatusNoContent},
+
+	// Reading positions
+	{Method: http.MethodGet, Path: "/users/me/recently-read", Tag: "Reading", Summary: "List the pages read or viewed last, views are recorded for users who opted in", Access: accessAuth,
+		Query: []queryParam{{Name: "limit", Type: "integer"}}, Response: []reading.RecentPage{}},
+	{Method: http.MethodGet, Path: "/users/me/recently-read/tracking", Tag: "Reading", Summary: "Get whether the viewed pages are recorded", Access: accessAuth,
+		Response: recentPagesTracking{}},
+	{Method: http.MethodPut, Path: "/users/me/recently-read/tracking", Tag: "Reading", Summary: "Opt in or out of recording the viewed pages, opting out deletes the recorded views", Access: accessAuth,
+		Body: struct {
+			Enabled bool `json:"enabled" binding:"required"`
+		}{}, Response: recentPagesTracking{}},
+	{Method: http.MethodGet, Path: "/pages/:id/reading-position", Tag: "Reading", Summary: "Get the reading position on a page", Access: accessAuth,
+		Response: reading.Position{}},
+	{Method: http.MethodPut, Path: "/pages/:id/reading-position", Tag: "Reading", Summary: "Save the reading position on a page", Access: accessAuth,
+		Body: struct {
+			Anchor string `json:"anchor"`
+		}{}, Response: reading.Position{}},
+
+	// Favorites
+	{Method: http.MethodGet, Path: "/me/favorites", Tag: "Favorites", Summary: "List the starred pages", Access: accessAuth,
+		Response: []favorites.FavoritePage{}},
+	{Method: http.MethodPost, Path: "/me/favorites", Tag: "Favorites", Summary: "Star a page", Access: accessAuth,
+		Body: struct {
+			PageID string `json:"pageId" binding:"required"`
+		}{}, Status: http.StatusCreated, Response: favorites.FavoritePage{}},
+	{Method: http.MethodDelete, Path: "/me/favorites/:pageId", Tag: "Favorites", Summary: "Unstar a page", Access: accessAuth,
+		Status: http.StatusNoContent},
+
+	// Saved searches
+	{Method: http.MethodGet, Path: "/users/me/saved-searches", Tag: "Saved searches", Summary: "List the saved searches", Access: accessAuth,
+		Response: []savedsearch.SavedSearch{}},
+	{Method: http.MethodPost, Path: "/users/me/saved-searches", Tag: "Saved searches", Summary: "Save a search, subscribed searches raise alerts", Access: accessAuth,
+		Body: savedSearchBody{}, Status: http.StatusCreated, Response: savedsearch.SavedSearch{}},
+	{Method: http.MethodPut, Path: "/users/me/saved-searches/:id", Tag: "Saved searches", Summary: "Change a saved search", Access: accessAuth,
+		Body: savedSearchBody{}, Response: savedsearch.SavedSearch{}},
+	{Method: http.MethodDelete, Path: "/users/me/saved-searches/:id", Tag: "Saved searches", Summary: "Delete a saved search and its alerts", Access: accessAuth,
+		Status: http.StatusNoContent},
+	{Method: http.MethodGet, Path: "/users/me/search-alerts", Tag: "Saved searches", Summary: "List the pages which started matching a subscribed search", Access: accessAuth,
+		Query: []queryParam{
+			{Name: "unread", Type: "boolean", Description: "Only the unread alerts"},
+		}, Response: []savedsearch.Alert{}},
+	{Method: http.MethodPost, Path: "/users/me/search-alerts/read", Tag: "Saved searches", Summary: "Mark all search alerts as read", Access: accessAuth,
+		Status: http.StatusNoContent},
+
+	// Spellcheck
+	{Method: http.MethodPost, Path: "/pages/:id/spellcheck", Tag: "Spellcheck", Summary: "Check the spelling of a page", Access: accessAuth,
+		Body: struct {
+			Language string  `json:"language"`
+			Content  *string `json:"content"`
+		}{}, Response: wiki.SpellcheckResult{}},
+	{Method: http.MethodGet, Path: "/spellcheck/languages", Tag: "Spellcheck", Summary: "List the languages with a dictionary", Access: accessAuth,
+		Response: []string{}},
+	{Method: http.MethodGet, Path: "/spellcheck/dictionary", Tag: "Spellcheck", Summary: "List the custom dictionary", Access: accessAuth,
+		Response: []spellcheck.CustomWord{}},
+	{Method: http.MethodPost, Path: "/spellcheck/dictionary", Tag: "Spellcheck", Summary: "Add a word to the custom dictionary", Access: accessAuth,
+		Body: struct {
+			Word string `json:"word" binding:"required"`
+		}{}, Status: http.StatusCreated, Response: spellcheck.CustomWord{}},
+	{Method: http.MethodDelete, Path: "/spellcheck/dictionary/:word", Tag: "Spellcheck", Summary: "Remove a word from the custom dictionary", Access: accessAuth,
+		Status: http.StatusNoContent},
+	{Method: http.MethodPost, Path: "/validate", Tag: "Pages", Summary: "Check Markdown for invalid frontmatter, unclosed code fences and malformed tables", Access: accessAuth,
+		Body: struct {
+			Content string `json:"content"`
+		}{}, Response: validate.Result{}},
+
+	// Assets
+	{Method: http.MethodPost, Path: "/pages/:id/assets", Tag: "Assets", Summary: "Upload an asset; with the form field replace=true it replaces the asset of the same name and keeps its previous version", Access: accessAuth,
+		Multipart: "file", Status: http.StatusCreated,
+		Response: struct {
+			File string `json:"file"`
+		}{}},
+	{Method: http.MethodPost, Path: "/pages/:id/assets/paste", Tag: "Assets", Summary: "Store a pasted PNG, JPEG, GIF or WebP image, sent as raw bytes or base64 data URL, and get the Markdown embedding it", Access: accessAuth,
+		Query:  []queryParam{{Name: "name", Description: "Name of the image, default screenshot-<UTC timestamp>"}},
+		Status: http.StatusCreated, Response: wiki.PastedImage{}},
+	{Method: http.MethodGet, Path: "/pages/:id/assets", Tag: "Assets", Summary: "List the assets of a page", Access: accessAuth,
+		Response: struct {
+			Files []string `json:"files"`
+		}{}},
+	{Method: http.MethodPut, Path: "/pages/:id/assets/rename", Tag: "Assets", Summary: "Rename an asset", Access: accessAuth,
+		Body: struct {
+			OldFilename string `json:"old_filename" binding:"required"`
+			NewFilename string `json:"new_filename" binding:"required"`
+		}{},
+		Response: struct {
+			URL string `json:"url"`
+		}{}},
+	{Method: http.MethodPut, Path: "/pages/:id/assets/:name", Tag: "Assets", Summary: "Rename an asset or move it to another page and rewrite the links to it", Access: accessAuth,
+		Body: struct {
+			Name   string `json:"name"`
+			PageID string `json:"pageId"`
+		}{},
+		Response: struct {
+			URL string `json:"url"`
+		}{}},
+	{Method: http.MethodDelete, Path: "/pages/:id/assets/:name", Tag: "Assets", Summary: "Delete an asset", Access: accessAuth,
+		Response: messageResponse{}},
+	{Method: http.MethodGet, Path: "/pages/:id/assets/:name/versions", Tag: "Assets", Summary: "List the current and previous versions of an asset", Access: accessAuth,
+		Response: assets.AssetVersions{}},
+	{Method: http.MethodGet, Path: "/pages/:id/assets/:name/versions/:hash", Tag: "Assets", Summary: "Download a previous version of an asset", Access: accessAuth,
+		ContentType: "application/octet-stream"},
+	{Method: http.MethodPost, Path: "/pages/:id/assets/:name/versions/:hash/restore", Tag: "Assets", Summary: "Restore a previous version of an asset, also of a deleted one", Access: accessAuth,
+		Response: struct {
+			URL string `json:"url"`
+		}{}},
+
+	// Admin
+	{Method: http.MethodPost, Path: "/admin/reindex", Tag: "Admin", Summary: "Rebuild the search index in the background", Access: accessAdmin,
+		Query: []queryParam{{Name: "partition", Description: "Rebuild only this partition"}}, Status: http.StatusAccepted, Response: search.IndexingStatus{}},
+	{Method: http.MethodGet, Path: "/admin/index-errors", Tag: "Admin", Summary: "List the files whose last indexing failed, with the error", Access: accessAdmin,
+		Response: []search.IndexResult{}},
+	{Method: http.MethodPost, Path: "/admin/import/analyze", Tag: "Admin", Summary: "Propose a hierarchy for a folder of Markdown files below the import directory", Access: accessAdmin,
+		Body: struct {
+			SourceDir string `json:"sourceDir" binding:"required"`
+		}{}, Response: importer.Plan{}},
+	{Method: http.MethodPost, Path: "/admin/import/upload", Tag: "Admin", Summary: "Upload a zip archive of Markdown files and propose a hierarchy for it", Access: accessAdmin,
+		Multipart: "file", Response: importer.Plan{}},
+	{Method: http.MethodPost, Path: "/admin/import/apply", Tag: "Admin", Summary: "Import the pages of an import plan", Access: accessAdmin,
+		Body: struct {
+			ParentID *string        `json:"parentId"`
+			Plan     *importer.Plan `json:"plan" binding:"required"`
+		}{}, Status: http.StatusCreated, Response: importer.Report{}},
+	{Method: http.MethodPost, Path: "/admin/replace", Tag: "Admin", Summary: "Find and replace text across a subtree, or preview it with dryRun", Access: accessAdmin,
+		Body: wiki.ReplaceOptions{}, Response: wiki.ReplaceResult{}},
+	{Method: http.MethodGet, Path: "/export/html", Tag: "Admin", Summary: "Download the wiki as static HTML site (zip)", Access: accessAdmin,
+		ContentType: "application/zip"},
+	{Method: http.MethodGet, Path: "/admin/settings", Tag: "Admin", Summary: "Get the runtime settings", Access: accessAdmin, Response: settings.Settings{}},
+	{Method: http.MethodPut, Path: "/admin/settings", Tag: "Admin", Summary: "Update the runtime settings; missing options keep their value", Access: accessAdmin,
+		Body: settings.Settings{}, Response: settings.Settings{}},
+	{Method: http.MethodPost, Path: "/admin/config/reload", Tag: "Admin", Summary: "Read the config file again and apply the log level and the settings", Access: accessAdmin,
+		Response: settings.Settings{}},
+	{Method: http.MethodGet, Path: "/admin/settings/password-policy", Tag: "Admin", Summary: "Get the password policy", Access: accessAdmin,
+		Response: auth.PasswordPolicy{}},
+	{Method: http.MethodPut, Path: "/admin/settings/password-policy", Tag: "Admin", Summary: "Update the password policy", Access: accessAdmin,
+		Body: auth.PasswordPolicy{}, Response: auth.PasswordPolicy{}},
+	{Method: http.MethodGet, Path: "/admin/git-sync", Tag: "Admin", Summary: "Get the outcome of the last git sync", Access: accessAdmin,
+		Response: gitsync.Status{}},
+	{Method: http.MethodPost, Path: "/admin/git-sync", Tag: "Admin", Summary: "Sync with the git remote now; 409 lists conflicts", Access: accessAdmin,
+		Response: gitsync.Status{}},
+	{Method: http.MethodGet, Path: "/admin/linkcheck", Tag: "Admin", Summary: "Get the dead external links found by the last check", Access: accessAdmin,
+		Response: wiki.LinkReport{}},
+	{Method: http.MethodPost, Path: "/admin/linkcheck", Tag: "Admin", Summary: "Check the external links in the background", Access: accessAdmin,
+		Status: http.StatusAccepted, Response: wiki.LinkReport{}},
+	{Method: http.MethodGet, Path: "/admin/stale-pages", Tag: "Admin", Summary: "Report pages neither modified nor viewed recently, or past their review-by date", Access: accessAdmin,
+		Query: []queryParam{
+			{Name: "months", Type: "integer", Description: "Months without modification, default 6"},
+			{Name: "viewedDays", Type: "integer", Description: "Pages viewed within this many days are not stale, default 90, 0 ignores views"},
+		}, Response: wiki.StaleReport{}},
+	{Method: http.MethodGet, Path: "/admin/duplicates", Tag: "Admin", Summary: "Report pages with identical or near-identical content", Access: accessAdmin,
+		Query: []queryParam{
+			{Name: "threshold", Type: "number", Description: "Similarity from which pages are duplicates, from 0 to 1, default 0.8"},
+		}, Response: wiki.DuplicateReport{}},
+	{Method: http.MethodGet, Path: "/admin/unused-assets", Tag: "Admin", Summary: "Report assets which no page links to", Access: accessAdmin,
+		Response: wiki.UnusedAssetReport{}},
+	{Method: http.MethodPost, Path: "/admin/unused-assets/delete", Tag: "Admin", Summary: "Delete the selected or all unused assets, or list them with dryRun", Access: accessAdmin,
+		Body: struct {
+			URLs   []string `json:"urls"`
+			DryRun bool     `json:"dryRun"`
+		}{},
+		Response: wiki.UnusedAssetCleanup{}},
+	{Method: http.MethodGet, Path: "/admin/reviews", Tag: "Admin", Summary: "List the pages whose review-by date has been reached", Access: accessAdmin,
+		Response: []wiki.ReviewPage{}},
+	{Method: http.MethodPost, Path: "/admin/reviews/remind", Tag: "Admin", Summary: "Remind the owners of the due pages now", Access: accessAdmin,
+		Response: wiki.ReviewReminderResult{}},
+	{Method: http.MethodPost, Path: "/admin/search-alerts/check", Tag: "Admin", Summary: "Check the subscribed searches for new matches now", Access: accessAdmin,
+		Response: wiki.SearchAlertResult{}},
+	{Method: http.MethodGet, Path: "/admin/integrity", Tag: "Admin", Summary: "Get the result of the last integrity check of the search database", Access: accessAdmin,
+		Response: search.IntegrityReport{}},
+	{Method: http.MethodPost, Path: "/admin/integrity", Tag: "Admin", Summary: "Check the search database now and rebuild the index if it is damaged", Access: accessAdmin,
+		Response: search.IntegrityReport{}},
+	{Method: http.MethodGet, Path: "/admin/redirects", Tag: "Admin", Summary: "List the redirects of moved and renamed pages", Access: accessAdmin,
+		Response: []wiki.Redirect{}},
+	{Method: http.MethodDelete, Path: "/admin/redirects", Tag: "Admin", Summary: "Delete the redirect of a route", Access: accessAdmin,
+		Query: []queryParam{{Name: "from", Description: "Old route of the page, e.g. docs/guide"}}, Status: http.StatusNoContent},
+}
@@ -0,0 +1,34 @@
+package http
+
+import (
+	"net/http"
+	"strings"
+)
+
+// StripBasePath serves the handler under the base path, e.g. /wiki behind a reverse proxy which
+// passes the full path on. The base path is removed before the request reaches the handler, so
+// the routes stay the same; the path itself is redirected to the base path with a slash.
+// Requests outside of the base path are not found.
+func StripBasePath(basePath string, next http.Handler) http.Handler {
+	if basePath == "" {
+		return next
+	}
+	strip := http.StripPrefix(basePath, next)
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == basePath {
+			target := basePath + "/"
+			if r.URL.RawQuery != "" {
+				target += "?" + r.URL.RawQuery
+			}
+			http.Redirect(w, r, target, http.StatusMovedPermanently)
+			return
+		}
+		if !strings.HasPrefix(r.URL.Path, basePath+"/") {
+			http.NotFound(w, r)
+			return
+		}
+		// gin prefixes its redirects, e.g. of a trailing slash, with X-Forwarded-Prefix
+		r.Header.Set("X-Forwarded-Prefix", basePath)
+		strip.ServeHTTP(w, r)
+	})
+}
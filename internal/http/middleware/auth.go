@@ -84,3 +84,42 @@ func RequireSelf(wikiInstance *wiki.Wiki) gin.HandlerFunc {
 		c.Next()
 	}
 }
+
+// RequireAuthUnlessPublic lets unauthenticated requests through while the wiki is publicly
+// readable and requires authentication otherwise. The public access mode is read per request,
+// so changing it in the settings takes effect immediately.
+// publicAccess is used as long as the public access mode hasn't been configured in the settings.
+func RequireAuthUnlessPublic(wikiInstance *wiki.Wiki, publicAccess bool) gin.HandlerFunc {
+	requireAuth := RequireAuth(wikiInstance)
+	return func(c *gin.Context) {
+		if wikiInstance.IsPublicAccess(publicAccess) {
+			c.Next()
+			return
+		}
+		requireAuth(c)
+	}
+}
+
+// RequireBasicAuth authenticates with the username or email and password of a user, for
+// clients like WebDAV drives which can't log in first. Bearer tokens are accepted as well.
+func RequireBasicAuth(wikiInstance *wiki.Wiki, realm string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var user *auth.User
+		var err error
+		if identifier, password, ok := c.Request.BasicAuth(); ok {
+			user, err = wikiInstance.GetUserService().GetUserByEmailOrUsernameAndPassword(identifier, password)
+		} else if token, ok := strings.CutPrefix(c.GetHeader("Authorization"), "Bearer "); ok {
+			user, err = wikiInstance.GetAuthService().ValidateToken(token)
+		} else {
+			err = auth.ErrUserInvalidCredentials
+		}
+		if err != nil {
+			c.Header("WWW-Authenticate", `Basic realm="`+realm+`", charset="UTF-8"`)
+			c.AbortWithStatus(http.StatusUnauthorized)
+			return
+		}
+
+		c.Set("user", user)
+		c.Next()
+	}
+}
@@ -0,0 +1,30 @@
+package middleware
+
+import (
+	"net/http"
+	"slices"
+
+	"github.com/Gomez12/wiki/internal/wiki"
+	"github.com/gin-gonic/gin"
+)
+
+// LimitRequestBody limits the body of every request to the maximum request size of the
+// settings, so a single request can't exhaust the memory. Requests announcing a larger body
+// are rejected right away with 413. The routes in ownLimit, like asset uploads, limit their
+// bodies themselves.
+func LimitRequestBody(wikiInstance *wiki.Wiki, ownLimit ...string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if c.Request.Body == nil || slices.Contains(ownLimit, c.FullPath()) {
+			c.Next()
+			return
+		}
+
+		limit := wikiInstance.MaxRequestSize()
+		if c.Request.ContentLength > limit {
+			c.AbortWithStatusJSON(http.StatusRequestEntityTooLarge, gin.H{"error": "request too large"})
+			return
+		}
+		c.Request.Body = http.MaxBytesReader(c.Writer, c.Request.Body, limit)
+		c.Next()
+	}
+}
@@ -0,0 +1,183 @@
+package middleware
+
+import (
+	"compress/gzip"
+	"mime"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+// minCompressSize is the size from which responses are compressed, smaller ones hardly shrink
+const minCompressSize = 1024
+
+// compressibleTypes are the content types worth compressing, images and archives are
+// compressed already
+var compressibleTypes = []string{
+	"application/json",
+	"application/javascript",
+	"application/xml",
+	"image/svg+xml",
+	"text/",
+}
+
+// Compress compresses responses with gzip for clients which accept it, if the content type
+// is compressible, e.g. JSON, HTML, JavaScript or CSS. Responses smaller than 1 KiB, range
+// requests and responses which are encoded already are sent as they are.
+func Compress() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if c.Request.Method == http.MethodHead || c.GetHeader("Range") != "" || !acceptsGzip(c.GetHeader("Accept-Encoding")) {
+			c.Next()
+			return
+		}
+
+		original := c.Writer
+		writer := &gzipWriter{ResponseWriter: original}
+		c.Writer = writer
+		defer func() {
+			if err := writer.close(); err != nil {
+				_ = c.Error(err)
+			}
+			c.Writer = original
+		}()
+		c.Next()
+	}
+}
+
+// acceptsGzip reports whether the Accept-Encoding header allows gzip
+func acceptsGzip(header string) bool {
+	for _, part := range strings.Split(header, ",") {
+		coding, params, _ := strings.Cut(strings.TrimSpace(part), ";")
+		coding = strings.TrimSpace(coding)
+		if coding != "gzip" && coding != "*" {
+			continue
+		}
+		q, ok := strings.CutPrefix(strings.ReplaceAll(params, " ", ""), "q=")
+		if !ok {
+			return true
+		}
+		if value, err := strconv.ParseFloat(q, 64); err == nil && value > 0 {
+			return true
+		}
+	}
+	return false
+}
+
+func compressible(contentType string) bool {
+	mediaType, _, err := mime.ParseMediaType(contentType)
+	if err != nil {
+		return false
+	}
+	for _, prefix := range compressibleTypes {
+		if strings.HasPrefix(mediaType, prefix) {
+			return true
+		}
+	}
+	return false
+}
+
+// gzipWriter holds back the first bytes of the response until it knows whether the
+// response is large enough to compress
+type gzipWriter struct {
+	gin.ResponseWriter
+	buf     []byte
+	decided bool
+	gz      *gzip.Writer
+}
+
+func (w *gzipWriter) Write(data []byte) (int, error) {
+	if !w.decided {
+		if len(w.buf)+len(data) < minCompressSize {
+			w.buf = append(w.buf, data...)
+			return len(data), nil
+		}
+		if err := w.decide(append(w.buf, data...), true); err != nil {
+			return 0, err
+		}
+		return len(data), nil
+	}
+	if w.gz != nil {
+		return w.gz.Write(data)
+	}
+	return w.ResponseWriter.Write(data)
+}
+
+func (w *gzipWriter) WriteString(s string) (int, error) {
+	return w.Write([]byte(s))
+}
+
+// WriteHeaderNow sends the headers, so whether to compress has to be decided by then
+func (w *gzipWriter) WriteHeaderNow() {
+	if !w.decided {
+		length, err := strconv.Atoi(w.ResponseWriter.Header().Get("Content-Length"))
+		_ = w.decide(nil, err != nil || length >= minCompressSize)
+	}
+	w.ResponseWriter.WriteHeaderNow()
+}
+
+func (w *gzipWriter) Flush() {
+	if !w.decided {
+		_ = w.decide(w.buf, true)
+	}
+	if w.gz != nil {
+		_ = w.gz.Flush()
+	}
+	w.ResponseWriter.Flush()
+}
+
+// decide sets up the compression if the response qualifies, and writes the bytes held back
+func (w *gzipWriter) decide(pending []byte, large bool) error {
+	w.decided = true
+	w.buf = nil
+
+	header := w.ResponseWriter.Header()
+	if header.Get("Content-Type") == "" && len(pending) > 0 {
+		header.Set("Content-Type", http.DetectContentType(pending))
+	}
+	status := w.ResponseWriter.Status()
+	if !compressible(header.Get("Content-Type")) || header.Get("Content-Encoding") != "" ||
+		status < http.StatusOK || status == http.StatusNoContent || status == http.StatusNotModified || status == http.StatusPartialContent {
+		if len(pending) == 0 {
+			return nil
+		}
+		_, err := w.ResponseWriter.Write(pending)
+		return err
+	}
+
+	header.Add("Vary", "Accept-Encoding")
+	if large {
+		header.Set("Content-Encoding", "gzip")
+		header.Del("Content-Length")
+		header.Del("Accept-Ranges")
+		// the compressed representation differs byte by byte
+		if etag := header.Get("ETag"); etag != "" && !strings.HasPrefix(etag, "W/") {
+			header.Set("ETag", "W/"+etag)
+		}
+		w.gz = gzip.NewWriter(w.ResponseWriter)
+		if len(pending) > 0 {
+			_, err := w.gz.Write(pending)
+			return err
+		}
+		return nil
+	}
+	if len(pending) == 0 {
+		return nil
+	}
+	_, err := w.ResponseWriter.Write(pending)
+	return err
+}
+
+// close writes what was held back and ends the compressed stream
+func (w *gzipWriter) close() error {
+	if !w.decided {
+		if err := w.decide(w.buf, false); err != nil {
+			return err
+		}
+	}
+	if w.gz != nil {
+		return w.gz.Close()
+	}
+	return nil
+}
@@ -0,0 +1,23 @@
+package middleware
+
+import (
+	"github.com/Gomez12/wiki/internal/core/proxy"
+	"github.com/gin-gonic/gin"
+)
+
+// untrustedForwardedHeaders are removed from requests which don't come from a trusted proxy.
+// gin only reads X-Forwarded-For of trusted proxies itself, see gin.Engine.SetTrustedProxies.
+var untrustedForwardedHeaders = []string{"X-Forwarded-Proto", "X-Forwarded-Host"}
+
+// TrustProxies removes the X-Forwarded headers of requests from clients other than the trusted
+// proxies, so clients connecting directly can't pretend to use HTTPS or another host
+func TrustProxies(trusted proxy.Trusted) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if !trusted.Contains(c.Request.RemoteAddr) {
+			for _, header := range untrustedForwardedHeaders {
+				c.Request.Header.Del(header)
+			}
+		}
+		c.Next()
+	}
+}
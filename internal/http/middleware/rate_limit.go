@@ -0,0 +1,67 @@
+package middleware
+
+import (
+	"math"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/Gomez12/wiki/internal/core/auth"
+	"github.com/Gomez12/wiki/internal/core/ratelimit"
+	"github.com/Gomez12/wiki/internal/wiki"
+	"github.com/gin-gonic/gin"
+)
+
+// RateLimit limits the requests of each client as configured in the settings, requests with
+// a token per user and others per client IP. All routes using the same handler share the
+// buckets. The state of the bucket is returned in the RateLimit-Limit, RateLimit-Remaining and
+// RateLimit-Reset headers; rejected requests get 429 Too Many Requests with Retry-After.
+func RateLimit(wikiInstance *wiki.Wiki) gin.HandlerFunc {
+	perIP, perToken := ratelimit.NewLimiter(), ratelimit.NewLimiter()
+	return func(c *gin.Context) {
+		config := wikiInstance.RateLimits()
+		limiter, rate, key := perIP, config.PerIP.Rate(), c.ClientIP()
+		if user := requestUser(wikiInstance, c); user != nil {
+			limiter, rate, key = perToken, config.PerToken.Rate(), user.ID
+		}
+		if !rate.Enabled() {
+			c.Next()
+			return
+		}
+
+		result := limiter.Allow(key, rate)
+		c.Header("RateLimit-Limit", strconv.Itoa(result.Limit))
+		c.Header("RateLimit-Remaining", strconv.Itoa(result.Remaining))
+		c.Header("RateLimit-Reset", ceilSeconds(result.Reset))
+		if !result.Allowed {
+			c.Header("Retry-After", ceilSeconds(result.RetryAfter))
+			c.AbortWithStatusJSON(http.StatusTooManyRequests, gin.H{"error": "Too many requests, try again later"})
+			return
+		}
+		c.Next()
+	}
+}
+
+// requestUser returns the user authenticated by a previous middleware or by the token of
+// the request, which public routes don't check
+func requestUser(wikiInstance *wiki.Wiki, c *gin.Context) *auth.User {
+	if value, exists := c.Get("user"); exists {
+		if user, ok := value.(*auth.User); ok {
+			return user
+		}
+	}
+	token, ok := strings.CutPrefix(c.GetHeader("Authorization"), "Bearer ")
+	if !ok {
+		return nil
+	}
+	user, err := wikiInstance.GetAuthService().ValidateToken(token)
+	if err != nil {
+		return nil
+	}
+	return user
+}
+
+func ceilSeconds(d time.Duration) string {
+	return strconv.Itoa(int(math.Ceil(d.Seconds())))
+}
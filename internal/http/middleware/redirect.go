@@ -0,0 +1,47 @@
+package middleware
+
+import (
+	"net/http"
+	"strings"
+
+	"github.com/Gomez12/wiki/internal/wiki"
+	"github.com/gin-gonic/gin"
+)
+
+// editPrefix is the path prefix of the editor in the web interface
+const editPrefix = "/e/"
+
+// RedirectMovedPages answers requests for the old route of a moved or renamed page with
+// 301 to its current route. Paths starting with one of the skipped prefixes are passed on.
+func RedirectMovedPages(wikiInstance *wiki.Wiki, skipPrefixes ...string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if c.Request.Method != http.MethodGet && c.Request.Method != http.MethodHead {
+			c.Next()
+			return
+		}
+		p := c.Request.URL.Path
+		for _, prefix := range skipPrefixes {
+			if strings.HasPrefix(p, prefix) {
+				c.Next()
+				return
+			}
+		}
+
+		prefix := "/"
+		if route, ok := strings.CutPrefix(p, editPrefix); ok {
+			prefix, p = editPrefix, route
+		}
+		redirect, err := wikiInstance.ResolveRedirect(p)
+		if err != nil {
+			c.Next()
+			return
+		}
+
+		location := wikiInstance.BasePath() + prefix + redirect.To
+		if c.Request.URL.RawQuery != "" {
+			location += "?" + c.Request.URL.RawQuery
+		}
+		c.Redirect(http.StatusMovedPermanently, location)
+		c.Abort()
+	}
+}
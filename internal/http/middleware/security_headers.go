@@ -0,0 +1,66 @@
+package middleware
+
+import (
+	"crypto/rand"
+	"encoding/base64"
+	"strings"
+
+	"github.com/Gomez12/wiki/internal/core/securityheaders"
+	"github.com/gin-gonic/gin"
+)
+
+const cspNonceKey = "cspNonce"
+
+// SecurityHeaders sets the security headers of every response. A nonce placeholder in the
+// Content-Security-Policy is replaced by a random nonce per request, see CSPNonce.
+// HSTS is only sent with HTTPS requests, also when TLS ends at a reverse proxy.
+func SecurityHeaders(config securityheaders.Config) gin.HandlerFunc {
+	config = config.Resolve()
+	usesNonce := strings.Contains(config.ContentSecurityPolicy, securityheaders.NoncePlaceholder)
+
+	return func(c *gin.Context) {
+		header := c.Writer.Header()
+		header.Set("X-Content-Type-Options", "nosniff")
+		if config.ReferrerPolicy != "" {
+			header.Set("Referrer-Policy", config.ReferrerPolicy)
+		}
+		if config.HSTS != "" && isHTTPS(c) {
+			header.Set("Strict-Transport-Security", config.HSTS)
+		}
+		if policy := config.ContentSecurityPolicy; policy != "" {
+			if usesNonce {
+				nonce := newNonce()
+				c.Set(cspNonceKey, nonce)
+				policy = strings.ReplaceAll(policy, securityheaders.NoncePlaceholder, nonce)
+			}
+			header.Set("Content-Security-Policy", policy)
+		}
+		c.Next()
+	}
+}
+
+// CSPNonce returns the nonce of the request, empty if the policy doesn't use one
+func CSPNonce(c *gin.Context) string {
+	return c.GetString(cspNonceKey)
+}
+
+// AllowCSPSource allows one more source for a directive of the policy of this response,
+// e.g. for a page which loads scripts from a CDN. Call it before writing the body.
+func AllowCSPSource(c *gin.Context, directive, source string) {
+	header := c.Writer.Header()
+	if policy := header.Get("Content-Security-Policy"); policy != "" {
+		header.Set("Content-Security-Policy", securityheaders.AddSource(policy, directive, source))
+	}
+}
+
+func isHTTPS(c *gin.Context) bool {
+	return c.Request.TLS != nil || strings.EqualFold(c.GetHeader("X-Forwarded-Proto"), "https")
+}
+
+func newNonce() string {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		panic("could not generate CSP nonce: " + err.Error())
+	}
+	return base64.StdEncoding.EncodeToString(b)
+}
@@ -0,0 +1,34 @@
+package middleware
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/Gomez12/wiki/internal/core/settings"
+	"github.com/Gomez12/wiki/internal/wiki"
+	"github.com/gin-gonic/gin"
+)
+
+// Timeout cancels the context of the request once the timeout selected from the settings has
+// passed, so the work of slow requests stops instead of piling up. Handlers pass the context
+// on and respond with 504 Gateway Timeout; requests whose handler wrote nothing get it here.
+func Timeout(wikiInstance *wiki.Wiki, seconds func(settings.RequestTimeoutConfig) int) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		timeout := time.Duration(seconds(wikiInstance.RequestTimeouts())) * time.Second
+		if timeout <= 0 {
+			c.Next()
+			return
+		}
+
+		ctx, cancel := context.WithTimeout(c.Request.Context(), timeout)
+		defer cancel()
+		c.Request = c.Request.WithContext(ctx)
+		c.Next()
+
+		if errors.Is(ctx.Err(), context.DeadlineExceeded) && !c.Writer.Written() {
+			c.AbortWithStatusJSON(http.StatusGatewayTimeout, gin.H{"error": "Request timed out"})
+		}
+	}
+}
@@ -0,0 +1,331 @@
+package http
+
+import (
+	"encoding/json"
+	"net/http"
+	"reflect"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/Gomez12/wiki/internal/core/securityheaders"
+	"github.com/Gomez12/wiki/internal/http/middleware"
+	"github.com/gin-gonic/gin"
+)
+
+// openAPIVersion is the version of the API described in the OpenAPI document
+const openAPIVersion = "1.0.0"
+
+// pathParam matches gin path parameters like :id
+var pathParam = regexp.MustCompile(`:([A-Za-z0-9_]+)`)
+
+var (
+	openAPIOnce sync.Once
+	openAPIDoc  []byte
+	openAPIErr  error
+)
+
+// OpenAPIHandler serves the OpenAPI 3 document generated from apiRoutes
+func OpenAPIHandler() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		openAPIOnce.Do(func() {
+			openAPIDoc, openAPIErr = json.MarshalIndent(buildOpenAPI(apiRoutes), "", "  ")
+		})
+		if openAPIErr != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": openAPIErr.Error()})
+			return
+		}
+		c.Data(http.StatusOK, "application/json; charset=utf-8", openAPIDoc)
+	}
+}
+
+const swaggerUICDN = "https://unpkg.com"
+
+// swaggerUIPage loads Swagger UI from a CDN, so it doesn't have to be bundled
+const swaggerUIPage = `<!DOCTYPE html>
+<html lang="en">
+<head>
+  <meta charset="utf-8">
+  <title>LeafWiki API</title>
+  <link rel="stylesheet" href="https://unpkg.com/swagger-ui-dist@5/swagger-ui.css">
+</head>
+<body>
+  <div id="swagger-ui"></div>
+  <script src="https://unpkg.com/swagger-ui-dist@5/swagger-ui-bundle.js"></script>
+  <script nonce="{nonce}">
+    window.ui = SwaggerUIBundle({ url: "{basePath}/api/openapi.json", dom_id: "#swagger-ui" });
+  </script>
+</body>
+</html>`
+
+// SwaggerUIHandler serves Swagger UI for exploring the API of the wiki served under basePath
+func SwaggerUIHandler(basePath string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		// the scripts and styles are loaded from the CDN, the page script is allowed by the nonce
+		middleware.AllowCSPSource(c, "script-src", swaggerUICDN)
+		middleware.AllowCSPSource(c, "style-src", swaggerUICDN)
+		page := strings.ReplaceAll(swaggerUIPage, securityheaders.NoncePlaceholder, middleware.CSPNonce(c))
+		page = strings.ReplaceAll(page, "{basePath}", basePath)
+		c.Data(http.StatusOK, "text/html; charset=utf-8", []byte(page))
+	}
+}
+
+func buildOpenAPI(routes []apiRoute) map[string]interface{} {
+	b := &schemaBuilder{schemas: map[string]interface{}{}, names: map[reflect.Type]string{}}
+	paths := map[string]map[string]interface{}{}
+
+	for _, r := range routes {
+		p := "/api" + pathParam.ReplaceAllString(r.Path, "{$1}")
+		if paths[p] == nil {
+			paths[p] = map[string]interface{}{}
+		}
+		paths[p][strings.ToLower(r.Method)] = b.operation(r)
+	}
+
+	tags := map[string]bool{}
+	for _, r := range routes {
+		tags[r.Tag] = true
+	}
+	tagNames := make([]string, 0, len(tags))
+	for t := range tags {
+		tagNames = append(tagNames, t)
+	}
+	sort.Strings(tagNames)
+	tagList := make([]map[string]string, len(tagNames))
+	for i, t := range tagNames {
+		tagList[i] = map[string]string{"name": t}
+	}
+
+	b.schemas["Error"] = map[string]interface{}{
+		"type": "object",
+		"properties": map[string]interface{}{
+			"error":  map[string]string{"type": "string"},
+			"fields": map[string]interface{}{"type": "object", "additionalProperties": map[string]interface{}{"type": "array", "items": map[string]string{"type": "string"}}},
+		},
+	}
+
+	return map[string]interface{}{
+		"openapi": "3.0.3",
+		"info": map[string]string{
+			"title":       "LeafWiki API",
+			"version":     openAPIVersion,
+			"description": "REST API of LeafWiki. Authenticate with POST /api/auth/login and pass the token as Bearer token.",
+		},
+		"tags":  tagList,
+		"paths": paths,
+		"components": map[string]interface{}{
+			"schemas": b.schemas,
+			"securitySchemes": map[string]interface{}{
+				"bearerAuth": map[string]string{"type": "http", "scheme": "bearer", "bearerFormat": "JWT"},
+			},
+		},
+	}
+}
+
+func (b *schemaBuilder) operation(r apiRoute) map[string]interface{} {
+	op := map[string]interface{}{
+		"tags":        []string{r.Tag},
+		"summary":     r.Summary,
+		"operationId": operationID(r),
+	}
+
+	switch r.Access {
+	case accessRead:
+		op["description"] = "Readable without authentication while public access is enabled."
+		op["security"] = []map[string][]string{{}, {"bearerAuth": {}}}
+	case accessAuth:
+		op["security"] = []map[string][]string{{"bearerAuth": {}}}
+	case accessAdmin:
+		op["description"] = "Requires the admin role."
+		op["security"] = []map[string][]string{{"bearerAuth": {}}}
+	}
+
+	var params []map[string]interface{}
+	for _, m := range pathParam.FindAllStringSubmatch(r.Path, -1) {
+		params = append(params, map[string]interface{}{
+			"name": m[1], "in": "path", "required": true,
+			"schema": map[string]string{"type": "string"},
+		})
+	}
+	for _, q := range r.Query {
+		typ := q.Type
+		if typ == "" {
+			typ = "string"
+		}
+		params = append(params, map[string]interface{}{
+			"name": q.Name, "in": "query", "required": q.Required, "description": q.Description,
+			"schema": map[string]string{"type": typ},
+		})
+	}
+	if len(params) > 0 {
+		op["parameters"] = params
+	}
+
+	if r.Body != nil {
+		op["requestBody"] = map[string]interface{}{
+			"required": true,
+			"content": map[string]interface{}{
+				"application/json": map[string]interface{}{"schema": b.schemaFor(reflect.TypeOf(r.Body))},
+			},
+		}
+	}
+	if r.Multipart != "" {
+		op["requestBody"] = map[string]interface{}{
+			"required": true,
+			"content": map[string]interface{}{
+				"multipart/form-data": map[string]interface{}{"schema": map[string]interface{}{
+					"type":       "object",
+					"required":   []string{r.Multipart},
+					"properties": map[string]interface{}{r.Multipart: map[string]string{"type": "string", "format": "binary"}},
+				}},
+			},
+		}
+	}
+
+	status := r.Status
+	if status == 0 {
+		status = http.StatusOK
+	}
+	success := map[string]interface{}{"description": http.StatusText(status)}
+	switch {
+	case r.ContentType != "":
+		success["content"] = map[string]interface{}{r.ContentType: map[string]interface{}{"schema": map[string]string{"type": "string"}}}
+	case r.Response != nil:
+		success["content"] = map[string]interface{}{
+			"application/json": map[string]interface{}{"schema": b.schemaFor(reflect.TypeOf(r.Response))},
+		}
+	}
+	errorResponse := map[string]interface{}{
+		"description": "Error",
+		"content": map[string]interface{}{
+			"application/json": map[string]interface{}{"schema": map[string]string{"$ref": "#/components/schemas/Error"}},
+		},
+	}
+	op["responses"] = map[string]interface{}{
+		strconv.Itoa(status): success,
+		"default":            errorResponse,
+	}
+	return op
+}
+
+// operationID derives an id like getPagesById from the method and path
+func operationID(r apiRoute) string {
+	var sb strings.Builder
+	sb.WriteString(strings.ToLower(r.Method))
+	for _, part := range strings.FieldsFunc(r.Path, func(c rune) bool { return c == '/' || c == '-' }) {
+		if name, ok := strings.CutPrefix(part, ":"); ok {
+			sb.WriteString("By")
+			part = name
+		}
+		sb.WriteString(strings.ToUpper(part[:1]) + part[1:])
+	}
+	return sb.String()
+}
+
+// schemaBuilder derives JSON schemas from Go types, using the json and binding struct tags.
+// Named structs become components, so recursive types like the tree are supported.
+type schemaBuilder struct {
+	schemas map[string]interface{}
+	names   map[reflect.Type]string
+}
+
+var timeType = reflect.TypeOf(time.Time{})
+
+func (b *schemaBuilder) schemaFor(t reflect.Type) map[string]interface{} {
+	for t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+
+	switch {
+	case t == timeType:
+		return map[string]interface{}{"type": "string", "format": "date-time"}
+	case t.Kind() == reflect.String:
+		return map[string]interface{}{"type": "string"}
+	case t.Kind() == reflect.Bool:
+		return map[string]interface{}{"type": "boolean"}
+	case t.Kind() >= reflect.Int && t.Kind() <= reflect.Uint64:
+		return map[string]interface{}{"type": "integer"}
+	case t.Kind() == reflect.Float32 || t.Kind() == reflect.Float64:
+		return map[string]interface{}{"type": "number"}
+	case t.Kind() == reflect.Slice || t.Kind() == reflect.Array:
+		return map[string]interface{}{"type": "array", "items": b.schemaFor(t.Elem())}
+	case t.Kind() == reflect.Map:
+		return map[string]interface{}{"type": "object", "additionalProperties": b.schemaFor(t.Elem())}
+	case t.Kind() == reflect.Struct:
+		if t.Name() == "" {
+			return b.objectSchema(t)
+		}
+		return map[string]interface{}{"$ref": "#/components/schemas/" + b.component(t)}
+	default:
+		return map[string]interface{}{}
+	}
+}
+
+// component registers a named struct as component and returns its name
+func (b *schemaBuilder) component(t reflect.Type) string {
+	if name, ok := b.names[t]; ok {
+		return name
+	}
+
+	name := strings.ToUpper(t.Name()[:1]) + t.Name()[1:]
+	if _, taken := b.schemas[name]; taken {
+		// another package has a type with the same name, e.g. api.Page and tree.Page
+		pkg := t.PkgPath()[strings.LastIndex(t.PkgPath(), "/")+1:]
+		name = strings.ToUpper(pkg[:1]) + pkg[1:] + name
+	}
+	b.names[t] = name
+	// registered before the properties are built, so recursive references resolve
+	b.schemas[name] = map[string]interface{}{}
+	b.schemas[name] = b.objectSchema(t)
+	return name
+}
+
+func (b *schemaBuilder) objectSchema(t reflect.Type) map[string]interface{} {
+	properties := map[string]interface{}{}
+	var required []string
+	b.addFields(t, properties, &required)
+
+	schema := map[string]interface{}{"type": "object", "properties": properties}
+	if len(required) > 0 {
+		sort.Strings(required)
+		schema["required"] = required
+	}
+	return schema
+}
+
+func (b *schemaBuilder) addFields(t reflect.Type, properties map[string]interface{}, required *[]string) {
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		tag := f.Tag.Get("json")
+		if tag == "-" {
+			continue
+		}
+		name, _, _ := strings.Cut(tag, ",")
+
+		// embedded structs without a json name are flattened like encoding/json does
+		if f.Anonymous && name == "" {
+			ft := f.Type
+			for ft.Kind() == reflect.Pointer {
+				ft = ft.Elem()
+			}
+			if ft.Kind() == reflect.Struct {
+				b.addFields(ft, properties, required)
+				continue
+			}
+		}
+		if !f.IsExported() {
+			continue
+		}
+		if name == "" {
+			name = f.Name
+		}
+
+		properties[name] = b.schemaFor(f.Type)
+		if strings.Contains(f.Tag.Get("binding"), "required") {
+			*required = append(*required, name)
+		}
+	}
+}
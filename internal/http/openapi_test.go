@@ -0,0 +1,110 @@
+package http
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/Gomez12/wiki/internal/wiki"
+)
+
+func TestAPIRoutesDocumentEveryRoute(t *testing.T) {
+	w, err := wiki.NewWiki(t.TempDir(), "admin", "secretkey", false)
+	if err != nil {
+		t.Fatalf("Failed to create wiki: %v", err)
+	}
+	defer w.Close()
+	router := NewRouter(w, false, "")
+
+	documented := map[string]bool{}
+	for _, r := range apiRoutes {
+		key := r.Method + " /api" + r.Path
+		if documented[key] {
+			t.Errorf("route %s is documented twice", key)
+		}
+		documented[key] = true
+	}
+
+	registered := map[string]bool{}
+	for _, r := range router.Routes() {
+		if !strings.HasPrefix(r.Path, "/api/") {
+			continue
+		}
+		key := r.Method + " " + r.Path
+		registered[key] = true
+		if !documented[key] {
+			t.Errorf("route %s is missing in apiRoutes", key)
+		}
+	}
+	for key := range documented {
+		if !registered[key] {
+			t.Errorf("documented route %s is not registered", key)
+		}
+	}
+}
+
+func TestOpenAPIEndpoint(t *testing.T) {
+	w, err := wiki.NewWiki(t.TempDir(), "admin", "secretkey", false)
+	if err != nil {
+		t.Fatalf("Failed to create wiki: %v", err)
+	}
+	defer w.Close()
+	router := NewRouter(w, false, "")
+
+	rec := httptest.NewRecorder()
+	router.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/api/openapi.json", nil))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("Expected 200 OK, got %d", rec.Code)
+	}
+
+	var doc struct {
+		OpenAPI string `json:"openapi"`
+		Paths   map[string]map[string]struct {
+			OperationID string                   `json:"operationId"`
+			Parameters  []map[string]interface{} `json:"parameters"`
+			Security    []map[string][]string    `json:"security"`
+		} `json:"paths"`
+		Components struct {
+			Schemas map[string]struct {
+				Properties map[string]map[string]interface{} `json:"properties"`
+				Required   []string                          `json:"required"`
+			} `json:"schemas"`
+		} `json:"components"`
+	}
+	if err := json.Unmarshal(rec.Body.Bytes(), &doc); err != nil {
+		t.Fatalf("invalid JSON: %v", err)
+	}
+	if !strings.HasPrefix(doc.OpenAPI, "3.") {
+		t.Errorf("expected OpenAPI 3, got %q", doc.OpenAPI)
+	}
+
+	getPage, ok := doc.Paths["/api/pages/{id}"]["get"]
+	if !ok {
+		t.Fatalf("expected GET /api/pages/{id} to be documented")
+	}
+	if getPage.OperationID != "getPagesById" || len(getPage.Parameters) != 2 || getPage.Parameters[0]["in"] != "path" || getPage.Parameters[1]["name"] != "asOf" {
+		t.Errorf("unexpected operation: %+v", getPage)
+	}
+	if len(doc.Paths["/api/users"]["get"].Security) != 1 {
+		t.Errorf("expected the user listing to require authentication")
+	}
+
+	// embedded structs are flattened and recursive types are referenced
+	page := doc.Components.Schemas["Page"]
+	for _, field := range []string{"id", "title", "slug", "content", "path"} {
+		if _, ok := page.Properties[field]; !ok {
+			t.Errorf("expected Page to have %s, got %v", field, page.Properties)
+		}
+	}
+	if children := doc.Components.Schemas["Node"].Properties["children"]; children["items"] == nil {
+		t.Errorf("expected Node children to reference Node, got %v", children)
+	}
+
+	rec = httptest.NewRecorder()
+	router.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/api/docs", nil))
+	if rec.Code != http.StatusOK || !strings.Contains(rec.Body.String(), "/api/openapi.json") {
+		t.Errorf("Expected Swagger UI page, got %d", rec.Code)
+	}
+}
@@ -3,11 +3,14 @@ package http
 import (
 	"embed"
 	"io/fs"
-	"log"
+	"log/slog"
 	"net/http"
+	"slices"
 	"strings"
 	"time"
 
+	"github.com/Gomez12/wiki/internal/core/securityheaders"
+	"github.com/Gomez12/wiki/internal/core/settings"
 	"github.com/Gomez12/wiki/internal/http/api"
 	"github.com/Gomez12/wiki/internal/http/middleware"
 	"github.com/Gomez12/wiki/internal/wiki"
@@ -31,6 +34,9 @@ var EmbedFrontend = "false"
 // Environment is a flag to set the environment
 var Environment = "development"
 
+// basicAuthRoutes accept basic auth besides the Bearer token, which browsers send on their own
+var basicAuthRoutes = []string{webdavPrefix, webdavPrefix + "/*path", pprofPrefix + "/*profile"}
+
 // NewRouter creates a new HTTP router for the wiki application.
 // Parameters:
 //   - wikiInstance: the wiki instance to serve
@@ -46,72 +52,118 @@ func NewRouter(wikiInstance *wiki.Wiki, publicAccess bool, injectCodeInHeader st
 		gin.SetMode(gin.DebugMode)
 	}
 
-	router := gin.Default()
+	router := gin.New()
+	// the client IP is only taken from X-Forwarded-For of trusted proxies
+	if err := router.SetTrustedProxies(wikiInstance.TrustedProxies().Strings()); err != nil {
+		panic("invalid trusted proxies: " + err.Error())
+	}
+	router.Use(middleware.TrustProxies(wikiInstance.TrustedProxies()))
+	router.Use(middleware.RequestID(), middleware.RequestLogger(), gin.Recovery())
+	router.Use(middleware.SecurityHeaders(wikiInstance.SecurityHeaders()))
+	router.Use(middleware.Compress())
+	// asset uploads have the upload limit of the settings
+	// uploads, imported archives and files written over WebDAV are limited by maxUploadSize
+	router.Use(middleware.LimitRequestBody(wikiInstance, "/api/pages/:id/assets", "/api/pages/:id/assets/paste",
+		"/api/admin/import/upload", webdavPrefix, webdavPrefix+"/*path"))
 	if EnableCors == "true" {
-		router.Use(cors.New(cors.Config{
-			AllowOrigins:     []string{"*"},
-			AllowMethods:     []string{"GET", "POST", "PUT", "DELETE", "OPTIONS"},
-			AllowHeaders:     []string{"Origin", "Content-Type", "Authorization"},
-			ExposeHeaders:    []string{"Content-Length"},
-			AllowCredentials: true,
-			MaxAge:           12 * time.Hour,
-		}))
+		// the API authenticates with the Bearer token set by the client, which needs no
+		// credentials mode. The routes accepting basic auth are left out, they reject
+		// cross-origin requests, see middleware.RejectCrossOrigin.
+		handleCors := cors.New(cors.Config{
+			AllowOrigins:  []string{"*"},
+			AllowMethods:  []string{"GET", "POST", "PUT", "DELETE", "OPTIONS"},
+			AllowHeaders:  []string{"Origin", "Content-Type", "Authorization"},
+			ExposeHeaders: []string{"Content-Length"},
+			MaxAge:        12 * time.Hour,
+		})
+		router.Use(func(c *gin.Context) {
+			if slices.Contains(basicAuthRoutes, c.FullPath()) {
+				c.Next()
+				return
+			}
+			handleCors(c)
+		})
 	}
 
-	router.StaticFS("/assets", gin.Dir(wikiInstance.GetAssetService().GetAssetsDir(), true))
+	router.GET("/assets/*filepath", api.AssetFileHandler(wikiInstance))
+	router.HEAD("/assets/*filepath", api.AssetFileHandler(wikiInstance))
+	if wikiInstance.ObsidianEnabled() {
+		router.GET(wiki.VaultFilesPrefix+"*filepath", api.VaultFileHandler(wikiInstance))
+	}
+
+	// search, rendering, exports and uploads share the rate limit of each client
+	rateLimit := middleware.RateLimit(wikiInstance)
+	// their work is cancelled after the timeouts of the settings
+	searchTimeout := middleware.Timeout(wikiInstance, func(t settings.RequestTimeoutConfig) int { return t.SearchSeconds })
+	renderTimeout := middleware.Timeout(wikiInstance, func(t settings.RequestTimeoutConfig) int { return t.RenderSeconds })
+	exportTimeout := middleware.Timeout(wikiInstance, func(t settings.RequestTimeoutConfig) int { return t.ExportSeconds })
 
 	nonAuthApiGroup := router.Group("/api")
 	{
 		// Auth
 		nonAuthApiGroup.POST("/auth/login", api.LoginUserHandler(wikiInstance))
 		nonAuthApiGroup.POST("/auth/refresh-token", api.RefreshTokenUserHandler(wikiInstance))
-		nonAuthApiGroup.GET("/config", func(c *gin.Context) {
-			c.JSON(200, gin.H{"publicAccess": publicAccess})
-		})
+		nonAuthApiGroup.GET("/config", api.GetConfigHandler(wikiInstance, publicAccess))
+		nonAuthApiGroup.POST("/setup", api.SetupHandler(wikiInstance))
 
-		// PUBLIC READ ACCESS (if enabled via flag or env):
-		// These routes are accessible without authentication when publicAccess == true.
-		// Only safe, read-only operations are allowed here (GET tree/pages).
-		if publicAccess {
-			nonAuthApiGroup.GET("/tree", api.GetTreeHandler(wikiInstance))
-			nonAuthApiGroup.GET("/pages/by-path", api.GetPageByPathHandler(wikiInstance))
-			nonAuthApiGroup.GET("/pages/lookup", api.LookupPagePathHandler(wikiInstance))
-			nonAuthApiGroup.GET("/pages/:id", api.GetPageHandler(wikiInstance))
-			nonAuthApiGroup.GET("/pages/history", api.GetPageHistoryHandler(wikiInstance))
-
-			// Search
-			nonAuthApiGroup.GET("/search/status", api.SearchStatusHandler(wikiInstance))
-			nonAuthApiGroup.GET("/search", api.SearchHandler(wikiInstance))
-		}
+		// API documentation
+		nonAuthApiGroup.GET("/openapi.json", OpenAPIHandler())
+		nonAuthApiGroup.GET("/docs", SwaggerUIHandler(wikiInstance.BasePath()))
+	}
+
+	// PUBLIC READ ACCESS (if enabled via flag, env or settings):
+	// These routes are accessible without authentication while public access is enabled.
+	// Only safe, read-only operations are allowed here (GET tree/pages).
+	readApiGroup := router.Group("/api")
+	readApiGroup.Use(middleware.RequireAuthUnlessPublic(wikiInstance, publicAccess))
+	{
+		readApiGroup.GET("/tree", api.GetTreeHandler(wikiInstance))
+		readApiGroup.GET("/pages/by-path", api.GetPageByPathHandler(wikiInstance))
+		readApiGroup.GET("/pages/lookup", api.LookupPagePathHandler(wikiInstance))
+		readApiGroup.GET("/pages/:id", api.GetPageHandler(wikiInstance))
+		readApiGroup.GET("/pages/history", api.GetPageHistoryHandler(wikiInstance))
+		readApiGroup.GET("/pages/history/:entryId", api.GetPageHistoryEntryHandler(wikiInstance))
+		readApiGroup.GET("/pages/labels", api.GetPageLabelsHandler(wikiInstance))
+		readApiGroup.GET("/pages/labels/:label", api.GetPageAtLabelHandler(wikiInstance))
+		readApiGroup.GET("/history", api.GetHistoryHandler(wikiInstance))
+		readApiGroup.GET("/pages/:id/status-rollup", api.GetStatusRollupHandler(wikiInstance))
+		readApiGroup.GET("/pages/:id/meta", api.GetPageMetaHandler(wikiInstance))
+		readApiGroup.GET("/pages/:id/toc", api.GetPageTOCHandler(wikiInstance))
+		readApiGroup.GET("/pages/:id/lint", api.GetPageLintHandler(wikiInstance))
+		readApiGroup.GET("/pages/:id/similar", api.GetSimilarPagesHandler(wikiInstance))
+		readApiGroup.GET("/pages/:id/html", rateLimit, renderTimeout, api.GetRenderedPageHandler(wikiInstance))
+		readApiGroup.GET("/pages/:id/export", rateLimit, exportTimeout, api.ExportPageHandler(wikiInstance))
+
+		// Search
+		readApiGroup.GET("/search/status", api.SearchStatusHandler(wikiInstance))
+		readApiGroup.GET("/search", rateLimit, searchTimeout, api.SearchHandler(wikiInstance))
+
+		// Stats & badges
+		readApiGroup.GET("/stats", api.GetStatsHandler(wikiInstance))
+		readApiGroup.GET("/badges/:name", api.BadgeHandler(wikiInstance))
 	}
 
 	requiresAuthGroup := router.Group("/api")
 	requiresAuthGroup.Use(middleware.RequireAuth(wikiInstance))
 	{
-		// If public access is disabled, we need to ensure that the tree and pages routes are protected
-		// and require authentication. If public access is enabled, these routes are already handled
-		if !publicAccess {
-			requiresAuthGroup.GET("/tree", api.GetTreeHandler(wikiInstance))
-			requiresAuthGroup.GET("/pages/:id", api.GetPageHandler(wikiInstance))
-			requiresAuthGroup.GET("/pages/lookup", api.LookupPagePathHandler(wikiInstance))
-			requiresAuthGroup.GET("/pages/by-path", api.GetPageByPathHandler(wikiInstance))
-			requiresAuthGroup.GET("/pages/history", api.GetPageHistoryHandler(wikiInstance))
-
-			// Search
-			requiresAuthGroup.GET("/search/status", api.SearchStatusHandler(wikiInstance))
-			requiresAuthGroup.GET("/search", api.SearchHandler(wikiInstance))
-		}
-
 		// Pages
 		requiresAuthGroup.POST("/pages", api.CreatePageHandler(wikiInstance))
 		requiresAuthGroup.POST("/pages/ensure", api.EnsurePageHandler(wikiInstance))
 		requiresAuthGroup.POST("/pages/copy/:id", api.CopyPageHandler(wikiInstance))
 		requiresAuthGroup.PUT("/pages/:id", api.UpdatePageHandler(wikiInstance))
+		requiresAuthGroup.GET("/pages/:id/autosave", api.GetDraftHandler(wikiInstance))
+		requiresAuthGroup.PUT("/pages/:id/autosave", api.SaveDraftHandler(wikiInstance))
+		requiresAuthGroup.DELETE("/pages/:id/autosave", api.DiscardDraftHandler(wikiInstance))
+		requiresAuthGroup.POST("/pages/:id/undo", api.UndoPageHandler(wikiInstance))
+		requiresAuthGroup.POST("/history/:id/label", api.LabelHistoryEntryHandler(wikiInstance))
 		requiresAuthGroup.DELETE("/pages/:id", api.DeletePageHandler(wikiInstance))
 
 		requiresAuthGroup.PUT("/pages/:id/move", api.MovePageHandler(wikiInstance))
+		requiresAuthGroup.GET("/pages/:id/move-check", api.CheckMovePageHandler(wikiInstance))
 		requiresAuthGroup.PUT("/pages/:id/sort", api.SortPagesHandler(wikiInstance))
+		requiresAuthGroup.POST("/tree/restructure", api.RestructureTreeHandler(wikiInstance))
 		requiresAuthGroup.GET("/pages/slug-suggestion", api.SuggestSlugHandler(wikiInstance))
+		requiresAuthGroup.GET("/pages/similar", api.FindSimilarPagesHandler(wikiInstance))
 
 		// User
 		requiresAuthGroup.POST("/users", middleware.RequireAdmin(wikiInstance), api.CreateUserHandler(wikiInstance))
@@ -122,13 +174,85 @@ func NewRouter(wikiInstance *wiki.Wiki, publicAccess bool, injectCodeInHeader st
 		// Change Own Password
 		requiresAuthGroup.PUT("/users/me/password", api.ChangeOwnPasswordUserHandler(wikiInstance))
 
+		// Reading positions
+		requiresAuthGroup.GET("/users/me/recently-read", api.GetRecentlyReadHandler(wikiInstance))
+		requiresAuthGroup.GET("/users/me/recently-read/tracking", api.GetRecentPagesTrackingHandler(wikiInstance))
+		requiresAuthGroup.PUT("/users/me/recently-read/tracking", api.SetRecentPagesTrackingHandler(wikiInstance))
+
+		// Favorites
+		requiresAuthGroup.GET("/me/favorites", api.GetFavoritesHandler(wikiInstance))
+		requiresAuthGroup.POST("/me/favorites", api.AddFavoriteHandler(wikiInstance))
+		requiresAuthGroup.DELETE("/me/favorites/:pageId", api.RemoveFavoriteHandler(wikiInstance))
+
+		// Saved searches
+		requiresAuthGroup.GET("/users/me/saved-searches", api.GetSavedSearchesHandler(wikiInstance))
+		requiresAuthGroup.POST("/users/me/saved-searches", api.CreateSavedSearchHandler(wikiInstance))
+		requiresAuthGroup.PUT("/users/me/saved-searches/:id", api.UpdateSavedSearchHandler(wikiInstance))
+		requiresAuthGroup.DELETE("/users/me/saved-searches/:id", api.DeleteSavedSearchHandler(wikiInstance))
+		requiresAuthGroup.GET("/users/me/search-alerts", api.GetSearchAlertsHandler(wikiInstance))
+		requiresAuthGroup.POST("/users/me/search-alerts/read", api.MarkSearchAlertsReadHandler(wikiInstance))
+		// Spellcheck
+		requiresAuthGroup.POST("/pages/:id/spellcheck", api.SpellcheckPageHandler(wikiInstance))
+		requiresAuthGroup.GET("/spellcheck/languages", api.GetSpellcheckLanguagesHandler(wikiInstance))
+		requiresAuthGroup.GET("/spellcheck/dictionary", api.GetCustomWordsHandler(wikiInstance))
+		requiresAuthGroup.POST("/spellcheck/dictionary", api.AddCustomWordHandler(wikiInstance))
+		requiresAuthGroup.DELETE("/spellcheck/dictionary/:word", api.RemoveCustomWordHandler(wikiInstance))
+		requiresAuthGroup.POST("/validate", api.ValidateMarkdownHandler())
+
+		requiresAuthGroup.GET("/pages/:id/reading-position", api.GetReadingPositionHandler(wikiInstance))
+		requiresAuthGroup.PUT("/pages/:id/reading-position", api.SaveReadingPositionHandler(wikiInstance))
+
 		// Assets
-		requiresAuthGroup.POST("/pages/:id/assets", api.UploadAssetHandler(wikiInstance))
+		requiresAuthGroup.POST("/pages/:id/assets", rateLimit, api.UploadAssetHandler(wikiInstance))
+		requiresAuthGroup.POST("/pages/:id/assets/paste", rateLimit, api.PasteAssetHandler(wikiInstance))
 		requiresAuthGroup.GET("/pages/:id/assets", api.ListAssetsHandler(wikiInstance))
 		requiresAuthGroup.PUT("/pages/:id/assets/rename", api.RenameAssetHandler(wikiInstance))
+		requiresAuthGroup.PUT("/pages/:id/assets/:name", api.MoveAssetHandler(wikiInstance))
 		requiresAuthGroup.DELETE("/pages/:id/assets/:name", api.DeleteAssetHandler(wikiInstance))
+		requiresAuthGroup.GET("/pages/:id/assets/:name/versions", api.GetAssetVersionsHandler(wikiInstance))
+		requiresAuthGroup.GET("/pages/:id/assets/:name/versions/:hash", api.GetAssetVersionFileHandler(wikiInstance))
+		requiresAuthGroup.POST("/pages/:id/assets/:name/versions/:hash/restore", api.RestoreAssetVersionHandler(wikiInstance))
+
+		// Admin
+		requiresAuthGroup.POST("/admin/reindex", middleware.RequireAdmin(wikiInstance), api.ReindexHandler(wikiInstance))
+		requiresAuthGroup.GET("/admin/index-errors", middleware.RequireAdmin(wikiInstance), api.GetIndexErrorsHandler(wikiInstance))
+		requiresAuthGroup.POST("/admin/import/analyze", middleware.RequireAdmin(wikiInstance), api.AnalyzeImportHandler(wikiInstance))
+		requiresAuthGroup.POST("/admin/import/upload", middleware.RequireAdmin(wikiInstance), api.UploadImportHandler(wikiInstance))
+		requiresAuthGroup.POST("/admin/import/apply", middleware.RequireAdmin(wikiInstance), api.ApplyImportHandler(wikiInstance))
+		requiresAuthGroup.POST("/admin/replace", middleware.RequireAdmin(wikiInstance), api.ReplaceHandler(wikiInstance))
+		requiresAuthGroup.GET("/export/html", middleware.RequireAdmin(wikiInstance), rateLimit, exportTimeout, api.ExportHTMLHandler(wikiInstance))
+		requiresAuthGroup.GET("/admin/settings/password-policy", middleware.RequireAdmin(wikiInstance), api.GetPasswordPolicyHandler(wikiInstance))
+		requiresAuthGroup.PUT("/admin/settings/password-policy", middleware.RequireAdmin(wikiInstance), api.UpdatePasswordPolicyHandler(wikiInstance))
+		requiresAuthGroup.GET("/admin/settings", middleware.RequireAdmin(wikiInstance), api.GetSettingsHandler(wikiInstance))
+		requiresAuthGroup.PUT("/admin/settings", middleware.RequireAdmin(wikiInstance), api.UpdateSettingsHandler(wikiInstance))
+		requiresAuthGroup.POST("/admin/config/reload", middleware.RequireAdmin(wikiInstance), api.ReloadConfigHandler(wikiInstance))
+		requiresAuthGroup.GET("/admin/git-sync", middleware.RequireAdmin(wikiInstance), api.GetGitSyncStatusHandler(wikiInstance))
+		requiresAuthGroup.POST("/admin/git-sync", middleware.RequireAdmin(wikiInstance), api.SyncGitHandler(wikiInstance))
+		requiresAuthGroup.GET("/admin/linkcheck", middleware.RequireAdmin(wikiInstance), api.GetLinkReportHandler(wikiInstance))
+		requiresAuthGroup.POST("/admin/linkcheck", middleware.RequireAdmin(wikiInstance), api.CheckLinksHandler(wikiInstance))
+		requiresAuthGroup.GET("/admin/stale-pages", middleware.RequireAdmin(wikiInstance), api.GetStalePagesHandler(wikiInstance))
+		requiresAuthGroup.GET("/admin/duplicates", middleware.RequireAdmin(wikiInstance), api.GetDuplicatePagesHandler(wikiInstance))
+		requiresAuthGroup.GET("/admin/unused-assets", middleware.RequireAdmin(wikiInstance), api.GetUnusedAssetsHandler(wikiInstance))
+		requiresAuthGroup.POST("/admin/unused-assets/delete", middleware.RequireAdmin(wikiInstance), api.DeleteUnusedAssetsHandler(wikiInstance))
+		requiresAuthGroup.GET("/admin/reviews", middleware.RequireAdmin(wikiInstance), api.GetDueReviewsHandler(wikiInstance))
+		requiresAuthGroup.POST("/admin/reviews/remind", middleware.RequireAdmin(wikiInstance), api.SendReviewRemindersHandler(wikiInstance))
+		requiresAuthGroup.POST("/admin/search-alerts/check", middleware.RequireAdmin(wikiInstance), api.CheckSavedSearchesHandler(wikiInstance))
+		requiresAuthGroup.GET("/admin/integrity", middleware.RequireAdmin(wikiInstance), api.GetIntegrityReportHandler(wikiInstance))
+		requiresAuthGroup.POST("/admin/integrity", middleware.RequireAdmin(wikiInstance), api.CheckIntegrityHandler(wikiInstance))
+		requiresAuthGroup.GET("/admin/redirects", middleware.RequireAdmin(wikiInstance), api.GetRedirectsHandler(wikiInstance))
+		requiresAuthGroup.DELETE("/admin/redirects", middleware.RequireAdmin(wikiInstance), api.DeleteRedirectHandler(wikiInstance))
+	}
+
+	if wikiInstance.WebDAVEnabled() {
+		registerWebDAV(router, wikiInstance)
+	}
+	if wikiInstance.ProfilingEnabled() {
+		registerProfiling(router, wikiInstance)
 	}
 
+	// old routes of moved and renamed pages are redirected before the frontend is served
+	redirectMovedPages := middleware.RedirectMovedPages(wikiInstance, "/api", "/assets", "/vault", "/static", webdavPrefix, pprofPrefix)
+
 	// If frontend embedding is enabled, serve it on all unknown routes
 	if EmbedFrontend == "true" {
 		fsys, err := fs.Sub(frontend, "dist")
@@ -142,30 +266,33 @@ func NewRouter(wikiInstance *wiki.Wiki, publicAccess bool, injectCodeInHeader st
 		}
 
 		// Serve the embedded frontend files js, css, ...
-		router.StaticFS("/static", http.FS(staticFS))
+		router.Group("/static", cacheStaticFiles(newEmbeddedETags(staticFS))).StaticFS("/", http.FS(staticFS))
 
+		etags := newEmbeddedETags(fsys)
 		router.GET("/favicon.svg", func(c *gin.Context) {
-			file, err := fsys.Open("favicon.svg")
-			if err != nil {
+			etag := etags.get("favicon.svg")
+			if etag == "" {
 				c.Status(http.StatusNotFound)
 				return
 			}
-			stat, err := file.Stat()
-			if err != nil {
-				c.Status(http.StatusInternalServerError)
-				return
-			}
-
-			c.DataFromReader(http.StatusOK, stat.Size(), "image/svg+xml", file, nil)
+			// the name doesn't change with the content, so clients have to revalidate
+			c.Header("Cache-Control", "no-cache")
+			c.Header("ETag", etag)
+			c.FileFromFS("favicon.svg", http.FS(fsys))
 		})
 
-		router.NoRoute(func(c *gin.Context) {
+		router.NoRoute(redirectMovedPages, func(c *gin.Context) {
 			if c.Request.Method == http.MethodGet &&
 				!strings.HasPrefix(c.Request.URL.Path, "/api") &&
 				!strings.HasPrefix(c.Request.URL.Path, "/assets") &&
+				!strings.HasPrefix(c.Request.URL.Path, "/vault") &&
+				!strings.HasPrefix(c.Request.URL.Path, webdavPrefix) &&
+				!strings.HasPrefix(c.Request.URL.Path, pprofPrefix) &&
 				!strings.HasPrefix(c.Request.URL.Path, "/static") {
 
 				c.Writer.Header().Set("Content-Type", "text/html; charset=utf-8")
+				// index.html references the current bundle and carries a new nonce each time
+				c.Header("Cache-Control", "no-cache")
 				data, err := fs.ReadFile(fsys, "index.html")
 				if err != nil {
 					c.Status(http.StatusNotFound)
@@ -177,10 +304,15 @@ func NewRouter(wikiInstance *wiki.Wiki, publicAccess bool, injectCodeInHeader st
 					// replaces the closing </head> tag with the injected code
 					newHtml := strings.Replace(html, "</head>", "  "+injectCodeInHeader+"\n  </head>", 1)
 					if newHtml == html {
-						log.Printf("Warning: could not inject code into header, </head> tag not found")
+						slog.Warn("could not inject code into header, </head> tag not found")
 					}
 					data = []byte(newHtml)
 				}
+				data = withBasePath(data, wikiInstance.BasePath())
+				if nonce := middleware.CSPNonce(c); nonce != "" {
+					// the scripts of the bundle and the injected code are allowed by the nonce
+					data = securityheaders.AddNonce(data, nonce)
+				}
 
 				c.Data(http.StatusOK, "text/html; charset=utf-8", data)
 
@@ -194,6 +326,8 @@ func NewRouter(wikiInstance *wiki.Wiki, publicAccess bool, injectCodeInHeader st
 			}
 		})
 
+	} else {
+		router.NoRoute(redirectMovedPages)
 	}
 
 	return router
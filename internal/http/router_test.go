@@ -1,15 +1,34 @@
 package http
 
 import (
+	"archive/zip"
 	"bytes"
+	"compress/gzip"
+	"context"
 	"encoding/json"
+	"fmt"
+	"io"
 	"mime/multipart"
 	"net/http"
 	"net/http/httptest"
+	"os"
+	"os/exec"
+	"path/filepath"
 	"strings"
 	"testing"
-
+	"testing/fstest"
+	"time"
+
+	"github.com/Gomez12/wiki/internal/core/gitsync"
+	"github.com/Gomez12/wiki/internal/core/lint"
+	"github.com/Gomez12/wiki/internal/core/securityheaders"
+	"github.com/Gomez12/wiki/internal/core/settings"
+	"github.com/Gomez12/wiki/internal/core/staticsite"
+	"github.com/Gomez12/wiki/internal/core/validate"
+	"github.com/Gomez12/wiki/internal/http/api"
+	"github.com/Gomez12/wiki/internal/test_utils"
 	"github.com/Gomez12/wiki/internal/wiki"
+	"github.com/gin-gonic/gin"
 )
 
 func authenticatedRequest(t *testing.T, router http.Handler, method, url string, body *strings.Reader) *httptest.ResponseRecorder {
@@ -620,6 +639,32 @@ func TestMovePage_InTheSamePlace(t *testing.T) {
 	}
 }
 
+func TestMoveCheckEndpoint(t *testing.T) {
+	wikiInstance, _ := wiki.NewWiki(t.TempDir(), "admin", "secretkey", false)
+	router := NewRouter(wikiInstance, false, "")
+
+	a, _ := wikiInstance.CreatePage(nil, "Section A", "section-a")
+	conflictPage, _ := wikiInstance.CreatePage(&a.ID, "Section B", "section-b")
+	existing, _ := wikiInstance.CreatePage(nil, "Section B", "section-b")
+
+	rec := authenticatedRequest(t, router, http.MethodGet, "/api/pages/"+conflictPage.ID+"/move-check?parentId=root", nil)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("Expected status 200, got %d: %s", rec.Code, rec.Body.String())
+	}
+	var check wiki.MoveCheck
+	if err := json.Unmarshal(rec.Body.Bytes(), &check); err != nil {
+		t.Fatalf("invalid response: %v", err)
+	}
+	if check.Conflict != wiki.MoveConflictSlugTaken || check.ConflictingPageID != existing.ID {
+		t.Errorf("expected a slug conflict, got %+v", check)
+	}
+
+	rec = authenticatedRequest(t, router, http.MethodGet, "/api/pages/missing/move-check", nil)
+	if rec.Code != http.StatusNotFound {
+		t.Errorf("Expected status 404, got %d", rec.Code)
+	}
+}
+
 func TestSortPagesEndpoint(t *testing.T) {
 	wikiInstance, _ := wiki.NewWiki(t.TempDir(), "admin", "secretkey", false)
 	router := NewRouter(wikiInstance, false, "")
@@ -809,6 +854,240 @@ func TestCreateUser_InvalidRole(t *testing.T) {
 	}
 }
 
+func TestRequestIDHeader(t *testing.T) {
+	wikiInstance, _ := wiki.NewWiki(t.TempDir(), "admin", "secretkey", false)
+	router := NewRouter(wikiInstance, false, "")
+
+	rec := httptest.NewRecorder()
+	router.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/api/config", nil))
+	if id := rec.Header().Get("X-Request-ID"); len(id) != 16 {
+		t.Errorf("Expected a generated request id, got %q", id)
+	}
+
+	req := httptest.NewRequest(http.MethodGet, "/api/config", nil)
+	req.Header.Set("X-Request-ID", "proxy-id.42")
+	rec = httptest.NewRecorder()
+	router.ServeHTTP(rec, req)
+	if id := rec.Header().Get("X-Request-ID"); id != "proxy-id.42" {
+		t.Errorf("Expected the request id of the proxy to be kept, got %q", id)
+	}
+
+	req = httptest.NewRequest(http.MethodGet, "/api/config", nil)
+	req.Header.Set("X-Request-ID", "bad id\nforged log line")
+	rec = httptest.NewRecorder()
+	router.ServeHTTP(rec, req)
+	if id := rec.Header().Get("X-Request-ID"); strings.Contains(id, " ") || len(id) != 16 {
+		t.Errorf("Expected an invalid request id to be replaced, got %q", id)
+	}
+}
+
+func TestSettingsEndpoint(t *testing.T) {
+	wikiInstance, _ := wiki.NewWiki(t.TempDir(), "admin", "secretkey", false)
+	router := NewRouter(wikiInstance, false, "")
+
+	anonymousTree := func() int {
+		rec := httptest.NewRecorder()
+		router.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/api/tree", nil))
+		return rec.Code
+	}
+	if code := anonymousTree(); code != http.StatusUnauthorized {
+		t.Fatalf("Expected 401 for anonymous tree request, got %d", code)
+	}
+
+	rec := authenticatedRequest(t, router, http.MethodGet, "/api/admin/settings", nil)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("Expected 200 OK, got %d", rec.Code)
+	}
+	var got map[string]interface{}
+	_ = json.Unmarshal(rec.Body.Bytes(), &got)
+	if got["siteTitle"] != "LeafWiki" || got["publicAccess"] != nil || got["math"] != "off" {
+		t.Errorf("Unexpected default settings: %v", got)
+	}
+
+	// options missing in the request keep their value
+	body := `{"siteTitle": "Team Wiki", "publicAccess": true, "historyRetentionDays": 90, "math": "mathml"}`
+	rec = authenticatedRequest(t, router, http.MethodPut, "/api/admin/settings", strings.NewReader(body))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("Expected 200 OK for settings update, got %d: %s", rec.Code, rec.Body.String())
+	}
+	got = nil
+	_ = json.Unmarshal(rec.Body.Bytes(), &got)
+	if got["siteTitle"] != "Team Wiki" || got["maxUploadSize"] != float64(500<<20) || got["historyRetentionDays"] != float64(90) {
+		t.Errorf("Unexpected settings after update: %v", got)
+	}
+
+	// public access takes effect without a restart
+	if code := anonymousTree(); code != http.StatusOK {
+		t.Errorf("Expected 200 for anonymous tree request after enabling public access, got %d", code)
+	}
+	configRec := httptest.NewRecorder()
+	router.ServeHTTP(configRec, httptest.NewRequest(http.MethodGet, "/api/config", nil))
+	if !strings.Contains(configRec.Body.String(), `"publicAccess":true`) || !strings.Contains(configRec.Body.String(), `"siteTitle":"Team Wiki"`) ||
+		!strings.Contains(configRec.Body.String(), `"math":"mathml"`) {
+		t.Errorf("Unexpected config: %s", configRec.Body.String())
+	}
+
+	for _, invalid := range []string{
+		`{"siteTitle": "  "}`,
+		`{"maxUploadSize": 0}`,
+		`{"streamThreshold": -1}`,
+		`{"historyRetentionDays": -1}`,
+		`{"webhook": {"url": "ftp://example.com"}}`,
+		`{"math": "latex"}`,
+		`{"ignorePatterns": ["drafts/[abc"]}`,
+	} {
+		rec = authenticatedRequest(t, router, http.MethodPut, "/api/admin/settings", strings.NewReader(invalid))
+		if rec.Code != http.StatusBadRequest {
+			t.Errorf("Expected 400 for %s, got %d", invalid, rec.Code)
+		}
+	}
+}
+
+func TestSetupEndpoint(t *testing.T) {
+	wikiInstance, err := wiki.NewWiki(t.TempDir(), "", "secretkey", false)
+	if err != nil {
+		t.Fatalf("Failed to create wiki: %v", err)
+	}
+	defer wikiInstance.Close()
+	router := NewRouter(wikiInstance, false, "")
+
+	config := func() string {
+		rec := httptest.NewRecorder()
+		router.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/api/config", nil))
+		return rec.Body.String()
+	}
+	if !strings.Contains(config(), `"setupRequired":true`) {
+		t.Fatalf("Expected setupRequired without users, got %s", config())
+	}
+
+	// there are no default credentials
+	login := httptest.NewRecorder()
+	router.ServeHTTP(login, httptest.NewRequest(http.MethodPost, "/api/auth/login", strings.NewReader(`{"identifier": "admin", "password": "admin"}`)))
+	if login.Code != http.StatusUnauthorized {
+		t.Errorf("Expected 401 for the former default credentials, got %d", login.Code)
+	}
+
+	setup := func(body string) *httptest.ResponseRecorder {
+		rec := httptest.NewRecorder()
+		req := httptest.NewRequest(http.MethodPost, "/api/setup", strings.NewReader(body))
+		req.Header.Set("Content-Type", "application/json")
+		router.ServeHTTP(rec, req)
+		return rec
+	}
+	if rec := setup(`{"username": "alice", "email": "alice@example.com", "password": "s3cret-passw0rd", "siteTitle": "` + strings.Repeat("x", 101) + `"}`); rec.Code != http.StatusBadRequest {
+		t.Errorf("Expected 400 for an invalid site title, got %d", rec.Code)
+	}
+	if rec := setup(`{"username": "alice", "email": "not-an-email", "password": "s3cret-passw0rd"}`); rec.Code != http.StatusBadRequest {
+		t.Errorf("Expected 400 for an invalid email, got %d", rec.Code)
+	}
+	if !strings.Contains(config(), `"setupRequired":true`) {
+		t.Errorf("Expected a failed setup to leave the setup open")
+	}
+
+	rec := setup(`{"username": "alice", "email": "alice@example.com", "password": "s3cret-passw0rd", "siteTitle": "Team Wiki", "publicAccess": true}`)
+	if rec.Code != http.StatusCreated {
+		t.Fatalf("Expected 201 Created, got %d: %s", rec.Code, rec.Body.String())
+	}
+	var token struct {
+		Token string `json:"token"`
+		User  struct {
+			Username string `json:"username"`
+			Role     string `json:"role"`
+		} `json:"user"`
+	}
+	_ = json.Unmarshal(rec.Body.Bytes(), &token)
+	if token.Token == "" || token.User.Username != "alice" || token.User.Role != "admin" {
+		t.Errorf("Expected the tokens of the new admin, got %s", rec.Body.String())
+	}
+	if body := config(); !strings.Contains(body, `"setupRequired":false`) || !strings.Contains(body, `"siteTitle":"Team Wiki"`) ||
+		!strings.Contains(body, `"publicAccess":true`) {
+		t.Errorf("Unexpected config after setup: %s", body)
+	}
+
+	// the endpoint is locked once a user exists
+	if rec := setup(`{"username": "mallory", "email": "mallory@example.com", "password": "s3cret-passw0rd"}`); rec.Code != http.StatusConflict {
+		t.Errorf("Expected 409 after the setup, got %d", rec.Code)
+	}
+}
+
+func TestReloadConfigEndpoint(t *testing.T) {
+	wikiInstance, _ := wiki.NewWiki(t.TempDir(), "admin", "secretkey", false)
+	router := NewRouter(wikiInstance, false, "")
+
+	rec := authenticatedRequest(t, router, http.MethodPost, "/api/admin/config/reload", nil)
+	if rec.Code != http.StatusNotFound {
+		t.Errorf("Expected 404 without config file, got %d", rec.Code)
+	}
+
+	// the reload function stands in for reading the config file of the server
+	patch := `{"siteTitle": "Reloaded"}`
+	reload := func() error {
+		_, err := wikiInstance.ApplySettings([]byte(patch))
+		return err
+	}
+	wikiInstance, _ = wiki.NewWiki(t.TempDir(), "admin", "secretkey", false, wiki.WithConfigReload(reload))
+	router = NewRouter(wikiInstance, false, "")
+
+	rec = authenticatedRequest(t, router, http.MethodPost, "/api/admin/config/reload", nil)
+	if rec.Code != http.StatusOK || !strings.Contains(rec.Body.String(), `"siteTitle":"Reloaded"`) {
+		t.Fatalf("Expected 200 with the reloaded settings, got %d: %s", rec.Code, rec.Body.String())
+	}
+
+	patch = `{"maxUploadSize": 0}`
+	rec = authenticatedRequest(t, router, http.MethodPost, "/api/admin/config/reload", nil)
+	if rec.Code != http.StatusBadRequest {
+		t.Errorf("Expected 400 for invalid settings in the config file, got %d", rec.Code)
+	}
+	if s, _ := wikiInstance.GetSettings(); s.SiteTitle != "Reloaded" || s.MaxUploadSize == 0 {
+		t.Errorf("Expected the settings to be kept after a failed reload, got %+v", s)
+	}
+}
+
+func TestPasswordPolicyEndpoint(t *testing.T) {
+	wikiInstance, _ := wiki.NewWiki(t.TempDir(), "admin", "secretkey", false)
+	router := NewRouter(wikiInstance, false, "")
+
+	policy := `{"minLength": 12, "requireDigit": true, "rejectBreached": true}`
+	rec := authenticatedRequest(t, router, http.MethodPut, "/api/admin/settings/password-policy", strings.NewReader(policy))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("Expected 200 OK for policy update, got %d: %s", rec.Code, rec.Body.String())
+	}
+
+	rec = authenticatedRequest(t, router, http.MethodGet, "/api/admin/settings/password-policy", nil)
+	var got map[string]interface{}
+	_ = json.Unmarshal(rec.Body.Bytes(), &got)
+	if got["minLength"] != float64(12) || got["requireDigit"] != true || got["rejectBreached"] != true {
+		t.Errorf("Unexpected policy: %v", got)
+	}
+
+	rec = authenticatedRequest(t, router, http.MethodPut, "/api/admin/settings/password-policy", strings.NewReader(`{"minLength": 0}`))
+	if rec.Code != http.StatusBadRequest {
+		t.Errorf("Expected 400 for invalid policy, got %d", rec.Code)
+	}
+
+	for _, password := range []string{"password1234", "longbutnodigits", "short1"} {
+		body := `{"username": "sam", "email": "sam@example.com", "password": "` + password + `", "role": "editor"}`
+		rec = authenticatedRequest(t, router, http.MethodPost, "/api/users", strings.NewReader(body))
+		if rec.Code != http.StatusBadRequest {
+			t.Errorf("Expected 400 for password %q, got %d", password, rec.Code)
+		}
+	}
+
+	body := `{"username": "sam", "email": "sam@example.com", "password": "tr0ub4dor-and-3", "role": "editor"}`
+	rec = authenticatedRequest(t, router, http.MethodPost, "/api/users", strings.NewReader(body))
+	if rec.Code != http.StatusCreated {
+		t.Fatalf("Expected 201 for valid password, got %d: %s", rec.Code, rec.Body.String())
+	}
+	var user map[string]interface{}
+	_ = json.Unmarshal(rec.Body.Bytes(), &user)
+
+	update := `{"username": "sam", "email": "sam@example.com", "password": "qwerty123", "role": "editor"}`
+	rec = authenticatedRequest(t, router, http.MethodPut, "/api/users/"+user["id"].(string), strings.NewReader(update))
+	if rec.Code != http.StatusBadRequest {
+		t.Errorf("Expected 400 for breached password on update, got %d", rec.Code)
+	}
+}
+
 func TestGetUsersEndpoint(t *testing.T) {
 	wikiInstance, _ := wiki.NewWiki(t.TempDir(), "admin", "secretkey", false)
 	router := NewRouter(wikiInstance, false, "")
@@ -818,14 +1097,65 @@ func TestGetUsersEndpoint(t *testing.T) {
 		t.Fatalf("Expected 200 OK, got %d", rec.Code)
 	}
 
-	var users []map[string]interface{}
-	if err := json.Unmarshal(rec.Body.Bytes(), &users); err != nil {
+	var resp struct {
+		Users []map[string]interface{} `json:"users"`
+		Total int                      `json:"total"`
+	}
+	if err := json.Unmarshal(rec.Body.Bytes(), &resp); err != nil {
 		t.Fatalf("Failed to decode response: %v", err)
 	}
 
-	if len(users) == 0 {
+	if len(resp.Users) == 0 || resp.Total != len(resp.Users) {
 		t.Errorf("Expected at least one user (admin), got none")
 	}
+	if _, ok := resp.Users[0]["password"]; ok {
+		t.Errorf("Expected no password in user listing")
+	}
+}
+
+func TestGetUsersEndpoint_PaginationSearchAndSort(t *testing.T) {
+	wikiInstance, _ := wiki.NewWiki(t.TempDir(), "admin", "secretkey", false)
+	router := NewRouter(wikiInstance, false, "")
+
+	for _, name := range []string{"carol", "alice", "bob"} {
+		if _, err := wikiInstance.CreateUser(name, name+"@example.com", "secretpassword", "editor"); err != nil {
+			t.Fatalf("Failed to create user: %v", err)
+		}
+	}
+
+	decode := func(rec *httptest.ResponseRecorder) ([]string, int) {
+		t.Helper()
+		var resp struct {
+			Users []struct {
+				Username string `json:"username"`
+			} `json:"users"`
+			Total int `json:"total"`
+		}
+		if err := json.Unmarshal(rec.Body.Bytes(), &resp); err != nil {
+			t.Fatalf("Failed to decode response: %v - %s", err, rec.Body.String())
+		}
+		var names []string
+		for _, u := range resp.Users {
+			names = append(names, u.Username)
+		}
+		return names, resp.Total
+	}
+
+	names, total := decode(authenticatedRequest(t, router, http.MethodGet, "/api/users?limit=2&offset=1", nil))
+	if total != 4 || strings.Join(names, ",") != "alice,bob" {
+		t.Errorf("Expected alice,bob of 4, got %v of %d", names, total)
+	}
+
+	names, total = decode(authenticatedRequest(t, router, http.MethodGet, "/api/users?q=EXAMPLE.com&sort=email&order=desc", nil))
+	if total != 3 || strings.Join(names, ",") != "carol,bob,alice" {
+		t.Errorf("Expected carol,bob,alice, got %v of %d", names, total)
+	}
+
+	for _, query := range []string{"sort=password", "order=up", "limit=-1", "limit=0", "limit=1000"} {
+		if rec := authenticatedRequest(t, router, http.MethodGet, "/api/users?"+query, nil); rec.Code != http.StatusBadRequest {
+			t.Errorf("Expected 400 for %s, got %d", query, rec.Code)
+		}
+	}
 }
 
 func TestUpdateUserEndpoint(t *testing.T) {
@@ -876,11 +1206,13 @@ func TestDeleteAdminUser_ShouldFail(t *testing.T) {
 
 	// Default Admin holen
 	rec := authenticatedRequest(t, router, http.MethodGet, "/api/users", nil)
-	var users []map[string]interface{}
-	_ = json.Unmarshal(rec.Body.Bytes(), &users)
+	var resp struct {
+		Users []map[string]interface{} `json:"users"`
+	}
+	_ = json.Unmarshal(rec.Body.Bytes(), &resp)
 
 	var adminID string
-	for _, u := range users {
+	for _, u := range resp.Users {
 		if u["role"] == "admin" {
 			adminID = u["id"].(string)
 		}
@@ -926,6 +1258,125 @@ func TestRequireAuthMiddleware_Unauthorized(t *testing.T) {
 	}
 }
 
+// The API must not authenticate with ambient credentials like cookies, else state-changing
+// routes would need CSRF protection
+func TestAuth_NoCookieSessions(t *testing.T) {
+	wikiInstance, _ := wiki.NewWiki(t.TempDir(), "admin", "secretkey", false)
+	router := NewRouter(wikiInstance, false, "")
+
+	login := httptest.NewRecorder()
+	router.ServeHTTP(login, httptest.NewRequest(http.MethodPost, "/api/auth/login", strings.NewReader(`{"identifier": "admin", "password": "admin"}`)))
+	if login.Code != http.StatusOK {
+		t.Fatalf("Expected 200 OK on login, got %d", login.Code)
+	}
+	if cookies := login.Result().Cookies(); len(cookies) != 0 {
+		t.Fatalf("Expected no session cookies, got %v", cookies)
+	}
+	var loginResp map[string]interface{}
+	_ = json.Unmarshal(login.Body.Bytes(), &loginResp)
+
+	req := httptest.NewRequest(http.MethodPost, "/api/pages", strings.NewReader(`{"title": "Forged", "slug": "forged"}`))
+	req.Header.Set("Content-Type", "application/json")
+	req.AddCookie(&http.Cookie{Name: "token", Value: loginResp["token"].(string)})
+	rec := httptest.NewRecorder()
+	router.ServeHTTP(rec, req)
+	if rec.Code != http.StatusUnauthorized {
+		t.Errorf("Expected 401 for a request with a cookie only, got %d", rec.Code)
+	}
+}
+
+func TestSecurityHeaders(t *testing.T) {
+	wikiInstance, _ := wiki.NewWiki(t.TempDir(), "admin", "secretkey", false)
+	router := NewRouter(wikiInstance, false, "")
+
+	rec := httptest.NewRecorder()
+	router.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/api/config", nil))
+	if rec.Header().Get("X-Content-Type-Options") != "nosniff" || rec.Header().Get("Referrer-Policy") != securityheaders.DefaultReferrerPolicy {
+		t.Errorf("Missing security headers: %v", rec.Header())
+	}
+	if rec.Header().Get("Strict-Transport-Security") != "" {
+		t.Errorf("Expected no HSTS over plain HTTP")
+	}
+	policy := rec.Header().Get("Content-Security-Policy")
+	if !strings.Contains(policy, "script-src 'self' 'nonce-") || strings.Contains(policy, securityheaders.NoncePlaceholder) {
+		t.Errorf("Expected a policy with a nonce, got %q", policy)
+	}
+
+	// the docs load Swagger UI from the CDN, their inline script gets the nonce
+	req := httptest.NewRequest(http.MethodGet, "/api/docs", nil)
+	req.RemoteAddr = "10.0.0.2:4711"
+	req.Header.Set("X-Forwarded-Proto", "https")
+	rec = httptest.NewRecorder()
+	router.ServeHTTP(rec, req)
+	if rec.Header().Get("Strict-Transport-Security") != securityheaders.DefaultHSTS {
+		t.Errorf("Expected HSTS behind a TLS proxy, got %q", rec.Header().Get("Strict-Transport-Security"))
+	}
+	policy = rec.Header().Get("Content-Security-Policy")
+	nonce := strings.SplitN(strings.SplitN(policy, "'nonce-", 2)[1], "'", 2)[0]
+	if !strings.Contains(policy, swaggerUICDN) || !strings.Contains(rec.Body.String(), `<script nonce="`+nonce+`">`) {
+		t.Errorf("Expected the docs to be allowed by the policy %q:\n%s", policy, rec.Body.String())
+	}
+
+	custom, _ := wiki.NewWiki(t.TempDir(), "admin", "secretkey", false, wiki.WithSecurityHeaders(securityheaders.Config{
+		ContentSecurityPolicy: "off",
+		ReferrerPolicy:        "no-referrer",
+	}))
+	rec = httptest.NewRecorder()
+	NewRouter(custom, false, "").ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/api/config", nil))
+	if rec.Header().Get("Content-Security-Policy") != "" || rec.Header().Get("Referrer-Policy") != "no-referrer" {
+		t.Errorf("Expected the overrides, got %v", rec.Header())
+	}
+}
+
+// Browsers send cached basic auth credentials with cross-site requests on their own, so the
+// routes accepting basic auth must reject them
+func TestBasicAuthRoutes_RejectCrossOrigin(t *testing.T) {
+	wikiInstance, _ := wiki.NewWiki(t.TempDir(), "admin", "secretkey", false, wiki.WithWebDAV(true), wiki.WithProfiling(true))
+	defer wikiInstance.Close()
+	router := NewRouter(wikiInstance, false, "")
+
+	request := func(method, url string, headers map[string]string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, url, nil)
+		req.SetBasicAuth("admin", "admin")
+		for name, value := range headers {
+			req.Header.Set(name, value)
+		}
+		rec := httptest.NewRecorder()
+		router.ServeHTTP(rec, req)
+		return rec
+	}
+
+	for _, tc := range []struct {
+		method, url string
+		headers     map[string]string
+	}{
+		{"PROPFIND", "/webdav/", map[string]string{"Origin": "https://evil.example"}},
+		{http.MethodPut, "/webdav/notes.md", map[string]string{"Origin": "https://evil.example", "Sec-Fetch-Site": "cross-site"}},
+		{http.MethodOptions, "/webdav/notes.md", map[string]string{"Origin": "https://evil.example", "Access-Control-Request-Method": "PUT"}},
+		{http.MethodGet, "/debug/pprof/heap", map[string]string{"Sec-Fetch-Site": "cross-site"}},
+		{http.MethodGet, "/debug/pprof/cmdline", map[string]string{"Origin": "null"}},
+	} {
+		rec := request(tc.method, tc.url, tc.headers)
+		if rec.Code != http.StatusForbidden || rec.Header().Get("Access-Control-Allow-Origin") != "" {
+			t.Errorf("%s %s %v: expected 403 without CORS headers, got %d %v", tc.method, tc.url, tc.headers, rec.Code, rec.Header())
+		}
+	}
+
+	// the same origin and clients other than browsers are let through
+	if rec := request("PROPFIND", "/webdav/", map[string]string{"Origin": "http://example.com", "Sec-Fetch-Site": "same-origin"}); rec.Code != http.StatusMultiStatus {
+		t.Errorf("Expected 207 for the same origin, got %d", rec.Code)
+	}
+	if rec := request(http.MethodGet, "/debug/pprof/cmdline", nil); rec.Code != http.StatusOK {
+		t.Errorf("Expected 200 without Origin, got %d", rec.Code)
+	}
+
+	// the API allows every origin, but without credentials
+	rec := request(http.MethodGet, "/api/config", map[string]string{"Origin": "https://other.example"})
+	if rec.Header().Get("Access-Control-Allow-Origin") != "*" || rec.Header().Get("Access-Control-Allow-Credentials") != "" {
+		t.Errorf("Expected CORS for any origin without credentials, got %v", rec.Header())
+	}
+}
+
 func TestRequireAuthMiddleware_InvalidToken(t *testing.T) {
 	wikiInstance, _ := wiki.NewWiki(t.TempDir(), "admin", "secretkey", false)
 	router := NewRouter(wikiInstance, false, "")
@@ -1019,6 +1470,130 @@ func TestAssetEndpoints(t *testing.T) {
 	}
 }
 
+func TestAssetFile_RangeRequests(t *testing.T) {
+	wikiInstance, _ := wiki.NewWiki(t.TempDir(), "admin", "secretkey", false)
+	router := NewRouter(wikiInstance, false, "")
+
+	page, err := wikiInstance.CreatePage(nil, "Media", "media")
+	if err != nil {
+		t.Fatalf("Failed to create page: %v", err)
+	}
+	content := []byte("0123456789abcdefghij")
+	file, _, err := test_utils.CreateMultipartFile("clip.mp4", content)
+	if err != nil {
+		t.Fatalf("Failed to create test file: %v", err)
+	}
+	defer file.Close()
+	if _, err := wikiInstance.UploadAsset(page.ID, file, "clip.mp4"); err != nil {
+		t.Fatalf("UploadAsset failed: %v", err)
+	}
+
+	rangeRequest := func() *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodGet, "/assets/"+page.ID+"/clip.mp4", nil)
+		req.Header.Set("Range", "bytes=10-14")
+		rec := httptest.NewRecorder()
+		router.ServeHTTP(rec, req)
+		return rec
+	}
+
+	// buffered below the stream threshold, streamed from disk with a threshold of 0
+	for _, threshold := range []string{"1048576", "0"} {
+		rec := authenticatedRequest(t, router, http.MethodPut, "/api/admin/settings", strings.NewReader(`{"streamThreshold": `+threshold+`}`))
+		if rec.Code != http.StatusOK {
+			t.Fatalf("Expected 200 OK for settings update, got %d: %s", rec.Code, rec.Body.String())
+		}
+
+		rec = rangeRequest()
+		if rec.Code != http.StatusPartialContent {
+			t.Fatalf("Expected 206 Partial Content, got %d", rec.Code)
+		}
+		if rec.Body.String() != "abcde" || rec.Header().Get("Content-Range") != "bytes 10-14/20" {
+			t.Errorf("Unexpected range response %q %s", rec.Body.String(), rec.Header().Get("Content-Range"))
+		}
+		if rec.Header().Get("Content-Type") != "video/mp4" || rec.Header().Get("Accept-Ranges") != "bytes" {
+			t.Errorf("Unexpected headers: %v", rec.Header())
+		}
+	}
+
+	rec := httptest.NewRecorder()
+	router.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/assets/"+page.ID+"/missing.mp4", nil))
+	if rec.Code != http.StatusNotFound {
+		t.Errorf("Expected 404 for a missing asset, got %d", rec.Code)
+	}
+}
+
+func TestAssetVersionEndpoints(t *testing.T) {
+	wikiInstance, _ := wiki.NewWiki(t.TempDir(), "admin", "secretkey", false)
+	router := NewRouter(wikiInstance, false, "")
+
+	page, err := wikiInstance.CreatePage(nil, "Versions", "versions")
+	if err != nil {
+		t.Fatalf("Failed to create page: %v", err)
+	}
+	login := authenticatedRequest(t, router, http.MethodPost, "/api/auth/login", strings.NewReader(`{"identifier": "admin", "password": "admin"}`))
+	var loginResp map[string]interface{}
+	if err := json.Unmarshal(login.Body.Bytes(), &loginResp); err != nil {
+		t.Fatalf("Invalid login JSON: %v", err)
+	}
+	token := loginResp["token"].(string)
+
+	upload := func(content string) string {
+		body := &bytes.Buffer{}
+		writer := multipart.NewWriter(body)
+		part, _ := writer.CreateFormFile("file", "notes.txt")
+		_, _ = part.Write([]byte(content))
+		_ = writer.WriteField("replace", "true")
+		writer.Close()
+		req := httptest.NewRequest(http.MethodPost, "/api/pages/"+page.ID+"/assets", body)
+		req.Header.Set("Content-Type", writer.FormDataContentType())
+		req.Header.Set("Authorization", "Bearer "+token)
+		rec := httptest.NewRecorder()
+		router.ServeHTTP(rec, req)
+		if rec.Code != http.StatusCreated {
+			t.Fatalf("Expected 201 Created on upload, got %d - %s", rec.Code, rec.Body.String())
+		}
+		var resp map[string]string
+		_ = json.Unmarshal(rec.Body.Bytes(), &resp)
+		return resp["file"]
+	}
+	if first, second := upload("v1"), upload("v2"); first != second {
+		t.Fatalf("Expected the asset to be replaced, got %s and %s", first, second)
+	}
+
+	versionsPath := "/api/pages/" + page.ID + "/assets/notes.txt/versions"
+	rec := authenticatedRequest(t, router, http.MethodGet, versionsPath, nil)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("Expected 200 OK for versions, got %d: %s", rec.Code, rec.Body.String())
+	}
+	var versions struct {
+		Current  *struct{ Hash string }
+		Versions []struct{ Hash string }
+	}
+	if err := json.Unmarshal(rec.Body.Bytes(), &versions); err != nil || versions.Current == nil || len(versions.Versions) != 1 {
+		t.Fatalf("Unexpected versions: %s", rec.Body.String())
+	}
+	hash := versions.Versions[0].Hash
+
+	rec = authenticatedRequest(t, router, http.MethodGet, versionsPath+"/"+hash, nil)
+	if rec.Code != http.StatusOK || rec.Body.String() != "v1" {
+		t.Errorf("Expected the previous content, got %d %q", rec.Code, rec.Body.String())
+	}
+	rec = authenticatedRequest(t, router, http.MethodPost, versionsPath+"/"+hash+"/restore", nil)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("Expected 200 OK for restore, got %d: %s", rec.Code, rec.Body.String())
+	}
+	assetRec := httptest.NewRecorder()
+	router.ServeHTTP(assetRec, httptest.NewRequest(http.MethodGet, "/assets/"+page.ID+"/notes.txt", nil))
+	if assetRec.Body.String() != "v1" {
+		t.Errorf("Expected the restored content, got %q", assetRec.Body.String())
+	}
+
+	rec = authenticatedRequest(t, router, http.MethodGet, versionsPath+"/"+strings.Repeat("0", 64), nil)
+	if rec.Code != http.StatusNotFound {
+		t.Errorf("Expected 404 for an unknown version, got %d", rec.Code)
+	}
+}
+
 // Lets check the indexing status
 func TestIndexingStatusEndpoint(t *testing.T) {
 	// Lets call /api/search/status
@@ -1040,3 +1615,1610 @@ func TestIndexingStatusEndpoint(t *testing.T) {
 		t.Errorf("Expected 'active' field in response, got: %v", status)
 	}
 }
+
+func TestIndexErrorsEndpoint(t *testing.T) {
+	wikiInstance, _ := wiki.NewWiki(t.TempDir(), "admin", "secretkey", false)
+	router := NewRouter(wikiInstance, false, "")
+
+	rec := authenticatedRequest(t, router, http.MethodGet, "/api/admin/index-errors", nil)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("Expected 200 OK, got %d - %s", rec.Code, rec.Body.String())
+	}
+	var results []map[string]interface{}
+	if err := json.Unmarshal(rec.Body.Bytes(), &results); err != nil {
+		t.Fatalf("Failed to parse JSON: %v", err)
+	}
+	if results == nil || len(results) != 0 {
+		t.Errorf("Expected an empty list, got %s", rec.Body.String())
+	}
+}
+
+func TestReindexEndpoint(t *testing.T) {
+	wikiInstance, _ := wiki.NewWiki(t.TempDir(), "admin", "secretkey", false)
+	router := NewRouter(wikiInstance, false, "")
+
+	if _, err := wikiInstance.CreatePage(nil, "Reindex Me", "reindex-me"); err != nil {
+		t.Fatalf("Failed to create page: %v", err)
+	}
+
+	rec := authenticatedRequest(t, router, http.MethodPost, "/api/admin/reindex", nil)
+	if rec.Code != http.StatusAccepted {
+		t.Fatalf("Expected 202 Accepted, got %d - %s", rec.Code, rec.Body.String())
+	}
+
+	deadline := time.Now().Add(5 * time.Second)
+	for wikiInstance.IsIndexingActive() {
+		if time.Now().After(deadline) {
+			t.Fatal("Reindex did not finish in time")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	statusRec := authenticatedRequest(t, router, http.MethodGet, "/api/search/status", nil)
+	var status map[string]interface{}
+	if err := json.Unmarshal(statusRec.Body.Bytes(), &status); err != nil {
+		t.Fatalf("Failed to parse JSON: %v", err)
+	}
+
+	total, _ := status["total"].(float64)
+	if total < 2 || status["processed"] != total {
+		t.Errorf("Expected all files to be processed, got: %v", status)
+	}
+
+	result, err := wikiInstance.Search(t.Context(), "reindex", 0, 10)
+	if err != nil {
+		t.Fatalf("Search failed: %v", err)
+	}
+	if result.Count != 1 {
+		t.Errorf("Expected 1 search hit after reindex, got %d", result.Count)
+	}
+}
+
+func TestReadingPositionEndpoints(t *testing.T) {
+	wikiInstance, _ := wiki.NewWiki(t.TempDir(), "admin", "secretkey", false)
+	router := NewRouter(wikiInstance, false, "")
+
+	page, err := wikiInstance.CreatePage(nil, "Long Read", "long-read")
+	if err != nil {
+		t.Fatalf("Failed to create page: %v", err)
+	}
+
+	rec := authenticatedRequest(t, router, http.MethodGet, "/api/pages/"+page.ID+"/reading-position", nil)
+	if rec.Code != http.StatusNotFound {
+		t.Fatalf("Expected 404 without position, got %d", rec.Code)
+	}
+
+	rec = authenticatedRequest(t, router, http.MethodPut, "/api/pages/"+page.ID+"/reading-position", strings.NewReader(`{"anchor": "chapter-2"}`))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("Expected 200 OK, got %d - %s", rec.Code, rec.Body.String())
+	}
+
+	rec = authenticatedRequest(t, router, http.MethodGet, "/api/pages/"+page.ID+"/reading-position", nil)
+	if rec.Code != http.StatusOK || !strings.Contains(rec.Body.String(), `"anchor":"chapter-2"`) {
+		t.Fatalf("Expected saved position, got %d - %s", rec.Code, rec.Body.String())
+	}
+
+	rec = authenticatedRequest(t, router, http.MethodPut, "/api/pages/unknown/reading-position", strings.NewReader(`{"anchor": "x"}`))
+	if rec.Code != http.StatusNotFound {
+		t.Fatalf("Expected 404 for unknown page, got %d", rec.Code)
+	}
+
+	rec = authenticatedRequest(t, router, http.MethodGet, "/api/users/me/recently-read", nil)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("Expected 200 OK, got %d - %s", rec.Code, rec.Body.String())
+	}
+
+	var recent []map[string]interface{}
+	if err := json.Unmarshal(rec.Body.Bytes(), &recent); err != nil {
+		t.Fatalf("Failed to parse JSON: %v", err)
+	}
+	if len(recent) != 1 || recent[0]["path"] != "long-read" || recent[0]["anchor"] != "chapter-2" {
+		t.Fatalf("Unexpected recently read list: %v", recent)
+	}
+
+	// Deleted pages disappear from the list
+	if err := wikiInstance.DeletePage(page.ID, false); err != nil {
+		t.Fatalf("Failed to delete page: %v", err)
+	}
+	rec = authenticatedRequest(t, router, http.MethodGet, "/api/users/me/recently-read", nil)
+	if strings.TrimSpace(rec.Body.String()) != "[]" {
+		t.Fatalf("Expected empty list after delete, got %s", rec.Body.String())
+	}
+}
+
+func TestGetPageByPathEndpoint_NotFoundSuggestions(t *testing.T) {
+	wikiInstance, _ := wiki.NewWiki(t.TempDir(), "admin", "secretkey", false)
+	router := NewRouter(wikiInstance, false, "")
+
+	if _, err := wikiInstance.CreatePage(nil, "Getting Started", "getting-started"); err != nil {
+		t.Fatalf("Failed to create page: %v", err)
+	}
+
+	rec := authenticatedRequest(t, router, http.MethodGet, "/api/pages/by-path?path=geting-started", nil)
+	if rec.Code != http.StatusNotFound {
+		t.Fatalf("Expected 404, got %d", rec.Code)
+	}
+
+	var resp struct {
+		Error       string `json:"error"`
+		Suggestions []struct {
+			Path   string `json:"path"`
+			Reason string `json:"reason"`
+		} `json:"suggestions"`
+	}
+	if err := json.Unmarshal(rec.Body.Bytes(), &resp); err != nil {
+		t.Fatalf("Failed to parse JSON: %v", err)
+	}
+	if len(resp.Suggestions) == 0 || resp.Suggestions[0].Path != "getting-started" {
+		t.Errorf("Expected suggestion for getting-started, got %+v", resp.Suggestions)
+	}
+}
+
+func TestRateLimit(t *testing.T) {
+	wikiInstance, _ := wiki.NewWiki(t.TempDir(), "admin", "secretkey", false)
+	router := NewRouter(wikiInstance, true, "")
+
+	s, _ := wikiInstance.GetSettings()
+	s.RateLimit.PerIP = settings.RateLimit{RequestsPerMinute: 1, Burst: 2}
+	if _, err := wikiInstance.UpdateSettings(s); err != nil {
+		t.Fatalf("UpdateSettings failed: %v", err)
+	}
+
+	var rec *httptest.ResponseRecorder
+	for i := 0; i < 3; i++ {
+		rec = httptest.NewRecorder()
+		router.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/api/search?q=welcome", nil))
+	}
+	if rec.Code != http.StatusTooManyRequests {
+		t.Fatalf("Expected 429 for the third request, got %d", rec.Code)
+	}
+	if rec.Header().Get("RateLimit-Limit") != "2" || rec.Header().Get("RateLimit-Remaining") != "0" || rec.Header().Get("Retry-After") != "60" {
+		t.Errorf("Unexpected rate limit headers: %v", rec.Header())
+	}
+
+	// requests with a token are counted per user
+	rec = authenticatedRequest(t, router, http.MethodGet, "/api/search?q=welcome", nil)
+	if rec.Code != http.StatusOK || rec.Header().Get("RateLimit-Limit") != "30" {
+		t.Errorf("Expected the limit of the user, got %d %v", rec.Code, rec.Header())
+	}
+}
+
+func TestRequestTimeout(t *testing.T) {
+	wikiInstance, _ := wiki.NewWiki(t.TempDir(), "admin", "secretkey", false)
+	defer wikiInstance.Close()
+	router := NewRouter(wikiInstance, true, "")
+	page, _ := wikiInstance.CreatePage(nil, "Slow", "slow")
+
+	// a deadline in the past stands in for a slow request
+	expired := func(url string) *httptest.ResponseRecorder {
+		ctx, cancel := context.WithDeadline(t.Context(), time.Now())
+		defer cancel()
+		rec := httptest.NewRecorder()
+		router.ServeHTTP(rec, httptest.NewRequestWithContext(ctx, http.MethodGet, url, nil))
+		return rec
+	}
+	for _, url := range []string{"/api/search?q=slow", "/api/pages/" + page.ID + "/html", "/api/pages/" + page.ID + "/export?format=html"} {
+		if rec := expired(url); rec.Code != http.StatusGatewayTimeout {
+			t.Errorf("Expected 504 for %s, got %d: %s", url, rec.Code, rec.Body.String())
+		}
+	}
+
+	s, _ := wikiInstance.GetSettings()
+	if s.RequestTimeout.SearchSeconds != 10 {
+		t.Errorf("Expected a search timeout of 10 seconds by default, got %d", s.RequestTimeout.SearchSeconds)
+	}
+	s.RequestTimeout.ExportSeconds = -1
+	if _, err := wikiInstance.UpdateSettings(s); err == nil {
+		t.Errorf("Expected a negative timeout to be rejected")
+	}
+}
+
+func TestRequestSizeLimits(t *testing.T) {
+	wikiInstance, _ := wiki.NewWiki(t.TempDir(), "admin", "secretkey", false, wiki.WithWebDAV(true))
+	defer wikiInstance.Close()
+	router := NewRouter(wikiInstance, false, "")
+	page, _ := wikiInstance.CreatePage(nil, "Limits", "limits")
+
+	s, _ := wikiInstance.GetSettings()
+	s.MaxRequestSize = 2048
+	s.MaxUploadSize = 4096
+	if _, err := wikiInstance.UpdateSettings(s); err != nil {
+		t.Fatalf("UpdateSettings failed: %v", err)
+	}
+
+	save := func(content string) int {
+		body, _ := json.Marshal(map[string]string{"title": "Limits", "slug": "limits", "content": content})
+		return authenticatedRequest(t, router, http.MethodPut, "/api/pages/"+page.ID, strings.NewReader(string(body))).Code
+	}
+	if code := save(strings.Repeat("a", 1024)); code != http.StatusOK {
+		t.Fatalf("Expected 200 for a small page, got %d", code)
+	}
+	if code := save(strings.Repeat("a", 4096)); code != http.StatusRequestEntityTooLarge {
+		t.Errorf("Expected 413 for a page above the request limit, got %d", code)
+	}
+
+	// uploads have their own limit
+	login := httptest.NewRecorder()
+	router.ServeHTTP(login, httptest.NewRequest(http.MethodPost, "/api/auth/login", strings.NewReader(`{"identifier": "admin", "password": "admin"}`)))
+	var loginResp map[string]interface{}
+	_ = json.Unmarshal(login.Body.Bytes(), &loginResp)
+	upload := func(size int) int {
+		body := &bytes.Buffer{}
+		writer := multipart.NewWriter(body)
+		part, _ := writer.CreateFormFile("file", "data.bin")
+		_, _ = part.Write(bytes.Repeat([]byte("x"), size))
+		writer.Close()
+		req := httptest.NewRequest(http.MethodPost, "/api/pages/"+page.ID+"/assets", body)
+		req.Header.Set("Content-Type", writer.FormDataContentType())
+		req.Header.Set("Authorization", "Bearer "+loginResp["token"].(string))
+		rec := httptest.NewRecorder()
+		router.ServeHTTP(rec, req)
+		return rec.Code
+	}
+	if code := upload(4096); code != http.StatusCreated {
+		t.Errorf("Expected 201 for an upload of the upload limit, got %d", code)
+	}
+	if code := upload(4097); code != http.StatusRequestEntityTooLarge {
+		t.Errorf("Expected 413 for an upload above the limit, got %d", code)
+	}
+
+	// as do import archives and files written over WebDAV
+	var archive bytes.Buffer
+	zw := zip.NewWriter(&archive)
+	f, _ := zw.CreateHeader(&zip.FileHeader{Name: "large.md", Method: zip.Store})
+	_, _ = f.Write([]byte("# Large\n" + strings.Repeat("a", 3000)))
+	if err := zw.Close(); err != nil {
+		t.Fatalf("Failed to create archive: %v", err)
+	}
+	body := &bytes.Buffer{}
+	writer := multipart.NewWriter(body)
+	part, _ := writer.CreateFormFile("file", "export.zip")
+	_, _ = part.Write(archive.Bytes())
+	writer.Close()
+	req := httptest.NewRequest(http.MethodPost, "/api/admin/import/upload", body)
+	req.Header.Set("Content-Type", writer.FormDataContentType())
+	req.Header.Set("Authorization", "Bearer "+loginResp["token"].(string))
+	rec := httptest.NewRecorder()
+	router.ServeHTTP(rec, req)
+	if rec.Code != http.StatusOK {
+		t.Errorf("Expected 200 for an import archive above the request limit, got %d - %s", rec.Code, rec.Body.String())
+	}
+
+	put := func(size int) int {
+		req := httptest.NewRequest(http.MethodPut, "/webdav/large.md", strings.NewReader(strings.Repeat("a", size)))
+		req.SetBasicAuth("admin", "admin")
+		rec := httptest.NewRecorder()
+		router.ServeHTTP(rec, req)
+		return rec.Code
+	}
+	if code := put(3000); code != http.StatusCreated {
+		t.Errorf("Expected 201 for a WebDAV file above the request limit, got %d", code)
+	}
+	if code := put(4097); code != http.StatusRequestEntityTooLarge {
+		t.Errorf("Expected 413 for a WebDAV file above the upload limit, got %d", code)
+	}
+}
+
+func TestCompression(t *testing.T) {
+	wikiInstance, _ := wiki.NewWiki(t.TempDir(), "admin", "secretkey", true)
+	defer wikiInstance.Close()
+	router := NewRouter(wikiInstance, true, "")
+	large, _ := wikiInstance.CreatePage(nil, "Large", "large")
+	content := strings.Repeat("All work and no play makes Jack a dull boy. ", 100)
+	if _, err := wikiInstance.UpdatePage(large.ID, "Large", "large", content); err != nil {
+		t.Fatalf("UpdatePage failed: %v", err)
+	}
+	small, _ := wikiInstance.CreatePage(nil, "Small", "small")
+
+	get := func(url, acceptEncoding string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodGet, url, nil)
+		if acceptEncoding != "" {
+			req.Header.Set("Accept-Encoding", acceptEncoding)
+		}
+		rec := httptest.NewRecorder()
+		router.ServeHTTP(rec, req)
+		return rec
+	}
+
+	rec := get("/api/pages/"+large.ID, "br, gzip")
+	if rec.Code != http.StatusOK || rec.Header().Get("Content-Encoding") != "gzip" {
+		t.Fatalf("Expected a gzip response, got %d with encoding %q", rec.Code, rec.Header().Get("Content-Encoding"))
+	}
+	if !strings.Contains(rec.Header().Get("Vary"), "Accept-Encoding") {
+		t.Errorf("Expected Vary: Accept-Encoding, got %q", rec.Header().Get("Vary"))
+	}
+	if etag := rec.Header().Get("ETag"); !strings.HasPrefix(etag, "W/") {
+		t.Errorf("Expected a weak ETag for the compressed response, got %q", etag)
+	}
+	reader, err := gzip.NewReader(rec.Body)
+	if err != nil {
+		t.Fatalf("Invalid gzip response: %v", err)
+	}
+	var page map[string]interface{}
+	if err := json.NewDecoder(reader).Decode(&page); err != nil {
+		t.Fatalf("Invalid JSON in the compressed response: %v", err)
+	}
+	if page["content"] != content {
+		t.Errorf("Unexpected content after decompression")
+	}
+
+	if rec := get("/api/pages/"+large.ID, ""); rec.Header().Get("Content-Encoding") != "" {
+		t.Errorf("Expected no compression without Accept-Encoding, got %q", rec.Header().Get("Content-Encoding"))
+	}
+	if rec := get("/api/pages/"+large.ID, "gzip;q=0"); rec.Header().Get("Content-Encoding") != "" {
+		t.Errorf("Expected no compression for gzip;q=0, got %q", rec.Header().Get("Content-Encoding"))
+	}
+	rec = get("/api/pages/"+small.ID, "gzip")
+	if rec.Header().Get("Content-Encoding") != "" || !json.Valid(rec.Body.Bytes()) {
+		t.Errorf("Expected a small response to be sent as it is, got encoding %q", rec.Header().Get("Content-Encoding"))
+	}
+}
+
+func TestCacheStaticFiles(t *testing.T) {
+	fsys := fstest.MapFS{"index-B1a2c3D4.js": {Data: []byte("console.log('hello')")}}
+	router := gin.New()
+	router.Group("/static", cacheStaticFiles(newEmbeddedETags(fsys))).StaticFS("/", http.FS(fsys))
+
+	rec := httptest.NewRecorder()
+	router.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/static/index-B1a2c3D4.js", nil))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("Expected 200, got %d", rec.Code)
+	}
+	if cc := rec.Header().Get("Cache-Control"); !strings.Contains(cc, "immutable") {
+		t.Errorf("Expected an immutable Cache-Control, got %q", cc)
+	}
+	etag := rec.Header().Get("ETag")
+	if etag == "" {
+		t.Fatal("Expected an ETag")
+	}
+
+	req := httptest.NewRequest(http.MethodGet, "/static/index-B1a2c3D4.js", nil)
+	req.Header.Set("If-None-Match", etag)
+	rec = httptest.NewRecorder()
+	router.ServeHTTP(rec, req)
+	if rec.Code != http.StatusNotModified {
+		t.Errorf("Expected 304 for a matching ETag, got %d", rec.Code)
+	}
+
+	rec = httptest.NewRecorder()
+	router.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/static/missing.js", nil))
+	if rec.Code != http.StatusNotFound || rec.Header().Get("Cache-Control") != "" {
+		t.Errorf("Expected 404 without caching for a missing file, got %d with %q", rec.Code, rec.Header().Get("Cache-Control"))
+	}
+}
+
+func TestBasePath(t *testing.T) {
+	wikiInstance, err := wiki.NewWiki(t.TempDir(), "admin", "secretkey", false, wiki.WithBasePath("/wiki/"))
+	if err != nil {
+		t.Fatalf("NewWiki failed: %v", err)
+	}
+	defer wikiInstance.Close()
+	handler := StripBasePath(wikiInstance.BasePath(), NewRouter(wikiInstance, true, ""))
+
+	get := func(url string) *httptest.ResponseRecorder {
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, url, nil))
+		return rec
+	}
+	if rec := get("/wiki/api/config"); rec.Code != http.StatusOK {
+		t.Errorf("Expected 200 under the base path, got %d", rec.Code)
+	}
+	if rec := get("/api/config"); rec.Code != http.StatusNotFound {
+		t.Errorf("Expected 404 outside of the base path, got %d", rec.Code)
+	}
+	if rec := get("/wiki?x=1"); rec.Code != http.StatusMovedPermanently || rec.Header().Get("Location") != "/wiki/?x=1" {
+		t.Errorf("Expected a redirect to /wiki/?x=1, got %d %q", rec.Code, rec.Header().Get("Location"))
+	}
+
+	page, _ := wikiInstance.CreatePage(nil, "Guide", "guide")
+	if _, err := wikiInstance.UpdatePage(page.ID, "Guide", "manual", "See [home](/home) and ![logo](/assets/"+page.ID+"/logo.png)"); err != nil {
+		t.Fatalf("UpdatePage failed: %v", err)
+	}
+	if rec := get("/wiki/guide"); rec.Code != http.StatusMovedPermanently || rec.Header().Get("Location") != "/wiki/manual" {
+		t.Errorf("Expected a redirect to /wiki/manual, got %d %q", rec.Code, rec.Header().Get("Location"))
+	}
+
+	rec := get("/wiki/api/pages/" + page.ID + "/html")
+	var rendered wiki.RenderedPage
+	if err := json.Unmarshal(rec.Body.Bytes(), &rendered); err != nil {
+		t.Fatalf("Invalid response %d: %s", rec.Code, rec.Body.String())
+	}
+	if !strings.Contains(rendered.HTML, `href="/wiki/home"`) || !strings.Contains(rendered.HTML, `src="/wiki/assets/`+page.ID+`/logo.png"`) {
+		t.Errorf("Expected the links to include the base path, got %s", rendered.HTML)
+	}
+
+	if rec := get("/wiki/api/docs"); !strings.Contains(rec.Body.String(), `"/wiki/api/openapi.json"`) {
+		t.Errorf("Expected the docs to load the spec under the base path")
+	}
+
+	if _, err := wiki.NewWiki(t.TempDir(), "admin", "secretkey", false, wiki.WithBasePath(`/wiki"`)); err == nil {
+		t.Error("Expected an error for an invalid base path")
+	}
+}
+
+func TestWithBasePath(t *testing.T) {
+	index := []byte(`<head><link rel="icon" href="./favicon.svg" /><script type="module" src="./static/index-B1a2c3D4.js"></script></head>`)
+
+	got := string(withBasePath(index, "/wiki"))
+	for _, want := range []string{`href="/wiki/favicon.svg"`, `src="/wiki/static/index-B1a2c3D4.js"`, `<meta name="leafwiki-base-path" content="/wiki">`} {
+		if !strings.Contains(got, want) {
+			t.Errorf("Expected %s in %s", want, got)
+		}
+	}
+
+	got = string(withBasePath(index, ""))
+	if !strings.Contains(got, `src="/static/index-B1a2c3D4.js"`) || strings.Contains(got, "leafwiki-base-path") {
+		t.Errorf("Expected root links without base path, got %s", got)
+	}
+}
+
+func TestTrustedProxies(t *testing.T) {
+	wikiInstance, err := wiki.NewWiki(t.TempDir(), "admin", "secretkey", false, wiki.WithTrustedProxies([]string{"10.0.0.2"}))
+	if err != nil {
+		t.Fatalf("NewWiki failed: %v", err)
+	}
+	defer wikiInstance.Close()
+	router := NewRouter(wikiInstance, false, "")
+
+	hsts := func(remoteAddr string) string {
+		req := httptest.NewRequest(http.MethodGet, "/api/config", nil)
+		req.RemoteAddr = remoteAddr
+		req.Header.Set("X-Forwarded-Proto", "https")
+		rec := httptest.NewRecorder()
+		router.ServeHTTP(rec, req)
+		return rec.Header().Get("Strict-Transport-Security")
+	}
+	if hsts("10.0.0.2:4711") == "" {
+		t.Error("Expected X-Forwarded-Proto of a trusted proxy to be used")
+	}
+	if hsts("10.0.0.3:4711") != "" {
+		t.Error("Expected X-Forwarded-Proto of other clients to be ignored")
+	}
+
+	if _, err := wiki.NewWiki(t.TempDir(), "admin", "secretkey", false, wiki.WithTrustedProxies([]string{"proxy.local"})); err == nil {
+		t.Error("Expected an error for an invalid proxy")
+	}
+}
+
+func TestBadgeEndpoints_PublicAccess(t *testing.T) {
+	wikiInstance, _ := wiki.NewWiki(t.TempDir(), "admin", "secretkey", false)
+	router := NewRouter(wikiInstance, true, "")
+
+	if _, err := wikiInstance.CreatePage(nil, "Docs", "docs"); err != nil {
+		t.Fatalf("Failed to create page: %v", err)
+	}
+
+	req := httptest.NewRequest(http.MethodGet, "/api/badges/pages", nil)
+	rec := httptest.NewRecorder()
+	router.ServeHTTP(rec, req)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("Expected 200 OK, got %d", rec.Code)
+	}
+	if !strings.HasPrefix(rec.Header().Get("Content-Type"), "image/svg+xml") {
+		t.Errorf("Expected SVG content type, got %s", rec.Header().Get("Content-Type"))
+	}
+	// Welcome page + Docs
+	if !strings.Contains(rec.Body.String(), ">2<") {
+		t.Errorf("Expected page count in badge, got %s", rec.Body.String())
+	}
+
+	req = httptest.NewRequest(http.MethodGet, "/api/badges/search?format=json", nil)
+	rec = httptest.NewRecorder()
+	router.ServeHTTP(rec, req)
+	var endpoint map[string]interface{}
+	if err := json.Unmarshal(rec.Body.Bytes(), &endpoint); err != nil {
+		t.Fatalf("Failed to parse JSON: %v", err)
+	}
+	if endpoint["schemaVersion"] != float64(1) || endpoint["label"] != "search" {
+		t.Errorf("Unexpected endpoint badge: %v", endpoint)
+	}
+
+	req = httptest.NewRequest(http.MethodGet, "/api/badges/unknown", nil)
+	rec = httptest.NewRecorder()
+	router.ServeHTTP(rec, req)
+	if rec.Code != http.StatusNotFound {
+		t.Errorf("Expected 404 for unknown badge, got %d", rec.Code)
+	}
+}
+
+func TestImportEndpoints(t *testing.T) {
+	sourceDir := t.TempDir()
+	w, _ := wiki.NewWiki(t.TempDir(), "admin", "secretkey", false, wiki.WithImportRoot(sourceDir))
+	router := NewRouter(w, false, "")
+
+	if err := os.WriteFile(filepath.Join(sourceDir, "meeting_notes.md"), []byte("# Meeting Notes\nAgenda"), 0o644); err != nil {
+		t.Fatalf("failed to write file: %v", err)
+	}
+
+	body, _ := json.Marshal(map[string]string{"sourceDir": sourceDir})
+	rec := authenticatedRequest(t, router, http.MethodPost, "/api/admin/import/analyze", strings.NewReader(string(body)))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("Expected 200 OK, got %d - %s", rec.Code, rec.Body.String())
+	}
+
+	var plan map[string]interface{}
+	if err := json.Unmarshal(rec.Body.Bytes(), &plan); err != nil {
+		t.Fatalf("Failed to parse JSON: %v", err)
+	}
+	pages, _ := plan["pages"].([]interface{})
+	if len(pages) != 1 || pages[0].(map[string]interface{})["slug"] != "meeting-notes" {
+		t.Fatalf("Unexpected plan: %s", rec.Body.String())
+	}
+
+	applyBody, _ := json.Marshal(map[string]interface{}{"plan": plan})
+	rec = authenticatedRequest(t, router, http.MethodPost, "/api/admin/import/apply", strings.NewReader(string(applyBody)))
+	if rec.Code != http.StatusCreated || !strings.Contains(rec.Body.String(), `"created":1`) {
+		t.Fatalf("Expected 201 Created, got %d - %s", rec.Code, rec.Body.String())
+	}
+
+	page, err := w.FindByPath("meeting-notes")
+	if err != nil || page.Title != "Meeting Notes" {
+		t.Errorf("Expected imported page, got %+v, %v", page, err)
+	}
+
+	missing := `{"sourceDir": "` + filepath.ToSlash(filepath.Join(sourceDir, "missing")) + `"}`
+	rec = authenticatedRequest(t, router, http.MethodPost, "/api/admin/import/analyze", strings.NewReader(missing))
+	if rec.Code != http.StatusNotFound {
+		t.Errorf("Expected 404 for missing source, got %d", rec.Code)
+	}
+
+	outside, _ := json.Marshal(map[string]string{"sourceDir": t.TempDir()})
+	rec = authenticatedRequest(t, router, http.MethodPost, "/api/admin/import/analyze", strings.NewReader(string(outside)))
+	if rec.Code != http.StatusBadRequest {
+		t.Errorf("Expected 400 for a source outside the import directory, got %d", rec.Code)
+	}
+}
+
+func TestImportUploadEndpoint(t *testing.T) {
+	w, _ := wiki.NewWiki(t.TempDir(), "admin", "secretkey", false)
+	router := NewRouter(w, false, "")
+
+	login := authenticatedRequest(t, router, http.MethodPost, "/api/auth/login", strings.NewReader(`{"identifier": "admin", "password": "admin"}`))
+	var loginResp map[string]interface{}
+	if err := json.Unmarshal(login.Body.Bytes(), &loginResp); err != nil {
+		t.Fatalf("Invalid login JSON: %v", err)
+	}
+	token := loginResp["token"].(string)
+
+	upload := func(data []byte) *httptest.ResponseRecorder {
+		body := &bytes.Buffer{}
+		writer := multipart.NewWriter(body)
+		part, _ := writer.CreateFormFile("file", "export.zip")
+		_, _ = part.Write(data)
+		writer.Close()
+		req := httptest.NewRequest(http.MethodPost, "/api/admin/import/upload", body)
+		req.Header.Set("Content-Type", writer.FormDataContentType())
+		req.Header.Set("Authorization", "Bearer "+token)
+		rec := httptest.NewRecorder()
+		router.ServeHTTP(rec, req)
+		return rec
+	}
+
+	var archive bytes.Buffer
+	zw := zip.NewWriter(&archive)
+	f, _ := zw.Create("meeting_notes.md")
+	_, _ = f.Write([]byte("# Meeting Notes\nAgenda"))
+	if err := zw.Close(); err != nil {
+		t.Fatalf("Failed to create archive: %v", err)
+	}
+
+	rec := upload(archive.Bytes())
+	if rec.Code != http.StatusOK {
+		t.Fatalf("Expected 200 OK, got %d - %s", rec.Code, rec.Body.String())
+	}
+	var plan map[string]interface{}
+	if err := json.Unmarshal(rec.Body.Bytes(), &plan); err != nil {
+		t.Fatalf("Failed to parse JSON: %v", err)
+	}
+
+	applyBody, _ := json.Marshal(map[string]interface{}{"plan": plan})
+	rec = authenticatedRequest(t, router, http.MethodPost, "/api/admin/import/apply", strings.NewReader(string(applyBody)))
+	if rec.Code != http.StatusCreated {
+		t.Fatalf("Expected 201 Created, got %d - %s", rec.Code, rec.Body.String())
+	}
+	if page, err := w.FindByPath("meeting-notes"); err != nil || page.Title != "Meeting Notes" {
+		t.Errorf("Expected imported page, got %+v, %v", page, err)
+	}
+
+	if rec := upload([]byte("no zip")); rec.Code != http.StatusBadRequest {
+		t.Errorf("Expected 400 for an invalid archive, got %d", rec.Code)
+	}
+}
+
+func TestSubtreeHistoryEndpoint(t *testing.T) {
+	w, _ := wiki.NewWiki(t.TempDir(), "admin", "secretkey", false)
+	router := NewRouter(w, false, "")
+
+	rec := authenticatedRequest(t, router, http.MethodGet, "/api/history?path=docs/&limit=10", nil)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("Expected 200 OK, got %d - %s", rec.Code, rec.Body.String())
+	}
+	var resp struct {
+		History []interface{} `json:"history"`
+		Total   int           `json:"total"`
+	}
+	if err := json.Unmarshal(rec.Body.Bytes(), &resp); err != nil {
+		t.Fatalf("Failed to parse JSON: %v", err)
+	}
+	if resp.History == nil || resp.Total != 0 {
+		t.Errorf("Expected empty history, got %s", rec.Body.String())
+	}
+
+	rec = authenticatedRequest(t, router, http.MethodGet, "/api/history?path=docs/&status=unknown", nil)
+	if rec.Code != http.StatusBadRequest {
+		t.Errorf("Expected 400 for invalid status, got %d", rec.Code)
+	}
+}
+
+func TestUndoPageEndpoint(t *testing.T) {
+	w, _ := wiki.NewWiki(t.TempDir(), "admin", "secretkey", false)
+	router := NewRouter(w, false, "")
+
+	page, err := w.CreatePage(nil, "Undo", "undo")
+	if err != nil {
+		t.Fatalf("Failed to create page: %v", err)
+	}
+	if _, err := w.UpdatePage(page.ID, "Undo", "undo", "original"); err != nil {
+		t.Fatalf("Failed to update page: %v", err)
+	}
+
+	rec := authenticatedRequest(t, router, http.MethodPost, "/api/pages/"+page.ID+"/undo", nil)
+	if rec.Code != http.StatusConflict {
+		t.Fatalf("Expected 409 Conflict without previous version, got %d - %s", rec.Code, rec.Body.String())
+	}
+
+	if _, err := w.UpdatePage(page.ID, "Undo", "undo", "changed"); err != nil {
+		t.Fatalf("Failed to update page: %v", err)
+	}
+	rec = authenticatedRequest(t, router, http.MethodPost, "/api/pages/"+page.ID+"/undo", nil)
+	if rec.Code != http.StatusOK || !strings.Contains(rec.Body.String(), `"content":"original"`) {
+		t.Errorf("Expected restored page, got %d - %s", rec.Code, rec.Body.String())
+	}
+}
+
+func TestETagOnPageAndTreeEndpoints(t *testing.T) {
+	w, _ := wiki.NewWiki(t.TempDir(), "admin", "secretkey", false)
+	router := NewRouter(w, true, "")
+
+	page, err := w.CreatePage(nil, "Cached", "cached")
+	if err != nil {
+		t.Fatalf("Failed to create page: %v", err)
+	}
+
+	get := func(url, etag string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodGet, url, nil)
+		if etag != "" {
+			req.Header.Set("If-None-Match", etag)
+		}
+		rec := httptest.NewRecorder()
+		router.ServeHTTP(rec, req)
+		return rec
+	}
+
+	for _, url := range []string{"/api/tree", "/api/pages/" + page.ID, "/api/pages/by-path?path=cached"} {
+		rec := get(url, "")
+		etag := rec.Header().Get("ETag")
+		if rec.Code != http.StatusOK || etag == "" {
+			t.Fatalf("%s: expected 200 with ETag, got %d %q", url, rec.Code, etag)
+		}
+
+		rec = get(url, "W/"+etag)
+		if rec.Code != http.StatusNotModified || rec.Body.Len() != 0 {
+			t.Errorf("%s: expected 304 without body, got %d", url, rec.Code)
+		}
+
+		rec = get(url, `"outdated"`)
+		if rec.Code != http.StatusOK {
+			t.Errorf("%s: expected 200 for outdated ETag, got %d", url, rec.Code)
+		}
+	}
+
+	// A change results in a new ETag
+	etag := get("/api/pages/"+page.ID, "").Header().Get("ETag")
+	if _, err := w.UpdatePage(page.ID, "Cached", "cached", "changed"); err != nil {
+		t.Fatalf("Failed to update page: %v", err)
+	}
+	if rec := get("/api/pages/"+page.ID, etag); rec.Code != http.StatusOK {
+		t.Errorf("Expected 200 after change, got %d", rec.Code)
+	}
+}
+
+func TestGetTreeWithPathAndDepth(t *testing.T) {
+	w, _ := wiki.NewWiki(t.TempDir(), "admin", "secretkey", false)
+	router := NewRouter(w, false, "")
+
+	docs, err := w.CreatePage(nil, "Docs", "docs")
+	if err != nil {
+		t.Fatalf("Failed to create page: %v", err)
+	}
+	guide, err := w.CreatePage(&docs.ID, "Guide", "guide")
+	if err != nil {
+		t.Fatalf("Failed to create page: %v", err)
+	}
+	if _, err := w.CreatePage(&guide.ID, "Install", "install"); err != nil {
+		t.Fatalf("Failed to create page: %v", err)
+	}
+
+	rec := authenticatedRequest(t, router, http.MethodGet, "/api/tree?path=docs&depth=1", nil)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("Expected 200 OK, got %d - %s", rec.Code, rec.Body.String())
+	}
+	var node struct {
+		Path     string `json:"path"`
+		Children []struct {
+			Path        string        `json:"path"`
+			HasChildren bool          `json:"hasChildren"`
+			Children    []interface{} `json:"children"`
+		} `json:"children"`
+	}
+	if err := json.Unmarshal(rec.Body.Bytes(), &node); err != nil {
+		t.Fatalf("Failed to parse JSON: %v", err)
+	}
+	if node.Path != "docs" || len(node.Children) != 1 {
+		t.Fatalf("Unexpected subtree: %s", rec.Body.String())
+	}
+	child := node.Children[0]
+	if child.Path != "docs/guide" || !child.HasChildren || len(child.Children) != 0 {
+		t.Errorf("Expected guide without children but with hasChildren, got %+v", child)
+	}
+
+	rec = authenticatedRequest(t, router, http.MethodGet, "/api/tree?path=docs/guide&children=true", nil)
+	var children []map[string]interface{}
+	if err := json.Unmarshal(rec.Body.Bytes(), &children); err != nil {
+		t.Fatalf("Failed to parse JSON: %v - %s", err, rec.Body.String())
+	}
+	if len(children) != 1 || children[0]["path"] != "docs/guide/install" || children[0]["hasChildren"] != false {
+		t.Errorf("Unexpected children: %s", rec.Body.String())
+	}
+
+	if rec := authenticatedRequest(t, router, http.MethodGet, "/api/tree?path=missing", nil); rec.Code != http.StatusNotFound {
+		t.Errorf("Expected 404 for missing path, got %d", rec.Code)
+	}
+	if rec := authenticatedRequest(t, router, http.MethodGet, "/api/tree?depth=-1", nil); rec.Code != http.StatusBadRequest {
+		t.Errorf("Expected 400 for invalid depth, got %d", rec.Code)
+	}
+}
+
+func TestExportHTMLEndpoint(t *testing.T) {
+	wikiInstance, _ := wiki.NewWiki(t.TempDir(), "admin", "secretkey", false)
+	defer wikiInstance.Close()
+	router := NewRouter(wikiInstance, false, "")
+
+	rec := authenticatedRequest(t, router, http.MethodGet, "/api/export/html", nil)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("Expected 200 OK, got %d - %s", rec.Code, rec.Body.String())
+	}
+	if ct := rec.Header().Get("Content-Type"); ct != "application/zip" {
+		t.Errorf("Expected zip content type, got %q", ct)
+	}
+
+	zr, err := zip.NewReader(bytes.NewReader(rec.Body.Bytes()), int64(rec.Body.Len()))
+	if err != nil {
+		t.Fatalf("Invalid zip archive: %v", err)
+	}
+	files := map[string]bool{}
+	for _, f := range zr.File {
+		files[f.Name] = true
+	}
+	for _, name := range []string{"index.html", "search-index.json", "welcome-to-leaf-wiki/index.html"} {
+		if !files[name] {
+			t.Errorf("Expected %s in archive, got %v", name, files)
+		}
+	}
+
+	anonymous := httptest.NewRecorder()
+	router.ServeHTTP(anonymous, httptest.NewRequest(http.MethodGet, "/api/export/html", nil))
+	if anonymous.Code != http.StatusUnauthorized {
+		t.Errorf("Expected 401 for anonymous export, got %d", anonymous.Code)
+	}
+}
+
+func TestExportPageEndpoint(t *testing.T) {
+	wikiInstance, _ := wiki.NewWiki(t.TempDir(), "admin", "secretkey", false)
+	defer wikiInstance.Close()
+	router := NewRouter(wikiInstance, false, "")
+
+	docs, _ := wikiInstance.CreatePage(nil, "Docs", "docs")
+	if _, err := wikiInstance.CreatePage(&docs.ID, "Install", "install"); err != nil {
+		t.Fatalf("CreatePage failed: %v", err)
+	}
+
+	rec := authenticatedRequest(t, router, http.MethodGet, "/api/pages/"+docs.ID+"/export?format=pdf&recursive=true", nil)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("Expected 200 OK, got %d - %s", rec.Code, rec.Body.String())
+	}
+	if ct := rec.Header().Get("Content-Type"); ct != "application/pdf" {
+		t.Errorf("Expected PDF content type, got %q", ct)
+	}
+	if !strings.Contains(rec.Header().Get("Content-Disposition"), `filename="docs.pdf"`) {
+		t.Errorf("Unexpected Content-Disposition %q", rec.Header().Get("Content-Disposition"))
+	}
+	body := rec.Body.String()
+	if !strings.HasPrefix(body, "%PDF-") || !strings.Contains(body, "(Install)") || !strings.Contains(body, "(Contents)") {
+		t.Errorf("Expected PDF with both pages and a table of contents")
+	}
+
+	if rec := authenticatedRequest(t, router, http.MethodGet, "/api/pages/"+docs.ID+"/export?format=odt", nil); rec.Code != http.StatusBadRequest {
+		t.Errorf("Expected 400 for unsupported format, got %d", rec.Code)
+	}
+	if rec := authenticatedRequest(t, router, http.MethodGet, "/api/pages/"+docs.ID+"/export?format=md&recursive=true", nil); rec.Code != http.StatusBadRequest {
+		t.Errorf("Expected 400 for recursive Markdown export, got %d", rec.Code)
+	}
+	if rec := authenticatedRequest(t, router, http.MethodGet, "/api/pages/missing/export", nil); rec.Code != http.StatusNotFound {
+		t.Errorf("Expected 404 for missing page, got %d", rec.Code)
+	}
+}
+
+func TestExportPageEndpoint_Formats(t *testing.T) {
+	wikiInstance, _ := wiki.NewWiki(t.TempDir(), "admin", "secretkey", false)
+	defer wikiInstance.Close()
+	router := NewRouter(wikiInstance, false, "")
+
+	plain, _ := wikiInstance.CreatePage(nil, "Plain", "plain")
+	if _, err := wikiInstance.UpdatePage(plain.ID, "Plain", "plain", "# Plain\nJust text."); err != nil {
+		t.Fatalf("UpdatePage failed: %v", err)
+	}
+
+	rec := authenticatedRequest(t, router, http.MethodGet, "/api/pages/"+plain.ID+"/export?format=md", nil)
+	if rec.Code != http.StatusOK || !strings.HasPrefix(rec.Header().Get("Content-Type"), "text/markdown") {
+		t.Fatalf("Expected Markdown, got %d %q", rec.Code, rec.Header().Get("Content-Type"))
+	}
+	if rec.Body.String() != "# Plain\nJust text." {
+		t.Errorf("Unexpected Markdown %q", rec.Body.String())
+	}
+
+	rec = authenticatedRequest(t, router, http.MethodGet, "/api/pages/"+plain.ID+"/export?format=html", nil)
+	if rec.Code != http.StatusOK || !strings.Contains(rec.Body.String(), "<h1>Plain</h1>") {
+		t.Errorf("Expected standalone HTML, got %d", rec.Code)
+	}
+
+	rec = authenticatedRequest(t, router, http.MethodGet, "/api/pages/"+plain.ID+"/export?format=docx", nil)
+	if rec.Code != http.StatusOK || !strings.Contains(rec.Header().Get("Content-Disposition"), `filename="plain.docx"`) {
+		t.Fatalf("Expected DOCX download, got %d %q", rec.Code, rec.Header().Get("Content-Disposition"))
+	}
+	if _, err := zip.NewReader(bytes.NewReader(rec.Body.Bytes()), int64(rec.Body.Len())); err != nil {
+		t.Errorf("Expected DOCX to be a zip package: %v", err)
+	}
+
+	// pages with assets are bundled with them
+	withImage, _ := wikiInstance.CreatePage(nil, "Diagram", "diagram")
+	assetDir := filepath.Join(wikiInstance.GetStorageDir(), "assets", withImage.ID)
+	if err := os.MkdirAll(assetDir, 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(assetDir, "chart.txt"), []byte("data"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	content := "See [chart](/assets/" + withImage.ID + "/chart.txt)."
+	if _, err := wikiInstance.UpdatePage(withImage.ID, "Diagram", "diagram", content); err != nil {
+		t.Fatalf("UpdatePage failed: %v", err)
+	}
+
+	rec = authenticatedRequest(t, router, http.MethodGet, "/api/pages/"+withImage.ID+"/export?format=md", nil)
+	if rec.Code != http.StatusOK || rec.Header().Get("Content-Type") != "application/zip" {
+		t.Fatalf("Expected zip, got %d %q", rec.Code, rec.Header().Get("Content-Type"))
+	}
+	zr, err := zip.NewReader(bytes.NewReader(rec.Body.Bytes()), int64(rec.Body.Len()))
+	if err != nil {
+		t.Fatalf("Invalid zip: %v", err)
+	}
+	files := map[string]string{}
+	for _, f := range zr.File {
+		rc, _ := f.Open()
+		data, _ := io.ReadAll(rc)
+		rc.Close()
+		files[f.Name] = string(data)
+	}
+	if files["diagram.md"] != "See [chart](assets/chart.txt)." {
+		t.Errorf("Expected relative asset links, got %q", files["diagram.md"])
+	}
+	if files["assets/chart.txt"] != "data" {
+		t.Errorf("Expected the asset in the zip, got %v", files)
+	}
+}
+
+func TestWebDAV(t *testing.T) {
+	wikiInstance, _ := wiki.NewWiki(t.TempDir(), "admin", "secretkey", false, wiki.WithWebDAV(true))
+	defer wikiInstance.Close()
+	router := NewRouter(wikiInstance, true, "")
+
+	webdavRequest := func(method, url, body string, authenticated bool) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, url, strings.NewReader(body))
+		if authenticated {
+			req.SetBasicAuth("admin", "admin")
+		}
+		if method == "PROPFIND" {
+			req.Header.Set("Depth", "1")
+		}
+		rec := httptest.NewRecorder()
+		router.ServeHTTP(rec, req)
+		return rec
+	}
+
+	// also needs credentials while the wiki is public
+	rec := webdavRequest("PROPFIND", "/webdav/", "", false)
+	if rec.Code != http.StatusUnauthorized || !strings.HasPrefix(rec.Header().Get("WWW-Authenticate"), "Basic") {
+		t.Fatalf("Expected 401 with Basic challenge, got %d", rec.Code)
+	}
+
+	rec = webdavRequest(http.MethodPut, "/webdav/notes.md", "# Notes", true)
+	if rec.Code != http.StatusCreated {
+		t.Fatalf("Expected 201 Created, got %d - %s", rec.Code, rec.Body.String())
+	}
+	data, err := os.ReadFile(filepath.Join(wikiInstance.GetStorageDir(), "root", "notes.md"))
+	if err != nil || string(data) != "# Notes" {
+		t.Errorf("Expected the file in the data directory, got %q (%v)", data, err)
+	}
+
+	rec = webdavRequest("PROPFIND", "/webdav/", "", true)
+	if rec.Code != http.StatusMultiStatus || !strings.Contains(rec.Body.String(), "/webdav/notes.md") {
+		t.Errorf("Expected listing with the new file, got %d - %s", rec.Code, rec.Body.String())
+	}
+
+	rec = webdavRequest(http.MethodGet, "/webdav/notes.md", "", true)
+	if rec.Code != http.StatusOK || rec.Body.String() != "# Notes" {
+		t.Errorf("Expected file content, got %d - %s", rec.Code, rec.Body.String())
+	}
+
+	req := httptest.NewRequest("PROPFIND", "/webdav/", nil)
+	req.SetBasicAuth("admin", "wrong")
+	rec = httptest.NewRecorder()
+	router.ServeHTTP(rec, req)
+	if rec.Code != http.StatusUnauthorized {
+		t.Errorf("Expected 401 for wrong password, got %d", rec.Code)
+	}
+}
+
+func TestWebDAV_RawHTML(t *testing.T) {
+	wikiInstance, _ := wiki.NewWiki(t.TempDir(), "admin", "secretkey", false, wiki.WithWebDAV(true))
+	defer wikiInstance.Close()
+	router := NewRouter(wikiInstance, false, "")
+	if _, err := wikiInstance.CreateUser("editor", "editor@example.com", "secretpassword", "editor"); err != nil {
+		t.Fatalf("Failed to create user: %v", err)
+	}
+	s, _ := wikiInstance.GetSettings()
+	s.RawHTML = staticsite.RawHTMLTrusted
+	if _, err := wikiInstance.UpdateSettings(s); err != nil {
+		t.Fatalf("UpdateSettings failed: %v", err)
+	}
+
+	webdavRequest := func(method, url, body, username, password string) int {
+		req := httptest.NewRequest(method, url, strings.NewReader(body))
+		req.SetBasicAuth(username, password)
+		if method == "MOVE" {
+			req.Header.Set("Destination", "/webdav/moved.md")
+		}
+		rec := httptest.NewRecorder()
+		router.ServeHTTP(rec, req)
+		return rec.Code
+	}
+	readFile := func(name string) string {
+		data, _ := os.ReadFile(filepath.Join(wikiInstance.GetStorageDir(), "root", name))
+		return string(data)
+	}
+	script := "<script>track()</script>\n"
+
+	if code := webdavRequest(http.MethodPut, "/webdav/notes.md", "# Notes\n"+script, "editor", "secretpassword"); code == http.StatusCreated {
+		t.Fatalf("Expected the script of the editor to be refused, got %d", code)
+	}
+	if _, err := os.Stat(filepath.Join(wikiInstance.GetStorageDir(), "root", "notes.md")); !os.IsNotExist(err) {
+		t.Fatalf("Expected no file to be created, got %v", err)
+	}
+
+	if code := webdavRequest(http.MethodPut, "/webdav/notes.md", "# Notes\n"+script, "admin", "admin"); code != http.StatusCreated {
+		t.Fatalf("Expected the admin to be trusted, got %d", code)
+	}
+	// the editor may keep the script added by the admin, but not add another one
+	if code := webdavRequest(http.MethodPut, "/webdav/notes.md", "# Notes\n\nMore\n"+script, "editor", "secretpassword"); code != http.StatusCreated {
+		t.Fatalf("Expected the existing script to be allowed, got %d", code)
+	}
+	if code := webdavRequest(http.MethodPut, "/webdav/notes.md", "# Notes\n<iframe src=x></iframe>", "editor", "secretpassword"); code == http.StatusCreated {
+		t.Fatalf("Expected the iframe of the editor to be refused, got %d", code)
+	}
+	if content := readFile("notes.md"); content != "# Notes\n\nMore\n"+script {
+		t.Errorf("Expected the refused write to leave the file unchanged, got %q", content)
+	}
+
+	// a file renamed to a Markdown file is checked too
+	if code := webdavRequest(http.MethodPut, "/webdav/notes.txt", script, "editor", "secretpassword"); code != http.StatusCreated {
+		t.Fatalf("Expected other files to be written, got %d", code)
+	}
+	if code := webdavRequest("MOVE", "/webdav/notes.txt", "", "editor", "secretpassword"); code < 400 {
+		t.Errorf("Expected the move to a Markdown file to be refused, got %d", code)
+	}
+}
+
+func TestWebDAV_DisabledByDefault(t *testing.T) {
+	wikiInstance, _ := wiki.NewWiki(t.TempDir(), "admin", "secretkey", false)
+	defer wikiInstance.Close()
+	router := NewRouter(wikiInstance, false, "")
+
+	req := httptest.NewRequest("PROPFIND", "/webdav/", nil)
+	req.SetBasicAuth("admin", "admin")
+	rec := httptest.NewRecorder()
+	router.ServeHTTP(rec, req)
+	if rec.Code == http.StatusMultiStatus {
+		t.Errorf("Expected WebDAV to be disabled")
+	}
+}
+
+func TestProfiling(t *testing.T) {
+	wikiInstance, _ := wiki.NewWiki(t.TempDir(), "admin", "secretkey", false, wiki.WithProfiling(true))
+	defer wikiInstance.Close()
+	router := NewRouter(wikiInstance, false, "")
+	if _, err := wikiInstance.CreateUser("editor", "editor@example.com", "secretpassword", "editor"); err != nil {
+		t.Fatalf("Failed to create user: %v", err)
+	}
+
+	pprofRequest := func(url, username, password string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodGet, url, nil)
+		if username != "" {
+			req.SetBasicAuth(username, password)
+		}
+		rec := httptest.NewRecorder()
+		router.ServeHTTP(rec, req)
+		return rec
+	}
+
+	if rec := pprofRequest("/debug/pprof/", "", ""); rec.Code != http.StatusUnauthorized {
+		t.Errorf("Expected 401 without credentials, got %d", rec.Code)
+	}
+	if rec := pprofRequest("/debug/pprof/", "editor", "secretpassword"); rec.Code != http.StatusForbidden {
+		t.Errorf("Expected 403 for an editor, got %d", rec.Code)
+	}
+
+	rec := pprofRequest("/debug/pprof/", "admin", "admin")
+	if rec.Code != http.StatusOK || !strings.Contains(rec.Body.String(), "goroutine") {
+		t.Errorf("Expected the list of profiles, got %d - %s", rec.Code, rec.Body.String())
+	}
+	rec = pprofRequest("/debug/pprof/heap?debug=1", "admin", "admin")
+	if rec.Code != http.StatusOK || !strings.Contains(rec.Body.String(), "heap profile") {
+		t.Errorf("Expected the heap profile, got %d - %.200s", rec.Code, rec.Body.String())
+	}
+	if rec := authenticatedRequest(t, router, http.MethodGet, "/debug/pprof/cmdline", nil); rec.Code != http.StatusOK {
+		t.Errorf("Expected the command line with a Bearer token, got %d", rec.Code)
+	}
+}
+
+func TestProfiling_DisabledByDefault(t *testing.T) {
+	wikiInstance, _ := wiki.NewWiki(t.TempDir(), "admin", "secretkey", false)
+	defer wikiInstance.Close()
+	router := NewRouter(wikiInstance, false, "")
+
+	req := httptest.NewRequest(http.MethodGet, "/debug/pprof/heap", nil)
+	req.SetBasicAuth("admin", "admin")
+	rec := httptest.NewRecorder()
+	router.ServeHTTP(rec, req)
+	if rec.Code != http.StatusNotFound {
+		t.Errorf("Expected 404 while profiling is disabled, got %d", rec.Code)
+	}
+}
+
+func TestGitSyncEndpoints(t *testing.T) {
+	wikiInstance, _ := wiki.NewWiki(t.TempDir(), "admin", "secretkey", false)
+	defer wikiInstance.Close()
+	router := NewRouter(wikiInstance, false, "")
+
+	if rec := authenticatedRequest(t, router, http.MethodGet, "/api/admin/git-sync", nil); rec.Code != http.StatusNotFound {
+		t.Errorf("Expected 404 without git sync, got %d", rec.Code)
+	}
+
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git is not installed")
+	}
+	remote := filepath.Join(t.TempDir(), "content.git")
+	if out, err := exec.Command("git", "init", "--quiet", "--bare", remote).CombinedOutput(); err != nil {
+		t.Fatalf("git init failed: %v %s", err, out)
+	}
+	synced, _ := wiki.NewWiki(t.TempDir(), "admin", "secretkey", false, wiki.WithGitSync(gitsync.Config{Remote: remote, Interval: time.Hour}))
+	defer synced.Close()
+	router = NewRouter(synced, false, "")
+
+	rec := authenticatedRequest(t, router, http.MethodPost, "/api/admin/git-sync", nil)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("Expected 200 OK, got %d - %s", rec.Code, rec.Body.String())
+	}
+	var status gitsync.Status
+	if err := json.Unmarshal(rec.Body.Bytes(), &status); err != nil || status.LastSync.IsZero() {
+		t.Errorf("Expected a sync status, got %s (%v)", rec.Body.String(), err)
+	}
+	if rec := authenticatedRequest(t, router, http.MethodGet, "/api/admin/git-sync", nil); rec.Code != http.StatusOK {
+		t.Errorf("Expected 200 OK, got %d", rec.Code)
+	}
+}
+
+func TestAutosaveEndpoints(t *testing.T) {
+	wikiInstance, _ := wiki.NewWiki(t.TempDir(), "admin", "secretkey", false)
+	defer wikiInstance.Close()
+	router := NewRouter(wikiInstance, false, "")
+
+	page, err := wikiInstance.CreatePage(nil, "Draft Page", "draft-page")
+	if err != nil {
+		t.Fatalf("CreatePage failed: %v", err)
+	}
+	url := "/api/pages/" + page.ID + "/autosave"
+
+	if rec := authenticatedRequest(t, router, http.MethodGet, url, nil); rec.Code != http.StatusNotFound {
+		t.Fatalf("Expected 404 without a draft, got %d", rec.Code)
+	}
+
+	rec := authenticatedRequest(t, router, http.MethodPut, url, strings.NewReader(`{"title": "Draft Page", "content": "# Unsaved"}`))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("Expected 200 OK, got %d - %s", rec.Code, rec.Body.String())
+	}
+
+	// the draft is recoverable, the page is unchanged
+	rec = authenticatedRequest(t, router, http.MethodGet, url, nil)
+	var draft map[string]interface{}
+	if err := json.Unmarshal(rec.Body.Bytes(), &draft); err != nil {
+		t.Fatalf("Invalid JSON response: %v", err)
+	}
+	if rec.Code != http.StatusOK || draft["content"] != "# Unsaved" || draft["savedAt"] == "" {
+		t.Errorf("Expected the draft, got %d - %s", rec.Code, rec.Body.String())
+	}
+	if saved, _ := wikiInstance.GetPage(page.ID); strings.Contains(saved.Content, "Unsaved") {
+		t.Errorf("Expected the page file to be unchanged, got %q", saved.Content)
+	}
+
+	// saving the page clears the draft
+	body := `{"title": "Draft Page", "slug": "draft-page", "content": "# Saved"}`
+	if rec := authenticatedRequest(t, router, http.MethodPut, "/api/pages/"+page.ID, strings.NewReader(body)); rec.Code != http.StatusOK {
+		t.Fatalf("Expected 200 OK, got %d - %s", rec.Code, rec.Body.String())
+	}
+	if rec := authenticatedRequest(t, router, http.MethodGet, url, nil); rec.Code != http.StatusNotFound {
+		t.Errorf("Expected the draft to be cleared on save, got %d", rec.Code)
+	}
+
+	authenticatedRequest(t, router, http.MethodPut, url, strings.NewReader(`{"title": "Draft Page", "content": "# Dropped"}`))
+	if rec := authenticatedRequest(t, router, http.MethodDelete, url, nil); rec.Code != http.StatusNoContent {
+		t.Errorf("Expected 204 No Content, got %d", rec.Code)
+	}
+	if rec := authenticatedRequest(t, router, http.MethodGet, url, nil); rec.Code != http.StatusNotFound {
+		t.Errorf("Expected the draft to be discarded, got %d", rec.Code)
+	}
+
+	rec = authenticatedRequest(t, router, http.MethodPut, "/api/pages/missing/autosave", strings.NewReader(`{"content": "x"}`))
+	if rec.Code != http.StatusNotFound {
+		t.Errorf("Expected 404 for unknown page, got %d", rec.Code)
+	}
+}
+
+func TestPageTOCEndpoint(t *testing.T) {
+	wikiInstance, _ := wiki.NewWiki(t.TempDir(), "admin", "secretkey", false)
+	defer wikiInstance.Close()
+	router := NewRouter(wikiInstance, false, "")
+
+	page, _ := wikiInstance.CreatePage(nil, "Guide", "guide")
+	if _, err := wikiInstance.UpdatePage(page.ID, "Guide", "guide", "# Guide\n## Getting Started\n## FAQ"); err != nil {
+		t.Fatalf("UpdatePage failed: %v", err)
+	}
+
+	rec := authenticatedRequest(t, router, http.MethodGet, "/api/pages/"+page.ID+"/toc", nil)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("Expected 200 OK, got %d", rec.Code)
+	}
+	var resp wiki.PageTOC
+	if err := json.Unmarshal(rec.Body.Bytes(), &resp); err != nil {
+		t.Fatalf("Invalid JSON: %v", err)
+	}
+	if len(resp.Headings) != 1 || len(resp.Headings[0].Children) != 2 || resp.Headings[0].Children[0].Anchor != "getting-started" {
+		t.Errorf("Unexpected headings: %s", rec.Body.String())
+	}
+
+	rec = authenticatedRequest(t, router, http.MethodGet, "/api/pages/missing/toc", nil)
+	if rec.Code != http.StatusNotFound {
+		t.Errorf("Expected 404 for unknown page, got %d", rec.Code)
+	}
+}
+
+func TestLintEndpoints(t *testing.T) {
+	wikiInstance, _ := wiki.NewWiki(t.TempDir(), "admin", "secretkey", false)
+	defer wikiInstance.Close()
+	router := NewRouter(wikiInstance, false, "")
+
+	page, _ := wikiInstance.CreatePage(nil, "Guide", "guide")
+	body := `{"title": "Guide", "slug": "guide", "content": "## Guide\n![](diagram.png) [setup](/missing)"}`
+	rec := authenticatedRequest(t, router, http.MethodPut, "/api/pages/"+page.ID, strings.NewReader(body))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("Expected 200 OK for update, got %d: %s", rec.Code, rec.Body.String())
+	}
+	var saved api.SavedPage
+	if err := json.Unmarshal(rec.Body.Bytes(), &saved); err != nil {
+		t.Fatalf("Invalid JSON: %v", err)
+	}
+	if saved.Page == nil || saved.ID != page.ID || len(saved.Lint.Warnings) != 3 {
+		t.Errorf("Expected the page with 3 lint warnings, got %s", rec.Body.String())
+	}
+
+	rec = authenticatedRequest(t, router, http.MethodGet, "/api/pages/"+page.ID+"/lint", nil)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("Expected 200 OK, got %d", rec.Code)
+	}
+	var result lint.Result
+	if err := json.Unmarshal(rec.Body.Bytes(), &result); err != nil {
+		t.Fatalf("Invalid JSON: %v", err)
+	}
+	if len(result.Warnings) != 3 || result.Warnings[0].Rule != lint.RuleMissingH1 {
+		t.Errorf("Unexpected lint result: %s", rec.Body.String())
+	}
+
+	rec = authenticatedRequest(t, router, http.MethodGet, "/api/pages/unknown/lint", nil)
+	if rec.Code != http.StatusNotFound {
+		t.Errorf("Expected 404 for unknown page, got %d", rec.Code)
+	}
+}
+
+func TestRenderedPageEndpoint(t *testing.T) {
+	wikiInstance, _ := wiki.NewWiki(t.TempDir(), "admin", "secretkey", false)
+	defer wikiInstance.Close()
+	router := NewRouter(wikiInstance, false, "")
+
+	page, _ := wikiInstance.CreatePage(nil, "Guide", "guide")
+	if _, err := wikiInstance.UpdatePage(page.ID, "Guide", "guide", "# Guide\n```python\nreturn None\n```\n<script>alert(1)</script>"); err != nil {
+		t.Fatalf("UpdatePage failed: %v", err)
+	}
+
+	rec := authenticatedRequest(t, router, http.MethodGet, "/api/pages/"+page.ID+"/html", nil)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("Expected 200 OK, got %d", rec.Code)
+	}
+	var resp wiki.RenderedPage
+	if err := json.Unmarshal(rec.Body.Bytes(), &resp); err != nil {
+		t.Fatalf("Invalid JSON: %v", err)
+	}
+	if !strings.Contains(resp.HTML, `<span class="kc">None</span>`) || strings.Contains(resp.HTML, "<script>") {
+		t.Errorf("Unexpected HTML: %s", resp.HTML)
+	}
+	if !strings.Contains(resp.Style, ".chroma {") {
+		t.Errorf("Expected the theme stylesheet, got %q", resp.Style)
+	}
+
+	// highlighting can be switched off
+	rec = authenticatedRequest(t, router, http.MethodPut, "/api/admin/settings", strings.NewReader(`{"codeTheme": "off"}`))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("Expected 200 OK for settings update, got %d: %s", rec.Code, rec.Body.String())
+	}
+	rec = authenticatedRequest(t, router, http.MethodGet, "/api/pages/"+page.ID+"/html", nil)
+	if strings.Contains(rec.Body.String(), "chroma") {
+		t.Errorf("Expected no highlighting, got %s", rec.Body.String())
+	}
+
+	rec = authenticatedRequest(t, router, http.MethodPut, "/api/admin/settings", strings.NewReader(`{"codeTheme": "neon"}`))
+	if rec.Code != http.StatusBadRequest {
+		t.Errorf("Expected 400 for unknown theme, got %d", rec.Code)
+	}
+}
+
+func TestLinkCheckEndpoints(t *testing.T) {
+	wikiInstance, _ := wiki.NewWiki(t.TempDir(), "admin", "secretkey", false)
+	defer wikiInstance.Close()
+	router := NewRouter(wikiInstance, false, "")
+
+	rec := authenticatedRequest(t, router, http.MethodGet, "/api/admin/linkcheck", nil)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("Expected 200 OK, got %d", rec.Code)
+	}
+	var report wiki.LinkReport
+	if err := json.Unmarshal(rec.Body.Bytes(), &report); err != nil {
+		t.Fatalf("Invalid JSON: %v", err)
+	}
+	if report.CheckedAt != nil || len(report.Pages) != 0 {
+		t.Errorf("Expected an empty report, got %s", rec.Body.String())
+	}
+
+	rec = authenticatedRequest(t, router, http.MethodPost, "/api/admin/linkcheck", nil)
+	if rec.Code != http.StatusAccepted {
+		t.Errorf("Expected 202 Accepted, got %d", rec.Code)
+	}
+}
+
+func TestReplaceEndpoint(t *testing.T) {
+	wikiInstance, _ := wiki.NewWiki(t.TempDir(), "admin", "secretkey", false)
+	defer wikiInstance.Close()
+	router := NewRouter(wikiInstance, false, "")
+
+	page, _ := wikiInstance.CreatePage(nil, "Notes", "notes")
+	if _, err := wikiInstance.UpdatePage(page.ID, "Notes", "notes", "old name"); err != nil {
+		t.Fatalf("UpdatePage failed: %v", err)
+	}
+
+	rec := authenticatedRequest(t, router, http.MethodPost, "/api/admin/replace", strings.NewReader(`{"find":"old","replace":"new","pageId":"`+page.ID+`"}`))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("Expected 200 OK, got %d: %s", rec.Code, rec.Body.String())
+	}
+	if updated, _ := wikiInstance.GetPage(page.ID); updated.Content != "new name" {
+		t.Errorf("Expected content to be replaced, got %q", updated.Content)
+	}
+
+	rec = authenticatedRequest(t, router, http.MethodPost, "/api/admin/replace", strings.NewReader(`{"find":""}`))
+	if rec.Code != http.StatusBadRequest {
+		t.Errorf("Expected 400 for empty find, got %d", rec.Code)
+	}
+}
+
+func TestRedirects(t *testing.T) {
+	wikiInstance, _ := wiki.NewWiki(t.TempDir(), "admin", "secretkey", false)
+	defer wikiInstance.Close()
+	router := NewRouter(wikiInstance, false, "")
+
+	page, _ := wikiInstance.CreatePage(nil, "Guide", "guide")
+	if _, err := wikiInstance.UpdatePage(page.ID, "Guide", "manual", ""); err != nil {
+		t.Fatalf("UpdatePage failed: %v", err)
+	}
+
+	for url, want := range map[string]string{"/guide?x=1": "/manual?x=1", "/e/guide": "/e/manual"} {
+		rec := httptest.NewRecorder()
+		router.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, url, nil))
+		if rec.Code != http.StatusMovedPermanently || rec.Header().Get("Location") != want {
+			t.Errorf("%s: expected 301 to %s, got %d %q", url, want, rec.Code, rec.Header().Get("Location"))
+		}
+	}
+	rec := httptest.NewRecorder()
+	router.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/unknown", nil))
+	if rec.Code != http.StatusNotFound {
+		t.Errorf("Expected 404 for unknown route, got %d", rec.Code)
+	}
+
+	rec = authenticatedRequest(t, router, http.MethodGet, "/api/pages/by-path?path=guide", nil)
+	if rec.Code != http.StatusNotFound || !strings.Contains(rec.Body.String(), `"to":"manual"`) {
+		t.Errorf("Expected 404 with redirect, got %d %s", rec.Code, rec.Body.String())
+	}
+
+	rec = authenticatedRequest(t, router, http.MethodGet, "/api/admin/redirects", nil)
+	var list []wiki.Redirect
+	if err := json.Unmarshal(rec.Body.Bytes(), &list); err != nil || len(list) != 1 || list[0].From != "guide" {
+		t.Fatalf("Expected one redirect, got %d %s", rec.Code, rec.Body.String())
+	}
+
+	rec = authenticatedRequest(t, router, http.MethodDelete, "/api/admin/redirects?from=guide", nil)
+	if rec.Code != http.StatusNoContent {
+		t.Errorf("Expected 204 No Content, got %d", rec.Code)
+	}
+	rec = authenticatedRequest(t, router, http.MethodDelete, "/api/admin/redirects?from=guide", nil)
+	if rec.Code != http.StatusNotFound {
+		t.Errorf("Expected 404 for deleted redirect, got %d", rec.Code)
+	}
+}
+
+func TestFavoriteEndpoints(t *testing.T) {
+	wikiInstance, _ := wiki.NewWiki(t.TempDir(), "admin", "secretkey", false)
+	router := NewRouter(wikiInstance, false, "")
+
+	page, err := wikiInstance.CreatePage(nil, "Handbook", "handbook")
+	if err != nil {
+		t.Fatalf("Failed to create page: %v", err)
+	}
+
+	rec := authenticatedRequest(t, router, http.MethodPost, "/api/me/favorites", strings.NewReader(`{"pageId": "`+page.ID+`"}`))
+	if rec.Code != http.StatusCreated {
+		t.Fatalf("Expected 201 Created, got %d - %s", rec.Code, rec.Body.String())
+	}
+
+	rec = authenticatedRequest(t, router, http.MethodPost, "/api/me/favorites", strings.NewReader(`{"pageId": "unknown"}`))
+	if rec.Code != http.StatusNotFound {
+		t.Fatalf("Expected 404 for unknown page, got %d", rec.Code)
+	}
+
+	rec = authenticatedRequest(t, router, http.MethodGet, "/api/me/favorites", nil)
+	var favs []map[string]interface{}
+	if err := json.Unmarshal(rec.Body.Bytes(), &favs); err != nil {
+		t.Fatalf("Failed to parse JSON: %v", err)
+	}
+	if len(favs) != 1 || favs[0]["path"] != "handbook" || favs[0]["title"] != "Handbook" {
+		t.Fatalf("Unexpected favorites: %v", favs)
+	}
+
+	rec = authenticatedRequest(t, router, http.MethodDelete, "/api/me/favorites/"+page.ID, nil)
+	if rec.Code != http.StatusNoContent {
+		t.Fatalf("Expected 204 No Content, got %d - %s", rec.Code, rec.Body.String())
+	}
+	rec = authenticatedRequest(t, router, http.MethodDelete, "/api/me/favorites/"+page.ID, nil)
+	if rec.Code != http.StatusNotFound {
+		t.Fatalf("Expected 404 for removed favorite, got %d", rec.Code)
+	}
+}
+
+func TestRecentPagesEndpoint(t *testing.T) {
+	wikiInstance, _ := wiki.NewWiki(t.TempDir(), "admin", "secretkey", false)
+	defer wikiInstance.Close()
+	router := NewRouter(wikiInstance, false, "")
+
+	page, err := wikiInstance.CreatePage(nil, "Runbook", "runbook")
+	if err != nil {
+		t.Fatalf("Failed to create page: %v", err)
+	}
+	read, err := wikiInstance.CreatePage(nil, "Handbook", "handbook")
+	if err != nil {
+		t.Fatalf("Failed to create page: %v", err)
+	}
+	recentPaths := func() []string {
+		t.Helper()
+		rec := authenticatedRequest(t, router, http.MethodGet, "/api/users/me/recently-read", nil)
+		var recent []map[string]interface{}
+		if err := json.Unmarshal(rec.Body.Bytes(), &recent); err != nil {
+			t.Fatalf("Failed to parse JSON: %v", err)
+		}
+		paths := []string{}
+		for _, entry := range recent {
+			paths = append(paths, entry["path"].(string))
+		}
+		return paths
+	}
+
+	// the list builds on the reading positions
+	authenticatedRequest(t, router, http.MethodPut, "/api/pages/"+read.ID+"/reading-position", strings.NewReader(`{"anchor": "intro"}`))
+
+	// tracking the views is opt-in per user
+	authenticatedRequest(t, router, http.MethodGet, "/api/pages/"+page.ID, nil)
+	if paths := recentPaths(); fmt.Sprint(paths) != "[handbook]" {
+		t.Fatalf("Expected only the read page before opting in, got %v", paths)
+	}
+	rec := authenticatedRequest(t, router, http.MethodGet, "/api/users/me/recently-read/tracking", nil)
+	if rec.Code != http.StatusOK || !strings.Contains(rec.Body.String(), `"enabled":false`) {
+		t.Fatalf("Expected tracking to be disabled, got %d - %s", rec.Code, rec.Body.String())
+	}
+	if rec := authenticatedRequest(t, router, http.MethodPut, "/api/users/me/recently-read/tracking", strings.NewReader(`{}`)); rec.Code != http.StatusBadRequest {
+		t.Errorf("Expected 400 without enabled, got %d", rec.Code)
+	}
+	rec = authenticatedRequest(t, router, http.MethodPut, "/api/users/me/recently-read/tracking", strings.NewReader(`{"enabled": true}`))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("Expected 200 OK, got %d - %s", rec.Code, rec.Body.String())
+	}
+
+	authenticatedRequest(t, router, http.MethodGet, "/api/pages/by-path?path=runbook", nil)
+	if paths := recentPaths(); fmt.Sprint(paths) != "[runbook handbook]" {
+		t.Fatalf("Expected the viewed page first, got %v", paths)
+	}
+
+	// disabling the tracking globally forgets the visits, the reading positions are kept
+	authenticatedRequest(t, router, http.MethodPut, "/api/admin/settings", strings.NewReader(`{"trackRecentPages": false}`))
+	authenticatedRequest(t, router, http.MethodGet, "/api/pages/"+page.ID, nil)
+	if paths := recentPaths(); fmt.Sprint(paths) != "[handbook]" {
+		t.Fatalf("Expected the visits to be deleted, got %v", paths)
+	}
+	authenticatedRequest(t, router, http.MethodPut, "/api/admin/settings", strings.NewReader(`{"trackRecentPages": true}`))
+
+	// opting out forgets them too
+	authenticatedRequest(t, router, http.MethodGet, "/api/pages/"+page.ID, nil)
+	authenticatedRequest(t, router, http.MethodPut, "/api/users/me/recently-read/tracking", strings.NewReader(`{"enabled": false}`))
+	if paths := recentPaths(); fmt.Sprint(paths) != "[handbook]" {
+		t.Fatalf("Expected the visits to be deleted after opting out, got %v", paths)
+	}
+}
+
+func TestValidateEndpoint(t *testing.T) {
+	wikiInstance, _ := wiki.NewWiki(t.TempDir(), "admin", "secretkey", false)
+	defer wikiInstance.Close()
+	router := NewRouter(wikiInstance, false, "")
+
+	body := `{"content": "---\ntags: [a\n---\n| a | b |\n|---|---|\n| 1 |  2 | 3 |\n\n` + "```" + `\ncode"}`
+	rec := authenticatedRequest(t, router, http.MethodPost, "/api/validate", strings.NewReader(body))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("Expected 200 OK, got %d - %s", rec.Code, rec.Body.String())
+	}
+	var resp validate.Result
+	if err := json.Unmarshal(rec.Body.Bytes(), &resp); err != nil {
+		t.Fatalf("Invalid JSON: %v", err)
+	}
+	var rules []string
+	for _, w := range resp.Warnings {
+		rules = append(rules, fmt.Sprintf("%s:%d", w.Rule, w.Line))
+	}
+	if got := strings.Join(rules, ","); got != "frontmatter:2,table:6,code-fence:8" {
+		t.Errorf("Unexpected warnings %s: %s", got, rec.Body.String())
+	}
+
+	rec = authenticatedRequest(t, router, http.MethodPost, "/api/validate", strings.NewReader(`{"content": "# Fine"}`))
+	if rec.Code != http.StatusOK || strings.TrimSpace(rec.Body.String()) != `{"warnings":[]}` {
+		t.Errorf("Expected no warnings, got %d - %s", rec.Code, rec.Body.String())
+	}
+
+	rec = authenticatedRequest(t, router, http.MethodPost, "/api/validate", strings.NewReader(`not json`))
+	if rec.Code != http.StatusBadRequest {
+		t.Errorf("Expected 400 for an invalid payload, got %d", rec.Code)
+	}
+}
+
+func TestSpellcheckEndpoints(t *testing.T) {
+	disabled, _ := wiki.NewWiki(t.TempDir(), "admin", "secretkey", false)
+	rec := authenticatedRequest(t, NewRouter(disabled, false, ""), http.MethodGet, "/api/spellcheck/languages", nil)
+	if rec.Code != http.StatusNotFound {
+		t.Fatalf("Expected 404 without dictionaries, got %d", rec.Code)
+	}
+
+	dicts := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dicts, "en_US.aff"), []byte("SET UTF-8\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(dicts, "en_US.dic"), []byte("2\nthe\nhandbook\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	wikiInstance, _ := wiki.NewWiki(t.TempDir(), "admin", "secretkey", false, wiki.WithSpellcheck(dicts))
+	router := NewRouter(wikiInstance, false, "")
+	page, err := wikiInstance.CreatePage(nil, "Handbook", "handbook")
+	if err != nil {
+		t.Fatalf("Failed to create page: %v", err)
+	}
+
+	// without a body the saved content is checked
+	rec = authenticatedRequest(t, router, http.MethodPost, "/api/pages/"+page.ID+"/spellcheck", nil)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("Expected 200 OK, got %d - %s", rec.Code, rec.Body.String())
+	}
+
+	rec = authenticatedRequest(t, router, http.MethodPost, "/api/pages/"+page.ID+"/spellcheck", strings.NewReader(`{"content": "the hanbook"}`))
+	var result struct {
+		Language     string `json:"language"`
+		Misspellings []struct {
+			Word   string `json:"word"`
+			Offset int    `json:"offset"`
+		} `json:"misspellings"`
+	}
+	if err := json.Unmarshal(rec.Body.Bytes(), &result); err != nil {
+		t.Fatalf("Failed to parse JSON: %v", err)
+	}
+	if result.Language != "en_US" || len(result.Misspellings) != 1 || result.Misspellings[0].Offset != 4 {
+		t.Fatalf("Unexpected result: %s", rec.Body.String())
+	}
+
+	rec = authenticatedRequest(t, router, http.MethodPost, "/api/spellcheck/dictionary", strings.NewReader(`{"word": "hanbook"}`))
+	if rec.Code != http.StatusCreated {
+		t.Fatalf("Expected 201 Created, got %d - %s", rec.Code, rec.Body.String())
+	}
+	rec = authenticatedRequest(t, router, http.MethodDelete, "/api/spellcheck/dictionary/hanbook", nil)
+	if rec.Code != http.StatusNoContent {
+		t.Fatalf("Expected 204 No Content, got %d", rec.Code)
+	}
+	rec = authenticatedRequest(t, router, http.MethodDelete, "/api/spellcheck/dictionary/hanbook", nil)
+	if rec.Code != http.StatusNotFound {
+		t.Fatalf("Expected 404 for a removed word, got %d", rec.Code)
+	}
+}
+
+func TestSimilarPagesEndpoints(t *testing.T) {
+	wikiInstance, _ := wiki.NewWiki(t.TempDir(), "admin", "secretkey", false)
+	router := NewRouter(wikiInstance, false, "")
+
+	var pageID, restoreID string
+	for _, title := range []string{"Backup", "Restore"} {
+		p, err := wikiInstance.CreatePage(nil, title, strings.ToLower(title))
+		if err != nil {
+			t.Fatalf("Failed to create page: %v", err)
+		}
+		if _, err := wikiInstance.UpdatePage(p.ID, title, strings.ToLower(title), "Restore postgres backups with pgrestore."); err != nil {
+			t.Fatalf("Failed to update page: %v", err)
+		}
+		pageID, restoreID = restoreID, p.ID
+	}
+	if err := wikiInstance.ReindexAll(); err != nil {
+		t.Fatalf("ReindexAll failed: %v", err)
+	}
+	for deadline := time.Now().Add(5 * time.Second); wikiInstance.GetIndexingStatus().IsActive(); {
+		if time.Now().After(deadline) {
+			t.Fatal("Indexing did not finish")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	rec := authenticatedRequest(t, router, http.MethodGet, "/api/pages/"+pageID+"/similar", nil)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("Expected 200 OK, got %d - %s", rec.Code, rec.Body.String())
+	}
+	var similar []map[string]interface{}
+	if err := json.Unmarshal(rec.Body.Bytes(), &similar); err != nil {
+		t.Fatalf("Failed to parse JSON: %v", err)
+	}
+	if len(similar) != 1 || similar[0]["pageId"] != restoreID {
+		t.Fatalf("Unexpected similar pages: %s", rec.Body.String())
+	}
+
+	rec = authenticatedRequest(t, router, http.MethodGet, "/api/pages/similar?title=Restore+postgres+backups", nil)
+	if rec.Code != http.StatusOK || !strings.Contains(rec.Body.String(), restoreID) {
+		t.Fatalf("Expected the page to be found by title, got %d - %s", rec.Code, rec.Body.String())
+	}
+
+	rec = authenticatedRequest(t, router, http.MethodGet, "/api/pages/"+pageID+"/similar?limit=0", nil)
+	if rec.Code != http.StatusBadRequest {
+		t.Fatalf("Expected 400 for an invalid limit, got %d", rec.Code)
+	}
+}
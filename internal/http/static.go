@@ -0,0 +1,68 @@
+package http
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"io/fs"
+	"strings"
+	"sync"
+
+	"github.com/gin-gonic/gin"
+)
+
+// embeddedETags derives the ETags of the embedded frontend files from their content,
+// each file is hashed once
+type embeddedETags struct {
+	fsys  fs.FS
+	mu    sync.Mutex
+	etags map[string]string
+}
+
+func newEmbeddedETags(fsys fs.FS) *embeddedETags {
+	return &embeddedETags{fsys: fsys, etags: map[string]string{}}
+}
+
+// get returns the ETag of the file, empty if it doesn't exist
+func (e *embeddedETags) get(name string) string {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	if etag, ok := e.etags[name]; ok {
+		return etag
+	}
+	data, err := fs.ReadFile(e.fsys, name)
+	if err != nil {
+		return ""
+	}
+	sum := sha256.Sum256(data)
+	etag := `"` + hex.EncodeToString(sum[:16]) + `"`
+	e.etags[name] = etag
+	return etag
+}
+
+// withBasePath points the links of index.html to the files under the base path and passes the
+// base path to the frontend. The bundle is built with relative links (see vite.config.ts),
+// which would resolve against the route of the page otherwise.
+func withBasePath(html []byte, basePath string) []byte {
+	page := string(html)
+	for _, file := range []string{"static/", "favicon.svg"} {
+		page = strings.ReplaceAll(page, `="./`+file, `="`+basePath+"/"+file)
+		page = strings.ReplaceAll(page, `="/`+file, `="`+basePath+"/"+file)
+	}
+	if basePath != "" {
+		page = strings.Replace(page, "</head>", `  <meta name="leafwiki-base-path" content="`+basePath+`">`+"\n  </head>", 1)
+	}
+	return []byte(page)
+}
+
+// cacheStaticFiles sets the caching headers of the files of the frontend bundle. Their names
+// contain the hash of their content (see vite.config.ts), so browsers may keep them forever.
+// The ETag lets clients revalidate nonetheless, the file server answers it with 304.
+func cacheStaticFiles(etags *embeddedETags) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if etag := etags.get(strings.TrimPrefix(c.Param("filepath"), "/")); etag != "" {
+			c.Header("Cache-Control", "public, max-age=31536000, immutable")
+			c.Header("ETag", etag)
+		}
+		c.Next()
+	}
+}
@@ -0,0 +1,64 @@
+package http
+
+import (
+	"log/slog"
+	"net/http"
+
+	"github.com/Gomez12/wiki/internal/core/auth"
+	"github.com/Gomez12/wiki/internal/http/middleware"
+	"github.com/Gomez12/wiki/internal/wiki"
+	"github.com/gin-gonic/gin"
+	"golang.org/x/net/webdav"
+)
+
+// webdavPrefix is the path the Markdown files are mounted at
+const webdavPrefix = "/webdav"
+
+var webdavMethods = []string{
+	"GET", "HEAD", "OPTIONS", "PUT", "DELETE",
+	"PROPFIND", "PROPPATCH", "MKCOL", "COPY", "MOVE", "LOCK", "UNLOCK",
+}
+
+// registerWebDAV serves the Markdown files at /webdav, so they can be mounted as network drive.
+// Every request needs the credentials of a user, also while the wiki is publicly readable.
+func registerWebDAV(router *gin.Engine, wikiInstance *wiki.Wiki) {
+	// locks have to outlive the requests
+	locks := webdav.NewMemLS()
+
+	handler := func(c *gin.Context) {
+		user := c.MustGet("user").(*auth.User)
+		// written files may be assets, so they get the limit of uploads
+		if c.Request.Method == http.MethodPut {
+			limit := wikiInstance.MaxUploadSize()
+			if c.Request.ContentLength > limit {
+				c.AbortWithStatusJSON(http.StatusRequestEntityTooLarge, gin.H{"error": "file too large"})
+				return
+			}
+			c.Request.Body = http.MaxBytesReader(c.Writer, c.Request.Body, limit)
+		}
+		// the paths in responses and the Destination header of MOVE and COPY include the base path
+		r := c.Request
+		if basePath := wikiInstance.BasePath(); basePath != "" {
+			r = r.Clone(r.Context())
+			r.URL.Path = basePath + r.URL.Path
+			r.URL.RawPath = ""
+		}
+		h := &webdav.Handler{
+			Prefix:     wikiInstance.BasePath() + webdavPrefix,
+			FileSystem: wikiInstance.WebDAVFileSystem(user),
+			LockSystem: locks,
+			Logger: func(r *http.Request, err error) {
+				if err != nil {
+					slog.Debug("webdav request failed", "method", r.Method, "path", r.URL.Path, "error", err)
+				}
+			},
+		}
+		h.ServeHTTP(c.Writer, r)
+	}
+
+	group := router.Group(webdavPrefix, middleware.RejectCrossOrigin(), middleware.RequireBasicAuth(wikiInstance, "LeafWiki"))
+	for _, method := range webdavMethods {
+		group.Handle(method, "", handler)
+		group.Handle(method, "/*path", handler)
+	}
+}
@@ -0,0 +1,203 @@
+package search
+
+import (
+	"fmt"
+	"io/fs"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/Gomez12/wiki/internal/core/seed"
+	"github.com/Gomez12/wiki/internal/core/tree"
+)
+
+// benchmarkPages is the size of the synthetic corpus, run `make bench` to compare releases
+const benchmarkPages = 1000
+
+// newBenchmarkCorpus writes the synthetic corpus to a new data directory and returns the
+// directory, the root of the Markdown files and a loaded tree service
+func newBenchmarkCorpus(b *testing.B, pages int) (string, string, *tree.TreeService) {
+	b.Helper()
+	quietLogs(b)
+	tmp := b.TempDir()
+	dataDir := filepath.Join(tmp, "root")
+	if err := seed.Corpus(dataDir, pages, 1); err != nil {
+		b.Fatalf("Corpus failed: %v", err)
+	}
+	treeSvc := tree.NewTreeService(tmp)
+	if err := treeSvc.LoadTree(); err != nil {
+		b.Fatalf("failed to load tree: %v", err)
+	}
+	return tmp, dataDir, treeSvc
+}
+
+// quietLogs drops the info logs of every indexed page while the benchmark runs
+func quietLogs(b *testing.B) {
+	previous := slog.Default()
+	slog.SetDefault(slog.New(slog.NewTextHandler(os.Stderr, &slog.HandlerOptions{Level: slog.LevelWarn})))
+	b.Cleanup(func() { slog.SetDefault(previous) })
+}
+
+// newBenchmarkIndex returns a search index with the corpus indexed
+func newBenchmarkIndex(b *testing.B, pages int) (*SQLiteIndex, string) {
+	b.Helper()
+	tmp, dataDir, treeSvc := newBenchmarkCorpus(b, pages)
+	index, err := NewSQLiteIndex(tmp)
+	if err != nil {
+		b.Fatalf("Failed to init SQLiteIndex: %v", err)
+	}
+	b.Cleanup(func() { index.Close() })
+	if err := BuildAndRunIndexer(b.Context(), treeSvc, index, dataDir, 4, NewIndexingStatus()); err != nil {
+		b.Fatalf("BuildAndRunIndexer failed: %v", err)
+	}
+	return index, dataDir
+}
+
+func BenchmarkBuildAndRunIndexer(b *testing.B) {
+	for _, workers := range []int{1, 4} {
+		b.Run(fmt.Sprintf("workers=%d", workers), func(b *testing.B) {
+			for b.Loop() {
+				// every run starts from a new tree and an empty index
+				b.StopTimer()
+				tmp, dataDir, treeSvc := newBenchmarkCorpus(b, benchmarkPages)
+				index, err := NewSQLiteIndex(tmp)
+				if err != nil {
+					b.Fatalf("Failed to init SQLiteIndex: %v", err)
+				}
+				b.StartTimer()
+
+				if err := BuildAndRunIndexer(b.Context(), treeSvc, index, dataDir, workers, NewIndexingStatus()); err != nil {
+					b.Fatalf("BuildAndRunIndexer failed: %v", err)
+				}
+
+				b.StopTimer()
+				index.Close()
+				b.StartTimer()
+			}
+			b.ReportMetric(float64(benchmarkPages), "pages/op")
+		})
+	}
+}
+
+// BenchmarkIndexPages compares writing single pages, as the watcher does, with the batches of
+// a full indexing run. Every run writes the same pages again, so they are replaced.
+func BenchmarkIndexPages(b *testing.B) {
+	content := strings.Repeat("Some words of the page to index. ", 50)
+	newIndex := func(b *testing.B) *SQLiteIndex {
+		quietLogs(b)
+		index, err := NewSQLiteIndex(b.TempDir())
+		if err != nil {
+			b.Fatalf("Failed to init SQLiteIndex: %v", err)
+		}
+		b.Cleanup(func() { index.Close() })
+		return index
+	}
+
+	b.Run("single", func(b *testing.B) {
+		index := newIndex(b)
+		for b.Loop() {
+			for i := range benchmarkPages {
+				id := fmt.Sprintf("id%d", i)
+				if err := index.IndexPage("/docs/"+id, "docs/"+id+".md", id, "Page "+id, content); err != nil {
+					b.Fatalf("IndexPage failed: %v", err)
+				}
+			}
+		}
+		b.ReportMetric(float64(benchmarkPages), "pages/op")
+	})
+
+	b.Run("batch", func(b *testing.B) {
+		index := newIndex(b)
+		batch := index.NewBatch(0, func(filePath string, err error) {
+			if err != nil {
+				b.Fatalf("write of %s failed: %v", filePath, err)
+			}
+		})
+		for b.Loop() {
+			for i := range benchmarkPages {
+				id := fmt.Sprintf("id%d", i)
+				batch.Add("/docs/"+id, "docs/"+id+".md", id, "Page "+id, content)
+			}
+			batch.Flush()
+		}
+		b.ReportMetric(float64(benchmarkPages), "pages/op")
+	})
+}
+
+func BenchmarkCaptureFileHistory(b *testing.B) {
+	b.Run("initial", func(b *testing.B) {
+		for b.Loop() {
+			b.StopTimer()
+			tmp, dataDir, _ := newBenchmarkCorpus(b, benchmarkPages)
+			index, err := NewSQLiteIndex(tmp)
+			if err != nil {
+				b.Fatalf("Failed to init SQLiteIndex: %v", err)
+			}
+			b.StartTimer()
+
+			if err := index.CaptureFileHistory(b.Context(), dataDir); err != nil {
+				b.Fatalf("CaptureFileHistory failed: %v", err)
+			}
+
+			b.StopTimer()
+			index.Close()
+			b.StartTimer()
+		}
+	})
+
+	// the common case: a few pages changed since the last capture
+	b.Run("incremental", func(b *testing.B) {
+		index, dataDir := newBenchmarkIndex(b, benchmarkPages)
+		if err := index.CaptureFileHistory(b.Context(), dataDir); err != nil {
+			b.Fatalf("CaptureFileHistory failed: %v", err)
+		}
+		var changed []string
+		err := filepath.WalkDir(dataDir, func(p string, d fs.DirEntry, err error) error {
+			if err == nil && len(changed) < 5 && filepath.Ext(p) == ".md" && d.Name() != "index.md" {
+				changed = append(changed, p)
+			}
+			return err
+		})
+		if err != nil {
+			b.Fatal(err)
+		}
+		run := 0
+		for b.Loop() {
+			b.StopTimer()
+			run++
+			for _, file := range changed {
+				content := fmt.Sprintf("# Changed\n\nRun %d of the benchmark.\n", run)
+				if err := os.WriteFile(file, []byte(content), 0o644); err != nil {
+					b.Fatal(err)
+				}
+			}
+			b.StartTimer()
+
+			if err := index.CaptureFileHistory(b.Context(), dataDir); err != nil {
+				b.Fatalf("CaptureFileHistory failed: %v", err)
+			}
+		}
+	})
+}
+
+func BenchmarkSearch(b *testing.B) {
+	index, _ := newBenchmarkIndex(b, benchmarkPages)
+	queries := []struct{ name, query string }{
+		// a word of almost every page
+		{"frequent", "the"},
+		{"rare", "retrospective"},
+		{"phrase", "search index"},
+		{"prefix", "perf"},
+	}
+	for _, q := range queries {
+		b.Run(q.name, func(b *testing.B) {
+			for b.Loop() {
+				if _, err := index.Search(b.Context(), q.query, 0, 20); err != nil {
+					b.Fatalf("Search failed: %v", err)
+				}
+			}
+		})
+	}
+}
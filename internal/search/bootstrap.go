@@ -3,35 +3,113 @@ package search
 import (
 	"bufio"
 	"bytes"
-	"log"
+	"context"
+	"maps"
+	"os"
 	"path/filepath"
 	"strings"
+	"time"
 
 	"github.com/Gomez12/wiki/internal/core/tree"
 )
 
 // BuildAndRunIndexer initializes the indexer with the given tree service and SQLite index,
-func BuildAndRunIndexer(treeService *tree.TreeService, sqliteIndex *SQLiteIndex, dataDir string, workers int, status *IndexingStatus) error {
+// and brings the index up to date with the Markdown files below dataDir: files which didn't
+// change since they were indexed are skipped and the pages of removed files are removed, so
+// only the changes made while the server was down are indexed. It stops early when ctx ends.
+func BuildAndRunIndexer(ctx context.Context, treeService *tree.TreeService, sqliteIndex *SQLiteIndex, dataDir string, workers int, status *IndexingStatus) error {
+	return runIndexer(ctx, treeService, sqliteIndex, dataDir, []string{dataDir}, workers, status, true)
+}
+
+// RebuildPartition clears a single partition of the index and indexes the files of its subtree again.
+// The other partitions are not touched and stay searchable during the rebuild.
+func RebuildPartition(ctx context.Context, treeService *tree.TreeService, sqliteIndex *SQLiteIndex, dataDir string, partition string, workers int, status *IndexingStatus) error {
+	if err := sqliteIndex.ClearPartition(partition); err != nil {
+		status.Finish()
+		return err
+	}
+
+	roots := []string{filepath.Join(dataDir, "index.md")}
+	if partition != RootPartition {
+		roots = []string{
+			filepath.Join(dataDir, partition),
+			filepath.Join(dataDir, partition+".md"),
+		}
+	}
+
+	return runIndexer(ctx, treeService, sqliteIndex, dataDir, roots, workers, status, false)
+}
+
+// runIndexer indexes all Markdown files below the given roots. With update, the roots are the
+// whole data dir and only the files whose state differs from when they were indexed are read.
+func runIndexer(ctx context.Context, treeService *tree.TreeService, sqliteIndex *SQLiteIndex, dataDir string, roots []string, workers int, status *IndexingStatus, update bool) error {
 	status.Start()
-	indexer := NewIndexer(dataDir, workers, func(file string, content []byte) error {
-		rel, err := filepath.Rel(dataDir, file)
+
+	var existing []string
+	files := map[string]os.FileInfo{}
+	for _, root := range roots {
+		if _, err := os.Stat(root); err != nil {
+			continue
+		}
+		existing = append(existing, root)
+		maps.Copy(files, markdownFiles(root, sqliteIndex.walkOptions(dataDir)))
+	}
+
+	indexed := map[string]indexedFile{}
+	if update {
+		var err error
+		if indexed, err = sqliteIndex.loadIndexedFiles(); err != nil {
+			indexerLog.Warn("could not load the indexed files, indexing all files", "error", err)
+			indexed = map[string]indexedFile{}
+		}
+	}
+	found := map[string]bool{}
+	unchanged := map[string]bool{}
+	for file, info := range files {
+		rel, err := relativeDataPath(dataDir, file)
 		if err != nil {
-			status.Fail()
-			return err
+			continue
 		}
-		routePath := strings.TrimSuffix(rel, filepath.Ext(rel))
-		routePath = filepath.ToSlash(routePath)
+		found[rel] = true
+		if f, ok := indexed[rel]; ok && f.unchanged(info) {
+			// a page missing in the tree is attached by indexing its file
+			if _, err := treeService.FindPageByRoutePath(treeService.GetTree().Children, routePathFromFile(rel)); err == nil {
+				unchanged[file] = true
+			}
+		}
+	}
+	status.SetTotal(len(files) - len(unchanged))
 
+	// the pages are written in batches, a page counts as indexed once its batch is written
+	batch := sqliteIndex.NewBatch(0, func(filePath string, err error) {
+		if err != nil {
+			indexerLog.Error("could not index page", "path", filePath, "error", err)
+			status.RecordError(filePath, err)
+			return
+		}
+		status.Success()
+	})
+
+	indexFunc := func(file string, content []byte) error {
+		if ctx.Err() != nil {
+			return nil
+		}
+		rel, err := relativeDataPath(dataDir, file)
+		if err != nil {
+			status.RecordError(file, err)
+			return err
+		}
 		// Remove "/index" suffix from the route path unconditionally
-		routePath = strings.TrimSuffix(routePath, "/index")
+		routePath := routePathFromFile(rel)
 
 		page, err := treeService.FindPageByRoutePath(treeService.GetTree().Children, routePath)
 		if err != nil {
 			// the page is on the filesystem but not in the tree, attach it automatically
 			node, ensureErr := ensureTreeNodeForFile(treeService, routePath, content)
 			if ensureErr != nil {
-				log.Printf("[indexer] auto-attach failed for %s: %v", rel, ensureErr)
-				status.Fail()
+				indexerLog.Error("could not attach missing path", "path", rel, "error", ensureErr)
+				status.RecordError(rel, ensureErr)
+				sqliteIndex.recordIndexResult(rel, ensureErr)
 				return nil
 			}
 			page = &tree.Page{PageNode: node, Content: string(content)}
@@ -40,18 +118,54 @@ func BuildAndRunIndexer(treeService *tree.TreeService, sqliteIndex *SQLiteIndex,
 		// Get path by PageID
 		pagePath := page.CalculatePath()
 
-		if err := sqliteIndex.IndexPage(pagePath, rel, page.ID, page.Title, string(content)); err != nil {
-			log.Printf("[indexer] error indexing page %s: %v", rel, err)
-			status.Fail()
-			return err
+		// the file info is from before the file was read, a later change is found next time
+		if info, ok := files[file]; ok {
+			batch.addFile(pagePath, rel, page.ID, page.Title, string(content), newIndexedFile(info))
+		} else {
+			batch.Add(pagePath, rel, page.ID, page.Title, string(content))
 		}
-
-		status.Success()
 		return nil
-	})
+	}
+
+	var err error
+	for _, root := range existing {
+		indexer := NewIndexer(root, workers, indexFunc)
+		indexer.walk = sqliteIndex.walkOptions(dataDir)
+		indexer.status = status
+		indexer.skip = func(path string) bool { return unchanged[path] }
+		if err = indexer.Start(ctx); err != nil {
+			break
+		}
+	}
+	batch.Flush()
+
+	// the files removed while the server was down, only known once the whole data dir was seen
+	removed := 0
+	if update && err == nil && ctx.Err() == nil {
+		for rel := range indexed {
+			if found[rel] {
+				continue
+			}
+			if _, removeErr := sqliteIndex.RemovePageByFilePath(rel); removeErr != nil {
+				indexerLog.Error("could not remove page from index", "path", rel, "error", removeErr)
+				continue
+			}
+			removed++
+		}
+		if pruneErr := sqliteIndex.pruneIndexResults(found); pruneErr != nil {
+			indexerLog.Warn("could not remove the results of removed files", "error", pruneErr)
+		}
+	}
 
-	err := indexer.Start()
 	status.Finish()
+
+	snapshot := status.Snapshot()
+	indexerLog.Info("indexing finished",
+		"files", snapshot.Processed, "failed", snapshot.Failed,
+		"unchanged", len(unchanged), "removed", removed,
+		"duration", snapshot.FinishedAt.Sub(snapshot.StartedAt).Round(time.Millisecond),
+		"avg_index_ms", snapshot.Metrics.AvgIndexMs, "max_index_ms", snapshot.Metrics.MaxIndexMs,
+		"backpressure_ms", snapshot.Metrics.BackpressureMs)
 	return err
 }
 
@@ -5,6 +5,7 @@ import (
 	"path/filepath"
 	"strings"
 	"testing"
+	"time"
 
 	"github.com/Gomez12/wiki/internal/core/tree"
 )
@@ -37,7 +38,7 @@ func TestBuildAndRunIndexer_BasicIndexing(t *testing.T) {
 	status := NewIndexingStatus()
 
 	corePath := filepath.Join(tmp, "root")
-	err = BuildAndRunIndexer(treeSvc, index, corePath, 2, status)
+	err = BuildAndRunIndexer(t.Context(), treeSvc, index, corePath, 2, status)
 	if err != nil {
 		t.Fatalf("BuildAndRunIndexer failed: %v", err)
 	}
@@ -64,4 +65,99 @@ func TestBuildAndRunIndexer_BasicIndexing(t *testing.T) {
 		t.Errorf("expected at least 1 indexed page, got %d", snap.Indexed)
 	}
 
+	if snap.Total != snap.Processed {
+		t.Errorf("expected all %d files to be processed, got %d", snap.Total, snap.Processed)
+	}
+
+}
+
+func TestBuildAndRunIndexer_OnlyChangedFiles(t *testing.T) {
+	tmp := t.TempDir()
+	corePath := filepath.Join(tmp, "root")
+	treeSvc := tree.NewTreeService(tmp)
+	if err := treeSvc.LoadTree(); err != nil {
+		t.Fatalf("failed to load tree: %v", err)
+	}
+
+	// modification times outside the racy window, so the files count as unchanged
+	modTime := time.Now().Add(-time.Hour)
+	writePage := func(slug, content string) {
+		t.Helper()
+		mdPath := filepath.Join(corePath, slug+".md")
+		if err := os.WriteFile(mdPath, []byte(content), 0644); err != nil {
+			t.Fatalf("Failed to write .md file: %v", err)
+		}
+		if err := os.Chtimes(mdPath, modTime, modTime); err != nil {
+			t.Fatal(err)
+		}
+	}
+	for _, slug := range []string{"docs", "guide", "old"} {
+		if _, err := treeSvc.CreatePage(nil, slug, slug); err != nil {
+			t.Fatalf("CreatePage failed: %v", err)
+		}
+		writePage(slug, "# "+slug+"\nThe "+slug+"word page.")
+	}
+	writePage("index", "# Home")
+
+	index, err := NewSQLiteIndex(tmp)
+	if err != nil {
+		t.Fatalf("Failed to init SQLiteIndex: %v", err)
+	}
+	if err := BuildAndRunIndexer(t.Context(), treeSvc, index, corePath, 2, NewIndexingStatus()); err != nil {
+		t.Fatalf("BuildAndRunIndexer failed: %v", err)
+	}
+	if err := index.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	// changes while the server is down
+	modTime = modTime.Add(time.Minute)
+	writePage("guide", "# guide\nThe changedword page.")
+	if _, err := treeSvc.CreatePage(nil, "fresh", "fresh"); err != nil {
+		t.Fatalf("CreatePage failed: %v", err)
+	}
+	writePage("fresh", "# fresh\nThe freshword page.")
+	if err := os.Remove(filepath.Join(corePath, "old.md")); err != nil {
+		t.Fatal(err)
+	}
+
+	index, err = NewSQLiteIndex(tmp)
+	if err != nil {
+		t.Fatalf("Failed to init SQLiteIndex: %v", err)
+	}
+	defer index.Close()
+	status := NewIndexingStatus()
+	if err := BuildAndRunIndexer(t.Context(), treeSvc, index, corePath, 2, status); err != nil {
+		t.Fatalf("BuildAndRunIndexer failed: %v", err)
+	}
+
+	if snap := status.Snapshot(); snap.Total != 2 || snap.Indexed != 2 {
+		t.Errorf("expected only the changed and the new file to be indexed, got %d of %d", snap.Indexed, snap.Total)
+	}
+	for query, want := range map[string]int{"docsword": 1, "changedword": 1, "guideword": 0, "freshword": 1, "oldword": 0} {
+		result, err := index.Search(t.Context(), query, 0, 10)
+		if err != nil {
+			t.Fatalf("Search failed: %v", err)
+		}
+		if result.Count != want {
+			t.Errorf("expected %d results for %q, got %d", want, query, result.Count)
+		}
+	}
+
+	// the pages of the other partition mode are not searchable, so all files are indexed again
+	index.SetPartitioned(true)
+	status = NewIndexingStatus()
+	if err := BuildAndRunIndexer(t.Context(), treeSvc, index, corePath, 2, status); err != nil {
+		t.Fatalf("BuildAndRunIndexer failed: %v", err)
+	}
+	if snap := status.Snapshot(); snap.Total != 4 {
+		t.Errorf("expected all 4 files to be indexed after the mode changed, got %d", snap.Total)
+	}
+	result, err := index.Search(t.Context(), "docsword", 0, 10)
+	if err != nil {
+		t.Fatalf("Search failed: %v", err)
+	}
+	if result.Count != 1 {
+		t.Errorf("expected the page in the partitioned index, got %d results", result.Count)
+	}
 }
@@ -0,0 +1,92 @@
+package search
+
+import (
+	"context"
+	"database/sql"
+	"path"
+	"strings"
+)
+
+// indexDeletedPageTx keeps the deleted pages index in line with a new history entry: a page
+// deleted at a path is indexed with its last snapshot, any other entry at the path removes it
+func indexDeletedPageTx(tx *sql.Tx, entryID int64, entry FileHistorySnapshot) error {
+	if _, err := tx.Exec(`DELETE FROM deleted_pages WHERE path = ?;`, entry.Path); err != nil {
+		return err
+	}
+	if entry.Status != FileStatusDeleted {
+		return nil
+	}
+	route := historyRoute(entry.Path)
+	_, err := tx.Exec(`
+		INSERT INTO deleted_pages (entryID, path, title, content)
+		VALUES (?, ?, ?, ?);
+	`, entryID, entry.Path, titleFromContent([]byte(entry.Content), path.Base(route)), PlainText(entry.Content))
+	return err
+}
+
+// historyRoute returns the route of the page a history path belongs to, e.g. docs for docs/index.md
+func historyRoute(historyPath string) string {
+	// the content of the root page
+	if historyPath == "index.md" {
+		return ""
+	}
+	if route, ok := strings.CutSuffix(historyPath, "/index.md"); ok {
+		return route
+	}
+	return strings.TrimSuffix(historyPath, ".md")
+}
+
+// SearchDeleted searches the last snapshots of the deleted pages. A negative limit returns
+// all matches. The items are marked as deleted and carry the history entry of the snapshot.
+func (s *SQLiteIndex) SearchDeleted(ctx context.Context, query string, offset, limit int) (*SearchResult, error) {
+	if s.db == nil {
+		return nil, sql.ErrConnDone
+	}
+
+	var total int
+	if err := s.db.QueryRowContext(ctx, `SELECT COUNT(*) FROM deleted_pages WHERE deleted_pages MATCH ?;`, query).Scan(&total); err != nil {
+		return nil, err
+	}
+
+	rows, err := s.db.QueryContext(ctx, `
+		SELECT d.entryID,
+			d.path,
+			highlight(deleted_pages, 2, '<b>', '</b>') AS highlighted_title,
+			snippet(deleted_pages, 3, '<b>', '</b>', '...', 16) AS excerpt,
+			bm25(deleted_pages, 10.0, 1.0) AS rank,
+			h.recorded_at
+		FROM deleted_pages d
+		JOIN file_history h ON h.id = d.entryID
+		WHERE deleted_pages MATCH ?
+		ORDER BY rank ASC
+		LIMIT ? OFFSET ?;
+	`, query, limit, offset)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var results []SearchResultItem
+	for rows.Next() {
+		var r SearchResultItem
+		var historyPath, recordedAt string
+		if err := rows.Scan(&r.HistoryEntryID, &historyPath, &r.Title, &r.Excerpt, &r.Rank, &recordedAt); err != nil {
+			return nil, err
+		}
+		r.Path = historyRoute(historyPath)
+		r.Deleted = true
+		deletedAt := parseSQLiteTimestamp(recordedAt).UTC()
+		r.DeletedAt = &deletedAt
+		results = append(results, r)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return &SearchResult{
+		Count:  total,
+		Items:  rankResults(query, results),
+		Limit:  limit,
+		Offset: offset,
+	}, nil
+}
@@ -0,0 +1,123 @@
+package search
+
+import (
+	"path"
+	"sort"
+	"strings"
+)
+
+// directoryMove is a renamed or moved directory, e.g. docs -> manual
+type directoryMove struct {
+	from string
+	to   string
+}
+
+// splitDirectoryMove returns the directory move of a file move by stripping the
+// common trailing path segments, e.g. docs/guides/a.md -> manual/guides/a.md is docs -> manual.
+func splitDirectoryMove(from, to string) (directoryMove, bool) {
+	fromParts := strings.Split(from, "/")
+	toParts := strings.Split(to, "/")
+	i, j := len(fromParts), len(toParts)
+	for i > 0 && j > 0 && fromParts[i-1] == toParts[j-1] {
+		i--
+		j--
+	}
+	if i == 0 || j == 0 || i == len(fromParts) {
+		return directoryMove{}, false
+	}
+	return directoryMove{
+		from: strings.Join(fromParts[:i], "/"),
+		to:   strings.Join(toParts[:j], "/"),
+	}, true
+}
+
+// matchDirectoryMoves pairs created entries with missing snapshots below a renamed directory.
+// A directory counts as renamed when its files show up below the same new directory and no
+// file is left below the old one. The files are paired by their path relative to the directory,
+// so edited files keep their history even if their content changed completely.
+func matchDirectoryMoves(currentFiles map[string]fileRecord, entries []FileHistorySnapshot, created []int, missing map[string]FileHistorySnapshot) map[int]FileHistorySnapshot {
+	support := map[directoryMove]int{}
+	for _, entry := range entries {
+		if entry.Status == FileStatusMoved && entry.PreviousPath != nil {
+			if dm, ok := splitDirectoryMove(*entry.PreviousPath, entry.Path); ok {
+				support[dm]++
+			}
+		}
+	}
+
+	missingByName := map[string][]string{}
+	for p, snap := range missing {
+		if snap.Status != FileStatusDeleted {
+			missingByName[path.Base(p)] = append(missingByName[path.Base(p)], p)
+		}
+	}
+	for _, idx := range created {
+		for _, p := range missingByName[path.Base(entries[idx].Path)] {
+			if dm, ok := splitDirectoryMove(p, entries[idx].Path); ok {
+				support[dm]++
+			}
+		}
+	}
+
+	// every old directory maps to the new directory with the most files
+	best := map[string]directoryMove{}
+	ambiguous := map[string]bool{}
+	for dm, n := range support {
+		current, ok := best[dm.from]
+		switch {
+		case !ok || n > support[current]:
+			best[dm.from] = dm
+			ambiguous[dm.from] = false
+		case n == support[current]:
+			ambiguous[dm.from] = true
+		}
+	}
+
+	var accepted []directoryMove
+	for from, dm := range best {
+		if ambiguous[from] || hasFilesBelow(currentFiles, from) {
+			continue
+		}
+		accepted = append(accepted, dm)
+	}
+	if len(accepted) == 0 {
+		return nil
+	}
+	// deeper directories first, so nested renames win over their parents
+	sort.Slice(accepted, func(i, j int) bool {
+		if len(accepted[i].to) != len(accepted[j].to) {
+			return len(accepted[i].to) > len(accepted[j].to)
+		}
+		return accepted[i].from < accepted[j].from
+	})
+
+	matches := map[int]FileHistorySnapshot{}
+	used := map[string]bool{}
+	for _, idx := range created {
+		for _, dm := range accepted {
+			rest, ok := strings.CutPrefix(entries[idx].Path, dm.to+"/")
+			if !ok {
+				continue
+			}
+			prevPath := dm.from + "/" + rest
+			snap, ok := missing[prevPath]
+			if !ok || snap.Status == FileStatusDeleted || used[prevPath] {
+				continue
+			}
+			used[prevPath] = true
+			matches[idx] = snap
+			break
+		}
+	}
+	return matches
+}
+
+func hasFilesBelow(currentFiles map[string]fileRecord, dir string) bool {
+	prefix := dir + "/"
+	for p := range currentFiles {
+		if strings.HasPrefix(p, prefix) {
+			return true
+		}
+	}
+	return false
+}
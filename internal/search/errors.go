@@ -0,0 +1,8 @@
+package search
+
+import "errors"
+
+var ErrIndexingInProgress = errors.New("indexing already in progress")
+var ErrHistoryEntryNotFound = errors.New("history entry not found")
+var ErrHistoryLabelNotFound = errors.New("history label not found")
+var ErrHistoryLabelExists = errors.New("history label already exists")
@@ -0,0 +1,200 @@
+package search
+
+import (
+	"context"
+	"database/sql"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// racyWindow is the time span in which a modification time is not trusted.
+// Files modified within this window are always hashed, because a second write
+// with the same size inside the same timestamp tick would otherwise go unnoticed.
+const racyWindow = 2 * time.Second
+
+// fileState is the cached metadata of a Markdown file.
+// It is used to skip reading and hashing files which did not change since the last scan.
+type fileState struct {
+	Size    int64
+	ModTime int64
+	Hash    string
+}
+
+type fileRecord struct {
+	FullPath string
+	Hash     string
+	Content  string
+	loaded   bool
+}
+
+// content returns the file content and reads it from disk when it was skipped during the scan.
+func (r fileRecord) content() (string, error) {
+	if r.loaded {
+		return r.Content, nil
+	}
+	data, err := os.ReadFile(r.FullPath)
+	if err != nil {
+		return "", err
+	}
+	return string(data), nil
+}
+
+func (s *SQLiteIndex) loadFileStates() (map[string]fileState, error) {
+	rows, err := s.db.Query(`SELECT path, size, mtime, hash FROM file_state;`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	states := make(map[string]fileState)
+	for rows.Next() {
+		var p string
+		var st fileState
+		if err := rows.Scan(&p, &st.Size, &st.ModTime, &st.Hash); err != nil {
+			return nil, err
+		}
+		states[p] = st
+	}
+
+	return states, rows.Err()
+}
+
+// saveFileStatesTx updates the cached file states within the given transaction
+func saveFileStatesTx(tx *sql.Tx, changed map[string]fileState, removed []string) error {
+	if len(changed) > 0 {
+		stmt, err := tx.Prepare(`
+			INSERT INTO file_state (path, size, mtime, hash)
+			VALUES (?, ?, ?, ?)
+			ON CONFLICT(path) DO UPDATE SET size = excluded.size, mtime = excluded.mtime, hash = excluded.hash;
+		`)
+		if err != nil {
+			return err
+		}
+		defer stmt.Close()
+
+		for p, st := range changed {
+			if _, err := stmt.Exec(p, st.Size, st.ModTime, st.Hash); err != nil {
+				return err
+			}
+		}
+	}
+
+	for _, p := range removed {
+		if _, err := tx.Exec(`DELETE FROM file_state WHERE path = ?;`, p); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// scanMarkdownFiles walks dataDir and returns a record for every Markdown file,
+// together with the file states which changed or were removed since the last scan.
+// Files whose size and modification time match the cached state are not read again;
+// their hash is taken from the cache and the content is loaded lazily when needed.
+func (s *SQLiteIndex) scanMarkdownFiles(ctx context.Context, dataDir string) (map[string]fileRecord, map[string]fileState, []string, error) {
+	if s.db == nil {
+		return nil, nil, nil, sql.ErrConnDone
+	}
+
+	states, err := s.loadFileStates()
+	if err != nil {
+		return nil, nil, nil, err
+	}
+
+	current := make(map[string]fileRecord)
+	changed := make(map[string]fileState)
+
+	err = walkFiles(dataDir, s.walkOptions(dataDir), func(p string, info fs.FileInfo, err error) error {
+		// a partial scan would record the files it missed as removed
+		if ctxErr := ctx.Err(); ctxErr != nil {
+			return ctxErr
+		}
+		if err != nil {
+			historyLog.Warn("walk error", "path", p, "error", err)
+			return nil
+		}
+
+		if info.IsDir() || filepath.Ext(p) != ".md" {
+			return nil
+		}
+
+		rel, relErr := relativeDataPath(dataDir, p)
+		if relErr != nil {
+			historyLog.Warn("could not resolve relative path", "path", p, "error", relErr)
+			return nil
+		}
+		rel = normalizeHistoryPath(rel)
+
+		modTime := info.ModTime().UnixNano()
+		if st, ok := states[rel]; ok && st.Size == info.Size() && st.ModTime == modTime && time.Since(info.ModTime()) > racyWindow {
+			current[rel] = fileRecord{FullPath: p, Hash: st.Hash}
+			return nil
+		}
+
+		content, readErr := os.ReadFile(p)
+		if readErr != nil {
+			historyLog.Warn("could not read file", "path", p, "error", readErr)
+			return nil
+		}
+
+		hash := hashBytes(content)
+		current[rel] = fileRecord{
+			FullPath: p,
+			Hash:     hash,
+			Content:  string(content),
+			loaded:   true,
+		}
+		changed[rel] = fileState{Size: info.Size(), ModTime: modTime, Hash: hash}
+		return nil
+	})
+
+	if err != nil && !os.IsNotExist(err) {
+		return nil, nil, nil, err
+	}
+
+	var removed []string
+	for p := range states {
+		if _, ok := current[p]; !ok {
+			removed = append(removed, p)
+		}
+	}
+
+	return current, changed, removed, nil
+}
+
+// readFileRecord reads a single Markdown file relative to dataDir.
+// It returns exists=false when the file is gone.
+func readFileRecord(dataDir, relPath string) (fileRecord, fileState, bool, error) {
+	fullPath := filepath.Join(dataDir, filepath.FromSlash(relPath))
+
+	info, err := os.Stat(fullPath)
+	if os.IsNotExist(err) {
+		return fileRecord{}, fileState{}, false, nil
+	}
+	if err != nil {
+		return fileRecord{}, fileState{}, false, err
+	}
+	if info.IsDir() {
+		return fileRecord{}, fileState{}, false, nil
+	}
+
+	content, err := os.ReadFile(fullPath)
+	if os.IsNotExist(err) {
+		return fileRecord{}, fileState{}, false, nil
+	}
+	if err != nil {
+		return fileRecord{}, fileState{}, false, err
+	}
+
+	hash := hashBytes(content)
+	record := fileRecord{
+		FullPath: fullPath,
+		Hash:     hash,
+		Content:  string(content),
+		loaded:   true,
+	}
+	return record, fileState{Size: info.Size(), ModTime: info.ModTime().UnixNano(), Hash: hash}, true, nil
+}
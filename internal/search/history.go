@@ -1,16 +1,16 @@
 package search
 
 import (
+	"context"
 	"crypto/sha256"
 	"database/sql"
 	"encoding/hex"
-	"io/fs"
-	"log"
-	"os"
+	"fmt"
 	"path/filepath"
-	"sort"
 	"strings"
 	"time"
+
+	"github.com/Gomez12/wiki/internal/core/tree"
 )
 
 type FileHistoryStatus string
@@ -42,12 +42,16 @@ type FileHistoryEntry struct {
 
 // CaptureFileHistory snapshots all Markdown files under dataDir.
 // It records new rows when files are created, modified, removed or moved.
-func (s *SQLiteIndex) CaptureFileHistory(dataDir string) error {
+// When ctx ends during the scan, nothing is recorded; the next capture picks the changes up.
+func (s *SQLiteIndex) CaptureFileHistory(ctx context.Context, dataDir string) error {
 	if s.db == nil {
 		return sql.ErrConnDone
 	}
 
-	currentFiles, err := scanMarkdownFiles(dataDir)
+	s.captureMu.Lock()
+	defer s.captureMu.Unlock()
+
+	currentFiles, changed, removed, err := s.scanMarkdownFiles(ctx, dataDir)
 	if err != nil {
 		return err
 	}
@@ -57,46 +61,170 @@ func (s *SQLiteIndex) CaptureFileHistory(dataDir string) error {
 		return err
 	}
 
-	aliasPaths := map[string]bool{}
-	for _, snap := range latest {
-		if snap.PreviousPath != nil {
-			aliasPaths[*snap.PreviousPath] = true
-		}
+	aliasPaths, err := s.movedAwayPaths()
+	if err != nil {
+		return err
 	}
 
 	missing := map[string]FileHistorySnapshot{}
-	moveCandidates := map[string][]FileHistorySnapshot{}
 	for path, snap := range latest {
 		if aliasPaths[path] {
 			continue
 		}
 		if _, ok := currentFiles[path]; !ok {
 			missing[path] = snap
-			if snap.Status != FileStatusDeleted {
-				key := movementKey(snap.Hash, path)
-				moveCandidates[key] = append(moveCandidates[key], snap)
+		}
+	}
+
+	entries := collectFileHistory(currentFiles, latest, missing)
+	return s.commitFileHistory(entries, changed, removed)
+}
+
+// CaptureFileChanges records history entries for the given files only.
+// Paths are relative to dataDir. Files which no longer exist are recorded as deleted,
+// unless a file with the same name and content or with similar content shows up in the same batch,
+// which is recorded as a move.
+// It is used by the watcher so edits show up in the history without a full scan.
+// When ctx ends before the changes are recorded, nothing is recorded.
+func (s *SQLiteIndex) CaptureFileChanges(ctx context.Context, dataDir string, relPaths []string) error {
+	if s.db == nil {
+		return sql.ErrConnDone
+	}
+
+	s.captureMu.Lock()
+	defer s.captureMu.Unlock()
+
+	currentFiles := map[string]fileRecord{}
+	latest := map[string]FileHistorySnapshot{}
+	missing := map[string]FileHistorySnapshot{}
+	changed := map[string]fileState{}
+	var removed []string
+
+	for _, filePath := range relPaths {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		relPath := normalizeHistoryPath(filePath)
+		if relPath == "" {
+			continue
+		}
+
+		snap, ok, err := s.latestFileSnapshot(relPath)
+		if err != nil {
+			return err
+		}
+		if ok {
+			latest[relPath] = snap
+		}
+
+		// the file is read by its name on disk, which may not be normalized
+		record, state, exists, err := readFileRecord(dataDir, filePath)
+		if err != nil {
+			historyLog.Warn("could not read file", "path", relPath, "error", err)
+			continue
+		}
+		if exists {
+			currentFiles[relPath] = record
+			changed[relPath] = state
+			continue
+		}
+
+		removed = append(removed, relPath)
+		if ok {
+			missing[relPath] = snap
+		}
+	}
+
+	entries := collectFileHistory(currentFiles, latest, missing)
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	return s.commitFileHistory(entries, changed, removed)
+}
+
+// RecordMoves records files which were moved on purpose, e.g. by a layout migration, so
+// their history continues at the new path without relying on the move detection. moves maps
+// the old to the new path, both relative to dataDir. Files without history are recorded as created.
+func (s *SQLiteIndex) RecordMoves(dataDir string, moves map[string]string) error {
+	if s.db == nil {
+		return sql.ErrConnDone
+	}
+
+	s.captureMu.Lock()
+	defer s.captureMu.Unlock()
+
+	var entries []FileHistorySnapshot
+	changed := map[string]fileState{}
+	var removed []string
+	for from, to := range moves {
+		from, to = normalizeHistoryPath(from), normalizeHistoryPath(to)
+		record, state, exists, err := readFileRecord(dataDir, to)
+		if err != nil {
+			return err
+		}
+		if !exists {
+			continue
+		}
+
+		entry := FileHistorySnapshot{Path: to, Hash: record.Hash, Content: record.Content, Status: FileStatusCreated}
+		snap, ok, err := s.latestFileSnapshot(from)
+		if err != nil {
+			return err
+		}
+		if ok && snap.Status != FileStatusDeleted {
+			prev := from
+			entry.Status = FileStatusMoved
+			entry.PreviousPath = &prev
+			if snap.Hash != record.Hash {
+				// the move keeps the previous content, the edit is recorded separately
+				entries = append(entries, FileHistorySnapshot{Path: to, Hash: snap.Hash, Content: snap.Content, Status: FileStatusMoved, PreviousPath: &prev})
+				entry.Status = FileStatusModified
+				entry.PreviousPath = nil
 			}
 		}
+		entries = append(entries, entry)
+		changed[to] = state
+		removed = append(removed, from)
 	}
 
+	return s.commitFileHistory(entries, changed, removed)
+}
+
+// collectFileHistory compares the current files with their latest snapshots and
+// returns the created, modified, moved and deleted entries to record.
+// Missing files with the same name and content as a new file are treated as moved.
+// Missing files below a renamed directory and missing files with similar content are
+// treated as moved and modified, so renaming and editing at once doesn't break the history chain.
+func collectFileHistory(currentFiles map[string]fileRecord, latest map[string]FileHistorySnapshot, missing map[string]FileHistorySnapshot) []FileHistorySnapshot {
+	moveCandidates := map[string][]FileHistorySnapshot{}
+	for path, snap := range missing {
+		if snap.Status != FileStatusDeleted {
+			key := movementKey(snap.Hash, path)
+			moveCandidates[key] = append(moveCandidates[key], snap)
+		}
+	}
+
+	var entries []FileHistorySnapshot
+	var created []int
 	for relPath, file := range currentFiles {
 		hash := file.Hash
-		content := file.Content
-		if snap, ok := latest[relPath]; ok {
-			if snap.Status == FileStatusDeleted {
-				if err := s.insertHistoryEntry(relPath, hash, content, FileStatusCreated, nil); err != nil {
-					return err
-				}
-				log.Printf("[history] recorded created for %s", relPath)
-				continue
-			}
+		if snap, ok := latest[relPath]; ok && snap.Status != FileStatusDeleted && snap.Hash == hash {
+			continue
+		}
 
-			if snap.Hash != hash {
-				if err := s.insertHistoryEntry(relPath, hash, content, FileStatusModified, nil); err != nil {
-					return err
-				}
-				log.Printf("[history] recorded modified for %s", relPath)
+		content, err := file.content()
+		if err != nil {
+			historyLog.Warn("could not read file", "path", relPath, "error", err)
+			continue
+		}
+
+		entry := FileHistorySnapshot{Path: relPath, Hash: hash, Content: content, Status: FileStatusCreated}
+
+		if snap, ok := latest[relPath]; ok {
+			if snap.Status != FileStatusDeleted {
+				entry.Status = FileStatusModified
 			}
+			entries = append(entries, entry)
 			continue
 		}
 
@@ -105,36 +233,128 @@ func (s *SQLiteIndex) CaptureFileHistory(dataDir string) error {
 			prev := snaps[0]
 			moveCandidates[key] = snaps[1:]
 			delete(missing, prev.Path)
-			if err := s.insertHistoryEntry(relPath, hash, content, FileStatusMoved, &prev.Path); err != nil {
-				return err
-			}
-			log.Printf("[history] recorded moved from %s to %s", prev.Path, relPath)
+			entry.Status = FileStatusMoved
+			entry.PreviousPath = &prev.Path
+		} else {
+			created = append(created, len(entries))
+		}
+		entries = append(entries, entry)
+	}
+
+	moved := matchDirectoryMoves(currentFiles, entries, created, missing)
+	remaining := created[:0:0]
+	for _, idx := range created {
+		if prev, ok := moved[idx]; ok {
+			delete(missing, prev.Path)
 			continue
 		}
+		remaining = append(remaining, idx)
+	}
+	for _, m := range matchSimilarMoves(entries, remaining, missing) {
+		if moved == nil {
+			moved = map[int]FileHistorySnapshot{}
+		}
+		moved[m.entry] = m.prev
+		delete(missing, m.prev.Path)
+	}
 
-		if err := s.insertHistoryEntry(relPath, hash, content, FileStatusCreated, nil); err != nil {
-			return err
+	if len(moved) > 0 {
+		withMoves := make([]FileHistorySnapshot, 0, len(entries)+len(moved))
+		for i, entry := range entries {
+			if prev, ok := moved[i]; ok {
+				prevPath := prev.Path
+				if prev.Hash == entry.Hash {
+					entry.Status = FileStatusMoved
+					entry.PreviousPath = &prevPath
+					withMoves = append(withMoves, entry)
+					continue
+				}
+				// the move keeps the previous content, the edit is recorded separately
+				withMoves = append(withMoves, FileHistorySnapshot{Path: entry.Path, Hash: prev.Hash, Content: prev.Content, Status: FileStatusMoved, PreviousPath: &prevPath})
+				entry.Status = FileStatusModified
+			}
+			withMoves = append(withMoves, entry)
 		}
-		log.Printf("[history] recorded created for %s", relPath)
+		entries = withMoves
 	}
 
 	for _, snap := range missing {
 		if snap.Status == FileStatusDeleted {
 			continue
 		}
-		if err := s.insertHistoryEntry(snap.Path, snap.Hash, snap.Content, FileStatusDeleted, nil); err != nil {
+		entries = append(entries, FileHistorySnapshot{Path: snap.Path, Hash: snap.Hash, Content: snap.Content, Status: FileStatusDeleted})
+	}
+
+	return entries
+}
+
+// commitFileHistory inserts the history entries and updates the cached file states
+// in a single transaction, so a capture run is either recorded completely or not at all.
+// captureMu must be held by the caller, so the entries were computed from the latest state.
+func (s *SQLiteIndex) commitFileHistory(entries []FileHistorySnapshot, changed map[string]fileState, removed []string) error {
+	if len(entries) == 0 && len(changed) == 0 && len(removed) == 0 {
+		return nil
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	tx, err := s.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer func() {
+		_ = tx.Rollback()
+	}()
+
+	if len(entries) > 0 {
+		stmt, err := tx.Prepare(`
+			INSERT INTO file_history (path, hash, content, status, previous_path)
+			VALUES (?, ?, ?, ?, ?);
+		`)
+		if err != nil {
 			return err
 		}
-		log.Printf("[history] recorded deleted for %s", snap.Path)
+		defer stmt.Close()
+
+		for _, entry := range entries {
+			var prev interface{}
+			if entry.PreviousPath != nil {
+				prev = *entry.PreviousPath
+			}
+			res, err := stmt.Exec(entry.Path, entry.Hash, entry.Content, entry.Status, prev)
+			if err != nil {
+				return err
+			}
+			id, err := res.LastInsertId()
+			if err != nil {
+				return err
+			}
+			if err := indexDeletedPageTx(tx, id, entry); err != nil {
+				return err
+			}
+		}
 	}
 
+	if err := saveFileStatesTx(tx, changed, removed); err != nil {
+		return err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return err
+	}
+
+	for _, entry := range entries {
+		if entry.PreviousPath != nil {
+			historyLog.Info("recorded change", "status", entry.Status, "from", *entry.PreviousPath, "path", entry.Path)
+		} else {
+			historyLog.Info("recorded change", "status", entry.Status, "path", entry.Path)
+		}
+	}
 	return nil
 }
 
 func (s *SQLiteIndex) latestFileSnapshots() (map[string]FileHistorySnapshot, error) {
-	s.mu.Lock()
-	defer s.mu.Unlock()
-
 	rows, err := s.db.Query(`
 		WITH latest AS (
 			SELECT MAX(id) AS id, path FROM file_history GROUP BY path
@@ -165,36 +385,207 @@ func (s *SQLiteIndex) latestFileSnapshots() (map[string]FileHistorySnapshot, err
 	return snapshots, rows.Err()
 }
 
-func (s *SQLiteIndex) insertHistoryEntry(path string, hash string, content string, status FileHistoryStatus, previousPath *string) error {
-	s.mu.Lock()
-	defer s.mu.Unlock()
+// movedAwayPaths returns all paths which were moved to another path after their latest snapshot.
+func (s *SQLiteIndex) movedAwayPaths() (map[string]bool, error) {
+	rows, err := s.db.Query(`
+		WITH latest AS (
+			SELECT MAX(id) AS id, path FROM file_history GROUP BY path
+		)
+		SELECT DISTINCT fh.previous_path
+		FROM file_history fh
+		JOIN latest l ON fh.previous_path = l.path
+		WHERE fh.id > l.id;
+	`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
 
-	var prev interface{}
-	if previousPath != nil {
-		prev = *previousPath
+	paths := map[string]bool{}
+	for rows.Next() {
+		var p string
+		if err := rows.Scan(&p); err != nil {
+			return nil, err
+		}
+		paths[p] = true
 	}
+	return paths, rows.Err()
+}
 
-	_, err := s.db.Exec(`
-		INSERT INTO file_history (path, hash, content, status, previous_path)
-		VALUES (?, ?, ?, ?, ?);
-	`, path, hash, content, status, prev)
+// latestFileSnapshot returns the latest snapshot recorded for path.
+// Paths which were moved away are reported as not found, like in the full scan.
+func (s *SQLiteIndex) latestFileSnapshot(path string) (FileHistorySnapshot, bool, error) {
+	var snap FileHistorySnapshot
+	var id int64
+	var prev sql.NullString
+	err := s.db.QueryRow(`
+		SELECT id, path, hash, content, status, previous_path
+		FROM file_history
+		WHERE path = ?
+		ORDER BY id DESC
+		LIMIT 1;
+	`, path).Scan(&id, &snap.Path, &snap.Hash, &snap.Content, &snap.Status, &prev)
+	if err == sql.ErrNoRows {
+		return snap, false, nil
+	}
+	if err != nil {
+		return snap, false, err
+	}
+	if prev.Valid {
+		snap.PreviousPath = &prev.String
+	}
+
+	var movedAway int
+	if err := s.db.QueryRow(`
+		SELECT COUNT(*) FROM file_history WHERE previous_path = ? AND id > ?;
+	`, path, id).Scan(&movedAway); err != nil {
+		return snap, false, err
+	}
+	if movedAway > 0 {
+		return snap, false, nil
+	}
 
-	return err
+	return snap, true, nil
+}
+
+// HistoryOptions controls which history entries are returned
+type HistoryOptions struct {
+	// Limit is the maximum number of entries; 0 returns all entries
+	Limit  int
+	Offset int
+	// IncludeContent loads the snapshot content; otherwise only metadata is returned
+	// and the content can be fetched with GetHistoryEntry
+	IncludeContent bool
+	// Status returns only entries with the given status, if set
+	Status FileHistoryStatus
 }
 
 // GetHistoryForPath returns history rows for the given path, following previous paths (moves).
 func (s *SQLiteIndex) GetHistoryForPath(path string) ([]FileHistoryEntry, error) {
+	entries, _, err := s.QueryHistoryForPath(path, HistoryOptions{IncludeContent: true})
+	return entries, err
+}
+
+// QueryHistoryForPath returns a window of the history of a path, newest first,
+// following previous paths (moves), together with the total number of matching entries.
+func (s *SQLiteIndex) QueryHistoryForPath(path string, opts HistoryOptions) ([]FileHistoryEntry, int, error) {
+	if s.db == nil {
+		return nil, 0, sql.ErrConnDone
+	}
+
+	paths, err := s.historyPathsLocked(path)
+	if err != nil {
+		return nil, 0, err
+	}
+	entries := []FileHistoryEntry{}
+	if len(paths) == 0 {
+		return entries, 0, nil
+	}
+
+	placeholders := strings.TrimSuffix(strings.Repeat("?,", len(paths)), ",")
+	where := fmt.Sprintf(`(path IN (%[1]s) OR previous_path IN (%[1]s))`, placeholders)
+	args := make([]interface{}, 0, len(paths)*2+3)
+	for _, p := range paths {
+		args = append(args, p)
+	}
+	for _, p := range paths {
+		args = append(args, p)
+	}
+	return s.queryHistoryLocked(where, args, opts)
+}
+
+// QueryHistoryForSubtree returns a window of the history of all files below a directory,
+// newest first, including files which were moved into or out of it.
+// An empty directory returns the history of the whole wiki.
+func (s *SQLiteIndex) QueryHistoryForSubtree(dir string, opts HistoryOptions) ([]FileHistoryEntry, int, error) {
+	if s.db == nil {
+		return nil, 0, sql.ErrConnDone
+	}
+
+	where := `1 = 1`
+	var args []interface{}
+	if dir = strings.Trim(normalizeHistoryPath(dir), "/"); dir != "" {
+		// the page of a directory is stored next to it until it gets children
+		like := escapeLike(dir) + `/%`
+		where = `(path LIKE ? ESCAPE '\' OR previous_path LIKE ? ESCAPE '\' OR path = ? OR previous_path = ?)`
+		args = append(args, like, like, dir+".md", dir+".md")
+	}
+
+	return s.queryHistoryLocked(where, args, opts)
+}
+
+// queryHistoryLocked returns a window of the history entries matching the where clause.
+// Lock must be held by the caller
+func (s *SQLiteIndex) queryHistoryLocked(where string, args []interface{}, opts HistoryOptions) ([]FileHistoryEntry, int, error) {
+	if opts.Status != "" {
+		where += ` AND status = ?`
+		args = append(args, opts.Status)
+	}
+
+	var total int
+	if err := s.db.QueryRow(`SELECT COUNT(*) FROM file_history WHERE `+where+`;`, args...).Scan(&total); err != nil {
+		return nil, 0, err
+	}
+
+	contentColumn := `''`
+	if opts.IncludeContent {
+		contentColumn = `content`
+	}
+	limit := opts.Limit
+	if limit <= 0 {
+		limit = -1
+	}
+	args = append(args, limit, opts.Offset)
+
+	rows, err := s.db.Query(`
+		SELECT id, path, hash, `+contentColumn+`, status, previous_path, recorded_at
+		FROM file_history
+		WHERE `+where+`
+		ORDER BY recorded_at DESC, id DESC
+		LIMIT ? OFFSET ?;
+	`, args...)
+	if err != nil {
+		return nil, 0, err
+	}
+	defer rows.Close()
+
+	entries := []FileHistoryEntry{}
+	for rows.Next() {
+		entry, err := scanHistoryEntry(rows)
+		if err != nil {
+			return nil, 0, err
+		}
+		entries = append(entries, *entry)
+	}
+
+	return entries, total, rows.Err()
+}
+
+// GetHistoryEntry returns a single history entry including its content
+func (s *SQLiteIndex) GetHistoryEntry(id int64) (*FileHistoryEntry, error) {
 	if s.db == nil {
 		return nil, sql.ErrConnDone
 	}
 
-	s.mu.Lock()
-	defer s.mu.Unlock()
+	row := s.db.QueryRow(`
+		SELECT id, path, hash, content, status, previous_path, recorded_at
+		FROM file_history
+		WHERE id = ?;
+	`, id)
 
+	entry, err := scanHistoryEntry(row)
+	if err == sql.ErrNoRows {
+		return nil, ErrHistoryEntryNotFound
+	}
+	return entry, err
+}
+
+// historyPathsLocked collects the path and all previous paths it was moved from.
+// Lock must be held by the caller
+func (s *SQLiteIndex) historyPathsLocked(path string) ([]string, error) {
 	visited := map[string]bool{}
 	queue := seedHistoryPaths(path)
-	seenIDs := map[int64]bool{}
-	var entries []FileHistoryEntry
+	var paths []string
 
 	for len(queue) > 0 {
 		current := queue[0]
@@ -203,98 +594,114 @@ func (s *SQLiteIndex) GetHistoryForPath(path string) ([]FileHistoryEntry, error)
 			continue
 		}
 		visited[current] = true
+		paths = append(paths, current)
 
 		rows, err := s.db.Query(`
-			SELECT id, path, hash, content, status, previous_path, recorded_at
+			SELECT DISTINCT previous_path
 			FROM file_history
-			WHERE path = ? OR previous_path = ?
-			ORDER BY recorded_at DESC, id DESC;
+			WHERE (path = ? OR previous_path = ?) AND previous_path IS NOT NULL;
 		`, current, current)
 		if err != nil {
 			return nil, err
 		}
-
 		for rows.Next() {
-			var entry FileHistoryEntry
-			var prev sql.NullString
-			var recordedAt string
-			if err := rows.Scan(&entry.ID, &entry.Path, &entry.Hash, &entry.Content, &entry.Status, &prev, &recordedAt); err != nil {
+			var prev string
+			if err := rows.Scan(&prev); err != nil {
 				rows.Close()
 				return nil, err
 			}
-			if prev.Valid {
-				entry.PreviousPath = &prev.String
-				if !visited[prev.String] {
-					queue = append(queue, prev.String)
-				}
-			}
-
-			entry.RecordedAt = parseSQLiteTimestamp(recordedAt)
-
-			if seenIDs[entry.ID] {
-				continue
+			if !visited[prev] {
+				queue = append(queue, prev)
 			}
-			seenIDs[entry.ID] = true
-			entries = append(entries, entry)
 		}
-
 		if err := rows.Close(); err != nil {
 			return nil, err
 		}
 	}
 
-	sort.Slice(entries, func(i, j int) bool {
-		if entries[i].RecordedAt.Equal(entries[j].RecordedAt) {
-			return entries[i].ID > entries[j].ID
-		}
-		return entries[i].RecordedAt.After(entries[j].RecordedAt)
-	})
+	return paths, nil
+}
 
-	return entries, nil
+type historyRowScanner interface {
+	Scan(dest ...any) error
 }
 
-type fileRecord struct {
-	Hash    string
-	Content string
+func scanHistoryEntry(row historyRowScanner) (*FileHistoryEntry, error) {
+	var entry FileHistoryEntry
+	var content sql.NullString
+	var prev sql.NullString
+	var recordedAt string
+	if err := row.Scan(&entry.ID, &entry.Path, &entry.Hash, &content, &entry.Status, &prev, &recordedAt); err != nil {
+		return nil, err
+	}
+	entry.Content = content.String
+	if prev.Valid {
+		entry.PreviousPath = &prev.String
+	}
+	entry.RecordedAt = parseSQLiteTimestamp(recordedAt)
+	return &entry, nil
 }
 
-func scanMarkdownFiles(dataDir string) (map[string]fileRecord, error) {
-	current := make(map[string]fileRecord)
+// GetMovedTargets returns the paths files were moved to from the given path,
+// following chains of moves. Targets which were deleted afterwards are skipped.
+func (s *SQLiteIndex) GetMovedTargets(path string) ([]string, error) {
+	if s.db == nil {
+		return nil, sql.ErrConnDone
+	}
 
-	err := filepath.WalkDir(dataDir, func(p string, d fs.DirEntry, err error) error {
-		if err != nil {
-			log.Printf("[history] walk error for %s: %v", p, err)
-			return nil
-		}
+	visited := map[string]bool{}
+	queue := seedHistoryPaths(path)
+	var targets []string
 
-		if d.IsDir() || filepath.Ext(d.Name()) != ".md" {
-			return nil
+	for len(queue) > 0 {
+		current := queue[0]
+		queue = queue[1:]
+		if visited[current] {
+			continue
 		}
+		visited[current] = true
 
-		rel, relErr := filepath.Rel(dataDir, p)
-		if relErr != nil {
-			log.Printf("[history] rel path error for %s: %v", p, relErr)
-			return nil
+		rows, err := s.db.Query(`
+			SELECT DISTINCT path FROM file_history
+			WHERE previous_path = ? AND status = ?;
+		`, current, FileStatusMoved)
+		if err != nil {
+			return nil, err
 		}
 
-		content, readErr := os.ReadFile(p)
-		if readErr != nil {
-			log.Printf("[history] read error for %s: %v", p, readErr)
-			return nil
+		var next []string
+		for rows.Next() {
+			var p string
+			if err := rows.Scan(&p); err != nil {
+				rows.Close()
+				return nil, err
+			}
+			next = append(next, p)
 		}
-
-		current[filepath.ToSlash(rel)] = fileRecord{
-			Hash:    hashBytes(content),
-			Content: string(content),
+		if err := rows.Close(); err != nil {
+			return nil, err
 		}
-		return nil
-	})
 
-	if os.IsNotExist(err) {
-		return current, nil
+		for _, p := range next {
+			if visited[p] {
+				continue
+			}
+
+			var status FileHistoryStatus
+			err := s.db.QueryRow(`
+				SELECT status FROM file_history WHERE path = ? ORDER BY id DESC LIMIT 1;
+			`, p).Scan(&status)
+			if err != nil {
+				return nil, err
+			}
+			if status != FileStatusDeleted {
+				targets = append(targets, p)
+			}
+			queue = append(queue, p)
+		}
 	}
 
-	return current, err
+	return targets, nil
 }
 
 func movementKey(hash string, relPath string) string {
@@ -355,9 +762,16 @@ func parseSQLiteTimestamp(value string) time.Time {
 	return time.Time{}
 }
 
+// normalizeHistoryPath returns the key of a path in the history. Paths are stored in Unicode
+// normalization form C, so files with decomposed names (e.g. created on macOS) match the routes.
 func normalizeHistoryPath(p string) string {
 	normalized := strings.TrimSpace(p)
 	normalized = strings.TrimLeft(normalized, "/")
 	normalized = filepath.ToSlash(normalized)
-	return normalized
+	return tree.NormalizeRoute(normalized)
+}
+
+// escapeLike escapes the wildcards of a LIKE pattern, to be used with ESCAPE '\'
+func escapeLike(s string) string {
+	return strings.NewReplacer(`\`, `\\`, `%`, `\%`, `_`, `\_`).Replace(s)
 }
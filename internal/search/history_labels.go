@@ -0,0 +1,159 @@
+package search
+
+import (
+	"database/sql"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// HistoryLabel pins a history entry under a name, e.g. a release version
+type HistoryLabel struct {
+	ID        int64     `json:"id"`
+	EntryID   int64     `json:"entryId"`
+	Label     string    `json:"label"`
+	Path      string    `json:"path"`
+	CreatedAt time.Time `json:"createdAt"`
+}
+
+// AddHistoryLabel labels a history entry. A label is unique within the history of a path,
+// including the paths it was moved from.
+func (s *SQLiteIndex) AddHistoryLabel(entryID int64, label string) (*HistoryLabel, error) {
+	if s.db == nil {
+		return nil, sql.ErrConnDone
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var entryPath string
+	err := s.db.QueryRow(`SELECT path FROM file_history WHERE id = ?;`, entryID).Scan(&entryPath)
+	if err == sql.ErrNoRows {
+		return nil, ErrHistoryEntryNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	if _, err := s.labeledEntryLocked(entryPath, label); err == nil {
+		return nil, ErrHistoryLabelExists
+	} else if err != ErrHistoryLabelNotFound {
+		return nil, err
+	}
+
+	res, err := s.db.Exec(`INSERT INTO history_labels (entry_id, label) VALUES (?, ?);`, entryID, label)
+	if err != nil {
+		return nil, err
+	}
+	id, err := res.LastInsertId()
+	if err != nil {
+		return nil, err
+	}
+
+	var createdAt string
+	if err := s.db.QueryRow(`SELECT created_at FROM history_labels WHERE id = ?;`, id).Scan(&createdAt); err != nil {
+		return nil, err
+	}
+
+	return &HistoryLabel{
+		ID:        id,
+		EntryID:   entryID,
+		Label:     label,
+		Path:      entryPath,
+		CreatedAt: parseSQLiteTimestamp(createdAt),
+	}, nil
+}
+
+// GetHistoryLabelsForPath returns the labels of the history of a path, newest entry first,
+// following previous paths (moves).
+func (s *SQLiteIndex) GetHistoryLabelsForPath(path string) ([]HistoryLabel, error) {
+	if s.db == nil {
+		return nil, sql.ErrConnDone
+	}
+
+	where, args, err := s.historyChainFilterLocked(path)
+	if err != nil {
+		return nil, err
+	}
+	labels := []HistoryLabel{}
+	if where == "" {
+		return labels, nil
+	}
+
+	rows, err := s.db.Query(`
+		SELECT l.id, l.entry_id, l.label, fh.path, l.created_at
+		FROM history_labels l
+		JOIN file_history fh ON fh.id = l.entry_id
+		WHERE `+where+`
+		ORDER BY l.entry_id DESC, l.id DESC;
+	`, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var label HistoryLabel
+		var createdAt string
+		if err := rows.Scan(&label.ID, &label.EntryID, &label.Label, &label.Path, &createdAt); err != nil {
+			return nil, err
+		}
+		label.CreatedAt = parseSQLiteTimestamp(createdAt)
+		labels = append(labels, label)
+	}
+	return labels, rows.Err()
+}
+
+// GetHistoryEntryByLabel returns the labeled history entry of a path including its content
+func (s *SQLiteIndex) GetHistoryEntryByLabel(path string, label string) (*FileHistoryEntry, error) {
+	if s.db == nil {
+		return nil, sql.ErrConnDone
+	}
+
+	return s.labeledEntryLocked(path, label)
+}
+
+// labeledEntryLocked returns the newest entry with the label in the history of path.
+// Lock must be held by the caller
+func (s *SQLiteIndex) labeledEntryLocked(path string, label string) (*FileHistoryEntry, error) {
+	where, args, err := s.historyChainFilterLocked(path)
+	if err != nil {
+		return nil, err
+	}
+	if where == "" {
+		return nil, ErrHistoryLabelNotFound
+	}
+	args = append(args, label)
+
+	row := s.db.QueryRow(`
+		SELECT fh.id, fh.path, fh.hash, fh.content, fh.status, fh.previous_path, fh.recorded_at
+		FROM history_labels l
+		JOIN file_history fh ON fh.id = l.entry_id
+		WHERE `+where+` AND l.label = ?
+		ORDER BY fh.id DESC
+		LIMIT 1;
+	`, args...)
+
+	entry, err := scanHistoryEntry(row)
+	if err == sql.ErrNoRows {
+		return nil, ErrHistoryLabelNotFound
+	}
+	return entry, err
+}
+
+// historyChainFilterLocked returns a where clause on file_history (aliased fh) matching
+// the history of path and all paths it was moved from. The clause is empty if there is no history.
+// Lock must be held by the caller
+func (s *SQLiteIndex) historyChainFilterLocked(path string) (string, []interface{}, error) {
+	paths, err := s.historyPathsLocked(path)
+	if err != nil || len(paths) == 0 {
+		return "", nil, err
+	}
+
+	placeholders := strings.TrimSuffix(strings.Repeat("?,", len(paths)), ",")
+	args := make([]interface{}, 0, len(paths))
+	for _, p := range paths {
+		args = append(args, p)
+	}
+	return fmt.Sprintf(`fh.path IN (%s)`, placeholders), args, nil
+}
@@ -0,0 +1,30 @@
+package search
+
+import (
+	"database/sql"
+	"time"
+)
+
+// PruneHistory deletes history entries recorded before the given time and returns how many were deleted.
+// The latest entry of every path, moves and labeled entries are kept, because change detection
+// and the history of moved pages depend on them.
+func (s *SQLiteIndex) PruneHistory(before time.Time) (int64, error) {
+	if s.db == nil {
+		return 0, sql.ErrConnDone
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	res, err := s.db.Exec(`
+		DELETE FROM file_history
+		WHERE recorded_at < ?
+			AND status != ?
+			AND id NOT IN (SELECT MAX(id) FROM file_history GROUP BY path)
+			AND id NOT IN (SELECT entry_id FROM history_labels);
+	`, before.UTC().Format("2006-01-02 15:04:05"), FileStatusMoved)
+	if err != nil {
+		return 0, err
+	}
+	return res.RowsAffected()
+}
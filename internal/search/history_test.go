@@ -1,9 +1,15 @@
 package search
 
 import (
+	"context"
+	"errors"
+	"fmt"
 	"os"
 	"path/filepath"
+	"strings"
+	"sync"
 	"testing"
+	"time"
 )
 
 func TestCaptureFileHistoryLifecycle(t *testing.T) {
@@ -135,7 +141,7 @@ func writeFile(t *testing.T, filename, content string) {
 
 func mustCapture(t *testing.T, index *SQLiteIndex, dataDir string) {
 	t.Helper()
-	if err := index.CaptureFileHistory(dataDir); err != nil {
+	if err := index.CaptureFileHistory(t.Context(), dataDir); err != nil {
 		t.Fatalf("capture failed: %v", err)
 	}
 }
@@ -155,3 +161,823 @@ func assertHistory(t *testing.T, row historyRow, expectedPath string, expectedSt
 		t.Fatalf("expected content to be stored for %s", row.path)
 	}
 }
+
+func TestCaptureFileHistorySkipsUnchangedMetadata(t *testing.T) {
+	tmpDir := t.TempDir()
+	dataDir := filepath.Join(tmpDir, "root")
+	if err := os.MkdirAll(dataDir, 0o755); err != nil {
+		t.Fatalf("failed to create data dir: %v", err)
+	}
+
+	index, err := NewSQLiteIndex(tmpDir)
+	if err != nil {
+		t.Fatalf("failed to create SQLiteIndex: %v", err)
+	}
+	defer index.Close()
+
+	note := filepath.Join(dataDir, "note.md")
+	writeFile(t, note, "# aaaa")
+	past := time.Now().Add(-time.Hour)
+	if err := os.Chtimes(note, past, past); err != nil {
+		t.Fatalf("failed to set mtime: %v", err)
+	}
+
+	mustCapture(t, index, dataDir)
+	if entries := readHistoryEntries(t, index); len(entries) != 1 {
+		t.Fatalf("expected 1 history row, got %d", len(entries))
+	}
+
+	// Same size and mtime: the file is not read again, so the change is not seen
+	writeFile(t, note, "# bbbb")
+	if err := os.Chtimes(note, past, past); err != nil {
+		t.Fatalf("failed to set mtime: %v", err)
+	}
+	mustCapture(t, index, dataDir)
+	if entries := readHistoryEntries(t, index); len(entries) != 1 {
+		t.Fatalf("expected unchanged metadata to be skipped, got %d rows", len(entries))
+	}
+
+	// Touching the file changes the mtime and triggers hashing
+	later := past.Add(time.Minute)
+	if err := os.Chtimes(note, later, later); err != nil {
+		t.Fatalf("failed to set mtime: %v", err)
+	}
+	mustCapture(t, index, dataDir)
+	entries := readHistoryEntries(t, index)
+	if len(entries) != 2 {
+		t.Fatalf("expected 2 history rows, got %d", len(entries))
+	}
+	assertHistory(t, entries[1], "note.md", FileStatusModified, "")
+}
+
+func TestCaptureFileChanges(t *testing.T) {
+	tmpDir := t.TempDir()
+	dataDir := filepath.Join(tmpDir, "root")
+	if err := os.MkdirAll(filepath.Join(dataDir, "docs"), 0o755); err != nil {
+		t.Fatalf("failed to create data dir: %v", err)
+	}
+
+	index, err := NewSQLiteIndex(tmpDir)
+	if err != nil {
+		t.Fatalf("failed to create SQLiteIndex: %v", err)
+	}
+	defer index.Close()
+
+	mustCaptureChanges := func(paths ...string) []historyRow {
+		t.Helper()
+		if err := index.CaptureFileChanges(t.Context(), dataDir, paths); err != nil {
+			t.Fatalf("capture changes failed: %v", err)
+		}
+		return readHistoryEntries(t, index)
+	}
+
+	original := filepath.Join(dataDir, "note.md")
+	writeFile(t, original, "# note")
+	writeFile(t, filepath.Join(dataDir, "other.md"), "# other")
+
+	// Only the reported file is recorded
+	entries := mustCaptureChanges("note.md")
+	if len(entries) != 1 {
+		t.Fatalf("expected 1 history row, got %d", len(entries))
+	}
+	assertHistory(t, entries[0], "note.md", FileStatusCreated, "")
+
+	writeFile(t, original, "# note\nupdated")
+	entries = mustCaptureChanges("note.md")
+	assertHistory(t, entries[len(entries)-1], "note.md", FileStatusModified, "")
+
+	// Unchanged content does not record a new row
+	entries = mustCaptureChanges("note.md")
+	if len(entries) != 2 {
+		t.Fatalf("expected 2 history rows, got %d", len(entries))
+	}
+
+	// Remove and create in the same batch is a move
+	moved := filepath.Join(dataDir, "docs", "note.md")
+	if err := os.Rename(original, moved); err != nil {
+		t.Fatalf("failed to move file: %v", err)
+	}
+	entries = mustCaptureChanges("note.md", "docs/note.md")
+	if len(entries) != 3 {
+		t.Fatalf("expected 3 history rows, got %d", len(entries))
+	}
+	assertHistory(t, entries[2], "docs/note.md", FileStatusMoved, "note.md")
+
+	// A late event for the old path does not record a deletion
+	entries = mustCaptureChanges("note.md")
+	if len(entries) != 3 {
+		t.Fatalf("expected moved path to be ignored, got %d rows", len(entries))
+	}
+
+	if err := os.Remove(moved); err != nil {
+		t.Fatalf("failed to delete file: %v", err)
+	}
+	entries = mustCaptureChanges("docs/note.md")
+	assertHistory(t, entries[len(entries)-1], "docs/note.md", FileStatusDeleted, "")
+
+	// The full scan agrees with the recorded state and only adds the unreported file
+	mustCapture(t, index, dataDir)
+	entries = readHistoryEntries(t, index)
+	if len(entries) != 5 {
+		t.Fatalf("expected 5 history rows, got %d", len(entries))
+	}
+	assertHistory(t, entries[4], "other.md", FileStatusCreated, "")
+}
+
+func TestCaptureFileChanges_Concurrent(t *testing.T) {
+	tmpDir := t.TempDir()
+	dataDir := filepath.Join(tmpDir, "root")
+	if err := os.MkdirAll(dataDir, 0o755); err != nil {
+		t.Fatalf("failed to create data dir: %v", err)
+	}
+
+	index, err := NewSQLiteIndex(tmpDir)
+	if err != nil {
+		t.Fatalf("failed to create SQLiteIndex: %v", err)
+	}
+	defer index.Close()
+
+	paths := make([]string, 50)
+	for i := range paths {
+		paths[i] = fmt.Sprintf("note-%d.md", i)
+		writeFile(t, filepath.Join(dataDir, paths[i]), fmt.Sprintf("# note %d", i))
+	}
+
+	// the watcher and a page save may capture the same changes at once
+	var wg sync.WaitGroup
+	errs := make(chan error, 16)
+	for i := 0; i < 8; i++ {
+		wg.Add(2)
+		go func() {
+			defer wg.Done()
+			errs <- index.CaptureFileChanges(t.Context(), dataDir, paths)
+		}()
+		go func() {
+			defer wg.Done()
+			errs <- index.CaptureFileHistory(t.Context(), dataDir)
+		}()
+	}
+	wg.Wait()
+	close(errs)
+	for err := range errs {
+		if err != nil {
+			t.Fatalf("capture failed: %v", err)
+		}
+	}
+
+	if entries := readHistoryEntries(t, index); len(entries) != len(paths) {
+		t.Fatalf("expected every file to be recorded once, got %d rows", len(entries))
+	}
+}
+
+func TestCaptureFileChanges_Cancelled(t *testing.T) {
+	tmpDir := t.TempDir()
+	dataDir := filepath.Join(tmpDir, "root")
+	if err := os.MkdirAll(dataDir, 0o755); err != nil {
+		t.Fatalf("failed to create data dir: %v", err)
+	}
+
+	index, err := NewSQLiteIndex(tmpDir)
+	if err != nil {
+		t.Fatalf("failed to create SQLiteIndex: %v", err)
+	}
+	defer index.Close()
+
+	writeFile(t, filepath.Join(dataDir, "note.md"), "# note")
+	ctx, cancel := context.WithCancel(t.Context())
+	cancel()
+	if err := index.CaptureFileChanges(ctx, dataDir, []string{"note.md"}); !errors.Is(err, context.Canceled) {
+		t.Fatalf("expected context.Canceled, got %v", err)
+	}
+	if entries := readHistoryEntries(t, index); len(entries) != 0 {
+		t.Errorf("expected nothing to be recorded, got %d rows", len(entries))
+	}
+}
+
+func TestCaptureFileHistoryBatchImport(t *testing.T) {
+	tmpDir := t.TempDir()
+	dataDir := filepath.Join(tmpDir, "root")
+	if err := os.MkdirAll(dataDir, 0o755); err != nil {
+		t.Fatalf("failed to create data dir: %v", err)
+	}
+
+	index, err := NewSQLiteIndex(tmpDir)
+	if err != nil {
+		t.Fatalf("failed to create SQLiteIndex: %v", err)
+	}
+	defer index.Close()
+
+	const files = 500
+	for i := 0; i < files; i++ {
+		writeFile(t, filepath.Join(dataDir, fmt.Sprintf("page-%03d.md", i)), fmt.Sprintf("# page %d", i))
+	}
+
+	mustCapture(t, index, dataDir)
+	entries := readHistoryEntries(t, index)
+	if len(entries) != files {
+		t.Fatalf("expected %d history rows, got %d", files, len(entries))
+	}
+
+	var states int
+	if err := index.GetDB().QueryRow(`SELECT COUNT(*) FROM file_state;`).Scan(&states); err != nil {
+		t.Fatalf("failed to count file states: %v", err)
+	}
+	if states != files {
+		t.Fatalf("expected %d file states, got %d", files, states)
+	}
+}
+
+func TestCaptureFileHistoryRenameWithEdit(t *testing.T) {
+	tmpDir := t.TempDir()
+	dataDir := filepath.Join(tmpDir, "root")
+	if err := os.MkdirAll(filepath.Join(dataDir, "docs"), 0o755); err != nil {
+		t.Fatalf("failed to create data dir: %v", err)
+	}
+
+	index, err := NewSQLiteIndex(tmpDir)
+	if err != nil {
+		t.Fatalf("failed to create SQLiteIndex: %v", err)
+	}
+	defer index.Close()
+
+	original := filepath.Join(dataDir, "draft.md")
+	body := "# Release plan\nWe ship the new editor in spring and migrate all existing pages before the summer break."
+	writeFile(t, original, body)
+	writeFile(t, filepath.Join(dataDir, "unrelated.md"), "# Unrelated\nCompletely different words in this file.")
+	mustCapture(t, index, dataDir)
+
+	// Rename and edit at once
+	if err := os.Remove(original); err != nil {
+		t.Fatalf("failed to remove file: %v", err)
+	}
+	writeFile(t, filepath.Join(dataDir, "docs", "release.md"), body+"\nUpdated: the beta starts in march.")
+	mustCapture(t, index, dataDir)
+
+	entries := readHistoryEntries(t, index)
+	if len(entries) != 4 {
+		t.Fatalf("expected 4 history rows, got %d", len(entries))
+	}
+	assertHistory(t, entries[2], "docs/release.md", FileStatusMoved, "draft.md")
+	if entries[2].content != body {
+		t.Errorf("expected move to keep the previous content, got %q", entries[2].content)
+	}
+	assertHistory(t, entries[3], "docs/release.md", FileStatusModified, "")
+
+	history, err := index.GetHistoryForPath("docs/release.md")
+	if err != nil {
+		t.Fatalf("GetHistoryForPath failed: %v", err)
+	}
+	if len(history) != 3 {
+		t.Fatalf("expected the history chain to include the old path, got %d entries", len(history))
+	}
+
+	// Replacing a file with unrelated content is still a delete and a create
+	if err := os.Remove(filepath.Join(dataDir, "unrelated.md")); err != nil {
+		t.Fatalf("failed to remove file: %v", err)
+	}
+	writeFile(t, filepath.Join(dataDir, "fresh.md"), "# Fresh\nNothing in common with the removed page.")
+	mustCapture(t, index, dataDir)
+
+	entries = readHistoryEntries(t, index)
+	if len(entries) != 6 {
+		t.Fatalf("expected 6 history rows, got %d", len(entries))
+	}
+	statuses := map[FileHistoryStatus]bool{entries[4].status: true, entries[5].status: true}
+	if !statuses[FileStatusCreated] || !statuses[FileStatusDeleted] {
+		t.Errorf("expected a create and a delete, got %s and %s", entries[4].status, entries[5].status)
+	}
+}
+
+func TestSimilarity(t *testing.T) {
+	a := shingles("one two three four five six seven eight")
+	if score := similarity(a, a); score != 1 {
+		t.Errorf("expected identical content to score 1, got %f", score)
+	}
+	b := shingles("one two three four five six seven nine")
+	if score := similarity(a, b); score < 0.5 || score >= 1 {
+		t.Errorf("expected similar content to score between 0.5 and 1, got %f", score)
+	}
+	if score := similarity(a, shingles("")); score != 0 {
+		t.Errorf("expected empty content to score 0, got %f", score)
+	}
+}
+
+func TestQueryHistoryForPath(t *testing.T) {
+	tmpDir := t.TempDir()
+	dataDir := filepath.Join(tmpDir, "root")
+	if err := os.MkdirAll(dataDir, 0o755); err != nil {
+		t.Fatalf("failed to create data dir: %v", err)
+	}
+
+	index, err := NewSQLiteIndex(tmpDir)
+	if err != nil {
+		t.Fatalf("failed to create SQLiteIndex: %v", err)
+	}
+	defer index.Close()
+
+	note := filepath.Join(dataDir, "note.md")
+	for i := 0; i < 5; i++ {
+		writeFile(t, note, fmt.Sprintf("# note %d", i))
+		mustCapture(t, index, dataDir)
+	}
+
+	entries, total, err := index.QueryHistoryForPath("note", HistoryOptions{Limit: 2, Offset: 1})
+	if err != nil {
+		t.Fatalf("query failed: %v", err)
+	}
+	if total != 5 || len(entries) != 2 {
+		t.Fatalf("expected 2 of 5 entries, got %d of %d", len(entries), total)
+	}
+	if entries[0].Content != "" {
+		t.Errorf("expected metadata only, got content %q", entries[0].Content)
+	}
+	if entries[0].ID <= entries[1].ID {
+		t.Errorf("expected newest entries first")
+	}
+
+	entries, total, err = index.QueryHistoryForPath("note", HistoryOptions{Status: FileStatusCreated, IncludeContent: true})
+	if err != nil {
+		t.Fatalf("query failed: %v", err)
+	}
+	if total != 1 || len(entries) != 1 || entries[0].Content != "# note 0" {
+		t.Fatalf("expected the created entry with content, got %+v", entries)
+	}
+
+	entry, err := index.GetHistoryEntry(entries[0].ID)
+	if err != nil {
+		t.Fatalf("GetHistoryEntry failed: %v", err)
+	}
+	if entry.Content != "# note 0" || entry.Status != FileStatusCreated {
+		t.Errorf("unexpected entry: %+v", entry)
+	}
+
+	if _, err := index.GetHistoryEntry(9999); err != ErrHistoryEntryNotFound {
+		t.Errorf("expected ErrHistoryEntryNotFound, got %v", err)
+	}
+}
+
+func TestHistoryNormalizesPaths(t *testing.T) {
+	tmpDir := t.TempDir()
+	dataDir := filepath.Join(tmpDir, "root")
+	if err := os.MkdirAll(dataDir, 0o755); err != nil {
+		t.Fatalf("failed to create data dir: %v", err)
+	}
+
+	index, err := NewSQLiteIndex(tmpDir)
+	if err != nil {
+		t.Fatalf("failed to create SQLiteIndex: %v", err)
+	}
+	defer index.Close()
+
+	// decomposed "café.md", as written on macOS
+	decomposed := "cafe\u0301.md"
+	writeFile(t, filepath.Join(dataDir, decomposed), "# café")
+	mustCapture(t, index, dataDir)
+
+	writeFile(t, filepath.Join(dataDir, decomposed), "# café\nupdated")
+	if err := index.CaptureFileChanges(t.Context(), dataDir, []string{decomposed}); err != nil {
+		t.Fatalf("capture changes failed: %v", err)
+	}
+
+	entries, total, err := index.QueryHistoryForPath("caf\u00e9", HistoryOptions{})
+	if err != nil {
+		t.Fatalf("query failed: %v", err)
+	}
+	if total != 2 || entries[0].Status != FileStatusModified || entries[1].Status != FileStatusCreated {
+		t.Fatalf("expected the created and modified entries for the composed route, got %+v", entries)
+	}
+}
+
+func TestCaptureFileHistoryDirectoryRename(t *testing.T) {
+	tmpDir := t.TempDir()
+	dataDir := filepath.Join(tmpDir, "root")
+	if err := os.MkdirAll(filepath.Join(dataDir, "docs", "guides"), 0o755); err != nil {
+		t.Fatalf("failed to create data dir: %v", err)
+	}
+
+	index, err := NewSQLiteIndex(tmpDir)
+	if err != nil {
+		t.Fatalf("failed to create SQLiteIndex: %v", err)
+	}
+	defer index.Close()
+
+	writeFile(t, filepath.Join(dataDir, "docs", "index.md"), "# docs")
+	writeFile(t, filepath.Join(dataDir, "docs", "a.md"), "# a")
+	writeFile(t, filepath.Join(dataDir, "docs", "guides", "b.md"), "# b")
+	writeFile(t, filepath.Join(dataDir, "other.md"), "# other")
+	mustCapture(t, index, dataDir)
+
+	// Rename the directory and completely rewrite one of the files
+	if err := os.Rename(filepath.Join(dataDir, "docs"), filepath.Join(dataDir, "manual")); err != nil {
+		t.Fatalf("failed to rename dir: %v", err)
+	}
+	writeFile(t, filepath.Join(dataDir, "manual", "a.md"), "# rewritten")
+	mustCapture(t, index, dataDir)
+
+	entries := readHistoryEntries(t, index)
+	byPath := map[string][]historyRow{}
+	for _, e := range entries[4:] {
+		byPath[e.path] = append(byPath[e.path], e)
+		if e.status == FileStatusDeleted || e.status == FileStatusCreated {
+			t.Errorf("expected no deletes or creates, got %s for %s", e.status, e.path)
+		}
+	}
+	assertHistory(t, byPath["manual/index.md"][0], "manual/index.md", FileStatusMoved, "docs/index.md")
+	assertHistory(t, byPath["manual/guides/b.md"][0], "manual/guides/b.md", FileStatusMoved, "docs/guides/b.md")
+	if rows := byPath["manual/a.md"]; len(rows) != 2 {
+		t.Fatalf("expected move and modify for the rewritten file, got %+v", rows)
+	}
+	assertHistory(t, byPath["manual/a.md"][0], "manual/a.md", FileStatusMoved, "docs/a.md")
+	assertHistory(t, byPath["manual/a.md"][1], "manual/a.md", FileStatusModified, "")
+
+	history, err := index.GetHistoryForPath("manual/a.md")
+	if err != nil {
+		t.Fatalf("GetHistoryForPath failed: %v", err)
+	}
+	if len(history) != 3 {
+		t.Errorf("expected the history chain to include the old path, got %d entries", len(history))
+	}
+
+	subtree, total, err := index.QueryHistoryForSubtree("manual/", HistoryOptions{})
+	if err != nil {
+		t.Fatalf("QueryHistoryForSubtree failed: %v", err)
+	}
+	if total != 4 || len(subtree) != 4 {
+		t.Errorf("expected 4 entries below manual/, got %d", total)
+	}
+
+	_, total, err = index.QueryHistoryForSubtree("docs", HistoryOptions{Status: FileStatusMoved})
+	if err != nil {
+		t.Fatalf("QueryHistoryForSubtree failed: %v", err)
+	}
+	if total != 3 {
+		t.Errorf("expected 3 files moved out of docs, got %d", total)
+	}
+
+	_, total, err = index.QueryHistoryForSubtree("", HistoryOptions{})
+	if err != nil {
+		t.Fatalf("QueryHistoryForSubtree failed: %v", err)
+	}
+	if total != len(entries) {
+		t.Errorf("expected the whole history, got %d of %d", total, len(entries))
+	}
+}
+
+func TestSplitDirectoryMove(t *testing.T) {
+	tests := []struct {
+		from, to string
+		want     directoryMove
+		ok       bool
+	}{
+		{"docs/a.md", "manual/a.md", directoryMove{"docs", "manual"}, true},
+		{"docs/guides/a.md", "manual/guides/a.md", directoryMove{"docs", "manual"}, true},
+		{"a.md", "docs/a.md", directoryMove{}, false},
+		{"docs/a.md", "docs/b.md", directoryMove{}, false},
+	}
+	for _, tt := range tests {
+		got, ok := splitDirectoryMove(tt.from, tt.to)
+		if ok != tt.ok || got != tt.want {
+			t.Errorf("splitDirectoryMove(%q, %q) = %+v, %v; want %+v, %v", tt.from, tt.to, got, ok, tt.want, tt.ok)
+		}
+	}
+}
+
+func TestHistoryLabels(t *testing.T) {
+	tmpDir := t.TempDir()
+	dataDir := filepath.Join(tmpDir, "root")
+	if err := os.MkdirAll(filepath.Join(dataDir, "docs"), 0o755); err != nil {
+		t.Fatalf("failed to create data dir: %v", err)
+	}
+
+	index, err := NewSQLiteIndex(tmpDir)
+	if err != nil {
+		t.Fatalf("failed to create SQLiteIndex: %v", err)
+	}
+	defer index.Close()
+
+	note := filepath.Join(dataDir, "note.md")
+	writeFile(t, note, "# release 1")
+	mustCapture(t, index, dataDir)
+	writeFile(t, note, "# release 2")
+	mustCapture(t, index, dataDir)
+
+	entries, _, err := index.QueryHistoryForPath("note", HistoryOptions{})
+	if err != nil {
+		t.Fatalf("query failed: %v", err)
+	}
+	first := entries[len(entries)-1]
+
+	label, err := index.AddHistoryLabel(first.ID, "v1.0")
+	if err != nil {
+		t.Fatalf("AddHistoryLabel failed: %v", err)
+	}
+	if label.Path != "note.md" || label.EntryID != first.ID {
+		t.Errorf("unexpected label: %+v", label)
+	}
+	if _, err := index.AddHistoryLabel(entries[0].ID, "v1.0"); err != ErrHistoryLabelExists {
+		t.Errorf("expected ErrHistoryLabelExists, got %v", err)
+	}
+	if _, err := index.AddHistoryLabel(9999, "v2.0"); err != ErrHistoryEntryNotFound {
+		t.Errorf("expected ErrHistoryEntryNotFound, got %v", err)
+	}
+
+	// Labels follow the page when it is moved
+	if err := os.Rename(note, filepath.Join(dataDir, "docs", "note.md")); err != nil {
+		t.Fatalf("failed to move file: %v", err)
+	}
+	mustCapture(t, index, dataDir)
+
+	entry, err := index.GetHistoryEntryByLabel("docs/note", "v1.0")
+	if err != nil {
+		t.Fatalf("GetHistoryEntryByLabel failed: %v", err)
+	}
+	if entry.Content != "# release 1" {
+		t.Errorf("expected labeled content, got %q", entry.Content)
+	}
+
+	labels, err := index.GetHistoryLabelsForPath("docs/note")
+	if err != nil {
+		t.Fatalf("GetHistoryLabelsForPath failed: %v", err)
+	}
+	if len(labels) != 1 || labels[0].Label != "v1.0" {
+		t.Errorf("unexpected labels: %+v", labels)
+	}
+
+	if _, err := index.GetHistoryEntryByLabel("docs/note", "v9"); err != ErrHistoryLabelNotFound {
+		t.Errorf("expected ErrHistoryLabelNotFound, got %v", err)
+	}
+}
+
+func TestPruneHistory(t *testing.T) {
+	tmpDir := t.TempDir()
+	dataDir := filepath.Join(tmpDir, "root")
+	if err := os.MkdirAll(filepath.Join(dataDir, "docs"), 0o755); err != nil {
+		t.Fatalf("failed to create data dir: %v", err)
+	}
+
+	index, err := NewSQLiteIndex(tmpDir)
+	if err != nil {
+		t.Fatalf("failed to create SQLiteIndex: %v", err)
+	}
+	defer index.Close()
+
+	note := filepath.Join(dataDir, "note.md")
+	for _, content := range []string{"# v1", "# v2", "# v3", "# v4"} {
+		writeFile(t, note, content)
+		mustCapture(t, index, dataDir)
+	}
+	if err := os.Rename(note, filepath.Join(dataDir, "docs", "note.md")); err != nil {
+		t.Fatalf("failed to move file: %v", err)
+	}
+	mustCapture(t, index, dataDir)
+
+	entries, _, err := index.QueryHistoryForPath("docs/note", HistoryOptions{})
+	if err != nil {
+		t.Fatalf("query failed: %v", err)
+	}
+	if len(entries) != 5 {
+		t.Fatalf("expected 5 entries, got %d", len(entries))
+	}
+	// label the second version, so it survives pruning
+	if _, err := index.AddHistoryLabel(entries[3].ID, "keep"); err != nil {
+		t.Fatalf("AddHistoryLabel failed: %v", err)
+	}
+
+	if _, err := index.GetDB().Exec(`UPDATE file_history SET recorded_at = '2000-01-01 00:00:00';`); err != nil {
+		t.Fatalf("failed to age history: %v", err)
+	}
+
+	deleted, err := index.PruneHistory(time.Now().AddDate(0, 0, -30))
+	if err != nil {
+		t.Fatalf("PruneHistory failed: %v", err)
+	}
+	// v1 and v3 are pruned; v2 is labeled, v4 is the latest of note.md and the move is kept
+	if deleted != 2 {
+		t.Errorf("expected 2 pruned entries, got %d", deleted)
+	}
+
+	rows := readHistoryEntries(t, index)
+	if len(rows) != 3 {
+		t.Fatalf("expected 3 remaining entries, got %d", len(rows))
+	}
+	assertHistory(t, rows[0], "note.md", FileStatusModified, "")
+	if rows[0].content != "# v2" {
+		t.Errorf("expected labeled content to be kept, got %q", rows[0].content)
+	}
+	assertHistory(t, rows[2], "docs/note.md", FileStatusMoved, "note.md")
+
+	// nothing changed, so the next capture doesn't record anything
+	mustCapture(t, index, dataDir)
+	if got := len(readHistoryEntries(t, index)); got != 3 {
+		t.Errorf("expected no new entries after pruning, got %d", got-3)
+	}
+}
+
+func TestFindDuplicates(t *testing.T) {
+	guide := "Install the agent with the package manager, then enable the service and check the logs for errors."
+	docs := []DuplicateDocument{
+		{ID: "a", Content: guide},
+		{ID: "b", Content: "Some unrelated text about the lunch menu of the canteen on Fridays and holidays."},
+		{ID: "c", Content: guide},
+		{ID: "d", Content: guide + " Restart it after updates."},
+		{ID: "e", Content: "Some unrelated text about the lunch menu of the canteen on Fridays and holidays."},
+		{ID: "f", Content: "A page about something else entirely, with its own words and sentences."},
+	}
+
+	clusters := FindDuplicates(docs, 0.7)
+	if len(clusters) != 2 {
+		t.Fatalf("expected 2 clusters, got %+v", clusters)
+	}
+	if got := clusters[0]; len(got.IDs) != 3 || got.IDs[0] != "a" || got.IDs[1] != "c" || got.IDs[2] != "d" || got.Identical || got.Similarity >= 1 || got.Similarity < 0.7 {
+		t.Errorf("unexpected near-identical cluster: %+v", got)
+	}
+	if got := clusters[1]; len(got.IDs) != 2 || !got.Identical || got.Similarity != 1 {
+		t.Errorf("unexpected identical cluster: %+v", got)
+	}
+
+	if clusters := FindDuplicates(docs, 0.95); len(clusters) != 2 || len(clusters[0].IDs) != 2 {
+		t.Errorf("expected only identical clusters with a strict threshold, got %+v", clusters)
+	}
+}
+
+func TestSearchDeleted(t *testing.T) {
+	tmpDir := t.TempDir()
+	dataDir := filepath.Join(tmpDir, "root")
+	if err := os.MkdirAll(filepath.Join(dataDir, "docs"), 0o755); err != nil {
+		t.Fatalf("failed to create data dir: %v", err)
+	}
+
+	index, err := NewSQLiteIndex(tmpDir)
+	if err != nil {
+		t.Fatalf("failed to create SQLiteIndex: %v", err)
+	}
+	defer index.Close()
+
+	writeFile(t, filepath.Join(dataDir, "docs", "index.md"), "# Docs\n\nThe kraken runbook.")
+	writeFile(t, filepath.Join(dataDir, "docs", "kraken.md"), "# Kraken\n\nRestart the kraken service.")
+	writeFile(t, filepath.Join(dataDir, "other.md"), "# Other\n\nNothing here.")
+	mustCapture(t, index, dataDir)
+
+	if res, err := index.SearchDeleted(t.Context(), "kraken", 0, 10); err != nil || res.Count != 0 {
+		t.Fatalf("expected no deleted pages yet, got %+v, %v", res, err)
+	}
+
+	if err := os.Remove(filepath.Join(dataDir, "docs", "kraken.md")); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Remove(filepath.Join(dataDir, "docs", "index.md")); err != nil {
+		t.Fatal(err)
+	}
+	mustCapture(t, index, dataDir)
+
+	res, err := index.SearchDeleted(t.Context(), "kraken", 0, 10)
+	if err != nil {
+		t.Fatalf("SearchDeleted failed: %v", err)
+	}
+	if res.Count != 2 || len(res.Items) != 2 {
+		t.Fatalf("expected both deleted pages, got %+v", res)
+	}
+	paths := map[string]SearchResultItem{}
+	for _, item := range res.Items {
+		paths[item.Path] = item
+	}
+	kraken, ok := paths["docs/kraken"]
+	if !ok || !kraken.Deleted || kraken.HistoryEntryID == 0 || kraken.DeletedAt == nil || !strings.Contains(kraken.Title, "Kraken") {
+		t.Fatalf("unexpected deleted page: %+v", res.Items)
+	}
+	if _, ok := paths["docs"]; !ok {
+		t.Errorf("expected the folder page under its route, got %+v", res.Items)
+	}
+	entry, err := index.GetHistoryEntry(kraken.HistoryEntryID)
+	if err != nil || !strings.Contains(entry.Content, "Restart the kraken service.") {
+		t.Errorf("expected the snapshot of the deleted page, got %+v, %v", entry, err)
+	}
+
+	// a page created again at the path is no longer deleted
+	writeFile(t, filepath.Join(dataDir, "docs", "kraken.md"), "# Kraken\n\nBack again.")
+	mustCapture(t, index, dataDir)
+	if res, _ := index.SearchDeleted(t.Context(), "kraken", 0, 10); res.Count != 1 || res.Items[0].Path != "docs" {
+		t.Errorf("expected only the folder page, got %+v", res)
+	}
+}
+
+func TestSnapshotsAsOf(t *testing.T) {
+	tmpDir := t.TempDir()
+	dataDir := filepath.Join(tmpDir, "root")
+	if err := os.MkdirAll(filepath.Join(dataDir, "docs"), 0o755); err != nil {
+		t.Fatalf("failed to create data dir: %v", err)
+	}
+
+	index, err := NewSQLiteIndex(tmpDir)
+	if err != nil {
+		t.Fatalf("failed to create SQLiteIndex: %v", err)
+	}
+	defer index.Close()
+
+	age := func(to string) {
+		t.Helper()
+		if _, err := index.GetDB().Exec(`UPDATE file_history SET recorded_at = ? WHERE recorded_at > '2021-01-01 00:00:00';`, to); err != nil {
+			t.Fatalf("failed to age history: %v", err)
+		}
+	}
+
+	writeFile(t, filepath.Join(dataDir, "docs", "index.md"), "# Docs")
+	writeFile(t, filepath.Join(dataDir, "docs", "setup.md"), "# Setup\n\nInstall v1.")
+	writeFile(t, filepath.Join(dataDir, "old.md"), "# Old")
+	writeFile(t, filepath.Join(dataDir, "note.md"), "# Note")
+	mustCapture(t, index, dataDir)
+	age("2020-01-01 00:00:00")
+
+	writeFile(t, filepath.Join(dataDir, "docs", "setup.md"), "# Setup\n\nInstall v2.")
+	if err := os.Remove(filepath.Join(dataDir, "old.md")); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Rename(filepath.Join(dataDir, "note.md"), filepath.Join(dataDir, "docs", "note.md")); err != nil {
+		t.Fatal(err)
+	}
+	mustCapture(t, index, dataDir)
+	age("2020-06-01 00:00:00")
+
+	if snaps, err := index.SnapshotsAsOf(time.Date(2019, 1, 1, 0, 0, 0, 0, time.UTC)); err != nil || len(snaps) != 0 {
+		t.Fatalf("expected no snapshots before the history, got %+v, %v", snaps, err)
+	}
+
+	snaps, err := index.SnapshotsAsOf(time.Date(2020, 3, 1, 0, 0, 0, 0, time.UTC))
+	if err != nil {
+		t.Fatalf("SnapshotsAsOf failed: %v", err)
+	}
+	var routes []string
+	for _, snap := range snaps {
+		routes = append(routes, snap.Route)
+	}
+	if fmt.Sprint(routes) != "[docs docs/setup note old]" {
+		t.Fatalf("unexpected routes before the changes: %v", routes)
+	}
+	if snaps[1].Content != "# Setup\n\nInstall v1." || snaps[1].Title != "Setup" {
+		t.Errorf("expected the first version of setup, got %+v", snaps[1])
+	}
+
+	snaps, err = index.SnapshotsAsOf(time.Date(2020, 7, 1, 0, 0, 0, 0, time.UTC))
+	if err != nil {
+		t.Fatalf("SnapshotsAsOf failed: %v", err)
+	}
+	routes = nil
+	for _, snap := range snaps {
+		routes = append(routes, snap.Route)
+	}
+	// old.md is deleted and note.md moved away
+	if fmt.Sprint(routes) != "[docs docs/note docs/setup]" {
+		t.Fatalf("unexpected routes after the changes: %v", routes)
+	}
+	if snaps[2].Content != "# Setup\n\nInstall v2." {
+		t.Errorf("expected the second version of setup, got %q", snaps[2].Content)
+	}
+}
+
+func TestHistoryRecorderCapturesContinuousWrites(t *testing.T) {
+	tmpDir := t.TempDir()
+	dataDir := filepath.Join(tmpDir, "root")
+	if err := os.MkdirAll(dataDir, 0o755); err != nil {
+		t.Fatalf("failed to create data dir: %v", err)
+	}
+	index, err := NewSQLiteIndex(tmpDir)
+	if err != nil {
+		t.Fatalf("failed to create SQLiteIndex: %v", err)
+	}
+	defer index.Close()
+
+	log := filepath.Join(dataDir, "log.md")
+	writeFile(t, log, "# log")
+
+	w := &Watcher{
+		DataDir:     dataDir,
+		Index:       index,
+		historyTick: time.NewTicker(historyReconcileInterval),
+		stopCh:      make(chan struct{}),
+		historyReq:  make(chan struct{}, 1),
+		historyFile: make(chan string, 256),
+		historyDone: make(chan struct{}),
+	}
+	go w.runHistoryRecorder()
+	defer func() { _ = w.Stop() }()
+
+	modified := func() bool {
+		for _, entry := range readHistoryEntries(t, index) {
+			if entry.path == "log.md" && entry.status == FileStatusModified {
+				return true
+			}
+		}
+		return false
+	}
+
+	// the writes never pause for the debounce, the max wait captures them anyway
+	deadline := time.Now().Add(historyMaxWait + 2*time.Second)
+	for i := 0; !modified(); i++ {
+		if time.Now().After(deadline) {
+			t.Fatal("expected the continuously written file to be captured")
+		}
+		writeFile(t, log, fmt.Sprintf("# log\nline %d", i))
+		w.historyFile <- "log.md"
+		time.Sleep(historyDebounce / 5)
+	}
+}
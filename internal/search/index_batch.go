@@ -0,0 +1,247 @@
+package search
+
+import (
+	"database/sql"
+	"fmt"
+	"sync"
+)
+
+// defaultIndexBatchSize is the number of pages written in one transaction during bulk indexing
+const defaultIndexBatchSize = 200
+
+// IndexBatch collects pages and writes them in transactions of a fixed number of pages with
+// prepared statements. Indexing a whole data directory this way is much faster than one
+// IndexPage call, and so one transaction, per page. It is safe for concurrent use.
+type IndexBatch struct {
+	index *SQLiteIndex
+	size  int
+	// done is called for every page once it is written, with the error of its write
+	done func(filePath string, err error)
+
+	mu      sync.Mutex
+	pending []batchPage
+}
+
+type batchPage struct {
+	path, filePath, pageID, title, content string
+	// file is the state of the indexed file, recorded with the page if set
+	file *indexedFile
+}
+
+// NewBatch returns a batch writing size pages per transaction, defaultIndexBatchSize if size
+// is zero. done may be nil.
+func (s *SQLiteIndex) NewBatch(size int, done func(filePath string, err error)) *IndexBatch {
+	if size <= 0 {
+		size = defaultIndexBatchSize
+	}
+	return &IndexBatch{index: s, size: size, done: done}
+}
+
+// Add queues the page like IndexPage and writes the queued pages once the batch is full.
+// The content is converted to plain text right away, outside the lock of the index.
+func (b *IndexBatch) Add(path, filePath, pageID, title, content string) {
+	b.add(batchPage{path: path, filePath: filePath, pageID: pageID, title: title, content: content})
+}
+
+// addFile queues the page like Add and records the state of its file with it
+func (b *IndexBatch) addFile(path, filePath, pageID, title, content string, file indexedFile) {
+	b.add(batchPage{path: path, filePath: filePath, pageID: pageID, title: title, content: content, file: &file})
+}
+
+func (b *IndexBatch) add(page batchPage) {
+	page.content = PlainText(page.content)
+
+	b.mu.Lock()
+	b.pending = append(b.pending, page)
+	var full []batchPage
+	if len(b.pending) >= b.size {
+		full, b.pending = b.pending, nil
+	}
+	b.mu.Unlock()
+
+	if full != nil {
+		b.write(full)
+	}
+}
+
+// Flush writes the queued pages
+func (b *IndexBatch) Flush() {
+	b.mu.Lock()
+	pages := b.pending
+	b.pending = nil
+	b.mu.Unlock()
+
+	if len(pages) > 0 {
+		b.write(pages)
+	}
+}
+
+func (b *IndexBatch) write(pages []batchPage) {
+	if err := b.index.writeBatch(pages); err != nil {
+		// a single page fails the whole transaction, so only it should fail
+		indexerLog.Warn("could not write batch, writing the pages one by one", "pages", len(pages), "error", err)
+		for _, page := range pages {
+			err := b.index.indexPlainText(page.path, page.filePath, page.pageID, page.title, page.content)
+			if err == nil && page.file != nil {
+				err = b.index.setIndexedFile(page.filePath, *page.file)
+			}
+			b.index.recordIndexResult(page.filePath, err)
+			b.report(page.filePath, err)
+		}
+		return
+	}
+	for _, page := range pages {
+		b.report(page.filePath, nil)
+	}
+}
+
+func (b *IndexBatch) report(filePath string, err error) {
+	if b.done != nil {
+		b.done(filePath, err)
+	}
+}
+
+// writeBatch writes the pages in one transaction
+func (s *SQLiteIndex) writeBatch(pages []batchPage) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.db == nil {
+		return sql.ErrConnDone
+	}
+
+	if s.partitioned {
+		// created before the transaction, partitionTableLocked doesn't use it
+		for _, page := range pages {
+			if _, err := s.partitionTableLocked(PartitionForPath(page.path), true); err != nil {
+				return err
+			}
+		}
+	}
+
+	tx, err := s.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer func() {
+		_ = tx.Rollback()
+	}()
+	stmts := &txStatements{tx: tx, stmts: map[string]*sql.Stmt{}}
+	defer stmts.close()
+
+	for _, page := range pages {
+		if s.partitioned {
+			err = s.indexPagePartitionedTx(stmts, page)
+		} else {
+			err = indexPageTx(stmts, page)
+		}
+		if err == nil && page.file != nil {
+			_, err = stmts.exec(upsertIndexedFileQuery, page.filePath, page.file.Size, page.file.ModTime)
+		}
+		if err == nil {
+			_, err = stmts.exec(upsertIndexResultQuery, indexResultArgs(page.filePath, nil)...)
+		}
+		if err != nil {
+			return fmt.Errorf("%s: %w", page.filePath, err)
+		}
+	}
+	return tx.Commit()
+}
+
+// indexPageTx replaces the page in the pages table
+func indexPageTx(stmts *txStatements, page batchPage) error {
+	query, args := deletePageQuery("pages", page.pageID)
+	if _, err := stmts.exec(query, args...); err != nil {
+		return err
+	}
+	_, err := stmts.exec(`
+		INSERT INTO pages (path, filepath, pageID, title, content)
+		VALUES (?, ?, ?, ?, ?);
+	`, page.path, page.filePath, page.pageID, page.title, page.content)
+	return err
+}
+
+// indexPagePartitionedTx moves the page into the partition of its path like
+// indexPagePartitionedLocked. The table of the partition has to exist.
+// Lock must be held by the caller
+func (s *SQLiteIndex) indexPagePartitionedTx(stmts *txStatements, page batchPage) error {
+	var previous string
+	err := stmts.queryRow(`SELECT partition FROM page_partitions WHERE pageID = ?;`, page.pageID).Scan(&previous)
+	if err != nil && err != sql.ErrNoRows {
+		return err
+	}
+	if err == nil {
+		table, err := s.partitionTableLocked(previous, false)
+		if err != nil {
+			return err
+		}
+		if table != "" {
+			query, args := deletePageQuery(table, page.pageID)
+			if _, err := stmts.exec(query, args...); err != nil {
+				return err
+			}
+		}
+	}
+
+	partition := PartitionForPath(page.path)
+	table, err := s.partitionTableLocked(partition, false)
+	if err != nil {
+		return err
+	}
+	if table == "" {
+		return fmt.Errorf("partition %q has no table", partition)
+	}
+	if _, err := stmts.exec(fmt.Sprintf(`
+		INSERT INTO %s (path, filepath, pageID, title, content)
+		VALUES (?, ?, ?, ?, ?);
+	`, table), page.path, page.filePath, page.pageID, page.title, page.content); err != nil {
+		return err
+	}
+
+	_, err = stmts.exec(`
+		INSERT INTO page_partitions (pageID, filepath, partition)
+		VALUES (?, ?, ?)
+		ON CONFLICT(pageID) DO UPDATE SET filepath = excluded.filepath, partition = excluded.partition;
+	`, page.pageID, page.filePath, partition)
+	return err
+}
+
+// txStatements prepares each statement of a transaction once
+type txStatements struct {
+	tx    *sql.Tx
+	stmts map[string]*sql.Stmt
+}
+
+func (t *txStatements) prepare(query string) (*sql.Stmt, error) {
+	if stmt, ok := t.stmts[query]; ok {
+		return stmt, nil
+	}
+	stmt, err := t.tx.Prepare(query)
+	if err != nil {
+		return nil, err
+	}
+	t.stmts[query] = stmt
+	return stmt, nil
+}
+
+func (t *txStatements) exec(query string, args ...any) (sql.Result, error) {
+	stmt, err := t.prepare(query)
+	if err != nil {
+		return nil, err
+	}
+	return stmt.Exec(args...)
+}
+
+func (t *txStatements) queryRow(query string, args ...any) *sql.Row {
+	stmt, err := t.prepare(query)
+	if err != nil {
+		// the row reports the same error on Scan
+		return t.tx.QueryRow(query, args...)
+	}
+	return stmt.QueryRow(args...)
+}
+
+func (t *txStatements) close() {
+	for _, stmt := range t.stmts {
+		_ = stmt.Close()
+	}
+}
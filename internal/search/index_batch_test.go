@@ -0,0 +1,94 @@
+package search
+
+import (
+	"fmt"
+	"sync"
+	"testing"
+)
+
+func TestIndexBatch(t *testing.T) {
+	index, err := NewSQLiteIndex(t.TempDir())
+	if err != nil {
+		t.Fatalf("failed to create SQLiteIndex: %v", err)
+	}
+	defer index.Close()
+
+	var mu sync.Mutex
+	written := map[string]error{}
+	batch := index.NewBatch(3, func(filePath string, err error) {
+		mu.Lock()
+		defer mu.Unlock()
+		written[filePath] = err
+	})
+
+	for i := range 7 {
+		batch.Add(fmt.Sprintf("/docs/page-%d", i), fmt.Sprintf("docs/page-%d.md", i), fmt.Sprintf("id-%d", i), "Page", "Batched **content**.")
+		if i == 2 && len(written) != 3 {
+			t.Errorf("Expected the first batch to be written once full, got %d pages", len(written))
+		}
+	}
+	if len(written) != 6 {
+		t.Errorf("Expected two full batches, got %d pages", len(written))
+	}
+	batch.Flush()
+	if len(written) != 7 {
+		t.Errorf("Expected all pages after Flush, got %d", len(written))
+	}
+	for file, err := range written {
+		if err != nil {
+			t.Errorf("Expected %s to be written, got %v", file, err)
+		}
+	}
+
+	// a page of the batch replaces the indexed one
+	batch.Add("/docs/renamed", "docs/renamed.md", "id-1", "Renamed", "Batched content.")
+	batch.Flush()
+	result, err := index.Search(t.Context(), "batched", 0, 20)
+	if err != nil {
+		t.Fatalf("search failed: %v", err)
+	}
+	if result.Count != 7 {
+		t.Errorf("Expected 7 pages, got %d", result.Count)
+	}
+	var path string
+	if err := index.GetDB().QueryRow(`SELECT path FROM pages WHERE pageID = ?`, "id-1").Scan(&path); err != nil || path != "/docs/renamed" {
+		t.Errorf("Expected the renamed page, got %q (%v)", path, err)
+	}
+}
+
+func TestIndexBatch_Partitioned(t *testing.T) {
+	index, err := NewSQLiteIndex(t.TempDir())
+	if err != nil {
+		t.Fatalf("failed to create SQLiteIndex: %v", err)
+	}
+	defer index.Close()
+	index.SetPartitioned(true)
+
+	if err := index.IndexPage("/docs/alpha", "docs/alpha.md", "alpha1", "Alpha", "Partitioned content."); err != nil {
+		t.Fatalf("failed to index alpha page: %v", err)
+	}
+
+	batch := index.NewBatch(0, nil)
+	// moves alpha to the new partition notes in the same transaction which creates it
+	batch.Add("/notes/alpha", "notes/alpha.md", "alpha1", "Alpha", "Partitioned content.")
+	batch.Add("/notes/beta", "notes/beta.md", "beta2", "Beta", "More partitioned content.")
+	batch.Flush()
+
+	partitions, err := index.ListPartitions()
+	if err != nil {
+		t.Fatalf("failed to list partitions: %v", err)
+	}
+	if len(partitions) != 2 || partitions[1] != "notes" {
+		t.Fatalf("expected partitions [docs notes], got %v", partitions)
+	}
+	if err := index.ClearPartition("docs"); err != nil {
+		t.Fatalf("failed to clear partition: %v", err)
+	}
+	result, err := index.Search(t.Context(), "partitioned", 0, 10)
+	if err != nil {
+		t.Fatalf("search failed: %v", err)
+	}
+	if result.Count != 2 {
+		t.Errorf("expected both pages in the notes partition, got %d results", result.Count)
+	}
+}
@@ -0,0 +1,114 @@
+package search
+
+import (
+	"database/sql"
+	"time"
+)
+
+// Statuses of the last indexing of a file
+const (
+	IndexResultOK    = "ok"
+	IndexResultError = "error"
+)
+
+// IndexResult is the outcome of the last indexing of a file. Unlike the errors of the
+// IndexingStatus, which only cover the current run, the results are kept in the search
+// database, so a file which failed at startup is still reported later.
+type IndexResult struct {
+	Path      string    `json:"path"`
+	Status    string    `json:"status"`
+	Message   string    `json:"message,omitempty"`
+	IndexedAt time.Time `json:"indexedAt"`
+}
+
+const upsertIndexResultQuery = `
+	INSERT INTO index_results (filepath, status, message, indexed_at)
+	VALUES (?, ?, ?, CURRENT_TIMESTAMP)
+	ON CONFLICT(filepath) DO UPDATE SET status = excluded.status, message = excluded.message, indexed_at = excluded.indexed_at;
+`
+
+// indexResultArgs returns the arguments of upsertIndexResultQuery
+func indexResultArgs(filePath string, err error) []any {
+	if err != nil {
+		return []any{filePath, IndexResultError, err.Error()}
+	}
+	return []any{filePath, IndexResultOK, ""}
+}
+
+// recordIndexResult keeps the outcome of indexing a file, err is nil on success.
+// A failed write is only logged, it must not fail the indexing.
+func (s *SQLiteIndex) recordIndexResult(filePath string, err error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.db == nil {
+		return
+	}
+	if _, execErr := s.db.Exec(upsertIndexResultQuery, indexResultArgs(filePath, err)...); execErr != nil {
+		searchLog.Warn("could not record index result", "path", filePath, "error", execErr)
+	}
+}
+
+// IndexErrors returns the files whose last indexing failed, sorted by path
+func (s *SQLiteIndex) IndexErrors() ([]IndexResult, error) {
+	if s.db == nil {
+		return nil, sql.ErrConnDone
+	}
+
+	rows, err := s.db.Query(`
+		SELECT filepath, status, message, indexed_at
+		FROM index_results
+		WHERE status = ?
+		ORDER BY filepath;
+	`, IndexResultError)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	results := []IndexResult{}
+	for rows.Next() {
+		var result IndexResult
+		var indexedAt string
+		if err := rows.Scan(&result.Path, &result.Status, &result.Message, &indexedAt); err != nil {
+			return nil, err
+		}
+		result.IndexedAt = parseSQLiteTimestamp(indexedAt)
+		results = append(results, result)
+	}
+	return results, rows.Err()
+}
+
+// pruneIndexResults removes the results of the files which are gone
+func (s *SQLiteIndex) pruneIndexResults(found map[string]bool) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.db == nil {
+		return sql.ErrConnDone
+	}
+
+	rows, err := s.db.Query(`SELECT filepath FROM index_results;`)
+	if err != nil {
+		return err
+	}
+	var gone []string
+	for rows.Next() {
+		var p string
+		if err := rows.Scan(&p); err != nil {
+			rows.Close()
+			return err
+		}
+		if !found[p] {
+			gone = append(gone, p)
+		}
+	}
+	if err := rows.Close(); err != nil {
+		return err
+	}
+
+	for _, p := range gone {
+		if _, err := s.db.Exec(`DELETE FROM index_results WHERE filepath = ?;`, p); err != nil {
+			return err
+		}
+	}
+	return nil
+}
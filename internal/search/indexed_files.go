@@ -0,0 +1,91 @@
+package search
+
+import (
+	"database/sql"
+	"io/fs"
+	"time"
+)
+
+// indexedFile is the size and modification time a file had when it was indexed.
+// At startup only the files whose state differs are indexed again.
+type indexedFile struct {
+	Size    int64
+	ModTime int64
+}
+
+// newIndexedFile returns the state of a file stat'ed before it was read. Like with the file
+// state cache, the modification time of a file changed within racyWindow is not trusted, it is
+// stored as zero, which never matches, so the file is indexed again at the next startup.
+func newIndexedFile(info fs.FileInfo) indexedFile {
+	modTime := info.ModTime().UnixNano()
+	if time.Since(info.ModTime()) < racyWindow {
+		modTime = 0
+	}
+	return indexedFile{Size: info.Size(), ModTime: modTime}
+}
+
+// unchanged reports whether the file still has the state it was indexed with
+func (f indexedFile) unchanged(info fs.FileInfo) bool {
+	return f.ModTime != 0 && f.Size == info.Size() && f.ModTime == info.ModTime().UnixNano()
+}
+
+// loadIndexedFiles returns the state of the indexed files by their path below the data dir.
+// If no state is recorded, the pages were indexed by a version which didn't record it, and if
+// the pages were indexed in the other partition mode, searches don't find them. In both cases
+// the index is cleared, so every file is indexed again and no stale page is left behind.
+func (s *SQLiteIndex) loadIndexedFiles() (map[string]indexedFile, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.db == nil {
+		return nil, sql.ErrConnDone
+	}
+
+	rows, err := s.db.Query(`SELECT filepath, size, mtime FROM indexed_files;`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	files := map[string]indexedFile{}
+	for rows.Next() {
+		var p string
+		var f indexedFile
+		if err := rows.Scan(&p, &f.Size, &f.ModTime); err != nil {
+			return nil, err
+		}
+		files[p] = f
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	otherMode := `SELECT EXISTS (SELECT 1 FROM index_partitions);`
+	if s.partitioned {
+		otherMode = `SELECT EXISTS (SELECT 1 FROM pages);`
+	}
+	var mismatch bool
+	if err := s.db.QueryRow(otherMode).Scan(&mismatch); err != nil {
+		return nil, err
+	}
+	if len(files) == 0 || mismatch {
+		return map[string]indexedFile{}, s.clearLocked()
+	}
+	return files, nil
+}
+
+// setIndexedFile records the state of a file indexed with IndexPage
+func (s *SQLiteIndex) setIndexedFile(filePath string, file indexedFile) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.db == nil {
+		return sql.ErrConnDone
+	}
+	_, err := s.db.Exec(upsertIndexedFileQuery, filePath, file.Size, file.ModTime)
+	return err
+}
+
+const upsertIndexedFileQuery = `
+	INSERT INTO indexed_files (filepath, size, mtime)
+	VALUES (?, ?, ?)
+	ON CONFLICT(filepath) DO UPDATE SET size = excluded.size, mtime = excluded.mtime;
+`
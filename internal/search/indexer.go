@@ -1,16 +1,30 @@
 package search
 
 import (
-	"log"
+	"context"
 	"os"
 	"path/filepath"
 	"sync"
+	"time"
 )
 
+// defaultQueueSize is the number of files waiting for a worker, see Indexer.QueueSize
+const defaultQueueSize = 100
+
 type Indexer struct {
 	DataDir   string
 	Workers   int
 	IndexFunc func(file string, content []byte) error
+	// QueueSize bounds the files found by the walk which wait for a worker, defaultQueueSize
+	// if zero. Once it is full, the walk waits: the workers, usually the writes to SQLite,
+	// set the pace.
+	QueueSize int
+	// walk configures symlinks and ignored files, relative to the data dir
+	walk walkOptions
+	// status receives the metrics of the queue and the timings of the files, may be nil
+	status *IndexingStatus
+	// skip leaves out the files for which it returns true, may be nil
+	skip func(path string) bool
 }
 
 func NewIndexer(dataDir string, workers int, fn func(string, []byte) error) *Indexer {
@@ -21,8 +35,17 @@ func NewIndexer(dataDir string, workers int, fn func(string, []byte) error) *Ind
 	}
 }
 
-func (i *Indexer) Start() error {
-	files := make(chan string, 100)
+// Start indexes the Markdown files below DataDir. When ctx ends, the walk stops and the
+// workers skip the queued files; Start then returns the error of ctx.
+func (i *Indexer) Start(ctx context.Context) error {
+	queueSize := i.QueueSize
+	if queueSize <= 0 {
+		queueSize = defaultQueueSize
+	}
+	files := make(chan string, queueSize)
+	if i.status != nil {
+		i.status.startPipeline(i.Workers, queueSize)
+	}
 	var wg sync.WaitGroup
 
 	// Start worker goroutines
@@ -31,28 +54,43 @@ func (i *Indexer) Start() error {
 		go func() {
 			defer wg.Done()
 			for file := range files {
+				if i.status != nil {
+					i.status.setQueueDepth(len(files))
+				}
+				if ctx.Err() != nil {
+					continue
+				}
+				start := time.Now()
 				content, err := os.ReadFile(file)
 				if err != nil {
-					log.Printf("[indexer] error reading file %s: %v", file, err)
+					indexerLog.Warn("could not read file", "path", file, "error", err)
 					continue
 				}
+				read := time.Since(start)
 
 				// Call the indexing function
+				start = time.Now()
 				if err := i.IndexFunc(file, content); err != nil {
-					log.Printf("[indexer] error indexing file %s: %v", file, err)
+					indexerLog.Error("could not index file", "path", file, "error", err)
+				}
+				if i.status != nil {
+					i.status.recordFileTiming(i.relativePath(file), read, time.Since(start))
 				}
 			}
 		}()
 	}
 
 	// Walk through the data directory and send files to the channel
-	err := filepath.Walk(i.DataDir, func(path string, info os.FileInfo, err error) error {
+	err := walkFiles(i.DataDir, i.walk, func(path string, info os.FileInfo, err error) error {
+		if ctxErr := ctx.Err(); ctxErr != nil {
+			return ctxErr
+		}
 		if err != nil {
-			log.Printf("[indexer] error walking path %s: %v", path, err)
+			indexerLog.Warn("walk error", "path", path, "error", err)
 			return err
 		}
-		if !info.IsDir() && filepath.Ext(path) == ".md" {
-			files <- path
+		if !info.IsDir() && filepath.Ext(path) == ".md" && (i.skip == nil || !i.skip(path)) {
+			i.enqueue(files, path)
 		}
 
 		return nil
@@ -63,3 +101,48 @@ func (i *Indexer) Start() error {
 
 	return err
 }
+
+// enqueue hands the file to the workers. A full queue blocks the walk until a worker is free;
+// the time it waits is recorded as backpressure.
+func (i *Indexer) enqueue(files chan string, path string) {
+	select {
+	case files <- path:
+	default:
+		start := time.Now()
+		files <- path
+		if i.status != nil {
+			i.status.recordBackpressure(time.Since(start))
+		}
+	}
+	if i.status != nil {
+		i.status.setQueueDepth(len(files))
+	}
+}
+
+// relativePath returns the path of the file below the data dir, as shown in the status
+func (i *Indexer) relativePath(file string) string {
+	dataDir := i.walk.dataDir
+	if dataDir == "" {
+		dataDir = i.DataDir
+	}
+	if rel, err := relativeDataPath(dataDir, file); err == nil {
+		return rel
+	}
+	return file
+}
+
+// markdownFiles returns the Markdown files below dataDir with their file info.
+// It is used to report the progress of an indexing run and to find the changed files.
+func markdownFiles(dataDir string, opts walkOptions) map[string]os.FileInfo {
+	files := map[string]os.FileInfo{}
+	_ = walkFiles(dataDir, opts, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			return nil
+		}
+		if !info.IsDir() && filepath.Ext(path) == ".md" {
+			files[path] = info
+		}
+		return nil
+	})
+	return files
+}
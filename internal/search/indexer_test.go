@@ -1,9 +1,14 @@
 package search
 
 import (
+	"context"
+	"errors"
+	"fmt"
 	"os"
 	"path/filepath"
+	"strings"
 	"testing"
+	"time"
 )
 
 // Hilfsfunktion: Testdatenstruktur anlegen
@@ -37,7 +42,7 @@ func TestIndexer_BasicIndexing(t *testing.T) {
 		return nil
 	})
 
-	err := indexer.Start()
+	err := indexer.Start(t.Context())
 	if err != nil {
 		t.Fatalf("indexer failed: %v", err)
 	}
@@ -62,3 +67,68 @@ func TestIndexer_BasicIndexing(t *testing.T) {
 		}
 	}
 }
+
+func TestIndexer_Cancelled(t *testing.T) {
+	tmpDir := t.TempDir()
+	createTestFiles(t, tmpDir, map[string]string{"index.md": "# Home", "docs/about.md": "About page"})
+
+	ctx, cancel := context.WithCancel(t.Context())
+	cancel()
+	var indexed []string
+	indexer := NewIndexer(tmpDir, 2, func(path string, content []byte) error {
+		indexed = append(indexed, path)
+		return nil
+	})
+	if err := indexer.Start(ctx); !errors.Is(err, context.Canceled) {
+		t.Errorf("Expected context.Canceled, got %v", err)
+	}
+	if len(indexed) != 0 {
+		t.Errorf("Expected no indexed files after the cancellation, got %v", indexed)
+	}
+}
+
+func TestIndexer_Backpressure(t *testing.T) {
+	tmpDir := t.TempDir()
+	files := map[string]string{}
+	for i := range 10 {
+		files[fmt.Sprintf("docs/page-%d.md", i)] = "content"
+	}
+	createTestFiles(t, tmpDir, files)
+
+	status := NewIndexingStatus()
+	status.Start()
+	// a slow worker fills the queue, so the walk has to wait
+	indexer := NewIndexer(tmpDir, 1, func(path string, content []byte) error {
+		time.Sleep(5 * time.Millisecond)
+		return nil
+	})
+	indexer.QueueSize = 2
+	indexer.status = status
+	if err := indexer.Start(t.Context()); err != nil {
+		t.Fatalf("indexer failed: %v", err)
+	}
+	status.Finish()
+
+	metrics := status.Snapshot().Metrics
+	if metrics.Workers != 1 || metrics.QueueCapacity != 2 {
+		t.Errorf("Expected 1 worker and a capacity of 2, got %+v", metrics)
+	}
+	if metrics.MaxQueueDepth != 2 || metrics.QueueDepth != 0 {
+		t.Errorf("Expected a full queue which was emptied, got %+v", metrics)
+	}
+	if metrics.BackpressureWaits == 0 || metrics.BackpressureMs == 0 {
+		t.Errorf("Expected the walk to wait for the worker, got %+v", metrics)
+	}
+	if metrics.AvgIndexMs < 5 || metrics.MaxIndexMs < metrics.AvgIndexMs {
+		t.Errorf("Expected the timings of the slow worker, got %+v", metrics)
+	}
+	if !strings.HasPrefix(metrics.SlowestFile, "docs/page-") {
+		t.Errorf("Expected the slowest file relative to the data dir, got %q", metrics.SlowestFile)
+	}
+
+	// the next run starts with new metrics
+	status.Start()
+	if metrics := status.Snapshot().Metrics; metrics != (IndexingMetrics{}) {
+		t.Errorf("Expected the metrics to be reset, got %+v", metrics)
+	}
+}
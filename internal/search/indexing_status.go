@@ -1,16 +1,48 @@
 package search
 
 import (
+	"fmt"
 	"sync"
 	"time"
 )
 
+// maxStatusErrors limits how many error messages are kept in the status
+const maxStatusErrors = 100
+
 type IndexingStatus struct {
 	mu         sync.RWMutex
 	Active     bool      `json:"active"`      // Indicates if indexing is currently active
 	Indexed    int       `json:"indexed"`     // Number of pages indexed
 	Failed     int       `json:"failed"`      // Number of pages that failed to index
+	Total      int       `json:"total"`       // Number of files found for the current run
+	Processed  int       `json:"processed"`   // Number of files processed in the current run
+	Errors     []string  `json:"errors"`      // Error messages of the current run
+	ETASeconds int       `json:"eta_seconds"` // Estimated remaining seconds for the current run
+	StartedAt  time.Time `json:"started_at"`  // Timestamp when indexing started
 	FinishedAt time.Time `json:"finished_at"` // Timestamp when indexing finished
+	// Metrics describe the pipeline of the current run
+	Metrics IndexingMetrics `json:"metrics"`
+
+	// readTotal and indexTotal add up the timings of the timed files, for the averages
+	readTotal  time.Duration
+	indexTotal time.Duration
+	timed      int
+}
+
+// IndexingMetrics describe the queue between the walk of the data directory and the workers,
+// and how long the files took. A growing backpressure means the workers, usually the writes to
+// SQLite, can't keep up with the walk.
+type IndexingMetrics struct {
+	Workers           int     `json:"workers"`            // Number of workers of the current run
+	QueueCapacity     int     `json:"queue_capacity"`     // Files which may wait for a worker
+	QueueDepth        int     `json:"queue_depth"`        // Files currently waiting for a worker
+	MaxQueueDepth     int     `json:"max_queue_depth"`    // Most files waiting at the same time
+	BackpressureWaits int     `json:"backpressure_waits"` // How often the walk waited for a full queue
+	BackpressureMs    int64   `json:"backpressure_ms"`    // How long the walk waited in total
+	AvgReadMs         float64 `json:"avg_read_ms"`        // Average time to read a file
+	AvgIndexMs        float64 `json:"avg_index_ms"`       // Average time to index a file; the file which fills a batch waits for its write to SQLite
+	MaxIndexMs        float64 `json:"max_index_ms"`       // Longest time to index a file
+	SlowestFile       string  `json:"slowest_file"`       // The file which took MaxIndexMs
 }
 
 func NewIndexingStatus() *IndexingStatus {
@@ -18,16 +50,38 @@ func NewIndexingStatus() *IndexingStatus {
 		Active:  false,
 		Indexed: 0,
 		Failed:  0,
+		Errors:  []string{},
 	}
 }
 
 func (s *IndexingStatus) Start() {
 	s.mu.Lock()
 	defer s.mu.Unlock()
+	s.reset()
+}
+
+// TryStart marks the indexing as active and returns false if it is already running.
+func (s *IndexingStatus) TryStart() bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.Active {
+		return false
+	}
+	s.reset()
+	return true
+}
+
+func (s *IndexingStatus) reset() {
 	s.Active = true
 	s.Indexed = 0
 	s.Failed = 0
+	s.Total = 0
+	s.Processed = 0
+	s.Errors = []string{}
+	s.StartedAt = time.Now()
 	s.FinishedAt = time.Time{} // Reset finished time
+	s.Metrics = IndexingMetrics{}
+	s.readTotal, s.indexTotal, s.timed = 0, 0, 0
 }
 
 func (s *IndexingStatus) Finish() {
@@ -37,16 +91,74 @@ func (s *IndexingStatus) Finish() {
 	s.FinishedAt = time.Now()
 }
 
+// SetTotal sets the number of files which will be processed in the current run.
+func (s *IndexingStatus) SetTotal(total int) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.Total = total
+}
+
 func (s *IndexingStatus) Success() {
 	s.mu.Lock()
 	defer s.mu.Unlock()
 	s.Indexed++
+	s.Processed++
 }
 
 func (s *IndexingStatus) Fail() {
 	s.mu.Lock()
 	defer s.mu.Unlock()
 	s.Failed++
+	s.Processed++
+}
+
+// RecordError marks a file as failed and keeps the error message for the status report.
+func (s *IndexingStatus) RecordError(file string, err error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.Failed++
+	s.Processed++
+	if len(s.Errors) < maxStatusErrors {
+		s.Errors = append(s.Errors, fmt.Sprintf("%s: %v", file, err))
+	}
+}
+
+// startPipeline records the workers and the capacity of the queue of an indexer
+func (s *IndexingStatus) startPipeline(workers, queueCapacity int) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.Metrics.Workers = workers
+	s.Metrics.QueueCapacity = queueCapacity
+	s.Metrics.QueueDepth = 0
+}
+
+// setQueueDepth records the number of files waiting for a worker
+func (s *IndexingStatus) setQueueDepth(depth int) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.Metrics.QueueDepth = depth
+	s.Metrics.MaxQueueDepth = max(s.Metrics.MaxQueueDepth, depth)
+}
+
+// recordBackpressure records that the walk waited for a full queue
+func (s *IndexingStatus) recordBackpressure(wait time.Duration) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.Metrics.BackpressureWaits++
+	s.Metrics.BackpressureMs += wait.Milliseconds()
+}
+
+// recordFileTiming records how long reading and indexing the file took
+func (s *IndexingStatus) recordFileTiming(file string, read, index time.Duration) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.readTotal += read
+	s.indexTotal += index
+	s.timed++
+	if ms := milliseconds(index); ms > s.Metrics.MaxIndexMs {
+		s.Metrics.MaxIndexMs = ms
+		s.Metrics.SlowestFile = file
+	}
 }
 
 // IsActive returns true if indexing is currently active.
@@ -59,10 +171,43 @@ func (s *IndexingStatus) IsActive() bool {
 func (s *IndexingStatus) Snapshot() *IndexingStatus {
 	s.mu.RLock()
 	defer s.mu.RUnlock()
+
+	errs := make([]string, len(s.Errors))
+	copy(errs, s.Errors)
+
+	metrics := s.Metrics
+	if s.timed > 0 {
+		metrics.AvgReadMs = milliseconds(s.readTotal / time.Duration(s.timed))
+		metrics.AvgIndexMs = milliseconds(s.indexTotal / time.Duration(s.timed))
+	}
+
 	return &IndexingStatus{
 		Active:     s.Active,
 		Indexed:    s.Indexed,
 		Failed:     s.Failed,
+		Total:      s.Total,
+		Processed:  s.Processed,
+		Errors:     errs,
+		ETASeconds: s.etaSecondsLocked(),
+		StartedAt:  s.StartedAt,
 		FinishedAt: s.FinishedAt,
+		Metrics:    metrics,
+	}
+}
+
+// milliseconds returns the duration in milliseconds, with a precision of microseconds
+func milliseconds(d time.Duration) float64 {
+	return float64(d.Microseconds()) / 1000
+}
+
+// etaSecondsLocked estimates the remaining time based on the average time per processed file
+// Lock must be held by the caller
+func (s *IndexingStatus) etaSecondsLocked() int {
+	if !s.Active || s.Processed == 0 || s.Total <= s.Processed {
+		return 0
 	}
+	elapsed := time.Since(s.StartedAt)
+	perFile := elapsed / time.Duration(s.Processed)
+	remaining := perFile * time.Duration(s.Total-s.Processed)
+	return int(remaining.Seconds())
 }
@@ -0,0 +1,127 @@
+package search
+
+import (
+	"database/sql"
+	"fmt"
+	"sort"
+	"time"
+)
+
+// IntegrityReport is the result of an integrity check of the search database
+type IntegrityReport struct {
+	CheckedAt time.Time `json:"checkedAt"`
+	OK        bool      `json:"ok"`
+	// Problems are the messages of PRAGMA integrity_check and of the full text tables
+	Problems []string `json:"problems"`
+	// Repaired is set when the problems were fixed by rebuilding the index tables,
+	// RepairError when the rebuild failed or problems remained
+	Repaired    bool   `json:"repaired"`
+	RepairError string `json:"repairError,omitempty"`
+}
+
+// CheckIntegrity runs PRAGMA integrity_check and the integrity check of every full text
+// table, and returns the problems found. No problems means the database is intact.
+func (s *SQLiteIndex) CheckIntegrity() ([]string, error) {
+	if s.db == nil {
+		return nil, sql.ErrConnDone
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	problems := []string{}
+	rows, err := s.db.Query(`PRAGMA integrity_check;`)
+	if err != nil {
+		return nil, err
+	}
+	for rows.Next() {
+		var message string
+		if err := rows.Scan(&message); err != nil {
+			rows.Close()
+			return nil, err
+		}
+		if message != "ok" {
+			problems = append(problems, message)
+		}
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+
+	tables, err := s.fullTextTablesLocked()
+	if err != nil {
+		// the partitions can't be listed if their table is damaged
+		return append(problems, fmt.Sprintf("index_partitions: %v", err)), nil
+	}
+	for _, table := range tables {
+		if _, err := s.db.Exec(fmt.Sprintf(`INSERT INTO %[1]s (%[1]s) VALUES ('integrity-check');`, table)); err != nil {
+			problems = append(problems, fmt.Sprintf("%s: %v", table, err))
+		}
+	}
+	return problems, nil
+}
+
+// RebuildIndexTables repairs the parts of the database which can be derived again:
+// the full text tables are dropped and created empty, the deleted pages are indexed again
+// from the history, the B-tree indexes are rebuilt and the file state caches are cleared.
+// The pages have to be indexed again afterwards. The file history itself can't be restored.
+func (s *SQLiteIndex) RebuildIndexTables() error {
+	if s.db == nil {
+		return sql.ErrConnDone
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	tables, err := s.fullTextTablesLocked()
+	if err != nil {
+		tables = []string{"pages", "deleted_pages"}
+	}
+	for _, table := range tables {
+		if _, err := s.db.Exec(fmt.Sprintf(`DROP TABLE IF EXISTS %s;`, table)); err != nil {
+			return err
+		}
+	}
+	s.partitions = nil
+
+	tx, err := s.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer func() {
+		_ = tx.Rollback()
+	}()
+
+	for _, stmt := range []string{`DELETE FROM index_partitions;`, `DELETE FROM page_partitions;`, `DELETE FROM file_state;`, `DELETE FROM indexed_files;`, `DELETE FROM index_results;`} {
+		if _, err := tx.Exec(stmt); err != nil {
+			return err
+		}
+	}
+	if err := migrateBaseSchema(tx); err != nil {
+		return err
+	}
+	if err := migrateDeletedPages(tx); err != nil {
+		return err
+	}
+	if err := tx.Commit(); err != nil {
+		return err
+	}
+
+	_, err = s.db.Exec(`REINDEX;`)
+	return err
+}
+
+// fullTextTablesLocked returns the FTS tables of the database, including the partitions.
+// Lock must be held by the caller
+func (s *SQLiteIndex) fullTextTablesLocked() ([]string, error) {
+	partitions, err := s.listPartitions()
+	if err != nil {
+		return nil, err
+	}
+	var tables []string
+	for _, table := range partitions {
+		tables = append(tables, table)
+	}
+	sort.Strings(tables)
+	return append([]string{"pages", "deleted_pages"}, tables...), nil
+}
@@ -0,0 +1,10 @@
+package search
+
+import "github.com/Gomez12/wiki/internal/core/logging"
+
+var (
+	watchLog   = logging.Component("watcher")
+	historyLog = logging.Component("history")
+	indexerLog = logging.Component("indexer")
+	searchLog  = logging.Component("search")
+)
@@ -0,0 +1,286 @@
+package search
+
+import (
+	"database/sql"
+	"fmt"
+)
+
+// migration upgrades the search database by one schema version.
+// Migrations run in their own transaction and must never be changed once released;
+// add a new migration instead.
+type migration struct {
+	version int
+	name    string
+	up      func(tx *sql.Tx) error
+}
+
+// migrations lists all schema changes in order.
+// The first migrations use IF NOT EXISTS, because databases created before the
+// migration framework already contain these tables.
+var migrations = []migration{
+	{version: 1, name: "create pages and file history", up: migrateBaseSchema},
+	{version: 2, name: "add file history content column", up: migrateHistoryContentColumn},
+	{version: 3, name: "create file state cache", up: migrateFileState},
+	{version: 4, name: "create index partitions", up: migrateIndexPartitions},
+	{version: 5, name: "create history labels", up: migrateHistoryLabels},
+	{version: 6, name: "create deleted pages index", up: migrateDeletedPages},
+	{version: 7, name: "create indexed file state", up: migrateIndexedFiles},
+	{version: 8, name: "create index results", up: migrateIndexResults},
+}
+
+// migrate brings the database up to the latest schema version.
+func (s *SQLiteIndex) migrate() error {
+	if _, err := s.db.Exec(`
+		CREATE TABLE IF NOT EXISTS schema_version (
+			version INTEGER NOT NULL
+		);
+	`); err != nil {
+		return err
+	}
+
+	current, err := s.schemaVersion()
+	if err != nil {
+		return err
+	}
+
+	for _, m := range migrations {
+		if m.version <= current {
+			continue
+		}
+		if err := s.runMigration(m); err != nil {
+			return fmt.Errorf("migration %d (%s) failed: %w", m.version, m.name, err)
+		}
+		searchLog.Info("applied schema migration", "version", m.version, "name", m.name)
+	}
+
+	return nil
+}
+
+// SchemaVersion returns the version of the applied schema
+func (s *SQLiteIndex) SchemaVersion() (int, error) {
+	if s.db == nil {
+		return 0, sql.ErrConnDone
+	}
+	return s.schemaVersion()
+}
+
+func (s *SQLiteIndex) schemaVersion() (int, error) {
+	var version sql.NullInt64
+	if err := s.db.QueryRow(`SELECT MAX(version) FROM schema_version;`).Scan(&version); err != nil {
+		return 0, err
+	}
+	return int(version.Int64), nil
+}
+
+func (s *SQLiteIndex) runMigration(m migration) error {
+	tx, err := s.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer func() {
+		_ = tx.Rollback()
+	}()
+
+	if err := m.up(tx); err != nil {
+		return err
+	}
+
+	if _, err := tx.Exec(`DELETE FROM schema_version;`); err != nil {
+		return err
+	}
+	if _, err := tx.Exec(`INSERT INTO schema_version (version) VALUES (?);`, m.version); err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+func migrateBaseSchema(tx *sql.Tx) error {
+	if _, err := tx.Exec(`
+		CREATE VIRTUAL TABLE IF NOT EXISTS pages USING fts5(
+			path UNINDEXED,
+			filepath UNINDEXED,
+			pageID,
+			title,
+			content
+		);
+	`); err != nil {
+		return err
+	}
+
+	if _, err := tx.Exec(`
+		CREATE TABLE IF NOT EXISTS file_history (
+			id INTEGER PRIMARY KEY AUTOINCREMENT,
+			path TEXT NOT NULL,
+			hash TEXT,
+			status TEXT NOT NULL,
+			previous_path TEXT,
+			recorded_at DATETIME DEFAULT CURRENT_TIMESTAMP
+		);
+	`); err != nil {
+		return err
+	}
+
+	if _, err := tx.Exec(`CREATE INDEX IF NOT EXISTS idx_file_history_path ON file_history(path);`); err != nil {
+		return err
+	}
+
+	_, err := tx.Exec(`CREATE INDEX IF NOT EXISTS idx_file_history_hash ON file_history(hash);`)
+	return err
+}
+
+// migrateHistoryContentColumn adds the content column to history tables of older databases
+func migrateHistoryContentColumn(tx *sql.Tx) error {
+	rows, err := tx.Query(`PRAGMA table_info(file_history);`)
+	if err != nil {
+		return err
+	}
+
+	hasContent := false
+	for rows.Next() {
+		var cid int
+		var name, ctype string
+		var notnull, pk int
+		var dflt interface{}
+		if err := rows.Scan(&cid, &name, &ctype, &notnull, &dflt, &pk); err != nil {
+			rows.Close()
+			return err
+		}
+		if name == "content" {
+			hasContent = true
+		}
+	}
+	if err := rows.Close(); err != nil {
+		return err
+	}
+
+	if hasContent {
+		return nil
+	}
+
+	_, err = tx.Exec(`ALTER TABLE file_history ADD COLUMN content TEXT;`)
+	return err
+}
+
+func migrateFileState(tx *sql.Tx) error {
+	_, err := tx.Exec(`
+		CREATE TABLE IF NOT EXISTS file_state (
+			path TEXT PRIMARY KEY,
+			size INTEGER NOT NULL,
+			mtime INTEGER NOT NULL,
+			hash TEXT NOT NULL
+		);
+	`)
+	return err
+}
+
+func migrateIndexPartitions(tx *sql.Tx) error {
+	if _, err := tx.Exec(`
+		CREATE TABLE IF NOT EXISTS index_partitions (
+			name TEXT PRIMARY KEY,
+			table_name TEXT NOT NULL
+		);
+	`); err != nil {
+		return err
+	}
+
+	_, err := tx.Exec(`
+		CREATE TABLE IF NOT EXISTS page_partitions (
+			pageID TEXT PRIMARY KEY,
+			filepath TEXT NOT NULL,
+			partition TEXT NOT NULL
+		);
+	`)
+	return err
+}
+
+func migrateHistoryLabels(tx *sql.Tx) error {
+	if _, err := tx.Exec(`
+		CREATE TABLE IF NOT EXISTS history_labels (
+			id INTEGER PRIMARY KEY AUTOINCREMENT,
+			entry_id INTEGER NOT NULL,
+			label TEXT NOT NULL,
+			created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+			UNIQUE (entry_id, label)
+		);
+	`); err != nil {
+		return err
+	}
+
+	_, err := tx.Exec(`CREATE INDEX IF NOT EXISTS idx_history_labels_label ON history_labels (label);`)
+	return err
+}
+
+// migrateDeletedPages creates the index of the deleted pages and fills it with the last
+// snapshot of every path whose latest history entry is a deletion
+func migrateDeletedPages(tx *sql.Tx) error {
+	if _, err := tx.Exec(`
+		CREATE VIRTUAL TABLE IF NOT EXISTS deleted_pages USING fts5(
+			entryID UNINDEXED,
+			path UNINDEXED,
+			title,
+			content
+		);
+	`); err != nil {
+		return err
+	}
+
+	rows, err := tx.Query(`
+		SELECT id, path, COALESCE(content, ''), status
+		FROM file_history
+		WHERE id IN (SELECT MAX(id) FROM file_history GROUP BY path) AND status = ?;
+	`, FileStatusDeleted)
+	if err != nil {
+		return err
+	}
+	type deleted struct {
+		id    int64
+		entry FileHistorySnapshot
+	}
+	var pages []deleted
+	for rows.Next() {
+		var d deleted
+		if err := rows.Scan(&d.id, &d.entry.Path, &d.entry.Content, &d.entry.Status); err != nil {
+			rows.Close()
+			return err
+		}
+		pages = append(pages, d)
+	}
+	if err := rows.Close(); err != nil {
+		return err
+	}
+
+	for _, d := range pages {
+		if err := indexDeletedPageTx(tx, d.id, d.entry); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func migrateIndexedFiles(tx *sql.Tx) error {
+	_, err := tx.Exec(`
+		CREATE TABLE IF NOT EXISTS indexed_files (
+			filepath TEXT PRIMARY KEY,
+			size INTEGER NOT NULL,
+			mtime INTEGER NOT NULL
+		);
+	`)
+	return err
+}
+
+func migrateIndexResults(tx *sql.Tx) error {
+	if _, err := tx.Exec(`
+		CREATE TABLE IF NOT EXISTS index_results (
+			filepath TEXT PRIMARY KEY,
+			status TEXT NOT NULL,
+			message TEXT NOT NULL DEFAULT '',
+			indexed_at DATETIME NOT NULL
+		);
+	`); err != nil {
+		return err
+	}
+
+	_, err := tx.Exec(`CREATE INDEX IF NOT EXISTS idx_index_results_status ON index_results (status);`)
+	return err
+}
@@ -0,0 +1,77 @@
+package search
+
+import (
+	"database/sql"
+	"path/filepath"
+	"testing"
+)
+
+func TestMigrations_UpgradeLegacyDatabase(t *testing.T) {
+	tmpDir := t.TempDir()
+
+	// Database created before the migration framework and the content column
+	legacy, err := sql.Open("sqlite", filepath.Join(tmpDir, "search.db"))
+	if err != nil {
+		t.Fatalf("failed to open legacy db: %v", err)
+	}
+	if _, err := legacy.Exec(`
+		CREATE TABLE file_history (
+			id INTEGER PRIMARY KEY AUTOINCREMENT,
+			path TEXT NOT NULL,
+			hash TEXT,
+			status TEXT NOT NULL,
+			previous_path TEXT,
+			recorded_at DATETIME DEFAULT CURRENT_TIMESTAMP
+		);
+		INSERT INTO file_history (path, hash, status) VALUES ('note.md', 'abc', 'created');
+	`); err != nil {
+		t.Fatalf("failed to create legacy schema: %v", err)
+	}
+	legacy.Close()
+
+	index, err := NewSQLiteIndex(tmpDir)
+	if err != nil {
+		t.Fatalf("failed to open index: %v", err)
+	}
+
+	version, err := index.SchemaVersion()
+	if err != nil {
+		t.Fatalf("failed to read schema version: %v", err)
+	}
+	if version != migrations[len(migrations)-1].version {
+		t.Fatalf("expected schema version %d, got %d", migrations[len(migrations)-1].version, version)
+	}
+
+	var path string
+	var content sql.NullString
+	if err := index.GetDB().QueryRow(`SELECT path, content FROM file_history;`).Scan(&path, &content); err != nil {
+		t.Fatalf("expected legacy history to be kept: %v", err)
+	}
+	if path != "note.md" || content.Valid {
+		t.Errorf("unexpected legacy row: %s %v", path, content)
+	}
+	index.Close()
+
+	// Reopening an up-to-date database does not run migrations again
+	index, err = NewSQLiteIndex(tmpDir)
+	if err != nil {
+		t.Fatalf("failed to reopen index: %v", err)
+	}
+	defer index.Close()
+
+	var rows int
+	if err := index.GetDB().QueryRow(`SELECT COUNT(*) FROM schema_version;`).Scan(&rows); err != nil {
+		t.Fatalf("failed to count schema versions: %v", err)
+	}
+	if rows != 1 {
+		t.Errorf("expected a single schema_version row, got %d", rows)
+	}
+}
+
+func TestMigrations_AreOrdered(t *testing.T) {
+	for i, m := range migrations {
+		if m.version != i+1 {
+			t.Fatalf("migration %q has version %d, expected %d", m.name, m.version, i+1)
+		}
+	}
+}
@@ -0,0 +1,314 @@
+package search
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// RootPartition holds pages which do not belong to a top-level subtree
+const RootPartition = "_root"
+
+// SetPartitioned enables or disables the partitioned index mode.
+// In partitioned mode every top-level subtree gets its own FTS table,
+// queries fan out over all partitions and single partitions can be rebuilt
+// while the rest of the index stays searchable.
+// It must be called before pages are indexed.
+func (s *SQLiteIndex) SetPartitioned(enabled bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.optionsMu.Lock()
+	defer s.optionsMu.Unlock()
+	s.partitioned = enabled
+}
+
+// IsPartitioned returns true if the index is split into partitions
+func (s *SQLiteIndex) IsPartitioned() bool {
+	s.optionsMu.RLock()
+	defer s.optionsMu.RUnlock()
+	return s.partitioned
+}
+
+// PartitionForPath returns the partition of a page path, which is its top-level slug.
+func PartitionForPath(p string) string {
+	p = strings.Trim(normalizeHistoryPath(p), "/")
+	if p == "" {
+		return RootPartition
+	}
+	if idx := strings.Index(p, "/"); idx >= 0 {
+		p = p[:idx]
+	}
+	return p
+}
+
+func partitionTableName(partition string) string {
+	return "pages_" + HashString(partition)[:16]
+}
+
+// partitionTableLocked returns the FTS table of a partition and creates it when requested.
+// Lock must be held by the caller
+func (s *SQLiteIndex) partitionTableLocked(partition string, create bool) (string, error) {
+	if table, ok := s.partitions[partition]; ok {
+		return table, nil
+	}
+
+	var table string
+	err := s.db.QueryRow(`SELECT table_name FROM index_partitions WHERE name = ?;`, partition).Scan(&table)
+	if err != nil && err != sql.ErrNoRows {
+		return "", err
+	}
+
+	if err == sql.ErrNoRows {
+		if !create {
+			return "", nil
+		}
+		table = partitionTableName(partition)
+		if _, err := s.db.Exec(fmt.Sprintf(`
+			CREATE VIRTUAL TABLE IF NOT EXISTS %s USING fts5(
+				path UNINDEXED,
+				filepath UNINDEXED,
+				pageID,
+				title,
+				content
+			);
+		`, table)); err != nil {
+			return "", err
+		}
+		if _, err := s.db.Exec(`INSERT INTO index_partitions (name, table_name) VALUES (?, ?);`, partition, table); err != nil {
+			return "", err
+		}
+	}
+
+	if s.partitions == nil {
+		s.partitions = map[string]string{}
+	}
+	s.partitions[partition] = table
+	return table, nil
+}
+
+// indexPagePartitionedLocked writes a page into the partition of its path.
+// Lock must be held by the caller
+func (s *SQLiteIndex) indexPagePartitionedLocked(path, filePath, pageID, title, content string) error {
+	if _, err := s.removePagePartitionedLocked(pageID); err != nil {
+		return err
+	}
+
+	partition := PartitionForPath(path)
+	table, err := s.partitionTableLocked(partition, true)
+	if err != nil {
+		return err
+	}
+
+	if _, err := s.db.Exec(fmt.Sprintf(`
+		INSERT INTO %s (path, filepath, pageID, title, content)
+		VALUES (?, ?, ?, ?, ?);
+	`, table), path, filePath, pageID, title, content); err != nil {
+		return err
+	}
+
+	_, err = s.db.Exec(`
+		INSERT INTO page_partitions (pageID, filepath, partition)
+		VALUES (?, ?, ?)
+		ON CONFLICT(pageID) DO UPDATE SET filepath = excluded.filepath, partition = excluded.partition;
+	`, pageID, filePath, partition)
+	return err
+}
+
+// removePagePartitionedLocked removes a page from the partition it was indexed in.
+// Lock must be held by the caller
+func (s *SQLiteIndex) removePagePartitionedLocked(pageID string) (int64, error) {
+	var partition string
+	err := s.db.QueryRow(`SELECT partition FROM page_partitions WHERE pageID = ?;`, pageID).Scan(&partition)
+	if err == sql.ErrNoRows {
+		return 0, nil
+	}
+	if err != nil {
+		return 0, err
+	}
+
+	table, err := s.partitionTableLocked(partition, false)
+	if err != nil {
+		return 0, err
+	}
+
+	var affected int64
+	if table != "" {
+		query, args := deletePageQuery(table, pageID)
+		res, err := s.db.Exec(query, args...)
+		if err != nil {
+			return 0, err
+		}
+		affected, _ = res.RowsAffected()
+	}
+
+	_, err = s.db.Exec(`DELETE FROM page_partitions WHERE pageID = ?;`, pageID)
+	return affected, err
+}
+
+// removeFilePartitionedLocked removes all pages which were indexed from the given file.
+// Lock must be held by the caller
+func (s *SQLiteIndex) removeFilePartitionedLocked(filePath string) (int64, error) {
+	rows, err := s.db.Query(`SELECT pageID FROM page_partitions WHERE filepath = ?;`, filePath)
+	if err != nil {
+		return 0, err
+	}
+
+	var pageIDs []string
+	for rows.Next() {
+		var id string
+		if err := rows.Scan(&id); err != nil {
+			rows.Close()
+			return 0, err
+		}
+		pageIDs = append(pageIDs, id)
+	}
+	if err := rows.Close(); err != nil {
+		return 0, err
+	}
+
+	var total int64
+	for _, id := range pageIDs {
+		n, err := s.removePagePartitionedLocked(id)
+		if err != nil {
+			return total, err
+		}
+		total += n
+	}
+	return total, nil
+}
+
+// ListPartitions returns the names of all partitions sorted by name
+func (s *SQLiteIndex) ListPartitions() ([]string, error) {
+	if s.db == nil {
+		return nil, sql.ErrConnDone
+	}
+
+	names, err := s.listPartitions()
+	if err != nil {
+		return nil, err
+	}
+
+	result := make([]string, 0, len(names))
+	for name := range names {
+		result = append(result, name)
+	}
+	sort.Strings(result)
+	return result, nil
+}
+
+func (s *SQLiteIndex) listPartitions() (map[string]string, error) {
+	rows, err := s.db.Query(`SELECT name, table_name FROM index_partitions;`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	partitions := map[string]string{}
+	for rows.Next() {
+		var name, table string
+		if err := rows.Scan(&name, &table); err != nil {
+			return nil, err
+		}
+		partitions[name] = table
+	}
+	return partitions, rows.Err()
+}
+
+// ClearPartition removes all pages of a single partition.
+// All other partitions stay searchable.
+func (s *SQLiteIndex) ClearPartition(partition string) error {
+	if s.db == nil {
+		return sql.ErrConnDone
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	table, err := s.partitionTableLocked(partition, false)
+	if err != nil {
+		return err
+	}
+	if table != "" {
+		if _, err := s.db.Exec(fmt.Sprintf(`DELETE FROM %s;`, table)); err != nil {
+			return err
+		}
+	}
+
+	for _, table := range []string{"indexed_files", "index_results"} {
+		if _, err := s.db.Exec(fmt.Sprintf(`
+			DELETE FROM %s WHERE filepath IN (SELECT filepath FROM page_partitions WHERE partition = ?);
+		`, table), partition); err != nil {
+			return err
+		}
+	}
+	_, err = s.db.Exec(`DELETE FROM page_partitions WHERE partition = ?;`, partition)
+	return err
+}
+
+// dropPartitionsLocked removes all partition tables
+// Lock must be held by the caller
+func (s *SQLiteIndex) dropPartitionsLocked() error {
+	partitions, err := s.listPartitions()
+	if err != nil {
+		return err
+	}
+
+	for _, table := range partitions {
+		if _, err := s.db.Exec(fmt.Sprintf(`DROP TABLE IF EXISTS %s;`, table)); err != nil {
+			return err
+		}
+	}
+
+	if _, err := s.db.Exec(`DELETE FROM index_partitions;`); err != nil {
+		return err
+	}
+	if _, err := s.db.Exec(`DELETE FROM page_partitions;`); err != nil {
+		return err
+	}
+
+	s.partitions = nil
+	return nil
+}
+
+// searchPartitions fans the query out over all partitions and merges the results.
+// Each partition returns its best offset+limit hits, which is enough to build the requested window.
+func (s *SQLiteIndex) searchPartitions(ctx context.Context, query string, offset, limit int) (*SearchResult, error) {
+	partitions, err := s.listPartitions()
+	if err != nil {
+		return nil, err
+	}
+
+	total := 0
+	var merged []SearchResultItem
+	for _, table := range partitions {
+		count, items, err := s.searchTable(ctx, table, query, offset+limit, 0)
+		if err != nil {
+			return nil, err
+		}
+		total += count
+		merged = append(merged, items...)
+	}
+
+	// bm25 ranks are computed per table, but are close enough to merge the partitions
+	sort.SliceStable(merged, func(i, j int) bool {
+		return merged[i].Rank < merged[j].Rank
+	})
+
+	window := []SearchResultItem{}
+	if offset < len(merged) {
+		end := offset + limit
+		if end > len(merged) {
+			end = len(merged)
+		}
+		window = merged[offset:end]
+	}
+
+	return &SearchResult{
+		Count:  total,
+		Items:  rankResults(query, window),
+		Limit:  limit,
+		Offset: offset,
+	}, nil
+}
@@ -0,0 +1,63 @@
+package search
+
+import (
+	"fmt"
+	"path"
+	"path/filepath"
+	"strings"
+)
+
+// windowsPaths is true where paths use drive letters, backslashes and \\?\ long path prefixes
+var windowsPaths = filepath.Separator == '\\'
+
+// relativeDataPath returns the slash-separated path of a file relative to the data dir.
+// Paths reported by the file watcher, found by walking the data dir and configured by the
+// user may differ in separators, long path prefixes and the case of the drive letter on
+// Windows, they all resolve to the same relative path.
+func relativeDataPath(dataDir, fullPath string) (string, error) {
+	return relativePath(dataDir, fullPath, windowsPaths)
+}
+
+// relativePath implements relativeDataPath, windows selects the path rules independent of
+// the platform, so both can be tested everywhere
+func relativePath(dataDir, fullPath string, windows bool) (string, error) {
+	base := cleanPath(dataDir, windows)
+	target := cleanPath(fullPath, windows)
+
+	if base == target || (windows && strings.EqualFold(base, target)) {
+		return ".", nil
+	}
+	prefix := strings.TrimSuffix(base, "/") + "/"
+	if len(target) < len(prefix) {
+		return "", fmt.Errorf("%s is not below %s", fullPath, dataDir)
+	}
+	head := target[:len(prefix)]
+	if head != prefix && !(windows && strings.EqualFold(head, prefix)) {
+		return "", fmt.Errorf("%s is not below %s", fullPath, dataDir)
+	}
+	return target[len(prefix):], nil
+}
+
+// cleanPath returns the path with forward slashes and without dot segments. On Windows the
+// long path prefix is removed and the drive letter upper-cased: \\?\c:\data is C:/data and
+// \\?\UNC\server\share is //server/share.
+func cleanPath(p string, windows bool) string {
+	if !windows {
+		return path.Clean(p)
+	}
+	p = strings.ReplaceAll(p, `\`, "/")
+	switch {
+	case strings.HasPrefix(p, "//?/UNC/"):
+		p = "//" + p[len("//?/UNC/"):]
+	case strings.HasPrefix(p, "//?/"), strings.HasPrefix(p, "//./"):
+		p = p[len("//?/"):]
+	}
+	if len(p) >= 2 && p[1] == ':' {
+		p = strings.ToUpper(p[:1]) + p[1:]
+	}
+	// path.Clean would collapse the double slash of UNC paths
+	if strings.HasPrefix(p, "//") {
+		return "/" + path.Clean(p[1:])
+	}
+	return path.Clean(p)
+}
@@ -0,0 +1,66 @@
+package search
+
+import "testing"
+
+func TestRelativePath(t *testing.T) {
+	tests := []struct {
+		name     string
+		dataDir  string
+		fullPath string
+		windows  bool
+		want     string
+	}{
+		{"unix", "/data/root", "/data/root/docs/guide.md", false, "docs/guide.md"},
+		{"unix trailing slash", "/data/root/", "/data/root/guide.md", false, "guide.md"},
+		{"unix data dir", "/data/root", "/data/root", false, "."},
+		{"unix backslash is a name", "/data/root", `/data/root/a\b.md`, false, `a\b.md`},
+		{"windows", `C:\wiki\root`, `C:\wiki\root\docs\guide.md`, true, "docs/guide.md"},
+		{"windows forward slashes", `C:\wiki\root`, "C:/wiki/root/docs/guide.md", true, "docs/guide.md"},
+		{"windows drive letter case", `c:\wiki\root`, `C:\wiki\root\guide.md`, true, "guide.md"},
+		{"windows long path event", `C:\wiki\root`, `\\?\C:\wiki\root\docs\guide.md`, true, "docs/guide.md"},
+		{"windows long path data dir", `\\?\C:\wiki\root`, `C:\wiki\root\guide.md`, true, "guide.md"},
+		{"windows long unc path", `\\server\share\root`, `\\?\UNC\server\share\root\guide.md`, true, "guide.md"},
+		{"windows case insensitive", `C:\Wiki\Root`, `C:\wiki\root\Guide.md`, true, "Guide.md"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := relativePath(tt.dataDir, tt.fullPath, tt.windows)
+			if err != nil || got != tt.want {
+				t.Errorf("relativePath(%q, %q) = %q, %v, want %q", tt.dataDir, tt.fullPath, got, err, tt.want)
+			}
+		})
+	}
+}
+
+func TestRelativePath_Outside(t *testing.T) {
+	tests := []struct {
+		dataDir  string
+		fullPath string
+		windows  bool
+	}{
+		{"/data/root", "/data/rootfolder/guide.md", false},
+		{"/data/root", "/data/other/guide.md", false},
+		{"/data/root", "/data/root/../secret.md", false},
+		{`C:\wiki\root`, `D:\wiki\root\guide.md`, true},
+		{`C:\wiki\root`, `\\?\C:\wiki\rootfolder\guide.md`, true},
+	}
+	for _, tt := range tests {
+		if got, err := relativePath(tt.dataDir, tt.fullPath, tt.windows); err == nil {
+			t.Errorf("relativePath(%q, %q) = %q, expected an error", tt.dataDir, tt.fullPath, got)
+		}
+	}
+}
+
+func TestRoutePathFromFile(t *testing.T) {
+	tests := map[string]string{
+		"guide.md":            "guide",
+		"docs/index.md":       "docs",
+		"docs/setup/index.md": "docs/setup",
+		"index.md":            "index",
+	}
+	for rel, want := range tests {
+		if got := routePathFromFile(rel); got != want {
+			t.Errorf("routePathFromFile(%q) = %q, want %q", rel, got, want)
+		}
+	}
+}
@@ -0,0 +1,64 @@
+package search
+
+import (
+	"database/sql"
+	"path"
+	"time"
+)
+
+// PageSnapshot is a page as it was recorded in the history at a point in time
+type PageSnapshot struct {
+	FileHistoryEntry
+	// Route is the route of the page at that time, e.g. docs for docs/index.md
+	Route string `json:"route"`
+	// Title is the first heading of the snapshot, or else derived from the slug
+	Title string `json:"title"`
+}
+
+// SnapshotsAsOf returns the latest snapshot of every file which existed at the given time,
+// ordered by path. Files which were deleted or moved away before then are left out.
+// Entries removed by PruneHistory are missing, so older points in time may be incomplete.
+func (s *SQLiteIndex) SnapshotsAsOf(asOf time.Time) ([]PageSnapshot, error) {
+	if s.db == nil {
+		return nil, sql.ErrConnDone
+	}
+
+	before := asOf.UTC().Format("2006-01-02 15:04:05")
+	rows, err := s.db.Query(`
+		WITH latest AS (
+			SELECT MAX(id) AS id FROM file_history WHERE recorded_at <= ? GROUP BY path
+		)
+		SELECT fh.id, fh.path, fh.hash, fh.content, fh.status, fh.previous_path, fh.recorded_at
+		FROM file_history fh
+		JOIN latest l ON fh.id = l.id
+		WHERE fh.status != ?
+			AND NOT EXISTS (
+				SELECT 1 FROM file_history m
+				WHERE m.previous_path = fh.path AND m.id > fh.id AND m.recorded_at <= ?
+			)
+		ORDER BY fh.path;
+	`, before, FileStatusDeleted, before)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	snapshots := []PageSnapshot{}
+	for rows.Next() {
+		entry, err := scanHistoryEntry(rows)
+		if err != nil {
+			return nil, err
+		}
+		snapshots = append(snapshots, newPageSnapshot(*entry))
+	}
+	return snapshots, rows.Err()
+}
+
+func newPageSnapshot(entry FileHistoryEntry) PageSnapshot {
+	route := historyRoute(entry.Path)
+	return PageSnapshot{
+		FileHistoryEntry: entry,
+		Route:            route,
+		Title:            titleFromContent([]byte(entry.Content), path.Base(route)),
+	}
+}
@@ -0,0 +1,120 @@
+package search
+
+import (
+	"maps"
+	"os"
+	"path/filepath"
+	"slices"
+	"time"
+
+	"github.com/Gomez12/wiki/internal/core/ignore"
+)
+
+// defaultPollInterval is used when the file system events are not available
+const defaultPollInterval = 10 * time.Second
+
+// polledFile is the state of a Markdown file or a directory at the last poll
+type polledFile struct {
+	isDir   bool
+	size    int64
+	modTime int64
+}
+
+// startPolling starts the history recorder and the poller, which compares the files with
+// the previous poll every PollInterval and passes the differences on like watcher events
+func (w *Watcher) startPolling() {
+	w.pollStop = make(chan struct{})
+	files := w.pollFiles()
+
+	go w.runHistoryRecorder()
+
+	go func() {
+		defer close(w.eventsDone)
+		ticker := time.NewTicker(w.PollInterval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ticker.C:
+				files = w.poll(files)
+			case <-w.pollStop:
+				return
+			}
+		}
+	}()
+
+	watchLog.Info("started polling", "dir", w.DataDir, "interval", w.PollInterval)
+}
+
+// poll indexes the files which were created or modified since the previous poll and removes
+// those which are gone. It returns the current files for the next poll.
+func (w *Watcher) poll(previous map[string]polledFile) map[string]polledFile {
+	current := w.pollFiles()
+
+	ignorePath := filepath.Join(w.DataDir, ignore.Filename)
+	if current[ignorePath] != previous[ignorePath] {
+		// changed rules apply to the next scan, which records newly ignored files as removed
+		if opts := w.walkOptions(); opts.ignore != nil {
+			opts.ignore.Reload()
+			w.requestHistorySnapshot()
+			return w.pollFiles()
+		}
+	}
+
+	// removed first, so a moved file is detached from its old place before it is attached
+	dirsChanged := false
+	for _, p := range slices.Sorted(maps.Keys(previous)) {
+		if _, ok := current[p]; ok || p == ignorePath {
+			continue
+		}
+		if previous[p].isDir {
+			w.detachFromTree(p)
+			dirsChanged = true
+		} else {
+			w.fileRemoved(p)
+		}
+	}
+	for _, p := range slices.Sorted(maps.Keys(current)) {
+		before, ok := previous[p]
+		if p == ignorePath || (ok && before == current[p]) {
+			continue
+		}
+		if current[p].isDir {
+			dirsChanged = dirsChanged || !ok
+		} else {
+			w.fileChanged(p)
+		}
+	}
+	// let the full scan pair up the files of moved directories, like with events
+	if dirsChanged {
+		w.requestHistorySnapshot()
+	}
+	return current
+}
+
+// pollFiles returns the Markdown files and directories below the data dir and the ignore file
+func (w *Watcher) pollFiles() map[string]polledFile {
+	files := map[string]polledFile{}
+	err := walkFiles(w.DataDir, w.walkOptions(), func(p string, info os.FileInfo, err error) error {
+		if err != nil {
+			watchLog.Warn("walk error", "path", p, "error", err)
+			return nil
+		}
+		switch {
+		case p == w.DataDir:
+		case info.IsDir():
+			files[p] = polledFile{isDir: true}
+		case filepath.Ext(p) == ".md":
+			files[p] = polledFile{size: info.Size(), modTime: info.ModTime().UnixNano()}
+		}
+		return nil
+	})
+	if err != nil {
+		watchLog.Warn("walk error", "path", w.DataDir, "error", err)
+	}
+
+	ignorePath := filepath.Join(w.DataDir, ignore.Filename)
+	if info, err := os.Stat(ignorePath); err == nil {
+		files[ignorePath] = polledFile{size: info.Size(), modTime: info.ModTime().UnixNano()}
+	}
+	return files
+}
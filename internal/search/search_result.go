@@ -1,5 +1,7 @@
 package search
 
+import "time"
+
 type SearchResult struct {
 	Limit  int                `json:"limit"`
 	Offset int                `json:"offset"`
@@ -13,4 +15,10 @@ type SearchResultItem struct {
 	Path    string  `json:"path"`
 	Rank    float64 `json:"rank"`
 	Excerpt string  `json:"excerpt"`
+	// Deleted marks a page which no longer exists, found in its last history snapshot
+	Deleted bool `json:"deleted,omitempty"`
+	// HistoryEntryID is the snapshot of a deleted page, see /pages/history/{entryId}
+	HistoryEntryID int64 `json:"history_entry_id,omitempty"`
+	// DeletedAt is when the deletion of a deleted page was recorded
+	DeletedAt *time.Time `json:"deleted_at,omitempty"`
 }
@@ -0,0 +1,235 @@
+package search
+
+import (
+	"fmt"
+	"math"
+	"sort"
+	"strings"
+	"unicode"
+
+	"github.com/microcosm-cc/bluemonday"
+	"github.com/russross/blackfriday/v2"
+	"golang.org/x/text/unicode/norm"
+)
+
+// Tuning of the similar pages
+const (
+	// keyTerms is the number of terms with the highest TF-IDF weight a text is compared by
+	keyTerms = 12
+	// maxTermLookups limits the document frequency lookups to the most frequent terms of a text
+	maxTermLookups = 100
+	// titleTermWeight counts a term of the title like this many terms of the content
+	titleTermWeight = 3
+	// minScore drops pages which share only common words like "the"
+	minScore = 0.1
+	// DuplicateScore is the score from which a similar page is likely a duplicate
+	DuplicateScore = 0.8
+)
+
+// SimilarPage is a page sharing key terms with a text
+type SimilarPage struct {
+	PageID string `json:"pageId"`
+	Path   string `json:"path"`
+	Title  string `json:"title"`
+	// Score is the share of the TF-IDF weight of the key terms the page contains, 0 to 1
+	Score float64 `json:"score"`
+	// Duplicate is true if the score reaches DuplicateScore
+	Duplicate bool `json:"duplicate"`
+	// Terms are the shared key terms, the heaviest first
+	Terms []string `json:"terms"`
+}
+
+// PlainText returns the text of Markdown as it is indexed
+func PlainText(markdown string) string {
+	html := blackfriday.Run([]byte(markdown))
+	return bluemonday.StrictPolicy().Sanitize(string(html))
+}
+
+// SimilarPages returns the indexed pages most similar to a plain text, see PlainText. The
+// key terms of the text are weighted by TF-IDF; the pages matching them are scored by the
+// weight they share. The page excludeID, usually the page of the text, is left out.
+func (s *SQLiteIndex) SimilarPages(title, content, excludeID string, limit int) ([]SimilarPage, error) {
+	if s.db == nil {
+		return nil, fmt.Errorf("search index not available")
+	}
+
+	tables := []string{"pages"}
+	if s.IsPartitioned() {
+		partitions, err := s.listPartitions()
+		if err != nil {
+			return nil, err
+		}
+		tables = tables[:0]
+		for _, table := range partitions {
+			tables = append(tables, table)
+		}
+		sort.Strings(tables)
+	}
+
+	keys, err := s.keyTermsLocked(tables, title, content)
+	if err != nil || len(keys) == 0 {
+		return []SimilarPage{}, err
+	}
+	total := 0.0
+	quoted := make([]string, len(keys))
+	for i, key := range keys {
+		total += key.weight
+		quoted[i] = `"` + key.term + `"`
+	}
+	query := "{title content}: (" + strings.Join(quoted, " OR ") + ")"
+
+	// bm25 picks the candidates, they are scored by the shared weight
+	candidates := limit * 4
+	if candidates < 20 {
+		candidates = 20
+	}
+	similar := []SimilarPage{}
+	for _, table := range tables {
+		rows, err := s.db.Query(fmt.Sprintf(`
+			SELECT pageID, path, title, content
+			FROM %[1]s
+			WHERE %[1]s MATCH ?
+			ORDER BY bm25(%[1]s)
+			LIMIT ?;
+		`, table), query, candidates+1)
+		if err != nil {
+			return nil, err
+		}
+		for rows.Next() {
+			var page SimilarPage
+			var pageContent string
+			if err := rows.Scan(&page.PageID, &page.Path, &page.Title, &pageContent); err != nil {
+				rows.Close()
+				return nil, err
+			}
+			if page.PageID == excludeID {
+				continue
+			}
+			words := termCounts(page.Title + " " + pageContent)
+			shared := 0.0
+			page.Terms = []string{}
+			for _, key := range keys {
+				if words[key.term] > 0 {
+					shared += key.weight
+					page.Terms = append(page.Terms, key.term)
+				}
+			}
+			page.Score = math.Round(shared/total*1000) / 1000
+			page.Duplicate = page.Score >= DuplicateScore
+			if page.Score >= minScore {
+				similar = append(similar, page)
+			}
+		}
+		err = rows.Err()
+		rows.Close()
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	sort.SliceStable(similar, func(i, j int) bool {
+		if similar[i].Score != similar[j].Score {
+			return similar[i].Score > similar[j].Score
+		}
+		return similar[i].Path < similar[j].Path
+	})
+	if len(similar) > limit {
+		similar = similar[:limit]
+	}
+	return similar, nil
+}
+
+type keyTerm struct {
+	term   string
+	weight float64
+}
+
+// keyTermsLocked returns the terms of a text with the highest TF-IDF weight. Terms which
+// are in no page can't be shared and are left out. The IDF is smoothed, so terms of every
+// page keep a small weight in wikis with only a few pages.
+func (s *SQLiteIndex) keyTermsLocked(tables []string, title, content string) ([]keyTerm, error) {
+	counts := termCounts(content)
+	for term, n := range termCounts(title) {
+		counts[term] += n * titleTermWeight
+	}
+	if len(counts) == 0 {
+		return nil, nil
+	}
+
+	docs := 0
+	for _, table := range tables {
+		var n int
+		if err := s.db.QueryRow(fmt.Sprintf(`SELECT COUNT(*) FROM %s;`, table)).Scan(&n); err != nil {
+			return nil, err
+		}
+		docs += n
+	}
+
+	terms := make([]string, 0, len(counts))
+	for term := range counts {
+		terms = append(terms, term)
+	}
+	sort.Slice(terms, func(i, j int) bool {
+		if counts[terms[i]] != counts[terms[j]] {
+			return counts[terms[i]] > counts[terms[j]]
+		}
+		return terms[i] < terms[j]
+	})
+	if len(terms) > maxTermLookups {
+		terms = terms[:maxTermLookups]
+	}
+
+	keys := []keyTerm{}
+	for _, term := range terms {
+		df := 0
+		for _, table := range tables {
+			var n int
+			query := fmt.Sprintf(`SELECT COUNT(*) FROM %[1]s WHERE %[1]s MATCH ?;`, table)
+			if err := s.db.QueryRow(query, `{title content}: "`+term+`"`).Scan(&n); err != nil {
+				return nil, err
+			}
+			df += n
+		}
+		if df == 0 {
+			continue
+		}
+		keys = append(keys, keyTerm{term: term, weight: float64(counts[term]) * math.Log(float64(docs+1)/float64(df))})
+	}
+
+	sort.SliceStable(keys, func(i, j int) bool {
+		return keys[i].weight > keys[j].weight
+	})
+	if len(keys) > keyTerms {
+		keys = keys[:keyTerms]
+	}
+	return keys, nil
+}
+
+// termCounts splits a text into terms like the tokenizer of the index: lower case, without
+// diacritics, split at everything but letters and digits. Terms shorter than three characters
+// and numbers are left out.
+func termCounts(text string) map[string]int {
+	counts := map[string]int{}
+	var term strings.Builder
+	letters, digitsOnly := 0, true
+	flush := func() {
+		if letters >= 3 && !digitsOnly {
+			counts[term.String()]++
+		}
+		term.Reset()
+		letters, digitsOnly = 0, true
+	}
+	for _, r := range norm.NFD.String(strings.ToLower(text)) {
+		switch {
+		case unicode.Is(unicode.Mn, r):
+		case unicode.IsLetter(r) || unicode.IsDigit(r):
+			term.WriteRune(r)
+			letters++
+			digitsOnly = digitsOnly && unicode.IsDigit(r)
+		default:
+			flush()
+		}
+	}
+	flush()
+	return counts
+}
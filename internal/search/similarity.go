@@ -0,0 +1,236 @@
+package search
+
+import (
+	"hash/fnv"
+	"sort"
+	"strings"
+)
+
+// moveSimilarityThreshold is the minimum similarity for a missing and a new file
+// to be recorded as a move, when their content is not identical.
+const moveSimilarityThreshold = 0.6
+
+// shingleSize is the number of words per shingle
+const shingleSize = 3
+
+type similarMove struct {
+	entry int
+	prev  FileHistorySnapshot
+	score float64
+}
+
+// shingles returns the hashed word shingles of the content.
+// Content with fewer words than a shingle is treated as a single shingle.
+func shingles(content string) map[uint64]struct{} {
+	words := strings.Fields(strings.ToLower(content))
+	set := make(map[uint64]struct{})
+	if len(words) == 0 {
+		return set
+	}
+
+	n := len(words) - shingleSize + 1
+	if n < 1 {
+		n = 1
+	}
+	for i := 0; i < n; i++ {
+		end := i + shingleSize
+		if end > len(words) {
+			end = len(words)
+		}
+		h := fnv.New64a()
+		_, _ = h.Write([]byte(strings.Join(words[i:end], " ")))
+		set[h.Sum64()] = struct{}{}
+	}
+	return set
+}
+
+// similarity returns the jaccard similarity of two shingle sets
+func similarity(a, b map[uint64]struct{}) float64 {
+	if len(a) == 0 || len(b) == 0 {
+		return 0
+	}
+	if len(a) > len(b) {
+		a, b = b, a
+	}
+	shared := 0
+	for h := range a {
+		if _, ok := b[h]; ok {
+			shared++
+		}
+	}
+	return float64(shared) / float64(len(a)+len(b)-shared)
+}
+
+// matchSimilarMoves pairs created entries with missing snapshots whose content is similar enough.
+// The best scoring pairs are matched first and every entry and snapshot is used at most once.
+func matchSimilarMoves(entries []FileHistorySnapshot, created []int, missing map[string]FileHistorySnapshot) []similarMove {
+	var candidates []FileHistorySnapshot
+	for _, snap := range missing {
+		if snap.Status != FileStatusDeleted {
+			candidates = append(candidates, snap)
+		}
+	}
+	if len(candidates) == 0 || len(created) == 0 {
+		return nil
+	}
+
+	candidateShingles := make([]map[uint64]struct{}, len(candidates))
+	for i, snap := range candidates {
+		candidateShingles[i] = shingles(snap.Content)
+	}
+
+	var pairs []similarMove
+	for _, idx := range created {
+		entryShingles := shingles(entries[idx].Content)
+		for i, snap := range candidates {
+			// the similarity can't exceed the ratio of the set sizes
+			small, large := len(entryShingles), len(candidateShingles[i])
+			if small > large {
+				small, large = large, small
+			}
+			if large == 0 || float64(small)/float64(large) < moveSimilarityThreshold {
+				continue
+			}
+			if score := similarity(entryShingles, candidateShingles[i]); score >= moveSimilarityThreshold {
+				pairs = append(pairs, similarMove{entry: idx, prev: snap, score: score})
+			}
+		}
+	}
+
+	sort.Slice(pairs, func(i, j int) bool {
+		if pairs[i].score != pairs[j].score {
+			return pairs[i].score > pairs[j].score
+		}
+		if pairs[i].prev.Path != pairs[j].prev.Path {
+			return pairs[i].prev.Path < pairs[j].prev.Path
+		}
+		return entries[pairs[i].entry].Path < entries[pairs[j].entry].Path
+	})
+
+	usedEntries := map[int]bool{}
+	usedPaths := map[string]bool{}
+	var matches []similarMove
+	for _, pair := range pairs {
+		if usedEntries[pair.entry] || usedPaths[pair.prev.Path] {
+			continue
+		}
+		usedEntries[pair.entry] = true
+		usedPaths[pair.prev.Path] = true
+		matches = append(matches, pair)
+	}
+	return matches
+}
+
+// DuplicateDocument is a document checked for duplicates
+type DuplicateDocument struct {
+	ID      string
+	Content string
+}
+
+// DuplicateCluster is a group of documents with identical or near-identical content
+type DuplicateCluster struct {
+	// IDs are in the order of the documents
+	IDs []string
+	// Similarity is the lowest similarity of two documents which linked the group, 1 if
+	// all documents are identical
+	Similarity float64
+	Identical  bool
+}
+
+// FindDuplicates groups the documents whose content is identical, by the hash the file
+// history uses, or whose shingles reach the similarity threshold. Near-identical documents
+// are chained, so a group can hold documents which are less similar to each other. The
+// largest groups come first.
+func FindDuplicates(docs []DuplicateDocument, threshold float64) []DuplicateCluster {
+	parent := make([]int, len(docs))
+	for i := range parent {
+		parent[i] = i
+	}
+	var find func(i int) int
+	find = func(i int) int {
+		if parent[i] != i {
+			parent[i] = find(parent[i])
+		}
+		return parent[i]
+	}
+	lowest := map[int]float64{}
+	union := func(a, b int, score float64) {
+		ra, rb := find(a), find(b)
+		if ra == rb {
+			return
+		}
+		parent[rb] = ra
+		if s, ok := lowest[rb]; ok && s < score {
+			score = s
+		}
+		if s, ok := lowest[ra]; !ok || score < s {
+			lowest[ra] = score
+		}
+	}
+
+	// identical documents share a hash, only one of them is compared by shingles
+	hashes := make([]string, len(docs))
+	byHash := map[string]int{}
+	var unique []int
+	for i, doc := range docs {
+		hash := HashString(doc.Content)
+		hashes[i] = hash
+		if first, ok := byHash[hash]; ok {
+			union(first, i, 1)
+			continue
+		}
+		byHash[hash] = i
+		unique = append(unique, i)
+	}
+
+	sets := make([]map[uint64]struct{}, len(unique))
+	for k, i := range unique {
+		sets[k] = shingles(docs[i].Content)
+	}
+	for a := range unique {
+		for b := a + 1; b < len(unique); b++ {
+			// the similarity can't exceed the ratio of the set sizes
+			small, large := len(sets[a]), len(sets[b])
+			if small > large {
+				small, large = large, small
+			}
+			if large == 0 || float64(small)/float64(large) < threshold {
+				continue
+			}
+			if score := similarity(sets[a], sets[b]); score >= threshold {
+				union(unique[a], unique[b], score)
+			}
+		}
+	}
+
+	groups := map[int][]int{}
+	var roots []int
+	for i := range docs {
+		root := find(i)
+		if _, ok := groups[root]; !ok {
+			roots = append(roots, root)
+		}
+		groups[root] = append(groups[root], i)
+	}
+
+	clusters := []DuplicateCluster{}
+	for _, root := range roots {
+		members := groups[root]
+		if len(members) < 2 {
+			continue
+		}
+		cluster := DuplicateCluster{Similarity: lowest[root], Identical: true}
+		for _, i := range members {
+			cluster.IDs = append(cluster.IDs, docs[i].ID)
+			cluster.Identical = cluster.Identical && hashes[i] == hashes[members[0]]
+		}
+		clusters = append(clusters, cluster)
+	}
+	sort.SliceStable(clusters, func(i, j int) bool {
+		if len(clusters[i].IDs) != len(clusters[j].IDs) {
+			return len(clusters[i].IDs) > len(clusters[j].IDs)
+		}
+		return clusters[i].Similarity > clusters[j].Similarity
+	})
+	return clusters
+}
@@ -0,0 +1,48 @@
+package search
+
+import (
+	"fmt"
+	"net/url"
+	"time"
+)
+
+// SQLiteConfig holds the connection settings of the search database.
+// The pragmas are applied to every connection of the pool.
+type SQLiteConfig struct {
+	// JournalMode is the SQLite journal mode; WAL lets readers run concurrently with a writer
+	JournalMode string
+	// Synchronous controls how often SQLite syncs to disk; NORMAL is safe in WAL mode
+	Synchronous string
+	// BusyTimeout is how long a connection waits for a lock before failing with SQLITE_BUSY
+	BusyTimeout time.Duration
+	// MaxOpenConns limits the size of the connection pool
+	MaxOpenConns int
+}
+
+// DefaultSQLiteConfig returns the settings used by NewSQLiteIndex
+func DefaultSQLiteConfig() SQLiteConfig {
+	return SQLiteConfig{
+		JournalMode:  "WAL",
+		Synchronous:  "NORMAL",
+		BusyTimeout:  5 * time.Second,
+		MaxOpenConns: 4,
+	}
+}
+
+// dsn builds the data source name for the given database file
+func (c SQLiteConfig) dsn(file string) string {
+	params := url.Values{}
+	if c.BusyTimeout > 0 {
+		params.Add("_pragma", fmt.Sprintf("busy_timeout(%d)", c.BusyTimeout.Milliseconds()))
+	}
+	if c.JournalMode != "" {
+		params.Add("_pragma", fmt.Sprintf("journal_mode(%s)", c.JournalMode))
+	}
+	if c.Synchronous != "" {
+		params.Add("_pragma", fmt.Sprintf("synchronous(%s)", c.Synchronous))
+	}
+	if len(params) == 0 {
+		return file
+	}
+	return file + "?" + params.Encode()
+}
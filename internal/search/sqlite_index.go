@@ -1,27 +1,54 @@
 package search
 
 import (
+	"context"
 	"database/sql"
+	"fmt"
 	"path"
 	"strings"
 	"sync"
+	"unicode"
 
-	"github.com/microcosm-cc/bluemonday"
-	"github.com/russross/blackfriday/v2"
+	"github.com/Gomez12/wiki/internal/core/ignore"
 	_ "modernc.org/sqlite" // Import SQLite driver
 )
 
 type SQLiteIndex struct {
+	// mu serializes writes. Reads don't take it: in WAL mode they see the last committed state
+	// on another pool connection while a write transaction runs, and busy_timeout makes them
+	// wait for the short moments the database itself is locked, e.g. during a checkpoint.
 	mu         sync.Mutex
 	storageDir string
 	filename   string
+	config     SQLiteConfig
 	db         *sql.DB
+
+	// captureMu serializes the history captures from reading the latest snapshots to
+	// committing the new entries, so concurrent captures don't record a change twice
+	captureMu sync.Mutex
+	// optionsMu guards partitioned, followSymlinks and ignore, which reads use as well
+	optionsMu sync.RWMutex
+	// partitioned splits the full text index into one table per top-level subtree
+	partitioned bool
+	// partitions caches the FTS tables of the partitions, only used by writes
+	partitions map[string]string
+
+	// followSymlinks walks symlinked directories in the data dir
+	followSymlinks bool
+	// ignore excludes files in the data dir, nil ignores nothing
+	ignore *ignore.Rules
 }
 
 func NewSQLiteIndex(storageDir string) (*SQLiteIndex, error) {
+	return NewSQLiteIndexWithConfig(storageDir, DefaultSQLiteConfig())
+}
+
+// NewSQLiteIndexWithConfig opens the search database with custom connection settings
+func NewSQLiteIndexWithConfig(storageDir string, config SQLiteConfig) (*SQLiteIndex, error) {
 	s := &SQLiteIndex{
 		storageDir: storageDir,
 		filename:   "search.db",
+		config:     config,
 	}
 
 	err := s.Connect()
@@ -34,10 +61,8 @@ func NewSQLiteIndex(storageDir string) (*SQLiteIndex, error) {
 		return nil, err
 	}
 
-	// Delete all existing entries
-	// This is a cleanup step to ensure the table is empty before indexing new data
-	return s, s.Clear()
-
+	// The indexed pages are kept, the indexing at startup only indexes the changed files again
+	return s, nil
 }
 
 func (s *SQLiteIndex) Connect() error {
@@ -46,10 +71,14 @@ func (s *SQLiteIndex) Connect() error {
 		return nil
 	}
 	// Connect to the database
-	db, err := sql.Open("sqlite", path.Join(s.storageDir, s.filename))
+	db, err := sql.Open("sqlite", s.config.dsn(path.Join(s.storageDir, s.filename)))
 	if err != nil {
 		return err
 	}
+	if s.config.MaxOpenConns > 0 {
+		db.SetMaxOpenConns(s.config.MaxOpenConns)
+		db.SetMaxIdleConns(s.config.MaxOpenConns)
+	}
 	s.db = db
 	return nil
 }
@@ -59,86 +88,36 @@ func (s *SQLiteIndex) ensureSchema() error {
 	if err != nil {
 		return err
 	}
-	// Create the users table if it doesn't exist
-	if _, err = s.db.Exec(`
-		CREATE VIRTUAL TABLE IF NOT EXISTS pages USING fts5(
-			path UNINDEXED,
-			filepath UNINDEXED,
-			pageID,
-			title,
-			content
-		);
-	`); err != nil {
-		return err
-	}
+	return s.migrate()
+}
 
-	if _, err = s.db.Exec(`
-		CREATE TABLE IF NOT EXISTS file_history (
-			id INTEGER PRIMARY KEY AUTOINCREMENT,
-			path TEXT NOT NULL,
-			hash TEXT,
-			content TEXT,
-			status TEXT NOT NULL,
-			previous_path TEXT,
-			recorded_at DATETIME DEFAULT CURRENT_TIMESTAMP
-		);
-	`); err != nil {
-		return err
-	}
+// Clear removes all pages from the index, so the next indexing run indexes every file
+func (s *SQLiteIndex) Clear() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.clearLocked()
+}
 
-	if err := s.ensureHistoryContentColumn(); err != nil {
+// clearLocked removes all pages, the state of the indexed files and their results
+// Lock must be held by the caller
+func (s *SQLiteIndex) clearLocked() error {
+	if _, err := s.db.Exec(`DELETE FROM pages`); err != nil {
 		return err
 	}
-
-	if _, err = s.db.Exec(`CREATE INDEX IF NOT EXISTS idx_file_history_path ON file_history(path);`); err != nil {
+	if _, err := s.db.Exec(`DELETE FROM indexed_files`); err != nil {
 		return err
 	}
-
-	if _, err = s.db.Exec(`CREATE INDEX IF NOT EXISTS idx_file_history_hash ON file_history(hash);`); err != nil {
+	if _, err := s.db.Exec(`DELETE FROM index_results`); err != nil {
 		return err
 	}
-
-	return nil
-}
-
-func (s *SQLiteIndex) ensureHistoryContentColumn() error {
-	rows, err := s.db.Query(`PRAGMA table_info(file_history);`)
-	if err != nil {
-		return err
-	}
-	defer rows.Close()
-
-	hasContent := false
-	for rows.Next() {
-		var cid int
-		var name, ctype string
-		var notnull, pk int
-		var dflt interface{}
-		if err := rows.Scan(&cid, &name, &ctype, &notnull, &dflt, &pk); err != nil {
-			return err
-		}
-		if name == "content" {
-			hasContent = true
-		}
-	}
-	if rows.Err() != nil {
-		return rows.Err()
-	}
-
-	if hasContent {
-		return nil
-	}
-
-	_, err = s.db.Exec(`ALTER TABLE file_history ADD COLUMN content TEXT;`)
-	return err
-}
-
-func (s *SQLiteIndex) Clear() error {
-	_, err := s.db.Exec(`DELETE FROM pages`)
-	return err
+	return s.dropPartitionsLocked()
 }
 
+// Close closes the database. It waits for running writes, so no transaction is cut off.
 func (s *SQLiteIndex) Close() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
 	if s.db != nil {
 		err := s.db.Close()
 		if err != nil {
@@ -157,6 +136,11 @@ func (s *SQLiteIndex) GetDB() *sql.DB {
 }
 
 func (s *SQLiteIndex) IndexPage(path string, filePath string, pageID string, title string, content string) error {
+	return s.indexPlainText(path, filePath, pageID, title, PlainText(content))
+}
+
+// indexPlainText writes a page whose content was already converted with PlainText
+func (s *SQLiteIndex) indexPlainText(path string, filePath string, pageID string, title string, sanitized string) error {
 	if s.db == nil {
 		return sql.ErrConnDone
 	}
@@ -164,14 +148,16 @@ func (s *SQLiteIndex) IndexPage(path string, filePath string, pageID string, tit
 	s.mu.Lock()
 	defer s.mu.Unlock()
 
-	_, err := s.db.Exec(`DELETE FROM pages WHERE pageID = ?`, pageID)
+	if s.partitioned {
+		return s.indexPagePartitionedLocked(path, filePath, pageID, title, sanitized)
+	}
+
+	query, args := deletePageQuery("pages", pageID)
+	_, err := s.db.Exec(query, args...)
 	if err != nil {
 		return err
 	}
 
-	plaintext := string(blackfriday.Run([]byte(content)))
-	sanitized := bluemonday.StrictPolicy().Sanitize(plaintext)
-
 	_, err = s.db.Exec(`
 		INSERT INTO pages (path, filepath, pageID, title, content)
 		VALUES (?, ?, ?, ?, ?);
@@ -183,13 +169,39 @@ func (s *SQLiteIndex) IndexPage(path string, filePath string, pageID string, tit
 func (s *SQLiteIndex) RemovePage(pageID string) error {
 	s.mu.Lock()
 	defer s.mu.Unlock()
-	_, err := s.db.Exec(`DELETE FROM pages WHERE pageID = ?`, pageID)
+	if s.partitioned {
+		_, err := s.removePagePartitionedLocked(pageID)
+		return err
+	}
+	query, args := deletePageQuery("pages", pageID)
+	_, err := s.db.Exec(query, args...)
 	return err
 }
 
+// deletePageQuery returns the statement deleting a page from a full text table. WHERE pageID = ?
+// alone scans the whole table, so the ID is looked up with a full text query, which uses the
+// index; its tokens may match other IDs too, which the comparison excludes. IDs without letters
+// and digits have no tokens and fall back to the scan.
+func deletePageQuery(table, pageID string) (string, []any) {
+	if !strings.ContainsFunc(pageID, func(r rune) bool { return unicode.IsLetter(r) || unicode.IsDigit(r) }) {
+		return fmt.Sprintf(`DELETE FROM %s WHERE pageID = ?;`, table), []any{pageID}
+	}
+	match := `pageID:"` + strings.ReplaceAll(pageID, `"`, `""`) + `"`
+	return fmt.Sprintf(`DELETE FROM %s WHERE %s MATCH ? AND pageID = ?;`, table, table), []any{match, pageID}
+}
+
 func (s *SQLiteIndex) RemovePageByFilePath(filePath string) (int64, error) {
 	s.mu.Lock()
 	defer s.mu.Unlock()
+	if _, err := s.db.Exec(`DELETE FROM indexed_files WHERE filepath = ?`, filePath); err != nil {
+		return 0, err
+	}
+	if _, err := s.db.Exec(`DELETE FROM index_results WHERE filepath = ?`, filePath); err != nil {
+		return 0, err
+	}
+	if s.partitioned {
+		return s.removeFilePartitionedLocked(filePath)
+	}
 	res, err := s.db.Exec(`DELETE FROM pages WHERE filepath = ?`, filePath)
 	if err != nil {
 		return 0, err
@@ -197,37 +209,57 @@ func (s *SQLiteIndex) RemovePageByFilePath(filePath string) (int64, error) {
 	return res.RowsAffected()
 }
 
-func (s *SQLiteIndex) Search(query string, offset, limit int) (*SearchResult, error) {
+// Search runs a full text query. The query is interrupted when ctx ends, e.g. because the
+// client disconnected.
+func (s *SQLiteIndex) Search(ctx context.Context, query string, offset, limit int) (*SearchResult, error) {
 	if s.db == nil {
 		return nil, sql.ErrConnDone
 	}
 
+	if s.IsPartitioned() {
+		return s.searchPartitions(ctx, query, offset, limit)
+	}
+
 	sr := &SearchResult{}
 
-	// 1. Count total matches
-	var total int
-	countQuery := `SELECT COUNT(*) FROM pages WHERE pages MATCH ?;`
-	if err := s.db.QueryRow(countQuery, query).Scan(&total); err != nil {
+	total, results, err := s.searchTable(ctx, "pages", query, limit, offset)
+	if err != nil {
 		return nil, err
 	}
 
 	sr.Count = total
+	sr.Items = rankResults(query, results)
+	sr.Limit = limit
+	sr.Offset = offset
 
-	searchQuery := `
+	return sr, nil
+}
+
+// searchTable runs a full text query against a single FTS table
+// and returns the total number of matches and the requested window of results.
+func (s *SQLiteIndex) searchTable(ctx context.Context, table string, query string, limit, offset int) (int, []SearchResultItem, error) {
+	// 1. Count total matches
+	var total int
+	countQuery := fmt.Sprintf(`SELECT COUNT(*) FROM %[1]s WHERE %[1]s MATCH ?;`, table)
+	if err := s.db.QueryRowContext(ctx, countQuery, query).Scan(&total); err != nil {
+		return 0, nil, err
+	}
+
+	searchQuery := fmt.Sprintf(`
 		SELECT pageID, 
 			path, 
-			highlight(pages, 3, '<b>', '</b>') AS highlighted_title,
-			snippet(pages, 4, '<b>', '</b>', '...', 16) AS excerpt,
-			bm25(pages, 10.0, 1.0) AS rank
-		FROM pages
-		WHERE pages MATCH ?
+			highlight(%[1]s, 3, '<b>', '</b>') AS highlighted_title,
+			snippet(%[1]s, 4, '<b>', '</b>', '...', 16) AS excerpt,
+			bm25(%[1]s, 10.0, 1.0) AS rank
+		FROM %[1]s
+		WHERE %[1]s MATCH ?
 		ORDER BY rank ASC
 		LIMIT ? OFFSET ?;
-	`
+	`, table)
 
-	rows, err := s.db.Query(searchQuery, query, limit, offset)
+	rows, err := s.db.QueryContext(ctx, searchQuery, query, limit, offset)
 	if err != nil {
-		return nil, err
+		return 0, nil, err
 	}
 	defer rows.Close()
 
@@ -235,11 +267,16 @@ func (s *SQLiteIndex) Search(query string, offset, limit int) (*SearchResult, er
 	for rows.Next() {
 		var r SearchResultItem
 		if err := rows.Scan(&r.PageID, &r.Path, &r.Title, &r.Excerpt, &r.Rank); err != nil {
-			return nil, err
+			return 0, nil, err
 		}
 		results = append(results, r)
 	}
 
+	return total, results, rows.Err()
+}
+
+// rankResults boosts results matching the query in their title and sorts them by rank
+func rankResults(query string, results []SearchResultItem) []SearchResultItem {
 	if results == nil {
 		results = []SearchResultItem{}
 	}
@@ -262,9 +299,5 @@ func (s *SQLiteIndex) Search(query string, offset, limit int) (*SearchResult, er
 		}
 	}
 
-	sr.Items = results
-	sr.Limit = limit
-	sr.Offset = offset
-
-	return sr, err
+	return results
 }
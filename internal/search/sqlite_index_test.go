@@ -1,8 +1,13 @@
 package search
 
 import (
+	"context"
+	"errors"
+	"fmt"
 	"strings"
+	"sync"
 	"testing"
+	"time"
 )
 
 func TestSQLiteIndex_IndexPage(t *testing.T) {
@@ -46,6 +51,46 @@ func TestSQLiteIndex_IndexPage(t *testing.T) {
 	}
 }
 
+func TestSQLiteIndex_IndexPageSimilarIDs(t *testing.T) {
+	index, err := NewSQLiteIndex(t.TempDir())
+	if err != nil {
+		t.Fatalf("failed to create SQLiteIndex: %v", err)
+	}
+	defer index.Close()
+
+	// the IDs share tokens or have none, only the exact ID may be replaced and removed
+	ids := []string{"abc-DEF", "abc-DEF-x", "ABC_def", "-_-", `a"b`}
+	for _, id := range ids {
+		if err := index.IndexPage("/"+id, id+".md", id, "Title", "Shared content."); err != nil {
+			t.Fatalf("IndexPage %s failed: %v", id, err)
+		}
+	}
+	for _, id := range ids {
+		if err := index.IndexPage("/"+id, id+".md", id, "Title", "Shared content, again."); err != nil {
+			t.Fatalf("IndexPage %s failed: %v", id, err)
+		}
+	}
+	count := func() int {
+		var n int
+		if err := index.GetDB().QueryRow(`SELECT count(*) FROM pages`).Scan(&n); err != nil {
+			t.Fatalf("count failed: %v", err)
+		}
+		return n
+	}
+	if n := count(); n != len(ids) {
+		t.Errorf("expected %d pages after indexing them again, got %d", len(ids), n)
+	}
+
+	for _, id := range []string{"abc-DEF", "-_-"} {
+		if err := index.RemovePage(id); err != nil {
+			t.Fatalf("RemovePage %s failed: %v", id, err)
+		}
+	}
+	if n := count(); n != len(ids)-2 {
+		t.Errorf("expected %d pages after removing two, got %d", len(ids)-2, n)
+	}
+}
+
 func TestSQLiteIndex_Search(t *testing.T) {
 	tmpDir := t.TempDir()
 
@@ -67,7 +112,7 @@ func TestSQLiteIndex_Search(t *testing.T) {
 	}
 
 	// Perform search
-	result, err := index.Search("content:search", 0, 10)
+	result, err := index.Search(t.Context(), "content:search", 0, 10)
 	if err != nil {
 		t.Fatalf("search failed: %v", err)
 	}
@@ -86,3 +131,339 @@ func TestSQLiteIndex_Search(t *testing.T) {
 		t.Errorf("expected PageID beta2, got %s", item.PageID)
 	}
 }
+
+func TestSQLiteIndex_SearchCancelled(t *testing.T) {
+	index, err := NewSQLiteIndex(t.TempDir())
+	if err != nil {
+		t.Fatalf("failed to create SQLiteIndex: %v", err)
+	}
+	defer index.Close()
+	if err := index.IndexPage("notes/alpha", "notes/alpha.md", "alpha1", "Alpha", "Some content."); err != nil {
+		t.Fatalf("failed to index page: %v", err)
+	}
+
+	ctx, cancel := context.WithCancel(t.Context())
+	cancel()
+	if _, err := index.Search(ctx, "content", 0, 10); !errors.Is(err, context.Canceled) {
+		t.Errorf("Expected context.Canceled, got %v", err)
+	}
+}
+
+func TestSQLiteIndex_PartitionedSearch(t *testing.T) {
+	tmpDir := t.TempDir()
+
+	index, err := NewSQLiteIndex(tmpDir)
+	if err != nil {
+		t.Fatalf("failed to create SQLiteIndex: %v", err)
+	}
+	defer index.Close()
+	index.SetPartitioned(true)
+
+	if err := index.IndexPage("/docs/alpha", "docs/alpha.md", "alpha1", "Alpha", "Partitioned search content."); err != nil {
+		t.Fatalf("failed to index alpha page: %v", err)
+	}
+	if err := index.IndexPage("/notes/beta", "notes/beta.md", "beta2", "Beta", "More partitioned search content."); err != nil {
+		t.Fatalf("failed to index beta page: %v", err)
+	}
+
+	partitions, err := index.ListPartitions()
+	if err != nil {
+		t.Fatalf("failed to list partitions: %v", err)
+	}
+	if len(partitions) != 2 || partitions[0] != "docs" || partitions[1] != "notes" {
+		t.Fatalf("expected partitions [docs notes], got %v", partitions)
+	}
+
+	result, err := index.Search(t.Context(), "partitioned", 0, 10)
+	if err != nil {
+		t.Fatalf("search failed: %v", err)
+	}
+	if result.Count != 2 || len(result.Items) != 2 {
+		t.Fatalf("expected 2 results across partitions, got %d (%d items)", result.Count, len(result.Items))
+	}
+
+	// Re-indexing a page into another subtree moves it to the new partition
+	if err := index.IndexPage("/notes/alpha", "notes/alpha.md", "alpha1", "Alpha", "Partitioned search content."); err != nil {
+		t.Fatalf("failed to re-index alpha page: %v", err)
+	}
+
+	if err := index.ClearPartition("docs"); err != nil {
+		t.Fatalf("failed to clear partition: %v", err)
+	}
+	result, err = index.Search(t.Context(), "partitioned", 0, 10)
+	if err != nil {
+		t.Fatalf("search failed: %v", err)
+	}
+	if result.Count != 2 {
+		t.Errorf("expected moved page to survive clearing its old partition, got %d results", result.Count)
+	}
+
+	if err := index.ClearPartition("notes"); err != nil {
+		t.Fatalf("failed to clear partition: %v", err)
+	}
+	result, err = index.Search(t.Context(), "partitioned", 0, 10)
+	if err != nil {
+		t.Fatalf("search failed: %v", err)
+	}
+	if result.Count != 0 {
+		t.Errorf("expected no results after clearing all partitions, got %d", result.Count)
+	}
+
+	if n, err := index.RemovePageByFilePath("notes/alpha.md"); err != nil || n != 0 {
+		t.Errorf("expected nothing to remove after clear, got %d (%v)", n, err)
+	}
+}
+
+func TestSQLiteIndex_AppliesPragmas(t *testing.T) {
+	index, err := NewSQLiteIndex(t.TempDir())
+	if err != nil {
+		t.Fatalf("failed to create SQLiteIndex: %v", err)
+	}
+	defer index.Close()
+
+	var journalMode string
+	if err := index.GetDB().QueryRow(`PRAGMA journal_mode;`).Scan(&journalMode); err != nil {
+		t.Fatalf("failed to read journal_mode: %v", err)
+	}
+	if !strings.EqualFold(journalMode, "wal") {
+		t.Errorf("expected WAL journal mode, got %s", journalMode)
+	}
+
+	var busyTimeout int
+	if err := index.GetDB().QueryRow(`PRAGMA busy_timeout;`).Scan(&busyTimeout); err != nil {
+		t.Fatalf("failed to read busy_timeout: %v", err)
+	}
+	if busyTimeout != 5000 {
+		t.Errorf("expected busy_timeout 5000, got %d", busyTimeout)
+	}
+
+	// synchronous=NORMAL is reported as 1
+	var synchronous int
+	if err := index.GetDB().QueryRow(`PRAGMA synchronous;`).Scan(&synchronous); err != nil {
+		t.Fatalf("failed to read synchronous: %v", err)
+	}
+	if synchronous != 1 {
+		t.Errorf("expected synchronous NORMAL (1), got %d", synchronous)
+	}
+}
+
+func TestSQLiteIndex_ConcurrentReadsAndWrites(t *testing.T) {
+	index, err := NewSQLiteIndex(t.TempDir())
+	if err != nil {
+		t.Fatalf("failed to create SQLiteIndex: %v", err)
+	}
+	defer index.Close()
+
+	var wg sync.WaitGroup
+	errs := make(chan error, 100)
+	for i := 0; i < 4; i++ {
+		wg.Add(2)
+		go func(i int) {
+			defer wg.Done()
+			for j := 0; j < 10; j++ {
+				id := fmt.Sprintf("page-%d-%d", i, j)
+				if err := index.IndexPage("docs/"+id, "docs/"+id+".md", id, "Concurrent", "concurrent content"); err != nil {
+					errs <- err
+				}
+			}
+		}(i)
+		go func() {
+			defer wg.Done()
+			for j := 0; j < 10; j++ {
+				if _, err := index.Search(t.Context(), "concurrent", 0, 10); err != nil {
+					errs <- err
+				}
+			}
+		}()
+	}
+	wg.Wait()
+	close(errs)
+
+	for err := range errs {
+		t.Errorf("unexpected error: %v", err)
+	}
+
+	result, err := index.Search(t.Context(), "concurrent", 0, 100)
+	if err != nil {
+		t.Fatalf("search failed: %v", err)
+	}
+	if result.Count != 40 {
+		t.Errorf("expected 40 results, got %d", result.Count)
+	}
+}
+
+func TestSQLiteIndex_SearchDuringWrite(t *testing.T) {
+	index, err := NewSQLiteIndex(t.TempDir())
+	if err != nil {
+		t.Fatalf("failed to create SQLiteIndex: %v", err)
+	}
+	defer index.Close()
+	if err := index.IndexPage("docs/alpha", "docs/alpha.md", "alpha", "Alpha", "committed content"); err != nil {
+		t.Fatalf("IndexPage failed: %v", err)
+	}
+
+	// a long write, like a batch, holds the lock and an open transaction
+	index.mu.Lock()
+	tx, err := index.db.Begin()
+	if err != nil {
+		index.mu.Unlock()
+		t.Fatalf("failed to begin transaction: %v", err)
+	}
+	defer func() {
+		_ = tx.Rollback()
+		index.mu.Unlock()
+	}()
+	if _, err := tx.Exec(`INSERT INTO pages (path, filepath, pageID, title, content) VALUES ('docs/beta', 'docs/beta.md', 'beta', 'Beta', 'uncommitted content');`); err != nil {
+		t.Fatalf("insert failed: %v", err)
+	}
+
+	ctx, cancel := context.WithTimeout(t.Context(), 5*time.Second)
+	defer cancel()
+	result, err := index.Search(ctx, "content", 0, 10)
+	if err != nil {
+		t.Fatalf("expected the search to run during the write, got %v", err)
+	}
+	if result.Count != 1 || result.Items[0].PageID != "alpha" {
+		t.Errorf("expected only the committed page, got %+v", result.Items)
+	}
+}
+
+func TestSQLiteIndex_SimilarPages(t *testing.T) {
+	for _, partitioned := range []bool{false, true} {
+		t.Run(fmt.Sprintf("partitioned=%v", partitioned), func(t *testing.T) {
+			index, err := NewSQLiteIndex(t.TempDir())
+			if err != nil {
+				t.Fatalf("failed to create SQLiteIndex: %v", err)
+			}
+			defer index.Close()
+			index.SetPartitioned(partitioned)
+
+			pages := []struct{ path, id, title, content string }{
+				{"ops/backup", "backup", "Backup", "Nightly postgres backups are restored with pgrestore on the standby server."},
+				{"ops/restore", "restore", "Restore", "Restore a postgres backup with pgrestore on the standby server."},
+				{"ops/deploy", "deploy", "Deploy", "Deployments run on the standby server after the nightly backups."},
+				{"team/lunch", "lunch", "Lunch", "The team meets for lunch on Fridays."},
+			}
+			for _, p := range pages {
+				if err := index.IndexPage(p.path, p.path+".md", p.id, p.title, p.content); err != nil {
+					t.Fatalf("IndexPage failed: %v", err)
+				}
+			}
+
+			similar, err := index.SimilarPages("Backup", PlainText(pages[0].content), "backup", 5)
+			if err != nil {
+				t.Fatalf("SimilarPages failed: %v", err)
+			}
+			if len(similar) < 2 || similar[0].PageID != "restore" || similar[1].PageID != "deploy" {
+				t.Fatalf("expected restore before deploy, got %+v", similar)
+			}
+			if similar[0].Score <= similar[1].Score || similar[0].Score > 1 {
+				t.Errorf("unexpected scores: %+v", similar)
+			}
+			for _, page := range similar {
+				if page.PageID == "backup" || page.PageID == "lunch" {
+					t.Errorf("unexpected page %q", page.PageID)
+				}
+			}
+
+			// a new page with the same text is a likely duplicate
+			similar, err = index.SimilarPages("Restore", "Restore a postgres backup with pgrestore on the standby server.", "", 1)
+			if err != nil {
+				t.Fatalf("SimilarPages failed: %v", err)
+			}
+			if len(similar) != 1 || similar[0].PageID != "restore" || !similar[0].Duplicate {
+				t.Errorf("expected a duplicate of restore, got %+v", similar)
+			}
+		})
+	}
+}
+
+func TestSQLiteIndex_CheckIntegrity(t *testing.T) {
+	index, err := NewSQLiteIndex(t.TempDir())
+	if err != nil {
+		t.Fatalf("failed to create SQLiteIndex: %v", err)
+	}
+	defer index.Close()
+
+	if err := index.IndexPage("docs", "docs.md", "1", "Docs", "hello world"); err != nil {
+		t.Fatalf("IndexPage failed: %v", err)
+	}
+	if problems, err := index.CheckIntegrity(); err != nil || len(problems) != 0 {
+		t.Fatalf("expected an intact database, got %v, %v", problems, err)
+	}
+
+	// the inverted index still lists the removed content
+	if _, err := index.GetDB().Exec(`DELETE FROM pages_content;`); err != nil {
+		t.Fatalf("failed to damage the index: %v", err)
+	}
+	problems, err := index.CheckIntegrity()
+	if err != nil {
+		t.Fatalf("CheckIntegrity failed: %v", err)
+	}
+	if len(problems) == 0 || !strings.Contains(strings.Join(problems, "\n"), "pages") {
+		t.Fatalf("expected the damaged pages table, got %v", problems)
+	}
+
+	if err := index.RebuildIndexTables(); err != nil {
+		t.Fatalf("RebuildIndexTables failed: %v", err)
+	}
+	if problems, err := index.CheckIntegrity(); err != nil || len(problems) != 0 {
+		t.Fatalf("expected the rebuilt database to be intact, got %v, %v", problems, err)
+	}
+	if err := index.IndexPage("docs", "docs.md", "1", "Docs", "hello world"); err != nil {
+		t.Fatalf("IndexPage failed: %v", err)
+	}
+	if res, err := index.Search(t.Context(), "hello", 0, 10); err != nil || res.Count != 1 {
+		t.Errorf("expected the page to be searchable again, got %+v, %v", res, err)
+	}
+}
+
+func TestSQLiteIndex_IndexErrors(t *testing.T) {
+	tmpDir := t.TempDir()
+	index, err := NewSQLiteIndex(tmpDir)
+	if err != nil {
+		t.Fatalf("Failed to init SQLiteIndex: %v", err)
+	}
+
+	index.recordIndexResult("docs/broken.md", errors.New("invalid front matter"))
+	index.recordIndexResult("docs/gone.md", errors.New("could not attach"))
+	batch := index.NewBatch(0, nil)
+	batch.Add("docs/fine", "docs/fine.md", "fine", "Fine", "Some content")
+	batch.Flush()
+
+	results, err := index.IndexErrors()
+	if err != nil {
+		t.Fatalf("IndexErrors failed: %v", err)
+	}
+	if len(results) != 2 || results[0].Path != "docs/broken.md" || results[0].Message != "invalid front matter" || results[0].IndexedAt.IsZero() {
+		t.Fatalf("expected the two failed files, got %+v", results)
+	}
+
+	// the results are kept in the database
+	if err := index.Close(); err != nil {
+		t.Fatal(err)
+	}
+	index, err = NewSQLiteIndex(tmpDir)
+	if err != nil {
+		t.Fatalf("Failed to init SQLiteIndex: %v", err)
+	}
+	defer index.Close()
+
+	// a successful run and the removal of the file clear the error
+	index.recordIndexResult("docs/broken.md", nil)
+	if _, err := index.RemovePageByFilePath("docs/gone.md"); err != nil {
+		t.Fatalf("RemovePageByFilePath failed: %v", err)
+	}
+	results, err = index.IndexErrors()
+	if err != nil {
+		t.Fatalf("IndexErrors failed: %v", err)
+	}
+	if len(results) != 0 {
+		t.Errorf("expected no errors, got %+v", results)
+	}
+
+	var status string
+	if err := index.GetDB().QueryRow(`SELECT status FROM index_results WHERE filepath = ?`, "docs/fine.md").Scan(&status); err != nil || status != IndexResultOK {
+		t.Errorf("expected the result of the batch write, got %q (%v)", status, err)
+	}
+}
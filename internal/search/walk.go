@@ -0,0 +1,150 @@
+package search
+
+import (
+	"errors"
+	"io/fs"
+	"os"
+	"path/filepath"
+
+	"github.com/Gomez12/wiki/internal/core/ignore"
+)
+
+// SetFollowSymlinks makes indexing, the history and the watcher follow symlinked directories
+// in the data dir, so content shared via symlinks is searchable and tracked. Links pointing
+// to one of their parent directories are skipped.
+// It must be called before the indexer or the watcher are started.
+func (s *SQLiteIndex) SetFollowSymlinks(enabled bool) {
+	s.optionsMu.Lock()
+	defer s.optionsMu.Unlock()
+	s.followSymlinks = enabled
+}
+
+// FollowsSymlinks returns true if symlinked directories are followed
+func (s *SQLiteIndex) FollowsSymlinks() bool {
+	s.optionsMu.RLock()
+	defer s.optionsMu.RUnlock()
+	return s.followSymlinks
+}
+
+// SetIgnoreRules excludes the files matching the rules from indexing, the history and the
+// watcher. The rules are relative to the data dir.
+// It must be called before the indexer or the watcher are started.
+func (s *SQLiteIndex) SetIgnoreRules(rules *ignore.Rules) {
+	s.optionsMu.Lock()
+	defer s.optionsMu.Unlock()
+	s.ignore = rules
+}
+
+// walkOptions configure which files below the data dir are walked
+type walkOptions struct {
+	dataDir        string
+	followSymlinks bool
+	ignore         *ignore.Rules
+}
+
+// walkOptions returns the options for walking the data dir
+func (s *SQLiteIndex) walkOptions(dataDir string) walkOptions {
+	s.optionsMu.RLock()
+	defer s.optionsMu.RUnlock()
+	return walkOptions{dataDir: dataDir, followSymlinks: s.followSymlinks, ignore: s.ignore}
+}
+
+// ignored returns true if the path is excluded by the ignore rules
+func (o walkOptions) ignored(p string, isDir bool) bool {
+	if o.ignore == nil {
+		return false
+	}
+	rel, err := relativeDataPath(o.dataDir, p)
+	return err == nil && o.ignore.Match(rel, isDir)
+}
+
+// walkFiles walks the tree below root like filepath.Walk, in lexical order. Ignored files
+// and directories are skipped. Symlinked files are passed with the info of their target.
+// Symlinked directories are walked below the path of the link if followSymlinks is set, and
+// passed like a file with the info of the link otherwise. Broken links are passed with the
+// info of the link.
+func walkFiles(root string, opts walkOptions, fn filepath.WalkFunc) error {
+	info, err := os.Stat(root)
+	if err != nil {
+		err = fn(root, nil, err)
+	} else if !opts.ignored(root, info.IsDir()) {
+		w := &walker{opts: opts, fn: fn, active: map[string]bool{}}
+		err = w.walk(root, info)
+	}
+	if errors.Is(err, filepath.SkipDir) || errors.Is(err, filepath.SkipAll) {
+		return nil
+	}
+	return err
+}
+
+type walker struct {
+	opts walkOptions
+	fn   filepath.WalkFunc
+	// active holds the real paths of the directories being walked, a link to one of them loops
+	active map[string]bool
+}
+
+func (w *walker) walk(p string, info fs.FileInfo) error {
+	if !info.IsDir() {
+		return w.fn(p, info, nil)
+	}
+
+	real, err := filepath.EvalSymlinks(p)
+	if err != nil {
+		return w.fn(p, info, err)
+	}
+	if w.active[real] {
+		indexerLog.Warn("skipping symlink loop", "path", p, "target", real)
+		return nil
+	}
+	w.active[real] = true
+	defer delete(w.active, real)
+
+	if err := w.fn(p, info, nil); err != nil {
+		if errors.Is(err, filepath.SkipDir) {
+			return nil
+		}
+		return err
+	}
+
+	entries, err := os.ReadDir(p)
+	if err != nil {
+		if err := w.fn(p, info, err); err != nil && !errors.Is(err, filepath.SkipDir) {
+			return err
+		}
+		return nil
+	}
+	for _, entry := range entries {
+		child := filepath.Join(p, entry.Name())
+		childInfo, err := w.info(child, entry)
+		if err != nil {
+			if err := w.fn(child, nil, err); err != nil && !errors.Is(err, filepath.SkipDir) {
+				return err
+			}
+			continue
+		}
+		if w.opts.ignored(child, childInfo.IsDir()) {
+			continue
+		}
+		if err := w.walk(child, childInfo); err != nil {
+			// only files return SkipDir here, it skips the rest of the directory
+			if errors.Is(err, filepath.SkipDir) {
+				return nil
+			}
+			return err
+		}
+	}
+	return nil
+}
+
+// info returns the info of the entry, following symlinks as configured
+func (w *walker) info(p string, entry fs.DirEntry) (fs.FileInfo, error) {
+	if entry.Type()&fs.ModeSymlink == 0 {
+		return entry.Info()
+	}
+	target, err := os.Stat(p)
+	if err != nil || (target.IsDir() && !w.opts.followSymlinks) {
+		return entry.Info()
+	}
+	return target, nil
+}
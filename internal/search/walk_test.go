@@ -0,0 +1,140 @@
+package search
+
+import (
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"testing"
+
+	"github.com/Gomez12/wiki/internal/core/ignore"
+)
+
+// setupSymlinkedData creates a data dir with a symlinked shared folder, a symlinked file
+// and a link back to the data dir
+func setupSymlinkedData(t *testing.T) string {
+	t.Helper()
+	tmpDir := t.TempDir()
+	dataDir := filepath.Join(tmpDir, "root")
+	shared := filepath.Join(tmpDir, "shared")
+	createTestFiles(t, dataDir, map[string]string{"index.md": "# Home"})
+	createTestFiles(t, shared, map[string]string{"guide.md": "# Guide", "nested/faq.md": "# FAQ"})
+
+	links := map[string]string{
+		filepath.Join(dataDir, "shared"):        shared,
+		filepath.Join(dataDir, "guide-link.md"): filepath.Join(shared, "guide.md"),
+		filepath.Join(shared, "nested", "back"): dataDir,
+		filepath.Join(dataDir, "broken.md"):     filepath.Join(tmpDir, "missing.md"),
+	}
+	for link, target := range links {
+		if err := os.Symlink(target, link); err != nil {
+			t.Skipf("symlinks are not supported: %v", err)
+		}
+	}
+	return dataDir
+}
+
+func walkedFiles(t *testing.T, dataDir string, followSymlinks bool) []string {
+	t.Helper()
+	var files []string
+	err := walkFiles(dataDir, walkOptions{dataDir: dataDir, followSymlinks: followSymlinks}, func(p string, info os.FileInfo, err error) error {
+		if err != nil {
+			t.Fatalf("walk error: %v", err)
+		}
+		if !info.IsDir() {
+			rel, _ := relativeDataPath(dataDir, p)
+			files = append(files, rel)
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("walkFiles failed: %v", err)
+	}
+	sort.Strings(files)
+	return files
+}
+
+func TestWalkFiles_Symlinks(t *testing.T) {
+	dataDir := setupSymlinkedData(t)
+
+	got := strings.Join(walkedFiles(t, dataDir, false), ",")
+	if want := "broken.md,guide-link.md,index.md,shared"; got != want {
+		t.Errorf("without following: got %s, want %s", got, want)
+	}
+
+	// the link back to the data dir is skipped instead of looping
+	got = strings.Join(walkedFiles(t, dataDir, true), ",")
+	if want := "broken.md,guide-link.md,index.md,shared/guide.md,shared/nested/faq.md"; got != want {
+		t.Errorf("following: got %s, want %s", got, want)
+	}
+}
+
+func TestCaptureFileHistory_FollowSymlinks(t *testing.T) {
+	dataDir := setupSymlinkedData(t)
+
+	index, err := NewSQLiteIndex(filepath.Dir(dataDir))
+	if err != nil {
+		t.Fatalf("failed to create SQLiteIndex: %v", err)
+	}
+	defer index.Close()
+	index.SetFollowSymlinks(true)
+
+	mustCapture(t, index, dataDir)
+	paths := map[string]bool{}
+	for _, entry := range readHistoryEntries(t, index) {
+		paths[entry.path] = true
+	}
+	for _, want := range []string{"index.md", "guide-link.md", "shared/guide.md", "shared/nested/faq.md"} {
+		if !paths[want] {
+			t.Errorf("expected history of %s, got %v", want, paths)
+		}
+	}
+
+	// unchanged files reached through the link are not recorded again
+	mustCapture(t, index, dataDir)
+	if entries := readHistoryEntries(t, index); len(entries) != len(paths) {
+		t.Errorf("expected no new entries, got %d", len(entries)-len(paths))
+	}
+}
+
+func TestIndexerAndHistory_IgnoreRules(t *testing.T) {
+	tmpDir := t.TempDir()
+	dataDir := filepath.Join(tmpDir, "root")
+	createTestFiles(t, dataDir, map[string]string{
+		ignore.Filename:                   "node_modules/\n/drafts/\n",
+		"index.md":                        "# Home",
+		"drafts/idea.md":                  "# Idea",
+		"docs/guide.md":                   "# Guide",
+		"docs/node_modules/pkg/readme.md": "# Package",
+	})
+
+	index, err := NewSQLiteIndex(tmpDir)
+	if err != nil {
+		t.Fatalf("failed to create SQLiteIndex: %v", err)
+	}
+	defer index.Close()
+	index.SetIgnoreRules(ignore.NewRules(dataDir))
+
+	var indexed []string
+	indexer := NewIndexer(dataDir, 1, func(path string, content []byte) error {
+		rel, _ := relativeDataPath(dataDir, path)
+		indexed = append(indexed, rel)
+		return nil
+	})
+	indexer.walk = index.walkOptions(dataDir)
+	if err := indexer.Start(t.Context()); err != nil {
+		t.Fatalf("indexer failed: %v", err)
+	}
+	sort.Strings(indexed)
+	if got := strings.Join(indexed, ","); got != "docs/guide.md,index.md" {
+		t.Errorf("unexpected indexed files: %s", got)
+	}
+	if total := len(markdownFiles(dataDir, index.walkOptions(dataDir))); total != 2 {
+		t.Errorf("expected 2 files to index, got %d", total)
+	}
+
+	mustCapture(t, index, dataDir)
+	if entries := readHistoryEntries(t, index); len(entries) != 2 {
+		t.Errorf("expected history of the 2 files which are not ignored, got %+v", entries)
+	}
+}
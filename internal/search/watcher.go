@@ -1,27 +1,50 @@
 package search
 
 import (
-	"log"
+	"context"
 	"os"
 	"path/filepath"
 	"strings"
+	"sync"
 	"time"
 
+	"github.com/Gomez12/wiki/internal/core/ignore"
 	"github.com/Gomez12/wiki/internal/core/tree"
 	"github.com/fsnotify/fsnotify"
 )
 
-const historyScanInterval = 5 * time.Minute
+const (
+	// historyDebounce groups watcher events, so a rename (remove + create) is recorded as one move
+	historyDebounce = 500 * time.Millisecond
+	// historyMaxWait captures the changes of files which are written continuously, the debounce
+	// would wait until the writes stop
+	historyMaxWait = 2 * time.Second
+	// historyMaxPending captures the pending changes right away once this many files changed
+	historyMaxPending = 1000
+	// historyReconcileInterval runs a full history scan as fallback for missed events
+	historyReconcileInterval = 30 * time.Minute
+)
 
 type Watcher struct {
 	DataDir     string
 	TreeService *tree.TreeService
 	Index       *SQLiteIndex
 	Status      *IndexingStatus
-	watcher     *fsnotify.Watcher
+	// PollInterval makes the watcher compare the files with the previous poll in this interval
+	// instead of relying on file system events, which network file systems and bind mounts of
+	// Docker on macOS and Windows don't deliver reliably. It must be set before Start.
+	PollInterval time.Duration
+	watcher      *fsnotify.Watcher
+	// pollStop stops the poller, nil while events are used
+	pollStop    chan struct{}
 	historyTick *time.Ticker
 	stopCh      chan struct{}
 	historyReq  chan struct{}
+	historyFile chan string
+	// eventsDone and historyDone are closed when the event loop and the history recorder exited
+	eventsDone  chan struct{}
+	historyDone chan struct{}
+	stopOnce    sync.Once
 }
 
 func NewWatcher(dataDir string, treeService *tree.TreeService, index *SQLiteIndex, status *IndexingStatus) (*Watcher, error) {
@@ -36,38 +59,55 @@ func NewWatcher(dataDir string, treeService *tree.TreeService, index *SQLiteInde
 	return watcher, nil
 }
 
+// Start watches the data dir for changes made outside of the API, with file system events or
+// by polling if PollInterval is set or no events are available
 func (w *Watcher) Start() error {
-	var err error
-	if w.watcher, err = fsnotify.NewWatcher(); err != nil {
-		return err
+	if w.PollInterval <= 0 {
+		watcher, err := fsnotify.NewWatcher()
+		if err != nil {
+			// e.g. the user has used up the inotify instances
+			watchLog.Warn("file system events are not available, polling instead", "interval", defaultPollInterval, "error", err)
+			w.PollInterval = defaultPollInterval
+		} else {
+			w.watcher = watcher
+		}
 	}
 
 	w.stopCh = make(chan struct{})
 	w.historyReq = make(chan struct{}, 1)
-	w.historyTick = time.NewTicker(historyScanInterval)
+	w.historyFile = make(chan string, 256)
+	w.historyTick = time.NewTicker(historyReconcileInterval)
+	w.eventsDone = make(chan struct{})
+	w.historyDone = make(chan struct{})
+
+	if w.watcher == nil {
+		w.startPolling()
+		return nil
+	}
 
-	err = filepath.Walk(w.DataDir, func(p string, info os.FileInfo, err error) error {
+	err := walkFiles(w.DataDir, w.walkOptions(), func(p string, info os.FileInfo, err error) error {
 		if err != nil {
-			log.Printf("[watcher] walk error: %v", err)
+			watchLog.Warn("walk error", "error", err)
 			return nil
 		}
 		if info.IsDir() {
 			if err := w.watcher.Add(p); err != nil {
-				log.Printf("[watcher] add error: %v", err)
+				watchLog.Warn("could not watch directory", "path", p, "error", err)
 			}
 		}
 		return nil
 	})
 	if err != nil {
 		w.historyTick.Stop()
-		close(w.stopCh)
 		_ = w.watcher.Close()
+		w.watcher, w.stopCh = nil, nil
 		return err
 	}
 
 	go w.runHistoryRecorder()
 
 	go func() {
+		defer close(w.eventsDone)
 		for {
 			select {
 			case event, ok := <-w.watcher.Events:
@@ -75,20 +115,33 @@ func (w *Watcher) Start() error {
 					return
 				}
 
-				// Normalize path
-				eventPath := filepath.ToSlash(event.Name)
+				// kept as reported: long paths on Windows (\\?\) don't allow forward slashes
+				eventPath := event.Name
 
 				info, statErr := os.Stat(eventPath)
-				isDir := statErr == nil && info.IsDir()
+				isDir := statErr == nil && info.IsDir() && (w.followSymlinks() || !isSymlink(eventPath))
+
+				opts := w.walkOptions()
+				if rel, err := relativeDataPath(w.DataDir, eventPath); err == nil && rel == ignore.Filename {
+					// changed rules apply to the next scan, which records newly ignored files as removed
+					if opts.ignore != nil {
+						opts.ignore.Reload()
+						w.requestHistorySnapshot()
+					}
+					continue
+				}
+				if opts.ignored(eventPath, isDir) {
+					continue
+				}
 
 				// New Directory or Moved
 				if (event.Op&(fsnotify.Create|fsnotify.Rename) != 0) && isDir {
 					// Watch recursive
-					log.Printf("[watcher] watching new dir: %s", eventPath)
-					if err := filepath.Walk(eventPath, func(p string, i os.FileInfo, walkErr error) error {
+					watchLog.Debug("watching new directory", "path", eventPath)
+					if err := walkFiles(eventPath, w.walkOptions(), func(p string, i os.FileInfo, walkErr error) error {
 						if walkErr != nil {
 							// Log and keep walking other files/dirs
-							log.Printf("[watcher] walk error for %s: %v", p, walkErr)
+							watchLog.Warn("walk error", "path", p, "error", walkErr)
 							return nil
 						}
 						if i == nil {
@@ -98,7 +151,7 @@ func (w *Watcher) Start() error {
 
 						if i.IsDir() {
 							if err := w.watcher.Add(p); err != nil {
-								log.Printf("[watcher] add error: %v", err)
+								watchLog.Warn("could not watch directory", "path", p, "error", err)
 								return nil // continue walking
 							}
 						} else if filepath.Ext(p) == ".md" {
@@ -106,100 +159,187 @@ func (w *Watcher) Start() error {
 						}
 						return nil
 					}); err != nil {
-						log.Printf("[watcher] walk error: %v", err)
+						watchLog.Warn("walk error", "path", eventPath, "error", err)
 					}
+					// The old location of a moved directory produces no file events,
+					// so let the full scan pair up the moved files.
+					w.requestHistorySnapshot()
 					continue
 				}
 
 				if filepath.Ext(eventPath) != ".md" {
+					// A removed or moved directory only produces an event for the directory itself
+					if event.Op&(fsnotify.Remove|fsnotify.Rename) != 0 && statErr != nil && filepath.Ext(eventPath) == "" {
+						w.detachFromTree(eventPath)
+					}
 					continue
 				}
 
 				switch {
 				case event.Op&(fsnotify.Create|fsnotify.Write) != 0:
-					reindexFile(eventPath, w.DataDir, w.TreeService, w.Index, w.Status)
-					w.requestHistorySnapshot()
+					w.fileChanged(eventPath)
 
 				case event.Op&fsnotify.Remove != 0:
-					relPath, err := filepath.Rel(w.DataDir, eventPath)
-					if err == nil {
-						log.Printf("[watcher] file removed: %s", relPath)
-						cnt, err := w.Index.RemovePageByFilePath(relPath)
-						if err != nil {
-							log.Printf("[watcher] remove error: %v", err)
-						} else {
-							log.Printf("[watcher] removed %d pages for: %s", cnt, relPath)
-						}
-					}
-					w.requestHistorySnapshot()
+					w.fileRemoved(eventPath)
 
 				case event.Op&fsnotify.Rename != 0 && !isDir:
-					relPath, err := filepath.Rel(w.DataDir, eventPath)
-					if err == nil {
-						log.Printf("[watcher] file renamed/removed: %s", relPath)
-						cnt, err := w.Index.RemovePageByFilePath(relPath)
-						if err != nil {
-							log.Printf("[watcher] remove error: %v", err)
-						} else {
-							log.Printf("[watcher] removed %d pages for: %s", cnt, relPath)
-						}
-					}
-					w.requestHistorySnapshot()
+					w.fileRemoved(eventPath)
 				}
 
 			case err, ok := <-w.watcher.Errors:
 				if !ok {
 					return
 				}
-				log.Printf("[watcher] error: %v", err)
+				watchLog.Error("watcher error", "error", err)
 			}
 		}
 	}()
 
-	log.Println("[watcher] started watching:", w.DataDir)
+	watchLog.Info("started watching", "dir", w.DataDir)
 	return nil
 }
 
+// runHistoryRecorder records file history for watcher events.
+// Changed files are collected for historyDebounce and captured as one batch, at the latest
+// historyMaxWait after the first change or once historyMaxPending files changed.
+// A full scan runs at startup, on request and every historyReconcileInterval to reconcile missed events.
+// When the watcher stops, pending changes are flushed and a final full scan is taken.
 func (w *Watcher) runHistoryRecorder() {
+	defer close(w.historyDone)
 	if w.Index == nil || w.historyTick == nil {
 		return
 	}
 
+	// the scans are cancelled when the watcher stops, the final snapshot covers them
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go func() {
+		select {
+		case <-w.stopCh:
+			cancel()
+		case <-ctx.Done():
+		}
+	}()
+
 	// Run once immediately so we capture state at startup.
-	if err := w.Index.CaptureFileHistory(w.DataDir); err != nil {
-		log.Printf("[history] initial snapshot error: %v", err)
+	if err := w.Index.CaptureFileHistory(ctx, w.DataDir); err != nil && ctx.Err() == nil {
+		historyLog.Error("initial snapshot failed", "error", err)
+	}
+
+	pending := map[string]struct{}{}
+	debounce := time.NewTimer(historyDebounce)
+	debounce.Stop()
+	defer debounce.Stop()
+	// deadline is set while changes are pending
+	var deadline <-chan time.Time
+
+	flush := func(ctx context.Context) {
+		deadline = nil
+		if len(pending) == 0 {
+			return
+		}
+		paths := make([]string, 0, len(pending))
+		for p := range pending {
+			paths = append(paths, p)
+		}
+		pending = map[string]struct{}{}
+		if err := w.Index.CaptureFileChanges(ctx, w.DataDir, paths); err != nil && ctx.Err() == nil {
+			historyLog.Error("capture failed", "error", err)
+		}
 	}
 
 	for {
 		select {
+		case relPath := <-w.historyFile:
+			if deadline == nil {
+				deadline = time.After(historyMaxWait)
+			}
+			pending[relPath] = struct{}{}
+			if len(pending) >= historyMaxPending {
+				debounce.Stop()
+				flush(ctx)
+				continue
+			}
+			debounce.Reset(historyDebounce)
+		case <-debounce.C:
+			flush(ctx)
+		case <-deadline:
+			debounce.Stop()
+			flush(ctx)
 		case <-w.historyTick.C:
-			if err := w.Index.CaptureFileHistory(w.DataDir); err != nil {
-				log.Printf("[history] snapshot error: %v", err)
+			if err := w.Index.CaptureFileHistory(ctx, w.DataDir); err != nil && ctx.Err() == nil {
+				historyLog.Error("snapshot failed", "error", err)
 			}
 		case <-w.historyReq:
-			if err := w.Index.CaptureFileHistory(w.DataDir); err != nil {
-				log.Printf("[history] snapshot error: %v", err)
+			if err := w.Index.CaptureFileHistory(ctx, w.DataDir); err != nil && ctx.Err() == nil {
+				historyLog.Error("snapshot failed", "error", err)
 			}
 		case <-w.stopCh:
+			// the event loop has exited, so the queue can be drained completely
+		drain:
+			for {
+				select {
+				case relPath := <-w.historyFile:
+					pending[relPath] = struct{}{}
+				default:
+					break drain
+				}
+			}
+			flush(context.Background())
+			if err := w.Index.CaptureFileHistory(context.Background(), w.DataDir); err != nil {
+				historyLog.Error("final snapshot failed", "error", err)
+			}
 			return
 		}
 	}
 }
 
+// Stop stops watching and waits until the pending history is recorded.
+// The event loop is stopped first, so no change is lost between the last event and the final snapshot.
 func (w *Watcher) Stop() error {
-	if w.historyTick != nil {
-		w.historyTick.Stop()
-	}
-	if w.stopCh != nil {
-		close(w.stopCh)
-	}
-	if w.historyReq != nil {
-		close(w.historyReq)
-	}
-	if w.watcher != nil {
-		return w.watcher.Close()
+	var err error
+	w.stopOnce.Do(func() {
+		if w.historyTick != nil {
+			w.historyTick.Stop()
+		}
+		if w.watcher != nil {
+			err = w.watcher.Close()
+			<-w.eventsDone
+		}
+		if w.pollStop != nil {
+			close(w.pollStop)
+			<-w.eventsDone
+		}
+		if w.stopCh != nil {
+			close(w.stopCh)
+			<-w.historyDone
+		}
+		watchLog.Info("stopped watching", "dir", w.DataDir)
+	})
+	return err
+}
+
+// fileChanged indexes a created or modified Markdown file and records its history
+func (w *Watcher) fileChanged(fullPath string) {
+	reindexFile(fullPath, w.DataDir, w.TreeService, w.Index, w.Status)
+	w.recordHistory(fullPath)
+}
+
+// fileRemoved removes a removed or renamed Markdown file from the index and the tree and
+// records its history, which pairs it up with the new name of a renamed file
+func (w *Watcher) fileRemoved(fullPath string) {
+	relPath, err := relativeDataPath(w.DataDir, fullPath)
+	if err == nil {
+		watchLog.Debug("file renamed or removed", "path", relPath)
+		cnt, err := w.Index.RemovePageByFilePath(relPath)
+		if err != nil {
+			watchLog.Error("could not remove page from index", "path", relPath, "error", err)
+		} else {
+			watchLog.Debug("removed pages from index", "path", relPath, "count", cnt)
+		}
 	}
-	return nil
+	w.detachFromTree(fullPath)
+	w.recordHistory(fullPath)
 }
 
 func (w *Watcher) requestHistorySnapshot() {
@@ -212,19 +352,92 @@ func (w *Watcher) requestHistorySnapshot() {
 	}
 }
 
+// walkOptions returns the symlink and ignore configuration of the index
+func (w *Watcher) walkOptions() walkOptions {
+	if w.Index == nil {
+		return walkOptions{dataDir: w.DataDir}
+	}
+	return w.Index.walkOptions(w.DataDir)
+}
+
+// followSymlinks returns true if symlinked directories are watched
+func (w *Watcher) followSymlinks() bool {
+	return w.walkOptions().followSymlinks
+}
+
+func isSymlink(p string) bool {
+	info, err := os.Lstat(p)
+	return err == nil && info.Mode()&os.ModeSymlink != 0
+}
+
+// recordHistory queues a changed file for the history recorder.
+// If the queue is full, it falls back to a full scan.
+func (w *Watcher) recordHistory(fullPath string) {
+	if w.historyFile == nil {
+		return
+	}
+	rel, err := relativeDataPath(w.DataDir, fullPath)
+	if err != nil {
+		historyLog.Warn("could not resolve relative path", "path", fullPath, "error", err)
+		return
+	}
+	select {
+	case w.historyFile <- rel:
+	default:
+		w.requestHistorySnapshot()
+	}
+}
+
+// detachFromTree removes the page of a file or folder which was removed outside of the API
+// from the in-memory tree, so the tree stays consistent with the disk.
+func (w *Watcher) detachFromTree(fullPath string) {
+	if w.TreeService == nil {
+		return
+	}
+	rel, err := relativeDataPath(w.DataDir, fullPath)
+	if err != nil {
+		watchLog.Warn("could not resolve relative path", "path", fullPath, "error", err)
+		return
+	}
+
+	routePath := routePathFromFile(rel)
+	if routePath == "" || routePath == "." {
+		return
+	}
+	removed, err := w.TreeService.DetachMissingPath(routePath)
+	if err != nil {
+		watchLog.Error("could not detach removed path", "path", rel, "error", err)
+		return
+	}
+	if removed {
+		watchLog.Info("detached removed path", "path", rel)
+	}
+}
+
+// routePathFromFile returns the route path of a file path relative to the data dir
+func routePathFromFile(rel string) string {
+	routePath := filepath.ToSlash(strings.TrimSuffix(rel, filepath.Ext(rel)))
+	return strings.TrimSuffix(routePath, "/index")
+}
+
 func reindexFile(fullPath, dataDir string, treeService *tree.TreeService, index *SQLiteIndex, status *IndexingStatus) {
-	rel, err := filepath.Rel(dataDir, fullPath)
+	rel, err := relativeDataPath(dataDir, fullPath)
 	if err != nil {
-		log.Printf("[watcher] rel path error: %v", err)
+		watchLog.Warn("could not resolve relative path", "path", fullPath, "error", err)
 		return
 	}
 
-	routePath := strings.TrimSuffix(rel, filepath.Ext(rel))
-	routePath = filepath.ToSlash(strings.TrimSuffix(routePath, "/index"))
+	routePath := routePathFromFile(rel)
 
+	// stat'ed before the read, so a change during the read is indexed again at startup
+	info, err := os.Stat(fullPath)
+	if err != nil {
+		watchLog.Warn("could not read file", "path", rel, "error", err)
+		return
+	}
 	content, err := os.ReadFile(fullPath)
 	if err != nil {
-		log.Printf("[watcher] read error: %v", err)
+		watchLog.Warn("could not read file", "path", rel, "error", err)
 		return
 	}
 
@@ -233,19 +446,24 @@ func reindexFile(fullPath, dataDir string, treeService *tree.TreeService, index
 		// File exists on disk but not in tree: auto-attach and continue indexing.
 		node, ensureErr := ensureTreeNodeForFile(treeService, routePath, content)
 		if ensureErr != nil {
-			log.Printf("[watcher] auto-attach failed for %s: %v", rel, ensureErr)
+			watchLog.Error("could not attach missing path", "path", rel, "error", ensureErr)
+			index.recordIndexResult(rel, ensureErr)
 			return
 		}
-		log.Printf("[watcher] auto-attached missing path: %s", rel)
+		watchLog.Info("attached missing path", "path", rel)
 		page = &tree.Page{PageNode: node, Content: string(content)}
 	}
 
 	err = index.IndexPage(page.CalculatePath(), rel, page.ID, page.Title, string(content))
+	if err == nil {
+		err = index.setIndexedFile(rel, newIndexedFile(info))
+	}
+	index.recordIndexResult(rel, err)
 	if err != nil {
-		status.Fail()
-		log.Printf("[watcher] index error: %v", err)
+		status.RecordError(rel, err)
+		watchLog.Error("could not index file", "path", rel, "error", err)
 	} else {
 		status.Success()
-		log.Printf("[watcher] indexed: %s", rel)
+		watchLog.Debug("indexed file", "path", rel)
 	}
 }
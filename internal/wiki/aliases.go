@@ -0,0 +1,102 @@
+package wiki
+
+import (
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/Gomez12/wiki/internal/core/frontmatter"
+	"github.com/Gomez12/wiki/internal/core/tree"
+)
+
+// aliasTTL is how long the aliases are cached. Changes made through the wiki reset the cache
+// at once, the TTL picks up files changed elsewhere, e.g. with WebDAV or an editor.
+const aliasTTL = time.Minute
+
+// aliasIndex maps the aliases of the pages, given by the aliases field of their frontmatter,
+// to the page IDs. It is built on demand, so the zero value is ready to use.
+type aliasIndex struct {
+	mu      sync.Mutex
+	pages   map[string]string
+	builtAt time.Time
+}
+
+// aliasKey normalizes an alias or a route for the lookup, aliases are not case-sensitive
+func aliasKey(route string) string {
+	return strings.ToLower(tree.NormalizeRoute(strings.Trim(strings.TrimSpace(route), "/")))
+}
+
+// pageByAlias returns the page with the alias. Pages whose route equals the alias take
+// precedence, this is up to the caller. If several pages share an alias, the first page
+// in the tree wins.
+func (w *Wiki) pageByAlias(route string) (*tree.Page, bool) {
+	key := aliasKey(route)
+	if key == "" {
+		return nil, false
+	}
+
+	w.aliases.mu.Lock()
+	if w.aliases.pages == nil || time.Since(w.aliases.builtAt) > aliasTTL {
+		w.aliases.pages = w.buildAliases()
+		w.aliases.builtAt = time.Now()
+	}
+	id, ok := w.aliases.pages[key]
+	w.aliases.mu.Unlock()
+	if !ok {
+		return nil, false
+	}
+
+	page, err := w.tree.GetPage(id)
+	if err != nil {
+		return nil, false
+	}
+	return page, true
+}
+
+// invalidateAliases rebuilds the aliases on the next lookup, called when pages changed
+func (w *Wiki) invalidateAliases() {
+	w.aliases.mu.Lock()
+	defer w.aliases.mu.Unlock()
+	w.aliases.pages = nil
+}
+
+// buildAliases reads the aliases of all pages
+func (w *Wiki) buildAliases() map[string]string {
+	pages := map[string]string{}
+	var walk func(nodes []*tree.PageNode)
+	walk = func(nodes []*tree.PageNode) {
+		for _, node := range nodes {
+			if page, err := w.tree.GetPage(node.ID); err == nil {
+				if fields, _, err := frontmatter.Parse(page.Content); err == nil {
+					for _, alias := range aliases(fields) {
+						key := aliasKey(alias)
+						if _, taken := pages[key]; key != "" && !taken {
+							pages[key] = node.ID
+						}
+					}
+				}
+			}
+			walk(node.Children)
+		}
+	}
+	walk(w.tree.GetTree().Children)
+	return pages
+}
+
+// aliases returns the aliases of a page, a list or a single string
+func aliases(fields map[string]any) []string {
+	var list []string
+	for _, key := range []string{"aliases", "alias"} {
+		switch v := fields[key].(type) {
+		case string:
+			list = append(list, v)
+		case []any:
+			for _, item := range v {
+				if s, ok := item.(string); ok {
+					list = append(list, s)
+				}
+			}
+		}
+	}
+	return list
+}
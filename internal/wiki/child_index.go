@@ -0,0 +1,95 @@
+package wiki
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/Gomez12/wiki/internal/core/frontmatter"
+	"github.com/Gomez12/wiki/internal/core/tree"
+	"github.com/russross/blackfriday/v2"
+)
+
+// childIndexField is the frontmatter flag which appends the index of the pages below
+const childIndexField = "childIndex"
+
+// maxSummaryLength is the length of the summaries in the child index in characters
+const maxSummaryLength = 160
+
+// appendChildIndex appends the listing of the pages below when the page enables it with
+// childIndex: true in its frontmatter. The pages are sorted by title and summarized by
+// their summary or description field, or else the first paragraph.
+func (w *Wiki) appendChildIndex(page *tree.Page, content string) string {
+	fields, _, err := frontmatter.Parse(page.Content)
+	if err != nil || fields[childIndexField] != true || len(page.Children) == 0 {
+		return content
+	}
+
+	children := append([]*tree.PageNode{}, page.Children...)
+	sort.SliceStable(children, func(i, j int) bool {
+		return strings.ToLower(children[i].Title) < strings.ToLower(children[j].Title)
+	})
+
+	var b strings.Builder
+	b.WriteString(strings.TrimRight(content, "\n"))
+	b.WriteString("\n\n## Pages\n\n")
+	for _, child := range children {
+		fmt.Fprintf(&b, "- [%s](%s)", escapeLinkText(child.Title), child.CalculatePath())
+		childPage, err := w.tree.GetPage(child.ID)
+		if err != nil {
+			wikiLog.Warn("child index: could not read page", "pageId", child.ID, "error", err)
+		} else if summary := summarize(childPage.Content); summary != "" {
+			b.WriteString(" – " + summary)
+		}
+		b.WriteString("\n")
+	}
+	return b.String()
+}
+
+// summarize returns the summary or description field of the page, or else the text of its
+// first paragraph, shortened to maxSummaryLength characters
+func summarize(content string) string {
+	fields, body, err := frontmatter.Parse(content)
+	if err != nil {
+		_, body, _ = frontmatter.Split(content)
+	}
+	summary, ok := frontmatter.String(fields, "summary")
+	if !ok {
+		summary, ok = frontmatter.String(fields, "description")
+	}
+	if !ok {
+		summary = firstParagraph(body)
+	}
+
+	summary = strings.Join(strings.Fields(summary), " ")
+	if utf8.RuneCountInString(summary) <= maxSummaryLength {
+		return summary
+	}
+	cut := string([]rune(summary)[:maxSummaryLength])
+	if i := strings.LastIndex(cut, " "); i > 0 {
+		cut = cut[:i]
+	}
+	return strings.TrimRight(cut, " ,.;:") + "…"
+}
+
+// firstParagraph returns the plain text of the first paragraph of the Markdown
+func firstParagraph(markdown string) string {
+	root := blackfriday.New(blackfriday.WithExtensions(blackfriday.CommonExtensions)).Parse([]byte(markdown))
+	for node := root.FirstChild; node != nil; node = node.Next {
+		if node.Type != blackfriday.Paragraph {
+			continue
+		}
+		var b strings.Builder
+		node.Walk(func(n *blackfriday.Node, entering bool) blackfriday.WalkStatus {
+			if entering && (n.Type == blackfriday.Text || n.Type == blackfriday.Code) {
+				b.Write(n.Literal)
+			}
+			return blackfriday.GoToNext
+		})
+		if text := strings.TrimSpace(b.String()); text != "" {
+			return text
+		}
+	}
+	return ""
+}
@@ -0,0 +1,51 @@
+package wiki
+
+import (
+	"errors"
+
+	"github.com/Gomez12/wiki/internal/core/drafts"
+	"github.com/Gomez12/wiki/internal/core/tree"
+)
+
+// SaveDraft autosaves the editor of a user for a page. The draft is kept in the database,
+// the Markdown file is only written when the page is saved. Like page saves, drafts are
+// limited to the maximum request size.
+func (w *Wiki) SaveDraft(userID, pageID, title, content string) (*drafts.Draft, error) {
+	if _, err := w.tree.GetPage(pageID); err != nil {
+		return nil, err
+	}
+	if int64(len(title)+len(content)) > w.MaxRequestSize() {
+		return nil, ErrDraftTooLarge
+	}
+	return w.drafts.Save(userID, pageID, title, content)
+}
+
+// GetDraft returns the autosaved draft of a user for a page, e.g. to recover the changes
+// after the browser crashed. The drafts of pages which no longer exist are removed.
+func (w *Wiki) GetDraft(userID, pageID string) (*drafts.Draft, error) {
+	if _, err := w.tree.GetPage(pageID); err != nil {
+		if errors.Is(err, tree.ErrPageNotFound) {
+			if err := w.drafts.DeleteForPage(pageID); err != nil {
+				wikiLog.Warn("could not remove drafts", "pageId", pageID, "error", err)
+			}
+		}
+		return nil, err
+	}
+	return w.drafts.Get(userID, pageID)
+}
+
+// DiscardDraft removes the draft of a user for a page, once the page is saved or the changes
+// are dropped. A missing draft is no error.
+func (w *Wiki) DiscardDraft(userID, pageID string) error {
+	return w.drafts.Delete(userID, pageID)
+}
+
+// deleteDraftsOf removes the drafts of a deleted page and its descendants
+func (w *Wiki) deleteDraftsOf(node *tree.PageNode) {
+	if err := w.drafts.DeleteForPage(node.ID); err != nil {
+		wikiLog.Warn("could not remove drafts", "pageId", node.ID, "error", err)
+	}
+	for _, child := range node.Children {
+		w.deleteDraftsOf(child)
+	}
+}
@@ -0,0 +1,92 @@
+package wiki
+
+import (
+	"math"
+	"strings"
+
+	"github.com/Gomez12/wiki/internal/core/frontmatter"
+	"github.com/Gomez12/wiki/internal/core/shared/errors"
+	"github.com/Gomez12/wiki/internal/core/tree"
+	"github.com/Gomez12/wiki/internal/search"
+)
+
+// DefaultDuplicateThreshold is the similarity from which pages are reported as duplicates
+const DefaultDuplicateThreshold = 0.8
+
+// duplicateMinWords leaves out stubs like a page with only a heading, they are alike anyway
+const duplicateMinWords = 10
+
+// DuplicatePage is a page of a duplicate group
+type DuplicatePage struct {
+	PageID string `json:"pageId"`
+	Title  string `json:"title"`
+	Path   string `json:"path"`
+}
+
+// DuplicateGroup are pages with identical or near-identical content
+type DuplicateGroup struct {
+	// Identical is true if the content of all pages is the same
+	Identical bool `json:"identical"`
+	// Similarity is the lowest similarity which linked the pages, 1 if identical
+	Similarity float64         `json:"similarity"`
+	Pages      []DuplicatePage `json:"pages"`
+}
+
+// DuplicateReport lists the groups of duplicate pages, the largest first
+type DuplicateReport struct {
+	Threshold float64          `json:"threshold"`
+	Groups    []DuplicateGroup `json:"groups"`
+}
+
+// DuplicatePages reports the pages whose content is identical or reaches the similarity
+// threshold, to consolidate copied documentation. The frontmatter is not compared.
+func (w *Wiki) DuplicatePages(threshold float64) (*DuplicateReport, error) {
+	ve := errors.NewValidationErrors()
+	if threshold <= 0 || threshold > 1 {
+		ve.Add("threshold", "Threshold must be greater than 0 and at most 1")
+		return nil, ve
+	}
+
+	pages := map[string]DuplicatePage{}
+	docs := []search.DuplicateDocument{}
+	var walk func(nodes []*tree.PageNode) error
+	walk = func(nodes []*tree.PageNode) error {
+		for _, node := range nodes {
+			page, err := w.tree.GetPage(node.ID)
+			if err != nil {
+				return err
+			}
+			_, body, _ := frontmatter.Split(page.Content)
+			body = strings.TrimSpace(body)
+			if len(strings.Fields(body)) >= duplicateMinWords {
+				pages[page.ID] = DuplicatePage{
+					PageID: page.ID,
+					Title:  page.Title,
+					Path:   strings.TrimPrefix(page.CalculatePath(), "/"),
+				}
+				docs = append(docs, search.DuplicateDocument{ID: page.ID, Content: body})
+			}
+			if err := walk(node.Children); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+	if err := walk(w.tree.GetTree().Children); err != nil {
+		return nil, err
+	}
+
+	report := &DuplicateReport{Threshold: threshold, Groups: []DuplicateGroup{}}
+	for _, cluster := range search.FindDuplicates(docs, threshold) {
+		group := DuplicateGroup{
+			Identical:  cluster.Identical,
+			Similarity: math.Round(cluster.Similarity*1000) / 1000,
+			Pages:      []DuplicatePage{},
+		}
+		for _, id := range cluster.IDs {
+			group.Pages = append(group.Pages, pages[id])
+		}
+		report.Groups = append(report.Groups, group)
+	}
+	return report, nil
+}
@@ -0,0 +1,19 @@
+package wiki
+
+import "errors"
+
+var ErrNothingToUndo = errors.New("no previous version to restore")
+
+var ErrShuttingDown = errors.New("wiki is shutting down")
+
+var ErrGitSyncDisabled = errors.New("git sync is not configured")
+
+var ErrVaultFileNotFound = errors.New("vault file not found")
+
+var ErrSpellcheckDisabled = errors.New("spellcheck is not configured")
+
+var ErrConfigReloadDisabled = errors.New("no config file is configured")
+
+var ErrSetupCompleted = errors.New("setup is already completed")
+
+var ErrDraftTooLarge = errors.New("draft is larger than the maximum request size")
@@ -0,0 +1,325 @@
+package wiki
+
+import (
+	"archive/zip"
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+
+	"github.com/Gomez12/wiki/internal/core/docx"
+	"github.com/Gomez12/wiki/internal/core/frontmatter"
+	"github.com/Gomez12/wiki/internal/core/importer"
+	"github.com/Gomez12/wiki/internal/core/pdf"
+	"github.com/Gomez12/wiki/internal/core/shared/errors"
+	"github.com/Gomez12/wiki/internal/core/staticsite"
+	"github.com/Gomez12/wiki/internal/core/tree"
+)
+
+// Export writes all pages as Markdown files to destDir, in the layout read by AnalyzeImport:
+// pages with children or with assets next to them become folders with an index.md, the
+// assets stay in the assets folder next to it. Titles which can't be derived from
+// the content are added to the frontmatter. Returns the number of exported pages.
+func (w *Wiki) Export(destDir string) (int, error) {
+	ve := errors.NewValidationErrors()
+	if strings.TrimSpace(destDir) == "" {
+		ve.Add("destDir", "Destination directory must not be empty")
+	} else if entries, err := os.ReadDir(destDir); err == nil && len(entries) > 0 {
+		ve.Add("destDir", "Destination directory must be empty")
+	}
+	if ve.HasErrors() {
+		return 0, ve
+	}
+
+	if err := os.MkdirAll(destDir, 0755); err != nil {
+		return 0, err
+	}
+	return w.exportPages(destDir, w.tree.GetTree().Children)
+}
+
+// ExportHTML renders the wiki as static HTML site, e.g. for a read-only mirror on GitHub Pages.
+// Returns the number of exported pages. The export stops at the next page when ctx ends.
+func (w *Wiki) ExportHTML(ctx context.Context, out staticsite.Writer) (int, error) {
+	s, err := w.GetSettings()
+	if err != nil {
+		return 0, err
+	}
+	return staticsite.Build(staticsite.Source{
+		SiteTitle:     s.SiteTitle,
+		RenderOptions: renderOptions(s),
+		Tree:          w.tree.GetTree(),
+		Content: func(node *tree.PageNode) (string, error) {
+			if err := ctx.Err(); err != nil {
+				return "", err
+			}
+			page, err := w.tree.GetPage(node.ID)
+			if err != nil {
+				return "", err
+			}
+			return w.expandedContent(page), nil
+		},
+		AssetsDir: w.asset.GetAssetsDir(),
+		PageAssetsDir: func(node *tree.PageNode) string {
+			return w.asset.PageFolderPath(node)
+		},
+	}, out)
+}
+
+// ExportPDF renders a page as PDF. With recursive, the pages below it follow as sections of
+// the same document, preceded by a table of contents. The pages are collected until ctx ends.
+func (w *Wiki) ExportPDF(ctx context.Context, id string, recursive bool, out io.Writer) error {
+	page, err := w.tree.GetPage(id)
+	if err != nil {
+		return err
+	}
+
+	sections := []pdf.Section{{Title: page.Title, Markdown: w.expandedContent(page)}}
+	if recursive {
+		if sections, err = w.appendPDFSections(ctx, sections, page.Children, 1); err != nil {
+			return err
+		}
+	}
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	return pdf.Render(out, page.Title, sections)
+}
+
+// ExportedFile is a page rendered for download
+type ExportedFile struct {
+	Filename    string
+	ContentType string
+	Data        []byte
+}
+
+// ExportPage renders a page in one of the formats pdf, md, html or docx, e.g. to share it outside
+// of the wiki. Markdown and HTML exports of pages with assets become a zip file with an assets
+// folder next to the page, the links to the assets are made relative. DOCX documents embed the
+// images instead. Only PDF exports can include the pages below (recursive).
+func (w *Wiki) ExportPage(ctx context.Context, id, format string, recursive bool) (*ExportedFile, error) {
+	ve := errors.NewValidationErrors()
+	switch format {
+	case "pdf", "md", "html", "docx":
+	default:
+		ve.Add("format", "Unsupported export format")
+	}
+	if recursive && format != "pdf" {
+		ve.Add("recursive", "Only PDF exports can include the pages below")
+	}
+	if ve.HasErrors() {
+		return nil, ve
+	}
+
+	page, err := w.tree.GetPage(id)
+	if err != nil {
+		return nil, err
+	}
+
+	var buf bytes.Buffer
+	if format == "pdf" {
+		if err := w.ExportPDF(ctx, page.ID, recursive, &buf); err != nil {
+			return nil, err
+		}
+		return &ExportedFile{Filename: page.Slug + ".pdf", ContentType: "application/pdf", Data: buf.Bytes()}, nil
+	}
+
+	assetURLs, err := w.ListAssets(page.ID)
+	if err != nil {
+		return nil, err
+	}
+	assetFile := func(name string) (string, error) {
+		return w.asset.AssetFilePath(page.PageNode, name)
+	}
+	// the links to the assets next to the page are relative already
+	content := strings.ReplaceAll(page.Content, "/assets/"+page.ID+"/", "assets/")
+	if format != "md" {
+		// the Markdown export is the source of the page and keeps the include directives
+		content = strings.ReplaceAll(w.expandedContent(page), "/assets/"+page.ID+"/", "assets/")
+	}
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	var file *ExportedFile
+	switch format {
+	case "md":
+		file = &ExportedFile{Filename: page.Slug + ".md", ContentType: "text/markdown; charset=utf-8", Data: []byte(content)}
+	case "html":
+		s, err := w.GetSettings()
+		if err != nil {
+			return nil, err
+		}
+		if err := staticsite.RenderPage(&buf, page.Title, content, renderOptions(s)); err != nil {
+			return nil, err
+		}
+		file = &ExportedFile{Filename: page.Slug + ".html", ContentType: "text/html; charset=utf-8", Data: buf.Bytes()}
+	case "docx":
+		err := docx.Render(&buf, page.Title, content, func(src string) ([]byte, bool) {
+			name, ok := strings.CutPrefix(src, "assets/")
+			if !ok || name != path.Base(name) {
+				return nil, false
+			}
+			file, err := assetFile(name)
+			if err != nil {
+				return nil, false
+			}
+			data, err := os.ReadFile(file)
+			return data, err == nil
+		})
+		if err != nil {
+			return nil, err
+		}
+		return &ExportedFile{
+			Filename:    page.Slug + ".docx",
+			ContentType: "application/vnd.openxmlformats-officedocument.wordprocessingml.document",
+			Data:        buf.Bytes(),
+		}, nil
+	}
+
+	if len(assetURLs) == 0 {
+		return file, nil
+	}
+	return bundleWithAssets(file, page.Slug, assetFile, assetURLs)
+}
+
+// bundleWithAssets zips the file together with the assets of its page
+func bundleWithAssets(file *ExportedFile, slug string, assetFile func(name string) (string, error), assetURLs []string) (*ExportedFile, error) {
+	var buf bytes.Buffer
+	zw := zip.NewWriter(&buf)
+	f, err := zw.Create(file.Filename)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := f.Write(file.Data); err != nil {
+		return nil, err
+	}
+	for _, url := range assetURLs {
+		name := path.Base(url)
+		filename, err := assetFile(name)
+		if err != nil {
+			return nil, err
+		}
+		data, err := os.ReadFile(filename)
+		if err != nil {
+			return nil, err
+		}
+		f, err := zw.Create("assets/" + name)
+		if err != nil {
+			return nil, err
+		}
+		if _, err := f.Write(data); err != nil {
+			return nil, err
+		}
+	}
+	if err := zw.Close(); err != nil {
+		return nil, err
+	}
+	return &ExportedFile{Filename: slug + ".zip", ContentType: "application/zip", Data: buf.Bytes()}, nil
+}
+
+func (w *Wiki) appendPDFSections(ctx context.Context, sections []pdf.Section, nodes []*tree.PageNode, depth int) ([]pdf.Section, error) {
+	for _, node := range nodes {
+		if err := ctx.Err(); err != nil {
+			return sections, err
+		}
+		page, err := w.tree.GetPage(node.ID)
+		if err != nil {
+			return sections, err
+		}
+		sections = append(sections, pdf.Section{Title: page.Title, Markdown: w.expandedContent(page), Depth: depth})
+		if sections, err = w.appendPDFSections(ctx, sections, node.Children, depth+1); err != nil {
+			return sections, err
+		}
+	}
+	return sections, nil
+}
+
+func (w *Wiki) exportPages(dir string, nodes []*tree.PageNode) (int, error) {
+	count := 0
+	for _, node := range nodes {
+		page, err := w.tree.GetPage(node.ID)
+		if err != nil {
+			return count, err
+		}
+
+		// the assets next to a page keep their place, so its relative links stay valid
+		pageAssets := hasPageFolderAssets(w.asset, node)
+		filename := filepath.Join(dir, node.Slug+".md")
+		if node.HasChildren() || pageAssets {
+			childDir := filepath.Join(dir, node.Slug)
+			if err := os.MkdirAll(childDir, 0755); err != nil {
+				return count, err
+			}
+			filename = filepath.Join(childDir, "index.md")
+		}
+
+		if err := os.WriteFile(filename, []byte(withTitle(page.Content, node.Title)), 0644); err != nil {
+			return count, fmt.Errorf("could not export page %s: %w", node.ID, err)
+		}
+		count++
+
+		if pageAssets {
+			if err := copyFiles(w.asset.PageFolderPath(node), filepath.Join(dir, node.Slug, "assets")); err != nil {
+				return count, fmt.Errorf("could not export the assets of page %s: %w", node.ID, err)
+			}
+		}
+
+		if node.HasChildren() {
+			n, err := w.exportPages(filepath.Join(dir, node.Slug), node.Children)
+			count += n
+			if err != nil {
+				return count, err
+			}
+		}
+	}
+	return count, nil
+}
+
+// copyFiles copies the files of srcDir to destDir
+func copyFiles(srcDir, destDir string) error {
+	entries, err := os.ReadDir(srcDir)
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(destDir, 0755); err != nil {
+		return err
+	}
+	for _, entry := range entries {
+		if !entry.Type().IsRegular() {
+			continue
+		}
+		data, err := os.ReadFile(filepath.Join(srcDir, entry.Name()))
+		if err != nil {
+			return err
+		}
+		if err := os.WriteFile(filepath.Join(destDir, entry.Name()), data, 0644); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// withTitle adds the title to the frontmatter, unless the importer derives the same title
+// from the content or the frontmatter already sets one
+func withTitle(content, title string) string {
+	if importer.ExtractTitle(content) == title {
+		return content
+	}
+	fields, _, err := frontmatter.Parse(content)
+	if _, exists := fields["title"]; err != nil || exists {
+		return content
+	}
+
+	// a JSON string is a valid double-quoted YAML scalar
+	quoted, _ := json.Marshal(title)
+	line := "title: " + string(quoted) + "\n"
+	if front, body, ok := frontmatter.Split(content); ok {
+		return "---\n" + line + front + "---\n" + body
+	}
+	return "---\n" + line + "---\n" + content
+}
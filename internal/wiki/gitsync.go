@@ -0,0 +1,74 @@
+package wiki
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/Gomez12/wiki/internal/core/gitsync"
+	"github.com/Gomez12/wiki/internal/search"
+)
+
+// gitSyncTimeout limits a single sync, so a hanging remote doesn't block the next ones
+const gitSyncTimeout = 5 * time.Minute
+
+// SyncGit syncs the content with the git remote right away.
+// When remote changes were merged, the page tree is reloaded and the search index rebuilt.
+// The sync isn't cancelled when ctx ends, e.g. because the client disconnected, since that
+// would stop git in the middle of a merge; it is limited by gitSyncTimeout instead.
+func (w *Wiki) SyncGit(ctx context.Context) (gitsync.Status, error) {
+	if w.gitSync == nil {
+		return gitsync.Status{}, ErrGitSyncDisabled
+	}
+
+	ctx, cancel := context.WithTimeout(context.WithoutCancel(ctx), gitSyncTimeout)
+	defer cancel()
+	pulled, err := w.gitSync.Sync(ctx)
+	if pulled {
+		w.reloadContent()
+	}
+	return w.gitSync.Status(), err
+}
+
+// GitSyncStatus returns the outcome of the last git sync
+func (w *Wiki) GitSyncStatus() (gitsync.Status, error) {
+	if w.gitSync == nil {
+		return gitsync.Status{}, ErrGitSyncDisabled
+	}
+	return w.gitSync.Status(), nil
+}
+
+// reloadContent picks up content changed on disk by a merge. The rebuilt search index
+// also attaches pages which exist on disk but are missing in the tree.
+func (w *Wiki) reloadContent() {
+	if err := w.tree.LoadTree(); err != nil {
+		wikiLog.Error("could not reload tree after git sync", "error", err)
+		return
+	}
+	w.invalidateAliases()
+	if err := w.ReindexAll(); err != nil && !errors.Is(err, search.ErrIndexingInProgress) && !errors.Is(err, ErrShuttingDown) {
+		wikiLog.Error("could not rebuild search index after git sync", "error", err)
+	}
+}
+
+// runGitSync syncs at startup and then periodically until stop is closed
+func (w *Wiki) runGitSync(stop <-chan struct{}) {
+	ticker := time.NewTicker(w.gitSync.Interval())
+	defer ticker.Stop()
+
+	for {
+		status, err := w.SyncGit(context.Background())
+		switch {
+		case errors.Is(err, gitsync.ErrConflict):
+			wikiLog.Warn("git sync stopped by conflicts", "files", status.Conflicts)
+		case err != nil:
+			wikiLog.Error("git sync failed", "error", err)
+		}
+
+		select {
+		case <-ticker.C:
+		case <-stop:
+			return
+		}
+	}
+}
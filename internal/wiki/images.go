@@ -0,0 +1,47 @@
+package wiki
+
+import (
+	"bytes"
+	"io"
+
+	"github.com/Gomez12/wiki/internal/core/images"
+)
+
+// maxProcessedImageSize is the size up to which uploaded images are processed, they are
+// held in memory meanwhile. Larger images are stored as they are.
+const maxProcessedImageSize = 64 << 20
+
+// processImage strips the metadata of uploaded JPEG and PNG images and scales down large
+// ones, as configured in the settings. Other files, and images which can't be read, are
+// stored as they are.
+func (w *Wiki) processImage(file io.Reader, filename string) (io.Reader, error) {
+	if !images.Supported(filename) {
+		return file, nil
+	}
+	s, err := w.settings.Get()
+	if err != nil {
+		return nil, err
+	}
+	if !s.StripImageMetadata && s.ImageMaxDimension == 0 {
+		return file, nil
+	}
+
+	data, err := io.ReadAll(io.LimitReader(file, maxProcessedImageSize+1))
+	if err != nil {
+		return nil, err
+	}
+	if len(data) > maxProcessedImageSize {
+		wikiLog.Warn("uploaded image is too large to be processed", "filename", filename)
+		return io.MultiReader(bytes.NewReader(data), file), nil
+	}
+	processed, err := images.Process(data, filename, images.Options{
+		StripMetadata: s.StripImageMetadata,
+		MaxDimension:  s.ImageMaxDimension,
+		Quality:       s.ImageQuality,
+	})
+	if err != nil {
+		wikiLog.Warn("could not process uploaded image", "filename", filename, "error", err)
+		return bytes.NewReader(data), nil
+	}
+	return bytes.NewReader(processed), nil
+}
@@ -0,0 +1,300 @@
+package wiki
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/Gomez12/wiki/internal/core/importer"
+	"github.com/Gomez12/wiki/internal/core/shared/errors"
+	"github.com/Gomez12/wiki/internal/core/tree"
+)
+
+// importUploadsDir holds the extracted archives of ImportArchive until they are imported
+const importUploadsDir = "import-uploads"
+
+// importUploadMaxAge is how long an extracted archive is kept when it is not imported
+const importUploadMaxAge = 24 * time.Hour
+
+// importArchiveExpansion is how many times the extracted files of an archive may be larger
+// than the upload size limit, Markdown compresses well
+const importArchiveExpansion = 10
+
+// AnalyzeImport proposes a hierarchy for an existing folder of Markdown files. The folder must
+// be below the import root, which relative paths are resolved against, or an uploaded archive.
+func (w *Wiki) AnalyzeImport(sourceDir string) (*importer.Plan, error) {
+	ve := errors.NewValidationErrors()
+	if strings.TrimSpace(sourceDir) == "" {
+		ve.Add("sourceDir", "Source directory must not be empty")
+		return nil, ve
+	}
+
+	dir, _, err := w.importSource(sourceDir)
+	if err != nil {
+		return nil, err
+	}
+	return importer.Analyze(dir)
+}
+
+// ImportArchive extracts an uploaded zip archive and proposes a hierarchy for its Markdown
+// files. ApplyImport removes the extracted files once the plan is imported, archives which
+// are not imported are removed after a day.
+func (w *Wiki) ImportArchive(r io.ReaderAt, size int64) (*importer.Plan, error) {
+	uploads := filepath.Join(w.storageDir, importUploadsDir)
+	if err := os.MkdirAll(uploads, 0o755); err != nil {
+		return nil, err
+	}
+	pruneImportUploads(uploads)
+
+	dir, err := os.MkdirTemp(uploads, "upload-")
+	if err != nil {
+		return nil, err
+	}
+	if err := importer.ExtractArchive(r, size, dir, w.MaxUploadSize()*importArchiveExpansion); err != nil {
+		_ = os.RemoveAll(dir)
+		return nil, err
+	}
+	plan, err := importer.Analyze(dir)
+	if err != nil {
+		_ = os.RemoveAll(dir)
+		return nil, err
+	}
+	return plan, nil
+}
+
+// pruneImportUploads removes the extracted archives which were not imported in time
+func pruneImportUploads(uploads string) {
+	entries, err := os.ReadDir(uploads)
+	if err != nil {
+		return
+	}
+	for _, entry := range entries {
+		if info, err := entry.Info(); err == nil && time.Since(info.ModTime()) > importUploadMaxAge {
+			_ = os.RemoveAll(filepath.Join(uploads, entry.Name()))
+		}
+	}
+}
+
+// importSource resolves the folder of an import, which must be the import root or below it, or
+// an extracted archive. Links are resolved before the check, so they can't lead outside
+// either. uploaded is true for archives.
+func (w *Wiki) importSource(sourceDir string) (dir string, uploaded bool, err error) {
+	if !filepath.IsAbs(sourceDir) {
+		if w.importRoot == "" {
+			return "", false, importer.ErrInvalidSourcePath
+		}
+		sourceDir = filepath.Join(w.importRoot, sourceDir)
+	}
+	sourceDir, err = filepath.Abs(sourceDir)
+	if err != nil {
+		return "", false, importer.ErrInvalidSourcePath
+	}
+
+	roots := []struct {
+		dir     string
+		uploads bool
+	}{{w.importRoot, false}, {filepath.Join(w.storageDir, importUploadsDir), true}}
+	for _, root := range roots {
+		if root.dir == "" {
+			continue
+		}
+		rootDir, err := filepath.Abs(root.dir)
+		if err != nil || !insideDir(sourceDir, rootDir) {
+			continue
+		}
+		resolvedRoot, err := filepath.EvalSymlinks(rootDir)
+		if err != nil {
+			return "", false, importer.ErrSourceNotFound
+		}
+		dir, err := filepath.EvalSymlinks(sourceDir)
+		if err != nil {
+			return "", false, importer.ErrSourceNotFound
+		}
+		// an archive is a folder of the uploads, not all of them
+		if !insideDir(dir, resolvedRoot) || (root.uploads && dir == resolvedRoot) {
+			return "", false, importer.ErrInvalidSourcePath
+		}
+		return dir, root.uploads, nil
+	}
+	return "", false, importer.ErrInvalidSourcePath
+}
+
+// insideDir reports whether path is dir or below it, both must be absolute and clean
+func insideDir(path, dir string) bool {
+	rel, err := filepath.Rel(dir, path)
+	return err == nil && rel != ".." && !strings.HasPrefix(rel, ".."+string(filepath.Separator))
+}
+
+// ApplyImport creates the pages of a (possibly edited) import plan below the given parent.
+// Slugs which are already taken below the same parent are resolved by plan.OnCollision:
+// a numeric suffix (default), merging into the existing page, or rejecting the whole import
+// with the list of collisions. The report counts the pages and lists every collision.
+func (w *Wiki) ApplyImport(plan *importer.Plan, parentID *string) (*importer.Report, error) {
+	ve := errors.NewValidationErrors()
+	if plan == nil || strings.TrimSpace(plan.SourceDir) == "" {
+		ve.Add("sourceDir", "Source directory must not be empty")
+	} else {
+		if !plan.OnCollision.Valid() {
+			ve.Add("onCollision", "On collision must be suffix, reject or merge")
+		}
+		w.validateImportPages(plan.Pages, ve)
+	}
+	if ve.HasErrors() {
+		return nil, ve
+	}
+	sourceDir, uploaded, err := w.importSource(plan.SourceDir)
+	if err != nil {
+		return nil, err
+	}
+
+	parent := w.tree.GetTree()
+	if parentID != nil && *parentID != "" && *parentID != "root" {
+		var err error
+		parent, err = w.tree.FindPageByID(w.tree.GetTree().Children, *parentID)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	if plan.OnCollision == importer.CollisionReject {
+		findImportCollisions(parent, plan.Pages, ve)
+		if ve.HasErrors() {
+			return nil, ve
+		}
+	}
+
+	report := &importer.Report{Collisions: []importer.Collision{}}
+	err = w.applyImportPages(sourceDir, parent, plan.Pages, plan.OnCollision, report)
+	w.invalidateAliases()
+	if err == nil && uploaded {
+		_ = os.RemoveAll(sourceDir)
+	}
+	return report, err
+}
+
+func (w *Wiki) validateImportPages(pages []*importer.ProposedPage, ve *errors.ValidationErrors) {
+	for _, p := range pages {
+		if strings.TrimSpace(p.Title) == "" {
+			ve.Add("pages", fmt.Sprintf("Title of '%s' must not be empty", p.SourcePath))
+		}
+		if err := w.slug.IsValidSlug(p.Slug); err != nil {
+			ve.Add("pages", fmt.Sprintf("Invalid slug '%s': %s", p.Slug, err.Error()))
+		}
+		w.validateImportPages(p.Children, ve)
+	}
+}
+
+// findImportCollisions adds an error for every page whose slug is taken by an existing page
+// or a sibling in the plan. parent is nil below pages which don't exist yet.
+func findImportCollisions(parent *tree.PageNode, pages []*importer.ProposedPage, ve *errors.ValidationErrors) {
+	seen := map[string]bool{}
+	for _, p := range pages {
+		name := p.SourcePath
+		if name == "" {
+			name = p.Title
+		}
+		if seen[p.Slug] || (parent != nil && parent.ChildAlreadyExists(p.Slug)) {
+			ve.Add("pages", fmt.Sprintf("Slug '%s' of '%s' is already taken", p.Slug, name))
+		}
+		seen[p.Slug] = true
+		findImportCollisions(nil, p.Children, ve)
+	}
+}
+
+// applyImportPages creates the pages depth-first, so parents always exist before their children
+func (w *Wiki) applyImportPages(sourceDir string, parent *tree.PageNode, pages []*importer.ProposedPage, strategy importer.CollisionStrategy, report *importer.Report) error {
+	// pages directly below the root are created without a parent id
+	var parentID *string
+	if parent.Parent != nil {
+		parentID = &parent.ID
+	}
+
+	for _, p := range pages {
+		content := ""
+		if p.SourcePath != "" {
+			var err error
+			content, err = importer.ReadSource(sourceDir, p.SourcePath)
+			if err != nil {
+				return fmt.Errorf("failed to read %s: %w", p.SourcePath, err)
+			}
+		}
+
+		var existing *tree.PageNode
+		for _, child := range parent.Children {
+			if child.Slug == p.Slug {
+				existing = child
+				break
+			}
+		}
+
+		node := existing
+		if existing != nil && strategy == importer.CollisionMerge {
+			merged, err := w.mergeImportedPage(existing, content)
+			if err != nil {
+				return err
+			}
+			collision := importer.Collision{
+				SourcePath: p.SourcePath, Slug: p.Slug, Resolution: importer.ResolutionMerged,
+				PageID: existing.ID, ResolvedSlug: existing.Slug,
+			}
+			if merged {
+				report.Merged++
+			} else {
+				report.Skipped++
+				collision.Resolution = importer.ResolutionSkipped
+				collision.Reason = "the existing page already has content"
+			}
+			report.Collisions = append(report.Collisions, collision)
+		} else {
+			title := strings.TrimSpace(p.Title)
+			slug := w.slug.GenerateUniqueSlug(parent, "", p.Slug)
+			id, err := w.tree.CreatePage(parentID, title, slug)
+			if err != nil {
+				return err
+			}
+			report.Created++
+
+			if content != "" {
+				if err := w.tree.UpdatePage(*id, title, slug, content); err != nil {
+					return err
+				}
+			}
+			if slug != p.Slug {
+				report.Collisions = append(report.Collisions, importer.Collision{
+					SourcePath: p.SourcePath, Slug: p.Slug, Resolution: importer.ResolutionRenamed,
+					PageID: *id, ResolvedSlug: slug,
+				})
+			}
+
+			node, err = w.tree.FindPageByID(w.tree.GetTree().Children, *id)
+			if err != nil {
+				return err
+			}
+		}
+
+		if err := w.applyImportPages(sourceDir, node, p.Children, strategy, report); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// mergeImportedPage gives an existing page the imported content, unless it already has content
+// besides the heading written for new pages. It reports false if the existing content was kept.
+// Pages without imported content, like folders, are always merged.
+func (w *Wiki) mergeImportedPage(existing *tree.PageNode, content string) (bool, error) {
+	if content == "" {
+		return true, nil
+	}
+	page, err := w.tree.GetPage(existing.ID)
+	if err != nil {
+		return false, err
+	}
+	if existing := strings.TrimSpace(page.Content); existing != "" && existing != "# "+page.Title {
+		return false, nil
+	}
+	return true, w.tree.UpdatePage(existing.ID, existing.Title, existing.Slug, content)
+}
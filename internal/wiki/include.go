@@ -0,0 +1,31 @@
+package wiki
+
+import (
+	"github.com/Gomez12/wiki/internal/core/assets"
+	"github.com/Gomez12/wiki/internal/core/include"
+	"github.com/Gomez12/wiki/internal/core/macro"
+	"github.com/Gomez12/wiki/internal/core/obsidian"
+	"github.com/Gomez12/wiki/internal/core/tree"
+)
+
+// expandedContent returns the Markdown of a page as used when it is rendered: the
+// {{include: path}} directives are replaced by the included pages, then the macros are
+// expanded for the page, also those of the included pages. Pages with childIndex: true get
+// the listing of their child pages appended. Vaults get their wikilinks and embeds
+// converted first. Links to the assets next to a page point to /assets/<page id>/, also
+// in the included pages.
+func (w *Wiki) expandedContent(page *tree.Page) string {
+	route, _ := w.routeOf(page.ID)
+	content := assets.ResolvePageFolderLinks(page.Content, page.ID)
+	if w.obsidian {
+		content = obsidian.Expand(content, route, vaultResolver{w})
+	}
+	content = include.Expand(content, route, func(route string) (string, bool) {
+		included, err := w.FindByPath(route)
+		if err != nil {
+			return "", false
+		}
+		return assets.ResolvePageFolderLinks(included.Content, included.ID), true
+	})
+	return w.appendChildIndex(page, macro.Expand(content, w.pageMacros(page)))
+}
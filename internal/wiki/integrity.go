@@ -0,0 +1,91 @@
+package wiki
+
+import (
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/Gomez12/wiki/internal/search"
+)
+
+// integrityState remembers the report of the last integrity check
+type integrityState struct {
+	mu   sync.Mutex
+	last *search.IntegrityReport
+}
+
+// CheckIntegrity checks the search database and repairs it if it is damaged: the index
+// tables are rebuilt and all pages are indexed again from the data dir. The file history
+// can't be rebuilt, problems which remain after the repair are reported in RepairError.
+// The report is kept for LastIntegrityCheck.
+func (w *Wiki) CheckIntegrity() *search.IntegrityReport {
+	return w.checkIntegrity(true)
+}
+
+// LastIntegrityCheck returns the report of the last integrity check, which runs at startup
+func (w *Wiki) LastIntegrityCheck() *search.IntegrityReport {
+	w.integrity.mu.Lock()
+	defer w.integrity.mu.Unlock()
+	return w.integrity.last
+}
+
+// checkIntegrity runs the check and the repair. The pages are only indexed again with
+// reindex, at startup the initial indexing follows anyway.
+func (w *Wiki) checkIntegrity(reindex bool) *search.IntegrityReport {
+	report := &search.IntegrityReport{CheckedAt: time.Now().UTC(), Problems: []string{}}
+	problems, err := w.searchIndex.CheckIntegrity()
+	if err != nil {
+		problems = []string{err.Error()}
+	}
+	report.Problems = problems
+	report.OK = len(problems) == 0
+
+	if !report.OK {
+		wikiLog.Error("search database is damaged, rebuilding the index", "problems", strings.Join(problems, "; "))
+		report.Repaired, report.RepairError = w.repairIndex(reindex)
+		if report.Repaired {
+			wikiLog.Info("search database repaired")
+		} else {
+			wikiLog.Error("could not repair the search database", "error", report.RepairError)
+		}
+	}
+
+	w.integrity.mu.Lock()
+	w.integrity.last = report
+	w.integrity.mu.Unlock()
+	return report
+}
+
+func (w *Wiki) repairIndex(reindex bool) (bool, string) {
+	if err := w.searchIndex.RebuildIndexTables(); err != nil {
+		return false, err.Error()
+	}
+	remaining, err := w.searchIndex.CheckIntegrity()
+	if err != nil {
+		return false, err.Error()
+	}
+	if len(remaining) > 0 {
+		return false, strings.Join(remaining, "; ")
+	}
+	if reindex {
+		if err := w.ReindexAll(); err != nil {
+			return false, err.Error()
+		}
+	}
+	return true, ""
+}
+
+// runIntegrityCheck checks the search database periodically until stop is closed
+func (w *Wiki) runIntegrityCheck(stop <-chan struct{}) {
+	ticker := time.NewTicker(w.integrityInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			w.checkIntegrity(true)
+		case <-stop:
+			return
+		}
+	}
+}
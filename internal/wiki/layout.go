@@ -0,0 +1,123 @@
+package wiki
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path"
+
+	"github.com/Gomez12/wiki/internal/core/assets"
+	"github.com/Gomez12/wiki/internal/core/tree"
+)
+
+// LayoutMigration is the result of MigrateLayout
+type LayoutMigration struct {
+	From tree.Layout `json:"from"`
+	To   tree.Layout `json:"to"`
+	// Moved counts the page files which were moved to the new layout
+	Moved int `json:"moved"`
+}
+
+// resolveLayout returns the layout of the data dir. A new data dir gets the requested
+// layout, flat if none is requested. A data dir keeps its layout, requesting another one
+// fails, because the pages have to be migrated with MigrateLayout first.
+func resolveLayout(storageDir string, treeService *tree.TreeService, requested tree.Layout) (tree.Layout, error) {
+	recorded, ok, err := tree.LoadLayout(storageDir)
+	if err != nil {
+		return "", err
+	}
+	if !ok && len(treeService.GetTree().Children) == 0 {
+		// a new data dir
+		if requested == "" {
+			requested = tree.LayoutFlat
+		}
+		return requested, tree.SaveLayout(storageDir, requested)
+	}
+	if requested != "" && requested != recorded {
+		return "", fmt.Errorf("the data dir uses the %s layout, migrate it to the %s layout with the migrate-layout command first", recorded, requested)
+	}
+	if !ok {
+		return recorded, tree.SaveLayout(storageDir, recorded)
+	}
+	return recorded, nil
+}
+
+// Layout returns how the pages are stored in the data dir
+func (w *Wiki) Layout() tree.Layout {
+	return w.tree.Layout()
+}
+
+// MigrateLayout moves the files of all pages to the layout and records it for the data dir.
+// The moves are recorded in the page history, so the history of every page continues at
+// its new file. It must not run while a server uses the data dir.
+func (w *Wiki) MigrateLayout(layout tree.Layout) (*LayoutMigration, error) {
+	from := w.tree.Layout()
+	dataDir := path.Join(w.storageDir, "root")
+	// the moves continue the history, so it has to include the latest edits
+	if err := w.searchIndex.CaptureFileHistory(context.Background(), dataDir); err != nil {
+		return nil, err
+	}
+	moves, err := w.tree.MigrateLayout(layout)
+	if len(moves) > 0 {
+		if recordErr := w.searchIndex.RecordMoves(dataDir, moves); recordErr != nil {
+			wikiLog.Error("could not record the moved pages in the history", "error", recordErr)
+		}
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &LayoutMigration{From: from, To: layout, Moved: len(moves)}, nil
+}
+
+// ensurePageFolder turns a page stored as slug.md into slug/index.md, e.g. to store its assets
+// next to it, and records the move in the page history
+func (w *Wiki) ensurePageFolder(id string) error {
+	from, to, err := w.tree.EnsurePageFolder(id)
+	if err != nil || from == "" {
+		return err
+	}
+	if err := w.searchIndex.RecordMoves(path.Join(w.storageDir, "root"), map[string]string{from: to}); err != nil {
+		wikiLog.Error("could not record the moved page in the history", "error", err)
+	}
+	return nil
+}
+
+// hasPageFolderAssets reports whether assets are stored next to the page
+func hasPageFolderAssets(service *assets.AssetService, page *tree.PageNode) bool {
+	entries, err := os.ReadDir(service.PageFolderPath(page))
+	return err == nil && len(entries) > 0
+}
+
+// PageFolderAsset returns the file of an asset stored next to its page
+func (w *Wiki) PageFolderAsset(pageID string, filename string) (string, error) {
+	page, err := w.tree.FindPageByID(w.tree.GetTree().Children, pageID)
+	if err != nil {
+		return "", err
+	}
+	if filename == "" || filename != path.Base(filename) {
+		return "", fmt.Errorf("%w: %s", assets.ErrInvalidAssetName, filename)
+	}
+	fullPath := path.Join(w.asset.PageFolderPath(page), filename)
+	if info, err := os.Stat(fullPath); err != nil || !info.Mode().IsRegular() {
+		return "", fmt.Errorf("%w: %s", assets.ErrAssetNotFound, filename)
+	}
+	return fullPath, nil
+}
+
+// AssetFile returns the file served at /assets/<page id>/<name>, stored next to the page
+// or in the assets dir. The assets of deleted pages which are left in the assets dir are
+// found as well.
+func (w *Wiki) AssetFile(pageID string, filename string) (string, error) {
+	if fullPath, err := w.PageFolderAsset(pageID, filename); err == nil {
+		return fullPath, nil
+	}
+	if pageID == "" || pageID != path.Base(pageID) || pageID == ".." ||
+		filename == "" || filename != path.Base(filename) || filename == ".." {
+		return "", fmt.Errorf("%w: %s", assets.ErrInvalidAssetName, filename)
+	}
+	fullPath := path.Join(w.asset.GetAssetsDir(), pageID, filename)
+	if info, err := os.Stat(fullPath); err != nil || !info.Mode().IsRegular() {
+		return "", fmt.Errorf("%w: %s", assets.ErrAssetNotFound, filename)
+	}
+	return fullPath, nil
+}
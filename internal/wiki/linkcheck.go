@@ -0,0 +1,146 @@
+package wiki
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"time"
+
+	"github.com/Gomez12/wiki/internal/core/linkcheck"
+	"github.com/Gomez12/wiki/internal/core/tree"
+)
+
+// DeadLinksPage lists the dead external links of a page
+type DeadLinksPage struct {
+	PageID string             `json:"pageId"`
+	Title  string             `json:"title"`
+	Path   string             `json:"path"`
+	Links  []linkcheck.Result `json:"links"`
+}
+
+// LinkReport is the outcome of the last link check
+type LinkReport struct {
+	Running bool `json:"running"`
+	// CheckedAt is nil until the first check finished
+	CheckedAt *time.Time `json:"checkedAt"`
+	// Checked counts the checked links of all pages, Dead the ones listed in Pages
+	Checked int             `json:"checked"`
+	Dead    int             `json:"dead"`
+	Pages   []DeadLinksPage `json:"pages"`
+}
+
+// CheckLinks starts checking the external links of all pages in the background
+// and returns the report of the previous check.
+func (w *Wiki) CheckLinks() (*LinkReport, error) {
+	if err := w.links.Begin(); err != nil {
+		return nil, err
+	}
+	err := w.startJob(func() {
+		defer w.links.End()
+		w.checkLinks()
+	})
+	if err != nil {
+		w.links.End()
+		return nil, err
+	}
+	return w.GetLinkReport()
+}
+
+// checkLinks checks the links of all pages, it is cancelled on shutdown
+func (w *Wiki) checkLinks() {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go func() {
+		select {
+		case <-w.stopPeriodic:
+			cancel()
+		case <-ctx.Done():
+		}
+	}()
+
+	pages := []linkcheck.Page{}
+	var collect func(nodes []*tree.PageNode)
+	collect = func(nodes []*tree.PageNode) {
+		for _, node := range nodes {
+			page, err := w.tree.GetPage(node.ID)
+			if err != nil {
+				wikiLog.Warn("link check: could not read page", "pageId", node.ID, "error", err)
+			} else {
+				pages = append(pages, linkcheck.Page{ID: page.ID, Content: page.Content})
+			}
+			collect(node.Children)
+		}
+	}
+	if root := w.tree.GetTree(); root != nil {
+		collect(root.Children)
+	}
+
+	started := time.Now()
+	if err := w.links.Check(ctx, pages); err != nil {
+		if !errors.Is(err, context.Canceled) {
+			wikiLog.Error("link check failed", "error", err)
+		}
+		return
+	}
+	wikiLog.Info("link check finished", "pages", len(pages), "duration", time.Since(started))
+}
+
+// GetLinkReport lists the dead links of the last check per page.
+// Pages deleted since the check are left out.
+func (w *Wiki) GetLinkReport() (*LinkReport, error) {
+	results, err := w.links.Results()
+	if err != nil {
+		return nil, err
+	}
+
+	report := &LinkReport{Running: w.links.Running(), Pages: []DeadLinksPage{}}
+	byPage := map[string]int{}
+	for _, result := range results {
+		if report.CheckedAt == nil || result.CheckedAt.After(*report.CheckedAt) {
+			checkedAt := result.CheckedAt
+			report.CheckedAt = &checkedAt
+		}
+		report.Checked++
+		if !result.Dead() {
+			continue
+		}
+		i, ok := byPage[result.PageID]
+		if !ok {
+			node, err := w.tree.FindPageByID(w.tree.GetTree().Children, result.PageID)
+			if err != nil {
+				continue
+			}
+			i = len(report.Pages)
+			byPage[result.PageID] = i
+			report.Pages = append(report.Pages, DeadLinksPage{
+				PageID: node.ID,
+				Title:  node.Title,
+				Path:   strings.TrimPrefix(node.CalculatePath(), "/"),
+				Links:  []linkcheck.Result{},
+			})
+		}
+		report.Dead++
+		report.Pages[i].Links = append(report.Pages[i].Links, result)
+	}
+	return report, nil
+}
+
+// runLinkCheck checks the links periodically until stop is closed
+func (w *Wiki) runLinkCheck(stop <-chan struct{}) {
+	ticker := time.NewTicker(w.links.Interval())
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+		case <-stop:
+			return
+		}
+		if err := w.links.Begin(); err != nil {
+			wikiLog.Info("skipping scheduled link check", "reason", err)
+			continue
+		}
+		w.checkLinks()
+		w.links.End()
+	}
+}
@@ -0,0 +1,82 @@
+package wiki
+
+import (
+	"path"
+	"regexp"
+	"strings"
+
+	"github.com/Gomez12/wiki/internal/core/tree"
+)
+
+// routeOf returns the route of a page, e.g. docs/guide
+func (w *Wiki) routeOf(id string) (string, bool) {
+	node, err := w.tree.FindPageByID(w.tree.GetTree().Children, id)
+	if err != nil {
+		return "", false
+	}
+	return strings.TrimPrefix(node.CalculatePath(), "/"), true
+}
+
+// pageMoved updates the links and redirects after the route of a page may have changed
+func (w *Wiki) pageMoved(id, oldRoute string) {
+	newRoute, ok := w.routeOf(id)
+	if !ok || oldRoute == newRoute || oldRoute == "" {
+		return
+	}
+	w.updateLinksAfterMove(oldRoute, newRoute)
+	w.addRedirects(id, oldRoute, newRoute)
+}
+
+// updateLinksAfterMove rewrites the links to a page and its descendants after its route
+// changed, so moving or renaming doesn't break navigation. Failures are logged, the move
+// itself already succeeded.
+func (w *Wiki) updateLinksAfterMove(oldRoute, newRoute string) {
+	rewriteAll := linkRewriter(oldRoute, newRoute)
+	dataDir := path.Join(w.storageDir, "root")
+	updated := 0
+	var walk func(nodes []*tree.PageNode)
+	walk = func(nodes []*tree.PageNode) {
+		for _, node := range nodes {
+			walk(node.Children)
+			page, err := w.tree.GetPage(node.ID)
+			if err != nil {
+				wikiLog.Warn("link update: could not read page", "pageId", node.ID, "error", err)
+				continue
+			}
+			content := rewriteAll(page.Content)
+			if content == page.Content {
+				continue
+			}
+			if err := w.writeReplacement(dataDir, page, content); err != nil {
+				wikiLog.Warn("link update: could not update page", "pageId", node.ID, "error", err)
+				continue
+			}
+			updated++
+		}
+	}
+	walk(w.tree.GetTree().Children)
+	if updated > 0 {
+		wikiLog.Info("updated links to moved page", "from", oldRoute, "to", newRoute, "pages", updated)
+	}
+}
+
+// linkRewriter returns a function which points the links to oldRoute and the routes below
+// it in Markdown content to newRoute
+func linkRewriter(oldRoute, newRoute string) func(content string) string {
+	rewrite := func(target string) string {
+		rest, ok := strings.CutPrefix(strings.TrimSuffix(target, "/"), oldRoute)
+		if !ok || (rest != "" && !strings.HasPrefix(rest, "/")) {
+			return target
+		}
+		return newRoute + rest
+	}
+	return func(content string) string {
+		for _, re := range []*regexp.Regexp{wikiLinkRegex, referenceLinkRegex} {
+			content = re.ReplaceAllStringFunc(content, func(match string) string {
+				groups := re.FindStringSubmatch(match)
+				return groups[1] + rewrite(groups[2])
+			})
+		}
+		return content
+	}
+}
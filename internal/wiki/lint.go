@@ -0,0 +1,71 @@
+package wiki
+
+import (
+	"net/url"
+	"path"
+	"strings"
+
+	"github.com/Gomez12/wiki/internal/core/lint"
+	"github.com/Gomez12/wiki/internal/core/settings"
+	"github.com/Gomez12/wiki/internal/core/tree"
+)
+
+// LintPage checks the saved content of a page with the lint rules enabled in the settings
+func (w *Wiki) LintPage(pageID string) (*lint.Result, error) {
+	page, err := w.tree.GetPage(pageID)
+	if err != nil {
+		return nil, err
+	}
+	result := w.lintPage(page)
+	return &result, nil
+}
+
+// lintPage checks the content of a page, links are resolved relative to its route like the
+// browser does
+func (w *Wiki) lintPage(page *tree.Page) lint.Result {
+	s, err := w.GetSettings()
+	if err != nil {
+		wikiLog.Warn("lint: could not read settings", "error", err)
+		s = settings.Defaults()
+	}
+	dir := path.Dir(page.CalculatePath())
+	return lint.Page(page.Content, s.Lint, func(target string) bool {
+		return w.linkTargetExists(page.PageNode, dir, target)
+	})
+}
+
+// linkTargetExists reports whether a link leads to a page, a moved page or an asset. dir is
+// the route the link is relative to, e.g. /docs for a link on the page docs/guide. Links to
+// the assets next to the page, like ./assets/diagram.png, are resolved like the renderer does,
+// see assets.ResolvePageFolderLinks.
+func (w *Wiki) linkTargetExists(page *tree.PageNode, dir, target string) bool {
+	if unescaped, err := url.PathUnescape(target); err == nil {
+		target = unescaped
+	}
+	if name, ok := strings.CutPrefix(strings.TrimPrefix(target, "./"), "assets/"); ok {
+		_, err := w.asset.AssetFilePath(page, name)
+		return err == nil
+	}
+	if !strings.HasPrefix(target, "/") {
+		target = path.Join(dir, target)
+	}
+	route := strings.Trim(path.Clean("/"+target), "/")
+	if route == "" {
+		return true
+	}
+
+	if rest, ok := strings.CutPrefix(route, "assets/"); ok {
+		pageID, name, _ := strings.Cut(rest, "/")
+		owner, err := w.tree.FindPageByID(w.tree.GetTree().Children, pageID)
+		if err != nil {
+			return false
+		}
+		_, err = w.asset.AssetFilePath(owner, name)
+		return err == nil
+	}
+	if _, err := w.FindByPath(route); err == nil {
+		return true
+	}
+	_, err := w.ResolveRedirect(route)
+	return err == nil
+}
@@ -0,0 +1,5 @@
+package wiki
+
+import "github.com/Gomez12/wiki/internal/core/logging"
+
+var wikiLog = logging.Component("wiki")
@@ -0,0 +1,140 @@
+package wiki
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/Gomez12/wiki/internal/core/frontmatter"
+	"github.com/Gomez12/wiki/internal/core/macro"
+	"github.com/Gomez12/wiki/internal/core/tree"
+	"github.com/Gomez12/wiki/internal/search"
+)
+
+// maxChildListDepth limits the levels listed by {{childlist depth=n}}
+const maxChildListDepth = 5
+
+// defaultRecentChanges is the number of pages listed by {{recentchanges}} without a limit
+const defaultRecentChanges = 10
+
+// maxRecentChanges limits the pages listed by {{recentchanges limit=n}}
+const maxRecentChanges = 100
+
+// pageMacros returns the macros expanded when the page is rendered:
+//
+//	{{date}}                             the current date, e.g. 2024-05-01
+//	{{pagetitle}}                        the title of the page
+//	{{field name=status}}                a frontmatter field of the page, empty if missing
+//	{{childlist depth=2}}                links to the pages below, nested up to depth levels
+//	{{recentchanges path=docs limit=5}}  the last changed pages below path, newest first
+func (w *Wiki) pageMacros(page *tree.Page) macro.Macros {
+	return macro.Macros{
+		"date": func(map[string]string) (string, error) {
+			return time.Now().Format(time.DateOnly), nil
+		},
+		"pagetitle": func(map[string]string) (string, error) {
+			return page.Title, nil
+		},
+		"field": func(args map[string]string) (string, error) {
+			name, ok := args["name"]
+			if !ok {
+				return "", errors.New("name is missing")
+			}
+			fields, _, err := frontmatter.Parse(page.Content)
+			if err != nil {
+				return "", err
+			}
+			value, _ := frontmatter.String(fields, name)
+			return value, nil
+		},
+		"childlist": func(args map[string]string) (string, error) {
+			depth, err := intArg(args, "depth", 1, maxChildListDepth)
+			if err != nil {
+				return "", err
+			}
+			var b strings.Builder
+			writeChildList(&b, page.Children, depth, "")
+			return strings.TrimRight(b.String(), "\n"), nil
+		},
+		"recentchanges": func(args map[string]string) (string, error) {
+			limit, err := intArg(args, "limit", defaultRecentChanges, maxRecentChanges)
+			if err != nil {
+				return "", err
+			}
+			return w.recentChanges(strings.Trim(args["path"], "/"), limit)
+		},
+	}
+}
+
+// writeChildList writes the pages as nested Markdown list
+func writeChildList(b *strings.Builder, nodes []*tree.PageNode, depth int, indent string) {
+	for _, node := range nodes {
+		fmt.Fprintf(b, "%s- [%s](%s)\n", indent, escapeLinkText(node.Title), node.CalculatePath())
+		if depth > 1 {
+			writeChildList(b, node.Children, depth-1, indent+"  ")
+		}
+	}
+}
+
+// recentChanges lists the pages below the route by the time of their last change
+func (w *Wiki) recentChanges(route string, limit int) (string, error) {
+	if w.searchIndex == nil {
+		return "", errors.New("the page history is not available")
+	}
+	nodes := w.tree.GetTree().Children
+	if route != "" {
+		page, err := w.FindByPath(route)
+		if err != nil {
+			return "", fmt.Errorf("page not found: %s", route)
+		}
+		nodes = page.Children
+	}
+
+	type change struct {
+		node *tree.PageNode
+		at   time.Time
+	}
+	changes := []change{}
+	var walk func(nodes []*tree.PageNode)
+	walk = func(nodes []*tree.PageNode) {
+		for _, node := range nodes {
+			entries, _, err := w.searchIndex.QueryHistoryForPath(node.CalculatePath(), search.HistoryOptions{Limit: 1})
+			if err == nil && len(entries) > 0 {
+				changes = append(changes, change{node, entries[0].RecordedAt})
+			}
+			walk(node.Children)
+		}
+	}
+	walk(nodes)
+
+	sort.SliceStable(changes, func(i, j int) bool { return changes[i].at.After(changes[j].at) })
+	if len(changes) > limit {
+		changes = changes[:limit]
+	}
+	var b strings.Builder
+	for _, c := range changes {
+		fmt.Fprintf(&b, "- [%s](%s) – %s\n", escapeLinkText(c.node.Title), c.node.CalculatePath(), c.at.Format(time.DateOnly))
+	}
+	return strings.TrimRight(b.String(), "\n"), nil
+}
+
+// intArg parses a numeric macro argument between 1 and max
+func intArg(args map[string]string, name string, fallback, max int) (int, error) {
+	value, ok := args[name]
+	if !ok {
+		return fallback, nil
+	}
+	n, err := strconv.Atoi(value)
+	if err != nil || n < 1 || n > max {
+		return 0, fmt.Errorf("%s must be a number between 1 and %d", name, max)
+	}
+	return n, nil
+}
+
+// escapeLinkText escapes the brackets of a title used as link text
+func escapeLinkText(title string) string {
+	return strings.NewReplacer(`\`, `\\`, "[", `\[`, "]", `\]`).Replace(title)
+}
@@ -0,0 +1,125 @@
+package wiki
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/Gomez12/wiki/internal/core/tree"
+)
+
+// Reasons why a page can't be moved
+const (
+	MoveConflictSameParent = "sameParent"
+	MoveConflictSlugTaken  = "slugTaken"
+	MoveConflictItself     = "itself"
+	MoveConflictCircular   = "circular"
+)
+
+// MoveCheck describes the effects of moving a page before it is moved
+type MoveCheck struct {
+	PageID   string `json:"pageId"`
+	OldRoute string `json:"oldRoute"`
+	NewRoute string `json:"newRoute"`
+	// Conflict is the reason the move would fail, empty if the page can be moved
+	Conflict string `json:"conflict,omitempty"`
+	// ConflictingPageID is the page below the new parent which already has the slug
+	ConflictingPageID string `json:"conflictingPageId,omitempty"`
+	// Redirects are added from the old routes of the page and its descendants
+	Redirects []MovedRoute `json:"redirects"`
+	// LinkUpdates are the pages whose links to the moved pages would be rewritten
+	LinkUpdates []LinkUpdate `json:"linkUpdates"`
+}
+
+// MovedRoute is the old and the new route of a page affected by a move
+type MovedRoute struct {
+	PageID string `json:"pageId"`
+	From   string `json:"from"`
+	To     string `json:"to"`
+}
+
+// LinkUpdate is a page which links to a moved page
+type LinkUpdate struct {
+	PageID string `json:"pageId"`
+	Title  string `json:"title"`
+	Path   string `json:"path"`
+}
+
+// CheckMove reports whether the page can be moved below the parent, and which redirects
+// and link updates the move would cause, without changing anything. Conflicts are reported
+// in the result; a missing page or parent is an error.
+func (w *Wiki) CheckMove(id, parentID string) (*MoveCheck, error) {
+	root := w.tree.GetTree()
+	if root == nil {
+		return nil, tree.ErrTreeNotLoaded
+	}
+	node, err := w.tree.FindPageByID(root.Children, id)
+	if err != nil {
+		return nil, tree.ErrPageNotFound
+	}
+	parent := root
+	if parentID != "" && parentID != "root" {
+		parent, err = w.tree.FindPageByID(root.Children, parentID)
+		if err != nil {
+			return nil, fmt.Errorf("new parent not found: %w", tree.ErrParentNotFound)
+		}
+	}
+
+	oldRoute := strings.TrimPrefix(node.CalculatePath(), "/")
+	newRoute := node.Slug
+	if parent != root {
+		newRoute = strings.TrimPrefix(parent.CalculatePath(), "/") + "/" + node.Slug
+	}
+	check := &MoveCheck{
+		PageID:      node.ID,
+		OldRoute:    oldRoute,
+		NewRoute:    newRoute,
+		Redirects:   []MovedRoute{},
+		LinkUpdates: []LinkUpdate{},
+	}
+
+	// the same order of checks as the move itself
+	switch {
+	case node.Parent == parent:
+		check.Conflict = MoveConflictSameParent
+	case parent.ChildAlreadyExists(node.Slug):
+		check.Conflict = MoveConflictSlugTaken
+		for _, child := range parent.Children {
+			if child.Slug == node.Slug {
+				check.ConflictingPageID = child.ID
+			}
+		}
+	case node.ID == parent.ID:
+		check.Conflict = MoveConflictItself
+	case node.IsChildOf(parent.ID, true):
+		check.Conflict = MoveConflictCircular
+	}
+	if check.Conflict != "" {
+		return check, nil
+	}
+
+	var addRedirects func(n *tree.PageNode)
+	addRedirects = func(n *tree.PageNode) {
+		from := strings.TrimPrefix(n.CalculatePath(), "/")
+		check.Redirects = append(check.Redirects, MovedRoute{PageID: n.ID, From: from, To: newRoute + strings.TrimPrefix(from, oldRoute)})
+		for _, child := range n.Children {
+			addRedirects(child)
+		}
+	}
+	addRedirects(node)
+
+	rewriteAll := linkRewriter(oldRoute, newRoute)
+	var walk func(nodes []*tree.PageNode)
+	walk = func(nodes []*tree.PageNode) {
+		for _, n := range nodes {
+			page, err := w.tree.GetPage(n.ID)
+			if err != nil {
+				wikiLog.Warn("move check: could not read page", "pageId", n.ID, "error", err)
+			} else if rewriteAll(page.Content) != page.Content {
+				check.LinkUpdates = append(check.LinkUpdates, LinkUpdate{PageID: n.ID, Title: n.Title, Path: strings.TrimPrefix(n.CalculatePath(), "/")})
+			}
+			walk(n.Children)
+		}
+	}
+	walk(root.Children)
+	return check, nil
+}
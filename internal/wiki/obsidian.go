@@ -0,0 +1,174 @@
+package wiki
+
+import (
+	"encoding/json"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+
+	"github.com/Gomez12/wiki/internal/core/obsidian"
+	"github.com/Gomez12/wiki/internal/core/tree"
+)
+
+// VaultFilesPrefix is the URL prefix of the attachments of an Obsidian vault
+const VaultFilesPrefix = "/vault/"
+
+// obsidianIgnorePatterns keep the settings and the trash of a vault out of the wiki
+var obsidianIgnorePatterns = []string{"/" + obsidian.ConfigDir + "/", "/" + obsidian.TrashDir + "/"}
+
+// ignorePatterns returns the patterns added to the rules of the ignore file
+func ignorePatterns(obsidianMode bool, patterns []string) []string {
+	if !obsidianMode {
+		return patterns
+	}
+	return append(append([]string{}, obsidianIgnorePatterns...), patterns...)
+}
+
+// ObsidianEnabled reports whether the pages are served as Obsidian vault
+func (w *Wiki) ObsidianEnabled() bool {
+	return w.obsidian
+}
+
+// VaultFile returns the path on disk of an attachment of the vault, given by its path relative
+// to the vault. Markdown files, hidden and ignored files are not served.
+func (w *Wiki) VaultFile(rel string) (string, error) {
+	rel = strings.Trim(path.Clean("/"+filepath.ToSlash(rel)), "/")
+	if !w.obsidian || rel == "" || strings.EqualFold(path.Ext(rel), ".md") {
+		return "", ErrVaultFileNotFound
+	}
+	for _, segment := range strings.Split(rel, "/") {
+		if strings.HasPrefix(segment, ".") {
+			return "", ErrVaultFileNotFound
+		}
+	}
+	if w.ignore.Match(rel, false) {
+		return "", ErrVaultFileNotFound
+	}
+	fullPath := filepath.Join(w.storageDir, "root", filepath.FromSlash(rel))
+	if info, err := os.Stat(fullPath); err != nil || !info.Mode().IsRegular() {
+		return "", ErrVaultFileNotFound
+	}
+	return fullPath, nil
+}
+
+// vaultResolver resolves the wikilinks and embeds of a vault against the pages of the wiki
+type vaultResolver struct {
+	w *Wiki
+}
+
+// Note finds a note like Obsidian: by its path in the vault, by its file name with the
+// shortest path winning, or by an alias in its frontmatter. Names are not case-sensitive.
+func (r vaultResolver) Note(name string) (string, string, bool) {
+	name = strings.Trim(strings.TrimSuffix(strings.TrimSpace(name), ".md"), "/")
+	if name == "" {
+		return "", "", false
+	}
+	if page, err := r.w.tree.FindPageByRoutePath(r.w.tree.GetTree().Children, name); err == nil {
+		return strings.TrimPrefix(page.CalculatePath(), "/"), page.Content, true
+	}
+
+	var best *tree.PageNode
+	bestRoute := ""
+	suffix := "/" + strings.ToLower(name)
+	r.walk(func(node *tree.PageNode, route string) bool {
+		if strings.HasSuffix("/"+strings.ToLower(route), suffix) && (best == nil || len(route) < len(bestRoute)) {
+			best, bestRoute = node, route
+		}
+		return false
+	})
+	if best == nil {
+		if page, ok := r.w.pageByAlias(name); ok {
+			return strings.TrimPrefix(page.CalculatePath(), "/"), page.Content, true
+		}
+	}
+	if best == nil {
+		return "", "", false
+	}
+	page, err := r.w.tree.GetPage(best.ID)
+	if err != nil {
+		return "", "", false
+	}
+	return bestRoute, page.Content, true
+}
+
+// walk calls visit for every page until it returns true
+func (r vaultResolver) walk(visit func(node *tree.PageNode, route string) bool) {
+	var walk func(nodes []*tree.PageNode) bool
+	walk = func(nodes []*tree.PageNode) bool {
+		for _, node := range nodes {
+			if visit(node, strings.TrimPrefix(node.CalculatePath(), "/")) || walk(node.Children) {
+				return true
+			}
+		}
+		return false
+	}
+	walk(r.w.tree.GetTree().Children)
+}
+
+// Attachment finds an embedded file like Obsidian: by its path in the vault, in the
+// attachment folder of the vault, next to the note, or else anywhere in the vault
+func (r vaultResolver) Attachment(name, route string) (string, bool) {
+	name = strings.Trim(filepath.ToSlash(name), "/")
+	candidates := []string{name}
+	if !strings.Contains(name, "/") {
+		if folder, ok := r.attachmentFolder(); ok {
+			if dir, relative := strings.CutPrefix(folder, "./"); relative {
+				folder = path.Join(path.Dir(route), dir)
+			}
+			candidates = append(candidates, path.Join(folder, name))
+		}
+		candidates = append(candidates, path.Join(path.Dir(route), name), path.Join(route, name))
+	}
+	for _, candidate := range candidates {
+		if _, err := r.w.VaultFile(candidate); err == nil {
+			return VaultFilesPrefix + obsidian.EscapePath(candidate), true
+		}
+	}
+	if strings.Contains(name, "/") {
+		return "", false
+	}
+
+	root := filepath.Join(r.w.storageDir, "root")
+	found := ""
+	_ = filepath.WalkDir(root, func(p string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return nil
+		}
+		if d.IsDir() && p != root && strings.HasPrefix(d.Name(), ".") {
+			return filepath.SkipDir
+		}
+		if !d.IsDir() && d.Name() == name {
+			rel, err := filepath.Rel(root, p)
+			if err == nil {
+				if _, err := r.w.VaultFile(rel); err == nil {
+					found = filepath.ToSlash(rel)
+					return filepath.SkipAll
+				}
+			}
+		}
+		return nil
+	})
+	if found == "" {
+		return "", false
+	}
+	return VaultFilesPrefix + obsidian.EscapePath(found), true
+}
+
+// attachmentFolder returns the attachment folder configured in the vault, ok is false if
+// attachments are stored next to the notes or in the root
+func (r vaultResolver) attachmentFolder() (string, bool) {
+	data, err := os.ReadFile(filepath.Join(r.w.storageDir, "root", obsidian.ConfigDir, "app.json"))
+	if err != nil {
+		return "", false
+	}
+	var app struct {
+		AttachmentFolderPath string `json:"attachmentFolderPath"`
+	}
+	if err := json.Unmarshal(data, &app); err != nil {
+		return "", false
+	}
+	folder := strings.Trim(app.AttachmentFolderPath, "/")
+	return folder, folder != "" && folder != "."
+}
@@ -0,0 +1,232 @@
+package wiki
+
+import (
+	"time"
+
+	"github.com/Gomez12/wiki/internal/core/access"
+	"github.com/Gomez12/wiki/internal/core/gitsync"
+	"github.com/Gomez12/wiki/internal/core/linkcheck"
+	"github.com/Gomez12/wiki/internal/core/notify"
+	"github.com/Gomez12/wiki/internal/core/securityheaders"
+	"github.com/Gomez12/wiki/internal/core/tree"
+	"github.com/Gomez12/wiki/internal/search"
+)
+
+// Option configures optional behaviour of a Wiki instance
+type Option func(*options)
+
+type options struct {
+	searchPartitions bool
+	accessChecker    access.Checker
+	searchDBConfig   *search.SQLiteConfig
+	webdav           bool
+	gitSync          *gitsync.Config
+	linkCheck        linkcheck.Config
+	// caseInsensitiveRoutes lets routes match slugs which differ only in case
+	caseInsensitiveRoutes bool
+	followSymlinks        bool
+	obsidian              bool
+	// reviewInterval is the time between two scans for due reviews, 0 disables the reminders
+	reviewInterval time.Duration
+	smtp           notify.SMTPConfig
+	// spellcheckDir holds the hunspell dictionaries, empty disables the spellcheck
+	spellcheckDir string
+	// importRoot is the directory whose folders can be imported, empty allows only uploaded
+	// archives
+	importRoot string
+	// searchAlertInterval is the time between two checks of the subscribed searches, 0
+	// disables the alerts
+	searchAlertInterval time.Duration
+	// integrityInterval is the time between two integrity checks of the search database,
+	// 0 checks only at startup
+	integrityInterval time.Duration
+	// layout is how the pages of a new data dir are stored, empty keeps the recorded layout
+	layout tree.Layout
+	// pageFolderAssets stores new assets next to their page instead of assets/<page id>
+	pageFolderAssets bool
+	securityHeaders  securityheaders.Config
+	// basePath is the path the wiki is served under behind a reverse proxy, e.g. /wiki
+	basePath string
+	// trustedProxies may set the X-Forwarded headers, nil trusts proxy.DefaultTrustedProxies
+	trustedProxies []string
+	// configReload reads the config file again, nil without config file
+	configReload func() error
+	// profiling serves the pprof endpoints to admins
+	profiling bool
+	// watchPollInterval makes the watcher poll for changes instead of using file system
+	// events, 0 uses the events
+	watchPollInterval time.Duration
+}
+
+// WithSearchPartitions splits the search index into one partition per top-level page
+func WithSearchPartitions(enabled bool) Option {
+	return func(o *options) {
+		o.searchPartitions = enabled
+	}
+}
+
+// WithAccessChecker sets the checker which decides which pages a user may read.
+// Without a checker every user may read every page.
+func WithAccessChecker(checker access.Checker) Option {
+	return func(o *options) {
+		o.accessChecker = checker
+	}
+}
+
+// WithSearchDBConfig overrides the connection settings of the search database
+func WithSearchDBConfig(config search.SQLiteConfig) Option {
+	return func(o *options) {
+		o.searchDBConfig = &config
+	}
+}
+
+// WithWebDAV allows mounting the Markdown files over WebDAV
+func WithWebDAV(enabled bool) Option {
+	return func(o *options) {
+		o.webdav = enabled
+	}
+}
+
+// WithGitSync syncs the content with a git remote in the background
+func WithGitSync(config gitsync.Config) Option {
+	return func(o *options) {
+		o.gitSync = &config
+	}
+}
+
+// WithLinkCheck configures the check of external links, e.g. to run it periodically
+func WithLinkCheck(config linkcheck.Config) Option {
+	return func(o *options) {
+		o.linkCheck = config
+	}
+}
+
+// WithCaseInsensitiveRoutes resolves routes which differ from a slug only in case, e.g.
+// /Docs/Setup finds docs/setup. Exact matches still win.
+func WithCaseInsensitiveRoutes(enabled bool) Option {
+	return func(o *options) {
+		o.caseInsensitiveRoutes = enabled
+	}
+}
+
+// WithFollowSymlinks indexes and tracks the history of symlinked directories in the data dir
+func WithFollowSymlinks(enabled bool) Option {
+	return func(o *options) {
+		o.followSymlinks = enabled
+	}
+}
+
+// WithObsidian serves the pages as Obsidian vault: wikilinks, embeds and aliases are resolved
+// when pages are rendered, and attachments are served at /vault
+func WithObsidian(enabled bool) Option {
+	return func(o *options) {
+		o.obsidian = enabled
+	}
+}
+
+// WithReviewReminders scans the pages for due review-by dates in this interval and reminds
+// their owners, 0 only lists them
+func WithReviewReminders(interval time.Duration) Option {
+	return func(o *options) {
+		o.reviewInterval = interval
+	}
+}
+
+// WithSearchAlerts checks the subscribed saved searches in this interval and alerts their
+// owners of the pages which started matching
+func WithSearchAlerts(interval time.Duration) Option {
+	return func(o *options) {
+		o.searchAlertInterval = interval
+	}
+}
+
+// WithIntegrityCheck checks the search database in this interval besides at startup and
+// rebuilds the index if it is damaged
+func WithIntegrityCheck(interval time.Duration) Option {
+	return func(o *options) {
+		o.integrityInterval = interval
+	}
+}
+
+// WithLayout sets how the pages of a new data dir are stored. An existing data dir keeps its
+// layout, a different one has to be migrated with MigrateLayout first.
+func WithLayout(layout tree.Layout) Option {
+	return func(o *options) {
+		o.layout = layout
+	}
+}
+
+// WithPageFolderAssets stores new assets in an assets folder next to their page, linked
+// relative to it as ./assets/<name>, so a page folder is portable as plain files
+func WithPageFolderAssets(enabled bool) Option {
+	return func(o *options) {
+		o.pageFolderAssets = enabled
+	}
+}
+
+// WithSMTP sets the mail server used to send review reminders
+func WithSMTP(config notify.SMTPConfig) Option {
+	return func(o *options) {
+		o.smtp = config
+	}
+}
+
+// WithSpellcheck enables the spellcheck with the hunspell dictionaries of dir, e.g.
+// en_US.aff and en_US.dic
+func WithSpellcheck(dir string) Option {
+	return func(o *options) {
+		o.spellcheckDir = dir
+	}
+}
+
+// WithImportRoot allows importing the folders below dir. Without it only uploaded archives can
+// be imported.
+func WithImportRoot(dir string) Option {
+	return func(o *options) {
+		o.importRoot = dir
+	}
+}
+
+// WithSecurityHeaders overrides the security headers sent with every response
+func WithSecurityHeaders(config securityheaders.Config) Option {
+	return func(o *options) {
+		o.securityHeaders = config
+	}
+}
+
+// WithBasePath serves the wiki under a path, e.g. /wiki, instead of the root
+func WithBasePath(basePath string) Option {
+	return func(o *options) {
+		o.basePath = basePath
+	}
+}
+
+// WithConfigReload sets the function reading the config file again, see ReloadConfig
+func WithConfigReload(reload func() error) Option {
+	return func(o *options) {
+		o.configReload = reload
+	}
+}
+
+// WithProfiling serves the profiles of the runtime at /debug/pprof to admins
+func WithProfiling(enabled bool) Option {
+	return func(o *options) {
+		o.profiling = enabled
+	}
+}
+
+// WithWatchPolling makes the watcher look for changes made outside of the wiki in this interval
+// instead of relying on file system events, e.g. for data dirs on NFS or in bind mounts
+func WithWatchPolling(interval time.Duration) Option {
+	return func(o *options) {
+		o.watchPollInterval = interval
+	}
+}
+
+// WithTrustedProxies sets the IP addresses and CIDR ranges of the reverse proxies whose
+// X-Forwarded-For, X-Forwarded-Proto and X-Forwarded-Host headers are trusted
+func WithTrustedProxies(proxies []string) Option {
+	return func(o *options) {
+		o.trustedProxies = proxies
+	}
+}
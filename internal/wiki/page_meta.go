@@ -0,0 +1,157 @@
+package wiki
+
+import (
+	"fmt"
+	"math"
+	"regexp"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/Gomez12/wiki/internal/core/frontmatter"
+	"github.com/Gomez12/wiki/internal/core/tree"
+	"github.com/Gomez12/wiki/internal/search"
+)
+
+// wordsPerMinute is the reading speed used for the reading time estimate
+const wordsPerMinute = 200
+
+// contributorFields are the frontmatter fields listing the people who wrote a page
+var contributorFields = []string{"author", "authors", "contributors"}
+
+// wikiLinkRegex matches root-relative Markdown links, e.g. [Guide](/docs/guide#setup),
+// the second group is the route
+var wikiLinkRegex = regexp.MustCompile(`(\]\(<?/)([^)\s#?>]*)`)
+
+// referenceLinkRegex matches root-relative reference link definitions, e.g. [guide]: /docs/guide,
+// the second group is the route
+var referenceLinkRegex = regexp.MustCompile(`(?m)^(\s{0,3}\[[^\]]+\]:\s*<?/)([^\s#?>]*)`)
+
+// PageMeta is metadata derived from the content and the history of a page
+type PageMeta struct {
+	PageID string `json:"pageId"`
+	Path   string `json:"path"`
+	// CreatedAt and ModifiedAt come from the page history, they are nil while the page has none
+	CreatedAt          *time.Time `json:"createdAt"`
+	ModifiedAt         *time.Time `json:"modifiedAt"`
+	WordCount          int        `json:"wordCount"`
+	CharacterCount     int        `json:"characterCount"`
+	ReadingTimeMinutes int        `json:"readingTimeMinutes"`
+	// Contributors are taken from the author, authors and contributors frontmatter fields
+	Contributors  []string `json:"contributors"`
+	BacklinkCount int      `json:"backlinkCount"`
+}
+
+// GetPageMeta derives the metadata of a page. Words and characters are counted in the
+// Markdown body without frontmatter; backlinks are the other pages linking to the page.
+func (w *Wiki) GetPageMeta(pageID string) (*PageMeta, error) {
+	page, err := w.tree.GetPage(pageID)
+	if err != nil {
+		return nil, err
+	}
+
+	fields, body, err := frontmatter.Parse(page.Content)
+	if err != nil {
+		wikiLog.Warn("page meta: could not parse frontmatter", "pageId", page.ID, "error", err)
+	}
+
+	route := strings.TrimPrefix(page.CalculatePath(), "/")
+	meta := &PageMeta{
+		PageID:         page.ID,
+		Path:           route,
+		WordCount:      len(strings.Fields(body)),
+		CharacterCount: utf8.RuneCountInString(strings.TrimSpace(body)),
+		Contributors:   contributors(fields),
+		BacklinkCount:  w.countBacklinks(page.ID, route),
+	}
+	meta.ReadingTimeMinutes = int(math.Ceil(float64(meta.WordCount) / wordsPerMinute))
+
+	if w.searchIndex != nil {
+		meta.CreatedAt, meta.ModifiedAt = w.historyDates(page.CalculatePath())
+	}
+	return meta, nil
+}
+
+// historyDates returns the time of the oldest and the newest history entry of a path
+func (w *Wiki) historyDates(pagePath string) (*time.Time, *time.Time) {
+	newest, total, err := w.searchIndex.QueryHistoryForPath(pagePath, search.HistoryOptions{Limit: 1})
+	if err != nil {
+		wikiLog.Warn("page meta: could not read history", "path", pagePath, "error", err)
+		return nil, nil
+	}
+	if len(newest) == 0 {
+		return nil, nil
+	}
+	modified := newest[0].RecordedAt
+	created := modified
+	if total > 1 {
+		oldest, _, err := w.searchIndex.QueryHistoryForPath(pagePath, search.HistoryOptions{Limit: 1, Offset: total - 1})
+		if err == nil && len(oldest) > 0 {
+			created = oldest[0].RecordedAt
+		}
+	}
+	return &created, &modified
+}
+
+// contributors collects the names of the contributor fields without duplicates
+func contributors(fields map[string]any) []string {
+	names := []string{}
+	seen := map[string]bool{}
+	add := func(value any) {
+		name := strings.TrimSpace(fmt.Sprint(value))
+		if name != "" && !seen[name] {
+			seen[name] = true
+			names = append(names, name)
+		}
+	}
+	for _, field := range contributorFields {
+		switch value := fields[field].(type) {
+		case nil, map[string]any:
+		case []any:
+			for _, item := range value {
+				add(item)
+			}
+		default:
+			add(value)
+		}
+	}
+	return names
+}
+
+// countBacklinks counts the other pages with a root-relative link to the route
+func (w *Wiki) countBacklinks(pageID, route string) int {
+	count := 0
+	var walk func(nodes []*tree.PageNode)
+	walk = func(nodes []*tree.PageNode) {
+		for _, node := range nodes {
+			walk(node.Children)
+			if node.ID == pageID {
+				continue
+			}
+			page, err := w.tree.GetPage(node.ID)
+			if err != nil {
+				wikiLog.Warn("page meta: could not read page", "pageId", node.ID, "error", err)
+				continue
+			}
+			if linksTo(page.Content, route) {
+				count++
+			}
+		}
+	}
+	if root := w.tree.GetTree(); root != nil {
+		walk(root.Children)
+	}
+	return count
+}
+
+// linksTo returns true if the Markdown content links to the route
+func linksTo(content, route string) bool {
+	for _, re := range []*regexp.Regexp{wikiLinkRegex, referenceLinkRegex} {
+		for _, match := range re.FindAllStringSubmatch(content, -1) {
+			if strings.TrimSuffix(match[2], "/") == route {
+				return true
+			}
+		}
+	}
+	return false
+}
@@ -0,0 +1,36 @@
+package wiki
+
+import (
+	"github.com/Gomez12/wiki/internal/core/auth"
+	"github.com/Gomez12/wiki/internal/core/shared/errors"
+)
+
+// GetPasswordPolicy returns the password policy applied to new passwords
+func (w *Wiki) GetPasswordPolicy() (auth.PasswordPolicy, error) {
+	return w.user.GetPasswordPolicy()
+}
+
+// UpdatePasswordPolicy replaces the password policy. Existing passwords are not affected.
+func (w *Wiki) UpdatePasswordPolicy(policy auth.PasswordPolicy) (auth.PasswordPolicy, error) {
+	if err := policy.Check(); err != nil {
+		ve := errors.NewValidationErrors()
+		ve.Add("minLength", err.Error())
+		return auth.PasswordPolicy{}, ve
+	}
+	if err := w.user.SetPasswordPolicy(policy); err != nil {
+		return auth.PasswordPolicy{}, err
+	}
+	return policy, nil
+}
+
+// validatePassword adds a validation error for every policy requirement the password doesn't meet
+func (w *Wiki) validatePassword(field, password string, ve *errors.ValidationErrors) error {
+	policy, err := w.user.GetPasswordPolicy()
+	if err != nil {
+		return err
+	}
+	for _, msg := range policy.Violations(password) {
+		ve.Add(field, msg)
+	}
+	return nil
+}
@@ -0,0 +1,82 @@
+package wiki
+
+import (
+	"bytes"
+	"encoding/base64"
+	"net/http"
+	"path"
+	"strings"
+	"time"
+
+	"github.com/Gomez12/wiki/internal/core/shared/errors"
+)
+
+// pastedImageTypes are the image types which can be pasted, with the extension they get.
+// SVG is left out, scripts in it would run when the public asset is opened.
+var pastedImageTypes = map[string]string{
+	"image/png":  ".png",
+	"image/jpeg": ".jpg",
+	"image/gif":  ".gif",
+	"image/webp": ".webp",
+}
+
+// maxPastedImageSize limits pasted images besides the upload limit, they are held in memory
+const maxPastedImageSize int64 = 50 << 20
+
+// PastedImage is an image pasted into the editor
+type PastedImage struct {
+	// File is the public path of the stored image
+	File string `json:"file"`
+	// Markdown embeds the image, ready to be inserted at the cursor
+	Markdown string `json:"markdown"`
+}
+
+// PasteImage stores an image from the clipboard as asset of the page, e.g. a screenshot.
+// data is the image itself or a data URL (data:image/png;base64,...); the type is detected
+// from the content. The image is named after the time it was pasted, unless a name is given.
+func (w *Wiki) PasteImage(pageID string, data []byte, name string) (*PastedImage, error) {
+	ve := errors.NewValidationErrors()
+	if text, ok := bytes.CutPrefix(bytes.TrimSpace(data), []byte("data:")); ok {
+		meta, encoded, found := bytes.Cut(text, []byte(","))
+		if !found || !bytes.HasSuffix(meta, []byte(";base64")) {
+			ve.Add("data", "Only base64 data URLs are supported")
+			return nil, ve
+		}
+		decoded, err := base64.StdEncoding.DecodeString(string(bytes.TrimSpace(encoded)))
+		if err != nil {
+			ve.Add("data", "The data URL is not valid base64")
+			return nil, ve
+		}
+		data = decoded
+	}
+
+	if int64(len(data)) > w.MaxPasteSize() {
+		ve.Add("data", "The image is larger than the limit of pasted images")
+		return nil, ve
+	}
+	ext, ok := pastedImageTypes[http.DetectContentType(data)]
+	if !ok {
+		ve.Add("data", "Only PNG, JPEG, GIF and WebP images can be pasted")
+		return nil, ve
+	}
+	name = strings.TrimSpace(strings.TrimSuffix(name, path.Ext(name)))
+	if name == "" {
+		name = "screenshot-" + time.Now().UTC().Format("20060102-150405")
+	}
+
+	url, err := w.UploadAsset(pageID, bytes.NewReader(data), name+ext)
+	if err != nil {
+		return nil, err
+	}
+	return &PastedImage{File: url, Markdown: "![" + escapeAltText(name) + "](" + url + ")"}, nil
+}
+
+// MaxPasteSize returns the size limit of pasted images in bytes, the upload limit up to 50 MiB
+func (w *Wiki) MaxPasteSize() int64 {
+	return min(w.MaxUploadSize(), maxPastedImageSize)
+}
+
+// escapeAltText escapes the characters which would end the alt text of a Markdown image
+func escapeAltText(text string) string {
+	return strings.NewReplacer(`\`, `\\`, "[", `\[`, "]", `\]`, "\n", " ").Replace(text)
+}
@@ -0,0 +1,131 @@
+package wiki
+
+import (
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/Gomez12/wiki/internal/core/tree"
+)
+
+// historicTree is the page tree reconstructed from the history as of a point in time
+type historicTree struct {
+	root *tree.PageNode
+	// content holds the snapshot content of every node with a page of its own
+	content map[*tree.PageNode]string
+}
+
+// TreeAsOf reconstructs the page tree as it was at the given time from the page history.
+// Nodes of pages which still exist carry their current id, also if they were moved since;
+// pages which no longer exist have an empty id and can be read with FindByPathAsOf.
+// Titles are taken from the first heading of the snapshots and siblings are sorted by slug,
+// because the history records the files only.
+func (w *Wiki) TreeAsOf(asOf time.Time) (*tree.PageNode, error) {
+	historic, err := w.historicTree(asOf)
+	if err != nil {
+		return nil, err
+	}
+	return historic.root, nil
+}
+
+// PageAsOf returns the page with the id as it was at the given time,
+// tree.ErrPageNotFound if it didn't exist then
+func (w *Wiki) PageAsOf(id string, asOf time.Time) (*tree.Page, error) {
+	historic, err := w.historicTree(asOf)
+	if err != nil {
+		return nil, err
+	}
+	node := findNodeByID(historic.root.Children, id)
+	if id == "" || node == nil {
+		return nil, tree.ErrPageNotFound
+	}
+	return &tree.Page{PageNode: node, Content: historic.content[node]}, nil
+}
+
+// FindByPathAsOf returns the page with the route as it was at the given time,
+// tree.ErrPageNotFound if it didn't exist then
+func (w *Wiki) FindByPathAsOf(route string, asOf time.Time) (*tree.Page, error) {
+	historic, err := w.historicTree(asOf)
+	if err != nil {
+		return nil, err
+	}
+	node := historic.root
+	for _, slug := range strings.Split(strings.Trim(route, "/"), "/") {
+		node = childBySlug(node, slug)
+		if node == nil {
+			return nil, tree.ErrPageNotFound
+		}
+	}
+	return &tree.Page{PageNode: node, Content: historic.content[node]}, nil
+}
+
+func (w *Wiki) historicTree(asOf time.Time) (*historicTree, error) {
+	snapshots, err := w.searchIndex.SnapshotsAsOf(asOf)
+	if err != nil {
+		return nil, err
+	}
+
+	historic := &historicTree{
+		root:    &tree.PageNode{ID: "root", Slug: "root", Title: "root", Children: []*tree.PageNode{}},
+		content: map[*tree.PageNode]string{},
+	}
+	for _, snap := range snapshots {
+		if snap.Route == "" {
+			continue
+		}
+		// folders without a page of their own are added with their slug as title
+		node := historic.root
+		for _, slug := range strings.Split(snap.Route, "/") {
+			child := childBySlug(node, slug)
+			if child == nil {
+				child = &tree.PageNode{Title: slug, Slug: slug, Parent: node, Children: []*tree.PageNode{}}
+				node.Children = append(node.Children, child)
+			}
+			node = child
+		}
+		node.Title = snap.Title
+		historic.content[node] = snap.Content
+	}
+
+	w.assignCurrentIDs(historic.root)
+	return historic, nil
+}
+
+// assignCurrentIDs sorts the children of the historic nodes by slug and sets the id
+// of the page which has the route today, or was moved away from it
+func (w *Wiki) assignCurrentIDs(node *tree.PageNode) {
+	sort.Slice(node.Children, func(i, j int) bool {
+		return node.Children[i].Slug < node.Children[j].Slug
+	})
+	for i, child := range node.Children {
+		child.Position = i
+		route := strings.TrimPrefix(child.CalculatePath(), "/")
+		if page, err := w.FindByPath(route); err == nil {
+			child.ID = page.ID
+		} else if redirect, err := w.ResolveRedirect(route); err == nil {
+			child.ID = redirect.PageID
+		}
+		w.assignCurrentIDs(child)
+	}
+}
+
+func childBySlug(node *tree.PageNode, slug string) *tree.PageNode {
+	for _, child := range node.Children {
+		if child.Slug == slug {
+			return child
+		}
+	}
+	return nil
+}
+
+func findNodeByID(nodes []*tree.PageNode, id string) *tree.PageNode {
+	for _, node := range nodes {
+		if node.ID == id {
+			return node
+		}
+		if found := findNodeByID(node.Children, id); found != nil {
+			return found
+		}
+	}
+	return nil
+}
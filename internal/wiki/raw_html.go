@@ -0,0 +1,53 @@
+package wiki
+
+import (
+	"slices"
+	"strings"
+
+	"github.com/Gomez12/wiki/internal/core/auth"
+	"github.com/Gomez12/wiki/internal/core/shared/errors"
+	"github.com/Gomez12/wiki/internal/core/staticsite"
+)
+
+// CheckRawHTML makes sure the user may save the content while raw HTML is trusted: users
+// without a trusted role may not add raw HTML which the sanitizer would change, e.g. scripts.
+// Such HTML already in the page, added by a trusted user, may stay.
+func (w *Wiki) CheckRawHTML(user *auth.User, pageID, content string) error {
+	restricted, err := w.rawHTMLRestricted(user)
+	if err != nil || !restricted {
+		return err
+	}
+
+	page, err := w.tree.GetPage(pageID)
+	if err != nil {
+		return err
+	}
+	return checkAddedRawHTML(page.Content, content)
+}
+
+// rawHTMLRestricted reports whether the user may only add raw HTML which the sanitizer keeps
+func (w *Wiki) rawHTMLRestricted(user *auth.User) (bool, error) {
+	s, err := w.GetSettings()
+	if err != nil {
+		return false, err
+	}
+	return s.RawHTML == staticsite.RawHTMLTrusted && !slices.Contains(s.RawHTMLTrustedRoles, user.Role), nil
+}
+
+// checkAddedRawHTML returns a validation error if the content has raw HTML the sanitizer would
+// change which the existing content doesn't have
+func checkAddedRawHTML(existing, content string) error {
+	existingTags := staticsite.DisallowedHTML(existing)
+	added := []string{}
+	for _, tag := range staticsite.DisallowedHTML(content) {
+		if !slices.Contains(existingTags, tag) {
+			added = append(added, tag)
+		}
+	}
+	if len(added) > 0 {
+		ve := errors.NewValidationErrors()
+		ve.Add("content", "Your role may not add this raw HTML: "+strings.Join(added, " "))
+		return ve
+	}
+	return nil
+}
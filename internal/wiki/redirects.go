@@ -0,0 +1,103 @@
+package wiki
+
+import (
+	"errors"
+	"strings"
+	"time"
+
+	"github.com/Gomez12/wiki/internal/core/redirects"
+	"github.com/Gomez12/wiki/internal/core/tree"
+)
+
+// Redirect points the old route of a moved or renamed page to its current route
+type Redirect struct {
+	From      string    `json:"from"`
+	To        string    `json:"to"`
+	PageID    string    `json:"pageId"`
+	CreatedAt time.Time `json:"createdAt"`
+}
+
+// addRedirects redirects the old routes of a moved page and its descendants
+func (w *Wiki) addRedirects(id, oldRoute, newRoute string) {
+	node, err := w.tree.FindPageByID(w.tree.GetTree().Children, id)
+	if err != nil {
+		return
+	}
+
+	var add func(node *tree.PageNode)
+	add = func(node *tree.PageNode) {
+		route := strings.TrimPrefix(node.CalculatePath(), "/")
+		from := oldRoute + strings.TrimPrefix(route, newRoute)
+		if err := w.redirects.Add(from, node.ID); err != nil {
+			wikiLog.Warn("could not add redirect", "from", from, "pageId", node.ID, "error", err)
+		}
+		// a redirect of the new route would point back to the page itself
+		if err := w.redirects.Delete(route); err != nil && !errors.Is(err, redirects.ErrRedirectNotFound) {
+			wikiLog.Warn("could not remove redirect", "from", route, "error", err)
+		}
+		for _, child := range node.Children {
+			add(child)
+		}
+	}
+	add(node)
+}
+
+// deleteRedirectsTo removes the redirects to a deleted page and its descendants
+func (w *Wiki) deleteRedirectsTo(node *tree.PageNode) {
+	if err := w.redirects.DeleteForPage(node.ID); err != nil {
+		wikiLog.Warn("could not remove redirects", "pageId", node.ID, "error", err)
+	}
+	for _, child := range node.Children {
+		w.deleteRedirectsTo(child)
+	}
+}
+
+// ResolveRedirect returns the redirect of a route. Routes of existing pages and redirects
+// to deleted pages return redirects.ErrRedirectNotFound.
+func (w *Wiki) ResolveRedirect(route string) (*Redirect, error) {
+	route = strings.Trim(route, "/")
+	if route == "" {
+		return nil, redirects.ErrRedirectNotFound
+	}
+	if _, err := w.FindByPath(route); err == nil {
+		return nil, redirects.ErrRedirectNotFound
+	}
+	redirect, err := w.redirects.Get(route)
+	if err != nil {
+		return nil, err
+	}
+	resolved, ok := w.resolve(redirect)
+	if !ok {
+		return nil, redirects.ErrRedirectNotFound
+	}
+	return resolved, nil
+}
+
+// ListRedirects returns all redirects to existing pages with their current route
+func (w *Wiki) ListRedirects() ([]*Redirect, error) {
+	stored, err := w.redirects.List()
+	if err != nil {
+		return nil, err
+	}
+	list := []*Redirect{}
+	for _, redirect := range stored {
+		if resolved, ok := w.resolve(redirect); ok {
+			list = append(list, resolved)
+		}
+	}
+	return list, nil
+}
+
+// DeleteRedirect removes the redirect of a route
+func (w *Wiki) DeleteRedirect(from string) error {
+	return w.redirects.Delete(strings.Trim(from, "/"))
+}
+
+// resolve looks up the current route of the redirected page
+func (w *Wiki) resolve(redirect *redirects.Redirect) (*Redirect, bool) {
+	to, ok := w.routeOf(redirect.PageID)
+	if !ok || to == redirect.From {
+		return nil, false
+	}
+	return &Redirect{From: redirect.From, To: to, PageID: redirect.PageID, CreatedAt: redirect.CreatedAt}, true
+}
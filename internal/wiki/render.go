@@ -0,0 +1,46 @@
+package wiki
+
+import (
+	"context"
+
+	"github.com/Gomez12/wiki/internal/core/highlight"
+	"github.com/Gomez12/wiki/internal/core/settings"
+	"github.com/Gomez12/wiki/internal/core/staticsite"
+)
+
+// RenderedPage is a page rendered to HTML on the server, e.g. for clients without JavaScript
+type RenderedPage struct {
+	PageID string `json:"pageId"`
+	HTML   string `json:"html"`
+	// Style is the stylesheet of the highlighted code blocks
+	Style string `json:"style"`
+}
+
+// RenderPage renders the Markdown of a page to sanitized HTML, with math and code highlighting
+// as configured in the settings. It stops before rendering when ctx has ended while the
+// includes and macros were expanded.
+func (w *Wiki) RenderPage(ctx context.Context, pageID string) (*RenderedPage, error) {
+	page, err := w.tree.GetPage(pageID)
+	if err != nil {
+		return nil, err
+	}
+	s, err := w.GetSettings()
+	if err != nil {
+		return nil, err
+	}
+	opts := renderOptions(s)
+	content := w.expandedContent(page)
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	return &RenderedPage{
+		PageID: page.ID,
+		HTML:   staticsite.PrefixLinks(staticsite.RenderHTML(content, opts), w.basePath),
+		Style:  highlight.CSS(opts.CodeTheme),
+	}, nil
+}
+
+// renderOptions returns the render options configured in the settings
+func renderOptions(s settings.Settings) staticsite.RenderOptions {
+	return staticsite.RenderOptions{Math: s.Math, CodeTheme: s.CodeTheme, RawHTML: s.RawHTML}
+}
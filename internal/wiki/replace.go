@@ -0,0 +1,161 @@
+package wiki
+
+import (
+	"context"
+	"path"
+	"regexp"
+	"strings"
+
+	"github.com/Gomez12/wiki/internal/core/shared/errors"
+	"github.com/Gomez12/wiki/internal/core/tree"
+)
+
+// maxReplacePreviewLines limits the changed lines listed per page
+const maxReplacePreviewLines = 20
+
+// ReplaceOptions describe a find and replace across pages
+type ReplaceOptions struct {
+	// Find is a literal text, or a regular expression if Regex is set
+	Find    string `json:"find"`
+	Replace string `json:"replace"`
+	// Regex enables regular expressions; Replace may then refer to groups, e.g. $1
+	Regex      bool `json:"regex"`
+	IgnoreCase bool `json:"ignoreCase"`
+	// PageID limits the replacement to the page and its descendants, all pages if empty
+	PageID string `json:"pageId"`
+	// DryRun only previews the changes
+	DryRun bool `json:"dryRun"`
+}
+
+// ReplacedLine is a changed line of a page
+type ReplacedLine struct {
+	Line   int    `json:"line"`
+	Before string `json:"before"`
+	After  string `json:"after"`
+}
+
+// ReplacedPage is a page with matches
+type ReplacedPage struct {
+	PageID  string `json:"pageId"`
+	Title   string `json:"title"`
+	Path    string `json:"path"`
+	Matches int    `json:"matches"`
+	// Lines lists the first changed lines as preview
+	Lines []ReplacedLine `json:"lines"`
+}
+
+// ReplaceResult lists the pages which were changed, or would be changed in a dry run
+type ReplaceResult struct {
+	DryRun  bool           `json:"dryRun"`
+	Matches int            `json:"matches"`
+	Pages   []ReplacedPage `json:"pages"`
+}
+
+// ReplaceInPages finds and replaces text in a subtree. Changed pages are written like an edit
+// and recorded in the page history, so every replacement can be undone.
+func (w *Wiki) ReplaceInPages(opts ReplaceOptions) (*ReplaceResult, error) {
+	ve := errors.NewValidationErrors()
+	if opts.Find == "" {
+		ve.Add("find", "Find must not be empty")
+	}
+	pattern := opts.Find
+	if !opts.Regex {
+		pattern = regexp.QuoteMeta(pattern)
+	}
+	if opts.IgnoreCase {
+		pattern = "(?i)" + pattern
+	}
+	re, err := regexp.Compile(pattern)
+	if err != nil {
+		ve.Add("find", "Invalid regular expression: "+err.Error())
+	}
+	if ve.HasErrors() {
+		return nil, ve
+	}
+	replace := func(s string) string {
+		if opts.Regex {
+			return re.ReplaceAllString(s, opts.Replace)
+		}
+		return re.ReplaceAllLiteralString(s, opts.Replace)
+	}
+
+	nodes := w.tree.GetTree().Children
+	if opts.PageID != "" {
+		node, err := w.tree.FindPageByID(nodes, opts.PageID)
+		if err != nil {
+			return nil, err
+		}
+		nodes = []*tree.PageNode{node}
+	}
+
+	dataDir := path.Join(w.storageDir, "root")
+	result := &ReplaceResult{DryRun: opts.DryRun, Pages: []ReplacedPage{}}
+	var walk func(nodes []*tree.PageNode) error
+	walk = func(nodes []*tree.PageNode) error {
+		for _, node := range nodes {
+			page, err := w.tree.GetPage(node.ID)
+			if err != nil {
+				return err
+			}
+			matches := len(re.FindAllStringIndex(page.Content, -1))
+			if matches > 0 {
+				content := replace(page.Content)
+				if content != page.Content {
+					result.Matches += matches
+					result.Pages = append(result.Pages, ReplacedPage{
+						PageID:  page.ID,
+						Title:   page.Title,
+						Path:    strings.TrimPrefix(page.CalculatePath(), "/"),
+						Matches: matches,
+						Lines:   changedLines(page.Content, replace),
+					})
+					if !opts.DryRun {
+						if err := w.writeReplacement(dataDir, page, content); err != nil {
+							return err
+						}
+					}
+				}
+			}
+			if err := walk(node.Children); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+	if err := walk(nodes); err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+// writeReplacement updates the page and records the content before and after in the history
+func (w *Wiki) writeReplacement(dataDir string, page *tree.Page, content string) error {
+	relPath := pageFilePath(dataDir, page)
+	if relPath != "" {
+		if err := w.searchIndex.CaptureFileChanges(context.Background(), dataDir, []string{relPath}); err != nil {
+			return err
+		}
+	}
+	if _, err := w.UpdatePage(page.ID, page.Title, page.Slug, content); err != nil {
+		return err
+	}
+	if relPath != "" {
+		return w.searchIndex.CaptureFileChanges(context.Background(), dataDir, []string{relPath})
+	}
+	return nil
+}
+
+// changedLines applies the replacement line by line for the preview.
+// Matches spanning several lines are not listed.
+func changedLines(content string, replace func(string) string) []ReplacedLine {
+	lines := []ReplacedLine{}
+	for i, line := range strings.Split(content, "\n") {
+		if after := replace(line); after != line {
+			lines = append(lines, ReplacedLine{Line: i + 1, Before: line, After: after})
+			if len(lines) == maxReplacePreviewLines {
+				break
+			}
+		}
+	}
+	return lines
+}
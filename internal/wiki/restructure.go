@@ -0,0 +1,203 @@
+package wiki
+
+import (
+	"context"
+	"fmt"
+	"path"
+	"sort"
+	"strings"
+
+	"github.com/Gomez12/wiki/internal/core/shared/errors"
+	"github.com/Gomez12/wiki/internal/core/tree"
+)
+
+// Restructure is the desired structure of a subtree, e.g. after drag and drop in the tree
+type Restructure struct {
+	// RootID is the page whose subtree is restructured, the whole tree if empty or "root"
+	RootID string `json:"rootId"`
+	// Children maps the root and every page which has children to its ordered child ids.
+	// Every page of the subtree must be listed exactly once.
+	Children map[string][]string `json:"children"`
+}
+
+// RestructureResult lists what RestructureTree changed
+type RestructureResult struct {
+	// Moved are the ids of the pages which got another parent
+	Moved []string `json:"moved"`
+	// Sorted counts the pages whose children were sorted
+	Sorted int `json:"sorted"`
+}
+
+// RestructureTree moves and sorts the pages of a subtree into the desired structure. Either
+// all moves and sorts are applied or, if one fails, the applied ones are undone. The links
+// and redirects are updated for the moved pages and the changed files are recorded in the
+// page history at once at the end.
+func (w *Wiki) RestructureTree(req Restructure) (*RestructureResult, error) {
+	root := w.tree.GetTree()
+	if root == nil {
+		return nil, tree.ErrTreeNotLoaded
+	}
+	rootKey := "root"
+	if req.RootID != "" && req.RootID != rootKey {
+		node, err := w.tree.FindPageByID(root.Children, req.RootID)
+		if err != nil {
+			return nil, err
+		}
+		root, rootKey = node, node.ID
+	}
+
+	nodes := map[string]*tree.PageNode{}
+	oldParents := map[string]string{}
+	oldOrders := map[string][]string{}
+	var collect func(parentKey string, parent *tree.PageNode)
+	collect = func(parentKey string, parent *tree.PageNode) {
+		for _, child := range parent.Children {
+			nodes[child.ID] = child
+			oldParents[child.ID] = parentKey
+			oldOrders[parentKey] = append(oldOrders[parentKey], child.ID)
+			collect(child.ID, child)
+		}
+	}
+	collect(rootKey, root)
+
+	newParents, err := validateRestructure(req.Children, rootKey, nodes)
+	if err != nil {
+		return nil, err
+	}
+
+	oldRoutes := map[string]string{}
+	var pending []string
+	for id := range nodes {
+		oldRoutes[id], _ = w.routeOf(id)
+		if newParents[id] != oldParents[id] {
+			pending = append(pending, id)
+		}
+	}
+	sort.Strings(pending)
+
+	// A move can depend on another one, e.g. when a slug is only free after a sibling moved
+	// away, so the pending moves are retried until none of them succeeds anymore
+	var applied []string
+	for len(pending) > 0 {
+		var failed []string
+		var lastErr error
+		for _, id := range pending {
+			if err := w.tree.MovePage(id, newParents[id]); err != nil {
+				failed = append(failed, id)
+				lastErr = err
+				continue
+			}
+			applied = append(applied, id)
+		}
+		if len(failed) == len(pending) {
+			w.undoRestructure(applied, oldParents, oldOrders)
+			return nil, lastErr
+		}
+		pending = failed
+	}
+
+	result := &RestructureResult{Moved: applied}
+	if result.Moved == nil {
+		result.Moved = []string{}
+	}
+	keys := make([]string, 0, len(req.Children))
+	for key := range req.Children {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	for _, key := range keys {
+		if len(req.Children[key]) == 0 {
+			continue
+		}
+		if err := w.tree.SortPages(key, req.Children[key]); err != nil {
+			w.undoRestructure(applied, oldParents, oldOrders)
+			return nil, err
+		}
+		result.Sorted++
+	}
+
+	// the deepest pages first, so the links to them are updated before those to their old parents
+	moved := append([]string{}, applied...)
+	sort.SliceStable(moved, func(i, j int) bool {
+		return strings.Count(oldRoutes[moved[i]], "/") > strings.Count(oldRoutes[moved[j]], "/")
+	})
+	for _, id := range moved {
+		w.pageMoved(id, oldRoutes[id])
+	}
+
+	// the pages are moved already, so the history is recorded even if the client is gone
+	if err := w.searchIndex.CaptureFileHistory(context.Background(), path.Join(w.storageDir, "root")); err != nil {
+		wikiLog.Error("could not record the restructured pages in the history", "error", err)
+	}
+	return result, nil
+}
+
+// validateRestructure checks that the desired children list every page of the subtree exactly
+// once and form a tree below the root, and returns the new parent of every page
+func validateRestructure(children map[string][]string, rootKey string, nodes map[string]*tree.PageNode) (map[string]string, error) {
+	ve := errors.NewValidationErrors()
+	newParents := map[string]string{}
+	for parentKey, ids := range children {
+		if parentKey != rootKey && nodes[parentKey] == nil {
+			ve.Add("children", fmt.Sprintf("Page %s is not part of the subtree", parentKey))
+			continue
+		}
+		slugs := map[string]bool{}
+		for _, id := range ids {
+			node := nodes[id]
+			if node == nil {
+				ve.Add("children", fmt.Sprintf("Page %s is not part of the subtree", id))
+				continue
+			}
+			if _, exists := newParents[id]; exists {
+				ve.Add("children", fmt.Sprintf("Page %s is listed more than once", id))
+				continue
+			}
+			if slugs[node.Slug] {
+				ve.Add("children", fmt.Sprintf("Slug %s is used twice below %s", node.Slug, parentKey))
+			}
+			slugs[node.Slug] = true
+			newParents[id] = parentKey
+		}
+	}
+	if ve.HasErrors() {
+		return nil, ve
+	}
+	if len(newParents) != len(nodes) {
+		ve.Add("children", fmt.Sprintf("%d pages of the subtree are missing", len(nodes)-len(newParents)))
+		return nil, ve
+	}
+
+	// every page has one parent, so pages which can't be reached from the root form a cycle
+	reached := 0
+	queue := []string{rootKey}
+	for len(queue) > 0 {
+		key := queue[0]
+		queue = queue[1:]
+		reached += len(children[key])
+		queue = append(queue, children[key]...)
+		if reached > len(nodes) {
+			break
+		}
+	}
+	if reached != len(nodes) {
+		ve.Add("children", "The pages must form a tree below the root")
+		return nil, ve
+	}
+	return newParents, nil
+}
+
+// undoRestructure moves the applied pages back in reverse order and restores the order
+// of their old parents. Failures are logged, there is nothing left to fall back to.
+func (w *Wiki) undoRestructure(applied []string, oldParents map[string]string, oldOrders map[string][]string) {
+	for i := len(applied) - 1; i >= 0; i-- {
+		if err := w.tree.MovePage(applied[i], oldParents[applied[i]]); err != nil {
+			wikiLog.Error("could not undo the move of a page", "pageId", applied[i], "error", err)
+		}
+	}
+	for parentKey, ids := range oldOrders {
+		if err := w.tree.SortPages(parentKey, ids); err != nil {
+			wikiLog.Error("could not restore the order of the pages", "pageId", parentKey, "error", err)
+		}
+	}
+}
@@ -0,0 +1,264 @@
+package wiki
+
+import (
+	"context"
+	stderrors "errors"
+	"fmt"
+	"net/http"
+	"slices"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/Gomez12/wiki/internal/core/frontmatter"
+	"github.com/Gomez12/wiki/internal/core/notify"
+	"github.com/Gomez12/wiki/internal/core/tree"
+)
+
+// ownerField is the frontmatter field with the usernames or email addresses of the users
+// responsible for a page, a single value or a list
+const ownerField = "owner"
+
+// ReviewDueEvent is the webhook event sent when a page is due for review
+const ReviewDueEvent = "page.review-due"
+
+// webhookClient delivers webhooks, the timeout keeps a slow receiver from blocking reminders
+var webhookClient = &http.Client{Timeout: 10 * time.Second}
+
+// ReviewPage is a page whose review-by date has been reached
+type ReviewPage struct {
+	PageID   string `json:"pageId"`
+	Title    string `json:"title"`
+	Path     string `json:"path"`
+	ReviewBy string `json:"reviewBy"`
+	// DaysOverdue is 0 on the review date
+	DaysOverdue int      `json:"daysOverdue"`
+	Owners      []string `json:"owners"`
+	// NotifiedAt is when the owners were reminded of the review date, nil if not yet
+	NotifiedAt *time.Time `json:"notifiedAt"`
+}
+
+// ReviewReminderResult is the outcome of sending the review reminders
+type ReviewReminderResult struct {
+	// Notified counts the pages whose owners were reminded on any channel
+	Notified int `json:"notified"`
+	// Failed lists the pages whose reminders could not be delivered, they are retried next time
+	Failed []string `json:"failed"`
+}
+
+// DueReviews returns the pages whose review-by date is today or has passed, the longest
+// overdue first
+func (w *Wiki) DueReviews() ([]ReviewPage, error) {
+	now := time.Now().UTC()
+	today, _ := time.Parse(time.DateOnly, now.Format(time.DateOnly))
+	due := []ReviewPage{}
+
+	var walk func(nodes []*tree.PageNode) error
+	walk = func(nodes []*tree.PageNode) error {
+		for _, node := range nodes {
+			page, err := w.tree.GetPage(node.ID)
+			if err != nil {
+				return err
+			}
+			if fields, _, err := frontmatter.Parse(page.Content); err == nil {
+				if reviewBy, ok := reviewDate(fields[reviewByField]); ok && reviewBy <= today.Format(time.DateOnly) {
+					date, _ := time.Parse(time.DateOnly, reviewBy)
+					notifiedAt, err := w.reviews.NotifiedAt(page.ID, reviewBy)
+					if err != nil {
+						return err
+					}
+					due = append(due, ReviewPage{
+						PageID:      page.ID,
+						Title:       page.Title,
+						Path:        strings.TrimPrefix(page.CalculatePath(), "/"),
+						ReviewBy:    reviewBy,
+						DaysOverdue: int(today.Sub(date).Hours() / 24),
+						Owners:      owners(fields),
+						NotifiedAt:  notifiedAt,
+					})
+				}
+			}
+			if err := walk(node.Children); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+	if err := walk(w.tree.GetTree().Children); err != nil {
+		return nil, err
+	}
+
+	sort.SliceStable(due, func(i, j int) bool {
+		if due[i].DaysOverdue == due[j].DaysOverdue {
+			return due[i].Path < due[j].Path
+		}
+		return due[i].DaysOverdue > due[j].DaysOverdue
+	})
+	return due, nil
+}
+
+// Channels of the review reminders, every channel delivers a review date once
+const (
+	reviewChannelWebhook = "webhook"
+	reviewChannelEmail   = "email"
+)
+
+// SendReviewReminders notifies the owners of the due pages by email and the webhook of the
+// settings. Every review date of a page is delivered once per channel, a failed channel is
+// retried next time without repeating the others. A page counts as reminded once any channel
+// delivered; pages without any configured channel are not marked, so they are notified once a
+// channel is set up.
+func (w *Wiki) SendReviewReminders(ctx context.Context) (*ReviewReminderResult, error) {
+	due, err := w.DueReviews()
+	if err != nil {
+		return nil, err
+	}
+	s, err := w.settings.Get()
+	if err != nil {
+		return nil, err
+	}
+	hook := notify.Webhook{URL: s.Webhook.URL, Secret: s.Webhook.Secret, Events: s.Webhook.Events}
+
+	result := &ReviewReminderResult{Failed: []string{}}
+	for _, page := range due {
+		sent, err := w.reviews.Delivered(page.PageID, page.ReviewBy)
+		if err != nil {
+			return nil, err
+		}
+		delivered, err := w.remind(ctx, hook, page, sent)
+		if err != nil {
+			wikiLog.Warn("could not send review reminder", "pageId", page.PageID, "error", err)
+			result.Failed = append(result.Failed, page.Path)
+		}
+		if delivered == 0 {
+			continue
+		}
+		if err := w.reviews.MarkNotified(page.PageID, page.ReviewBy); err != nil {
+			return nil, err
+		}
+		result.Notified++
+	}
+	return result, nil
+}
+
+// remind delivers the reminder of a page on the channels which apply and haven't delivered it
+// yet (sent), and records every delivery. It returns the number of channels which delivered.
+func (w *Wiki) remind(ctx context.Context, hook notify.Webhook, page ReviewPage, sent map[string]bool) (int, error) {
+	delivered := 0
+	var errs []error
+	deliver := func(channel string, send func() error) error {
+		if err := send(); err != nil {
+			errs = append(errs, fmt.Errorf("%s: %w", channel, err))
+			return nil
+		}
+		delivered++
+		return w.reviews.MarkDelivered(page.PageID, page.ReviewBy, channel)
+	}
+
+	if hook.Subscribed(ReviewDueEvent) && !sent[reviewChannelWebhook] {
+		err := deliver(reviewChannelWebhook, func() error {
+			payload := map[string]any{"event": ReviewDueEvent, "page": page}
+			return hook.Send(ctx, webhookClient, ReviewDueEvent, payload)
+		})
+		if err != nil {
+			return delivered, err
+		}
+	}
+
+	if recipients := w.ownerEmails(page.Owners); w.mailer.Enabled() && len(recipients) > 0 && !sent[reviewChannelEmail] {
+		err := deliver(reviewChannelEmail, func() error {
+			subject := "Review due: " + page.Title
+			body := fmt.Sprintf("The page %q (/%s) was due for review on %s.\n\nPlease check that it is still up to date and move its review-by date.\n",
+				page.Title, page.Path, page.ReviewBy)
+			return w.mailer.Send(recipients, subject, body)
+		})
+		if err != nil {
+			return delivered, err
+		}
+	}
+
+	return delivered, stderrors.Join(errs...)
+}
+
+// ownerEmails resolves the owners of a page, usernames or email addresses, to the email
+// addresses of their accounts. Other addresses are left out, so editing a page can't send
+// mail to anyone.
+func (w *Wiki) ownerEmails(names []string) []string {
+	emails := []string{}
+	var users map[string]string
+	for _, name := range names {
+		if users == nil {
+			users = map[string]string{}
+			list, err := w.user.GetUsers()
+			if err != nil {
+				wikiLog.Warn("could not list users", "error", err)
+			}
+			for _, user := range list {
+				if user.Email == "" {
+					continue
+				}
+				users[strings.ToLower(user.Username)] = user.Email
+				users[strings.ToLower(user.Email)] = user.Email
+			}
+		}
+		if email := users[strings.ToLower(name)]; email != "" {
+			if !slices.Contains(emails, email) {
+				emails = append(emails, email)
+			}
+		} else {
+			wikiLog.Warn("review owner is no user with an email address", "owner", name)
+		}
+	}
+	return emails
+}
+
+// owners returns the owners of the frontmatter, a single value or a list
+func owners(fields map[string]any) []string {
+	names := []string{}
+	add := func(value any) {
+		if name := strings.TrimSpace(fmt.Sprint(value)); name != "" {
+			names = append(names, name)
+		}
+	}
+	switch value := fields[ownerField].(type) {
+	case nil, map[string]any:
+	case []any:
+		for _, item := range value {
+			add(item)
+		}
+	default:
+		add(value)
+	}
+	return names
+}
+
+// runReviewReminders sends the review reminders at startup and then periodically until
+// stop is closed
+func (w *Wiki) runReviewReminders(stop <-chan struct{}) {
+	ticker := time.NewTicker(w.reviewInterval)
+	defer ticker.Stop()
+
+	for {
+		ctx, cancel := context.WithCancel(context.Background())
+		go func() {
+			select {
+			case <-stop:
+				cancel()
+			case <-ctx.Done():
+			}
+		}()
+		result, err := w.SendReviewReminders(ctx)
+		cancel()
+		if err != nil {
+			wikiLog.Error("review reminders failed", "error", err)
+		} else if result.Notified > 0 || len(result.Failed) > 0 {
+			wikiLog.Info("review reminders sent", "notified", result.Notified, "failed", len(result.Failed))
+		}
+
+		select {
+		case <-ticker.C:
+		case <-stop:
+			return
+		}
+	}
+}
@@ -0,0 +1,239 @@
+package wiki
+
+import (
+	"context"
+	stderrors "errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/Gomez12/wiki/internal/core/auth"
+	"github.com/Gomez12/wiki/internal/core/notify"
+	"github.com/Gomez12/wiki/internal/core/savedsearch"
+	"github.com/Gomez12/wiki/internal/core/shared/errors"
+	"github.com/Gomez12/wiki/internal/search"
+)
+
+// SearchMatchEvent is the webhook event sent when pages start matching a subscribed search
+const SearchMatchEvent = "search.match"
+
+// maxAlertMatches limits the matches of a subscribed search compared per check, the best
+// ranked first
+const maxAlertMatches = 200
+
+// SearchAlertResult is the outcome of checking the subscribed searches
+type SearchAlertResult struct {
+	// Checked counts the subscribed searches which were run
+	Checked int `json:"checked"`
+	// Alerts counts the pages which started matching
+	Alerts int `json:"alerts"`
+	// Failed lists the IDs of the searches which could not be run or whose notification
+	// could not be delivered
+	Failed []string `json:"failed"`
+}
+
+// GetSavedSearches returns the saved searches of a user by name
+func (w *Wiki) GetSavedSearches(userID string) ([]*savedsearch.SavedSearch, error) {
+	return w.savedSearches.List(userID)
+}
+
+// CreateSavedSearch saves a search query for a user. Subscribed searches raise alerts for
+// the pages which start matching after it was saved.
+func (w *Wiki) CreateSavedSearch(userID, name, query string, subscribed bool) (*savedsearch.SavedSearch, error) {
+	name, query = strings.TrimSpace(name), strings.TrimSpace(query)
+	if err := w.validateSavedSearch(name, query); err != nil {
+		return nil, err
+	}
+	return w.savedSearches.Create(userID, name, query, subscribed)
+}
+
+// UpdateSavedSearch changes a saved search of a user
+func (w *Wiki) UpdateSavedSearch(userID, id, name, query string, subscribed bool) (*savedsearch.SavedSearch, error) {
+	name, query = strings.TrimSpace(name), strings.TrimSpace(query)
+	if err := w.validateSavedSearch(name, query); err != nil {
+		return nil, err
+	}
+	return w.savedSearches.Update(userID, id, name, query, subscribed)
+}
+
+// DeleteSavedSearch removes a saved search of a user with its alerts
+func (w *Wiki) DeleteSavedSearch(userID, id string) error {
+	return w.savedSearches.Delete(userID, id)
+}
+
+// GetSearchAlerts returns the alerts of the subscribed searches of a user, the newest first
+func (w *Wiki) GetSearchAlerts(userID string, unreadOnly bool) ([]*savedsearch.Alert, error) {
+	return w.savedSearches.Alerts(userID, unreadOnly)
+}
+
+// MarkSearchAlertsRead marks all alerts of a user as read
+func (w *Wiki) MarkSearchAlertsRead(userID string) error {
+	return w.savedSearches.MarkAlertsRead(userID)
+}
+
+func (w *Wiki) validateSavedSearch(name, query string) error {
+	ve := errors.NewValidationErrors()
+	if name == "" {
+		ve.Add("name", "Name must not be empty")
+	}
+	if query == "" {
+		ve.Add("query", "Query must not be empty")
+	} else if _, err := w.Search(context.Background(), query, 0, 1); err != nil {
+		ve.Add("query", "Query is not a valid search")
+	}
+	if ve.HasErrors() {
+		return ve
+	}
+	return nil
+}
+
+// CheckSavedSearches runs the subscribed searches and compares their matches with the last
+// check. Pages which didn't match before or whose content changed raise an alert for the
+// owner of the search, who is also notified by email and the webhook of the settings. The
+// first check of a search only records its matches.
+func (w *Wiki) CheckSavedSearches(ctx context.Context) (*SearchAlertResult, error) {
+	subscribed, err := w.savedSearches.Subscribed()
+	if err != nil {
+		return nil, err
+	}
+	s, err := w.settings.Get()
+	if err != nil {
+		return nil, err
+	}
+	hook := notify.Webhook{URL: s.Webhook.URL, Secret: s.Webhook.Secret, Events: s.Webhook.Events}
+
+	result := &SearchAlertResult{Failed: []string{}}
+	for _, saved := range subscribed {
+		if ctx.Err() != nil {
+			return result, ctx.Err()
+		}
+		user, err := w.user.GetUserByID(saved.UserID)
+		if err != nil {
+			// the searches of deleted users are left alone
+			continue
+		}
+		alerts, err := w.checkSavedSearch(ctx, user, saved)
+		if err != nil {
+			wikiLog.Warn("could not check saved search", "searchId", saved.ID, "error", err)
+			result.Failed = append(result.Failed, saved.ID)
+			continue
+		}
+		result.Checked++
+		result.Alerts += len(alerts)
+		if len(alerts) == 0 {
+			continue
+		}
+		if err := w.sendSearchAlerts(ctx, hook, user, saved, alerts); err != nil {
+			wikiLog.Warn("could not send search alert", "searchId", saved.ID, "error", err)
+			result.Failed = append(result.Failed, saved.ID)
+		}
+	}
+	return result, nil
+}
+
+// checkSavedSearch records the current matches of a search and returns its new alerts
+func (w *Wiki) checkSavedSearch(ctx context.Context, user *auth.User, saved *savedsearch.SavedSearch) ([]savedsearch.Alert, error) {
+	res, err := w.SearchForUser(ctx, user, saved.Query, 0, maxAlertMatches)
+	if err != nil {
+		return nil, err
+	}
+	previous, err := w.savedSearches.Matches(saved.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	matches := map[string]string{}
+	alerts := []savedsearch.Alert{}
+	for _, item := range res.Items {
+		page, err := w.tree.GetPage(item.PageID)
+		if err != nil {
+			continue
+		}
+		hash := search.HashString(page.Content)
+		matches[page.ID] = hash
+		if saved.CheckedAt == nil {
+			continue
+		}
+		change := savedsearch.ChangeNew
+		if before, ok := previous[page.ID]; ok {
+			if before == hash {
+				continue
+			}
+			change = savedsearch.ChangeUpdated
+		}
+		alerts = append(alerts, savedsearch.Alert{
+			SearchID:   saved.ID,
+			SearchName: saved.Name,
+			PageID:     page.ID,
+			Title:      page.Title,
+			Path:       strings.TrimPrefix(page.CalculatePath(), "/"),
+			Change:     change,
+		})
+	}
+	if err := w.savedSearches.RecordCheck(saved, matches, alerts); err != nil {
+		return nil, err
+	}
+	return alerts, nil
+}
+
+// sendSearchAlerts notifies the owner of a search by email and the webhook, the alerts are
+// kept for the user either way
+func (w *Wiki) sendSearchAlerts(ctx context.Context, hook notify.Webhook, user *auth.User, saved *savedsearch.SavedSearch, alerts []savedsearch.Alert) error {
+	var errs []error
+
+	if hook.Subscribed(SearchMatchEvent) {
+		payload := map[string]any{
+			"event":  SearchMatchEvent,
+			"user":   user.Username,
+			"search": map[string]string{"id": saved.ID, "name": saved.Name, "query": saved.Query},
+			"pages":  alerts,
+		}
+		if err := hook.Send(ctx, webhookClient, SearchMatchEvent, payload); err != nil {
+			errs = append(errs, fmt.Errorf("webhook: %w", err))
+		}
+	}
+
+	if w.mailer.Enabled() && user.Email != "" {
+		var body strings.Builder
+		fmt.Fprintf(&body, "These pages started matching your saved search %q (%s):\n\n", saved.Name, saved.Query)
+		for _, alert := range alerts {
+			fmt.Fprintf(&body, "- %s (/%s), %s\n", alert.Title, alert.Path, alert.Change)
+		}
+		if err := w.mailer.Send([]string{user.Email}, "Search alert: "+saved.Name, body.String()); err != nil {
+			errs = append(errs, fmt.Errorf("email: %w", err))
+		}
+	}
+
+	return stderrors.Join(errs...)
+}
+
+// runSearchAlerts checks the subscribed searches periodically until stop is closed. The
+// first check waits one interval, so it doesn't run while the index is built.
+func (w *Wiki) runSearchAlerts(stop <-chan struct{}) {
+	ticker := time.NewTicker(w.searchAlertInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+		case <-stop:
+			return
+		}
+
+		ctx, cancel := context.WithCancel(context.Background())
+		go func() {
+			select {
+			case <-stop:
+				cancel()
+			case <-ctx.Done():
+			}
+		}()
+		result, err := w.CheckSavedSearches(ctx)
+		cancel()
+		if err != nil {
+			wikiLog.Error("search alerts failed", "error", err)
+		} else if result.Alerts > 0 || len(result.Failed) > 0 {
+			wikiLog.Info("search alerts checked", "checked", result.Checked, "alerts", result.Alerts, "failed", len(result.Failed))
+		}
+	}
+}
@@ -0,0 +1,272 @@
+package wiki
+
+import (
+	"encoding/json"
+	stderrors "errors"
+	"net/url"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/Gomez12/wiki/internal/core/auth"
+	"github.com/Gomez12/wiki/internal/core/highlight"
+	"github.com/Gomez12/wiki/internal/core/ignore"
+	"github.com/Gomez12/wiki/internal/core/settings"
+	"github.com/Gomez12/wiki/internal/core/shared/errors"
+)
+
+// maxSiteTitleLength is the maximum length of the site title in characters
+const maxSiteTitleLength = 100
+
+// historyRetentionInterval is how often history older than the retention period is pruned
+const historyRetentionInterval = 24 * time.Hour
+
+// GetSettings returns the runtime settings of the wiki
+func (w *Wiki) GetSettings() (settings.Settings, error) {
+	return w.settings.Get()
+}
+
+// ApplySettings updates the settings with the options of patch, a JSON object like the body of
+// PUT /api/admin/settings. Options missing in patch keep their value.
+func (w *Wiki) ApplySettings(patch []byte) (settings.Settings, error) {
+	s, err := w.GetSettings()
+	if err != nil {
+		return s, err
+	}
+	if err := json.Unmarshal(patch, &s); err != nil {
+		ve := errors.NewValidationErrors()
+		ve.Add("settings", "Invalid settings: "+err.Error())
+		return s, ve
+	}
+	return w.UpdateSettings(s)
+}
+
+// ReloadConfig reads the config file again and applies its reloadable options, like the log
+// level and the settings section. Errors in the file are returned as validation error.
+func (w *Wiki) ReloadConfig() (settings.Settings, error) {
+	if w.configReload == nil {
+		return settings.Settings{}, ErrConfigReloadDisabled
+	}
+	if err := w.configReload(); err != nil {
+		var ve *errors.ValidationErrors
+		if !stderrors.As(err, &ve) {
+			ve = errors.NewValidationErrors()
+			ve.Add("config", err.Error())
+		}
+		return settings.Settings{}, ve
+	}
+	return w.GetSettings()
+}
+
+// UpdateSettings validates and stores the runtime settings. They take effect immediately.
+func (w *Wiki) UpdateSettings(s settings.Settings) (settings.Settings, error) {
+	if ve := validateSettings(&s); ve.HasErrors() {
+		return settings.Settings{}, ve
+	}
+
+	previous, err := w.settings.Get()
+	if err != nil {
+		return settings.Settings{}, err
+	}
+	if err := w.settings.Save(s); err != nil {
+		return settings.Settings{}, err
+	}
+	// disabling the tracking also forgets what was tracked so far
+	if previous.TrackRecentPages && !s.TrackRecentPages {
+		if err := w.reading.DeleteAllVisits(); err != nil {
+			wikiLog.Warn("could not remove page visits", "error", err)
+		}
+	}
+	w.ignore.SetPatterns(ignorePatterns(w.obsidian, s.IgnorePatterns))
+	w.applyHistoryRetention()
+	return w.settings.Get()
+}
+
+// validateSettings normalizes the settings, e.g. trims the site title, and checks them
+func validateSettings(s *settings.Settings) *errors.ValidationErrors {
+	s.SiteTitle = strings.TrimSpace(s.SiteTitle)
+	s.Webhook.URL = strings.TrimSpace(s.Webhook.URL)
+	if s.Webhook.Events == nil {
+		s.Webhook.Events = []string{}
+	}
+	patterns := []string{}
+	for _, pattern := range s.IgnorePatterns {
+		if pattern = strings.TrimSpace(pattern); pattern != "" {
+			patterns = append(patterns, pattern)
+		}
+	}
+	s.IgnorePatterns = patterns
+	if s.RawHTMLTrustedRoles == nil {
+		s.RawHTMLTrustedRoles = []string{}
+	}
+
+	ve := errors.NewValidationErrors()
+	if s.SiteTitle == "" {
+		ve.Add("siteTitle", "Site title must not be empty")
+	} else if utf8.RuneCountInString(s.SiteTitle) > maxSiteTitleLength {
+		ve.Add("siteTitle", "Site title must not be longer than 100 characters")
+	}
+	if s.MaxUploadSize <= 0 {
+		ve.Add("maxUploadSize", "Upload limit must be greater than 0")
+	}
+	if s.MaxRequestSize <= 0 {
+		ve.Add("maxRequestSize", "Request size limit must be greater than 0")
+	}
+	if s.StreamThreshold < 0 {
+		ve.Add("streamThreshold", "Stream threshold must not be negative")
+	}
+	if s.ImageMaxDimension < 0 {
+		ve.Add("imageMaxDimension", "Image max dimension must not be negative")
+	}
+	if s.ImageQuality < 1 || s.ImageQuality > 100 {
+		ve.Add("imageQuality", "Image quality must be between 1 and 100")
+	}
+	if s.Lint.MaxLineLength <= 0 {
+		ve.Add("lint.maxLineLength", "Max line length must be greater than 0")
+	}
+	if s.HistoryRetentionDays < 0 {
+		ve.Add("historyRetentionDays", "History retention must not be negative")
+	}
+	if s.Webhook.URL != "" {
+		if u, err := url.Parse(s.Webhook.URL); err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			ve.Add("webhook.url", "Webhook URL must be an http or https URL")
+		}
+	}
+	for _, event := range s.Webhook.Events {
+		if strings.TrimSpace(event) == "" {
+			ve.Add("webhook.events", "Webhook events must not be empty")
+			break
+		}
+	}
+	if !s.Math.Valid() {
+		ve.Add("math", "Math must be one of off, mathml or markup")
+	}
+	if !highlight.ValidTheme(s.CodeTheme) {
+		ve.Add("codeTheme", "Code theme must be off or the name of a Chroma style")
+	}
+	if !s.RawHTML.Valid() {
+		ve.Add("rawHTML", "Raw HTML must be one of strip, sanitize or trusted")
+	}
+	for _, role := range s.RawHTMLTrustedRoles {
+		if !auth.IsValidRole(role) {
+			ve.Add("rawHTMLTrustedRoles", "Unknown role "+role)
+			break
+		}
+	}
+	validateRateLimit(ve, "rateLimit.perIP", s.RateLimit.PerIP)
+	validateRateLimit(ve, "rateLimit.perToken", s.RateLimit.PerToken)
+	if s.RequestTimeout.SearchSeconds < 0 || s.RequestTimeout.RenderSeconds < 0 || s.RequestTimeout.ExportSeconds < 0 {
+		ve.Add("requestTimeout", "Request timeouts must not be negative")
+	}
+	for _, pattern := range s.IgnorePatterns {
+		if err := ignore.ValidatePattern(pattern); err != nil {
+			ve.Add("ignorePatterns", err.Error())
+		}
+	}
+	return ve
+}
+
+func validateRateLimit(ve *errors.ValidationErrors, field string, limit settings.RateLimit) {
+	if limit.RequestsPerMinute < 0 {
+		ve.Add(field, "Requests per minute must not be negative")
+	} else if limit.RequestsPerMinute > 0 && limit.Burst < 1 {
+		ve.Add(field, "Burst must be at least 1")
+	}
+}
+
+// IsPublicAccess reports whether unauthenticated users may read the wiki.
+// The fallback is used as long as the public access mode hasn't been configured.
+func (w *Wiki) IsPublicAccess(fallback bool) bool {
+	s, err := w.settings.Get()
+	if err != nil {
+		wikiLog.Error("could not load settings", "error", err)
+		return fallback
+	}
+	return s.PublicAccessOr(fallback)
+}
+
+// MaxUploadSize returns the upload limit for assets in bytes
+func (w *Wiki) MaxUploadSize() int64 {
+	s, err := w.settings.Get()
+	if err != nil {
+		wikiLog.Error("could not load settings", "error", err)
+		return settings.DefaultMaxUploadSize
+	}
+	return s.MaxUploadSize
+}
+
+// MaxRequestSize returns the size limit in bytes of the body of requests other than uploads
+func (w *Wiki) MaxRequestSize() int64 {
+	s, err := w.settings.Get()
+	if err != nil {
+		wikiLog.Error("could not load settings", "error", err)
+		return settings.DefaultMaxRequestSize
+	}
+	return s.MaxRequestSize
+}
+
+// StreamThreshold returns the size in bytes from which assets are streamed from disk
+func (w *Wiki) StreamThreshold() int64 {
+	s, err := w.settings.Get()
+	if err != nil {
+		wikiLog.Error("could not load settings", "error", err)
+		return settings.DefaultStreamThreshold
+	}
+	return s.StreamThreshold
+}
+
+// RateLimits returns the limits of the requests to expensive endpoints
+func (w *Wiki) RateLimits() settings.RateLimitConfig {
+	s, err := w.settings.Get()
+	if err != nil {
+		wikiLog.Error("could not load settings", "error", err)
+		return settings.Defaults().RateLimit
+	}
+	return s.RateLimit
+}
+
+// RequestTimeouts returns the timeouts of searches, rendering and exports
+func (w *Wiki) RequestTimeouts() settings.RequestTimeoutConfig {
+	s, err := w.settings.Get()
+	if err != nil {
+		wikiLog.Error("could not load settings", "error", err)
+		return settings.Defaults().RequestTimeout
+	}
+	return s.RequestTimeout
+}
+
+// applyHistoryRetention prunes history older than the configured retention period
+func (w *Wiki) applyHistoryRetention() {
+	s, err := w.settings.Get()
+	if err != nil {
+		wikiLog.Error("could not load settings", "error", err)
+		return
+	}
+	if s.HistoryRetentionDays == 0 {
+		return
+	}
+
+	deleted, err := w.searchIndex.PruneHistory(time.Now().AddDate(0, 0, -s.HistoryRetentionDays))
+	if err != nil {
+		wikiLog.Error("could not prune history", "error", err)
+		return
+	}
+	if deleted > 0 {
+		wikiLog.Info("pruned history", "entries", deleted, "retentionDays", s.HistoryRetentionDays)
+	}
+}
+
+// runHistoryRetention applies the history retention periodically until stop is closed
+func (w *Wiki) runHistoryRetention(stop <-chan struct{}) {
+	ticker := time.NewTicker(historyRetentionInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			w.applyHistoryRetention()
+		case <-stop:
+			return
+		}
+	}
+}
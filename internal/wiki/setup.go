@@ -0,0 +1,64 @@
+package wiki
+
+import (
+	"strings"
+
+	"github.com/Gomez12/wiki/internal/core/auth"
+)
+
+// SetupRequest configures a new wiki: the first admin and the site
+type SetupRequest struct {
+	Username string `json:"username" binding:"required"`
+	Email    string `json:"email" binding:"required"`
+	Password string `json:"password" binding:"required"`
+	// SiteTitle keeps the default title if empty
+	SiteTitle string `json:"siteTitle"`
+	// PublicAccess keeps following the flag if nil
+	PublicAccess *bool `json:"publicAccess"`
+}
+
+// SetupRequired reports whether the wiki waits for Setup, which is the case as long as no
+// user exists
+func (w *Wiki) SetupRequired() (bool, error) {
+	hasUsers, err := w.user.HasUsers()
+	return !hasUsers, err
+}
+
+// Setup creates the first admin and stores the site settings, then logs the admin in.
+// It is only allowed while no user exists and fails with ErrSetupCompleted afterwards.
+func (w *Wiki) Setup(req SetupRequest) (*auth.AuthToken, error) {
+	w.setupMu.Lock()
+	defer w.setupMu.Unlock()
+
+	required, err := w.SetupRequired()
+	if err != nil {
+		return nil, err
+	}
+	if !required {
+		return nil, ErrSetupCompleted
+	}
+
+	s, err := w.GetSettings()
+	if err != nil {
+		return nil, err
+	}
+	if title := strings.TrimSpace(req.SiteTitle); title != "" {
+		s.SiteTitle = title
+	}
+	if req.PublicAccess != nil {
+		s.PublicAccess = req.PublicAccess
+	}
+	// checked before the admin is created, so invalid settings don't complete the setup
+	if ve := validateSettings(&s); ve.HasErrors() {
+		return nil, ve
+	}
+
+	if _, err := w.CreateUser(req.Username, req.Email, req.Password, auth.RoleAdmin); err != nil {
+		return nil, err
+	}
+	if _, err := w.UpdateSettings(s); err != nil {
+		return nil, err
+	}
+	wikiLog.Info("setup completed", "admin", req.Username)
+	return w.Login(req.Username, req.Password)
+}
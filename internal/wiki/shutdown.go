@@ -0,0 +1,73 @@
+package wiki
+
+import (
+	"context"
+	"errors"
+)
+
+// startJob runs fn in the background and tracks it for Shutdown.
+// It returns ErrShuttingDown once the shutdown has begun.
+func (w *Wiki) startJob(fn func()) error {
+	w.jobsMu.Lock()
+	defer w.jobsMu.Unlock()
+	if w.closing {
+		return ErrShuttingDown
+	}
+
+	w.jobs.Add(1)
+	go func() {
+		defer w.jobs.Done()
+		fn()
+	}()
+	return nil
+}
+
+// Shutdown stops the wiki in order, so no work is lost:
+//  1. no new background jobs are started
+//  2. running indexing jobs are finished; when ctx ends first, they are cancelled
+//     and only the pages currently being written are completed
+//  3. the file watcher records pending changes and takes a final history snapshot
+//  4. the databases are closed
+//
+// The HTTP server should be shut down before, so no request uses the closed databases.
+func (w *Wiki) Shutdown(ctx context.Context) error {
+	w.jobsMu.Lock()
+	if w.closing {
+		w.jobsMu.Unlock()
+		return nil
+	}
+	w.closing = true
+	w.jobsMu.Unlock()
+
+	close(w.stopPeriodic)
+
+	done := make(chan struct{})
+	go func() {
+		w.jobs.Wait()
+		close(done)
+	}()
+	select {
+	case <-done:
+	case <-ctx.Done():
+		if w.status.IsActive() {
+			wikiLog.Warn("cancelling running indexing job")
+		}
+		w.cancelJobs()
+		<-done
+	}
+	w.cancelJobs()
+	w.status.Finish()
+
+	if w.searchWatcher != nil {
+		if err := w.searchWatcher.Stop(); err != nil {
+			wikiLog.Error("could not stop search watcher", "error", err)
+		}
+	}
+
+	var errs []error
+	errs = append(errs, w.user.Close(), w.reading.Close(), w.favorites.Close(), w.drafts.Close(), w.redirects.Close(), w.settings.Close(), w.reviews.Close(), w.savedSearches.Close(), w.searchIndex.Close(), w.links.Close())
+	if w.spellcheck != nil {
+		errs = append(errs, w.spellcheck.Close())
+	}
+	return errors.Join(errs...)
+}
@@ -0,0 +1,65 @@
+package wiki
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/Gomez12/wiki/internal/core/auth"
+	"github.com/Gomez12/wiki/internal/core/shared/errors"
+	"github.com/Gomez12/wiki/internal/search"
+)
+
+// Limits of the similar pages
+const (
+	DefaultSimilarLimit = 5
+	MaxSimilarLimit     = 50
+)
+
+// SimilarPagesForUser returns the pages related to a page by the key terms they share, for
+// "See also" suggestions. Pages the user may not read are left out; the user is nil for
+// anonymous (public) access.
+func (w *Wiki) SimilarPagesForUser(user *auth.User, pageID string, limit int) ([]search.SimilarPage, error) {
+	page, err := w.tree.GetPage(pageID)
+	if err != nil {
+		return nil, err
+	}
+	return w.similarForUser(user, page.Title, page.Content, page.ID, limit)
+}
+
+// SimilarToForUser returns the pages similar to the title and Markdown content of a page
+// which doesn't exist yet, to point out likely duplicates when a page is created
+func (w *Wiki) SimilarToForUser(user *auth.User, title, content string, limit int) ([]search.SimilarPage, error) {
+	ve := errors.NewValidationErrors()
+	if strings.TrimSpace(title) == "" && strings.TrimSpace(content) == "" {
+		ve.Add("title", "Title or content is required")
+		return nil, ve
+	}
+	return w.similarForUser(user, title, content, "", limit)
+}
+
+func (w *Wiki) similarForUser(user *auth.User, title, content, excludeID string, limit int) ([]search.SimilarPage, error) {
+	ve := errors.NewValidationErrors()
+	if limit < 1 || limit > MaxSimilarLimit {
+		ve.Add("limit", fmt.Sprintf("Limit must be between 1 and %d", MaxSimilarLimit))
+		return nil, ve
+	}
+	if w.searchIndex == nil {
+		return nil, fmt.Errorf("search index not available")
+	}
+
+	if w.access.AllowsAll() {
+		return w.searchIndex.SimilarPages(title, search.PlainText(content), excludeID, limit)
+	}
+	// fetch all candidates, some of them may be hidden from the user
+	similar, err := w.searchIndex.SimilarPages(title, search.PlainText(content), excludeID, MaxSimilarLimit)
+	if err != nil {
+		return nil, err
+	}
+	allowed := []search.SimilarPage{}
+	for _, page := range similar {
+		if len(allowed) < limit && w.access.CanRead(user, page.Path) {
+			allowed = append(allowed, page)
+		}
+	}
+	return allowed, nil
+}
@@ -0,0 +1,116 @@
+package wiki
+
+import (
+	stderrors "errors"
+	"strings"
+
+	"github.com/Gomez12/wiki/internal/core/frontmatter"
+	"github.com/Gomez12/wiki/internal/core/shared/errors"
+	"github.com/Gomez12/wiki/internal/core/spellcheck"
+)
+
+// langField is the frontmatter field with the language of a page, e.g. en_US
+const langField = "lang"
+
+// SpellcheckResult lists the misspelled words of a page in the checked language
+type SpellcheckResult struct {
+	Language     string                   `json:"language"`
+	Misspellings []spellcheck.Misspelling `json:"misspellings"`
+}
+
+// SpellcheckEnabled tells if dictionaries are configured
+func (w *Wiki) SpellcheckEnabled() bool {
+	return w.spellcheck != nil
+}
+
+// SpellcheckLanguages returns the languages with a dictionary
+func (w *Wiki) SpellcheckLanguages() ([]string, error) {
+	if w.spellcheck == nil {
+		return nil, ErrSpellcheckDisabled
+	}
+	return w.spellcheck.Languages()
+}
+
+// SpellcheckPage checks the spelling of a page. content replaces the saved content, so the
+// editor can check unsaved changes. Without a language the lang field of the frontmatter is
+// used, or the only dictionary if there is just one.
+func (w *Wiki) SpellcheckPage(pageID, language string, content *string) (*SpellcheckResult, error) {
+	if w.spellcheck == nil {
+		return nil, ErrSpellcheckDisabled
+	}
+	page, err := w.tree.GetPage(pageID)
+	if err != nil {
+		return nil, err
+	}
+	text := page.Content
+	if content != nil {
+		text = *content
+	}
+
+	language = strings.TrimSpace(language)
+	if language == "" {
+		if fields, _, err := frontmatter.Parse(text); err == nil {
+			if lang, ok := fields[langField].(string); ok {
+				language = strings.TrimSpace(lang)
+			}
+		}
+	}
+	if language == "" {
+		languages, err := w.spellcheck.Languages()
+		if err != nil {
+			return nil, err
+		}
+		if len(languages) == 1 {
+			language = languages[0]
+		}
+	}
+
+	ve := errors.NewValidationErrors()
+	if language == "" {
+		ve.Add("language", "Language is required")
+		return nil, ve
+	}
+	misspellings, err := w.spellcheck.Check(language, text)
+	if stderrors.Is(err, spellcheck.ErrUnknownLanguage) {
+		ve.Add("language", "No dictionary for language "+language)
+		return nil, ve
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &SpellcheckResult{Language: language, Misspellings: misspellings}, nil
+}
+
+// GetCustomWords returns the custom dictionary of the wiki
+func (w *Wiki) GetCustomWords() ([]*spellcheck.CustomWord, error) {
+	if w.spellcheck == nil {
+		return nil, ErrSpellcheckDisabled
+	}
+	return w.spellcheck.Store().List()
+}
+
+// AddCustomWord adds a word to the custom dictionary, it is accepted in every language
+func (w *Wiki) AddCustomWord(word, userID string) (*spellcheck.CustomWord, error) {
+	if w.spellcheck == nil {
+		return nil, ErrSpellcheckDisabled
+	}
+	word = strings.TrimSpace(word)
+	ve := errors.NewValidationErrors()
+	if word == "" {
+		ve.Add("word", "Word is required")
+	} else if strings.ContainsFunc(word, func(r rune) bool { return r == ' ' || r == '\t' || r == '\n' }) {
+		ve.Add("word", "Word must not contain spaces")
+	}
+	if ve.HasErrors() {
+		return nil, ve
+	}
+	return w.spellcheck.Store().Add(word, userID)
+}
+
+// RemoveCustomWord removes a word from the custom dictionary
+func (w *Wiki) RemoveCustomWord(word string) error {
+	if w.spellcheck == nil {
+		return ErrSpellcheckDisabled
+	}
+	return w.spellcheck.Store().Remove(word)
+}
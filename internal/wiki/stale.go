@@ -0,0 +1,178 @@
+package wiki
+
+import (
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/Gomez12/wiki/internal/core/frontmatter"
+	"github.com/Gomez12/wiki/internal/core/shared/errors"
+	"github.com/Gomez12/wiki/internal/core/tree"
+)
+
+// reviewByField is the frontmatter field with the date a page must be reviewed by
+const reviewByField = "review-by"
+
+// Reasons a page is reported as stale
+const (
+	// StaleReasonUnchanged: not modified within the months and not viewed within the days
+	StaleReasonUnchanged = "unchanged"
+	// StaleReasonReviewOverdue: the review-by date has passed
+	StaleReasonReviewOverdue = "review-overdue"
+)
+
+// Defaults of the stale page report
+const (
+	DefaultStaleMonths     = 6
+	DefaultStaleViewedDays = 90
+)
+
+// StaleOptions select the pages of the stale page report
+type StaleOptions struct {
+	// Months without modification after which a page is stale
+	Months int
+	// ViewedDays keep a page off the report if it was viewed within this many days, 0 ignores views
+	ViewedDays int
+}
+
+// StalePage is a page which should be reviewed
+type StalePage struct {
+	PageID string `json:"pageId"`
+	Title  string `json:"title"`
+	Path   string `json:"path"`
+	// ModifiedAt comes from the page history, or the file if the page has none
+	ModifiedAt *time.Time `json:"modifiedAt"`
+	// LastViewedAt is the last reading position or tracked visit of any user
+	LastViewedAt *time.Time `json:"lastViewedAt"`
+	ReviewBy     string     `json:"reviewBy,omitempty"`
+	Reasons      []string   `json:"reasons"`
+}
+
+// StaleReport lists the stale pages, the longest unchanged first
+type StaleReport struct {
+	ModifiedBefore time.Time   `json:"modifiedBefore"`
+	ViewedBefore   *time.Time  `json:"viewedBefore"`
+	Pages          []StalePage `json:"pages"`
+}
+
+// StalePages reports the pages which were neither modified for opts.Months nor viewed for
+// opts.ViewedDays, and the pages whose review-by date has passed.
+func (w *Wiki) StalePages(opts StaleOptions) (*StaleReport, error) {
+	ve := errors.NewValidationErrors()
+	if opts.Months <= 0 {
+		ve.Add("months", "Months must be greater than 0")
+	}
+	if opts.ViewedDays < 0 {
+		ve.Add("viewedDays", "Viewed days must not be negative")
+	}
+	if ve.HasErrors() {
+		return nil, ve
+	}
+
+	now := time.Now().UTC()
+	report := &StaleReport{ModifiedBefore: now.AddDate(0, -opts.Months, 0), Pages: []StalePage{}}
+	if opts.ViewedDays > 0 {
+		viewedBefore := now.AddDate(0, 0, -opts.ViewedDays)
+		report.ViewedBefore = &viewedBefore
+	}
+
+	lastViewed, err := w.reading.LastViewed()
+	if err != nil {
+		return nil, err
+	}
+	today := now.Format(time.DateOnly)
+	dataDir := path.Join(w.storageDir, "root")
+
+	var walk func(nodes []*tree.PageNode) error
+	walk = func(nodes []*tree.PageNode) error {
+		for _, node := range nodes {
+			page, err := w.tree.GetPage(node.ID)
+			if err != nil {
+				return err
+			}
+			entry := StalePage{
+				PageID:     page.ID,
+				Title:      page.Title,
+				Path:       strings.TrimPrefix(page.CalculatePath(), "/"),
+				ModifiedAt: w.modifiedAt(dataDir, page),
+				Reasons:    []string{},
+			}
+			if viewed, ok := lastViewed[page.ID]; ok {
+				entry.LastViewedAt = &viewed
+			}
+
+			unchanged := entry.ModifiedAt == nil || entry.ModifiedAt.Before(report.ModifiedBefore)
+			unviewed := report.ViewedBefore == nil || entry.LastViewedAt == nil || entry.LastViewedAt.Before(*report.ViewedBefore)
+			if unchanged && unviewed {
+				entry.Reasons = append(entry.Reasons, StaleReasonUnchanged)
+			}
+
+			if fields, _, err := frontmatter.Parse(page.Content); err == nil {
+				if reviewBy, ok := reviewDate(fields[reviewByField]); ok {
+					entry.ReviewBy = reviewBy
+					// dates in the same format compare as strings
+					if reviewBy < today {
+						entry.Reasons = append(entry.Reasons, StaleReasonReviewOverdue)
+					}
+				}
+			}
+
+			if len(entry.Reasons) > 0 {
+				report.Pages = append(report.Pages, entry)
+			}
+			if err := walk(node.Children); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+	if err := walk(w.tree.GetTree().Children); err != nil {
+		return nil, err
+	}
+
+	sort.SliceStable(report.Pages, func(i, j int) bool {
+		a, b := report.Pages[i].ModifiedAt, report.Pages[j].ModifiedAt
+		if a == nil || b == nil {
+			return a == nil && b != nil
+		}
+		return a.Before(*b)
+	})
+	return report, nil
+}
+
+// modifiedAt returns the time of the newest history entry of a page, or else the
+// modification time of its file
+func (w *Wiki) modifiedAt(dataDir string, page *tree.Page) *time.Time {
+	if w.searchIndex != nil {
+		if _, modified := w.historyDates(page.CalculatePath()); modified != nil {
+			return modified
+		}
+	}
+	relPath := pageFilePath(dataDir, page)
+	if relPath == "" {
+		return nil
+	}
+	info, err := os.Stat(filepath.Join(dataDir, filepath.FromSlash(relPath)))
+	if err != nil {
+		return nil
+	}
+	modified := info.ModTime().UTC()
+	return &modified
+}
+
+// reviewDate returns the review-by date of the frontmatter as YYYY-MM-DD, YAML parsers
+// return dates either as string or as time
+func reviewDate(value any) (string, bool) {
+	switch v := value.(type) {
+	case time.Time:
+		return v.Format(time.DateOnly), true
+	case string:
+		if t, err := time.Parse(time.DateOnly, strings.TrimSpace(v)); err == nil {
+			return t.Format(time.DateOnly), true
+		}
+	}
+	return "", false
+}
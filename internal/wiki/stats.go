@@ -0,0 +1,48 @@
+package wiki
+
+import (
+	"io/fs"
+	"path"
+	"path/filepath"
+	"time"
+
+	"github.com/Gomez12/wiki/internal/core/tree"
+	"github.com/Gomez12/wiki/internal/search"
+)
+
+// Stats are wiki-wide numbers used for dashboards and badges
+type Stats struct {
+	PageCount   int                    `json:"pageCount"`
+	LastUpdated time.Time              `json:"lastUpdated"`
+	Indexing    *search.IndexingStatus `json:"indexing"`
+}
+
+// GetStats counts the pages of the tree and finds the latest modification of a page file
+func (w *Wiki) GetStats() *Stats {
+	stats := &Stats{
+		Indexing: w.GetIndexingStatus(),
+	}
+
+	var count func(nodes []*tree.PageNode)
+	count = func(nodes []*tree.PageNode) {
+		for _, node := range nodes {
+			stats.PageCount++
+			count(node.Children)
+		}
+	}
+	if root := w.tree.GetTree(); root != nil {
+		count(root.Children)
+	}
+
+	_ = filepath.WalkDir(path.Join(w.storageDir, "root"), func(p string, d fs.DirEntry, err error) error {
+		if err != nil || d.IsDir() || filepath.Ext(p) != ".md" {
+			return nil
+		}
+		if info, err := d.Info(); err == nil && info.ModTime().After(stats.LastUpdated) {
+			stats.LastUpdated = info.ModTime()
+		}
+		return nil
+	})
+
+	return stats
+}
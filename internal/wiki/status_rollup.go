@@ -0,0 +1,86 @@
+package wiki
+
+import (
+	"strings"
+
+	"github.com/Gomez12/wiki/internal/core/frontmatter"
+	"github.com/Gomez12/wiki/internal/core/tree"
+)
+
+// defaultStatusField is the frontmatter field used for the status rollup
+const defaultStatusField = "status"
+
+// StatusRollupEntry is a descendant page with its status
+type StatusRollupEntry struct {
+	ID     string `json:"id"`
+	Title  string `json:"title"`
+	Path   string `json:"path"`
+	Status string `json:"status"`
+}
+
+// StatusRollup summarizes the status field of all descendants of a page
+type StatusRollup struct {
+	PageID     string              `json:"pageId"`
+	Field      string              `json:"field"`
+	Total      int                 `json:"total"`
+	WithStatus int                 `json:"withStatus"`
+	Counts     map[string]int      `json:"counts"`
+	Pages      []StatusRollupEntry `json:"pages"`
+}
+
+// GetStatusRollup reads the frontmatter field of all descendants of a page
+// and counts the pages per status, e.g. for a progress summary of a project subtree.
+// Status values are compared case-insensitively; pages without the field are listed with an empty status.
+func (w *Wiki) GetStatusRollup(pageID string, field string) (*StatusRollup, error) {
+	if field == "" {
+		field = defaultStatusField
+	}
+
+	page, err := w.tree.GetPage(pageID)
+	if err != nil {
+		return nil, err
+	}
+
+	rollup := &StatusRollup{
+		PageID: page.ID,
+		Field:  field,
+		Counts: map[string]int{},
+		Pages:  []StatusRollupEntry{},
+	}
+
+	var walk func(children []*tree.PageNode)
+	walk = func(children []*tree.PageNode) {
+		for _, child := range children {
+			walk(child.Children)
+
+			childPage, err := w.tree.GetPage(child.ID)
+			if err != nil {
+				wikiLog.Warn("status rollup: could not read page", "pageId", child.ID, "error", err)
+				continue
+			}
+
+			fields, _, err := frontmatter.Parse(childPage.Content)
+			if err != nil {
+				wikiLog.Warn("status rollup: could not parse frontmatter", "pageId", child.ID, "error", err)
+			}
+
+			status, _ := frontmatter.String(fields, field)
+			status = strings.ToLower(strings.TrimSpace(status))
+
+			rollup.Total++
+			if status != "" {
+				rollup.WithStatus++
+				rollup.Counts[status]++
+			}
+			rollup.Pages = append(rollup.Pages, StatusRollupEntry{
+				ID:     child.ID,
+				Title:  child.Title,
+				Path:   strings.TrimPrefix(child.CalculatePath(), "/"),
+				Status: status,
+			})
+		}
+	}
+	walk(page.Children)
+
+	return rollup, nil
+}
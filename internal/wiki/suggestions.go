@@ -0,0 +1,193 @@
+package wiki
+
+import (
+	"math"
+	"path"
+	"sort"
+	"strings"
+
+	"github.com/Gomez12/wiki/internal/core/auth"
+	"github.com/Gomez12/wiki/internal/core/tree"
+)
+
+const (
+	// maxPageSuggestions limits the number of "did you mean" suggestions
+	maxPageSuggestions = 5
+	// minSuggestionScore is the minimum similarity for a fuzzy suggestion
+	minSuggestionScore = 0.5
+	// favoriteSuggestionBoost is added to the score of similar pages the user starred
+	favoriteSuggestionBoost = 0.2
+	// maxSuggestionRouteLength is the longest path which is compared with all pages, any 404
+	// may ask for suggestions, so longer paths only get the pages moved away from them
+	maxSuggestionRouteLength = 256
+)
+
+// Reasons for a page suggestion
+const (
+	SuggestionReasonMoved   = "moved"
+	SuggestionReasonSimilar = "similar"
+)
+
+// PageSuggestion is a page the user may have meant when a path does not exist
+type PageSuggestion struct {
+	ID     string  `json:"id"`
+	Title  string  `json:"title"`
+	Path   string  `json:"path"`
+	Reason string  `json:"reason"`
+	Score  float64 `json:"score"`
+}
+
+// SuggestPages returns pages matching a path which does not exist.
+// Pages which previously lived at the path according to the history come first,
+// followed by pages with a similar path, slug or title.
+func (w *Wiki) SuggestPages(route string) []PageSuggestion {
+	return w.SuggestPagesForUser(nil, route)
+}
+
+// SuggestPagesForUser is SuggestPages with the favorites of the user ranked higher among
+// the similar pages, leaving out the pages the user may not read. The user is nil for
+// anonymous (public) access.
+func (w *Wiki) SuggestPagesForUser(user *auth.User, route string) []PageSuggestion {
+	route = strings.Trim(strings.TrimSpace(route), "/")
+	suggestions := []PageSuggestion{}
+	if route == "" {
+		return suggestions
+	}
+
+	seen := map[string]bool{}
+
+	if w.searchIndex != nil {
+		targets, err := w.searchIndex.GetMovedTargets(route)
+		if err != nil {
+			wikiLog.Warn("could not look up moved pages", "path", route, "error", err)
+		}
+		for _, target := range targets {
+			page, err := w.FindByPath(historyPathToRoute(target))
+			if err != nil || seen[page.ID] {
+				continue
+			}
+			seen[page.ID] = true
+			pagePath := strings.TrimPrefix(page.CalculatePath(), "/")
+			if !w.access.AllowsAll() && !w.access.CanRead(user, pagePath) {
+				continue
+			}
+			suggestions = append(suggestions, PageSuggestion{
+				ID:     page.ID,
+				Title:  page.Title,
+				Path:   pagePath,
+				Reason: SuggestionReasonMoved,
+				Score:  1,
+			})
+		}
+	}
+
+	starred := map[string]bool{}
+	if user != nil {
+		favs, err := w.favorites.List(user.ID)
+		if err != nil {
+			wikiLog.Warn("could not look up favorites", "userId", user.ID, "error", err)
+		}
+		for _, fav := range favs {
+			starred[fav.PageID] = true
+		}
+	}
+
+	var similar []PageSuggestion
+	lastSegment := path.Base(route)
+	var walk func(nodes []*tree.PageNode)
+	walk = func(nodes []*tree.PageNode) {
+		for _, node := range nodes {
+			walk(node.Children)
+			if seen[node.ID] {
+				continue
+			}
+
+			nodePath := strings.TrimPrefix(node.CalculatePath(), "/")
+			score := similarity(strings.ToLower(route), strings.ToLower(nodePath))
+			if s := similarity(strings.ToLower(lastSegment), strings.ToLower(node.Slug)); s > score {
+				score = s
+			}
+			if s := similarity(strings.ToLower(lastSegment), strings.ToLower(node.Title)); s > score {
+				score = s
+			}
+			if score < minSuggestionScore {
+				continue
+			}
+			if !w.access.AllowsAll() && !w.access.CanRead(user, nodePath) {
+				continue
+			}
+			if starred[node.ID] {
+				score = math.Min(1, score+favoriteSuggestionBoost)
+			}
+
+			similar = append(similar, PageSuggestion{
+				ID:     node.ID,
+				Title:  node.Title,
+				Path:   nodePath,
+				Reason: SuggestionReasonSimilar,
+				Score:  score,
+			})
+		}
+	}
+	if len(route) <= maxSuggestionRouteLength {
+		walk(w.tree.GetTree().Children)
+	}
+
+	sort.SliceStable(similar, func(i, j int) bool {
+		if similar[i].Score == similar[j].Score {
+			return similar[i].Path < similar[j].Path
+		}
+		return similar[i].Score > similar[j].Score
+	})
+	suggestions = append(suggestions, similar...)
+
+	if len(suggestions) > maxPageSuggestions {
+		suggestions = suggestions[:maxPageSuggestions]
+	}
+	return suggestions
+}
+
+// historyPathToRoute converts a file path of the history into a route path
+func historyPathToRoute(p string) string {
+	p = strings.TrimSuffix(p, ".md")
+	p = strings.TrimSuffix(p, "/index")
+	return p
+}
+
+// similarity returns a value between 0 and 1 based on the Levenshtein distance. Strings
+// whose lengths differ too much to reach minSuggestionScore return 0 without computing it.
+func similarity(a, b string) float64 {
+	if a == b {
+		return 1
+	}
+	ra, rb := []rune(a), []rune(b)
+	shortest, longest := min(len(ra), len(rb)), max(len(ra), len(rb))
+	if longest == 0 {
+		return 1
+	}
+	// the distance is at least the difference of the lengths
+	if 1-float64(longest-shortest)/float64(longest) < minSuggestionScore {
+		return 0
+	}
+	return 1 - float64(levenshtein(ra, rb))/float64(longest)
+}
+
+func levenshtein(a, b []rune) int {
+	prev := make([]int, len(b)+1)
+	curr := make([]int, len(b)+1)
+	for j := range prev {
+		prev[j] = j
+	}
+	for i := 1; i <= len(a); i++ {
+		curr[0] = i
+		for j := 1; j <= len(b); j++ {
+			cost := 1
+			if a[i-1] == b[j-1] {
+				cost = 0
+			}
+			curr[j] = min(prev[j]+1, curr[j-1]+1, prev[j-1]+cost)
+		}
+		prev, curr = curr, prev
+	}
+	return prev[len(b)]
+}
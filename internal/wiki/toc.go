@@ -0,0 +1,18 @@
+package wiki
+
+import "github.com/Gomez12/wiki/internal/core/toc"
+
+// PageTOC is the table of contents of a page
+type PageTOC struct {
+	PageID   string         `json:"pageId"`
+	Headings []*toc.Heading `json:"headings"`
+}
+
+// GetPageTOC returns the heading hierarchy of a page with the anchors used by the web interface
+func (w *Wiki) GetPageTOC(pageID string) (*PageTOC, error) {
+	page, err := w.tree.GetPage(pageID)
+	if err != nil {
+		return nil, err
+	}
+	return &PageTOC{PageID: page.ID, Headings: toc.Extract(page.Content)}, nil
+}
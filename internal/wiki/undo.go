@@ -0,0 +1,77 @@
+package wiki
+
+import (
+	"context"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+
+	"github.com/Gomez12/wiki/internal/core/tree"
+	"github.com/Gomez12/wiki/internal/search"
+)
+
+// UndoPage reverts the most recent change of a page by restoring the latest history snapshot
+// whose content differs from the current content. The current content is recorded first,
+// so the reverted change stays in the history and undoing again restores it.
+func (w *Wiki) UndoPage(id string) (*tree.Page, error) {
+	page, err := w.tree.GetPage(id)
+	if err != nil {
+		return nil, err
+	}
+
+	dataDir := path.Join(w.storageDir, "root")
+	relPath := pageFilePath(dataDir, page)
+	if relPath != "" {
+		if err := w.searchIndex.CaptureFileChanges(context.Background(), dataDir, []string{relPath}); err != nil {
+			return nil, err
+		}
+	}
+
+	entries, _, err := w.searchIndex.QueryHistoryForPath(page.CalculatePath(), search.HistoryOptions{})
+	if err != nil {
+		return nil, err
+	}
+
+	currentHash := search.HashString(page.Content)
+	var previous *search.FileHistoryEntry
+	for i := range entries {
+		if entries[i].Status != search.FileStatusDeleted && entries[i].Hash != currentHash {
+			previous = &entries[i]
+			break
+		}
+	}
+	if previous == nil {
+		return nil, ErrNothingToUndo
+	}
+
+	snapshot, err := w.searchIndex.GetHistoryEntry(previous.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := w.tree.UpdatePage(id, page.Title, page.Slug, snapshot.Content); err != nil {
+		return nil, err
+	}
+	w.invalidateAliases()
+
+	if relPath != "" {
+		if err := w.searchIndex.CaptureFileChanges(context.Background(), dataDir, []string{relPath}); err != nil {
+			return nil, err
+		}
+	}
+
+	return w.tree.GetPage(id)
+}
+
+// pageFilePath returns the path of the Markdown file of a page relative to dataDir,
+// or an empty string if it doesn't exist
+func pageFilePath(dataDir string, page *tree.Page) string {
+	route := strings.TrimPrefix(page.CalculatePath(), "/")
+	for _, relPath := range []string{route + ".md", route + "/index.md"} {
+		if _, err := os.Stat(filepath.Join(dataDir, filepath.FromSlash(relPath))); err == nil {
+			return relPath
+		}
+	}
+	return ""
+}
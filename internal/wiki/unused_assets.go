@@ -0,0 +1,171 @@
+package wiki
+
+import (
+	"net/url"
+	"os"
+	"path"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/Gomez12/wiki/internal/core/assets"
+	"github.com/Gomez12/wiki/internal/core/tree"
+)
+
+// assetLinkRegex matches the asset paths in Markdown and HTML, e.g. /assets/<page id>/<name>
+var assetLinkRegex = regexp.MustCompile(`/assets/([^/\s()"'<>?#]+)/([^/\s()"'<>?#]+)`)
+
+// UnusedAsset is an asset which no page links to
+type UnusedAsset struct {
+	PageID string `json:"pageId"`
+	Title  string `json:"title"`
+	// Path is the route of the page the asset belongs to
+	Path string `json:"path"`
+	Name string `json:"name"`
+	URL  string `json:"url"`
+	Size int64  `json:"size"`
+}
+
+// UnusedAssetReport lists the unused assets, ordered by page path and name
+type UnusedAssetReport struct {
+	Assets    []UnusedAsset `json:"assets"`
+	TotalSize int64         `json:"totalSize"`
+}
+
+// UnusedAssetCleanup is the result of DeleteUnusedAssets
+type UnusedAssetCleanup struct {
+	DryRun bool `json:"dryRun"`
+	// Deleted are the assets which were deleted, or would be with DryRun
+	Deleted   []UnusedAsset `json:"deleted"`
+	TotalSize int64         `json:"totalSize"`
+	// Skipped are the requested URLs which are not (or no longer) unused assets
+	Skipped []string `json:"skipped"`
+}
+
+// UnusedAssets reports the assets which are not linked from the Markdown of any page,
+// neither by their /assets/<page id>/<name> path nor relative to their page folder
+func (w *Wiki) UnusedAssets() (*UnusedAssetReport, error) {
+	root := w.tree.GetTree()
+	if root == nil {
+		return nil, tree.ErrTreeNotLoaded
+	}
+
+	used := map[string]bool{}
+	var nodes []*tree.PageNode
+	var walk func(children []*tree.PageNode) error
+	walk = func(children []*tree.PageNode) error {
+		for _, node := range children {
+			page, err := w.tree.GetPage(node.ID)
+			if err != nil {
+				return err
+			}
+			content := assets.ResolvePageFolderLinks(page.Content, page.ID)
+			for _, match := range assetLinkRegex.FindAllStringSubmatch(content, -1) {
+				used[assetKey(unescapePath(match[1]), unescapePath(match[2]))] = true
+			}
+			nodes = append(nodes, node)
+			if err := walk(node.Children); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+	if err := walk(root.Children); err != nil {
+		return nil, err
+	}
+
+	report := &UnusedAssetReport{Assets: []UnusedAsset{}}
+	for _, node := range nodes {
+		urls, err := w.asset.ListAssetsForPage(node)
+		if err != nil {
+			return nil, err
+		}
+		for _, assetURL := range urls {
+			name := path.Base(assetURL)
+			if used[assetKey(node.ID, name)] {
+				continue
+			}
+			entry := UnusedAsset{
+				PageID: node.ID,
+				Title:  node.Title,
+				Path:   strings.TrimPrefix(node.CalculatePath(), "/"),
+				Name:   name,
+				URL:    "/assets/" + node.ID + "/" + name,
+			}
+			if file, err := w.asset.AssetFilePath(node, name); err == nil {
+				if info, err := os.Stat(file); err == nil {
+					entry.Size = info.Size()
+				}
+			}
+			report.Assets = append(report.Assets, entry)
+			report.TotalSize += entry.Size
+		}
+	}
+	sort.SliceStable(report.Assets, func(i, j int) bool {
+		a, b := report.Assets[i], report.Assets[j]
+		if a.Path != b.Path {
+			return a.Path < b.Path
+		}
+		return a.Name < b.Name
+	})
+	return report, nil
+}
+
+// DeleteUnusedAssets deletes the unused assets with the given URLs (/assets/<page id>/<name>),
+// or all unused assets if urls is empty. Assets which are linked by now are skipped. With
+// dryRun nothing is deleted, the result lists what would be.
+func (w *Wiki) DeleteUnusedAssets(urls []string, dryRun bool) (*UnusedAssetCleanup, error) {
+	report, err := w.UnusedAssets()
+	if err != nil {
+		return nil, err
+	}
+
+	selected := report.Assets
+	result := &UnusedAssetCleanup{DryRun: dryRun, Deleted: []UnusedAsset{}, Skipped: []string{}}
+	if len(urls) > 0 {
+		unused := map[string]UnusedAsset{}
+		for _, asset := range report.Assets {
+			unused[asset.URL] = asset
+		}
+		selected = nil
+		for _, assetURL := range urls {
+			asset, ok := unused[assetURL]
+			if !ok {
+				result.Skipped = append(result.Skipped, assetURL)
+				continue
+			}
+			delete(unused, assetURL)
+			selected = append(selected, asset)
+		}
+	}
+
+	for _, asset := range selected {
+		if !dryRun {
+			node, err := w.tree.FindPageByID(w.tree.GetTree().Children, asset.PageID)
+			if err != nil {
+				return result, err
+			}
+			if err := w.asset.DeleteAsset(node, asset.Name); err != nil {
+				return result, err
+			}
+		}
+		result.Deleted = append(result.Deleted, asset)
+		result.TotalSize += asset.Size
+	}
+	if !dryRun && len(result.Deleted) > 0 {
+		wikiLog.Info("deleted unused assets", "count", len(result.Deleted), "bytes", result.TotalSize)
+	}
+	return result, nil
+}
+
+func assetKey(pageID, name string) string {
+	return pageID + "/" + name
+}
+
+// unescapePath decodes a percent-encoded path segment of a link, e.g. my%20image.png
+func unescapePath(segment string) string {
+	if unescaped, err := url.PathUnescape(segment); err == nil {
+		return unescaped
+	}
+	return segment
+}
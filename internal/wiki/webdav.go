@@ -0,0 +1,260 @@
+package wiki
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path"
+	"strings"
+
+	"github.com/Gomez12/wiki/internal/core/auth"
+	"golang.org/x/net/webdav"
+)
+
+// WebDAVEnabled returns true if the Markdown files may be mounted over WebDAV
+func (w *Wiki) WebDAVEnabled() bool {
+	return w.webdav
+}
+
+// WebDAVFileSystem returns the Markdown files of the wiki as seen by the user.
+// Changes are written directly to disk and picked up by the file watcher, which updates
+// the tree and the search index, so they need search indexing to be enabled.
+// Pages the user may not read are hidden and can't be written, and like page saves, the
+// files may only get the raw HTML the role of the user is trusted with.
+func (w *Wiki) WebDAVFileSystem(user *auth.User) webdav.FileSystem {
+	var fsys webdav.FileSystem = webdav.Dir(path.Join(w.storageDir, "root"))
+	if !w.access.AllowsAll() {
+		fsys = &accessFileSystem{
+			FileSystem: fsys,
+			canRead: func(name string) bool {
+				return w.access.CanRead(user, routePathOfFile(name))
+			},
+		}
+	}
+	return &rawHTMLFileSystem{
+		FileSystem: fsys,
+		restricted: func() (bool, error) { return w.rawHTMLRestricted(user) },
+	}
+}
+
+// routePathOfFile returns the route path of a file below the root folder, e.g. /docs/index.md is docs
+func routePathOfFile(name string) string {
+	name = strings.TrimSuffix(strings.Trim(path.Clean("/"+name), "/"), ".md")
+	if name == "index" {
+		return ""
+	}
+	return strings.TrimSuffix(name, "/index")
+}
+
+// accessFileSystem hides the files of pages the user may not read
+type accessFileSystem struct {
+	webdav.FileSystem
+	canRead func(name string) bool
+}
+
+func (f *accessFileSystem) Mkdir(ctx context.Context, name string, perm os.FileMode) error {
+	if !f.canRead(name) {
+		return os.ErrPermission
+	}
+	return f.FileSystem.Mkdir(ctx, name, perm)
+}
+
+func (f *accessFileSystem) OpenFile(ctx context.Context, name string, flag int, perm os.FileMode) (webdav.File, error) {
+	if !f.canRead(name) {
+		return nil, os.ErrNotExist
+	}
+	file, err := f.FileSystem.OpenFile(ctx, name, flag, perm)
+	if err != nil {
+		return nil, err
+	}
+	return &accessFile{File: file, name: name, canRead: f.canRead}, nil
+}
+
+func (f *accessFileSystem) RemoveAll(ctx context.Context, name string) error {
+	if !f.canRead(name) {
+		return os.ErrNotExist
+	}
+	return f.FileSystem.RemoveAll(ctx, name)
+}
+
+func (f *accessFileSystem) Rename(ctx context.Context, oldName, newName string) error {
+	if !f.canRead(oldName) {
+		return os.ErrNotExist
+	}
+	if !f.canRead(newName) {
+		return os.ErrPermission
+	}
+	return f.FileSystem.Rename(ctx, oldName, newName)
+}
+
+func (f *accessFileSystem) Stat(ctx context.Context, name string) (os.FileInfo, error) {
+	if !f.canRead(name) {
+		return nil, os.ErrNotExist
+	}
+	return f.FileSystem.Stat(ctx, name)
+}
+
+// accessFile drops hidden pages from directory listings
+type accessFile struct {
+	webdav.File
+	name    string
+	canRead func(name string) bool
+}
+
+func (f *accessFile) Readdir(count int) ([]fs.FileInfo, error) {
+	infos, err := f.File.Readdir(count)
+	visible := infos[:0]
+	for _, info := range infos {
+		if f.canRead(path.Join(f.name, info.Name())) {
+			visible = append(visible, info)
+		}
+	}
+	return visible, err
+}
+
+// rawHTMLFileSystem checks the Markdown files written by users whose role isn't trusted with
+// raw HTML. The written content is buffered and only replaces the file once it was checked.
+type rawHTMLFileSystem struct {
+	webdav.FileSystem
+	restricted func() (bool, error)
+}
+
+func (f *rawHTMLFileSystem) OpenFile(ctx context.Context, name string, flag int, perm os.FileMode) (webdav.File, error) {
+	if flag&(os.O_WRONLY|os.O_RDWR) == 0 || path.Ext(name) != ".md" {
+		return f.FileSystem.OpenFile(ctx, name, flag, perm)
+	}
+	restricted, err := f.restricted()
+	if err != nil {
+		return nil, err
+	}
+	if !restricted {
+		return f.FileSystem.OpenFile(ctx, name, flag, perm)
+	}
+
+	existing, err := f.readFile(ctx, name)
+	if err != nil && !errors.Is(err, os.ErrNotExist) {
+		return nil, err
+	}
+	if errors.Is(err, os.ErrNotExist) {
+		if flag&os.O_CREATE == 0 {
+			return nil, err
+		}
+		// like opening the file, fails if the folder doesn't exist
+		if _, err := f.FileSystem.Stat(ctx, path.Dir(name)); err != nil {
+			return nil, err
+		}
+	}
+	file := &rawHTMLFile{fsys: f.FileSystem, ctx: ctx, name: name, flag: flag, perm: perm, existing: existing}
+	if flag&os.O_TRUNC == 0 {
+		file.buf.WriteString(existing)
+	}
+	return file, nil
+}
+
+func (f *rawHTMLFileSystem) Rename(ctx context.Context, oldName, newName string) error {
+	// renaming another file to a Markdown file turns its content into a page
+	if path.Ext(newName) == ".md" && path.Ext(oldName) != ".md" {
+		restricted, err := f.restricted()
+		if err != nil {
+			return err
+		}
+		if restricted {
+			if info, err := f.FileSystem.Stat(ctx, oldName); err == nil && !info.IsDir() {
+				content, err := f.readFile(ctx, oldName)
+				if err != nil {
+					return err
+				}
+				existing, err := f.readFile(ctx, newName)
+				if err != nil && !errors.Is(err, os.ErrNotExist) {
+					return err
+				}
+				if err := checkAddedRawHTML(existing, content); err != nil {
+					return fmt.Errorf("%w: %v", os.ErrPermission, err)
+				}
+			}
+		}
+	}
+	return f.FileSystem.Rename(ctx, oldName, newName)
+}
+
+func (f *rawHTMLFileSystem) readFile(ctx context.Context, name string) (string, error) {
+	file, err := f.FileSystem.OpenFile(ctx, name, os.O_RDONLY, 0)
+	if err != nil {
+		return "", err
+	}
+	defer file.Close()
+	data, err := io.ReadAll(file)
+	return string(data), err
+}
+
+// rawHTMLFile buffers the content written to a Markdown file and writes it to the file once
+// it was checked, when the file is closed or stat'ed after the write
+type rawHTMLFile struct {
+	fsys     webdav.FileSystem
+	ctx      context.Context
+	name     string
+	flag     int
+	perm     os.FileMode
+	existing string
+	buf      bytes.Buffer
+	written  bool
+	err      error
+}
+
+func (f *rawHTMLFile) Write(p []byte) (int, error) {
+	if f.written {
+		return 0, os.ErrClosed
+	}
+	return f.buf.Write(p)
+}
+
+func (f *rawHTMLFile) Read(p []byte) (int, error) {
+	return 0, os.ErrInvalid
+}
+
+func (f *rawHTMLFile) Seek(offset int64, whence int) (int64, error) {
+	return 0, os.ErrInvalid
+}
+
+func (f *rawHTMLFile) Readdir(count int) ([]fs.FileInfo, error) {
+	return nil, os.ErrInvalid
+}
+
+func (f *rawHTMLFile) Stat() (fs.FileInfo, error) {
+	if err := f.flush(); err != nil {
+		return nil, err
+	}
+	return f.fsys.Stat(f.ctx, f.name)
+}
+
+func (f *rawHTMLFile) Close() error {
+	return f.flush()
+}
+
+// flush checks the content and writes it to the file, once
+func (f *rawHTMLFile) flush() error {
+	if f.written {
+		return f.err
+	}
+	f.written = true
+	if err := checkAddedRawHTML(f.existing, f.buf.String()); err != nil {
+		f.err = fmt.Errorf("%w: %v", os.ErrPermission, err)
+		return f.err
+	}
+
+	file, err := f.fsys.OpenFile(f.ctx, f.name, f.flag|os.O_TRUNC, f.perm)
+	if err != nil {
+		f.err = err
+		return err
+	}
+	_, err = f.buf.WriteTo(file)
+	if closeErr := file.Close(); err == nil {
+		err = closeErr
+	}
+	f.err = err
+	return err
+}
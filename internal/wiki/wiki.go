@@ -1,16 +1,34 @@
 package wiki
 
 import (
+	"context"
+	stderrors "errors"
 	"fmt"
-	"log"
-	"mime/multipart"
+	"io"
 	"path"
 	"regexp"
 	"strings"
+	"sync"
+	"time"
 
+	"github.com/Gomez12/wiki/internal/core/access"
 	"github.com/Gomez12/wiki/internal/core/assets"
 	"github.com/Gomez12/wiki/internal/core/auth"
+	"github.com/Gomez12/wiki/internal/core/drafts"
+	"github.com/Gomez12/wiki/internal/core/favorites"
+	"github.com/Gomez12/wiki/internal/core/gitsync"
+	"github.com/Gomez12/wiki/internal/core/ignore"
+	"github.com/Gomez12/wiki/internal/core/linkcheck"
+	"github.com/Gomez12/wiki/internal/core/notify"
+	"github.com/Gomez12/wiki/internal/core/proxy"
+	"github.com/Gomez12/wiki/internal/core/reading"
+	"github.com/Gomez12/wiki/internal/core/redirects"
+	"github.com/Gomez12/wiki/internal/core/review"
+	"github.com/Gomez12/wiki/internal/core/savedsearch"
+	"github.com/Gomez12/wiki/internal/core/securityheaders"
+	"github.com/Gomez12/wiki/internal/core/settings"
 	"github.com/Gomez12/wiki/internal/core/shared/errors"
+	"github.com/Gomez12/wiki/internal/core/spellcheck"
 	"github.com/Gomez12/wiki/internal/core/tree"
 	"github.com/Gomez12/wiki/internal/search"
 )
@@ -21,89 +39,267 @@ type Wiki struct {
 	auth          *auth.AuthService
 	user          *auth.UserService
 	asset         *assets.AssetService
+	access        *access.Cache
+	reading       *reading.ReadingStore
+	favorites     *favorites.FavoriteStore
+	drafts        *drafts.DraftStore
+	redirects     *redirects.RedirectStore
+	settings      *settings.SettingsStore
 	searchIndex   *search.SQLiteIndex
 	status        *search.IndexingStatus
 	storageDir    string
 	searchWatcher *search.Watcher
+	// stopPeriodic stops the periodic jobs like history pruning and git sync
+	stopPeriodic chan struct{}
+	// gitSync is nil unless the content is synced with a git remote
+	gitSync *gitsync.Syncer
+	// webdav allows mounting the Markdown files, see WebDAVFileSystem
+	webdav bool
+	// links checks the external links of the pages
+	links *linkcheck.Checker
+	// ignore excludes files from indexing, the history and the tree
+	ignore *ignore.Rules
+	// obsidian serves the pages as Obsidian vault, see WithObsidian
+	obsidian bool
+	// aliases are additional routes of the pages
+	aliases aliasIndex
+	// reviews remembers the sent review reminders, see SendReviewReminders
+	reviews        *review.ReminderStore
+	reviewInterval time.Duration
+	mailer         *notify.Mailer
+	// spellcheck is nil unless dictionaries are configured, see WithSpellcheck
+	spellcheck *spellcheck.Checker
+	// importRoot is the directory whose folders can be imported, see WithImportRoot
+	importRoot string
+	// savedSearches holds the saved searches and their alerts, see CheckSavedSearches
+	savedSearches       *savedsearch.Store
+	searchAlertInterval time.Duration
+	// integrity is the last integrity check of the search database, see CheckIntegrity
+	integrity         integrityState
+	integrityInterval time.Duration
+	// securityHeaders overrides the headers set by the router, see WithSecurityHeaders
+	securityHeaders securityheaders.Config
+	// basePath is the path the wiki is served under, "" for the root, see WithBasePath
+	basePath       string
+	trustedProxies proxy.Trusted
+	configReload   func() error
+	// profiling serves the pprof endpoints, see WithProfiling
+	profiling bool
+	// setupMu serializes Setup, so only one request creates the first admin
+	setupMu sync.Mutex
+
+	// jobs tracks background work like indexing, so shutdown can wait for it.
+	// jobsMu guards closing, so no job starts after shutdown began.
+	jobs    sync.WaitGroup
+	jobsMu  sync.Mutex
+	closing bool
+	// jobsCtx is cancelled when Shutdown gives up waiting, the indexing jobs stop then
+	jobsCtx    context.Context
+	cancelJobs context.CancelFunc
 }
 
 // Email-RegEx (Basic-Check, nicht RFC-konform, aber gut genug)
 var emailRegex = regexp.MustCompile(`^[a-zA-Z0-9._%+\-]+@[a-zA-Z0-9.\-]+$`)
-var defaultAdminPassword = "admin"
 
-func NewWiki(storageDir string, adminPassword string, jwtSecret string, enableSearchIndexing bool) (*Wiki, error) {
-	// Initialize the user store
-	store, err := auth.NewUserStore(storageDir)
+// indexingWorkers is the number of workers used to build the search index
+const indexingWorkers = 4
+
+func NewWiki(storageDir string, adminPassword string, jwtSecret string, enableSearchIndexing bool, opts ...Option) (*Wiki, error) {
+	o := &options{}
+	for _, opt := range opts {
+		opt(o)
+	}
+
+	basePath, err := proxy.NormalizeBasePath(o.basePath)
 	if err != nil {
 		return nil, err
 	}
+	if o.trustedProxies == nil {
+		o.trustedProxies = proxy.DefaultTrustedProxies
+	}
+	trustedProxies, err := proxy.ParseTrusted(o.trustedProxies)
+	if err != nil {
+		return nil, fmt.Errorf("invalid trusted proxies: %w", err)
+	}
 
-	if adminPassword == "" {
-		adminPassword = defaultAdminPassword
+	var gitSyncer *gitsync.Syncer
+	if o.gitSync != nil {
+		var err error
+		if gitSyncer, err = gitsync.New(storageDir, *o.gitSync); err != nil {
+			return nil, fmt.Errorf("invalid git sync configuration: %w", err)
+		}
 	}
 
-	// Initialize the user service
-	userService := auth.NewUserService(store)
-	if err := userService.InitDefaultAdmin(adminPassword); err != nil {
+	// Initialize the user store
+	store, err := auth.NewUserStore(storageDir)
+	if err != nil {
 		return nil, err
 	}
 
+	// Initialize the user service. Without admin password the first admin is created by Setup.
+	userService := auth.NewUserService(store)
+	if adminPassword != "" {
+		if err := userService.InitDefaultAdmin(adminPassword); err != nil {
+			return nil, err
+		}
+	}
+
 	// Initialize the auth service
 	authService := auth.NewAuthService(userService, jwtSecret)
 
 	// Initialize the tree service
 	treeService := tree.NewTreeService(storageDir)
+	treeService.SetCaseInsensitiveRoutes(o.caseInsensitiveRoutes)
 	if err := treeService.LoadTree(); err != nil {
 		return nil, err
 	}
+	layout, err := resolveLayout(storageDir, treeService, o.layout)
+	if err != nil {
+		return nil, err
+	}
+	treeService.SetLayout(layout)
 
 	slugService := tree.NewSlugService()
 
 	assetService := assets.NewAssetService(storageDir, slugService)
+	assetService.SetPageFolders(o.pageFolderAssets)
 
-	sqliteIndex, err := search.NewSQLiteIndex(storageDir)
+	readingStore, err := reading.NewReadingStore(storageDir)
+	if err != nil {
+		return nil, err
+	}
+
+	favoriteStore, err := favorites.NewFavoriteStore(storageDir)
+	if err != nil {
+		return nil, err
+	}
+
+	draftStore, err := drafts.NewDraftStore(storageDir)
+	if err != nil {
+		return nil, err
+	}
+
+	redirectStore, err := redirects.NewRedirectStore(storageDir)
+	if err != nil {
+		return nil, err
+	}
+
+	settingsStore, err := settings.NewSettingsStore(storageDir)
+	if err != nil {
+		return nil, err
+	}
+
+	reminderStore, err := review.NewReminderStore(storageDir)
+	if err != nil {
+		return nil, err
+	}
+
+	savedSearchStore, err := savedsearch.NewStore(storageDir)
+	if err != nil {
+		return nil, err
+	}
+
+	searchDBConfig := search.DefaultSQLiteConfig()
+	if o.searchDBConfig != nil {
+		searchDBConfig = *o.searchDBConfig
+	}
+	sqliteIndex, err := search.NewSQLiteIndexWithConfig(storageDir, searchDBConfig)
 	if err != nil {
 		return nil, fmt.Errorf("failed to init search index: %w", err)
 	}
+	sqliteIndex.SetPartitioned(o.searchPartitions)
+	sqliteIndex.SetFollowSymlinks(o.followSymlinks)
+
+	ignoreRules := ignore.NewRules(path.Join(storageDir, "root"))
+	if s, err := settingsStore.Get(); err != nil {
+		wikiLog.Error("could not load settings", "error", err)
+	} else {
+		ignoreRules.SetPatterns(ignorePatterns(o.obsidian, s.IgnorePatterns))
+	}
+	sqliteIndex.SetIgnoreRules(ignoreRules)
+
+	linkChecker, err := linkcheck.New(storageDir, o.linkCheck)
+	if err != nil {
+		return nil, fmt.Errorf("failed to init link checker: %w", err)
+	}
+
+	var spellChecker *spellcheck.Checker
+	if o.spellcheckDir != "" {
+		if spellChecker, err = spellcheck.New(storageDir, o.spellcheckDir); err != nil {
+			return nil, fmt.Errorf("failed to init spellcheck: %w", err)
+		}
+	}
 
 	// status object for indexing
 	status := search.NewIndexingStatus()
 
-	var searchWatcher *search.Watcher
+	// Initialize the wiki service
+	wiki := &Wiki{
+		tree:         treeService,
+		slug:         slugService,
+		user:         userService,
+		auth:         authService,
+		asset:        assetService,
+		access:       access.NewCache(o.accessChecker),
+		reading:      readingStore,
+		favorites:    favoriteStore,
+		drafts:       draftStore,
+		redirects:    redirectStore,
+		settings:     settingsStore,
+		storageDir:   storageDir,
+		searchIndex:  sqliteIndex,
+		status:       status,
+		stopPeriodic: make(chan struct{}),
+		gitSync:      gitSyncer,
+		webdav:       o.webdav,
+		links:        linkChecker,
+		ignore:       ignoreRules,
+		obsidian:     o.obsidian,
+		reviews:      reminderStore,
+		mailer:       notify.NewMailer(o.smtp),
+		spellcheck:   spellChecker,
+		importRoot:   o.importRoot,
+
+		reviewInterval:      o.reviewInterval,
+		savedSearches:       savedSearchStore,
+		searchAlertInterval: o.searchAlertInterval,
+		integrityInterval:   o.integrityInterval,
+		securityHeaders:     o.securityHeaders,
+		basePath:            basePath,
+		trustedProxies:      trustedProxies,
+		configReload:        o.configReload,
+		profiling:           o.profiling,
+	}
+	wiki.jobsCtx, wiki.cancelJobs = context.WithCancel(context.Background())
+
+	// a damaged index is rebuilt before the pages are indexed
+	wiki.checkIntegrity(false)
+
 	if enableSearchIndexing {
 		// starts the indexing process in a separate goroutine
-		go func() {
-			err := search.BuildAndRunIndexer(treeService, sqliteIndex, path.Join(storageDir, "root"), 4, status)
-			if err != nil {
-				log.Printf("indexing failed: %v", err)
+		_ = wiki.startJob(func() {
+			err := search.BuildAndRunIndexer(wiki.jobsCtx, treeService, sqliteIndex, path.Join(storageDir, "root"), indexingWorkers, status)
+			if err != nil && wiki.jobsCtx.Err() == nil {
+				wikiLog.Error("indexing failed", "error", err)
 			}
-		}()
+		})
 
 		// Start the file watcher for indexing
-		var err error
-		searchWatcher, err = search.NewWatcher(path.Join(storageDir, "root"), treeService, sqliteIndex, status)
+		searchWatcher, err := search.NewWatcher(path.Join(storageDir, "root"), treeService, sqliteIndex, status)
 		if err != nil {
-			log.Printf("failed to create file watcher: %v", err)
+			wikiLog.Error("could not create file watcher", "error", err)
 		} else {
-			go func() {
+			searchWatcher.PollInterval = o.watchPollInterval
+			wiki.searchWatcher = searchWatcher
+			_ = wiki.startJob(func() {
 				if err := searchWatcher.Start(); err != nil {
-					log.Printf("failed to start file watcher: %v", err)
+					wikiLog.Error("could not start file watcher", "error", err)
 				}
-			}()
+			})
 		}
-	}
-
-	// Initialize the wiki service
-	wiki := &Wiki{
-		tree:          treeService,
-		slug:          slugService,
-		user:          userService,
-		auth:          authService,
-		asset:         assetService,
-		storageDir:    storageDir,
-		searchIndex:   sqliteIndex,
-		status:        status,
-		searchWatcher: searchWatcher,
+	} else if err := sqliteIndex.Clear(); err != nil {
+		// without indexing the kept pages would go stale
+		wikiLog.Error("could not clear search index", "error", err)
 	}
 
 	// Ensure the welcome page exists
@@ -111,6 +307,29 @@ func NewWiki(storageDir string, adminPassword string, jwtSecret string, enableSe
 		return nil, err
 	}
 
+	wiki.applyHistoryRetention()
+	_ = wiki.startJob(func() { wiki.runHistoryRetention(wiki.stopPeriodic) })
+
+	if wiki.gitSync != nil {
+		_ = wiki.startJob(func() { wiki.runGitSync(wiki.stopPeriodic) })
+	}
+
+	if wiki.links.Interval() > 0 {
+		_ = wiki.startJob(func() { wiki.runLinkCheck(wiki.stopPeriodic) })
+	}
+
+	if wiki.reviewInterval > 0 {
+		_ = wiki.startJob(func() { wiki.runReviewReminders(wiki.stopPeriodic) })
+	}
+
+	if wiki.searchAlertInterval > 0 {
+		_ = wiki.startJob(func() { wiki.runSearchAlerts(wiki.stopPeriodic) })
+	}
+
+	if wiki.integrityInterval > 0 {
+		_ = wiki.startJob(func() { wiki.runIntegrityCheck(wiki.stopPeriodic) })
+	}
+
 	return wiki, nil
 }
 
@@ -264,14 +483,26 @@ func (w *Wiki) UpdatePage(id, title, slug, content string) (*tree.Page, error) {
 		return nil, ve
 	}
 
+	oldRoute, _ := w.routeOf(id)
 	err := w.tree.UpdatePage(id, title, slug, content)
 	if err != nil {
 		return nil, err
 	}
+	w.invalidateAliases()
+	w.pageMoved(id, oldRoute)
 
 	return w.tree.GetPage(id)
 }
 
+// UpdatePageForUser is UpdatePage for a user, who may only add the raw HTML their role is
+// trusted with
+func (w *Wiki) UpdatePageForUser(user *auth.User, id, title, slug, content string) (*tree.Page, error) {
+	if err := w.CheckRawHTML(user, id, content); err != nil {
+		return nil, err
+	}
+	return w.UpdatePage(id, title, slug, content)
+}
+
 func (w *Wiki) CopyPage(currentPageID string, targetParentID *string, title string, slug string) (*tree.Page, error) {
 	// Validate the request
 	ve := errors.NewValidationErrors()
@@ -305,7 +536,13 @@ func (w *Wiki) CopyPage(currentPageID string, targetParentID *string, title stri
 		return nil, err
 	}
 
-	// Copy assets!
+	// Copy assets! The assets next to the page need a folder for the copy.
+	if hasPageFolderAssets(w.asset, page.PageNode) {
+		if err := w.ensurePageFolder(copy.ID); err != nil {
+			cleanup()
+			return nil, err
+		}
+	}
 	if err := w.asset.CopyAllAssets(page.PageNode, copy.PageNode); err != nil {
 		cleanup()
 		return nil, err
@@ -320,6 +557,7 @@ func (w *Wiki) CopyPage(currentPageID string, targetParentID *string, title stri
 		_ = w.asset.DeleteAllAssetsForPage(copy.PageNode)
 		return nil, err
 	}
+	w.invalidateAliases()
 
 	return copy, nil
 }
@@ -333,16 +571,25 @@ func (w *Wiki) DeletePage(id string, recursive bool) error {
 	if err := w.tree.DeletePage(id, recursive); err != nil {
 		return err
 	}
+	w.invalidateAliases()
+	w.deleteRedirectsTo(page.PageNode)
+	w.deleteDraftsOf(page.PageNode)
 
 	if err := w.asset.DeleteAllAssetsForPage(page.PageNode); err != nil {
-		log.Printf("warning: could not delete assets for page %s: %v", page.ID, err)
+		wikiLog.Warn("could not delete assets", "pageId", page.ID, "error", err)
 	}
 
 	return nil
 }
 
+// MovePage moves a page below another parent, updates the links to it and redirects the old route
 func (w *Wiki) MovePage(id, parentID string) error {
-	return w.tree.MovePage(id, parentID)
+	oldRoute, _ := w.routeOf(id)
+	if err := w.tree.MovePage(id, parentID); err != nil {
+		return err
+	}
+	w.pageMoved(id, oldRoute)
+	return nil
 }
 
 func (w *Wiki) SortPages(parentID string, orderedIDs []string) error {
@@ -353,24 +600,108 @@ func (w *Wiki) GetPage(id string) (*tree.Page, error) {
 	return w.tree.GetPage(id)
 }
 
-// GetPageHistory returns the history entries for a page path and the hash of the current on-disk content.
-func (w *Wiki) GetPageHistory(route string) ([]search.FileHistoryEntry, string, error) {
+// GetPageHistory returns a window of the history entries for a page path, the total number
+// of matching entries and the hash of the current on-disk content.
+func (w *Wiki) GetPageHistory(route string, opts search.HistoryOptions) ([]search.FileHistoryEntry, int, string, error) {
+	if err := validateHistoryOptions(opts); err != nil {
+		return nil, 0, "", err
+	}
+
 	page, err := w.FindByPath(route)
 	if err != nil {
-		return nil, "", err
+		return nil, 0, "", err
 	}
 
-	entries, err := w.searchIndex.GetHistoryForPath(page.CalculatePath())
+	entries, total, err := w.searchIndex.QueryHistoryForPath(page.CalculatePath(), opts)
 	if err != nil {
-		return nil, "", err
+		return nil, 0, "", err
 	}
 
 	currentHash := search.HashString(page.Content)
-	return entries, currentHash, nil
+	return entries, total, currentHash, nil
+}
+
+// GetSubtreeHistory returns a window of the history entries of all files below a directory,
+// e.g. "docs/", including deleted files and files which were moved out of it.
+func (w *Wiki) GetSubtreeHistory(dir string, opts search.HistoryOptions) ([]search.FileHistoryEntry, int, error) {
+	if err := validateHistoryOptions(opts); err != nil {
+		return nil, 0, err
+	}
+	return w.searchIndex.QueryHistoryForSubtree(dir, opts)
+}
+
+func validateHistoryOptions(opts search.HistoryOptions) error {
+	ve := errors.NewValidationErrors()
+	if opts.Limit < 0 {
+		ve.Add("limit", "Limit must not be negative")
+	}
+	if opts.Offset < 0 {
+		ve.Add("offset", "Offset must not be negative")
+	}
+	switch opts.Status {
+	case "", search.FileStatusCreated, search.FileStatusModified, search.FileStatusDeleted, search.FileStatusMoved:
+	default:
+		ve.Add("status", "Invalid status")
+	}
+	if ve.HasErrors() {
+		return ve
+	}
+	return nil
 }
 
+// GetPageHistoryEntry returns a single history snapshot including its content
+func (w *Wiki) GetPageHistoryEntry(id int64) (*search.FileHistoryEntry, error) {
+	return w.searchIndex.GetHistoryEntry(id)
+}
+
+// maxHistoryLabelLength is the maximum length of a history label
+const maxHistoryLabelLength = 100
+
+// LabelHistoryEntry pins a history entry under a label, e.g. "v1.0 release notes"
+func (w *Wiki) LabelHistoryEntry(id int64, label string) (*search.HistoryLabel, error) {
+	ve := errors.NewValidationErrors()
+	label = strings.TrimSpace(label)
+	if label == "" {
+		ve.Add("label", "Label must not be empty")
+	}
+	if len(label) > maxHistoryLabelLength {
+		ve.Add("label", fmt.Sprintf("Label must not be longer than %d characters", maxHistoryLabelLength))
+	}
+	if ve.HasErrors() {
+		return nil, ve
+	}
+
+	return w.searchIndex.AddHistoryLabel(id, label)
+}
+
+// GetPageLabels returns the labeled history entries of a page
+func (w *Wiki) GetPageLabels(route string) ([]search.HistoryLabel, error) {
+	page, err := w.FindByPath(route)
+	if err != nil {
+		return nil, err
+	}
+	return w.searchIndex.GetHistoryLabelsForPath(page.CalculatePath())
+}
+
+// GetPageAtLabel returns the history snapshot of a page as of the given label
+func (w *Wiki) GetPageAtLabel(route string, label string) (*search.FileHistoryEntry, error) {
+	page, err := w.FindByPath(route)
+	if err != nil {
+		return nil, err
+	}
+	return w.searchIndex.GetHistoryEntryByLabel(page.CalculatePath(), strings.TrimSpace(label))
+}
+
+// FindByPath returns the page with the route, or else the page with the route as alias
 func (w *Wiki) FindByPath(route string) (*tree.Page, error) {
-	return w.tree.FindPageByRoutePath(w.tree.GetTree().Children, route)
+	page, err := w.tree.FindPageByRoutePath(w.tree.GetTree().Children, route)
+	if err == nil {
+		return page, nil
+	}
+	if aliased, ok := w.pageByAlias(route); ok {
+		return aliased, nil
+	}
+	return nil, err
 }
 
 func (w *Wiki) LookupPagePath(path string) (*tree.PathLookup, error) {
@@ -412,8 +743,8 @@ func (w *Wiki) CreateUser(username, email, password, role string) (*auth.PublicU
 	}
 	if password == "" {
 		ve.Add("password", "Password must not be empty")
-	} else if len(password) < 8 {
-		ve.Add("password", "Password must be at least 8 characters long")
+	} else if err := w.validatePassword("password", password, ve); err != nil {
+		return nil, err
 	}
 	if !auth.IsValidRole(role) {
 		ve.Add("role", "Invalid role")
@@ -442,6 +773,11 @@ func (w *Wiki) UpdateUser(id, username, email, password, role string) (*auth.Pub
 	} else if !emailRegex.MatchString(email) {
 		ve.Add("email", "Email is not valid")
 	}
+	if password != "" {
+		if err := w.validatePassword("password", password, ve); err != nil {
+			return nil, err
+		}
+	}
 	if !auth.IsValidRole(role) {
 		ve.Add("role", "Invalid role")
 	}
@@ -454,6 +790,7 @@ func (w *Wiki) UpdateUser(id, username, email, password, role string) (*auth.Pub
 	if err != nil {
 		return nil, err
 	}
+	w.access.InvalidateUser(id)
 
 	return user.ToPublicUser(), nil
 }
@@ -462,8 +799,8 @@ func (w *Wiki) ChangeOwnPassword(id, oldPassword, newPassword string) error {
 	ve := errors.NewValidationErrors()
 	if newPassword == "" {
 		ve.Add("newPassword", "New password must not be empty")
-	} else if len(newPassword) < 8 {
-		ve.Add("newPassword", "New password must be at least 8 characters long")
+	} else if err := w.validatePassword("newPassword", newPassword, ve); err != nil {
+		return err
 	}
 
 	_, err := w.GetUserService().DoesIDAndPasswordMatch(id, oldPassword)
@@ -479,15 +816,25 @@ func (w *Wiki) ChangeOwnPassword(id, oldPassword, newPassword string) error {
 }
 
 func (w *Wiki) DeleteUser(id string) error {
-	return w.user.DeleteUser(id)
+	if err := w.user.DeleteUser(id); err != nil {
+		return err
+	}
+	w.access.InvalidateUser(id)
+	if err := w.favorites.DeleteForUser(id); err != nil {
+		return err
+	}
+	if err := w.drafts.DeleteForUser(id); err != nil {
+		return err
+	}
+	return w.reading.DeleteForUser(id)
 }
 
 func (w *Wiki) UpdatePassword(id, password string) error {
 	ve := errors.NewValidationErrors()
 	if password == "" {
 		ve.Add("password", "Password must not be empty")
-	} else if len(password) < 8 {
-		ve.Add("password", "Password must be at least 8 characters long")
+	} else if err := w.validatePassword("password", password, ve); err != nil {
+		return err
 	}
 
 	if ve.HasErrors() {
@@ -511,6 +858,39 @@ func (w *Wiki) GetUsers() ([]*auth.PublicUser, error) {
 	return publicUsers, nil
 }
 
+// maxUserListLimit is the maximum page size of a user listing
+const maxUserListLimit = 500
+
+// ListUsers returns a window of the users matching the options, without sensitive fields,
+// together with the total number of matching users.
+func (w *Wiki) ListUsers(opts auth.UserListOptions) ([]*auth.PublicUser, int, error) {
+	ve := errors.NewValidationErrors()
+	if opts.Limit < 1 || opts.Limit > maxUserListLimit {
+		ve.Add("limit", fmt.Sprintf("Limit must be between 1 and %d", maxUserListLimit))
+	}
+	if opts.Offset < 0 {
+		ve.Add("offset", "Offset must not be negative")
+	}
+	if opts.Sort != "" && !auth.IsValidUserSort(opts.Sort) {
+		ve.Add("sort", "Invalid sort field")
+	}
+	if ve.HasErrors() {
+		return nil, 0, ve
+	}
+
+	users, total, err := w.user.ListUsers(opts)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	publicUsers := make([]*auth.PublicUser, len(users))
+	for i, user := range users {
+		publicUsers[i] = user.ToPublicUser()
+	}
+
+	return publicUsers, total, nil
+}
+
 func (w *Wiki) GetUserByID(id string) (*auth.PublicUser, error) {
 	user, err := w.user.GetUserByID(id)
 	if err != nil {
@@ -529,12 +909,73 @@ func (w *Wiki) ResetAdminUserPassword() (*auth.User, error) {
 	return adminUser, nil
 }
 
-func (w *Wiki) UploadAsset(pageID string, file multipart.File, filename string) (string, error) {
+func (w *Wiki) UploadAsset(pageID string, file io.Reader, filename string) (string, error) {
+	page, err := w.tree.FindPageByID(w.tree.GetTree().Children, pageID)
+	if err != nil {
+		return "", err
+	}
+	if w.asset.PageFolders() {
+		if err := w.ensurePageFolder(page.ID); err != nil {
+			return "", err
+		}
+	}
+	content, err := w.processImage(file, filename)
+	if err != nil {
+		return "", err
+	}
+	return w.asset.SaveAssetForPage(page, content, filename)
+}
+
+// ReplaceAsset uploads an asset which replaces the asset of the same name, the replaced
+// content is kept as previous version
+func (w *Wiki) ReplaceAsset(pageID string, file io.Reader, filename string) (string, error) {
+	page, err := w.tree.FindPageByID(w.tree.GetTree().Children, pageID)
+	if err != nil {
+		return "", err
+	}
+	if w.asset.PageFolders() {
+		if err := w.ensurePageFolder(page.ID); err != nil {
+			return "", err
+		}
+	}
+	content, err := w.processImage(file, filename)
+	if err != nil {
+		return "", err
+	}
+	return w.asset.ReplaceAssetForPage(page, content, filename)
+}
+
+// AssetVersions returns the current and previous versions of an asset
+func (w *Wiki) AssetVersions(pageID, filename string) (*assets.AssetVersions, error) {
+	page, err := w.tree.FindPageByID(w.tree.GetTree().Children, pageID)
+	if err != nil {
+		return nil, err
+	}
+	return w.asset.ListAssetVersions(page, filename)
+}
+
+// AssetVersionFile returns the file with the content of a previous version of an asset
+func (w *Wiki) AssetVersionFile(pageID, filename, hash string) (string, error) {
 	page, err := w.tree.FindPageByID(w.tree.GetTree().Children, pageID)
 	if err != nil {
 		return "", err
 	}
-	return w.asset.SaveAssetForPage(page, file, filename)
+	return w.asset.AssetVersionPath(page, filename, hash)
+}
+
+// RestoreAssetVersion restores a previous version of an asset, also of a deleted one
+func (w *Wiki) RestoreAssetVersion(pageID, filename, hash string) (string, error) {
+	page, err := w.tree.FindPageByID(w.tree.GetTree().Children, pageID)
+	if err != nil {
+		return "", err
+	}
+	// a deleted asset is restored like a new upload
+	if _, err := w.asset.AssetFilePath(page, filename); err != nil && w.asset.PageFolders() {
+		if err := w.ensurePageFolder(page.ID); err != nil {
+			return "", err
+		}
+	}
+	return w.asset.RestoreAssetVersion(page, filename, hash)
 }
 
 func (w *Wiki) ListAssets(pageID string) ([]string, error) {
@@ -553,6 +994,55 @@ func (w *Wiki) RenameAsset(pageID string, oldFilename, newFilename string) (stri
 	return w.asset.RenameAsset(page, oldFilename, newFilename)
 }
 
+// MoveAsset renames an asset or moves it to another page (targetPageID, the same page if empty)
+// and rewrites the links to it in the Markdown of the page which owned it. Assets moved to
+// another page are linked as /assets/<target id>/<name> from there. Returns the public path.
+func (w *Wiki) MoveAsset(pageID, filename, targetPageID, newFilename string) (string, error) {
+	page, err := w.tree.GetPage(pageID)
+	if err != nil {
+		return "", err
+	}
+	if newFilename == "" {
+		newFilename = filename
+	}
+
+	var url string
+	target := page.PageNode
+	if targetPageID == "" || targetPageID == page.ID {
+		if newFilename == filename {
+			ve := errors.NewValidationErrors()
+			ve.Add("name", "The asset already has this name")
+			return "", ve
+		}
+		url, err = w.asset.RenameAsset(page.PageNode, filename, newFilename)
+	} else {
+		if target, err = w.tree.FindPageByID(w.tree.GetTree().Children, targetPageID); err != nil {
+			return "", err
+		}
+		if w.asset.PageFolders() {
+			if err := w.ensurePageFolder(target.ID); err != nil {
+				return "", err
+			}
+		}
+		url, err = w.asset.MoveAsset(page.PageNode, target, filename, newFilename)
+	}
+	if err != nil {
+		return "", err
+	}
+
+	link := url
+	if target.ID != page.ID && strings.HasPrefix(url, assets.PageFolderLinkPrefix) {
+		link = "/assets/" + target.ID + "/" + newFilename
+	}
+	content := assets.RewriteAssetLinks(page.Content, page.ID, filename, link)
+	if content != page.Content {
+		if err := w.writeReplacement(path.Join(w.storageDir, "root"), page, content); err != nil {
+			wikiLog.Warn("could not update the links to the moved asset", "pageId", page.ID, "error", err)
+		}
+	}
+	return url, nil
+}
+
 func (w *Wiki) DeleteAsset(pageID string, filename string) error {
 	page, err := w.tree.FindPageByID(w.tree.GetTree().Children, pageID)
 	if err != nil {
@@ -565,15 +1055,319 @@ func (w *Wiki) GetIndexingStatus() *search.IndexingStatus {
 	return w.status.Snapshot()
 }
 
+// IndexErrors returns the files whose last indexing failed, with the error of each
+func (w *Wiki) IndexErrors() ([]search.IndexResult, error) {
+	return w.searchIndex.IndexErrors()
+}
+
 func (w *Wiki) IsIndexingActive() bool {
 	return w.status != nil && w.status.IsActive()
 }
 
-func (w *Wiki) Search(query string, offset, limit int) (*search.SearchResult, error) {
+// ReindexAll wipes the search index and rebuilds it in the background.
+// The progress can be followed through the indexing status.
+func (w *Wiki) ReindexAll() error {
+	if !w.status.TryStart() {
+		return search.ErrIndexingInProgress
+	}
+
+	err := w.startJob(func() {
+		if err := w.searchIndex.Clear(); err != nil {
+			wikiLog.Error("reindex failed: could not clear index", "error", err)
+			w.status.Finish()
+			return
+		}
+		if err := search.BuildAndRunIndexer(w.jobsCtx, w.tree, w.searchIndex, path.Join(w.storageDir, "root"), indexingWorkers, w.status); err != nil && w.jobsCtx.Err() == nil {
+			wikiLog.Error("reindex failed", "error", err)
+		}
+	})
+	if err != nil {
+		w.status.Finish()
+	}
+	return err
+}
+
+// ReindexPartition rebuilds a single partition of the search index in the background.
+// The other partitions stay searchable while the partition is rebuilt.
+func (w *Wiki) ReindexPartition(partition string) error {
+	ve := errors.NewValidationErrors()
+	if !w.searchIndex.IsPartitioned() {
+		ve.Add("partition", "Search index is not partitioned")
+	} else if partition == "" {
+		ve.Add("partition", "Partition must not be empty")
+	}
+	if ve.HasErrors() {
+		return ve
+	}
+
+	if !w.status.TryStart() {
+		return search.ErrIndexingInProgress
+	}
+
+	err := w.startJob(func() {
+		if err := search.RebuildPartition(w.jobsCtx, w.tree, w.searchIndex, path.Join(w.storageDir, "root"), partition, indexingWorkers, w.status); err != nil && w.jobsCtx.Err() == nil {
+			wikiLog.Error("reindex of partition failed", "partition", partition, "error", err)
+		}
+	})
+	if err != nil {
+		w.status.Finish()
+	}
+	return err
+}
+
+// Search queries the full text index, the query is interrupted when ctx ends
+func (w *Wiki) Search(ctx context.Context, query string, offset, limit int) (*search.SearchResult, error) {
 	if w.searchIndex == nil {
 		return nil, fmt.Errorf("search index not available")
 	}
-	return w.searchIndex.Search(query, offset, limit)
+	return w.searchIndex.Search(ctx, query, offset, limit)
+}
+
+// SearchForUser searches the index and drops all results the user may not read.
+// The user is nil for anonymous (public) access.
+// Results are fetched in batches until the requested window is filled, so the
+// window is stable across pages. Count is exact once all matches were checked
+// and an upper bound otherwise.
+func (w *Wiki) SearchForUser(ctx context.Context, user *auth.User, query string, offset, limit int) (*search.SearchResult, error) {
+	if w.access.AllowsAll() {
+		return w.Search(ctx, query, offset, limit)
+	}
+	if w.searchIndex == nil {
+		return nil, fmt.Errorf("search index not available")
+	}
+
+	batchSize := (offset + limit) * 2
+	if batchSize < 50 {
+		batchSize = 50
+	}
+
+	allowed := []search.SearchResultItem{}
+	denied := 0
+	scanned := 0
+	total := 0
+	for {
+		batch, err := w.searchIndex.Search(ctx, query, scanned, batchSize)
+		if err != nil {
+			return nil, err
+		}
+		total = batch.Count
+		for _, item := range batch.Items {
+			if w.access.CanRead(user, item.Path) {
+				allowed = append(allowed, item)
+			} else {
+				denied++
+			}
+		}
+		scanned += len(batch.Items)
+
+		if len(allowed) >= offset+limit || len(batch.Items) < batchSize || scanned >= total {
+			break
+		}
+	}
+
+	window := []search.SearchResultItem{}
+	if offset < len(allowed) {
+		end := offset + limit
+		if end > len(allowed) {
+			end = len(allowed)
+		}
+		window = allowed[offset:end]
+	}
+
+	return &search.SearchResult{
+		Count:  total - denied,
+		Items:  window,
+		Limit:  limit,
+		Offset: offset,
+	}, nil
+}
+
+// SearchWithDeletedForUser searches like SearchForUser and also the last snapshots of the
+// deleted pages, so content which no longer exists can be found and restored from the
+// history. Deleted pages follow the existing ones.
+func (w *Wiki) SearchWithDeletedForUser(ctx context.Context, user *auth.User, query string, offset, limit int) (*search.SearchResult, error) {
+	res, err := w.SearchForUser(ctx, user, query, offset, limit)
+	if err != nil {
+		return nil, err
+	}
+
+	deleted, err := w.searchIndex.SearchDeleted(ctx, query, 0, -1)
+	if err != nil {
+		return nil, err
+	}
+	allowed := []search.SearchResultItem{}
+	for _, item := range deleted.Items {
+		if w.access.AllowsAll() || w.access.CanRead(user, item.Path) {
+			allowed = append(allowed, item)
+		}
+	}
+
+	start := offset - res.Count
+	if start < 0 {
+		start = 0
+	}
+	for i := start; i < len(allowed) && len(res.Items) < limit; i++ {
+		res.Items = append(res.Items, allowed[i])
+	}
+	res.Count += len(allowed)
+	return res, nil
+}
+
+// InvalidateAccessCache drops all cached access decisions.
+// It must be called whenever permissions change.
+func (w *Wiki) InvalidateAccessCache() {
+	w.access.Invalidate()
+}
+
+// maxReadingAnchorLength limits the size of a stored scroll anchor
+const maxReadingAnchorLength = 512
+
+// SaveReadingPosition remembers where a user stopped reading a page
+func (w *Wiki) SaveReadingPosition(userID, pageID, anchor string) (*reading.Position, error) {
+	ve := errors.NewValidationErrors()
+	if len(anchor) > maxReadingAnchorLength {
+		ve.Add("anchor", fmt.Sprintf("Anchor must not be longer than %d characters", maxReadingAnchorLength))
+	}
+	if ve.HasErrors() {
+		return nil, ve
+	}
+
+	if _, err := w.tree.GetPage(pageID); err != nil {
+		return nil, err
+	}
+
+	return w.reading.SavePosition(userID, pageID, anchor)
+}
+
+func (w *Wiki) GetReadingPosition(userID, pageID string) (*reading.Position, error) {
+	return w.reading.GetPosition(userID, pageID)
+}
+
+// GetRecentlyRead returns the pages a user read or viewed last, most recent first, to continue
+// where they left off. Views are only recorded while tracking is enabled and the user opted
+// in, otherwise the list has the read pages only. Positions of pages which no longer exist
+// are removed.
+func (w *Wiki) GetRecentlyRead(userID string, limit int) ([]*reading.RecentPage, error) {
+	positions, err := w.reading.ListActivity(userID, limit)
+	if err != nil {
+		return nil, err
+	}
+	return w.recentPages(positions), nil
+}
+
+// recentPages adds the title and path to the positions of pages, positions of pages which
+// no longer exist are removed
+func (w *Wiki) recentPages(positions []*reading.Position) []*reading.RecentPage {
+	recent := []*reading.RecentPage{}
+	for _, pos := range positions {
+		page, err := w.tree.GetPage(pos.PageID)
+		if stderrors.Is(err, tree.ErrPageNotFound) {
+			if err := w.reading.DeleteForPage(pos.PageID); err != nil {
+				wikiLog.Warn("could not remove reading positions", "pageId", pos.PageID, "error", err)
+			}
+			continue
+		}
+		if err != nil {
+			wikiLog.Warn("could not load recently read page", "pageId", pos.PageID, "error", err)
+			continue
+		}
+		recent = append(recent, &reading.RecentPage{
+			PageID:    page.ID,
+			Title:     page.Title,
+			Path:      strings.TrimPrefix(page.CalculatePath(), "/"),
+			Anchor:    pos.Anchor,
+			UpdatedAt: pos.UpdatedAt,
+		})
+	}
+	return recent
+}
+
+// AddFavorite stars a page for a user
+func (w *Wiki) AddFavorite(userID, pageID string) (*favorites.FavoritePage, error) {
+	page, err := w.tree.GetPage(pageID)
+	if err != nil {
+		return nil, err
+	}
+	fav, err := w.favorites.Add(userID, pageID)
+	if err != nil {
+		return nil, err
+	}
+	return &favorites.FavoritePage{
+		PageID:    page.ID,
+		Title:     page.Title,
+		Path:      strings.TrimPrefix(page.CalculatePath(), "/"),
+		CreatedAt: fav.CreatedAt,
+	}, nil
+}
+
+// RemoveFavorite unstars a page for a user
+func (w *Wiki) RemoveFavorite(userID, pageID string) error {
+	return w.favorites.Remove(userID, pageID)
+}
+
+// GetFavorites returns the pages a user starred, most recently starred first.
+// Favorites of pages which no longer exist are removed.
+func (w *Wiki) GetFavorites(userID string) ([]*favorites.FavoritePage, error) {
+	list, err := w.favorites.List(userID)
+	if err != nil {
+		return nil, err
+	}
+
+	pages := []*favorites.FavoritePage{}
+	for _, fav := range list {
+		page, err := w.tree.GetPage(fav.PageID)
+		if err != nil {
+			if err := w.favorites.DeleteForPage(fav.PageID); err != nil {
+				wikiLog.Warn("could not remove favorites", "pageId", fav.PageID, "error", err)
+			}
+			continue
+		}
+		pages = append(pages, &favorites.FavoritePage{
+			PageID:    page.ID,
+			Title:     page.Title,
+			Path:      strings.TrimPrefix(page.CalculatePath(), "/"),
+			CreatedAt: fav.CreatedAt,
+		})
+	}
+	return pages, nil
+}
+
+// RecordPageVisit remembers that a user viewed a page, if tracking is enabled in the settings
+// and the user opted in. Errors are only logged, a failed record must not fail the page view.
+func (w *Wiki) RecordPageVisit(userID, pageID string) {
+	if !w.TracksRecentPages() {
+		return
+	}
+	if tracking, err := w.reading.Tracking(userID); err != nil || !tracking {
+		if err != nil {
+			wikiLog.Warn("could not look up visit tracking", "userId", userID, "error", err)
+		}
+		return
+	}
+	if err := w.reading.RecordVisit(userID, pageID); err != nil {
+		wikiLog.Warn("could not record page visit", "pageId", pageID, "error", err)
+	}
+}
+
+// TracksRecentPages reports whether page visits may be recorded for the users who opted in
+func (w *Wiki) TracksRecentPages() bool {
+	s, err := w.settings.Get()
+	if err != nil {
+		wikiLog.Error("could not load settings", "error", err)
+		return false
+	}
+	return s.TrackRecentPages
+}
+
+// SetRecentPagesTracking opts a user in or out of recording the pages they view. Opting out
+// removes the visits recorded so far.
+func (w *Wiki) SetRecentPagesTracking(userID string, enabled bool) error {
+	return w.reading.SetTracking(userID, enabled)
+}
+
+// RecentPagesTracking reports whether a user opted in to recording the pages they view
+func (w *Wiki) RecentPagesTracking(userID string) (bool, error) {
+	return w.reading.Tracking(userID)
 }
 
 func (w *Wiki) GetUserService() *auth.UserService {
@@ -592,17 +1386,30 @@ func (w *Wiki) GetStorageDir() string {
 	return w.storageDir
 }
 
-func (w *Wiki) Close() error {
-	w.status.Finish()
-	if err := w.user.Close(); err != nil {
-		return err
-	}
+// SecurityHeaders returns the overrides of the security headers, see WithSecurityHeaders
+func (w *Wiki) SecurityHeaders() securityheaders.Config {
+	return w.securityHeaders
+}
 
-	if w.searchWatcher != nil {
-		if err := w.searchWatcher.Stop(); err != nil {
-			log.Printf("error stopping search watcher: %v", err)
-		}
-	}
+// BasePath returns the path the wiki is served under, "" for the root, see WithBasePath
+func (w *Wiki) BasePath() string {
+	return w.basePath
+}
 
-	return w.searchIndex.Close()
+// TrustedProxies returns the proxies whose X-Forwarded headers are trusted
+func (w *Wiki) TrustedProxies() proxy.Trusted {
+	return w.trustedProxies
+}
+
+// ProfilingEnabled returns true if admins may fetch profiles at /debug/pprof
+func (w *Wiki) ProfilingEnabled() bool {
+	return w.profiling
+}
+
+// Close stops the background work right away and closes the databases.
+// Use Shutdown to give running indexing jobs time to finish.
+func (w *Wiki) Close() error {
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	return w.Shutdown(ctx)
 }
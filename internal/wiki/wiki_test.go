@@ -1,10 +1,38 @@
 package wiki
 
 import (
+	"archive/zip"
+	"bytes"
+	"context"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"image"
+	"image/png"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
 	"testing"
-
+	"time"
+	"unicode/utf8"
+
+	"github.com/Gomez12/wiki/internal/core/auth"
+	"github.com/Gomez12/wiki/internal/core/drafts"
+	"github.com/Gomez12/wiki/internal/core/gitsync"
+	"github.com/Gomez12/wiki/internal/core/importer"
+	"github.com/Gomez12/wiki/internal/core/linkcheck"
+	"github.com/Gomez12/wiki/internal/core/lint"
+	"github.com/Gomez12/wiki/internal/core/notify"
+	"github.com/Gomez12/wiki/internal/core/redirects"
+	"github.com/Gomez12/wiki/internal/core/savedsearch"
 	verrors "github.com/Gomez12/wiki/internal/core/shared/errors"
+	"github.com/Gomez12/wiki/internal/core/staticsite"
 	"github.com/Gomez12/wiki/internal/core/tree"
+	"github.com/Gomez12/wiki/internal/search"
 	"github.com/Gomez12/wiki/internal/test_utils"
 )
 
@@ -17,6 +45,16 @@ func setupTestWiki(t *testing.T) *Wiki {
 	return w
 }
 
+// setupImportWiki returns a wiki which imports the folders below the returned directory
+func setupImportWiki(t *testing.T) (*Wiki, string) {
+	root := t.TempDir()
+	w, err := NewWiki(t.TempDir(), "admin", "secretkey", false, WithImportRoot(root))
+	if err != nil {
+		t.Fatalf("Failed to create wiki: %v", err)
+	}
+	return w, root
+}
+
 func TestWiki_CreatePage_Root(t *testing.T) {
 	w := setupTestWiki(t)
 
@@ -377,6 +415,27 @@ func TestWiki_InitDefaultAdmin_UsesGivenPassword(t *testing.T) {
 	}
 }
 
+func TestWiki_Setup(t *testing.T) {
+	w, err := NewWiki(t.TempDir(), "", "secretkey", false)
+	if err != nil {
+		t.Fatalf("Failed to create wiki: %v", err)
+	}
+	defer w.Close()
+
+	if required, err := w.SetupRequired(); err != nil || !required {
+		t.Fatalf("Expected a setup without admin password, got %v, %v", required, err)
+	}
+	if _, err := w.Setup(SetupRequest{Username: "alice", Email: "alice@example.com", Password: "s3cret-passw0rd", SiteTitle: " Docs "}); err != nil {
+		t.Fatalf("Setup failed: %v", err)
+	}
+	if s, _ := w.GetSettings(); s.SiteTitle != "Docs" {
+		t.Errorf("Expected the site title to be set, got %q", s.SiteTitle)
+	}
+	if _, err := w.Setup(SetupRequest{Username: "bob", Email: "bob@example.com", Password: "s3cret-passw0rd"}); !errors.Is(err, ErrSetupCompleted) {
+		t.Errorf("Expected ErrSetupCompleted for a second setup, got %v", err)
+	}
+}
+
 func TestWiki_ResetAdminUserPassword_ChangesPassword(t *testing.T) {
 	w := setupTestWiki(t)
 
@@ -436,3 +495,2529 @@ func TestWiki_ResetAdminPasswordWithoutJWTSecret(t *testing.T) {
 		t.Error("Expected new password to be set, got empty string")
 	}
 }
+
+type prefixDenyChecker struct {
+	prefix string
+}
+
+func (c prefixDenyChecker) CanRead(user *auth.User, subtree string) bool {
+	if user != nil && user.HasRole(auth.RoleAdmin) {
+		return true
+	}
+	return !strings.HasPrefix(subtree, c.prefix)
+}
+
+func TestWiki_SearchForUser_FiltersByAccess(t *testing.T) {
+	tempDir := t.TempDir()
+	w, err := NewWiki(tempDir, "admin", "secretkey", false, WithAccessChecker(prefixDenyChecker{prefix: "private"}))
+	if err != nil {
+		t.Fatalf("Failed to create wiki: %v", err)
+	}
+	defer w.Close()
+
+	pages := []struct{ path, id string }{
+		{"private/a", "p1"},
+		{"docs/a", "d1"},
+		{"private/b", "p2"},
+		{"docs/b", "d2"},
+	}
+	for _, p := range pages {
+		if err := w.searchIndex.IndexPage(p.path, p.path+".md", p.id, "Guide "+p.id, "shared keyword"); err != nil {
+			t.Fatalf("IndexPage failed: %v", err)
+		}
+	}
+
+	editor := &auth.User{ID: "editor", Role: auth.RoleEditor}
+	result, err := w.SearchForUser(t.Context(), editor, "keyword", 0, 10)
+	if err != nil {
+		t.Fatalf("SearchForUser failed: %v", err)
+	}
+	if result.Count != 2 || len(result.Items) != 2 {
+		t.Fatalf("expected 2 readable results, got count=%d items=%d", result.Count, len(result.Items))
+	}
+	for _, item := range result.Items {
+		if strings.HasPrefix(item.Path, "private") {
+			t.Errorf("unexpected private result %s", item.Path)
+		}
+	}
+
+	// Pagination works on the filtered results
+	result, err = w.SearchForUser(t.Context(), editor, "keyword", 1, 1)
+	if err != nil {
+		t.Fatalf("SearchForUser failed: %v", err)
+	}
+	if len(result.Items) != 1 || strings.HasPrefix(result.Items[0].Path, "private") {
+		t.Fatalf("expected second readable result, got %+v", result.Items)
+	}
+
+	admin := &auth.User{ID: "admin", Role: auth.RoleAdmin}
+	result, err = w.SearchForUser(t.Context(), admin, "keyword", 0, 10)
+	if err != nil {
+		t.Fatalf("SearchForUser failed: %v", err)
+	}
+	if result.Count != 4 {
+		t.Fatalf("expected admin to see all 4 results, got %d", result.Count)
+	}
+}
+
+func TestWiki_WebDAVFileSystem_HidesUnreadablePages(t *testing.T) {
+	w, err := NewWiki(t.TempDir(), "admin", "secretkey", false, WithAccessChecker(prefixDenyChecker{prefix: "private"}))
+	if err != nil {
+		t.Fatalf("Failed to create wiki: %v", err)
+	}
+	defer w.Close()
+
+	private, _ := w.CreatePage(nil, "Private", "private")
+	if _, err := w.CreatePage(&private.ID, "Secret", "secret"); err != nil {
+		t.Fatalf("CreatePage failed: %v", err)
+	}
+	if _, err := w.CreatePage(nil, "Docs", "docs"); err != nil {
+		t.Fatalf("CreatePage failed: %v", err)
+	}
+
+	ctx := context.Background()
+	fs := w.WebDAVFileSystem(&auth.User{ID: "editor", Role: auth.RoleEditor})
+	if _, err := fs.Stat(ctx, "/docs.md"); err != nil {
+		t.Errorf("Expected docs to be visible: %v", err)
+	}
+	for _, name := range []string{"/private", "/private/secret.md", "/private/index.md"} {
+		if _, err := fs.Stat(ctx, name); !os.IsNotExist(err) {
+			t.Errorf("Expected %s to be hidden, got %v", name, err)
+		}
+	}
+	if err := fs.RemoveAll(ctx, "/private"); !os.IsNotExist(err) {
+		t.Errorf("Expected removing a hidden folder to fail, got %v", err)
+	}
+
+	root, err := fs.OpenFile(ctx, "/", os.O_RDONLY, 0)
+	if err != nil {
+		t.Fatalf("OpenFile failed: %v", err)
+	}
+	defer root.Close()
+	infos, err := root.Readdir(-1)
+	if err != nil {
+		t.Fatalf("Readdir failed: %v", err)
+	}
+	for _, info := range infos {
+		if strings.HasPrefix(info.Name(), "private") {
+			t.Errorf("Expected %s to be hidden in the listing", info.Name())
+		}
+	}
+
+	// admins see everything
+	adminFS := w.WebDAVFileSystem(&auth.User{ID: "admin", Role: auth.RoleAdmin})
+	if _, err := adminFS.Stat(ctx, "/private/secret.md"); err != nil {
+		t.Errorf("Expected admin to see the private page: %v", err)
+	}
+}
+
+func TestWiki_GetStatusRollup(t *testing.T) {
+	w := setupTestWiki(t)
+	defer w.Close()
+
+	project, err := w.CreatePage(nil, "Project", "project")
+	if err != nil {
+		t.Fatalf("CreatePage failed: %v", err)
+	}
+
+	tasks := []struct{ slug, status string }{
+		{"task-a", "Done"},
+		{"task-b", "in-progress"},
+		{"task-c", "done"},
+		{"task-d", ""},
+	}
+	for _, task := range tasks {
+		page, err := w.CreatePage(&project.ID, task.slug, task.slug)
+		if err != nil {
+			t.Fatalf("CreatePage failed: %v", err)
+		}
+		content := "# " + task.slug
+		if task.status != "" {
+			content = "---\nstatus: " + task.status + "\n---\n" + content
+		}
+		if _, err := w.UpdatePage(page.ID, task.slug, task.slug, content); err != nil {
+			t.Fatalf("UpdatePage failed: %v", err)
+		}
+	}
+
+	rollup, err := w.GetStatusRollup(project.ID, "")
+	if err != nil {
+		t.Fatalf("GetStatusRollup failed: %v", err)
+	}
+	if rollup.Total != 4 || rollup.WithStatus != 3 {
+		t.Errorf("expected 4 pages with 3 statuses, got %d/%d", rollup.Total, rollup.WithStatus)
+	}
+	if rollup.Counts["done"] != 2 || rollup.Counts["in-progress"] != 1 {
+		t.Errorf("unexpected counts: %v", rollup.Counts)
+	}
+
+	if _, err := w.GetStatusRollup("missing", ""); err == nil {
+		t.Error("expected error for unknown page")
+	}
+}
+
+func TestWiki_GetPageMeta(t *testing.T) {
+	w := setupTestWiki(t)
+	defer w.Close()
+
+	docs, err := w.CreatePage(nil, "Docs", "docs")
+	if err != nil {
+		t.Fatalf("CreatePage failed: %v", err)
+	}
+	content := "---\nauthor: Alice\ncontributors: [Bob, Alice]\n---\n# Docs\n\n" + strings.Repeat("word ", 399) + "end"
+	if _, err := w.UpdatePage(docs.ID, "Docs", "docs", content); err != nil {
+		t.Fatalf("UpdatePage failed: %v", err)
+	}
+	for _, slug := range []string{"a", "b"} {
+		page, err := w.CreatePage(nil, slug, slug)
+		if err != nil {
+			t.Fatalf("CreatePage failed: %v", err)
+		}
+		if _, err := w.UpdatePage(page.ID, slug, slug, "See [docs](/docs#intro) and [again](/docs)"); err != nil {
+			t.Fatalf("UpdatePage failed: %v", err)
+		}
+	}
+	if err := w.searchIndex.CaptureFileHistory(t.Context(), filepath.Join(w.GetStorageDir(), "root")); err != nil {
+		t.Fatalf("CaptureFileHistory failed: %v", err)
+	}
+
+	meta, err := w.GetPageMeta(docs.ID)
+	if err != nil {
+		t.Fatalf("GetPageMeta failed: %v", err)
+	}
+	if meta.Path != "docs" || meta.WordCount != 402 || meta.ReadingTimeMinutes != 3 {
+		t.Errorf("unexpected counts: %+v", meta)
+	}
+	if meta.CharacterCount != len("# Docs\n\n")+399*5+len("end") {
+		t.Errorf("unexpected character count %d", meta.CharacterCount)
+	}
+	if strings.Join(meta.Contributors, ",") != "Alice,Bob" {
+		t.Errorf("unexpected contributors %v", meta.Contributors)
+	}
+	if meta.BacklinkCount != 2 {
+		t.Errorf("expected 2 backlinks, got %d", meta.BacklinkCount)
+	}
+	if meta.CreatedAt == nil || meta.ModifiedAt == nil {
+		t.Errorf("expected dates from history, got %+v", meta)
+	}
+
+	if _, err := w.GetPageMeta("missing"); err == nil {
+		t.Error("expected error for unknown page")
+	}
+}
+
+func TestWiki_SuggestPages(t *testing.T) {
+	w := setupTestWiki(t)
+	defer w.Close()
+
+	dataDir := filepath.Join(w.GetStorageDir(), "root")
+
+	setup, err := w.CreatePage(nil, "Setup", "setup")
+	if err != nil {
+		t.Fatalf("CreatePage failed: %v", err)
+	}
+	guides, err := w.CreatePage(nil, "Guides", "guides")
+	if err != nil {
+		t.Fatalf("CreatePage failed: %v", err)
+	}
+	if _, err := w.CreatePage(nil, "Installation Guide", "installation-guide"); err != nil {
+		t.Fatalf("CreatePage failed: %v", err)
+	}
+	if err := w.searchIndex.CaptureFileHistory(t.Context(), dataDir); err != nil {
+		t.Fatalf("CaptureFileHistory failed: %v", err)
+	}
+
+	if err := w.MovePage(setup.ID, guides.ID); err != nil {
+		t.Fatalf("MovePage failed: %v", err)
+	}
+	if err := w.searchIndex.CaptureFileHistory(t.Context(), dataDir); err != nil {
+		t.Fatalf("CaptureFileHistory failed: %v", err)
+	}
+
+	suggestions := w.SuggestPages("setup")
+	if len(suggestions) == 0 || suggestions[0].Path != "guides/setup" || suggestions[0].Reason != SuggestionReasonMoved {
+		t.Fatalf("expected moved page first, got %+v", suggestions)
+	}
+
+	suggestions = w.SuggestPages("/instalation-guide")
+	if len(suggestions) == 0 || suggestions[0].Path != "installation-guide" || suggestions[0].Reason != SuggestionReasonSimilar {
+		t.Fatalf("expected similar page, got %+v", suggestions)
+	}
+
+	if suggestions := w.SuggestPages("completely-unrelated-xyz"); len(suggestions) != 0 {
+		t.Errorf("expected no suggestions, got %+v", suggestions)
+	}
+	if suggestions := w.SuggestPages(strings.Repeat("installation-guide/", 20)); len(suggestions) != 0 {
+		t.Errorf("expected no fuzzy suggestions for a long path, got %+v", suggestions)
+	}
+}
+
+func TestWiki_SuggestPages_FiltersByAccess(t *testing.T) {
+	w, err := NewWiki(t.TempDir(), "admin", "secretkey", false, WithAccessChecker(prefixDenyChecker{prefix: "private"}))
+	if err != nil {
+		t.Fatalf("Failed to create wiki: %v", err)
+	}
+	defer w.Close()
+
+	if _, err := w.CreatePage(nil, "Private Notes", "private-notes"); err != nil {
+		t.Fatalf("CreatePage failed: %v", err)
+	}
+	if _, err := w.CreatePage(nil, "Public Notes", "public-notes"); err != nil {
+		t.Fatalf("CreatePage failed: %v", err)
+	}
+
+	editor := &auth.User{ID: "editor", Role: auth.RoleEditor}
+	for _, user := range []*auth.User{nil, editor} {
+		suggestions := w.SuggestPagesForUser(user, "privat-notes")
+		if len(suggestions) != 1 || suggestions[0].Path != "public-notes" {
+			t.Errorf("expected only the readable page for %v, got %+v", user, suggestions)
+		}
+	}
+	admin := &auth.User{ID: "admin", Role: auth.RoleAdmin}
+	if suggestions := w.SuggestPagesForUser(admin, "privat-notes"); len(suggestions) == 0 || suggestions[0].Path != "private-notes" {
+		t.Errorf("expected the private page for the admin, got %+v", suggestions)
+	}
+}
+
+func TestWiki_ApplyImport(t *testing.T) {
+	w, sourceDir := setupImportWiki(t)
+
+	if err := os.MkdirAll(filepath.Join(sourceDir, "notes"), 0o755); err != nil {
+		t.Fatalf("failed to create dir: %v", err)
+	}
+	files := map[string]string{
+		"home.md":        "# Imported Home\nSee [child](child.md)",
+		"child.md":       "# Child Note",
+		"notes/index.md": "# Notes",
+		"notes/todo.md":  "# Todo",
+	}
+	for name, content := range files {
+		if err := os.WriteFile(filepath.Join(sourceDir, filepath.FromSlash(name)), []byte(content), 0o644); err != nil {
+			t.Fatalf("failed to write file: %v", err)
+		}
+	}
+
+	// An existing page with the same slug must not be overwritten
+	if _, err := w.CreatePage(nil, "Existing", "home"); err != nil {
+		t.Fatalf("CreatePage failed: %v", err)
+	}
+
+	plan, err := w.AnalyzeImport(sourceDir)
+	if err != nil {
+		t.Fatalf("AnalyzeImport failed: %v", err)
+	}
+
+	report, err := w.ApplyImport(plan, nil)
+	if err != nil {
+		t.Fatalf("ApplyImport failed: %v", err)
+	}
+	if report.Created != 4 {
+		t.Errorf("expected 4 created pages, got %d", report.Created)
+	}
+	if len(report.Collisions) != 1 || report.Collisions[0].Resolution != importer.ResolutionRenamed ||
+		report.Collisions[0].Slug != "home" || report.Collisions[0].ResolvedSlug != "home-1" {
+		t.Errorf("expected home to be renamed to home-1, got %+v", report.Collisions)
+	}
+
+	home, err := w.FindByPath("home-1")
+	if err != nil {
+		t.Fatalf("expected imported home page at home-1: %v", err)
+	}
+	if home.Title != "Imported Home" || !strings.Contains(home.Content, "See [child]") {
+		t.Errorf("unexpected imported page: %+v", home)
+	}
+
+	child, err := w.FindByPath("home-1/child")
+	if err != nil || child.Content != "# Child Note" {
+		t.Errorf("expected linked page below home, got %+v, %v", child, err)
+	}
+
+	if _, err := w.FindByPath("notes/todo"); err != nil {
+		t.Errorf("expected folder page with child: %v", err)
+	}
+}
+
+func TestWiki_ApplyImport_InvalidPlan(t *testing.T) {
+	w, root := setupImportWiki(t)
+
+	plan := &importer.Plan{
+		SourceDir: root,
+		Pages:     []*importer.ProposedPage{{SourcePath: "../secret.md", Title: "Secret", Slug: "secret"}},
+	}
+	if _, err := w.ApplyImport(plan, nil); !errors.Is(err, importer.ErrInvalidSourcePath) {
+		t.Errorf("expected ErrInvalidSourcePath, got %v", err)
+	}
+
+	plan.Pages[0] = &importer.ProposedPage{Title: "", Slug: "Not Valid"}
+	_, err := w.ApplyImport(plan, nil)
+	if _, ok := err.(*verrors.ValidationErrors); !ok {
+		t.Errorf("expected validation error, got %v", err)
+	}
+}
+
+func TestWiki_ImportSourceRestricted(t *testing.T) {
+	outside := t.TempDir()
+	if err := os.WriteFile(filepath.Join(outside, "secret.md"), []byte("# Secret"), 0o644); err != nil {
+		t.Fatalf("failed to write file: %v", err)
+	}
+
+	// without an import root only archives can be imported
+	w := setupTestWiki(t)
+	for _, dir := range []string{outside, "docs"} {
+		if _, err := w.AnalyzeImport(dir); !errors.Is(err, importer.ErrInvalidSourcePath) {
+			t.Errorf("expected ErrInvalidSourcePath for %q, got %v", dir, err)
+		}
+	}
+
+	w, root := setupImportWiki(t)
+	if err := os.MkdirAll(filepath.Join(root, "docs"), 0o755); err != nil {
+		t.Fatalf("failed to create dir: %v", err)
+	}
+	for _, dir := range []string{outside, "../" + filepath.Base(outside), filepath.Join(root, "..")} {
+		if _, err := w.AnalyzeImport(dir); !errors.Is(err, importer.ErrInvalidSourcePath) {
+			t.Errorf("expected ErrInvalidSourcePath for %q, got %v", dir, err)
+		}
+	}
+	if _, err := w.AnalyzeImport("docs"); err != nil {
+		t.Errorf("expected a folder of the import root to be analyzed, got %v", err)
+	}
+	if _, err := w.AnalyzeImport("missing"); !errors.Is(err, importer.ErrSourceNotFound) {
+		t.Errorf("expected ErrSourceNotFound, got %v", err)
+	}
+	if err := os.Symlink(outside, filepath.Join(root, "link")); err == nil {
+		if _, err := w.AnalyzeImport("link"); !errors.Is(err, importer.ErrInvalidSourcePath) {
+			t.Errorf("expected ErrInvalidSourcePath for a link leading outside, got %v", err)
+		}
+	}
+
+	plan := &importer.Plan{SourceDir: outside, Pages: []*importer.ProposedPage{{SourcePath: "secret.md", Title: "Secret", Slug: "secret"}}}
+	if _, err := w.ApplyImport(plan, nil); !errors.Is(err, importer.ErrInvalidSourcePath) {
+		t.Errorf("expected ErrInvalidSourcePath for a plan outside the import root, got %v", err)
+	}
+	if _, err := w.FindByPath("secret"); err == nil {
+		t.Errorf("expected nothing to be imported")
+	}
+}
+
+func TestWiki_ImportArchive(t *testing.T) {
+	w := setupTestWiki(t)
+
+	var buf bytes.Buffer
+	zw := zip.NewWriter(&buf)
+	for name, content := range map[string]string{"docs/index.md": "# Docs", "docs/setup.md": "# Setup"} {
+		f, _ := zw.Create(name)
+		_, _ = f.Write([]byte(content))
+	}
+	if err := zw.Close(); err != nil {
+		t.Fatalf("failed to create archive: %v", err)
+	}
+
+	plan, err := w.ImportArchive(bytes.NewReader(buf.Bytes()), int64(buf.Len()))
+	if err != nil {
+		t.Fatalf("ImportArchive failed: %v", err)
+	}
+	if len(plan.Pages) != 1 || plan.Pages[0].Slug != "docs" || len(plan.Pages[0].Children) != 1 {
+		t.Fatalf("unexpected plan: %+v", plan.Pages)
+	}
+
+	// the uploads as a whole are not a source
+	if _, err := w.AnalyzeImport(filepath.Dir(plan.SourceDir)); !errors.Is(err, importer.ErrInvalidSourcePath) {
+		t.Errorf("expected ErrInvalidSourcePath for the uploads, got %v", err)
+	}
+
+	if _, err := w.ApplyImport(plan, nil); err != nil {
+		t.Fatalf("ApplyImport failed: %v", err)
+	}
+	if _, err := w.FindByPath("docs/setup"); err != nil {
+		t.Errorf("expected the imported page: %v", err)
+	}
+	if _, err := os.Stat(plan.SourceDir); !os.IsNotExist(err) {
+		t.Errorf("expected the extracted archive to be removed, got %v", err)
+	}
+
+	if _, err := w.ImportArchive(bytes.NewReader([]byte("no zip")), 6); !errors.Is(err, importer.ErrInvalidArchive) {
+		t.Errorf("expected ErrInvalidArchive, got %v", err)
+	}
+}
+
+func TestWiki_ApplyImport_CollisionStrategies(t *testing.T) {
+	newPlan := func(t *testing.T, sourceDir string) *importer.Plan {
+		if err := os.MkdirAll(filepath.Join(sourceDir, "docs"), 0o755); err != nil {
+			t.Fatalf("failed to create dir: %v", err)
+		}
+		for name, content := range map[string]string{"docs/index.md": "# Docs\nImported", "docs/setup.md": "# Setup"} {
+			if err := os.WriteFile(filepath.Join(sourceDir, filepath.FromSlash(name)), []byte(content), 0o644); err != nil {
+				t.Fatalf("failed to write file: %v", err)
+			}
+		}
+		return &importer.Plan{
+			SourceDir: sourceDir,
+			Pages: []*importer.ProposedPage{{
+				SourcePath: "docs/index.md", Title: "Docs", Slug: "docs",
+				Children: []*importer.ProposedPage{{SourcePath: "docs/setup.md", Title: "Setup", Slug: "setup"}},
+			}},
+		}
+	}
+
+	t.Run("reject", func(t *testing.T) {
+		w, root := setupImportWiki(t)
+		if _, err := w.CreatePage(nil, "Docs", "docs"); err != nil {
+			t.Fatalf("CreatePage failed: %v", err)
+		}
+		plan := newPlan(t, root)
+		plan.OnCollision = importer.CollisionReject
+
+		_, err := w.ApplyImport(plan, nil)
+		ve, ok := err.(*verrors.ValidationErrors)
+		if !ok || len(ve.Errors) != 1 || !strings.Contains(ve.Errors[0].Message, "'docs'") {
+			t.Fatalf("expected the collision as validation error, got %v", err)
+		}
+		if _, err := w.FindByPath("docs/setup"); err == nil {
+			t.Errorf("expected nothing to be imported")
+		}
+	})
+
+	t.Run("merge", func(t *testing.T) {
+		w, root := setupImportWiki(t)
+		docs, err := w.CreatePage(nil, "Docs", "docs")
+		if err != nil {
+			t.Fatalf("CreatePage failed: %v", err)
+		}
+		plan := newPlan(t, root)
+		plan.OnCollision = importer.CollisionMerge
+
+		report, err := w.ApplyImport(plan, nil)
+		if err != nil {
+			t.Fatalf("ApplyImport failed: %v", err)
+		}
+		if report.Created != 1 || report.Merged != 1 || len(report.Collisions) != 1 ||
+			report.Collisions[0].Resolution != importer.ResolutionMerged || report.Collisions[0].PageID != docs.ID {
+			t.Errorf("unexpected report: %+v", report)
+		}
+		merged, err := w.GetPage(docs.ID)
+		if err != nil || !strings.Contains(merged.Content, "Imported") {
+			t.Errorf("expected the empty page to get the imported content, got %+v, %v", merged, err)
+		}
+		if _, err := w.FindByPath("docs/setup"); err != nil {
+			t.Errorf("expected the child below the existing page: %v", err)
+		}
+	})
+
+	t.Run("merge keeps existing content", func(t *testing.T) {
+		w, root := setupImportWiki(t)
+		docs, err := w.CreatePage(nil, "Docs", "docs")
+		if err != nil {
+			t.Fatalf("CreatePage failed: %v", err)
+		}
+		if _, err := w.UpdatePage(docs.ID, "Docs", "docs", "# Docs\nWritten by hand"); err != nil {
+			t.Fatalf("UpdatePage failed: %v", err)
+		}
+		plan := newPlan(t, root)
+		plan.OnCollision = importer.CollisionMerge
+
+		report, err := w.ApplyImport(plan, nil)
+		if err != nil {
+			t.Fatalf("ApplyImport failed: %v", err)
+		}
+		if report.Created != 1 || report.Merged != 0 || report.Skipped != 1 || len(report.Collisions) != 1 {
+			t.Fatalf("unexpected report: %+v", report)
+		}
+		if c := report.Collisions[0]; c.Resolution != importer.ResolutionSkipped || c.PageID != docs.ID || c.Reason == "" {
+			t.Errorf("expected the page to be reported as skipped with a reason, got %+v", c)
+		}
+		kept, err := w.GetPage(docs.ID)
+		if err != nil || !strings.Contains(kept.Content, "Written by hand") || strings.Contains(kept.Content, "Imported") {
+			t.Errorf("expected the existing content to be kept, got %+v, %v", kept, err)
+		}
+		if _, err := w.FindByPath("docs/setup"); err != nil {
+			t.Errorf("expected the child below the existing page: %v", err)
+		}
+	})
+
+	t.Run("invalid", func(t *testing.T) {
+		w, root := setupImportWiki(t)
+		plan := newPlan(t, root)
+		plan.OnCollision = "overwrite"
+		if _, err := w.ApplyImport(plan, nil); err == nil {
+			t.Errorf("expected an error for an unknown strategy")
+		}
+	})
+}
+
+func TestWiki_ExportRoundTrip(t *testing.T) {
+	w := setupTestWiki(t)
+
+	docs, err := w.CreatePage(nil, "Docs", "docs")
+	if err != nil {
+		t.Fatalf("CreatePage failed: %v", err)
+	}
+	guide, err := w.CreatePage(&docs.ID, "User Guide", "guide")
+	if err != nil {
+		t.Fatalf("CreatePage failed: %v", err)
+	}
+	if _, err := w.UpdatePage(guide.ID, guide.Title, guide.Slug, "Read me first"); err != nil {
+		t.Fatalf("UpdatePage failed: %v", err)
+	}
+
+	other, importRoot := setupImportWiki(t)
+	destDir := filepath.Join(importRoot, "export")
+	count, err := w.Export(destDir)
+	if err != nil {
+		t.Fatalf("Export failed: %v", err)
+	}
+	if count != 3 {
+		t.Errorf("expected 3 exported pages, got %d", count)
+	}
+	data, err := os.ReadFile(filepath.Join(destDir, "docs", "guide.md"))
+	if err != nil {
+		t.Fatalf("expected exported guide: %v", err)
+	}
+	if string(data) != "---\ntitle: \"User Guide\"\n---\nRead me first" {
+		t.Errorf("unexpected exported content: %q", data)
+	}
+
+	if _, err := w.Export(destDir); err == nil {
+		t.Errorf("expected error when exporting into a non-empty directory")
+	}
+
+	plan, err := other.AnalyzeImport("export")
+	if err != nil {
+		t.Fatalf("AnalyzeImport failed: %v", err)
+	}
+	if _, err := other.ApplyImport(plan, nil); err != nil {
+		t.Fatalf("ApplyImport failed: %v", err)
+	}
+	imported, err := other.FindByPath("docs/guide")
+	if err != nil {
+		t.Fatalf("expected imported guide: %v", err)
+	}
+	if imported.Title != "User Guide" {
+		t.Errorf("expected title to survive the round trip, got %q", imported.Title)
+	}
+}
+
+func TestWiki_UndoPage(t *testing.T) {
+	w := setupTestWiki(t)
+	defer w.Close()
+
+	page, err := w.CreatePage(nil, "Notes", "notes")
+	if err != nil {
+		t.Fatalf("CreatePage failed: %v", err)
+	}
+	if _, err := w.UpdatePage(page.ID, "Notes", "notes", "first version"); err != nil {
+		t.Fatalf("UpdatePage failed: %v", err)
+	}
+
+	// Only the current version is known
+	if _, err := w.UndoPage(page.ID); !errors.Is(err, ErrNothingToUndo) {
+		t.Fatalf("expected ErrNothingToUndo, got %v", err)
+	}
+
+	if _, err := w.UpdatePage(page.ID, "Notes", "notes", "accidental save"); err != nil {
+		t.Fatalf("UpdatePage failed: %v", err)
+	}
+
+	undone, err := w.UndoPage(page.ID)
+	if err != nil {
+		t.Fatalf("UndoPage failed: %v", err)
+	}
+	if undone.Content != "first version" {
+		t.Errorf("expected previous content to be restored, got %q", undone.Content)
+	}
+
+	// The reverted change is kept in the history
+	entries, total, _, err := w.GetPageHistory("notes", search.HistoryOptions{IncludeContent: true})
+	if err != nil {
+		t.Fatalf("GetPageHistory failed: %v", err)
+	}
+	if total != 3 || entries[1].Content != "accidental save" {
+		t.Errorf("expected the accidental save in the history, got %+v", entries)
+	}
+
+	if _, err := w.UndoPage("missing"); !errors.Is(err, tree.ErrPageNotFound) {
+		t.Errorf("expected ErrPageNotFound, got %v", err)
+	}
+}
+
+func TestWiki_HistoryLabels(t *testing.T) {
+	w := setupTestWiki(t)
+	defer w.Close()
+
+	page, err := w.CreatePage(nil, "Release Notes", "release-notes")
+	if err != nil {
+		t.Fatalf("CreatePage failed: %v", err)
+	}
+	if _, err := w.UpdatePage(page.ID, page.Title, page.Slug, "v1 notes"); err != nil {
+		t.Fatalf("UpdatePage failed: %v", err)
+	}
+	dataDir := filepath.Join(w.GetStorageDir(), "root")
+	if err := w.searchIndex.CaptureFileChanges(t.Context(), dataDir, []string{"release-notes.md"}); err != nil {
+		t.Fatalf("CaptureFileChanges failed: %v", err)
+	}
+
+	entries, _, _, err := w.GetPageHistory("release-notes", search.HistoryOptions{})
+	if err != nil || len(entries) != 1 {
+		t.Fatalf("expected one history entry, got %+v, %v", entries, err)
+	}
+
+	if _, err := w.LabelHistoryEntry(entries[0].ID, "  "); err == nil {
+		t.Errorf("expected validation error for empty label")
+	}
+	if _, err := w.LabelHistoryEntry(entries[0].ID, " v1.0 "); err != nil {
+		t.Fatalf("LabelHistoryEntry failed: %v", err)
+	}
+
+	if _, err := w.UpdatePage(page.ID, page.Title, page.Slug, "v2 notes"); err != nil {
+		t.Fatalf("UpdatePage failed: %v", err)
+	}
+
+	labels, err := w.GetPageLabels("release-notes")
+	if err != nil || len(labels) != 1 || labels[0].Label != "v1.0" {
+		t.Fatalf("unexpected labels: %+v, %v", labels, err)
+	}
+
+	snapshot, err := w.GetPageAtLabel("release-notes", "v1.0")
+	if err != nil {
+		t.Fatalf("GetPageAtLabel failed: %v", err)
+	}
+	if snapshot.Content != "v1 notes" {
+		t.Errorf("expected v1 content, got %q", snapshot.Content)
+	}
+}
+
+func TestWiki_TreeFollowsExternalChanges(t *testing.T) {
+	w, err := NewWiki(t.TempDir(), "admin", "secretkey", true)
+	if err != nil {
+		t.Fatalf("Failed to create wiki: %v", err)
+	}
+	defer w.Close()
+
+	page, err := w.CreatePage(nil, "External", "external")
+	if err != nil {
+		t.Fatalf("CreatePage failed: %v", err)
+	}
+
+	hasSlug := func(slug string) bool {
+		_, err := w.FindByPath(slug)
+		return err == nil
+	}
+	waitFor := func(desc string, cond func() bool) {
+		t.Helper()
+		deadline := time.Now().Add(5 * time.Second)
+		for !cond() {
+			if time.Now().After(deadline) {
+				t.Fatalf("timed out waiting for %s", desc)
+			}
+			time.Sleep(20 * time.Millisecond)
+		}
+	}
+
+	// give the watcher time to start
+	time.Sleep(200 * time.Millisecond)
+
+	dataDir := filepath.Join(w.GetStorageDir(), "root")
+	if err := os.Remove(filepath.Join(dataDir, "external.md")); err != nil {
+		t.Fatalf("failed to remove file: %v", err)
+	}
+	waitFor("removed page to be detached", func() bool { return !hasSlug("external") })
+
+	if _, err := w.GetPage(page.ID); err == nil {
+		t.Errorf("expected removed page to be gone")
+	}
+
+	if err := os.WriteFile(filepath.Join(dataDir, "added.md"), []byte("# Added"), 0o644); err != nil {
+		t.Fatalf("failed to write file: %v", err)
+	}
+	waitFor("added page to be attached", func() bool { return hasSlug("added") })
+}
+
+func TestWiki_WatchPolling(t *testing.T) {
+	w, err := NewWiki(t.TempDir(), "admin", "secretkey", true, WithWatchPolling(50*time.Millisecond))
+	if err != nil {
+		t.Fatalf("Failed to create wiki: %v", err)
+	}
+	defer w.Close()
+
+	if _, err := w.CreatePage(nil, "Polled", "polled"); err != nil {
+		t.Fatalf("CreatePage failed: %v", err)
+	}
+	waitFor := func(desc string, cond func() bool) {
+		t.Helper()
+		deadline := time.Now().Add(5 * time.Second)
+		for !cond() {
+			if time.Now().After(deadline) {
+				t.Fatalf("timed out waiting for %s", desc)
+			}
+			time.Sleep(20 * time.Millisecond)
+		}
+	}
+	found := func(query string) bool {
+		res, err := w.Search(t.Context(), query, 0, 10)
+		return err == nil && res.Count == 1
+	}
+
+	// give the poller time to take its first snapshot
+	time.Sleep(200 * time.Millisecond)
+
+	dataDir := filepath.Join(w.GetStorageDir(), "root")
+	if err := os.WriteFile(filepath.Join(dataDir, "polled.md"), []byte("# Polled\n\nnarwhal"), 0o644); err != nil {
+		t.Fatalf("failed to write file: %v", err)
+	}
+	waitFor("modified page to be indexed", func() bool { return found("narwhal") })
+
+	if err := os.WriteFile(filepath.Join(dataDir, "added.md"), []byte("# Added\n\npangolin"), 0o644); err != nil {
+		t.Fatalf("failed to write file: %v", err)
+	}
+	waitFor("added page to be attached and indexed", func() bool {
+		_, err := w.FindByPath("added")
+		return err == nil && found("pangolin")
+	})
+
+	if err := os.Remove(filepath.Join(dataDir, "added.md")); err != nil {
+		t.Fatalf("failed to remove file: %v", err)
+	}
+	waitFor("removed page to be detached", func() bool {
+		_, err := w.FindByPath("added")
+		return err != nil && !found("pangolin")
+	})
+}
+
+func TestWiki_ShutdownRecordsPendingHistory(t *testing.T) {
+	storageDir := t.TempDir()
+	w, err := NewWiki(storageDir, "admin", "secretkey", true)
+	if err != nil {
+		t.Fatalf("Failed to create wiki: %v", err)
+	}
+
+	page, err := w.CreatePage(nil, "Notes", "notes")
+	if err != nil {
+		t.Fatalf("CreatePage failed: %v", err)
+	}
+
+	// give the watcher time to start
+	time.Sleep(200 * time.Millisecond)
+
+	// the change is still debounced when the shutdown starts
+	if err := os.WriteFile(filepath.Join(storageDir, "root", "notes.md"), []byte("# last words"), 0o644); err != nil {
+		t.Fatalf("failed to write file: %v", err)
+	}
+	time.Sleep(50 * time.Millisecond)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if err := w.Shutdown(ctx); err != nil {
+		t.Fatalf("Shutdown failed: %v", err)
+	}
+	if err := w.Close(); err != nil {
+		t.Errorf("expected Close after Shutdown to be a no-op, got %v", err)
+	}
+	if err := w.ReindexAll(); !errors.Is(err, ErrShuttingDown) {
+		t.Errorf("expected ErrShuttingDown, got %v", err)
+	}
+
+	index, err := search.NewSQLiteIndex(storageDir)
+	if err != nil {
+		t.Fatalf("failed to open search index: %v", err)
+	}
+	defer index.Close()
+
+	entries, _, err := index.QueryHistoryForPath(page.Slug, search.HistoryOptions{IncludeContent: true})
+	if err != nil {
+		t.Fatalf("query failed: %v", err)
+	}
+	if len(entries) == 0 || entries[0].Content != "# last words" {
+		t.Errorf("expected the pending change to be recorded on shutdown, got %+v", entries)
+	}
+}
+
+func TestWiki_SyncGit_SharesPagesBetweenWikis(t *testing.T) {
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git is not installed")
+	}
+	remote := filepath.Join(t.TempDir(), "content.git")
+	if out, err := exec.Command("git", "init", "--quiet", "--bare", remote).CombinedOutput(); err != nil {
+		t.Fatalf("git init failed: %v %s", err, out)
+	}
+	// a long interval, so only the sync at startup runs in the background
+	config := gitsync.Config{Remote: remote, Interval: time.Hour, Strategy: gitsync.StrategyTheirs}
+
+	a, err := NewWiki(t.TempDir(), "admin", "secretkey", false, WithGitSync(config))
+	if err != nil {
+		t.Fatalf("Failed to create wiki: %v", err)
+	}
+	defer a.Close()
+	page, err := a.CreatePage(nil, "Shared", "shared")
+	if err != nil {
+		t.Fatalf("CreatePage failed: %v", err)
+	}
+	if _, err := a.UpdatePage(page.ID, "Shared", "shared", "# Shared\nfrom A"); err != nil {
+		t.Fatalf("UpdatePage failed: %v", err)
+	}
+	if _, err := a.SyncGit(context.Background()); err != nil {
+		t.Fatalf("SyncGit failed: %v", err)
+	}
+
+	b, err := NewWiki(t.TempDir(), "admin", "secretkey", false, WithGitSync(config))
+	if err != nil {
+		t.Fatalf("Failed to create wiki: %v", err)
+	}
+	defer b.Close()
+	// a sync requested by a client which disconnected still completes
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	status, err := b.SyncGit(ctx)
+	if err != nil {
+		t.Fatalf("SyncGit failed: %v (%+v)", err, status)
+	}
+	shared, err := b.GetPage(page.ID)
+	if err != nil {
+		t.Fatalf("Expected the page of A in B: %v", err)
+	}
+	if shared.Content != "# Shared\nfrom A" {
+		t.Errorf("Unexpected content %q", shared.Content)
+	}
+	if status, _ := b.GitSyncStatus(); status.LastSync.IsZero() || status.Error != "" {
+		t.Errorf("Unexpected status %+v", status)
+	}
+}
+
+func TestWiki_SyncGit_Disabled(t *testing.T) {
+	w := setupTestWiki(t)
+	defer w.Close()
+	if _, err := w.SyncGit(context.Background()); !errors.Is(err, ErrGitSyncDisabled) {
+		t.Errorf("Expected ErrGitSyncDisabled, got %v", err)
+	}
+	if _, err := NewWiki(t.TempDir(), "admin", "secretkey", false, WithGitSync(gitsync.Config{})); !errors.Is(err, gitsync.ErrRemoteRequired) {
+		t.Errorf("Expected ErrRemoteRequired, got %v", err)
+	}
+}
+
+func TestWiki_CheckLinks(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/ok" {
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer server.Close()
+
+	w, err := NewWiki(t.TempDir(), "admin", "secretkey", false, WithLinkCheck(linkcheck.Config{Delay: time.Millisecond}))
+	if err != nil {
+		t.Fatalf("Failed to create wiki: %v", err)
+	}
+	defer w.Close()
+
+	page, err := w.CreatePage(nil, "Links", "links")
+	if err != nil {
+		t.Fatalf("CreatePage failed: %v", err)
+	}
+	content := "[ok](" + server.URL + "/ok) [gone](" + server.URL + "/gone)"
+	if _, err := w.UpdatePage(page.ID, "Links", "links", content); err != nil {
+		t.Fatalf("UpdatePage failed: %v", err)
+	}
+
+	report, err := w.CheckLinks()
+	if err != nil {
+		t.Fatalf("CheckLinks failed: %v", err)
+	}
+	if report.CheckedAt != nil {
+		t.Errorf("expected no previous check, got %+v", report)
+	}
+
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		if report, err = w.GetLinkReport(); err != nil {
+			t.Fatalf("GetLinkReport failed: %v", err)
+		}
+		if !report.Running {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatal("link check did not finish")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	if report.Checked < 2 || report.Dead != 1 || report.CheckedAt == nil {
+		t.Fatalf("unexpected report %+v", report)
+	}
+	if len(report.Pages) != 1 || report.Pages[0].Path != "links" || report.Pages[0].Links[0].URL != server.URL+"/gone" {
+		t.Errorf("unexpected dead links %+v", report.Pages)
+	}
+}
+
+func TestWiki_ReplaceInPages(t *testing.T) {
+	w := setupTestWiki(t)
+	defer w.Close()
+
+	docs, _ := w.CreatePage(nil, "Docs", "docs")
+	guide, _ := w.CreatePage(&docs.ID, "Guide", "guide")
+	other, _ := w.CreatePage(nil, "Other", "other")
+	for _, p := range []*tree.Page{docs, guide, other} {
+		if _, err := w.UpdatePage(p.ID, p.Title, p.Slug, "Install v1.2 with leafwiki v1.2\nUse Leafwiki"); err != nil {
+			t.Fatalf("UpdatePage failed: %v", err)
+		}
+	}
+
+	preview, err := w.ReplaceInPages(ReplaceOptions{Find: "v1.2", Replace: "v2.0", PageID: docs.ID, DryRun: true})
+	if err != nil {
+		t.Fatalf("ReplaceInPages failed: %v", err)
+	}
+	if preview.Matches != 4 || len(preview.Pages) != 2 || preview.Pages[1].Path != "docs/guide" {
+		t.Fatalf("unexpected preview %+v", preview)
+	}
+	if lines := preview.Pages[0].Lines; len(lines) != 1 || lines[0].Line != 1 || lines[0].After != "Install v2.0 with leafwiki v2.0" {
+		t.Errorf("unexpected preview lines %+v", lines)
+	}
+	if page, _ := w.GetPage(guide.ID); strings.Contains(page.Content, "v2.0") {
+		t.Error("expected the dry run not to change the page")
+	}
+
+	result, err := w.ReplaceInPages(ReplaceOptions{Find: `leafwiki v(\d)`, Replace: "LeafWiki $1", Regex: true, IgnoreCase: true})
+	if err != nil {
+		t.Fatalf("ReplaceInPages failed: %v", err)
+	}
+	if result.Matches != 3 || len(result.Pages) != 3 {
+		t.Fatalf("unexpected result %+v", result)
+	}
+	page, _ := w.GetPage(other.ID)
+	if page.Content != "Install v1.2 with LeafWiki 1.2\nUse Leafwiki" {
+		t.Errorf("unexpected content %q", page.Content)
+	}
+	entries, _, err := w.searchIndex.QueryHistoryForPath(page.CalculatePath(), search.HistoryOptions{IncludeContent: true})
+	if err != nil || len(entries) < 2 || entries[0].Content != page.Content {
+		t.Errorf("expected the replacement to be recorded in history, got %+v (%v)", entries, err)
+	}
+
+	var vErr *verrors.ValidationErrors
+	if _, err := w.ReplaceInPages(ReplaceOptions{Find: "(", Regex: true}); !errors.As(err, &vErr) {
+		t.Errorf("expected validation error for invalid regex, got %v", err)
+	}
+	if _, err := w.ReplaceInPages(ReplaceOptions{Find: "x", PageID: "missing"}); !errors.Is(err, tree.ErrPageNotFound) {
+		t.Errorf("expected ErrPageNotFound, got %v", err)
+	}
+}
+
+func TestWiki_MovePage_UpdatesLinks(t *testing.T) {
+	w := setupTestWiki(t)
+	defer w.Close()
+
+	docs, _ := w.CreatePage(nil, "Docs", "docs")
+	guide, _ := w.CreatePage(&docs.ID, "Guide", "guide")
+	archive, _ := w.CreatePage(nil, "Archive", "archive")
+	home, _ := w.CreatePage(nil, "Home", "home")
+	content := "See [guide](/docs/guide#setup), [docs](/docs) and [other](/docs-old).\n\n[ref]: /docs/guide\n"
+	if _, err := w.UpdatePage(home.ID, "Home", "home", content); err != nil {
+		t.Fatalf("UpdatePage failed: %v", err)
+	}
+
+	if err := w.MovePage(docs.ID, archive.ID); err != nil {
+		t.Fatalf("MovePage failed: %v", err)
+	}
+	page, _ := w.GetPage(home.ID)
+	want := "See [guide](/archive/docs/guide#setup), [docs](/archive/docs) and [other](/docs-old).\n\n[ref]: /archive/docs/guide\n"
+	if page.Content != want {
+		t.Errorf("unexpected content after move:\n%s", page.Content)
+	}
+
+	if _, err := w.UpdatePage(guide.ID, "Guide", "manual", ""); err != nil {
+		t.Fatalf("UpdatePage failed: %v", err)
+	}
+	page, _ = w.GetPage(home.ID)
+	if !strings.Contains(page.Content, "[guide](/archive/docs/manual#setup)") || !strings.Contains(page.Content, "[ref]: /archive/docs/manual") {
+		t.Errorf("unexpected content after rename:\n%s", page.Content)
+	}
+	if meta, _ := w.GetPageMeta(guide.ID); meta.BacklinkCount != 1 {
+		t.Errorf("expected the renamed page to keep its backlink, got %d", meta.BacklinkCount)
+	}
+}
+
+func TestWiki_CheckMove(t *testing.T) {
+	w := setupTestWiki(t)
+	defer w.Close()
+
+	docs, _ := w.CreatePage(nil, "Docs", "docs")
+	guide, _ := w.CreatePage(&docs.ID, "Guide", "guide")
+	archive, _ := w.CreatePage(nil, "Archive", "archive")
+	home, _ := w.CreatePage(nil, "Home", "home")
+	if _, err := w.UpdatePage(home.ID, "Home", "home", "See [guide](/docs/guide)"); err != nil {
+		t.Fatalf("UpdatePage failed: %v", err)
+	}
+
+	check, err := w.CheckMove(docs.ID, archive.ID)
+	if err != nil {
+		t.Fatalf("CheckMove failed: %v", err)
+	}
+	if check.Conflict != "" || check.NewRoute != "archive/docs" {
+		t.Errorf("unexpected check: %+v", check)
+	}
+	if len(check.Redirects) != 2 || check.Redirects[1].PageID != guide.ID || check.Redirects[1].To != "archive/docs/guide" {
+		t.Errorf("unexpected redirects: %+v", check.Redirects)
+	}
+	if len(check.LinkUpdates) != 1 || check.LinkUpdates[0].PageID != home.ID {
+		t.Errorf("unexpected link updates: %+v", check.LinkUpdates)
+	}
+	if _, err := w.FindByPath("docs/guide"); err != nil {
+		t.Errorf("expected the check not to move the page: %v", err)
+	}
+
+	taken, _ := w.CreatePage(&archive.ID, "Docs", "docs")
+	check, err = w.CheckMove(docs.ID, archive.ID)
+	if err != nil || check.Conflict != MoveConflictSlugTaken || check.ConflictingPageID != taken.ID {
+		t.Errorf("expected a slug conflict, got %+v, %v", check, err)
+	}
+	if check, _ := w.CheckMove(docs.ID, guide.ID); check.Conflict != MoveConflictCircular {
+		t.Errorf("expected a circular move, got %+v", check)
+	}
+	if check, _ := w.CheckMove(docs.ID, "root"); check.Conflict != MoveConflictSameParent {
+		t.Errorf("expected the same parent, got %+v", check)
+	}
+	if _, err := w.CheckMove(docs.ID, "missing"); !errors.Is(err, tree.ErrParentNotFound) {
+		t.Errorf("expected ErrParentNotFound, got %v", err)
+	}
+}
+
+func TestWiki_Redirects(t *testing.T) {
+	w := setupTestWiki(t)
+	defer w.Close()
+
+	docs, _ := w.CreatePage(nil, "Docs", "docs")
+	guide, _ := w.CreatePage(&docs.ID, "Guide", "guide")
+	archive, _ := w.CreatePage(nil, "Archive", "archive")
+
+	if err := w.MovePage(docs.ID, archive.ID); err != nil {
+		t.Fatalf("MovePage failed: %v", err)
+	}
+	redirect, err := w.ResolveRedirect("/docs/guide")
+	if err != nil || redirect.To != "archive/docs/guide" || redirect.PageID != guide.ID {
+		t.Fatalf("expected redirect of the child page, got %+v (%v)", redirect, err)
+	}
+
+	// renaming again keeps the old redirects pointing to the current route
+	if _, err := w.UpdatePage(guide.ID, "Guide", "manual", ""); err != nil {
+		t.Fatalf("UpdatePage failed: %v", err)
+	}
+	if redirect, err := w.ResolveRedirect("docs/guide"); err != nil || redirect.To != "archive/docs/manual" {
+		t.Errorf("expected redirect to follow the rename, got %+v (%v)", redirect, err)
+	}
+	list, err := w.ListRedirects()
+	if err != nil || len(list) != 3 {
+		t.Fatalf("expected 3 redirects, got %+v (%v)", list, err)
+	}
+
+	// a new page at the old route wins over the redirect
+	if _, err := w.CreatePage(nil, "Docs", "docs"); err != nil {
+		t.Fatalf("CreatePage failed: %v", err)
+	}
+	if _, err := w.ResolveRedirect("docs"); !errors.Is(err, redirects.ErrRedirectNotFound) {
+		t.Errorf("expected no redirect for an existing page, got %v", err)
+	}
+
+	if err := w.DeleteRedirect("archive/docs/guide"); err != nil {
+		t.Errorf("DeleteRedirect failed: %v", err)
+	}
+	if err := w.DeletePage(archive.ID, true); err != nil {
+		t.Fatalf("DeletePage failed: %v", err)
+	}
+	if list, _ := w.ListRedirects(); len(list) != 0 {
+		t.Errorf("expected redirects of deleted pages to be removed, got %+v", list)
+	}
+}
+
+func TestWiki_RenderPage_ExpandsIncludes(t *testing.T) {
+	w := setupTestWiki(t)
+	defer w.Close()
+
+	shared, _ := w.CreatePage(nil, "Shared", "shared")
+	warning, _ := w.CreatePage(&shared.ID, "Warning", "warning")
+	guide, _ := w.CreatePage(nil, "Guide", "guide")
+	if _, err := w.UpdatePage(warning.ID, "Warning", "warning", "**Back up first.** {{include: guide}}"); err != nil {
+		t.Fatalf("UpdatePage failed: %v", err)
+	}
+	if _, err := w.UpdatePage(guide.ID, "Guide", "guide", "# Guide\n\n{{include: shared/warning}}\n"); err != nil {
+		t.Fatalf("UpdatePage failed: %v", err)
+	}
+
+	rendered, err := w.RenderPage(t.Context(), guide.ID)
+	if err != nil {
+		t.Fatalf("RenderPage failed: %v", err)
+	}
+	if !strings.Contains(rendered.HTML, "<strong>Back up first.</strong>") || !strings.Contains(rendered.HTML, "include of guide skipped") {
+		t.Errorf("expected the include to be expanded once, got %s", rendered.HTML)
+	}
+
+	// the Markdown export keeps the directive
+	file, err := w.ExportPage(t.Context(), guide.ID, "md", false)
+	if err != nil {
+		t.Fatalf("ExportPage failed: %v", err)
+	}
+	if !strings.Contains(string(file.Data), "{{include: shared/warning}}") {
+		t.Errorf("expected the directive in the Markdown export, got %s", file.Data)
+	}
+}
+
+func TestWiki_RenderAndExport_Cancelled(t *testing.T) {
+	w := setupTestWiki(t)
+	parent, _ := w.CreatePage(nil, "Parent", "parent")
+	if _, err := w.CreatePage(&parent.ID, "Child", "child"); err != nil {
+		t.Fatalf("CreatePage failed: %v", err)
+	}
+
+	ctx, cancel := context.WithCancel(t.Context())
+	cancel()
+	if _, err := w.RenderPage(ctx, parent.ID); !errors.Is(err, context.Canceled) {
+		t.Errorf("Expected RenderPage to stop, got %v", err)
+	}
+	if _, err := w.ExportPage(ctx, parent.ID, "pdf", true); !errors.Is(err, context.Canceled) {
+		t.Errorf("Expected the PDF export to stop, got %v", err)
+	}
+	out, err := staticsite.NewDirWriter(t.TempDir())
+	if err != nil {
+		t.Fatalf("NewDirWriter failed: %v", err)
+	}
+	if _, err := w.ExportHTML(ctx, out); !errors.Is(err, context.Canceled) {
+		t.Errorf("Expected the HTML export to stop, got %v", err)
+	}
+}
+
+func TestWiki_RenderPage_ExpandsMacros(t *testing.T) {
+	w := setupTestWiki(t)
+	defer w.Close()
+
+	docs, _ := w.CreatePage(nil, "Docs", "docs")
+	guide, _ := w.CreatePage(&docs.ID, "Guide", "guide")
+	if _, err := w.CreatePage(&guide.ID, "Setup", "setup"); err != nil {
+		t.Fatalf("CreatePage failed: %v", err)
+	}
+	if _, err := w.UpdatePage(guide.ID, "Guide", "guide", "# Guide"); err != nil {
+		t.Fatalf("UpdatePage failed: %v", err)
+	}
+	if err := w.searchIndex.CaptureFileHistory(t.Context(), filepath.Join(w.GetStorageDir(), "root")); err != nil {
+		t.Fatalf("CaptureFileHistory failed: %v", err)
+	}
+	content := "---\nstatus: draft\n---\n# {{pagetitle}} ({{field name=status}})\n\n{{childlist depth=2}}\n\n{{recentchanges path=docs limit=1}}\n\n{{childlist depth=9}}"
+	if _, err := w.UpdatePage(docs.ID, "Docs", "docs", content); err != nil {
+		t.Fatalf("UpdatePage failed: %v", err)
+	}
+
+	page, err := w.GetPage(docs.ID)
+	if err != nil {
+		t.Fatalf("GetPage failed: %v", err)
+	}
+	got := w.expandedContent(page)
+	for _, want := range []string{
+		"# Docs (draft)",
+		"- [Guide](/docs/guide)\n  - [Setup](/docs/guide/setup)",
+		"*⚠ childlist: depth must be a number between 1 and 5*",
+	} {
+		if !strings.Contains(got, want) {
+			t.Errorf("expected %q in %q", want, got)
+		}
+	}
+	// both pages below docs changed today, the limit lists one of them
+	if n := strings.Count(got, ") – "+time.Now().Format(time.DateOnly)); n != 1 {
+		t.Errorf("expected one recent change, got %d in %q", n, got)
+	}
+}
+
+func TestWiki_RenderPage_AppendsChildIndex(t *testing.T) {
+	w := setupTestWiki(t)
+	defer w.Close()
+
+	docs, _ := w.CreatePage(nil, "Docs", "docs")
+	setup, _ := w.CreatePage(&docs.ID, "Setup", "setup")
+	reference, _ := w.CreatePage(&docs.ID, "Reference", "reference")
+	if _, err := w.UpdatePage(setup.ID, "Setup", "setup", "# Setup\n\nInstall the **binary** and `run` it.\n\nMore text."); err != nil {
+		t.Fatalf("UpdatePage failed: %v", err)
+	}
+	if _, err := w.UpdatePage(reference.ID, "Reference", "reference", "---\nsummary: The REST endpoints\n---\n# Reference"); err != nil {
+		t.Fatalf("UpdatePage failed: %v", err)
+	}
+
+	page, _ := w.GetPage(docs.ID)
+	if got := w.expandedContent(page); strings.Contains(got, "## Pages") {
+		t.Errorf("expected no index without the flag, got %q", got)
+	}
+
+	if _, err := w.UpdatePage(docs.ID, "Docs", "docs", "---\nchildIndex: true\n---\n# Docs\n"); err != nil {
+		t.Fatalf("UpdatePage failed: %v", err)
+	}
+	page, _ = w.GetPage(docs.ID)
+	want := "# Docs\n\n## Pages\n\n- [Reference](/docs/reference) – The REST endpoints\n- [Setup](/docs/setup) – Install the binary and run it.\n"
+	if got := w.expandedContent(page); !strings.HasSuffix(got, want) {
+		t.Errorf("expected the index %q, got %q", want, got)
+	}
+}
+
+func TestSummarize_Shortens(t *testing.T) {
+	summary := summarize(strings.Repeat("word ", 100))
+	if utf8.RuneCountInString(summary) > maxSummaryLength+1 || !strings.HasSuffix(summary, "word…") {
+		t.Errorf("unexpected summary %q", summary)
+	}
+}
+
+func TestWiki_Obsidian_RendersWikilinksAndEmbeds(t *testing.T) {
+	dir := t.TempDir()
+	w, err := NewWiki(dir, "admin", "secretkey", false, WithObsidian(true))
+	if err != nil {
+		t.Fatalf("Failed to create wiki: %v", err)
+	}
+	defer w.Close()
+
+	projects, _ := w.CreatePage(nil, "Projects", "projects")
+	plan, _ := w.CreatePage(&projects.ID, "Plan", "plan")
+	home, _ := w.CreatePage(nil, "Home", "home")
+	if _, err := w.UpdatePage(plan.ID, "Plan", "plan", "---\naliases: [Roadmap]\n---\n# Plan\n\n## Goals\nShip it.\n"); err != nil {
+		t.Fatalf("UpdatePage failed: %v", err)
+	}
+	content := "See [[Roadmap|the roadmap]].\n\n![[plan#Goals]]\n\n![[logo.png]]\n"
+	if _, err := w.UpdatePage(home.ID, "Home", "home", content); err != nil {
+		t.Fatalf("UpdatePage failed: %v", err)
+	}
+	if err := os.MkdirAll(filepath.Join(dir, "root", "media"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, "root", "media", "logo.png"), []byte("png"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	rendered, err := w.RenderPage(t.Context(), home.ID)
+	if err != nil {
+		t.Fatalf("RenderPage failed: %v", err)
+	}
+	for _, want := range []string{`<a href="/projects/plan" rel="nofollow">the roadmap</a>`, "Ship it.", `<img src="/vault/media/logo.png"`} {
+		if !strings.Contains(rendered.HTML, want) {
+			t.Errorf("expected %q in the rendered page, got %s", want, rendered.HTML)
+		}
+	}
+
+	// the note itself is not rewritten
+	page, _ := w.GetPage(home.ID)
+	if page.Content != content {
+		t.Errorf("expected the content to be kept, got %q", page.Content)
+	}
+
+	if _, err := w.VaultFile("media/logo.png"); err != nil {
+		t.Errorf("expected the attachment to be served, got %v", err)
+	}
+	for _, rel := range []string{"home.md", ".obsidian/app.json", "../secret.png"} {
+		if _, err := w.VaultFile(rel); !errors.Is(err, ErrVaultFileNotFound) {
+			t.Errorf("expected %s not to be served, got %v", rel, err)
+		}
+	}
+}
+
+func TestWiki_FindByPath_Aliases(t *testing.T) {
+	w := setupTestWiki(t)
+	defer w.Close()
+
+	docs, _ := w.CreatePage(nil, "Docs", "docs")
+	setup, _ := w.CreatePage(&docs.ID, "Setup", "setup")
+	if _, err := w.UpdatePage(setup.ID, "Setup", "setup", "---\naliases:\n  - install\n  - /old/Getting Started/\n---\n# Setup\n"); err != nil {
+		t.Fatalf("UpdatePage failed: %v", err)
+	}
+
+	for _, route := range []string{"install", "old/getting started", "/Old/Getting Started"} {
+		page, err := w.FindByPath(route)
+		if err != nil || page.ID != setup.ID {
+			t.Errorf("expected %q to resolve to the page, got %v, %v", route, page, err)
+		}
+	}
+
+	// routes of pages take precedence over aliases
+	install, _ := w.CreatePage(nil, "Install", "install")
+	if page, err := w.FindByPath("install"); err != nil || page.ID != install.ID {
+		t.Errorf("expected the page to win over the alias, got %v, %v", page, err)
+	}
+
+	// removed aliases no longer resolve
+	if _, err := w.UpdatePage(setup.ID, "Setup", "setup", "# Setup\n"); err != nil {
+		t.Fatalf("UpdatePage failed: %v", err)
+	}
+	if _, err := w.FindByPath("old/getting started"); !errors.Is(err, tree.ErrPageNotFound) {
+		t.Errorf("expected the alias to be removed, got %v", err)
+	}
+}
+
+func TestWiki_Favorites(t *testing.T) {
+	w := setupTestWiki(t)
+	defer w.Close()
+
+	guide, _ := w.CreatePage(nil, "Setup Guide", "setup-guide")
+	guides, _ := w.CreatePage(nil, "Setup Guides", "setup-guides")
+	user := &auth.User{ID: "u1"}
+
+	// without favorites the closest match wins
+	if suggestions := w.SuggestPagesForUser(user, "setup-gide"); len(suggestions) < 2 || suggestions[0].ID != guide.ID {
+		t.Fatalf("expected the closest page first, got %+v", suggestions)
+	}
+
+	if _, err := w.AddFavorite(user.ID, guides.ID); err != nil {
+		t.Fatalf("AddFavorite failed: %v", err)
+	}
+	if _, err := w.AddFavorite(user.ID, "unknown"); !errors.Is(err, tree.ErrPageNotFound) {
+		t.Errorf("expected ErrPageNotFound for unknown pages, got %v", err)
+	}
+	if suggestions := w.SuggestPagesForUser(user, "setup-gide"); suggestions[0].ID != guides.ID {
+		t.Errorf("expected the favorite first, got %+v", suggestions)
+	}
+	if suggestions := w.SuggestPages("setup-gide"); suggestions[0].ID != guide.ID {
+		t.Errorf("expected anonymous suggestions to be unchanged, got %+v", suggestions)
+	}
+
+	// deleted pages disappear from the favorites
+	if err := w.DeletePage(guides.ID, false); err != nil {
+		t.Fatalf("DeletePage failed: %v", err)
+	}
+	favs, err := w.GetFavorites(user.ID)
+	if err != nil || len(favs) != 0 {
+		t.Errorf("expected no favorites after delete, got %v, %v", favs, err)
+	}
+}
+
+func TestWiki_StalePages(t *testing.T) {
+	w := setupTestWiki(t)
+	defer w.Close()
+
+	old, _ := w.CreatePage(nil, "Old", "old")
+	viewed, _ := w.CreatePage(nil, "Old But Read", "old-but-read")
+	review, _ := w.CreatePage(nil, "Review", "review")
+	fresh, _ := w.CreatePage(nil, "Fresh", "fresh")
+	if _, err := w.UpdatePage(review.ID, "Review", "review", "---\nreview-by: 2020-01-31\n---\n# Review\n"); err != nil {
+		t.Fatalf("UpdatePage failed: %v", err)
+	}
+	if _, err := w.UpdatePage(fresh.ID, "Fresh", "fresh", "---\nreview-by: 2999-01-01\n---\n# Fresh\n"); err != nil {
+		t.Fatalf("UpdatePage failed: %v", err)
+	}
+
+	// without history the modification time of the file counts
+	longAgo := time.Now().AddDate(-1, 0, 0)
+	for _, slug := range []string{"old", "old-but-read"} {
+		if err := os.Chtimes(filepath.Join(w.storageDir, "root", slug+".md"), longAgo, longAgo); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if _, err := w.SaveReadingPosition("u1", viewed.ID, ""); err != nil {
+		t.Fatalf("SaveReadingPosition failed: %v", err)
+	}
+
+	report, err := w.StalePages(StaleOptions{Months: 6, ViewedDays: 30})
+	if err != nil {
+		t.Fatalf("StalePages failed: %v", err)
+	}
+	reasons := map[string][]string{}
+	for _, page := range report.Pages {
+		reasons[page.PageID] = page.Reasons
+	}
+	if len(reasons) != 2 || len(reasons[old.ID]) != 1 || reasons[old.ID][0] != StaleReasonUnchanged {
+		t.Fatalf("expected the old and the overdue page, got %+v", report.Pages)
+	}
+	if got := reasons[review.ID]; len(got) != 1 || got[0] != StaleReasonReviewOverdue {
+		t.Errorf("expected the review to be overdue, got %v", got)
+	}
+	if report.Pages[0].PageID != old.ID {
+		t.Errorf("expected the longest unchanged page first, got %+v", report.Pages)
+	}
+
+	// ignoring views reports the page which was read as well
+	report, _ = w.StalePages(StaleOptions{Months: 6})
+	if len(report.Pages) != 3 {
+		t.Errorf("expected views to be ignored, got %+v", report.Pages)
+	}
+
+	if _, err := w.StalePages(StaleOptions{}); err == nil {
+		t.Error("expected a validation error without months")
+	}
+}
+
+func TestWiki_ReviewReminders(t *testing.T) {
+	w := setupTestWiki(t)
+	defer w.Close()
+
+	overdue, _ := w.CreatePage(nil, "Overdue", "overdue")
+	future, _ := w.CreatePage(nil, "Future", "future")
+	if _, err := w.UpdatePage(overdue.ID, "Overdue", "overdue", "---\nreview-by: 2020-01-31\nowner: [ada, ops@example.com]\n---\n# Overdue\n"); err != nil {
+		t.Fatalf("UpdatePage failed: %v", err)
+	}
+	if _, err := w.UpdatePage(future.ID, "Future", "future", "---\nreview-by: 2999-01-01\nowner: ada\n---\n# Future\n"); err != nil {
+		t.Fatalf("UpdatePage failed: %v", err)
+	}
+
+	due, err := w.DueReviews()
+	if err != nil {
+		t.Fatalf("DueReviews failed: %v", err)
+	}
+	if len(due) != 1 || due[0].PageID != overdue.ID || due[0].DaysOverdue <= 0 {
+		t.Fatalf("expected the overdue page, got %+v", due)
+	}
+	if got := due[0].Owners; len(got) != 2 || got[0] != "ada" || got[1] != "ops@example.com" {
+		t.Errorf("unexpected owners: %v", got)
+	}
+
+	// without a channel nothing is delivered and nothing is marked
+	result, err := w.SendReviewReminders(context.Background())
+	if err != nil {
+		t.Fatalf("SendReviewReminders failed: %v", err)
+	}
+	if result.Notified != 0 {
+		t.Errorf("expected no reminders without a channel, got %+v", result)
+	}
+
+	var event, signature string
+	calls := 0
+	server := httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, r *http.Request) {
+		calls++
+		event = r.Header.Get("X-LeafWiki-Event")
+		signature = r.Header.Get("X-LeafWiki-Signature")
+	}))
+	defer server.Close()
+
+	s, _ := w.GetSettings()
+	s.Webhook.URL = server.URL
+	s.Webhook.Secret = "secret"
+	if _, err := w.UpdateSettings(s); err != nil {
+		t.Fatalf("UpdateSettings failed: %v", err)
+	}
+
+	result, err = w.SendReviewReminders(context.Background())
+	if err != nil {
+		t.Fatalf("SendReviewReminders failed: %v", err)
+	}
+	if result.Notified != 1 || calls != 1 {
+		t.Fatalf("expected one reminder, got %+v after %d calls", result, calls)
+	}
+	if event != ReviewDueEvent || !strings.HasPrefix(signature, "sha256=") {
+		t.Errorf("unexpected headers: event %q, signature %q", event, signature)
+	}
+
+	// every review date is notified once
+	result, err = w.SendReviewReminders(context.Background())
+	if err != nil {
+		t.Fatalf("SendReviewReminders failed: %v", err)
+	}
+	if result.Notified != 0 || calls != 1 {
+		t.Errorf("expected no second reminder, got %+v after %d calls", result, calls)
+	}
+	if due, _ := w.DueReviews(); len(due) != 1 || due[0].NotifiedAt == nil {
+		t.Errorf("expected the page to be marked as notified, got %+v", due)
+	}
+}
+
+func TestWiki_ReviewReminders_Channels(t *testing.T) {
+	// the mail server refuses connections, so the emails fail
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("failed to listen: %v", err)
+	}
+	port := listener.Addr().(*net.TCPAddr).Port
+	listener.Close()
+
+	w, err := NewWiki(t.TempDir(), "admin", "secretkey", false,
+		WithSMTP(notify.SMTPConfig{Host: "127.0.0.1", Port: port, From: "wiki@example.com"}))
+	if err != nil {
+		t.Fatalf("Failed to create wiki: %v", err)
+	}
+	defer w.Close()
+	if _, err := w.CreateUser("ada", "ada@example.com", "secretpassword", "editor"); err != nil {
+		t.Fatalf("CreateUser failed: %v", err)
+	}
+
+	// owners are only resolved to the addresses of accounts
+	if got := w.ownerEmails([]string{"ada", "ADA@example.com", "stranger@example.org", "nobody"}); len(got) != 1 || got[0] != "ada@example.com" {
+		t.Errorf("ownerEmails() = %v, want only the address of ada", got)
+	}
+
+	page, _ := w.CreatePage(nil, "Overdue", "overdue")
+	if _, err := w.UpdatePage(page.ID, "Overdue", "overdue", "---\nreview-by: 2020-01-31\nowner: ada\n---\n# Overdue\n"); err != nil {
+		t.Fatalf("UpdatePage failed: %v", err)
+	}
+	calls := 0
+	server := httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, r *http.Request) { calls++ }))
+	defer server.Close()
+	s, _ := w.GetSettings()
+	s.Webhook.URL = server.URL
+	if _, err := w.UpdateSettings(s); err != nil {
+		t.Fatalf("UpdateSettings failed: %v", err)
+	}
+
+	// the webhook delivers, the email fails: the page is reminded and the email is retried
+	for run := 1; run <= 2; run++ {
+		result, err := w.SendReviewReminders(context.Background())
+		if err != nil {
+			t.Fatalf("SendReviewReminders failed: %v", err)
+		}
+		if len(result.Failed) != 1 || result.Failed[0] != "overdue" || calls != 1 {
+			t.Errorf("run %d: expected the email to fail and one webhook call, got %+v after %d calls", run, result, calls)
+		}
+		if want := map[int]int{1: 1, 2: 0}[run]; result.Notified != want {
+			t.Errorf("run %d: expected %d notified pages, got %d", run, want, result.Notified)
+		}
+		if due, _ := w.DueReviews(); len(due) != 1 || due[0].NotifiedAt == nil {
+			t.Errorf("run %d: expected the page to be marked as reminded, got %+v", run, due)
+		}
+	}
+}
+
+func TestWiki_LintPage(t *testing.T) {
+	w := setupTestWiki(t)
+	defer w.Close()
+
+	docs, _ := w.CreatePage(nil, "Docs", "docs")
+	setup, _ := w.CreatePage(&docs.ID, "Setup", "setup")
+	guide, _ := w.CreatePage(&docs.ID, "Guide", "guide")
+	source := filepath.Join(t.TempDir(), "diagram.png")
+	if err := os.WriteFile(source, []byte("png"), 0644); err != nil {
+		t.Fatalf("failed to write asset: %v", err)
+	}
+	file, err := os.Open(source)
+	if err != nil {
+		t.Fatalf("failed to open asset: %v", err)
+	}
+	defer file.Close()
+	url, err := w.UploadAsset(guide.ID, file, "diagram.png")
+	if err != nil {
+		t.Fatalf("UploadAsset failed: %v", err)
+	}
+	if err := w.MovePage(setup.ID, "root"); err != nil {
+		t.Fatalf("MovePage failed: %v", err)
+	}
+
+	content := "## Guide\n" +
+		"[setup](setup) [moved](/docs/setup) [docs](../docs) [root](/) [diagram](" + url + ")\n" +
+		"[missing](missing) [old](/docs/gone) [asset](/assets/" + guide.ID + "/gone.png)\n"
+	if _, err := w.UpdatePage(guide.ID, guide.Title, guide.Slug, content); err != nil {
+		t.Fatalf("UpdatePage failed: %v", err)
+	}
+
+	result, err := w.LintPage(guide.ID)
+	if err != nil {
+		t.Fatalf("LintPage failed: %v", err)
+	}
+	var got []string
+	for _, warning := range result.Warnings {
+		got = append(got, fmt.Sprintf("%s:%d", warning.Rule, warning.Line))
+	}
+	// the setup link of the page at docs/guide leads to the sibling docs/setup, which moved
+	want := []string{"missing-h1:1", "broken-link:3", "broken-link:3", "broken-link:3"}
+	if fmt.Sprint(got) != fmt.Sprint(want) {
+		t.Errorf("LintPage() = %v (%+v), want %v", got, result.Warnings, want)
+	}
+
+	s, err := w.GetSettings()
+	if err != nil {
+		t.Fatalf("GetSettings failed: %v", err)
+	}
+	s.Lint.MissingH1, s.Lint.BrokenLinks = false, false
+	if _, err := w.UpdateSettings(s); err != nil {
+		t.Fatalf("UpdateSettings failed: %v", err)
+	}
+	if result, _ := w.LintPage(guide.ID); len(result.Warnings) != 0 {
+		t.Errorf("expected the disabled rules to be skipped, got %+v", result.Warnings)
+	}
+	s.Lint.MaxLineLength = 0
+	if _, err := w.UpdateSettings(s); err == nil {
+		t.Errorf("expected an error for a max line length of 0")
+	}
+
+	if _, err := w.LintPage("missing"); err == nil {
+		t.Errorf("expected an error for an unknown page")
+	}
+
+	// the assets next to a page are linked relative to it
+	folders, err := NewWiki(t.TempDir(), "admin", "secretkey", false, WithPageFolderAssets(true))
+	if err != nil {
+		t.Fatalf("Failed to create wiki: %v", err)
+	}
+	defer folders.Close()
+	page, err := folders.CreatePage(nil, "Guide", "guide")
+	if err != nil {
+		t.Fatalf("CreatePage failed: %v", err)
+	}
+	if _, err := file.Seek(0, 0); err != nil {
+		t.Fatalf("failed to rewind asset: %v", err)
+	}
+	if url, err := folders.UploadAsset(page.ID, file, "diagram.png"); err != nil || url != "./assets/diagram.png" {
+		t.Fatalf("UploadAsset() = %q, %v", url, err)
+	}
+	content = "# Guide\n" +
+		"[a](./assets/diagram.png) [b](assets/diagram.png) [c](/assets/" + page.ID + "/diagram.png)\n" +
+		"[gone](./assets/gone.png)\n"
+	if _, err := folders.UpdatePage(page.ID, page.Title, page.Slug, content); err != nil {
+		t.Fatalf("UpdatePage failed: %v", err)
+	}
+	result, err = folders.LintPage(page.ID)
+	if err != nil {
+		t.Fatalf("LintPage failed: %v", err)
+	}
+	if len(result.Warnings) != 1 || result.Warnings[0].Rule != lint.RuleBrokenLink || result.Warnings[0].Line != 3 {
+		t.Errorf("expected only the missing asset next to the page, got %+v", result.Warnings)
+	}
+}
+
+func TestWiki_SearchWithDeletedForUser(t *testing.T) {
+	w, err := NewWiki(t.TempDir(), "admin", "secretkey", false, WithAccessChecker(prefixDenyChecker{prefix: "private"}))
+	if err != nil {
+		t.Fatalf("Failed to create wiki: %v", err)
+	}
+	defer w.Close()
+
+	dataDir := filepath.Join(w.GetStorageDir(), "root")
+	var ids []string
+	for _, slug := range []string{"kraken", "private"} {
+		page, _ := w.CreatePage(nil, "Kraken "+slug, slug)
+		if _, err := w.UpdatePage(page.ID, page.Title, slug, "# Kraken "+slug+"\n\nThe kraken runbook."); err != nil {
+			t.Fatalf("UpdatePage failed: %v", err)
+		}
+		ids = append(ids, page.ID)
+	}
+	live, _ := w.CreatePage(nil, "Live", "live")
+	if err := w.searchIndex.IndexPage("live", "live.md", live.ID, "Live", "The kraken is alive."); err != nil {
+		t.Fatalf("IndexPage failed: %v", err)
+	}
+	if err := w.searchIndex.CaptureFileHistory(t.Context(), dataDir); err != nil {
+		t.Fatalf("CaptureFileHistory failed: %v", err)
+	}
+	for _, id := range ids {
+		if err := w.DeletePage(id, false); err != nil {
+			t.Fatalf("DeletePage failed: %v", err)
+		}
+	}
+	if err := w.searchIndex.CaptureFileHistory(t.Context(), dataDir); err != nil {
+		t.Fatalf("CaptureFileHistory failed: %v", err)
+	}
+
+	editor := &auth.User{ID: "editor", Role: auth.RoleEditor}
+	if res, _ := w.SearchForUser(t.Context(), editor, "kraken", 0, 10); res.Count != 1 {
+		t.Fatalf("expected only the live page without deleted pages, got %+v", res)
+	}
+	res, err := w.SearchWithDeletedForUser(t.Context(), editor, "kraken", 0, 10)
+	if err != nil {
+		t.Fatalf("SearchWithDeletedForUser failed: %v", err)
+	}
+	if res.Count != 2 || len(res.Items) != 2 || res.Items[0].PageID != live.ID || !res.Items[1].Deleted || res.Items[1].Path != "kraken" {
+		t.Fatalf("expected the live page and the readable deleted page, got %+v", res)
+	}
+
+	// the deleted pages follow the live ones across windows
+	admin := &auth.User{ID: "admin", Role: auth.RoleAdmin}
+	res, err = w.SearchWithDeletedForUser(t.Context(), admin, "kraken", 1, 1)
+	if err != nil {
+		t.Fatalf("SearchWithDeletedForUser failed: %v", err)
+	}
+	if res.Count != 3 || len(res.Items) != 1 || !res.Items[0].Deleted {
+		t.Errorf("expected the first deleted page in the second window, got %+v", res)
+	}
+}
+
+func TestWiki_SavedSearchAlerts(t *testing.T) {
+	w := setupTestWiki(t)
+	defer w.Close()
+
+	admin, err := w.GetUserService().GetUserByEmailOrUsernameAndPassword("admin", "admin")
+	if err != nil {
+		t.Fatalf("could not get admin: %v", err)
+	}
+	update := func(page *tree.Page, content string) {
+		t.Helper()
+		if _, err := w.UpdatePage(page.ID, page.Title, page.Slug, content); err != nil {
+			t.Fatalf("UpdatePage failed: %v", err)
+		}
+		if err := w.searchIndex.IndexPage(page.Slug, page.Slug+".md", page.ID, page.Title, content); err != nil {
+			t.Fatalf("IndexPage failed: %v", err)
+		}
+	}
+
+	backup, _ := w.CreatePage(nil, "Backup", "backup")
+	update(backup, "Nightly postgres backups.")
+
+	var ve *verrors.ValidationErrors
+	if _, err := w.CreateSavedSearch(admin.ID, " ", "postgres", true); !errors.As(err, &ve) {
+		t.Fatalf("expected a validation error without name, got %v", err)
+	}
+	saved, err := w.CreateSavedSearch(admin.ID, "Postgres", "postgres", true)
+	if err != nil {
+		t.Fatalf("CreateSavedSearch failed: %v", err)
+	}
+
+	// the first check only records the matches
+	result, err := w.CheckSavedSearches(context.Background())
+	if err != nil {
+		t.Fatalf("CheckSavedSearches failed: %v", err)
+	}
+	if result.Checked != 1 || result.Alerts != 0 {
+		t.Fatalf("expected no alerts on the first check, got %+v", result)
+	}
+
+	var event string
+	server := httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, r *http.Request) {
+		event = r.Header.Get("X-LeafWiki-Event")
+	}))
+	defer server.Close()
+	s, _ := w.GetSettings()
+	s.Webhook.URL = server.URL
+	if _, err := w.UpdateSettings(s); err != nil {
+		t.Fatalf("UpdateSettings failed: %v", err)
+	}
+
+	restore, _ := w.CreatePage(nil, "Restore", "restore")
+	update(restore, "Restore postgres backups with pgrestore.")
+	update(backup, "Nightly postgres backups, kept for a week.")
+	lunch, _ := w.CreatePage(nil, "Lunch", "lunch")
+	update(lunch, "Lunch is on Fridays.")
+
+	result, err = w.CheckSavedSearches(context.Background())
+	if err != nil {
+		t.Fatalf("CheckSavedSearches failed: %v", err)
+	}
+	if result.Alerts != 2 || len(result.Failed) != 0 || event != SearchMatchEvent {
+		t.Fatalf("expected two alerts by webhook, got %+v and event %q", result, event)
+	}
+	alerts, err := w.GetSearchAlerts(admin.ID, true)
+	if err != nil {
+		t.Fatalf("GetSearchAlerts failed: %v", err)
+	}
+	changes := map[string]savedsearch.Change{}
+	for _, alert := range alerts {
+		changes[alert.PageID] = alert.Change
+	}
+	if len(alerts) != 2 || changes[restore.ID] != savedsearch.ChangeNew || changes[backup.ID] != savedsearch.ChangeUpdated {
+		t.Fatalf("unexpected alerts: %+v", alerts)
+	}
+
+	// unchanged matches raise no alerts
+	if result, _ := w.CheckSavedSearches(context.Background()); result.Alerts != 0 {
+		t.Errorf("expected no new alerts, got %+v", result)
+	}
+
+	if err := w.MarkSearchAlertsRead(admin.ID); err != nil {
+		t.Fatalf("MarkSearchAlertsRead failed: %v", err)
+	}
+	if unread, _ := w.GetSearchAlerts(admin.ID, true); len(unread) != 0 {
+		t.Errorf("expected no unread alerts, got %+v", unread)
+	}
+	if all, _ := w.GetSearchAlerts(admin.ID, false); len(all) != 2 {
+		t.Errorf("expected the read alerts to be kept, got %+v", all)
+	}
+
+	if err := w.DeleteSavedSearch("someone-else", saved.ID); !errors.Is(err, savedsearch.ErrSavedSearchNotFound) {
+		t.Errorf("expected ErrSavedSearchNotFound for another user, got %v", err)
+	}
+	if err := w.DeleteSavedSearch(admin.ID, saved.ID); err != nil {
+		t.Fatalf("DeleteSavedSearch failed: %v", err)
+	}
+	if all, _ := w.GetSearchAlerts(admin.ID, false); len(all) != 0 {
+		t.Errorf("expected the alerts to be deleted with the search, got %+v", all)
+	}
+}
+
+func TestWiki_SpellcheckPage(t *testing.T) {
+	if _, err := setupTestWiki(t).SpellcheckPage("root", "", nil); !errors.Is(err, ErrSpellcheckDisabled) {
+		t.Fatalf("Expected ErrSpellcheckDisabled, got %v", err)
+	}
+
+	dicts := t.TempDir()
+	for name, content := range map[string]string{
+		"en_US.aff": "SET UTF-8\nTRY esianrtolcdugmphbyfvkwz\nSFX S Y 1\nSFX S 0 s .\n",
+		"en_US.dic": "3\nthe\npage/S\nwiki/S\n",
+		"de_DE.aff": "SET UTF-8\n",
+		"de_DE.dic": "2\ndie\nSeite\n",
+	} {
+		if err := os.WriteFile(filepath.Join(dicts, name), []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	w, err := NewWiki(t.TempDir(), "admin", "secretkey", false, WithSpellcheck(dicts))
+	if err != nil {
+		t.Fatalf("Failed to create wiki: %v", err)
+	}
+	defer w.Close()
+
+	page, _ := w.CreatePage(nil, "Docs", "docs")
+	if _, err := w.UpdatePage(page.ID, "Docs", "docs", "---\nlang: de_DE\n---\ndie Seite"); err != nil {
+		t.Fatalf("UpdatePage failed: %v", err)
+	}
+
+	// the language comes from the frontmatter
+	result, err := w.SpellcheckPage(page.ID, "", nil)
+	if err != nil {
+		t.Fatalf("SpellcheckPage failed: %v", err)
+	}
+	if result.Language != "de_DE" || len(result.Misspellings) != 0 {
+		t.Errorf("unexpected result: %+v", result)
+	}
+
+	content := "the wiki pgaes"
+	result, err = w.SpellcheckPage(page.ID, "en_US", &content)
+	if err != nil {
+		t.Fatalf("SpellcheckPage failed: %v", err)
+	}
+	if len(result.Misspellings) != 1 || result.Misspellings[0].Offset != 9 || result.Misspellings[0].Suggestions[0] != "pages" {
+		t.Fatalf("unexpected misspellings: %+v", result.Misspellings)
+	}
+
+	if _, err := w.AddCustomWord("Pgaes", "u1"); err != nil {
+		t.Fatalf("AddCustomWord failed: %v", err)
+	}
+	if result, _ := w.SpellcheckPage(page.ID, "en_US", &content); len(result.Misspellings) != 0 {
+		t.Errorf("expected the custom word to be accepted, got %+v", result.Misspellings)
+	}
+
+	var ve *verrors.ValidationErrors
+	if _, err := w.SpellcheckPage(page.ID, "fr_FR", nil); !errors.As(err, &ve) {
+		t.Errorf("expected a validation error for an unknown language, got %v", err)
+	}
+	if _, err := w.AddCustomWord("two words", "u1"); !errors.As(err, &ve) {
+		t.Errorf("expected a validation error, got %v", err)
+	}
+}
+
+func TestWiki_SimilarPagesForUser(t *testing.T) {
+	w, err := NewWiki(t.TempDir(), "admin", "secretkey", false, WithAccessChecker(prefixDenyChecker{prefix: "private"}))
+	if err != nil {
+		t.Fatalf("Failed to create wiki: %v", err)
+	}
+	defer w.Close()
+
+	page, _ := w.CreatePage(nil, "Backup", "backup")
+	content := "Nightly postgres backups are restored with pgrestore."
+	if _, err := w.UpdatePage(page.ID, "Backup", "backup", content); err != nil {
+		t.Fatalf("UpdatePage failed: %v", err)
+	}
+	pages := []struct{ path, id, content string }{
+		{"backup", page.ID, content},
+		{"docs/restore", "d1", "Restore postgres backups with pgrestore."},
+		{"private/restore", "p1", "Restore postgres backups with pgrestore."},
+		{"docs/lunch", "d2", "Lunch is on Fridays."},
+	}
+	for _, p := range pages {
+		if err := w.searchIndex.IndexPage(p.path, p.path+".md", p.id, p.path, p.content); err != nil {
+			t.Fatalf("IndexPage failed: %v", err)
+		}
+	}
+
+	editor := &auth.User{ID: "editor", Role: auth.RoleEditor}
+	similar, err := w.SimilarPagesForUser(editor, page.ID, DefaultSimilarLimit)
+	if err != nil {
+		t.Fatalf("SimilarPagesForUser failed: %v", err)
+	}
+	if len(similar) != 1 || similar[0].PageID != "d1" {
+		t.Fatalf("expected only the readable related page, got %+v", similar)
+	}
+
+	admin := &auth.User{ID: "admin", Role: auth.RoleAdmin}
+	if similar, _ := w.SimilarToForUser(admin, "Restore", "Restore postgres backups with pgrestore.", DefaultSimilarLimit); len(similar) != 3 || !similar[0].Duplicate {
+		t.Errorf("expected the duplicates first, got %+v", similar)
+	}
+
+	var ve *verrors.ValidationErrors
+	if _, err := w.SimilarToForUser(admin, " ", "", DefaultSimilarLimit); !errors.As(err, &ve) {
+		t.Errorf("expected a validation error without title and content, got %v", err)
+	}
+	if _, err := w.SimilarPagesForUser(admin, page.ID, MaxSimilarLimit+1); !errors.As(err, &ve) {
+		t.Errorf("expected a validation error for the limit, got %v", err)
+	}
+}
+
+func TestWiki_DuplicatePages(t *testing.T) {
+	w := setupTestWiki(t)
+	defer w.Close()
+
+	guide := "Install the agent with the package manager of your distribution, then enable the service, open the firewall port for the collector and check the logs for errors before you add the host to the monitoring dashboard."
+	contents := map[string]string{
+		"install":      "---\ntitle: Install\n---\n" + guide,
+		"install-copy": guide,
+		"install-old":  guide + " Restart it after updates.",
+		"lunch":        "Some unrelated text about the lunch menu of the canteen on Fridays and holidays.",
+		"stub-a":       "# Todo",
+		"stub-b":       "# Todo",
+	}
+	ids := map[string]string{}
+	for slug, content := range contents {
+		page, err := w.CreatePage(nil, slug, slug)
+		if err != nil {
+			t.Fatalf("CreatePage failed: %v", err)
+		}
+		if _, err := w.UpdatePage(page.ID, slug, slug, content); err != nil {
+			t.Fatalf("UpdatePage failed: %v", err)
+		}
+		ids[page.ID] = slug
+	}
+
+	report, err := w.DuplicatePages(DefaultDuplicateThreshold)
+	if err != nil {
+		t.Fatalf("DuplicatePages failed: %v", err)
+	}
+	// the frontmatter is ignored, stubs are not reported
+	if len(report.Groups) != 1 || len(report.Groups[0].Pages) != 3 || report.Groups[0].Identical {
+		t.Fatalf("expected one group of the install pages, got %+v", report.Groups)
+	}
+	for _, page := range report.Groups[0].Pages {
+		if !strings.HasPrefix(ids[page.PageID], "install") || page.Path != ids[page.PageID] {
+			t.Errorf("unexpected page %+v", page)
+		}
+	}
+
+	report, err = w.DuplicatePages(1)
+	if err != nil {
+		t.Fatalf("DuplicatePages failed: %v", err)
+	}
+	if len(report.Groups) != 1 || len(report.Groups[0].Pages) != 2 || !report.Groups[0].Identical {
+		t.Errorf("expected only the identical pages, got %+v", report.Groups)
+	}
+
+	var ve *verrors.ValidationErrors
+	if _, err := w.DuplicatePages(1.5); !errors.As(err, &ve) {
+		t.Errorf("expected a validation error, got %v", err)
+	}
+}
+
+func TestWiki_PointInTime(t *testing.T) {
+	w := setupTestWiki(t)
+	defer w.Close()
+
+	dataDir := filepath.Join(w.GetStorageDir(), "root")
+	capture := func(recordedAt string) {
+		t.Helper()
+		if err := w.searchIndex.CaptureFileHistory(t.Context(), dataDir); err != nil {
+			t.Fatalf("CaptureFileHistory failed: %v", err)
+		}
+		if _, err := w.searchIndex.GetDB().Exec(`UPDATE file_history SET recorded_at = ? WHERE recorded_at > '2021-01-01 00:00:00';`, recordedAt); err != nil {
+			t.Fatalf("failed to age history: %v", err)
+		}
+	}
+
+	docs, _ := w.CreatePage(nil, "Docs", "docs")
+	setup, _ := w.CreatePage(&docs.ID, "Setup", "setup")
+	other, _ := w.CreatePage(nil, "Other", "other")
+	if _, err := w.UpdatePage(setup.ID, "Setup", "setup", "# Setup\n\nInstall v1."); err != nil {
+		t.Fatalf("UpdatePage failed: %v", err)
+	}
+	if _, err := w.UpdatePage(other.ID, "Other", "other", "# Other page"); err != nil {
+		t.Fatalf("UpdatePage failed: %v", err)
+	}
+	capture("2020-01-01 00:00:00")
+
+	if _, err := w.UpdatePage(setup.ID, "Setup", "setup", "# Setup\n\nInstall v2."); err != nil {
+		t.Fatalf("UpdatePage failed: %v", err)
+	}
+	if err := w.DeletePage(other.ID, false); err != nil {
+		t.Fatalf("DeletePage failed: %v", err)
+	}
+	capture("2020-06-01 00:00:00")
+
+	lastYear := time.Date(2020, 3, 1, 0, 0, 0, 0, time.UTC)
+	root, err := w.TreeAsOf(lastYear)
+	if err != nil {
+		t.Fatalf("TreeAsOf failed: %v", err)
+	}
+	// the welcome page follows docs and the deleted other page
+	if len(root.Children) != 3 || root.Children[0].ID != docs.ID || root.Children[1].Slug != "other" || root.Children[1].ID != "" {
+		t.Fatalf("expected docs and the deleted other page, got %+v", root.Children)
+	}
+	if len(root.Children[0].Children) != 1 || root.Children[0].Children[0].ID != setup.ID {
+		t.Fatalf("expected setup below docs, got %+v", root.Children[0].Children)
+	}
+
+	page, err := w.PageAsOf(setup.ID, lastYear)
+	if err != nil || page.Content != "# Setup\n\nInstall v1." {
+		t.Fatalf("expected the first version of setup, got %+v, %v", page, err)
+	}
+	if page, err := w.PageAsOf(setup.ID, time.Now()); err != nil || page.Content != "# Setup\n\nInstall v2." {
+		t.Errorf("expected the current version of setup, got %+v, %v", page, err)
+	}
+	deleted, err := w.FindByPathAsOf("other", lastYear)
+	if err != nil || deleted.Title != "Other page" || deleted.Content != "# Other page" {
+		t.Errorf("expected the deleted page by path, got %+v, %v", deleted, err)
+	}
+	if _, err := w.FindByPathAsOf("other", time.Now()); !errors.Is(err, tree.ErrPageNotFound) {
+		t.Errorf("expected the deleted page to be missing today, got %v", err)
+	}
+	if _, err := w.PageAsOf(setup.ID, time.Date(2019, 1, 1, 0, 0, 0, 0, time.UTC)); !errors.Is(err, tree.ErrPageNotFound) {
+		t.Errorf("expected no page before the history, got %v", err)
+	}
+}
+
+func TestWiki_CheckIntegrity(t *testing.T) {
+	w := setupTestWiki(t)
+	defer w.Close()
+
+	if last := w.LastIntegrityCheck(); last == nil || !last.OK {
+		t.Fatalf("expected an intact database at startup, got %+v", last)
+	}
+
+	page, _ := w.CreatePage(nil, "Kraken", "kraken")
+	if _, err := w.UpdatePage(page.ID, page.Title, page.Slug, "# Kraken\n\nThe kraken runbook."); err != nil {
+		t.Fatalf("UpdatePage failed: %v", err)
+	}
+	if err := w.searchIndex.IndexPage("kraken", "kraken.md", page.ID, page.Title, "The kraken runbook."); err != nil {
+		t.Fatalf("IndexPage failed: %v", err)
+	}
+	if _, err := w.searchIndex.GetDB().Exec(`DELETE FROM pages_content;`); err != nil {
+		t.Fatalf("failed to damage the index: %v", err)
+	}
+
+	report := w.CheckIntegrity()
+	if report.OK || len(report.Problems) == 0 || !report.Repaired || report.RepairError != "" {
+		t.Fatalf("expected the damaged index to be repaired, got %+v", report)
+	}
+	if w.LastIntegrityCheck() != report {
+		t.Errorf("expected the report to be kept")
+	}
+
+	// the pages are indexed again in the background
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		res, err := w.Search(t.Context(), "kraken", 0, 10)
+		if err == nil && res.Count == 1 {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("expected the page to be indexed again, got %+v, %v", res, err)
+		}
+		time.Sleep(20 * time.Millisecond)
+	}
+}
+
+func TestWiki_MigrateLayout(t *testing.T) {
+	dir := t.TempDir()
+	w, err := NewWiki(dir, "admin", "secretkey", false, WithLayout(tree.LayoutFlat))
+	if err != nil {
+		t.Fatalf("Failed to create wiki: %v", err)
+	}
+	page, err := w.CreatePage(nil, "Notes", "notes")
+	if err != nil {
+		t.Fatalf("CreatePage failed: %v", err)
+	}
+	if _, err := w.UpdatePage(page.ID, page.Title, page.Slug, "first version"); err != nil {
+		t.Fatalf("UpdatePage failed: %v", err)
+	}
+
+	migration, err := w.MigrateLayout(tree.LayoutFolder)
+	if err != nil {
+		t.Fatalf("MigrateLayout failed: %v", err)
+	}
+	if migration.From != tree.LayoutFlat || migration.To != tree.LayoutFolder || migration.Moved != 2 {
+		t.Errorf("unexpected migration: %+v", migration)
+	}
+	if _, err := os.Stat(filepath.Join(dir, "root", "notes", "index.md")); err != nil {
+		t.Errorf("expected notes/index.md: %v", err)
+	}
+
+	// the history continues at the new file
+	entries, _, _, err := w.GetPageHistory("notes", search.HistoryOptions{IncludeContent: true})
+	if err != nil {
+		t.Fatalf("GetPageHistory failed: %v", err)
+	}
+	if len(entries) < 2 || entries[0].Status != search.FileStatusMoved || entries[1].Content != "first version" {
+		t.Errorf("expected the move on top of the previous history, got %+v", entries)
+	}
+	w.Close()
+
+	// the data dir keeps its layout, another one has to be migrated to first
+	if _, err := NewWiki(dir, "admin", "secretkey", false, WithLayout(tree.LayoutFlat)); err == nil {
+		t.Fatal("expected an error for a layout which differs from the data dir")
+	}
+	reopened, err := NewWiki(dir, "admin", "secretkey", false)
+	if err != nil {
+		t.Fatalf("Failed to reopen wiki: %v", err)
+	}
+	defer reopened.Close()
+	if reopened.Layout() != tree.LayoutFolder {
+		t.Errorf("expected the folder layout, got %s", reopened.Layout())
+	}
+}
+
+func TestWiki_PageFolderAssets(t *testing.T) {
+	dir := t.TempDir()
+	w, err := NewWiki(dir, "admin", "secretkey", false, WithPageFolderAssets(true))
+	if err != nil {
+		t.Fatalf("Failed to create wiki: %v", err)
+	}
+	defer w.Close()
+
+	page, err := w.CreatePage(nil, "Guide", "guide")
+	if err != nil {
+		t.Fatalf("CreatePage failed: %v", err)
+	}
+	file, _, err := test_utils.CreateMultipartFile("diagram.png", []byte("image content"))
+	if err != nil {
+		t.Fatalf("Failed to create test file: %v", err)
+	}
+	defer file.Close()
+
+	url, err := w.UploadAsset(page.ID, file, "diagram.png")
+	if err != nil {
+		t.Fatalf("UploadAsset failed: %v", err)
+	}
+	if url != "./assets/diagram.png" {
+		t.Errorf("expected a relative link, got %q", url)
+	}
+	// the page becomes a folder to hold its assets
+	if _, err := os.Stat(filepath.Join(dir, "root", "guide", "assets", "diagram.png")); err != nil {
+		t.Fatalf("expected the asset next to the page: %v", err)
+	}
+	if _, err := w.UpdatePage(page.ID, page.Title, page.Slug, "# Guide\n\n![Diagram](./assets/diagram.png)\n"); err != nil {
+		t.Fatalf("UpdatePage failed: %v", err)
+	}
+
+	if fullPath, err := w.PageFolderAsset(page.ID, "diagram.png"); err != nil || !strings.HasSuffix(fullPath, "guide/assets/diagram.png") {
+		t.Errorf("expected the asset file, got %q %v", fullPath, err)
+	}
+	if _, err := w.PageFolderAsset(page.ID, "../index.md"); err == nil {
+		t.Error("expected an error for a path outside of the assets folder")
+	}
+
+	rendered, err := w.RenderPage(t.Context(), page.ID)
+	if err != nil {
+		t.Fatalf("RenderPage failed: %v", err)
+	}
+	if !strings.Contains(rendered.HTML, `src="/assets/`+page.ID+`/diagram.png"`) {
+		t.Errorf("expected the asset link to be resolved, got %s", rendered.HTML)
+	}
+
+	exportDir := filepath.Join(t.TempDir(), "export")
+	if _, err := w.Export(exportDir); err != nil {
+		t.Fatalf("Export failed: %v", err)
+	}
+	if _, err := os.Stat(filepath.Join(exportDir, "guide", "assets", "diagram.png")); err != nil {
+		t.Errorf("expected the asset next to the exported page: %v", err)
+	}
+
+	exported, err := w.ExportPage(t.Context(), page.ID, "md", false)
+	if err != nil {
+		t.Fatalf("ExportPage failed: %v", err)
+	}
+	if exported.Filename != "guide.zip" {
+		t.Errorf("expected the page to be bundled with its asset, got %s", exported.Filename)
+	}
+}
+
+func TestWiki_MoveAsset(t *testing.T) {
+	w := setupTestWiki(t)
+	defer w.Close()
+
+	page, _ := w.CreatePage(nil, "Guide", "guide")
+	other, _ := w.CreatePage(nil, "Other", "other")
+	file, _, err := test_utils.CreateMultipartFile("diagram.png", []byte("image content"))
+	if err != nil {
+		t.Fatalf("Failed to create test file: %v", err)
+	}
+	defer file.Close()
+	url, err := w.UploadAsset(page.ID, file, "diagram.png")
+	if err != nil {
+		t.Fatalf("UploadAsset failed: %v", err)
+	}
+	if _, err := w.UpdatePage(page.ID, page.Title, page.Slug, "![Diagram]("+url+")\n"); err != nil {
+		t.Fatalf("UpdatePage failed: %v", err)
+	}
+
+	renamed, err := w.MoveAsset(page.ID, "diagram.png", "", "overview.png")
+	if err != nil {
+		t.Fatalf("MoveAsset failed: %v", err)
+	}
+	updated, _ := w.GetPage(page.ID)
+	if updated.Content != "![Diagram]("+renamed+")\n" {
+		t.Errorf("expected the link to be renamed, got %q", updated.Content)
+	}
+
+	moved, err := w.MoveAsset(page.ID, "overview.png", other.ID, "")
+	if err != nil {
+		t.Fatalf("MoveAsset failed: %v", err)
+	}
+	if moved != "/assets/"+other.ID+"/overview.png" {
+		t.Errorf("unexpected url %q", moved)
+	}
+	updated, _ = w.GetPage(page.ID)
+	if updated.Content != "![Diagram]("+moved+")\n" {
+		t.Errorf("expected the link to point to the other page, got %q", updated.Content)
+	}
+	if files, _ := w.ListAssets(page.ID); len(files) != 0 {
+		t.Errorf("expected no assets left at the page, got %v", files)
+	}
+}
+
+func TestWiki_UnusedAssets(t *testing.T) {
+	w := setupTestWiki(t)
+	defer w.Close()
+
+	page, _ := w.CreatePage(nil, "Guide", "guide")
+	other, _ := w.CreatePage(nil, "Other", "other")
+	for _, name := range []string{"used.png", "linked-elsewhere.png", "unused.png", "stale.png"} {
+		file, _, err := test_utils.CreateMultipartFile(name, []byte(name))
+		if err != nil {
+			t.Fatalf("Failed to create test file: %v", err)
+		}
+		if _, err := w.UploadAsset(page.ID, file, name); err != nil {
+			t.Fatalf("UploadAsset failed: %v", err)
+		}
+		file.Close()
+	}
+	if _, err := w.UpdatePage(page.ID, page.Title, page.Slug, "![Used](/assets/"+page.ID+"/used.png)\n"); err != nil {
+		t.Fatalf("UpdatePage failed: %v", err)
+	}
+	if _, err := w.UpdatePage(other.ID, other.Title, other.Slug, `<img src="/assets/`+page.ID+`/linked-elsewhere.png">`); err != nil {
+		t.Fatalf("UpdatePage failed: %v", err)
+	}
+
+	report, err := w.UnusedAssets()
+	if err != nil {
+		t.Fatalf("UnusedAssets failed: %v", err)
+	}
+	if len(report.Assets) != 2 || report.Assets[0].Name != "stale.png" || report.Assets[1].Name != "unused.png" {
+		t.Fatalf("unexpected unused assets: %+v", report.Assets)
+	}
+	if report.TotalSize != int64(len("stale.png")+len("unused.png")) {
+		t.Errorf("unexpected total size %d", report.TotalSize)
+	}
+
+	dryRun, err := w.DeleteUnusedAssets(nil, true)
+	if err != nil || len(dryRun.Deleted) != 2 {
+		t.Fatalf("expected a dry run of 2 assets, got %+v %v", dryRun, err)
+	}
+	if files, _ := w.ListAssets(page.ID); len(files) != 4 {
+		t.Errorf("expected the dry run to keep the assets, got %v", files)
+	}
+
+	used := "/assets/" + page.ID + "/used.png"
+	unused := "/assets/" + page.ID + "/unused.png"
+	result, err := w.DeleteUnusedAssets([]string{unused, used}, false)
+	if err != nil {
+		t.Fatalf("DeleteUnusedAssets failed: %v", err)
+	}
+	if len(result.Deleted) != 1 || result.Deleted[0].URL != unused || len(result.Skipped) != 1 || result.Skipped[0] != used {
+		t.Errorf("unexpected cleanup: %+v", result)
+	}
+	if files, _ := w.ListAssets(page.ID); len(files) != 3 {
+		t.Errorf("expected 3 assets left, got %v", files)
+	}
+}
+
+func TestWiki_UploadScalesDownImages(t *testing.T) {
+	w := setupTestWiki(t)
+	defer w.Close()
+
+	s, _ := w.GetSettings()
+	if !s.StripImageMetadata || s.ImageMaxDimension != 0 {
+		t.Fatalf("expected the metadata to be stripped and the size to be kept by default, got %+v", s)
+	}
+	s.ImageMaxDimension = 50
+	if _, err := w.UpdateSettings(s); err != nil {
+		t.Fatalf("UpdateSettings failed: %v", err)
+	}
+
+	page, _ := w.CreatePage(nil, "Screenshots", "screenshots")
+	var buf bytes.Buffer
+	_ = png.Encode(&buf, image.NewRGBA(image.Rect(0, 0, 200, 100)))
+	file, _, err := test_utils.CreateMultipartFile("screen.png", buf.Bytes())
+	if err != nil {
+		t.Fatalf("Failed to create test file: %v", err)
+	}
+	defer file.Close()
+	if _, err := w.UploadAsset(page.ID, file, "screen.png"); err != nil {
+		t.Fatalf("UploadAsset failed: %v", err)
+	}
+
+	stored, err := w.AssetFile(page.ID, "screen.png")
+	if err != nil {
+		t.Fatalf("AssetFile failed: %v", err)
+	}
+	f, _ := os.Open(stored)
+	defer f.Close()
+	config, err := png.DecodeConfig(f)
+	if err != nil || config.Width != 50 || config.Height != 25 {
+		t.Errorf("expected the image to be scaled down to 50x25, got %+v %v", config, err)
+	}
+}
+
+func TestWiki_PasteImage(t *testing.T) {
+	w := setupTestWiki(t)
+	defer w.Close()
+
+	page, _ := w.CreatePage(nil, "Notes", "notes")
+	var buf bytes.Buffer
+	_ = png.Encode(&buf, image.NewRGBA(image.Rect(0, 0, 2, 2)))
+
+	pasted, err := w.PasteImage(page.ID, buf.Bytes(), "")
+	if err != nil {
+		t.Fatalf("PasteImage failed: %v", err)
+	}
+	if !strings.HasPrefix(pasted.File, "/assets/"+page.ID+"/screenshot-") || !strings.HasSuffix(pasted.File, ".png") {
+		t.Errorf("expected a timestamped name, got %s", pasted.File)
+	}
+	if !strings.HasPrefix(pasted.Markdown, "![screenshot-") || !strings.HasSuffix(pasted.Markdown, "]("+pasted.File+")") {
+		t.Errorf("unexpected markdown %s", pasted.Markdown)
+	}
+
+	dataURL := "data:image/png;base64," + base64.StdEncoding.EncodeToString(buf.Bytes())
+	pasted, err = w.PasteImage(page.ID, []byte(dataURL), "Build [failed].png")
+	if err != nil {
+		t.Fatalf("PasteImage failed for a data URL: %v", err)
+	}
+	if pasted.File != "/assets/"+page.ID+"/build-failed.png" || pasted.Markdown != `![Build \[failed\]](`+pasted.File+")" {
+		t.Errorf("unexpected pasted image %+v", pasted)
+	}
+
+	for _, invalid := range []string{"<svg onload=alert(1)></svg>", "data:image/png,plain", "data:image/png;base64,!!"} {
+		if _, err := w.PasteImage(page.ID, []byte(invalid), ""); !errors.As(err, new(*verrors.ValidationErrors)) {
+			t.Errorf("expected a validation error for %q, got %v", invalid, err)
+		}
+	}
+}
+
+func TestWiki_RestructureTree(t *testing.T) {
+	w := setupTestWiki(t)
+	defer w.Close()
+
+	docs, _ := w.CreatePage(nil, "Docs", "docs")
+	guide, _ := w.CreatePage(nil, "Guide", "guide")
+	intro, _ := w.CreatePage(&docs.ID, "Intro", "intro")
+	setup, _ := w.CreatePage(&docs.ID, "Setup", "setup")
+	welcome := w.tree.GetTree().Children[0]
+
+	// a missing page is rejected before anything is moved
+	_, err := w.RestructureTree(Restructure{Children: map[string][]string{
+		"root":   {welcome.ID, guide.ID, docs.ID},
+		guide.ID: {setup.ID},
+	}})
+	if _, ok := err.(*verrors.ValidationErrors); !ok {
+		t.Fatalf("expected a validation error for the missing page, got %v", err)
+	}
+
+	// a cycle is rejected
+	_, err = w.RestructureTree(Restructure{Children: map[string][]string{
+		"root":   {welcome.ID, docs.ID},
+		guide.ID: {intro.ID},
+		intro.ID: {guide.ID, setup.ID},
+	}})
+	if _, ok := err.(*verrors.ValidationErrors); !ok {
+		t.Fatalf("expected a validation error for the cycle, got %v", err)
+	}
+
+	// the docs page moves below the guide, setup moves up and becomes the first page
+	result, err := w.RestructureTree(Restructure{Children: map[string][]string{
+		"root":   {setup.ID, welcome.ID, guide.ID},
+		guide.ID: {docs.ID},
+		docs.ID:  {intro.ID},
+	}})
+	if err != nil {
+		t.Fatalf("RestructureTree failed: %v", err)
+	}
+	if len(result.Moved) != 2 {
+		t.Errorf("expected 2 moved pages, got %v", result.Moved)
+	}
+	if _, err := w.FindByPath("guide/docs/intro"); err != nil {
+		t.Errorf("expected intro below guide/docs: %v", err)
+	}
+	if first := w.tree.GetTree().Children[0]; first.ID != setup.ID {
+		t.Errorf("expected setup to be the first page, got %s", first.Slug)
+	}
+	if redirect, err := w.ResolveRedirect("docs/intro"); err != nil || redirect.To != "guide/docs/intro" {
+		t.Errorf("expected a redirect from the old route, got %+v %v", redirect, err)
+	}
+}
+
+func TestWiki_RestructureTree_UndoesMovesOnFailure(t *testing.T) {
+	w := setupTestWiki(t)
+	defer w.Close()
+
+	a, _ := w.CreatePage(nil, "A", "a")
+	b, _ := w.CreatePage(nil, "B", "b")
+	aNotes, _ := w.CreatePage(&a.ID, "Notes", "notes")
+	bNotes, _ := w.CreatePage(&b.ID, "Notes", "notes")
+	aExtra, _ := w.CreatePage(&a.ID, "Extra", "extra")
+	welcome := w.tree.GetTree().Children[0]
+
+	// the extra page can move, but the notes pages block each other
+	_, err := w.RestructureTree(Restructure{Children: map[string][]string{
+		"root": {welcome.ID, a.ID, b.ID},
+		a.ID:   {bNotes.ID},
+		b.ID:   {aNotes.ID, aExtra.ID},
+	}})
+	if !errors.Is(err, tree.ErrPageAlreadyExists) {
+		t.Fatalf("expected ErrPageAlreadyExists, got %v", err)
+	}
+	if _, err := w.FindByPath("a/extra"); err != nil {
+		t.Errorf("expected the applied move to be undone: %v", err)
+	}
+}
+
+func TestWiki_CheckRawHTML(t *testing.T) {
+	w := setupTestWiki(t)
+
+	page, _ := w.CreatePage(nil, "Home", "home")
+	editor := &auth.User{Role: auth.RoleEditor}
+	admin := &auth.User{Role: auth.RoleAdmin}
+	script := "<script>track()</script>\n"
+
+	// sanitized raw HTML is harmless, everybody may write it
+	if err := w.CheckRawHTML(editor, page.ID, script); err != nil {
+		t.Fatalf("expected no check while raw HTML is sanitized, got %v", err)
+	}
+
+	s, _ := w.GetSettings()
+	s.RawHTML = staticsite.RawHTMLTrusted
+	if _, err := w.UpdateSettings(s); err != nil {
+		t.Fatalf("UpdateSettings failed: %v", err)
+	}
+
+	var ve *verrors.ValidationErrors
+	if err := w.CheckRawHTML(editor, page.ID, "<sup>2</sup> "+script); !errors.As(err, &ve) || !strings.HasSuffix(ve.Errors[0].Message, ": <script>") {
+		t.Fatalf("expected a validation error for the editor, got %v", err)
+	}
+	if err := w.CheckRawHTML(admin, page.ID, script); err != nil {
+		t.Fatalf("expected the admin to be trusted, got %v", err)
+	}
+	if _, err := w.UpdatePageForUser(editor, page.ID, "Home", "home", script); !errors.As(err, &ve) {
+		t.Fatalf("expected the save of the editor to be refused, got %v", err)
+	}
+	if saved, _ := w.GetPage(page.ID); strings.Contains(saved.Content, "script") {
+		t.Fatalf("expected the refused save to leave the page unchanged, got %q", saved.Content)
+	}
+	// the editor may keep the script added by the admin
+	if _, err := w.UpdatePage(page.ID, "Home", "home", script); err != nil {
+		t.Fatalf("UpdatePage failed: %v", err)
+	}
+	if err := w.CheckRawHTML(editor, page.ID, "Intro\n\n"+script); err != nil {
+		t.Errorf("expected existing raw HTML to be allowed, got %v", err)
+	}
+	rendered, _ := w.RenderPage(t.Context(), page.ID)
+	if !strings.Contains(rendered.HTML, script) {
+		t.Errorf("expected trusted raw HTML to be kept, got %s", rendered.HTML)
+	}
+
+	s.RawHTML = "unsafe"
+	if _, err := w.UpdateSettings(s); !errors.As(err, &ve) {
+		t.Errorf("expected a validation error for an unknown mode, got %v", err)
+	}
+}
+
+func TestWiki_Drafts(t *testing.T) {
+	w := setupTestWiki(t)
+	defer w.Close()
+
+	parent, _ := w.CreatePage(nil, "Parent", "parent")
+	child, _ := w.CreatePage(&parent.ID, "Child", "child")
+	for _, id := range []string{parent.ID, child.ID} {
+		if _, err := w.SaveDraft("u1", id, "Draft", "# Unsaved"); err != nil {
+			t.Fatalf("SaveDraft failed: %v", err)
+		}
+	}
+
+	s, _ := w.GetSettings()
+	s.MaxRequestSize = 1024
+	if _, err := w.UpdateSettings(s); err != nil {
+		t.Fatalf("UpdateSettings failed: %v", err)
+	}
+	if _, err := w.SaveDraft("u1", parent.ID, "Draft", strings.Repeat("a", 1024)); !errors.Is(err, ErrDraftTooLarge) {
+		t.Errorf("expected ErrDraftTooLarge, got %v", err)
+	}
+
+	// deleting the parent recursively removes the drafts of the whole subtree
+	if err := w.DeletePage(parent.ID, true); err != nil {
+		t.Fatalf("DeletePage failed: %v", err)
+	}
+	for _, id := range []string{parent.ID, child.ID} {
+		if _, err := w.drafts.Get("u1", id); !errors.Is(err, drafts.ErrDraftNotFound) {
+			t.Errorf("expected the draft of %s to be removed, got %v", id, err)
+		}
+	}
+}
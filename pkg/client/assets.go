@@ -0,0 +1,99 @@
+package client
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"mime/multipart"
+	"net/http"
+	"net/url"
+)
+
+// UploadAsset uploads a file to a page and returns the URL of the stored asset.
+// The content is buffered in memory so the request can be retried after a token refresh.
+func (c *Client) UploadAsset(ctx context.Context, pageID, filename string, content io.Reader) (string, error) {
+	var buf bytes.Buffer
+	form := multipart.NewWriter(&buf)
+	part, err := form.CreateFormFile("file", filename)
+	if err != nil {
+		return "", err
+	}
+	if _, err := io.Copy(part, content); err != nil {
+		return "", fmt.Errorf("read asset: %w", err)
+	}
+	if err := form.Close(); err != nil {
+		return "", err
+	}
+
+	req := &request{
+		method:      http.MethodPost,
+		path:        assetsPath(pageID),
+		body:        buf.Bytes(),
+		contentType: form.FormDataContentType(),
+	}
+	var resp struct {
+		File string `json:"file"`
+	}
+	if err := c.do(ctx, req, &resp); err != nil {
+		return "", err
+	}
+	return resp.File, nil
+}
+
+// ListAssets returns the URLs of the assets of a page
+func (c *Client) ListAssets(ctx context.Context, pageID string) ([]string, error) {
+	var resp struct {
+		Files []string `json:"files"`
+	}
+	if err := c.do(ctx, &request{method: http.MethodGet, path: assetsPath(pageID)}, &resp); err != nil {
+		return nil, err
+	}
+	return resp.Files, nil
+}
+
+// RenameAsset renames an asset of a page and returns its new URL
+func (c *Client) RenameAsset(ctx context.Context, pageID, oldFilename, newFilename string) (string, error) {
+	req, err := jsonRequest(http.MethodPut, assetsPath(pageID)+"/rename", map[string]string{
+		"old_filename": oldFilename,
+		"new_filename": newFilename,
+	})
+	if err != nil {
+		return "", err
+	}
+	var resp struct {
+		URL string `json:"url"`
+	}
+	if err := c.do(ctx, req, &resp); err != nil {
+		return "", err
+	}
+	return resp.URL, nil
+}
+
+// MoveAsset renames an asset (newFilename) or moves it to another page (targetPageID) and
+// returns its new URL. The links to it in the page which owned it are rewritten.
+func (c *Client) MoveAsset(ctx context.Context, pageID, filename, targetPageID, newFilename string) (string, error) {
+	req, err := jsonRequest(http.MethodPut, assetsPath(pageID)+"/"+url.PathEscape(filename), map[string]string{
+		"name":   newFilename,
+		"pageId": targetPageID,
+	})
+	if err != nil {
+		return "", err
+	}
+	var resp struct {
+		URL string `json:"url"`
+	}
+	if err := c.do(ctx, req, &resp); err != nil {
+		return "", err
+	}
+	return resp.URL, nil
+}
+
+// DeleteAsset deletes an asset of a page
+func (c *Client) DeleteAsset(ctx context.Context, pageID, filename string) error {
+	return c.do(ctx, &request{method: http.MethodDelete, path: assetsPath(pageID) + "/" + url.PathEscape(filename)}, nil)
+}
+
+func assetsPath(pageID string) string {
+	return "/pages/" + url.PathEscape(pageID) + "/assets"
+}
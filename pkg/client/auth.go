@@ -0,0 +1,70 @@
+package client
+
+import (
+	"context"
+	"errors"
+	"net/http"
+)
+
+var ErrNoRefreshToken = errors.New("no refresh token, log in first")
+
+// Login authenticates with a username or email and keeps the returned tokens for later requests
+func (c *Client) Login(ctx context.Context, identifier, password string) (*AuthToken, error) {
+	req, err := jsonRequest(http.MethodPost, "/auth/login", map[string]string{
+		"identifier": identifier,
+		"password":   password,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	var token AuthToken
+	if err := c.do(ctx, req, &token); err != nil {
+		return nil, err
+	}
+	c.setTokens(&token)
+	return &token, nil
+}
+
+// Refresh exchanges the refresh token for a new pair of tokens
+func (c *Client) Refresh(ctx context.Context) error {
+	c.mu.RLock()
+	refreshToken := c.refreshToken
+	c.mu.RUnlock()
+	if refreshToken == "" {
+		return ErrNoRefreshToken
+	}
+
+	req, err := jsonRequest(http.MethodPost, "/auth/refresh-token", map[string]string{"token": refreshToken})
+	if err != nil {
+		return err
+	}
+	resp, err := c.send(ctx, req, "")
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode >= http.StatusBadRequest {
+		return newAPIError(resp)
+	}
+
+	var token AuthToken
+	if err := decodeJSON(resp, &token); err != nil {
+		return err
+	}
+	c.setTokens(&token)
+	return nil
+}
+
+func (c *Client) canRefresh() bool {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return c.refreshToken != ""
+}
+
+func (c *Client) setTokens(token *AuthToken) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.token = token.Token
+	c.refreshToken = token.RefreshToken
+}
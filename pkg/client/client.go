@@ -0,0 +1,230 @@
+// Package client is a Go client for the LeafWiki REST API.
+//
+// It covers authentication, pages, the page tree, search and assets, so automation tools and
+// CI scripts can talk to a wiki without hand-rolling HTTP calls:
+//
+//	c := client.New("https://wiki.example.com")
+//	if err := c.Login(ctx, "admin", "password"); err != nil {
+//		log.Fatal(err)
+//	}
+//	page, err := c.GetPageByPath(ctx, "docs/getting-started")
+//
+// After Login the client refreshes an expired access token and retries the request once.
+package client
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+)
+
+// Client talks to a single LeafWiki instance. It is safe for concurrent use.
+type Client struct {
+	baseURL    string
+	httpClient *http.Client
+	userAgent  string
+
+	mu           sync.RWMutex
+	token        string
+	refreshToken string
+}
+
+// Option configures optional behaviour of a Client
+type Option func(*Client)
+
+// WithHTTPClient sets the HTTP client used for requests, http.DefaultClient by default
+func WithHTTPClient(hc *http.Client) Option {
+	return func(c *Client) {
+		c.httpClient = hc
+	}
+}
+
+// WithToken sets an access token, e.g. one obtained by an earlier Login
+func WithToken(token string) Option {
+	return func(c *Client) {
+		c.token = token
+	}
+}
+
+// WithUserAgent sets the User-Agent header sent with every request
+func WithUserAgent(userAgent string) Option {
+	return func(c *Client) {
+		c.userAgent = userAgent
+	}
+}
+
+// New creates a client for the wiki at baseURL, e.g. https://wiki.example.com
+func New(baseURL string, opts ...Option) *Client {
+	c := &Client{
+		baseURL:    strings.TrimRight(baseURL, "/"),
+		httpClient: http.DefaultClient,
+		userAgent:  "leafwiki-go-client",
+	}
+	for _, opt := range opts {
+		opt(c)
+	}
+	return c
+}
+
+// Token returns the current access token
+func (c *Client) Token() string {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return c.token
+}
+
+// SetToken replaces the access token and drops the refresh token
+func (c *Client) SetToken(token string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.token = token
+	c.refreshToken = ""
+}
+
+// request describes an API call. body is sent as is, so it can be replayed after a token refresh.
+type request struct {
+	method      string
+	path        string
+	query       url.Values
+	body        []byte
+	contentType string
+}
+
+func jsonRequest(method, path string, payload any) (*request, error) {
+	req := &request{method: method, path: path}
+	if payload != nil {
+		body, err := json.Marshal(payload)
+		if err != nil {
+			return nil, fmt.Errorf("encode request: %w", err)
+		}
+		req.body = body
+		req.contentType = "application/json"
+	}
+	return req, nil
+}
+
+// do sends req and decodes the JSON response into out, if out is not nil
+func (c *Client) do(ctx context.Context, req *request, out any) error {
+	resp, err := c.send(ctx, req, c.Token())
+	if err != nil {
+		return err
+	}
+
+	if resp.StatusCode == http.StatusUnauthorized && c.canRefresh() {
+		resp.Body.Close()
+		if err := c.Refresh(ctx); err != nil {
+			return err
+		}
+		if resp, err = c.send(ctx, req, c.Token()); err != nil {
+			return err
+		}
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode >= http.StatusBadRequest {
+		return newAPIError(resp)
+	}
+	if out == nil || resp.StatusCode == http.StatusNoContent {
+		return nil
+	}
+	return decodeJSON(resp, out)
+}
+
+func decodeJSON(resp *http.Response, out any) error {
+	if err := json.NewDecoder(resp.Body).Decode(out); err != nil {
+		return fmt.Errorf("decode response: %w", err)
+	}
+	return nil
+}
+
+func (c *Client) send(ctx context.Context, req *request, token string) (*http.Response, error) {
+	u := c.baseURL + "/api" + req.path
+	if len(req.query) > 0 {
+		u += "?" + req.query.Encode()
+	}
+
+	var body io.Reader
+	if req.body != nil {
+		body = bytes.NewReader(req.body)
+	}
+	httpReq, err := http.NewRequestWithContext(ctx, req.method, u, body)
+	if err != nil {
+		return nil, err
+	}
+	httpReq.Header.Set("Accept", "application/json")
+	if req.contentType != "" {
+		httpReq.Header.Set("Content-Type", req.contentType)
+	}
+	if c.userAgent != "" {
+		httpReq.Header.Set("User-Agent", c.userAgent)
+	}
+	if token != "" {
+		httpReq.Header.Set("Authorization", "Bearer "+token)
+	}
+	return c.httpClient.Do(httpReq)
+}
+
+// APIError is returned for responses with an error status code
+type APIError struct {
+	StatusCode int
+	Message    string
+	// Fields holds the messages of a validation error, keyed by field name
+	Fields map[string]string
+}
+
+func (e *APIError) Error() string {
+	if e.Message == "" {
+		return fmt.Sprintf("leafwiki: %s", http.StatusText(e.StatusCode))
+	}
+	if len(e.Fields) > 0 {
+		fields := make([]string, 0, len(e.Fields))
+		for field, msg := range e.Fields {
+			fields = append(fields, field+": "+msg)
+		}
+		return fmt.Sprintf("leafwiki: %s (%s)", e.Message, strings.Join(fields, ", "))
+	}
+	return fmt.Sprintf("leafwiki: %s", e.Message)
+}
+
+func newAPIError(resp *http.Response) error {
+	apiErr := &APIError{StatusCode: resp.StatusCode}
+	var body struct {
+		Error  string `json:"error"`
+		Fields []struct {
+			Field   string `json:"field"`
+			Message string `json:"message"`
+		} `json:"fields"`
+	}
+	if err := json.NewDecoder(io.LimitReader(resp.Body, 1<<20)).Decode(&body); err == nil {
+		apiErr.Message = body.Error
+		if len(body.Fields) > 0 {
+			apiErr.Fields = make(map[string]string, len(body.Fields))
+			for _, f := range body.Fields {
+				apiErr.Fields[f.Field] = f.Message
+			}
+		}
+	}
+	return apiErr
+}
+
+// IsNotFound reports whether err is an APIError with status 404
+func IsNotFound(err error) bool {
+	return hasStatus(err, http.StatusNotFound)
+}
+
+// IsUnauthorized reports whether err is an APIError with status 401
+func IsUnauthorized(err error) bool {
+	return hasStatus(err, http.StatusUnauthorized)
+}
+
+func hasStatus(err error, status int) bool {
+	var apiErr *APIError
+	return errors.As(err, &apiErr) && apiErr.StatusCode == status
+}
@@ -0,0 +1,187 @@
+package client
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/Gomez12/wiki/pkg/leafwiki"
+)
+
+func newTestClient(t *testing.T) *Client {
+	t.Helper()
+	srv, err := leafwiki.New(t.TempDir(), "secretkey",
+		leafwiki.WithAdminPassword("admin-password"),
+		leafwiki.WithSearchIndexing(false),
+	)
+	if err != nil {
+		t.Fatalf("leafwiki.New failed: %v", err)
+	}
+	ts := httptest.NewServer(srv.Handler())
+	t.Cleanup(func() {
+		ts.Close()
+		srv.Close()
+	})
+
+	c := New(ts.URL+"/", WithHTTPClient(ts.Client()))
+	if _, err := c.Login(context.Background(), "admin", "admin-password"); err != nil {
+		t.Fatalf("Login failed: %v", err)
+	}
+	return c
+}
+
+func TestClient_PageLifecycle(t *testing.T) {
+	ctx := context.Background()
+	c := newTestClient(t)
+
+	docs, err := c.CreatePage(ctx, "", "Docs", "docs")
+	if err != nil {
+		t.Fatalf("CreatePage failed: %v", err)
+	}
+	guide, err := c.EnsurePage(ctx, "docs/guide", "Guide")
+	if err != nil {
+		t.Fatalf("EnsurePage failed: %v", err)
+	}
+	if _, err := c.UpdatePage(ctx, guide.ID, "Guide", "guide", "# Guide"); err != nil {
+		t.Fatalf("UpdatePage failed: %v", err)
+	}
+
+	page, err := c.GetPageByPath(ctx, "docs/guide")
+	if err != nil {
+		t.Fatalf("GetPageByPath failed: %v", err)
+	}
+	if page.ID != guide.ID || page.Content != "# Guide" || page.Path != "docs/guide" {
+		t.Errorf("unexpected page: %+v", page)
+	}
+
+	page, err = c.UpdatePageFrontmatter(ctx, guide.ID, "Guide", "guide", "# Guide", map[string]any{"owner": "ops", "review-by": "2025-01-01"})
+	if err != nil {
+		t.Fatalf("UpdatePageFrontmatter failed: %v", err)
+	}
+	if page.Frontmatter["owner"] != "ops" || page.Content != "---\nowner: ops\nreview-by: \"2025-01-01\"\n---\n# Guide" {
+		t.Errorf("unexpected page: %+v", page)
+	}
+
+	children, err := c.GetChildren(ctx, "docs")
+	if err != nil {
+		t.Fatalf("GetChildren failed: %v", err)
+	}
+	if len(children) != 1 || children[0].ID != guide.ID {
+		t.Errorf("unexpected children: %+v", children)
+	}
+
+	if err := c.MovePage(ctx, guide.ID, ""); err != nil {
+		t.Fatalf("MovePage failed: %v", err)
+	}
+	tree, err := c.GetTree(ctx, TreeOptions{Depth: 1})
+	if err != nil {
+		t.Fatalf("GetTree failed: %v", err)
+	}
+	moved := false
+	for _, child := range tree.Children {
+		if child.ID == guide.ID {
+			moved = child.Path == "guide"
+		}
+	}
+	if !moved {
+		t.Errorf("expected guide at the root after move")
+	}
+
+	if err := c.DeletePage(ctx, docs.ID, false); err != nil {
+		t.Fatalf("DeletePage failed: %v", err)
+	}
+	if _, err := c.GetPage(ctx, docs.ID); !IsNotFound(err) {
+		t.Errorf("expected not found after delete, got %v", err)
+	}
+}
+
+func TestClient_ValidationError(t *testing.T) {
+	c := newTestClient(t)
+
+	_, err := c.CreatePage(context.Background(), "", "Invalid", "Not A Slug")
+	apiErr, ok := err.(*APIError)
+	if !ok {
+		t.Fatalf("expected APIError, got %v", err)
+	}
+	if apiErr.StatusCode != http.StatusBadRequest || apiErr.Fields["slug"] == "" {
+		t.Errorf("unexpected error: %+v", apiErr)
+	}
+}
+
+func TestClient_Assets(t *testing.T) {
+	ctx := context.Background()
+	c := newTestClient(t)
+
+	page, err := c.CreatePage(ctx, "", "Files", "files")
+	if err != nil {
+		t.Fatalf("CreatePage failed: %v", err)
+	}
+	if _, err := c.UploadAsset(ctx, page.ID, "notes.txt", strings.NewReader("hello")); err != nil {
+		t.Fatalf("UploadAsset failed: %v", err)
+	}
+	renamed, err := c.RenameAsset(ctx, page.ID, "notes.txt", "readme.txt")
+	if err != nil {
+		t.Fatalf("RenameAsset failed: %v", err)
+	}
+	if !strings.HasSuffix(renamed, "readme.txt") {
+		t.Errorf("unexpected asset url %q", renamed)
+	}
+
+	moved, err := c.MoveAsset(ctx, page.ID, "readme.txt", "", "notes.txt")
+	if err != nil {
+		t.Fatalf("MoveAsset failed: %v", err)
+	}
+	if !strings.HasSuffix(moved, "/notes.txt") {
+		t.Errorf("unexpected asset url %q", moved)
+	}
+
+	files, err := c.ListAssets(ctx, page.ID)
+	if err != nil {
+		t.Fatalf("ListAssets failed: %v", err)
+	}
+	if len(files) != 1 {
+		t.Fatalf("expected 1 asset, got %v", files)
+	}
+
+	if err := c.DeleteAsset(ctx, page.ID, "notes.txt"); err != nil {
+		t.Fatalf("DeleteAsset failed: %v", err)
+	}
+	if files, _ = c.ListAssets(ctx, page.ID); len(files) != 0 {
+		t.Errorf("expected no assets after delete, got %v", files)
+	}
+}
+
+func TestClient_RefreshesExpiredToken(t *testing.T) {
+	ctx := context.Background()
+	c := newTestClient(t)
+
+	c.mu.Lock()
+	c.token = "expired"
+	c.mu.Unlock()
+
+	if _, err := c.CreatePage(ctx, "", "Refreshed", "refreshed"); err != nil {
+		t.Fatalf("expected request to succeed after refresh, got %v", err)
+	}
+	if c.Token() == "expired" {
+		t.Errorf("expected a new access token")
+	}
+
+	c.SetToken("expired")
+	if _, err := c.CreatePage(ctx, "", "Other", "other"); !IsUnauthorized(err) {
+		t.Errorf("expected unauthorized without refresh token, got %v", err)
+	}
+}
+
+func TestClient_Search(t *testing.T) {
+	c := newTestClient(t)
+
+	result, err := c.Search(context.Background(), "anything", 0, 5)
+	if err != nil {
+		t.Fatalf("Search failed: %v", err)
+	}
+	if result.Limit != 5 {
+		t.Errorf("expected limit 5, got %d", result.Limit)
+	}
+}
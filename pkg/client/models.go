@@ -0,0 +1,57 @@
+package client
+
+// User is the public part of a wiki user
+type User struct {
+	ID       string `json:"id"`
+	Username string `json:"username"`
+	Email    string `json:"email"`
+	Role     string `json:"role"`
+}
+
+// AuthToken is returned by Login
+type AuthToken struct {
+	Token        string `json:"token"`
+	RefreshToken string `json:"refresh_token"`
+	User         *User  `json:"user"`
+}
+
+// Page is a page with its content
+type Page struct {
+	ID       string `json:"id"`
+	Title    string `json:"title"`
+	Slug     string `json:"slug"`
+	Path     string `json:"path"`
+	Position int    `json:"position"`
+	Content  string `json:"content"`
+	// Frontmatter holds the fields of the YAML frontmatter of the content
+	Frontmatter map[string]any `json:"frontmatter"`
+}
+
+// Node is an entry of the page tree
+type Node struct {
+	ID       string `json:"id"`
+	Title    string `json:"title"`
+	Slug     string `json:"slug"`
+	Path     string `json:"path"`
+	Position int    `json:"position"`
+	// HasChildren is set even if the children are not included because of a depth limit
+	HasChildren bool    `json:"hasChildren"`
+	Children    []*Node `json:"children"`
+}
+
+// SearchResult is a page of search hits
+type SearchResult struct {
+	Limit  int                `json:"limit"`
+	Offset int                `json:"offset"`
+	Count  int                `json:"count"`
+	Items  []SearchResultItem `json:"items"`
+}
+
+// SearchResultItem is a single search hit
+type SearchResultItem struct {
+	PageID  string  `json:"page_id"`
+	Title   string  `json:"title"`
+	Path    string  `json:"path"`
+	Rank    float64 `json:"rank"`
+	Excerpt string  `json:"excerpt"`
+}
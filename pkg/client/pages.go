@@ -0,0 +1,103 @@
+package client
+
+import (
+	"context"
+	"net/http"
+	"net/url"
+	"strconv"
+)
+
+// GetPage returns the page with the given id
+func (c *Client) GetPage(ctx context.Context, id string) (*Page, error) {
+	var page Page
+	if err := c.do(ctx, &request{method: http.MethodGet, path: "/pages/" + url.PathEscape(id)}, &page); err != nil {
+		return nil, err
+	}
+	return &page, nil
+}
+
+// GetPageByPath returns the page with the given route path, e.g. docs/getting-started
+func (c *Client) GetPageByPath(ctx context.Context, path string) (*Page, error) {
+	req := &request{method: http.MethodGet, path: "/pages/by-path", query: url.Values{"path": {path}}}
+	var page Page
+	if err := c.do(ctx, req, &page); err != nil {
+		return nil, err
+	}
+	return &page, nil
+}
+
+// CreatePage creates an empty page below parentID, or at the root if parentID is empty
+func (c *Client) CreatePage(ctx context.Context, parentID, title, slug string) (*Page, error) {
+	payload := struct {
+		ParentID *string `json:"parentId"`
+		Title    string  `json:"title"`
+		Slug     string  `json:"slug"`
+	}{Title: title, Slug: slug}
+	if parentID != "" {
+		payload.ParentID = &parentID
+	}
+	return c.pageRequest(ctx, http.MethodPost, "/pages", payload)
+}
+
+// EnsurePage creates the page at path together with its missing parents and returns it.
+// An existing page is returned unchanged.
+func (c *Client) EnsurePage(ctx context.Context, path, title string) (*Page, error) {
+	return c.pageRequest(ctx, http.MethodPost, "/pages/ensure", map[string]string{
+		"path":        path,
+		"targetTitle": title,
+	})
+}
+
+// UpdatePage replaces the title, slug and content of a page
+func (c *Client) UpdatePage(ctx context.Context, id, title, slug, content string) (*Page, error) {
+	return c.pageRequest(ctx, http.MethodPut, "/pages/"+url.PathEscape(id), map[string]string{
+		"title":   title,
+		"slug":    slug,
+		"content": content,
+	})
+}
+
+// UpdatePageFrontmatter updates a page and replaces the frontmatter of the content with the fields.
+// Empty fields remove the frontmatter.
+func (c *Client) UpdatePageFrontmatter(ctx context.Context, id, title, slug, content string, fields map[string]any) (*Page, error) {
+	if fields == nil {
+		fields = map[string]any{}
+	}
+	return c.pageRequest(ctx, http.MethodPut, "/pages/"+url.PathEscape(id), map[string]any{
+		"title":       title,
+		"slug":        slug,
+		"content":     content,
+		"frontmatter": fields,
+	})
+}
+
+// DeletePage deletes a page. Pages with children are only deleted if recursive is set.
+func (c *Client) DeletePage(ctx context.Context, id string, recursive bool) error {
+	req := &request{
+		method: http.MethodDelete,
+		path:   "/pages/" + url.PathEscape(id),
+		query:  url.Values{"recursive": {strconv.FormatBool(recursive)}},
+	}
+	return c.do(ctx, req, nil)
+}
+
+// MovePage moves a page below parentID, or to the root if parentID is empty
+func (c *Client) MovePage(ctx context.Context, id, parentID string) error {
+	req, err := jsonRequest(http.MethodPut, "/pages/"+url.PathEscape(id)+"/move", map[string]string{"parentId": parentID})
+	if err != nil {
+		return err
+	}
+	return c.do(ctx, req, nil)
+}
+
+func (c *Client) pageRequest(ctx context.Context, method, path string, payload any) (*Page, error) {
+	req, err := jsonRequest(method, path, payload)
+	if err != nil {
+		return nil, err
+	}
+	var page Page
+	if err := c.do(ctx, req, &page); err != nil {
+		return nil, err
+	}
+	return &page, nil
+}
@@ -0,0 +1,22 @@
+package client
+
+import (
+	"context"
+	"net/http"
+	"net/url"
+	"strconv"
+)
+
+// Search runs a full-text search. A limit of 0 uses the server default.
+func (c *Client) Search(ctx context.Context, query string, offset, limit int) (*SearchResult, error) {
+	values := url.Values{"q": {query}, "offset": {strconv.Itoa(offset)}}
+	if limit > 0 {
+		values.Set("limit", strconv.Itoa(limit))
+	}
+
+	var result SearchResult
+	if err := c.do(ctx, &request{method: http.MethodGet, path: "/search", query: values}, &result); err != nil {
+		return nil, err
+	}
+	return &result, nil
+}
@@ -0,0 +1,49 @@
+package client
+
+import (
+	"context"
+	"net/http"
+	"net/url"
+	"strconv"
+)
+
+// TreeOptions selects the part of the page tree returned by GetTree
+type TreeOptions struct {
+	// Path returns the subtree below this page path instead of the whole tree
+	Path string
+	// Depth limits the number of descendant levels, 0 means unlimited.
+	// Use Depth 1 for a node with its direct children.
+	Depth int
+}
+
+// GetTree returns the page tree, or the subtree selected by opts
+func (c *Client) GetTree(ctx context.Context, opts TreeOptions) (*Node, error) {
+	var node Node
+	if err := c.do(ctx, &request{method: http.MethodGet, path: "/tree", query: treeQuery(opts)}, &node); err != nil {
+		return nil, err
+	}
+	return &node, nil
+}
+
+// GetChildren returns the direct children of the page at path, or of the root if path is empty
+func (c *Client) GetChildren(ctx context.Context, path string) ([]*Node, error) {
+	query := treeQuery(TreeOptions{Path: path})
+	query.Set("children", "true")
+
+	var children []*Node
+	if err := c.do(ctx, &request{method: http.MethodGet, path: "/tree", query: query}, &children); err != nil {
+		return nil, err
+	}
+	return children, nil
+}
+
+func treeQuery(opts TreeOptions) url.Values {
+	query := url.Values{}
+	if opts.Path != "" {
+		query.Set("path", opts.Path)
+	}
+	if opts.Depth > 0 {
+		query.Set("depth", strconv.Itoa(opts.Depth))
+	}
+	return query
+}
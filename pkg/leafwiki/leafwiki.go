@@ -0,0 +1,103 @@
+// Package leafwiki allows embedding LeafWiki into other Go programs.
+//
+// A Server bundles the wiki services and the HTTP handler serving the API and the frontend:
+//
+//	srv, err := leafwiki.New("./data", "jwt-secret", leafwiki.WithPublicAccess(true))
+//	if err != nil {
+//		log.Fatal(err)
+//	}
+//	defer srv.Close()
+//	http.ListenAndServe(":8080", srv.Handler())
+//
+// The handler serves absolute paths (/api, /assets, ...), so it has to be mounted at the root
+// of its own host or listener.
+package leafwiki
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"os"
+
+	leafhttp "github.com/Gomez12/wiki/internal/http"
+	"github.com/Gomez12/wiki/internal/wiki"
+)
+
+var ErrJWTSecretRequired = errors.New("jwt secret is required")
+
+// Server is an embeddable LeafWiki instance
+type Server struct {
+	wiki    *wiki.Wiki
+	handler http.Handler
+}
+
+// New creates a wiki stored in dataDir. The directory is created if it doesn't exist.
+func New(dataDir string, jwtSecret string, opts ...Option) (*Server, error) {
+	if jwtSecret == "" {
+		return nil, ErrJWTSecretRequired
+	}
+
+	o := &options{searchIndexing: true}
+	for _, opt := range opts {
+		opt(o)
+	}
+
+	if err := os.MkdirAll(dataDir, 0755); err != nil {
+		return nil, err
+	}
+
+	w, err := wiki.NewWiki(dataDir, o.adminPassword, jwtSecret, o.searchIndexing, o.wikiOptions...)
+	if err != nil {
+		return nil, err
+	}
+
+	return &Server{
+		wiki:    w,
+		handler: leafhttp.StripBasePath(w.BasePath(), leafhttp.NewRouter(w, o.publicAccess, o.injectCodeInHeader)),
+	}, nil
+}
+
+// Handler returns the HTTP handler serving the API and the frontend
+func (s *Server) Handler() http.Handler {
+	return s.handler
+}
+
+func (s *Server) Pages() Pages {
+	return s.wiki
+}
+
+func (s *Server) Tree() Tree {
+	return s.wiki
+}
+
+func (s *Server) Search() Search {
+	return s.wiki
+}
+
+func (s *Server) History() History {
+	return s.wiki
+}
+
+// SetupRequired reports whether no user exists yet, the first admin is then created with
+// POST /api/setup
+func (s *Server) SetupRequired() (bool, error) {
+	return s.wiki.SetupRequired()
+}
+
+// ApplySettings updates the runtime settings with the options of patch, a JSON object like
+// the body of PUT /api/admin/settings. Options missing in patch keep their value.
+func (s *Server) ApplySettings(patch []byte) error {
+	_, err := s.wiki.ApplySettings(patch)
+	return err
+}
+
+// Close stops the background workers right away and closes the databases
+func (s *Server) Close() error {
+	return s.wiki.Close()
+}
+
+// Shutdown waits for running indexing jobs until ctx ends, records pending page history
+// and closes the databases. Shut down the HTTP server serving Handler first.
+func (s *Server) Shutdown(ctx context.Context) error {
+	return s.wiki.Shutdown(ctx)
+}
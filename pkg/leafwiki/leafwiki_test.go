@@ -0,0 +1,55 @@
+package leafwiki
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestServer_EmbedsWiki(t *testing.T) {
+	srv, err := New(t.TempDir(), "secretkey", WithSearchIndexing(false), WithPublicAccess(true))
+	if err != nil {
+		t.Fatalf("New failed: %v", err)
+	}
+	defer srv.Close()
+
+	page, err := srv.Pages().CreatePage(nil, "Embedded", "embedded")
+	if err != nil {
+		t.Fatalf("CreatePage failed: %v", err)
+	}
+	if _, err := srv.Pages().UpdatePage(page.ID, page.Title, page.Slug, "# Embedded"); err != nil {
+		t.Fatalf("UpdatePage failed: %v", err)
+	}
+
+	found := false
+	for _, child := range srv.Tree().GetTree().Children {
+		if child.ID == page.ID {
+			found = true
+		}
+	}
+	if !found {
+		t.Errorf("expected page in tree")
+	}
+
+	req := httptest.NewRequest(http.MethodGet, "/api/pages/by-path?path=embedded", nil)
+	rec := httptest.NewRecorder()
+	srv.Handler().ServeHTTP(rec, req)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected 200 OK, got %d - %s", rec.Code, rec.Body.String())
+	}
+
+	var resp map[string]interface{}
+	if err := json.Unmarshal(rec.Body.Bytes(), &resp); err != nil {
+		t.Fatalf("failed to parse JSON: %v", err)
+	}
+	if resp["content"] != "# Embedded" {
+		t.Errorf("unexpected page: %v", resp)
+	}
+}
+
+func TestNew_RequiresJWTSecret(t *testing.T) {
+	if _, err := New(t.TempDir(), ""); err != ErrJWTSecretRequired {
+		t.Errorf("expected ErrJWTSecretRequired, got %v", err)
+	}
+}
@@ -0,0 +1,211 @@
+package leafwiki
+
+import (
+	"time"
+
+	"github.com/Gomez12/wiki/internal/wiki"
+)
+
+// Option configures optional behaviour of a Server
+type Option func(*options)
+
+type options struct {
+	adminPassword      string
+	publicAccess       bool
+	injectCodeInHeader string
+	searchIndexing     bool
+	wikiOptions        []wiki.Option
+}
+
+// WithAdminPassword sets the initial admin password, used only if no admin exists
+func WithAdminPassword(password string) Option {
+	return func(o *options) {
+		o.adminPassword = password
+	}
+}
+
+// WithPublicAccess allows unauthenticated read-only access to the wiki
+func WithPublicAccess(enabled bool) Option {
+	return func(o *options) {
+		o.publicAccess = enabled
+	}
+}
+
+// WithInjectCodeInHeader injects raw HTML before the closing </head> tag of the frontend.
+// The code is not sanitized, only use trusted input.
+func WithInjectCodeInHeader(code string) Option {
+	return func(o *options) {
+		o.injectCodeInHeader = code
+	}
+}
+
+// WithSearchIndexing enables the background indexer and file watcher (enabled by default)
+func WithSearchIndexing(enabled bool) Option {
+	return func(o *options) {
+		o.searchIndexing = enabled
+	}
+}
+
+// WithSearchPartitions splits the search index into one partition per top-level page
+func WithSearchPartitions(enabled bool) Option {
+	return func(o *options) {
+		o.wikiOptions = append(o.wikiOptions, wiki.WithSearchPartitions(enabled))
+	}
+}
+
+// WithAccessChecker sets the checker which decides which pages a user may read
+func WithAccessChecker(checker AccessChecker) Option {
+	return func(o *options) {
+		o.wikiOptions = append(o.wikiOptions, wiki.WithAccessChecker(checker))
+	}
+}
+
+// WithWebDAV serves the Markdown files at /webdav, so users can mount the wiki as network drive
+func WithWebDAV(enabled bool) Option {
+	return func(o *options) {
+		o.wikiOptions = append(o.wikiOptions, wiki.WithWebDAV(enabled))
+	}
+}
+
+// WithCaseInsensitiveRoutes resolves routes which differ from a slug only in case
+func WithCaseInsensitiveRoutes(enabled bool) Option {
+	return func(o *options) {
+		o.wikiOptions = append(o.wikiOptions, wiki.WithCaseInsensitiveRoutes(enabled))
+	}
+}
+
+// WithFollowSymlinks indexes and tracks the history of symlinked directories in the data dir
+func WithFollowSymlinks(enabled bool) Option {
+	return func(o *options) {
+		o.wikiOptions = append(o.wikiOptions, wiki.WithFollowSymlinks(enabled))
+	}
+}
+
+// WithObsidian serves the data dir as Obsidian vault with wikilinks, embeds and aliases
+func WithObsidian(enabled bool) Option {
+	return func(o *options) {
+		o.wikiOptions = append(o.wikiOptions, wiki.WithObsidian(enabled))
+	}
+}
+
+// WithReviewReminders reminds the owners of pages past their review-by date in this interval
+func WithReviewReminders(interval time.Duration) Option {
+	return func(o *options) {
+		o.wikiOptions = append(o.wikiOptions, wiki.WithReviewReminders(interval))
+	}
+}
+
+// WithSearchAlerts checks the subscribed saved searches in this interval and alerts their
+// owners of the pages which started matching
+func WithSearchAlerts(interval time.Duration) Option {
+	return func(o *options) {
+		o.wikiOptions = append(o.wikiOptions, wiki.WithSearchAlerts(interval))
+	}
+}
+
+// WithIntegrityCheck checks the search database in this interval besides at startup and
+// rebuilds the index if it is damaged
+func WithIntegrityCheck(interval time.Duration) Option {
+	return func(o *options) {
+		o.wikiOptions = append(o.wikiOptions, wiki.WithIntegrityCheck(interval))
+	}
+}
+
+// WithLayout sets how the pages of a new data dir are stored, flat slug.md files or a
+// folder with an index.md per page. An existing data dir keeps its layout.
+func WithLayout(layout Layout) Option {
+	return func(o *options) {
+		o.wikiOptions = append(o.wikiOptions, wiki.WithLayout(layout))
+	}
+}
+
+// WithPageFolderAssets stores new assets in an assets folder next to their page, linked as
+// ./assets/<name>, instead of the central assets dir
+func WithPageFolderAssets(enabled bool) Option {
+	return func(o *options) {
+		o.wikiOptions = append(o.wikiOptions, wiki.WithPageFolderAssets(enabled))
+	}
+}
+
+// WithSMTP sets the mail server used to send review reminders
+func WithSMTP(config SMTPConfig) Option {
+	return func(o *options) {
+		o.wikiOptions = append(o.wikiOptions, wiki.WithSMTP(config))
+	}
+}
+
+// WithSpellcheck enables the spellcheck with the hunspell dictionaries (.aff and .dic) of dir
+func WithSpellcheck(dir string) Option {
+	return func(o *options) {
+		o.wikiOptions = append(o.wikiOptions, wiki.WithSpellcheck(dir))
+	}
+}
+
+// WithImportRoot allows admins to import the folders below dir with the import API. Without it
+// only uploaded zip archives can be imported.
+func WithImportRoot(dir string) Option {
+	return func(o *options) {
+		o.wikiOptions = append(o.wikiOptions, wiki.WithImportRoot(dir))
+	}
+}
+
+// WithLinkCheck configures the check of external links, e.g. to run it periodically
+func WithLinkCheck(config LinkCheckConfig) Option {
+	return func(o *options) {
+		o.wikiOptions = append(o.wikiOptions, wiki.WithLinkCheck(config))
+	}
+}
+
+// WithGitSync pulls from and pushes to a git remote periodically
+func WithGitSync(config GitSyncConfig) Option {
+	return func(o *options) {
+		o.wikiOptions = append(o.wikiOptions, wiki.WithGitSync(config))
+	}
+}
+
+// WithSecurityHeaders overrides the Content-Security-Policy, HSTS and Referrer-Policy headers.
+// Empty fields keep the defaults, "off" disables a header.
+func WithSecurityHeaders(config SecurityHeadersConfig) Option {
+	return func(o *options) {
+		o.wikiOptions = append(o.wikiOptions, wiki.WithSecurityHeaders(config))
+	}
+}
+
+// WithBasePath serves the wiki under a path behind a reverse proxy, e.g. /wiki. The proxy
+// passes the requests on with the full path.
+func WithBasePath(basePath string) Option {
+	return func(o *options) {
+		o.wikiOptions = append(o.wikiOptions, wiki.WithBasePath(basePath))
+	}
+}
+
+// WithTrustedProxies sets the IP addresses and CIDR ranges of the reverse proxies whose
+// X-Forwarded headers are trusted. The default are the loopback and private networks.
+func WithTrustedProxies(proxies []string) Option {
+	return func(o *options) {
+		o.wikiOptions = append(o.wikiOptions, wiki.WithTrustedProxies(proxies))
+	}
+}
+
+// WithWatchPolling looks for changes of the files in this interval instead of relying on file
+// system events, for data dirs on NFS or in bind mounts of Docker on macOS and Windows
+func WithWatchPolling(interval time.Duration) Option {
+	return func(o *options) {
+		o.wikiOptions = append(o.wikiOptions, wiki.WithWatchPolling(interval))
+	}
+}
+
+// WithProfiling serves the pprof endpoints at /debug/pprof, for admins only
+func WithProfiling(enabled bool) Option {
+	return func(o *options) {
+		o.wikiOptions = append(o.wikiOptions, wiki.WithProfiling(enabled))
+	}
+}
+
+// WithConfigReload sets the function reading the config file again, called by
+// POST /api/admin/config/reload
+func WithConfigReload(reload func() error) Option {
+	return func(o *options) {
+		o.wikiOptions = append(o.wikiOptions, wiki.WithConfigReload(reload))
+	}
+}
@@ -0,0 +1,76 @@
+package leafwiki
+
+import (
+	"context"
+
+	"github.com/Gomez12/wiki/internal/core/access"
+	"github.com/Gomez12/wiki/internal/core/auth"
+	"github.com/Gomez12/wiki/internal/core/gitsync"
+	"github.com/Gomez12/wiki/internal/core/linkcheck"
+	"github.com/Gomez12/wiki/internal/core/notify"
+	"github.com/Gomez12/wiki/internal/core/securityheaders"
+	"github.com/Gomez12/wiki/internal/core/tree"
+	"github.com/Gomez12/wiki/internal/search"
+	"github.com/Gomez12/wiki/internal/wiki"
+)
+
+// Types used by the service interfaces
+type (
+	Page            = tree.Page
+	PageNode        = tree.PageNode
+	SearchResult    = search.SearchResult
+	IndexingStatus  = search.IndexingStatus
+	HistoryEntry    = search.FileHistoryEntry
+	HistoryOptions  = search.HistoryOptions
+	User            = auth.User
+	AccessChecker   = access.Checker
+	GitSyncConfig   = gitsync.Config
+	LinkCheckConfig = linkcheck.Config
+	SMTPConfig      = notify.SMTPConfig
+	Layout          = tree.Layout
+
+	SecurityHeadersConfig = securityheaders.Config
+)
+
+// Layouts of the pages in the data dir, see WithLayout
+const (
+	LayoutFlat   = tree.LayoutFlat
+	LayoutFolder = tree.LayoutFolder
+)
+
+// Pages creates, reads, updates and deletes pages
+type Pages interface {
+	CreatePage(parentID *string, title string, slug string) (*Page, error)
+	GetPage(id string) (*Page, error)
+	FindByPath(route string) (*Page, error)
+	UpdatePage(id, title, slug, content string) (*Page, error)
+	DeletePage(id string, recursive bool) error
+}
+
+// Tree reads and rearranges the page hierarchy
+type Tree interface {
+	GetTree() *PageNode
+	MovePage(id, parentID string) error
+	SortPages(parentID string, orderedIDs []string) error
+}
+
+// Search queries the full text index
+type Search interface {
+	Search(ctx context.Context, query string, offset, limit int) (*SearchResult, error)
+	SearchForUser(ctx context.Context, user *User, query string, offset, limit int) (*SearchResult, error)
+	GetIndexingStatus() *IndexingStatus
+	ReindexAll() error
+}
+
+// History reads the recorded changes of pages
+type History interface {
+	GetPageHistory(route string, opts HistoryOptions) ([]HistoryEntry, int, string, error)
+	GetPageHistoryEntry(id int64) (*HistoryEntry, error)
+}
+
+var (
+	_ Pages   = (*wiki.Wiki)(nil)
+	_ Tree    = (*wiki.Wiki)(nil)
+	_ Search  = (*wiki.Wiki)(nil)
+	_ History = (*wiki.Wiki)(nil)
+)
@@ -0,0 +1,231 @@
+package leafwiki
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+)
+
+var (
+	ErrInvalidSpaceName = errors.New("invalid space name")
+	ErrSpaceExists      = errors.New("space already exists")
+	ErrSpaceNotFound    = errors.New("space not found")
+	ErrHostInUse        = errors.New("host is already used by another space")
+)
+
+// SpacePathPrefix is the path below which every space is reachable by name, e.g. /spaces/docs/api/tree
+const SpacePathPrefix = "/spaces/"
+
+var spaceNameRegex = regexp.MustCompile(`^[a-z0-9][a-z0-9-]{0,62}$`)
+
+// SpaceConfig describes one wiki hosted by Spaces
+type SpaceConfig struct {
+	// Name identifies the space, lowercase letters, digits and dashes
+	Name string
+	// DataDir holds the pages, databases and users of the space
+	DataDir string
+	// Hosts are the hostnames routed to the space, e.g. docs.example.com
+	Hosts []string
+	// Options configure the wiki of the space
+	Options []Option
+}
+
+// Spaces hosts several independent wikis in one process. Every space has its own data
+// directory, search index, users and permissions. Requests are routed by hostname first,
+// then by the path prefix /spaces/<name>/ and otherwise go to the default space.
+//
+// The web interface expects to be served at the root of a host, so use hostnames for it;
+// the path prefix is meant for API clients.
+type Spaces struct {
+	jwtSecret string
+
+	mu           sync.RWMutex
+	spaces       map[string]*space
+	hosts        map[string]*space
+	defaultSpace string
+}
+
+type space struct {
+	config  SpaceConfig
+	server  *Server
+	handler http.Handler
+}
+
+// NewSpaces creates an empty registry. Every space signs its tokens with its own secret
+// derived from jwtSecret, so a token of one space is not valid in another.
+func NewSpaces(jwtSecret string) (*Spaces, error) {
+	if jwtSecret == "" {
+		return nil, ErrJWTSecretRequired
+	}
+	return &Spaces{
+		jwtSecret: jwtSecret,
+		spaces:    map[string]*space{},
+		hosts:     map[string]*space{},
+	}, nil
+}
+
+// Add opens the wiki of a space and starts routing requests to it
+func (s *Spaces) Add(config SpaceConfig) (*Server, error) {
+	if !spaceNameRegex.MatchString(config.Name) {
+		return nil, fmt.Errorf("%w: %q", ErrInvalidSpaceName, config.Name)
+	}
+	hosts := make([]string, 0, len(config.Hosts))
+	for _, host := range config.Hosts {
+		hosts = append(hosts, normalizeHost(host))
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if _, ok := s.spaces[config.Name]; ok {
+		return nil, fmt.Errorf("%w: %s", ErrSpaceExists, config.Name)
+	}
+	for _, host := range hosts {
+		if _, ok := s.hosts[host]; ok {
+			return nil, fmt.Errorf("%w: %s", ErrHostInUse, host)
+		}
+	}
+
+	server, err := New(config.DataDir, s.jwtSecret+"/"+config.Name, config.Options...)
+	if err != nil {
+		return nil, fmt.Errorf("could not open space %s: %w", config.Name, err)
+	}
+
+	sp := &space{
+		config:  config,
+		server:  server,
+		handler: http.StripPrefix(SpacePathPrefix+config.Name, server.Handler()),
+	}
+	s.spaces[config.Name] = sp
+	for _, host := range hosts {
+		s.hosts[host] = sp
+	}
+	return server, nil
+}
+
+// Remove stops routing to a space and shuts its wiki down
+func (s *Spaces) Remove(ctx context.Context, name string) error {
+	s.mu.Lock()
+	sp, ok := s.spaces[name]
+	if ok {
+		delete(s.spaces, name)
+		for host, hostSpace := range s.hosts {
+			if hostSpace == sp {
+				delete(s.hosts, host)
+			}
+		}
+		if s.defaultSpace == name {
+			s.defaultSpace = ""
+		}
+	}
+	s.mu.Unlock()
+
+	if !ok {
+		return fmt.Errorf("%w: %s", ErrSpaceNotFound, name)
+	}
+	return sp.server.Shutdown(ctx)
+}
+
+// SetDefault routes requests which match no host and no path prefix to the space.
+// An empty name answers them with 404.
+func (s *Spaces) SetDefault(name string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if _, ok := s.spaces[name]; name != "" && !ok {
+		return fmt.Errorf("%w: %s", ErrSpaceNotFound, name)
+	}
+	s.defaultSpace = name
+	return nil
+}
+
+// Get returns the server of a space
+func (s *Spaces) Get(name string) (*Server, bool) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	sp, ok := s.spaces[name]
+	if !ok {
+		return nil, false
+	}
+	return sp.server, true
+}
+
+// Names returns the names of all spaces in alphabetical order
+func (s *Spaces) Names() []string {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	names := make([]string, 0, len(s.spaces))
+	for name := range s.spaces {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// ServeHTTP routes the request to its space
+func (s *Spaces) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	s.mu.RLock()
+	sp, ok := s.hosts[normalizeHost(r.Host)]
+	prefixed := false
+	if !ok {
+		if name, found := spaceFromPath(r.URL.Path); found {
+			sp, ok = s.spaces[name]
+			prefixed = ok
+		}
+	}
+	if !ok && s.defaultSpace != "" {
+		sp, ok = s.spaces[s.defaultSpace]
+	}
+	s.mu.RUnlock()
+
+	switch {
+	case !ok:
+		http.Error(w, "Unknown space", http.StatusNotFound)
+	case prefixed:
+		if r.URL.Path == SpacePathPrefix+sp.config.Name {
+			http.Redirect(w, r, r.URL.Path+"/", http.StatusMovedPermanently)
+			return
+		}
+		sp.handler.ServeHTTP(w, r)
+	default:
+		sp.server.Handler().ServeHTTP(w, r)
+	}
+}
+
+// Shutdown shuts down the wikis of all spaces. Shut down the HTTP server serving Spaces first.
+func (s *Spaces) Shutdown(ctx context.Context) error {
+	s.mu.Lock()
+	spaces := s.spaces
+	s.spaces, s.hosts, s.defaultSpace = map[string]*space{}, map[string]*space{}, ""
+	s.mu.Unlock()
+
+	var errs []error
+	for name, sp := range spaces {
+		if err := sp.server.Shutdown(ctx); err != nil {
+			errs = append(errs, fmt.Errorf("space %s: %w", name, err))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// spaceFromPath returns the space name of a path below SpacePathPrefix
+func spaceFromPath(p string) (string, bool) {
+	rest, ok := strings.CutPrefix(p, SpacePathPrefix)
+	if !ok {
+		return "", false
+	}
+	name, _, _ := strings.Cut(rest, "/")
+	return name, name != ""
+}
+
+// normalizeHost drops the port and lowercases the hostname
+func normalizeHost(host string) string {
+	if h, _, err := net.SplitHostPort(host); err == nil {
+		host = h
+	}
+	return strings.ToLower(strings.TrimSuffix(host, "."))
+}
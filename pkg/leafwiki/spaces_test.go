@@ -0,0 +1,112 @@
+package leafwiki
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func newTestSpaces(t *testing.T) *Spaces {
+	t.Helper()
+	spaces, err := NewSpaces("secretkey")
+	if err != nil {
+		t.Fatalf("NewSpaces failed: %v", err)
+	}
+	t.Cleanup(func() { _ = spaces.Shutdown(context.Background()) })
+
+	for _, cfg := range []SpaceConfig{
+		{Name: "docs", DataDir: t.TempDir(), Hosts: []string{"Docs.Example.com"}},
+		{Name: "team", DataDir: t.TempDir()},
+	} {
+		cfg.Options = []Option{WithSearchIndexing(false), WithPublicAccess(true)}
+		server, err := spaces.Add(cfg)
+		if err != nil {
+			t.Fatalf("Add %s failed: %v", cfg.Name, err)
+		}
+		page, err := server.Pages().CreatePage(nil, "Only in "+cfg.Name, "only-in-"+cfg.Name)
+		if err != nil {
+			t.Fatalf("CreatePage failed: %v", err)
+		}
+		if _, err := server.Pages().UpdatePage(page.ID, page.Title, page.Slug, "# "+cfg.Name); err != nil {
+			t.Fatalf("UpdatePage failed: %v", err)
+		}
+	}
+	return spaces
+}
+
+func getPageContent(t *testing.T, handler http.Handler, host, url string) (int, string) {
+	t.Helper()
+	req := httptest.NewRequest(http.MethodGet, url, nil)
+	req.Host = host
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, req)
+	if rec.Code != http.StatusOK {
+		return rec.Code, ""
+	}
+	var page struct {
+		Content string `json:"content"`
+	}
+	if err := json.Unmarshal(rec.Body.Bytes(), &page); err != nil {
+		t.Fatalf("failed to parse JSON: %v", err)
+	}
+	return rec.Code, page.Content
+}
+
+func TestSpaces_RoutesByHostAndPath(t *testing.T) {
+	spaces := newTestSpaces(t)
+
+	if code, content := getPageContent(t, spaces, "docs.example.com:8080", "/api/pages/by-path?path=only-in-docs"); code != http.StatusOK || content != "# docs" {
+		t.Errorf("expected docs page by host, got %d %q", code, content)
+	}
+	if code, content := getPageContent(t, spaces, "localhost", "/spaces/team/api/pages/by-path?path=only-in-team"); code != http.StatusOK || content != "# team" {
+		t.Errorf("expected team page by path, got %d %q", code, content)
+	}
+	// the spaces are independent
+	if code, _ := getPageContent(t, spaces, "docs.example.com", "/api/pages/by-path?path=only-in-team"); code != http.StatusNotFound {
+		t.Errorf("expected team page to be missing in docs, got %d", code)
+	}
+	if code, _ := getPageContent(t, spaces, "localhost", "/api/pages/by-path?path=only-in-team"); code != http.StatusNotFound {
+		t.Errorf("expected 404 without default space, got %d", code)
+	}
+
+	if err := spaces.SetDefault("team"); err != nil {
+		t.Fatalf("SetDefault failed: %v", err)
+	}
+	if code, content := getPageContent(t, spaces, "localhost", "/api/pages/by-path?path=only-in-team"); code != http.StatusOK || content != "# team" {
+		t.Errorf("expected default space, got %d %q", code, content)
+	}
+	if got := strings.Join(spaces.Names(), ","); got != "docs,team" {
+		t.Errorf("unexpected names %q", got)
+	}
+}
+
+func TestSpaces_Lifecycle(t *testing.T) {
+	spaces := newTestSpaces(t)
+
+	if _, err := spaces.Add(SpaceConfig{Name: "docs", DataDir: t.TempDir()}); !errors.Is(err, ErrSpaceExists) {
+		t.Errorf("expected ErrSpaceExists, got %v", err)
+	}
+	if _, err := spaces.Add(SpaceConfig{Name: "other", DataDir: t.TempDir(), Hosts: []string{"docs.example.com"}}); !errors.Is(err, ErrHostInUse) {
+		t.Errorf("expected ErrHostInUse, got %v", err)
+	}
+	if _, err := spaces.Add(SpaceConfig{Name: "Bad Name", DataDir: t.TempDir()}); !errors.Is(err, ErrInvalidSpaceName) {
+		t.Errorf("expected ErrInvalidSpaceName, got %v", err)
+	}
+
+	if err := spaces.Remove(context.Background(), "docs"); err != nil {
+		t.Fatalf("Remove failed: %v", err)
+	}
+	if _, ok := spaces.Get("docs"); ok {
+		t.Errorf("expected docs to be removed")
+	}
+	if code, _ := getPageContent(t, spaces, "docs.example.com", "/api/pages/by-path?path=only-in-docs"); code != http.StatusNotFound {
+		t.Errorf("expected removed space to be unreachable, got %d", code)
+	}
+	if err := spaces.Remove(context.Background(), "docs"); !errors.Is(err, ErrSpaceNotFound) {
+		t.Errorf("expected ErrSpaceNotFound, got %v", err)
+	}
+}
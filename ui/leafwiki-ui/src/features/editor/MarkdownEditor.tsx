@@ -20,11 +20,17 @@ import MarkdownCodeEditor from './MarkdownCodeEditor'
 import MarkdownToolbar from './MarkdownToolbar'
 import { insertHeadingAtStart, insertWrappedText } from './editorCommands'
 
-import { uploadAsset, UploadAssetResponse } from '@/lib/api/assets'
+import {
+  pasteImage,
+  PasteImageResponse,
+  uploadAsset,
+  UploadAssetResponse,
+} from '@/lib/api/assets'
 import {
   IMAGE_EXTENSIONS,
   MAX_UPLOAD_SIZE,
   MAX_UPLOAD_SIZE_MB,
+  PASTED_IMAGE_TYPES,
 } from '@/lib/config'
 import { useEditorStore } from '@/stores/editor'
 import { toast } from 'sonner'
@@ -113,22 +119,35 @@ const MarkdownEditor = (
 
         // Upload each file
         try {
-          const res: UploadAssetResponse = await uploadAsset(pageId, file)
-
-          toast.success(`Uploaded ${file.name}`)
-
-          // The result of uploadAsset looks like this:
-          // {"file":"/assets/0NmpvSivg/preview-scrollbar.gif"}
-          const uploadedFile = res.file
-          const ext = file.name.split('.').pop()?.toLowerCase()
-
-          const isImage =
-            file.type.startsWith('image/') ||
-            IMAGE_EXTENSIONS.includes(ext ?? '')
-
-          const markdown = isImage
-            ? `![${file.name}](${uploadedFile})\n`
-            : `[${file.name}](${uploadedFile})\n`
+          let markdown: string
+          if (PASTED_IMAGE_TYPES.includes(file.type)) {
+            // Screenshots come as image.png and are named after the time they were pasted
+            const generic = !file.name || /^image\.\w+$/.test(file.name)
+            const res: PasteImageResponse = await pasteImage(
+              pageId,
+              file,
+              generic ? undefined : file.name,
+            )
+            toast.success('Pasted image')
+            markdown = `${res.markdown}\n`
+          } else {
+            const res: UploadAssetResponse = await uploadAsset(pageId, file)
+
+            toast.success(`Uploaded ${file.name}`)
+
+            // The result of uploadAsset looks like this:
+            // {"file":"/assets/0NmpvSivg/preview-scrollbar.gif"}
+            const uploadedFile = res.file
+            const ext = file.name.split('.').pop()?.toLowerCase()
+
+            const isImage =
+              file.type.startsWith('image/') ||
+              IMAGE_EXTENSIONS.includes(ext ?? '')
+
+            markdown = isImage
+              ? `![${file.name}](${uploadedFile})\n`
+              : `[${file.name}](${uploadedFile})\n`
+          }
 
           const view = editorViewRef.current
           if (!view) continue
@@ -375,12 +394,13 @@ const MarkdownEditor = (
           <MarkdownPreview
             content={debouncedPreview}
             path={path}
+            pageId={pageId}
             key={assetVersion}
           />
         </div>
       </div>
     )
-  }, [assetVersion, debouncedPreview, setPreviewRef, path])
+  }, [assetVersion, debouncedPreview, setPreviewRef, path, pageId])
 
   // TODO: Known Issues:
   // * When we resize the window, the preview does not update immediately.
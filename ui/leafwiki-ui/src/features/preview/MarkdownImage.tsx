@@ -1,13 +1,18 @@
 /* eslint-disable react-hooks/set-state-in-effect */
+import { BASE_PATH } from '@/lib/config'
+import { resolvePageAsset } from '@/lib/urlUtil'
 import { useEffect, useState } from 'react'
 
-type Props = React.ImgHTMLAttributes<HTMLImageElement>
+type Props = React.ImgHTMLAttributes<HTMLImageElement> & {
+  pageId?: string
+}
 
-export function MarkdownImage({ src = '', alt, ...rest }: Props) {
+export function MarkdownImage({ src: rawSrc = '', alt, pageId, ...rest }: Props) {
+  const src = resolvePageAsset(rawSrc, pageId)
   const [versionedSrc, setVersionedSrc] = useState(src)
 
   useEffect(() => {
-    if (!src?.startsWith('/assets/')) {
+    if (!src?.startsWith(`${BASE_PATH}/assets/`)) {
       setVersionedSrc(src)
       return
     }
@@ -1,8 +1,9 @@
 import { Link } from 'react-router-dom'
 
 import { Button } from '@/components/ui/button'
+import { BASE_PATH } from '@/lib/config'
 import { DIALOG_CREATE_PAGE_BY_PATH } from '@/lib/registries'
-import { buildViewUrl } from '@/lib/urlUtil'
+import { buildViewUrl, resolvePageAsset } from '@/lib/urlUtil'
 import { useAppMode } from '@/lib/useAppMode'
 import { useAuthStore } from '@/stores/auth'
 import { useDialogsStore } from '@/stores/dialogs'
@@ -14,9 +15,15 @@ interface MarkdownLinkProps extends AnchorHTMLAttributes<HTMLAnchorElement> {
   href?: string
   children?: ReactNode
   path?: string
+  pageId?: string
 }
 
-export function MarkdownLink({ href, children, ...props }: MarkdownLinkProps) {
+export function MarkdownLink({
+  href,
+  children,
+  pageId,
+  ...props
+}: MarkdownLinkProps) {
   const openDialog = useDialogsStore((s) => s.openDialog)
   const getPageByPath = useTreeStore((s) => s.getPageByPath)
   const user = useAuthStore((s) => s.user)
@@ -43,10 +50,14 @@ export function MarkdownLink({ href, children, ...props }: MarkdownLinkProps) {
 
   if (isInternal) {
     // check if it is a asset link
-    if (href.startsWith('assets/') || href.startsWith('/assets/')) {
+    if (
+      href.startsWith('assets/') ||
+      href.startsWith('./assets/') ||
+      href.startsWith('/assets/')
+    ) {
       return (
         <a
-          href={href}
+          href={resolvePageAsset(href, pageId)}
           {...props}
           target="_blank"
           rel="noopener noreferrer"
@@ -66,7 +77,9 @@ export function MarkdownLink({ href, children, ...props }: MarkdownLinkProps) {
       // For relative links, we need to add the current path as prefix.
       let currentPath: string
       if (!props.path) {
-        currentPath = buildViewUrl(window.location.pathname)
+        currentPath = buildViewUrl(
+          window.location.pathname.slice(BASE_PATH.length),
+        )
       } else {
         currentPath = props.path
       }
@@ -1,6 +1,12 @@
 import { remarkLineNumber } from '@/features/preview/remarkLineNumber'
 import 'highlight.js/styles/github-dark.css'
-import { ClassAttributes, HTMLAttributes, useCallback, useMemo } from 'react'
+import {
+  ClassAttributes,
+  HTMLAttributes,
+  ImgHTMLAttributes,
+  useCallback,
+  useMemo,
+} from 'react'
 import ReactMarkdown from 'react-markdown'
 import { JSX } from 'react/jsx-runtime'
 import rehypeHighlight from 'rehype-highlight'
@@ -13,21 +19,30 @@ import MermaidBlock from './MermaidBlock'
 type Props = {
   content: string
   path?: string
+  // pageId resolves the links to the assets stored next to the page
+  pageId?: string
 }
 
-export default function MarkdownPreview({ content, path }: Props) {
+export default function MarkdownPreview({ content, path, pageId }: Props) {
   const markdownLink = useCallback(
     (
       props: ClassAttributes<HTMLAnchorElement> &
         HTMLAttributes<HTMLAnchorElement>,
-    ) => <MarkdownLink path={path} {...props} />,
-    [path],
+    ) => <MarkdownLink path={path} pageId={pageId} {...props} />,
+    [path, pageId],
+  )
+
+  const markdownImage = useCallback(
+    (props: ImgHTMLAttributes<HTMLImageElement>) => (
+      <MarkdownImage pageId={pageId} {...props} />
+    ),
+    [pageId],
   )
 
   const components = useMemo(
     () => ({
       a: markdownLink,
-      img: MarkdownImage,
+      img: markdownImage,
       h1: ({
         children,
         ...props
@@ -122,7 +137,7 @@ export default function MarkdownPreview({ content, path }: Props) {
         )
       },
     }),
-    [markdownLink],
+    [markdownLink, markdownImage],
   )
 
   return (
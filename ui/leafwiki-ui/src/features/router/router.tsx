@@ -1,3 +1,4 @@
+import { BASE_PATH } from '@/lib/config'
 import { createBrowserRouter, Navigate, RouteObject } from 'react-router-dom'
 import LoginForm from '../auth/LoginForm'
 import PageEditor from '../editor/PageEditor.tsx'
@@ -57,4 +58,4 @@ export const createLeafWikiRouter = (isReadOnlyViewer: boolean) =>
         </AuthWrapper>
       ),
     },
-  ] satisfies RouteObject[])
+  ] satisfies RouteObject[], { basename: BASE_PATH || '/' })
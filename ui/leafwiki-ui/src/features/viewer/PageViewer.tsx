@@ -132,7 +132,11 @@ export default function PageViewer() {
       {/* we keep the content also during loading to avoid flickering */}
       {page && !error && (
         <article className="page-viewer__content">
-          <MarkdownPreview content={page.content} path={page.path} />
+          <MarkdownPreview
+            content={page.content}
+            path={page.path}
+            pageId={page.id}
+          />
         </article>
       )}
       {renderError()}
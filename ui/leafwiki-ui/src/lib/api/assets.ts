@@ -20,6 +20,29 @@ export async function uploadAsset(
   }
 }
 
+export type PasteImageResponse = {
+  file: string
+  markdown: string
+}
+
+// pasteImage stores an image from the clipboard, under a timestamped name
+// unless a name is given, and returns the Markdown embedding it
+export async function pasteImage(
+  pageId: string,
+  image: Blob,
+  name?: string,
+): Promise<PasteImageResponse> {
+  const query = name ? `?name=${encodeURIComponent(name)}` : ''
+  try {
+    return (await fetchWithAuth(`/api/pages/${pageId}/assets/paste${query}`, {
+      method: 'POST',
+      body: image,
+    })) as PasteImageResponse
+  } catch {
+    throw new Error('Image paste failed')
+  }
+}
+
 export async function getAssets(pageId: string): Promise<string[]> {
   const data = await fetchWithAuth(`/api/pages/${pageId}/assets`, {})
   const typedData = data as { files: string[] }
@@ -47,7 +47,8 @@ export async function fetchWithAuth(
   const logout = store.logout
 
   const headers = new Headers(options.headers || {})
-  if (!(options.body instanceof FormData)) {
+  // form data and binary bodies bring their own content type
+  if (!(options.body instanceof FormData) && !(options.body instanceof Blob)) {
     headers.set('Content-Type', 'application/json')
   }
   if (token) headers.set('Authorization', `Bearer ${token}`)
@@ -57,7 +58,8 @@ export async function fetchWithAuth(
   if (
     options.body &&
     typeof options.body === 'object' &&
-    !(options.body instanceof FormData)
+    !(options.body instanceof FormData) &&
+    !(options.body instanceof Blob)
   ) {
     originalBody = JSON.stringify(options.body)
   }
@@ -7,9 +7,26 @@ export type User = {
   role: 'admin' | 'editor'
 }
 
+type UserList = {
+  users: User[]
+  total: number
+}
+
+// usersPageSize is the largest page the server returns
+const usersPageSize = 500
+
 export async function getUsers(): Promise<User[]> {
   try {
-    return (await fetchWithAuth('/api/users')) as User[]
+    const users: User[] = []
+    for (;;) {
+      const list = (await fetchWithAuth(
+        `/api/users?limit=${usersPageSize}&offset=${users.length}`,
+      )) as UserList
+      users.push(...list.users)
+      if (list.users.length === 0 || users.length >= list.total) {
+        return users
+      }
+    }
   } catch {
     throw new Error('User fetch failed')
   }
@@ -1,6 +1,13 @@
-export const API_BASE_URL = (
-  import.meta.env.VITE_API_URL || 'http://localhost:8080'
-).replace(/\/$/, '')
+// BASE_PATH is the path the wiki is served under behind a reverse proxy, e.g. /wiki.
+// The server passes it in a meta tag of index.html.
+export const BASE_PATH =
+  document
+    .querySelector<HTMLMetaElement>('meta[name="leafwiki-base-path"]')
+    ?.content.replace(/\/$/, '') ?? ''
+
+export const API_BASE_URL =
+  (import.meta.env.VITE_API_URL || 'http://localhost:8080').replace(/\/$/, '') +
+  BASE_PATH
 
 export const MAX_UPLOAD_SIZE_MB = 50
 export const MAX_UPLOAD_SIZE = MAX_UPLOAD_SIZE_MB * 1024 * 1024
@@ -13,3 +20,11 @@ export const IMAGE_EXTENSIONS = [
   'bmp',
   'svg',
 ]
+
+// Images of these types are pasted with a timestamped name
+export const PASTED_IMAGE_TYPES = [
+  'image/png',
+  'image/jpeg',
+  'image/gif',
+  'image/webp',
+]
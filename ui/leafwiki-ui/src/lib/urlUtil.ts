@@ -1,3 +1,5 @@
+import { BASE_PATH } from './config'
+
 export function buildEditUrl(pathname: string): string {
   if (pathname.startsWith('/e/')) {
     return pathname
@@ -17,3 +19,14 @@ export function buildViewUrl(pathname: string): string {
 
   return pathname
 }
+
+const pageAssetPattern = /^(?:\.\/)?assets\/(.+)$/
+
+// resolvePageAsset turns a link to an asset stored next to the page, ./assets/<name>,
+// into the URL it is served at, like the server does when it renders the page.
+// Asset URLs are served under the base path.
+export function resolvePageAsset(src: string, pageId?: string): string {
+  const match = pageId ? pageAssetPattern.exec(src) : null
+  const resolved = match ? `/assets/${pageId}/${match[1]}` : src
+  return resolved.startsWith('/assets/') ? BASE_PATH + resolved : resolved
+}
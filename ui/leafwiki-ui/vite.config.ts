@@ -13,6 +13,8 @@ export default defineConfig({
   optimizeDeps: {
     include: ['mermaid', 'dagre-d3-es'],
   },
+  // relative links, so the bundle works under any base path; the server rewrites index.html
+  base: './',
   build: {
     assetsDir: 'static', // <--- here you change the target directory
     rollupOptions: {